@@ -0,0 +1,281 @@
+package frostdb
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/go-kit/log/level"
+	"github.com/oklog/ulid/v2"
+	"github.com/thanos-io/objstore"
+)
+
+// DefaultBlockGCGracePeriod is the default duration superseded blocks are
+// kept before they are deleted, see WithBlockGC.
+const DefaultBlockGCGracePeriod = 10 * time.Minute
+
+// BlockGCStats describes the blocks deleted by a garbage collection.
+type BlockGCStats struct {
+	// Blocks is the number of blocks deleted.
+	Blocks int
+	// Bytes is the total size of the objects of the blocks deleted.
+	Bytes int64
+}
+
+// BlockCollector is implemented by data sinks that can delete the blocks
+// that are no longer referenced.
+type BlockCollector interface {
+	// CollectGarbage deletes the blocks under prefix that were superseded,
+	// or orphaned, more than gracePeriod ago.
+	CollectGarbage(ctx context.Context, prefix string, gracePeriod time.Duration) (BlockGCStats, error)
+}
+
+// WithBlockGC collects the garbage of the persisted blocks of all tables at
+// the given interval, deleting blocks that were superseded more than
+// gracePeriod ago. The grace period must be longer than the longest running
+// query, since queries may still read superseded blocks. Only data sinks
+// implementing BlockCollector are collected, e.g. a DefaultObjstoreBucket
+// with manifests enabled, see StorageWithManifests.
+func WithBlockGC(interval, gracePeriod time.Duration) Option {
+	return func(s *ColumnStore) error {
+		s.blockGCInterval = interval
+		s.blockGCGracePeriod = gracePeriod
+		return nil
+	}
+}
+
+// CollectGarbage deletes the superseded and orphaned persisted blocks of the
+// table from all data sinks implementing BlockCollector.
+func (t *Table) CollectGarbage(ctx context.Context) error {
+	gracePeriod := t.db.columnStore.blockGCGracePeriod
+	prefix := filepath.Join(t.db.name, t.name)
+	collected := false
+	for _, sink := range t.db.sinks {
+		collector, ok := sink.(BlockCollector)
+		if !ok {
+			continue
+		}
+		stats, err := collector.CollectGarbage(ctx, prefix, gracePeriod)
+		t.metrics.blocksCollected.Add(float64(stats.Blocks))
+		t.metrics.bytesReclaimed.Add(float64(stats.Bytes))
+		if err != nil {
+			return fmt.Errorf("collect garbage of %s: %w", sink, err)
+		}
+		if stats.Blocks > 0 {
+			level.Debug(t.logger).Log("msg", "collected blocks", "table", t.name, "sink", sink.String(), "blocks", stats.Blocks, "bytes", stats.Bytes)
+			collected = true
+		}
+	}
+	if collected {
+		return t.RefreshStorageUsage(ctx)
+	}
+	return nil
+}
+
+// startBlockGCLoop starts collecting the garbage of the table's persisted
+// blocks in the background if no background loop is running yet.
+func (t *Table) startBlockGCLoop() {
+	interval := t.db.columnStore.blockGCInterval
+	if interval <= 0 || len(t.db.sinks) == 0 {
+		return
+	}
+
+	t.blockGCMtx.Lock()
+	defer t.blockGCMtx.Unlock()
+	if t.stopBlockGC != nil {
+		return
+	}
+
+	t.stopBlockGC = make(chan struct{})
+	t.blockGCDone = make(chan struct{})
+	go func(stop <-chan struct{}, done chan<- struct{}) {
+		defer close(done)
+		ticker := t.db.columnStore.clock.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C():
+				if t.db.MaintenancePaused() {
+					continue
+				}
+				t.db.columnStore.scheduler.Do(WorkBlockGC, t.db.name+"/"+t.name, func() {
+					if err := t.CollectGarbage(context.Background()); err != nil {
+						level.Warn(t.logger).Log("msg", "failed to collect garbage", "table", t.name, "err", err)
+					}
+				})
+			}
+		}
+	}(t.stopBlockGC, t.blockGCDone)
+}
+
+// stopBlockGCLoop stops the background garbage collection loop, if any, and
+// waits for it to exit.
+func (t *Table) stopBlockGCLoop() {
+	t.blockGCMtx.Lock()
+	defer t.blockGCMtx.Unlock()
+	if t.stopBlockGC == nil {
+		return
+	}
+	close(t.stopBlockGC)
+	<-t.blockGCDone
+	t.stopBlockGC = nil
+	t.blockGCDone = nil
+}
+
+// CollectGarbage implements the BlockCollector interface. Blocks that were
+// superseded in the manifest of the table more than gracePeriod ago are
+// deleted and removed from the manifest, as are orphaned blocks, see
+// DeleteOrphanedBlocks. Without manifests, no blocks are superseded. With
+// leases enabled, only the writer holding the lease of the table collects its
+// garbage, other writers skip it, see StorageWithLeases.
+func (b *DefaultObjstoreBucket) CollectGarbage(ctx context.Context, prefix string, gracePeriod time.Duration) (BlockGCStats, error) {
+	if !b.manifestsEnabled {
+		return BlockGCStats{}, nil
+	}
+	prefix = filepath.Clean(prefix)
+	if b.leases != nil {
+		held, err := b.leases.holds(ctx, b, prefix)
+		if err != nil || !held {
+			return BlockGCStats{}, err
+		}
+	}
+
+	b.manifestsMtx.Lock()
+	manifest, err := b.loadManifest(ctx, prefix)
+	b.manifestsMtx.Unlock()
+	if err != nil {
+		return BlockGCStats{}, err
+	}
+
+	cutoff := b.clock.Now().Add(-gracePeriod)
+	var expired []string
+	for block, at := range manifest.superseded {
+		if at.Before(cutoff) {
+			expired = append(expired, block)
+		}
+	}
+	sort.Strings(expired)
+
+	var stats BlockGCStats
+	var collected []string
+	var deleteErr error
+	for _, block := range expired {
+		var n int64
+		n, deleteErr = b.deleteBlockObjects(ctx, filepath.Join(prefix, block))
+		if deleteErr != nil {
+			break
+		}
+		collected = append(collected, block)
+		stats.Blocks++
+		stats.Bytes += n
+	}
+	if len(collected) > 0 {
+		// Superseded blocks whose objects were deleted, but that are still
+		// listed because committing failed, are collected again.
+		if err := b.commitManifest(ctx, prefix, func(m *tableManifest) bool {
+			for _, block := range collected {
+				delete(m.superseded, block)
+			}
+			return true
+		}); err != nil {
+			return stats, err
+		}
+	}
+	if deleteErr != nil {
+		return stats, deleteErr
+	}
+
+	orphaned, err := b.deleteOrphanedBlocks(ctx, prefix, gracePeriod)
+	stats.Blocks += orphaned.Blocks
+	stats.Bytes += orphaned.Bytes
+	return stats, err
+}
+
+// supersedeBlock replaces the block in the given directory with the block
+// read from r, or removes it if r is nil, in a single version of the
+// manifest of its table. The objects of the replaced block are kept until
+// they are collected, so that queries reading it don't fail.
+func (b *DefaultObjstoreBucket) supersedeBlock(ctx context.Context, blockDir string, r io.Reader) error {
+	blockName := filepath.Join(blockDir, "data.parquet")
+	db, table, block, ok := parseBlockName(blockName)
+	if !ok {
+		return fmt.Errorf("invalid block %s", blockDir)
+	}
+	prefix := filepath.Join(db, table)
+	if b.leases != nil {
+		if err := b.leases.acquire(ctx, b, blockName); err != nil {
+			return err
+		}
+	}
+
+	replacement := ""
+	if r != nil {
+		id, err := ulid.Parse(filepath.Base(block))
+		if err != nil {
+			return err
+		}
+		// The replacement keeps the time of the block, so that it is read
+		// by the same scans. Orphaned replacements are aged by the time
+		// they were uploaded, not by their IDs, see DeleteOrphanedBlocks.
+		replacement = filepath.Join(filepath.Dir(block), ulid.MustNew(id.Time(), ulid.DefaultEntropy()).String())
+		name := filepath.Join(prefix, replacement, "data.parquet")
+		defer b.startCommit(name)()
+		if err := b.Bucket.Upload(ctx, name, r); err != nil {
+			return err
+		}
+		if b.blockStatsEnabled {
+			if err := b.writeBlockStats(ctx, name); err != nil {
+				level.Warn(b.logger).Log("msg", "failed to write block stats", "block", name, "err", err)
+			}
+		}
+		if err := b.updateBlockIndex(ctx, name, true); err != nil {
+			return err
+		}
+	}
+
+	now := b.clock.Now()
+	if err := b.commitManifest(ctx, prefix, func(m *tableManifest) bool {
+		delete(m.blocks, block)
+		m.superseded[block] = now
+		if replacement != "" {
+			m.blocks[replacement] = struct{}{}
+		}
+		return true
+	}); err != nil {
+		return err
+	}
+	return b.updateBlockIndex(ctx, blockName, false)
+}
+
+// deleteBlockObjects deletes all objects of the block in the given directory
+// and returns their total size.
+func (b *DefaultObjstoreBucket) deleteBlockObjects(ctx context.Context, blockDir string) (int64, error) {
+	var names []string
+	if err := b.Bucket.Iter(ctx, blockDir, func(name string) error {
+		names = append(names, name)
+		return nil
+	}, objstore.WithRecursiveIter); err != nil {
+		return 0, err
+	}
+	size := int64(0)
+	for _, name := range names {
+		attrs, err := b.Bucket.Attributes(ctx, name)
+		if err != nil {
+			if b.IsObjNotFoundErr(err) {
+				continue
+			}
+			return size, err
+		}
+		if err := b.Bucket.Delete(ctx, name); err != nil && !b.IsObjNotFoundErr(err) {
+			return size, err
+		}
+		size += attrs.Size
+	}
+	b.forgetBlock(blockDir)
+	return size, nil
+}
@@ -0,0 +1,446 @@
+package frostdb
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/go-kit/log/level"
+	"github.com/oklog/ulid/v2"
+)
+
+// BlockIndexName is the name of the object, relative to the prefix of a
+// database, that lists the blocks of all tables of the database.
+const BlockIndexName = "blocks.index"
+
+// blockIndex is the in-memory representation of a database's block index
+// object. It maps table names to the set of block ULIDs of the table. Blocks
+// of a time partition are prefixed with the directory of the partition, e.g.
+// "timestamp=0_3600000/<ulid>".
+type blockIndex struct {
+	tables map[string]map[string]struct{}
+}
+
+func newBlockIndex() *blockIndex {
+	return &blockIndex{tables: map[string]map[string]struct{}{}}
+}
+
+// add adds the block to the index and returns whether the index changed.
+func (i *blockIndex) add(table, block string) bool {
+	blocks, ok := i.tables[table]
+	if !ok {
+		blocks = map[string]struct{}{}
+		i.tables[table] = blocks
+	}
+	if _, ok := blocks[block]; ok {
+		return false
+	}
+	blocks[block] = struct{}{}
+	return true
+}
+
+// remove removes the block from the index and returns whether the index
+// changed.
+func (i *blockIndex) remove(table, block string) bool {
+	blocks, ok := i.tables[table]
+	if !ok {
+		return false
+	}
+	if _, ok := blocks[block]; !ok {
+		return false
+	}
+	delete(blocks, block)
+	if len(blocks) == 0 {
+		delete(i.tables, table)
+	}
+	return true
+}
+
+func (i *blockIndex) tableNames() []string {
+	names := make([]string, 0, len(i.tables))
+	for name := range i.tables {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (i *blockIndex) blocks(table string) []string {
+	blocks := make([]string, 0, len(i.tables[table]))
+	for block := range i.tables[table] {
+		blocks = append(blocks, block)
+	}
+	sort.Strings(blocks)
+	return blocks
+}
+
+// MarshalText encodes the index as one "<table>/<block>" line per block.
+func (i *blockIndex) MarshalText() ([]byte, error) {
+	var buf bytes.Buffer
+	for _, table := range i.tableNames() {
+		for _, block := range i.blocks(table) {
+			buf.WriteString(table)
+			buf.WriteByte('/')
+			buf.WriteString(block)
+			buf.WriteByte('\n')
+		}
+	}
+	return buf.Bytes(), nil
+}
+
+func (i *blockIndex) UnmarshalText(data []byte) error {
+	s := bufio.NewScanner(bytes.NewReader(data))
+	for s.Scan() {
+		line := s.Text()
+		if line == "" {
+			continue
+		}
+		table, block, ok := strings.Cut(line, "/")
+		if !ok || table == "" {
+			return fmt.Errorf("invalid block index entry %q", line)
+		}
+		id := block
+		if partition, rest, ok := strings.Cut(block, "/"); ok {
+			if _, ok := parsePartitionDir(partition); !ok {
+				return fmt.Errorf("invalid block index entry %q", line)
+			}
+			id = rest
+		}
+		if _, err := ulid.Parse(id); err != nil {
+			return fmt.Errorf("invalid block index entry %q: %w", line, err)
+		}
+		i.add(table, block)
+	}
+	return s.Err()
+}
+
+// StorageWithBlockIndex enables maintaining a block index object per database
+// in the bucket. The index is updated whenever blocks are uploaded or deleted
+// through the DefaultObjstoreBucket and used instead of listing the bucket to
+// discover tables and blocks. If the index object is missing or corrupt, it is
+// rebuilt by listing the bucket. Blocks written to the bucket by other means
+// are not discovered while the index exists.
+func StorageWithBlockIndex(enabled bool) DefaultObjstoreBucketOption {
+	return func(b *DefaultObjstoreBucket) {
+		b.blockIndexEnabled = enabled
+	}
+}
+
+// Upload implements the DataSink interface. Uploaded blocks are added to the
+// block index of their database and their column statistics are written next
+// to them. If leases are enabled, the lease of the table is acquired first.
+// If manifests are enabled, blocks are committed to the manifest of their
+// table last, so that blocks are only visible once completely uploaded.
+func (b *DefaultObjstoreBucket) Upload(ctx context.Context, name string, r io.Reader) error {
+	if b.leases != nil {
+		if err := b.leases.acquire(ctx, b, name); err != nil {
+			return err
+		}
+	}
+	defer b.startCommit(name)()
+	if err := b.Bucket.Upload(ctx, name, r); err != nil {
+		return err
+	}
+	return b.commitUpload(ctx, name)
+}
+
+// NewMultipartUpload implements the MultipartUploader interface using the
+// uploader configured with StorageWithMultipartUploader. Objects uploaded this
+// way are handled like the ones uploaded with Upload: the lease of the table
+// is acquired first and blocks are committed once the upload is complete.
+func (b *DefaultObjstoreBucket) NewMultipartUpload(ctx context.Context, name string) (MultipartUpload, error) {
+	if b.multipartUploader == nil {
+		return nil, errors.New("multipart uploads are not configured")
+	}
+	if b.leases != nil {
+		if err := b.leases.acquire(ctx, b, name); err != nil {
+			return nil, err
+		}
+	}
+	done := b.startCommit(name)
+	upload, err := b.multipartUploader.NewMultipartUpload(ctx, name)
+	if err != nil {
+		done()
+		return nil, err
+	}
+	return &bucketMultipartUpload{MultipartUpload: upload, bucket: b, name: name, done: done}, nil
+}
+
+// bucketMultipartUpload is a multipart upload of a DefaultObjstoreBucket.
+type bucketMultipartUpload struct {
+	MultipartUpload
+	bucket *DefaultObjstoreBucket
+	name   string
+	done   func()
+}
+
+func (u *bucketMultipartUpload) Complete(ctx context.Context) error {
+	defer u.done()
+	if err := u.MultipartUpload.Complete(ctx); err != nil {
+		return err
+	}
+	return u.bucket.commitUpload(ctx, u.name)
+}
+
+func (u *bucketMultipartUpload) Abort(ctx context.Context) error {
+	defer u.done()
+	return u.MultipartUpload.Abort(ctx)
+}
+
+// commitUpload writes the column statistics of the uploaded object if it is a
+// block and adds it to the block index of its database and the manifest of
+// its table.
+func (b *DefaultObjstoreBucket) commitUpload(ctx context.Context, name string) error {
+	if _, _, _, ok := parseBlockName(name); ok && b.blockStatsEnabled {
+		// Statistics are only used to prune scans, so failing to write
+		// them doesn't fail the upload.
+		if err := b.writeBlockStats(ctx, name); err != nil {
+			level.Warn(b.logger).Log("msg", "failed to write block stats", "block", name, "err", err)
+		}
+	}
+	if err := b.updateBlockIndex(ctx, name, true); err != nil {
+		return err
+	}
+	return b.updateManifest(ctx, name, true)
+}
+
+// Delete implements the DataSink interface. Deleted blocks are removed from
+// the block index of their database along with their column statistics. If
+// leases are enabled, the lease of the table is acquired first. If manifests
+// are enabled, blocks are removed from the manifest of their table before
+// they are deleted.
+func (b *DefaultObjstoreBucket) Delete(ctx context.Context, name string) error {
+	if b.leases != nil {
+		if err := b.leases.acquire(ctx, b, name); err != nil {
+			return err
+		}
+	}
+	if err := b.updateManifest(ctx, name, false); err != nil {
+		return err
+	}
+	if err := b.Bucket.Delete(ctx, name); err != nil {
+		return err
+	}
+	if _, _, _, ok := parseBlockName(name); ok {
+		if err := b.deleteBlockStats(ctx, name); err != nil {
+			return err
+		}
+	}
+	return b.updateBlockIndex(ctx, name, false)
+}
+
+// parseBlockName splits the name of a block file, in the format
+// "<db>/<table>/<ulid>/data.parquet", or
+// "<db>/<table>/<partition>/<ulid>/data.parquet" for blocks of a time
+// partition, into its components. The block of a partition includes the
+// directory of the partition.
+func parseBlockName(name string) (db, table, block string, ok bool) {
+	parts := strings.Split(name, "/")
+	if len(parts) == 5 {
+		if _, ok := parsePartitionDir(parts[2]); !ok {
+			return "", "", "", false
+		}
+		parts = []string{parts[0], parts[1], parts[2] + "/" + parts[3], parts[4]}
+	}
+	if len(parts) != 4 || parts[3] != "data.parquet" {
+		return "", "", "", false
+	}
+	if _, err := ulid.Parse(filepath.Base(parts[2])); err != nil {
+		return "", "", "", false
+	}
+	return parts[0], parts[1], parts[2], true
+}
+
+func (b *DefaultObjstoreBucket) updateBlockIndex(ctx context.Context, name string, add bool) error {
+	if !b.blockIndexEnabled {
+		return nil
+	}
+	db, table, block, ok := parseBlockName(name)
+	if !ok {
+		return nil
+	}
+
+	b.blockIndexesMtx.Lock()
+	defer b.blockIndexesMtx.Unlock()
+
+	index, err := b.loadBlockIndex(ctx, db)
+	if err != nil {
+		return err
+	}
+
+	changed := false
+	if add {
+		changed = index.add(table, block)
+	} else {
+		changed = index.remove(table, block)
+	}
+	if !changed {
+		return nil
+	}
+	return b.writeBlockIndex(ctx, db, index)
+}
+
+// loadBlockIndex returns the block index of the database, reading it from
+// the bucket if it isn't loaded yet. A missing or corrupt index is rebuilt by
+// listing the bucket. blockIndexesMtx must be held.
+func (b *DefaultObjstoreBucket) loadBlockIndex(ctx context.Context, db string) (*blockIndex, error) {
+	if index, ok := b.blockIndexes[db]; ok {
+		return index, nil
+	}
+
+	index, err := b.readBlockIndex(ctx, db)
+	if err != nil {
+		level.Info(b.logger).Log("msg", "rebuilding block index", "db", db, "err", err)
+		index, err = b.rebuildBlockIndex(ctx, db)
+		if err != nil {
+			return nil, fmt.Errorf("rebuild block index: %w", err)
+		}
+		if err := b.writeBlockIndex(ctx, db, index); err != nil {
+			return nil, err
+		}
+	}
+
+	b.blockIndexes[db] = index
+	return index, nil
+}
+
+func (b *DefaultObjstoreBucket) readBlockIndex(ctx context.Context, db string) (*blockIndex, error) {
+	r, err := b.Get(ctx, filepath.Join(db, BlockIndexName))
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	index := newBlockIndex()
+	if err := index.UnmarshalText(data); err != nil {
+		return nil, err
+	}
+	return index, nil
+}
+
+func (b *DefaultObjstoreBucket) rebuildBlockIndex(ctx context.Context, db string) (*blockIndex, error) {
+	tables, err := b.listPrefixes(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+
+	index := newBlockIndex()
+	for _, table := range tables {
+		prefix := filepath.Join(db, table)
+		if err := b.listPartitionedBlocks(ctx, prefix, nil, func(blockDir string) error {
+			block, err := filepath.Rel(prefix, filepath.Clean(blockDir))
+			if err != nil {
+				return err
+			}
+			if _, err := ulid.Parse(filepath.Base(block)); err == nil {
+				index.add(table, block)
+			}
+			return nil
+		}); err != nil {
+			return nil, err
+		}
+	}
+	return index, nil
+}
+
+func (b *DefaultObjstoreBucket) writeBlockIndex(ctx context.Context, db string, index *blockIndex) error {
+	data, err := index.MarshalText()
+	if err != nil {
+		return err
+	}
+	if err := b.Bucket.Upload(ctx, filepath.Join(db, BlockIndexName), bytes.NewReader(data)); err != nil {
+		return fmt.Errorf("write block index: %w", err)
+	}
+	return nil
+}
+
+// iterBlocks calls f with the directory of each block under the prefix of a
+// table. The manifest of the table, or else the block index, is used instead
+// of listing the bucket if enabled.
+func (b *DefaultObjstoreBucket) iterBlocks(ctx context.Context, prefix string, f func(blockDir string) error) error {
+	return b.iterPartitionedBlocks(ctx, prefix, nil, f)
+}
+
+// iterPartitionedBlocks is like iterBlocks, but skips the blocks of the time
+// partitions for which keep returns false. If keep is nil, the blocks of all
+// partitions are passed to f.
+func (b *DefaultObjstoreBucket) iterPartitionedBlocks(ctx context.Context, prefix string, keep func(timePartition) bool, f func(blockDir string) error) error {
+	db, table := filepath.Split(filepath.Clean(prefix))
+	db = filepath.Clean(db)
+	if db == "." || strings.Contains(db, "/") || (!b.manifestsEnabled && !b.blockIndexEnabled) {
+		return b.listPartitionedBlocks(ctx, prefix, keep, f)
+	}
+
+	var blocks []string
+	if b.manifestsEnabled {
+		b.manifestsMtx.Lock()
+		manifest, err := b.loadManifest(ctx, filepath.Join(db, table))
+		if err == nil {
+			blocks = manifest.sortedBlocks()
+		}
+		b.manifestsMtx.Unlock()
+		if err != nil {
+			return err
+		}
+	} else {
+		b.blockIndexesMtx.Lock()
+		index, err := b.loadBlockIndex(ctx, db)
+		if err == nil {
+			blocks = index.blocks(table)
+		}
+		b.blockIndexesMtx.Unlock()
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, block := range blocks {
+		if dir, _, ok := strings.Cut(block, "/"); ok && keep != nil {
+			if p, ok := parsePartitionDir(dir); ok && !keep(p) {
+				continue
+			}
+		}
+		if err := f(filepath.Join(prefix, block)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// listPartitionedBlocks calls f with the directory of each block under the
+// prefix of a table by listing the bucket. The blocks of time partitions
+// rejected by keep are not listed.
+func (b *DefaultObjstoreBucket) listPartitionedBlocks(ctx context.Context, prefix string, keep func(timePartition) bool, f func(blockDir string) error) error {
+	var partitions []string
+	// The prefix is listed as a directory, so that objects next to the
+	// table, e.g. its lease, are not listed by buckets matching plain
+	// prefixes.
+	if err := b.Iter(ctx, strings.TrimSuffix(prefix, "/")+"/", func(name string) error {
+		if p, ok := parsePartitionDir(filepath.Base(name)); ok {
+			if keep == nil || keep(p) {
+				partitions = append(partitions, name)
+			}
+			return nil
+		}
+		return f(name)
+	}); err != nil {
+		return err
+	}
+	for _, partition := range partitions {
+		if err := b.Iter(ctx, partition, f); err != nil {
+			return err
+		}
+	}
+	return nil
+}
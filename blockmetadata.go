@@ -0,0 +1,212 @@
+package frostdb
+
+import (
+	"container/list"
+	"context"
+	"io"
+	"path/filepath"
+	"sync"
+
+	"github.com/oklog/ulid/v2"
+)
+
+// DefaultBlockMetadataCacheSize is the default number of bytes of block
+// metadata cached by a DefaultObjstoreBucket.
+const DefaultBlockMetadataCacheSize = 64 * MiB
+
+// StorageWithBlockMetadataCacheSize sets the number of bytes of block metadata
+// (footers, page indexes and bloom filters) cached in memory, so that repeated
+// scans of a block only read its data pages from the bucket. A size <= 0
+// disables caching.
+func StorageWithBlockMetadataCacheSize(size int64) DefaultObjstoreBucketOption {
+	return func(b *DefaultObjstoreBucket) {
+		b.blockMetadata.maxBytes = size
+	}
+}
+
+// blockMetadataCache caches the metadata sections of blocks by block ULID.
+// Blocks are immutable, so cached sections only need to be removed when
+// blocks are deleted.
+type blockMetadataCache struct {
+	mtx      sync.Mutex
+	maxBytes int64
+	size     int64
+	lru      *list.List
+	blocks   map[ulid.ULID]*list.Element
+}
+
+// blockMetadata are the cached metadata sections of a block.
+type blockMetadata struct {
+	id       ulid.ULID
+	sections []blockSection
+	size     int64
+}
+
+// blockSection is a byte range of a block.
+type blockSection struct {
+	offset int64
+	data   []byte
+}
+
+func newBlockMetadataCache(maxBytes int64) *blockMetadataCache {
+	return &blockMetadataCache{
+		maxBytes: maxBytes,
+		lru:      list.New(),
+		blocks:   make(map[ulid.ULID]*list.Element),
+	}
+}
+
+func (c *blockMetadataCache) enabled() bool {
+	return c.maxBytes > 0
+}
+
+// read reads p at off from the cached sections of the given block. It returns
+// false if off is not within a cached section. If p extends past the end of
+// the section, the bytes within the section are read and io.EOF is returned:
+// bloom filter headers are decoded through a buffered reader that reads past
+// the end of the bloom filter, but only needs the bytes within it.
+func (c *blockMetadataCache) read(id ulid.ULID, p []byte, off int64) (int, bool, error) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	e, ok := c.blocks[id]
+	if !ok {
+		return 0, false, nil
+	}
+	for _, s := range e.Value.(*blockMetadata).sections {
+		if off < s.offset || off >= s.offset+int64(len(s.data)) {
+			continue
+		}
+		c.lru.MoveToFront(e)
+		n := copy(p, s.data[off-s.offset:])
+		if n < len(p) {
+			return n, true, io.EOF
+		}
+		return n, true, nil
+	}
+	return 0, false, nil
+}
+
+// contains returns true if the given byte range of the block is cached.
+func (c *blockMetadataCache) contains(id ulid.ULID, off, length int64) bool {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	e, ok := c.blocks[id]
+	if !ok {
+		return false
+	}
+	for _, s := range e.Value.(*blockMetadata).sections {
+		if off >= s.offset && off+length <= s.offset+int64(len(s.data)) {
+			return true
+		}
+	}
+	return false
+}
+
+// add caches the given section of the block, evicting the least recently used
+// blocks if the cache exceeds its size budget.
+func (c *blockMetadataCache) add(id ulid.ULID, section blockSection) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	e, ok := c.blocks[id]
+	if !ok {
+		e = c.lru.PushFront(&blockMetadata{id: id})
+		c.blocks[id] = e
+	}
+	c.lru.MoveToFront(e)
+	md := e.Value.(*blockMetadata)
+	md.sections = append(md.sections, section)
+	md.size += int64(len(section.data))
+	c.size += int64(len(section.data))
+	for c.size > c.maxBytes && c.lru.Len() > 0 {
+		c.removeLocked(c.lru.Back())
+	}
+}
+
+// remove removes the cached sections of the given block.
+func (c *blockMetadataCache) remove(id ulid.ULID) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	if e, ok := c.blocks[id]; ok {
+		c.removeLocked(e)
+	}
+}
+
+func (c *blockMetadataCache) removeLocked(e *list.Element) {
+	md := e.Value.(*blockMetadata)
+	c.lru.Remove(e)
+	delete(c.blocks, md.id)
+	c.size -= md.size
+}
+
+// blockReaderAt returns a reader for the given block file. If block metadata
+// caching is enabled, the metadata sections of the block are read through
+// the cache.
+func (b *DefaultObjstoreBucket) blockReaderAt(ctx context.Context, blockName string) (io.ReaderAt, error) {
+	r, err := b.GetReaderAt(ctx, blockName)
+	if err != nil {
+		return nil, err
+	}
+	if !b.blockMetadata.enabled() {
+		return r, nil
+	}
+	id, err := ulid.Parse(filepath.Base(filepath.Dir(blockName)))
+	if err != nil {
+		return r, nil
+	}
+	return &blockMetadataReaderAt{ReaderAt: r, cache: b.blockMetadata, id: id}, nil
+}
+
+// blockMetadataReaderAt reads a block, serving the metadata sections of the
+// block from a blockMetadataCache. It implements the callbacks parquet.OpenFile
+// uses to announce the sections it is about to read, and caches each
+// announced section that is not cached yet. Like footerReaderAt, it serves the
+// magic header of the block without reading it.
+type blockMetadataReaderAt struct {
+	io.ReaderAt
+	cache *blockMetadataCache
+	id    ulid.ULID
+}
+
+func (r *blockMetadataReaderAt) ReadAt(p []byte, off int64) (int, error) {
+	if off == 0 && len(p) <= len(parquetMagic) {
+		return copy(p, parquetMagic), nil
+	}
+	if n, ok, err := r.cache.read(r.id, p, off); ok {
+		return n, err
+	}
+	return r.ReaderAt.ReadAt(p, off)
+}
+
+// setSection caches the given section of the block if it is not cached yet.
+// Errors are ignored, since the section is read right after and the read
+// reports them.
+func (r *blockMetadataReaderAt) setSection(offset, length int64) {
+	if length <= 0 || r.cache.contains(r.id, offset, length) {
+		return
+	}
+	data := make([]byte, length)
+	if n, err := r.ReaderAt.ReadAt(data, offset); n != len(data) || (err != nil && err != io.EOF) {
+		return
+	}
+	r.cache.add(r.id, blockSection{offset: offset, data: data})
+}
+
+func (r *blockMetadataReaderAt) SetMagicFooterSection(offset, length int64) {
+	r.setSection(offset, length)
+}
+
+func (r *blockMetadataReaderAt) SetFooterSection(offset, length int64) {
+	r.setSection(offset, length)
+}
+
+func (r *blockMetadataReaderAt) SetColumnIndexSection(offset, length int64) {
+	r.setSection(offset, length)
+}
+
+func (r *blockMetadataReaderAt) SetOffsetIndexSection(offset, length int64) {
+	r.setSection(offset, length)
+}
+
+func (r *blockMetadataReaderAt) SetBloomFilterSection(offset, length int64) {
+	r.setSection(offset, length)
+}
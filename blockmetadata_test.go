@@ -0,0 +1,60 @@
+package frostdb
+
+import (
+	"context"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/thanos-io/objstore"
+
+	"github.com/polarsignals/frostdb/dynparquet"
+)
+
+func TestBlockMetadataCache(t *testing.T) {
+	ctx := context.Background()
+	inner := &rangeCountingBucket{Bucket: objstore.NewInMemBucket()}
+	bucket := NewDefaultObjstoreBucket(inner)
+
+	c, err := New(WithLogger(newTestLogger(t)), WithReadWriteStorage(bucket))
+	require.NoError(t, err)
+	defer c.Close()
+	db, err := c.DB(ctx, "test")
+	require.NoError(t, err)
+	table, err := db.Table("test", NewTableConfig(dynparquet.SampleDefinition()))
+	require.NoError(t, err)
+	insertSampleRecords(ctx, t, table, 1, 2, 3)
+	block := table.ActiveBlock()
+	var wg sync.WaitGroup
+	wg.Add(1)
+	require.NoError(t, table.RotateBlock(ctx, block, WithRotateBlockWaitGroup(&wg)))
+	wg.Wait()
+
+	blockName := filepath.Join("test", "test", block.ulid.String(), "data.parquet")
+	attribs, err := bucket.Attributes(ctx, blockName)
+	require.NoError(t, err)
+
+	open := func() int64 {
+		before := inner.ranges.Load()
+		file, err := bucket.openBlockFile(ctx, blockName, attribs.Size, false)
+		require.NoError(t, err)
+		require.Equal(t, int64(3), file.NumRows())
+		return inner.ranges.Load() - before
+	}
+
+	require.NotZero(t, open())
+	// The metadata of the block is cached, so opening it again does not read
+	// from the bucket.
+	require.Zero(t, open())
+
+	// Schema reads are served from the cache as well.
+	before := inner.ranges.Load()
+	file, err := bucket.openBlockFooter(ctx, blockName)
+	require.NoError(t, err)
+	require.Equal(t, int64(3), file.NumRows())
+	require.Equal(t, before, inner.ranges.Load())
+
+	bucket.blockMetadata.remove(block.ulid)
+	require.NotZero(t, open())
+}
@@ -0,0 +1,314 @@
+package frostdb
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/go-kit/log/level"
+	"github.com/oklog/ulid/v2"
+	"github.com/parquet-go/parquet-go"
+
+	"github.com/polarsignals/frostdb/storage"
+)
+
+// DefaultBlockQuarantineDuration is the default duration a corrupt block is
+// skipped by scans before it is read again.
+const DefaultBlockQuarantineDuration = 10 * time.Minute
+
+// CorruptBlock describes a block that was quarantined because it is corrupt.
+type CorruptBlock struct {
+	// Block is the name of the data object of the block.
+	Block string
+	// Err is the error reading the block.
+	Err error
+	// Until is the time until which scans skip the block.
+	Until time.Time
+}
+
+// StorageWithCorruptBlockHandler sets a function that is called whenever a
+// block is quarantined, e.g. to alert on or to repair corrupt blocks.
+func StorageWithCorruptBlockHandler(f func(CorruptBlock)) DefaultObjstoreBucketOption {
+	return func(b *DefaultObjstoreBucket) {
+		b.quarantine.onCorrupt = f
+	}
+}
+
+// StorageWithBlockQuarantineDuration sets the duration a corrupt block is
+// skipped by scans. Once it passed, the block is fetched from the bucket
+// again, so that blocks that were repaired in the meantime, e.g. by
+// uploading an intact copy, are read again. The default is
+// DefaultBlockQuarantineDuration.
+func StorageWithBlockQuarantineDuration(d time.Duration) DefaultObjstoreBucketOption {
+	return func(b *DefaultObjstoreBucket) {
+		b.quarantine.duration = d
+	}
+}
+
+// StorageWithBlockVerification verifies the checksums and encodings of all
+// pages of a block the first time it is scanned, so that corrupt pages are
+// detected before their row groups are handed to queries. Without
+// verification, only blocks whose footer is corrupt are detected. Verifying
+// a block reads all of its pages, so it should only be enabled if the
+// bucket is read through a local cache, see WithLocalBlockCache.
+func StorageWithBlockVerification() DefaultObjstoreBucketOption {
+	return func(b *DefaultObjstoreBucket) {
+		b.quarantine.verify = true
+	}
+}
+
+// QuarantinedBlocks returns the names of the data objects of the blocks that
+// are currently skipped by scans because they are corrupt.
+func (b *DefaultObjstoreBucket) QuarantinedBlocks() []string {
+	return b.quarantine.list(b.clock.Now())
+}
+
+// blockQuarantine tracks the blocks of a bucket that are corrupt. A block is
+// considered corrupt if it cannot be decoded although all of its bytes were
+// read from the bucket without error, so that blocks are not quarantined
+// because the bucket is unavailable.
+type blockQuarantine struct {
+	duration  time.Duration
+	verify    bool
+	onCorrupt func(CorruptBlock)
+
+	mtx sync.Mutex
+	// blocks maps the quarantined blocks to the time until which they are
+	// quarantined.
+	blocks map[string]time.Time
+	// verified are the blocks whose pages were verified. Blocks are
+	// immutable, so they only need to be verified once.
+	verified map[string]struct{}
+}
+
+func newBlockQuarantine() *blockQuarantine {
+	return &blockQuarantine{
+		duration: DefaultBlockQuarantineDuration,
+		blocks:   make(map[string]time.Time),
+		verified: make(map[string]struct{}),
+	}
+}
+
+// quarantined returns whether the given block is quarantined at now. Blocks
+// whose quarantine passed are released.
+func (q *blockQuarantine) quarantined(blockName string, now time.Time) bool {
+	q.mtx.Lock()
+	defer q.mtx.Unlock()
+	until, ok := q.blocks[blockName]
+	if !ok {
+		return false
+	}
+	if now.Before(until) {
+		return true
+	}
+	delete(q.blocks, blockName)
+	return false
+}
+
+func (q *blockQuarantine) add(blockName string, err error, now time.Time) CorruptBlock {
+	q.mtx.Lock()
+	defer q.mtx.Unlock()
+	block := CorruptBlock{Block: blockName, Err: err, Until: now.Add(q.duration)}
+	q.blocks[blockName] = block.Until
+	return block
+}
+
+func (q *blockQuarantine) list(now time.Time) []string {
+	q.mtx.Lock()
+	defer q.mtx.Unlock()
+	blocks := make([]string, 0, len(q.blocks))
+	for block, until := range q.blocks {
+		if now.Before(until) {
+			blocks = append(blocks, block)
+		}
+	}
+	sort.Strings(blocks)
+	return blocks
+}
+
+// needsVerification returns whether the pages of the given block need to be
+// verified.
+func (q *blockQuarantine) needsVerification(blockName string) bool {
+	if !q.verify {
+		return false
+	}
+	q.mtx.Lock()
+	defer q.mtx.Unlock()
+	_, ok := q.verified[blockName]
+	return !ok
+}
+
+func (q *blockQuarantine) setVerified(blockName string) {
+	q.mtx.Lock()
+	defer q.mtx.Unlock()
+	q.verified[blockName] = struct{}{}
+}
+
+// forget removes the given block after it was deleted or replaced.
+func (q *blockQuarantine) forget(blockName string) {
+	q.mtx.Lock()
+	defer q.mtx.Unlock()
+	delete(q.blocks, blockName)
+	delete(q.verified, blockName)
+}
+
+// corruptBlockError is returned when a block that was read without error
+// cannot be decoded.
+type corruptBlockError struct {
+	block string
+	err   error
+}
+
+func (e *corruptBlockError) Error() string {
+	return fmt.Sprintf("corrupt block %s: %v", e.block, e.err)
+}
+
+func (e *corruptBlockError) Unwrap() error {
+	return e.err
+}
+
+// readErrorReaderAt records whether reading from the wrapped reader failed.
+// Reads outside of the block, e.g. at the offset of a corrupt footer length,
+// fail without reaching the wrapped reader and are not recorded, so that the
+// block is reported as corrupt. The sections announced by parquet.OpenFile are
+// passed on to the wrapped reader, so that its metadata is cached.
+type readErrorReaderAt struct {
+	io.ReaderAt
+	size int64
+
+	mtx sync.Mutex
+	err error
+}
+
+func newReadErrorReaderAt(r io.ReaderAt, size int64) *readErrorReaderAt {
+	return &readErrorReaderAt{ReaderAt: r, size: size}
+}
+
+func (r *readErrorReaderAt) inBounds(off, length int64) bool {
+	return off >= 0 && length >= 0 && off+length <= r.size
+}
+
+func (r *readErrorReaderAt) ReadAt(p []byte, off int64) (int, error) {
+	if off < 0 || off > r.size {
+		return 0, fmt.Errorf("read at offset %d outside of block of %d bytes", off, r.size)
+	}
+	n, err := r.ReaderAt.ReadAt(p, off)
+	if err != nil && !errors.Is(err, io.EOF) {
+		r.mtx.Lock()
+		r.err = err
+		r.mtx.Unlock()
+	}
+	return n, err
+}
+
+func (r *readErrorReaderAt) setSection(offset, length int64) {
+	if md, ok := r.ReaderAt.(*blockMetadataReaderAt); ok && r.inBounds(offset, length) {
+		md.setSection(offset, length)
+	}
+}
+
+func (r *readErrorReaderAt) SetMagicFooterSection(offset, length int64) {
+	r.setSection(offset, length)
+}
+
+func (r *readErrorReaderAt) SetFooterSection(offset, length int64) {
+	r.setSection(offset, length)
+}
+
+func (r *readErrorReaderAt) SetColumnIndexSection(offset, length int64) {
+	r.setSection(offset, length)
+}
+
+func (r *readErrorReaderAt) SetOffsetIndexSection(offset, length int64) {
+	r.setSection(offset, length)
+}
+
+func (r *readErrorReaderAt) SetBloomFilterSection(offset, length int64) {
+	r.setSection(offset, length)
+}
+
+func (r *readErrorReaderAt) readError() error {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	return r.err
+}
+
+// blockError returns the error of decoding the given block read using r as
+// a corruptBlockError if reading the block did not fail.
+func blockError(blockName string, r *readErrorReaderAt, err error) error {
+	if r.readError() != nil {
+		return fmt.Errorf("failed to open block: %s :%v", blockName, err)
+	}
+	return &corruptBlockError{block: blockName, err: err}
+}
+
+// verifyPages reads and decodes all pages of file, which is the given block
+// read using r.
+func verifyPages(blockName string, r *readErrorReaderAt, file *parquet.File) error {
+	for _, rg := range file.RowGroups() {
+		for _, chunk := range rg.ColumnChunks() {
+			if err := verifyChunk(chunk); err != nil {
+				return blockError(blockName, r, err)
+			}
+		}
+	}
+	return nil
+}
+
+func verifyChunk(chunk parquet.ColumnChunk) error {
+	pages := chunk.Pages()
+	defer pages.Close()
+	for {
+		page, err := pages.ReadPage()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		parquet.Release(page)
+	}
+}
+
+// openOrQuarantine opens the given block using open. If the block is
+// corrupt, the cached sections of the block are removed and the block is
+// opened again, so that a block that is only corrupt in a cache is fetched
+// from the bucket again. If it is still corrupt, it is quarantined and nil
+// is returned, so that scans skip it.
+func (b *DefaultObjstoreBucket) openOrQuarantine(blockName string, open func() (*parquet.File, error)) (*parquet.File, error) {
+	file, err := open()
+	var corrupt *corruptBlockError
+	if !errors.As(err, &corrupt) {
+		return file, err
+	}
+	level.Warn(b.logger).Log("msg", "block is corrupt, fetching it again", "block", blockName, "err", err)
+	b.invalidateBlock(blockName)
+
+	file, err = open()
+	if !errors.As(err, &corrupt) {
+		return file, err
+	}
+	block := b.quarantine.add(blockName, corrupt.err, b.clock.Now())
+	level.Error(b.logger).Log("msg", "quarantined corrupt block", "block", blockName, "until", block.Until, "err", corrupt.err)
+	if b.quarantine.onCorrupt != nil {
+		b.quarantine.onCorrupt(block)
+	}
+	return nil, nil
+}
+
+// invalidateBlock removes the sections of the given block from the metadata
+// cache and the local block cache.
+func (b *DefaultObjstoreBucket) invalidateBlock(blockName string) {
+	if id, err := ulid.Parse(filepath.Base(filepath.Dir(blockName))); err == nil {
+		b.blockMetadata.remove(id)
+	}
+	if cached, ok := b.Bucket.(*storage.CachedBucket); ok {
+		if err := cached.Cache().Invalidate(blockName); err != nil {
+			level.Warn(b.logger).Log("msg", "failed to invalidate cached block", "block", blockName, "err", err)
+		}
+	}
+}
@@ -0,0 +1,159 @@
+package frostdb
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/apache/arrow/go/v17/arrow"
+	"github.com/oklog/ulid/v2"
+
+	"github.com/polarsignals/frostdb/dynparquet"
+	"github.com/polarsignals/frostdb/pqarrow"
+	"github.com/polarsignals/frostdb/query/logicalplan"
+)
+
+// maxBlockSchemaVariants is the maximum number of arrow schemas cached per
+// block, one for each set of iterator options the schema was resolved with.
+const maxBlockSchemaVariants = 16
+
+// blockSchemaCache caches the arrow schemas resolved for persisted blocks,
+// which include the union of the concrete dynamic columns of the blocks, so
+// that repeated queries of wide tables don't resolve them again for every row
+// group. Blocks are immutable and identified by their ULID, but they may be
+// rewritten or deleted, so the schemas of a table are invalidated when its
+// blocks change.
+type blockSchemaCache struct {
+	mtx    sync.Mutex
+	size   int
+	blocks map[blockSchemaKey]map[string]*arrow.Schema
+}
+
+type blockSchemaKey struct {
+	table string
+	block ulid.ULID
+}
+
+func newBlockSchemaCache(size int) *blockSchemaCache {
+	return &blockSchemaCache{
+		size:   size,
+		blocks: map[blockSchemaKey]map[string]*arrow.Schema{},
+	}
+}
+
+func (c *blockSchemaCache) get(table string, block ulid.ULID, options string) (*arrow.Schema, bool) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	schema, ok := c.blocks[blockSchemaKey{table: table, block: block}][options]
+	return schema, ok
+}
+
+func (c *blockSchemaCache) add(table string, block ulid.ULID, options string, schema *arrow.Schema) {
+	if c.size <= 0 {
+		return
+	}
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	key := blockSchemaKey{table: table, block: block}
+	schemas, ok := c.blocks[key]
+	if !ok {
+		if len(c.blocks) >= c.size {
+			// Evict an arbitrary block.
+			for k := range c.blocks {
+				delete(c.blocks, k)
+				break
+			}
+		}
+		schemas = map[string]*arrow.Schema{}
+		c.blocks[key] = schemas
+	}
+	if len(schemas) >= maxBlockSchemaVariants {
+		for k := range schemas {
+			delete(schemas, k)
+			break
+		}
+	}
+	schemas[options] = schema
+}
+
+// invalidateBlock removes the schemas of the given block of a table.
+func (c *blockSchemaCache) invalidateBlock(table string, block ulid.ULID) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	delete(c.blocks, blockSchemaKey{table: table, block: block})
+}
+
+// invalidate removes the schemas of all blocks of a table.
+func (c *blockSchemaCache) invalidate(table string) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	for k := range c.blocks {
+		if k.table == table {
+			delete(c.blocks, k)
+		}
+	}
+}
+
+// len returns the number of blocks with cached schemas.
+func (c *blockSchemaCache) len() int {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	return len(c.blocks)
+}
+
+// blockSchemaOptionsKey returns the cache key of the iterator options that
+// the arrow schema of a row group depends on.
+func blockSchemaOptionsKey(options logicalplan.IterOptions) string {
+	var b strings.Builder
+	for _, e := range options.PhysicalProjection {
+		fmt.Fprintf(&b, "%T(%s),", e, e.Name())
+	}
+	b.WriteByte('|')
+	for _, e := range options.DistinctColumns {
+		fmt.Fprintf(&b, "%T(%s),", e, e.Name())
+	}
+	fmt.Fprintf(&b, "|%t|%t", options.Filter == nil, options.DictionaryEncode)
+	return b.String()
+}
+
+// blockRowGroup is a row group of a persisted block that resolves its arrow
+// schema using the block schema cache of its database.
+type blockRowGroup struct {
+	dynparquet.DynamicRowGroup
+	table *Table
+	block ulid.ULID
+}
+
+var _ pqarrow.ArrowSchemaRowGroup = (*blockRowGroup)(nil)
+
+func (g *blockRowGroup) ArrowSchema(ctx context.Context, s *dynparquet.Schema, options logicalplan.IterOptions) (*arrow.Schema, error) {
+	cache := g.table.db.blockSchemas
+	key := blockSchemaOptionsKey(options)
+	if schema, ok := cache.get(g.table.name, g.block, key); ok {
+		return schema, nil
+	}
+	schema, err := pqarrow.ParquetSchemaToArrowSchema(ctx, g.Schema(), s, options)
+	if err != nil {
+		return nil, err
+	}
+	cache.add(g.table.name, g.block, key, schema)
+	return schema, nil
+}
+
+// withBlockSchema wraps the row groups of the given persisted block so that
+// their arrow schemas are cached.
+func (t *Table) withBlockSchema(block ulid.ULID, v any) any {
+	if block == (ulid.ULID{}) {
+		return v
+	}
+	rg, ok := v.(dynparquet.DynamicRowGroup)
+	if !ok {
+		return v
+	}
+	if _, ok := v.(interface{ Release() }); ok {
+		// Row groups that need to be released are not read from blocks.
+		return v
+	}
+	return &blockRowGroup{DynamicRowGroup: rg, table: t, block: block}
+}
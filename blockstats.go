@@ -0,0 +1,474 @@
+package frostdb
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+
+	"github.com/cespare/xxhash/v2"
+	"github.com/go-kit/log/level"
+	"github.com/parquet-go/parquet-go"
+	"github.com/parquet-go/parquet-go/bloom"
+
+	"github.com/polarsignals/frostdb/query/expr"
+)
+
+// BlockStatsName is the name of the object, relative to the directory of a
+// block, that contains the column statistics of the block.
+const BlockStatsName = "stats.json"
+
+// DefaultBlockStatsCacheSize is the default number of block statistics cached
+// by a DefaultObjstoreBucket.
+const DefaultBlockStatsCacheSize = 4096
+
+// blockStats are the statistics of all columns of a block. They are written
+// next to the block when it is uploaded, so that scans can skip blocks that
+// cannot match a filter without opening them.
+type blockStats struct {
+	Columns []blockColumnStats `json:"columns"`
+}
+
+// blockColumnStats are the statistics of a column across all row groups of a
+// block. Min and Max are encoded like parquet statistics and are nil if the
+// bounds of the column are unknown or the column only contains nulls.
+type blockColumnStats struct {
+	Name      string       `json:"name"`
+	Kind      parquet.Kind `json:"kind"`
+	Length    int          `json:"length,omitempty"`
+	Unsigned  bool         `json:"unsigned,omitempty"`
+	Min       []byte       `json:"min,omitempty"`
+	Max       []byte       `json:"max,omitempty"`
+	NullCount int64        `json:"null_count"`
+	NumValues int64        `json:"num_values"`
+	// Bloom is a split block bloom filter of the values of the column. It is
+	// only set for the first sorting column of a block.
+	Bloom []byte `json:"bloom,omitempty"`
+}
+
+// maxBlockBloomFilterValues is the maximum number of distinct values of the
+// first sorting column of a block for which a bloom filter is written, which
+// limits the size of the filter to about 80KiB.
+const maxBlockBloomFilterValues = 1 << 16
+
+const blockBloomFilterBitsPerValue = 10
+
+// StorageWithBlockStats configures whether column statistics, including a
+// bloom filter of the first sorting column, are written next to blocks when
+// they are uploaded. Statistics are enabled by default.
+// Existing statistics are used to prune blocks regardless of this option.
+func StorageWithBlockStats(enabled bool) DefaultObjstoreBucketOption {
+	return func(b *DefaultObjstoreBucket) {
+		b.blockStatsEnabled = enabled
+	}
+}
+
+// StorageWithBlockStatsCacheSize sets the number of block statistics cached
+// for scans. A size <= 0 disables caching.
+func StorageWithBlockStatsCacheSize(size int) DefaultObjstoreBucketOption {
+	return func(b *DefaultObjstoreBucket) {
+		b.blockStatsCacheSize = size
+	}
+}
+
+// newBlockStats aggregates the column chunk statistics of the row groups of
+// file. It returns false if the schema of the file is not flat, since filters
+// only address top-level columns.
+func newBlockStats(file *parquet.File) (*blockStats, bool) {
+	fields := file.Schema().Fields()
+	stats := &blockStats{Columns: make([]blockColumnStats, len(fields))}
+	types := make([]parquet.Type, len(fields))
+	// knownBounds is unset if a row group has non-null values but no bounds.
+	knownBounds := make([]bool, len(fields))
+	for i, field := range fields {
+		if !field.Leaf() {
+			return nil, false
+		}
+		t := field.Type()
+		types[i] = t
+		knownBounds[i] = true
+		stats.Columns[i] = blockColumnStats{
+			Name:   field.Name(),
+			Kind:   t.Kind(),
+			Length: t.Length(),
+		}
+		if lt := t.LogicalType(); lt != nil && lt.Integer != nil {
+			stats.Columns[i].Unsigned = !lt.Integer.IsSigned
+		}
+	}
+
+	for _, rg := range file.Metadata().RowGroups {
+		if len(rg.Columns) != len(fields) {
+			return nil, false
+		}
+		for i, chunk := range rg.Columns {
+			c := &stats.Columns[i]
+			md := chunk.MetaData
+			c.NumValues += md.NumValues
+			c.NullCount += md.Statistics.NullCount
+			if md.NumValues == md.Statistics.NullCount {
+				continue
+			}
+			if md.Statistics.MinValue == nil || md.Statistics.MaxValue == nil {
+				knownBounds[i] = false
+				continue
+			}
+
+			minValue := c.Kind.Value(md.Statistics.MinValue)
+			if c.Min == nil || types[i].Compare(minValue, c.Kind.Value(c.Min)) < 0 {
+				c.Min = minValue.Bytes()
+			}
+			maxValue := c.Kind.Value(md.Statistics.MaxValue)
+			if c.Max == nil || types[i].Compare(maxValue, c.Kind.Value(c.Max)) > 0 {
+				c.Max = maxValue.Bytes()
+			}
+		}
+	}
+
+	for i := range stats.Columns {
+		if !knownBounds[i] {
+			stats.Columns[i].Min, stats.Columns[i].Max = nil, nil
+		}
+	}
+	return stats, true
+}
+
+// node returns the parquet node of the column. It returns false if the type
+// of the column is not supported.
+func (c *blockColumnStats) node() (parquet.Node, bool) {
+	switch c.Kind {
+	case parquet.Boolean:
+		return parquet.Leaf(parquet.BooleanType), true
+	case parquet.Int32:
+		if c.Unsigned {
+			return parquet.Uint(32), true
+		}
+		return parquet.Int(32), true
+	case parquet.Int64:
+		if c.Unsigned {
+			return parquet.Uint(64), true
+		}
+		return parquet.Int(64), true
+	case parquet.Float:
+		return parquet.Leaf(parquet.FloatType), true
+	case parquet.Double:
+		return parquet.Leaf(parquet.DoubleType), true
+	case parquet.ByteArray:
+		return parquet.Leaf(parquet.ByteArrayType), true
+	case parquet.FixedLenByteArray:
+		return parquet.Leaf(parquet.FixedLenByteArrayType(c.Length)), true
+	default:
+		return nil, false
+	}
+}
+
+// particulate returns the statistics as an expr.Particulate with a single
+// column chunk per column. It returns false if the type of a column is not
+// supported.
+func (s *blockStats) particulate() (expr.Particulate, bool) {
+	g := parquet.Group{}
+	columns := make(map[string]*blockColumnStats, len(s.Columns))
+	for i := range s.Columns {
+		c := &s.Columns[i]
+		node, ok := c.node()
+		if !ok {
+			return nil, false
+		}
+		g[c.Name] = node
+		columns[c.Name] = c
+	}
+
+	schema := parquet.NewSchema("block-stats", g)
+	chunks := make([]parquet.ColumnChunk, 0, len(s.Columns))
+	for i, field := range schema.Fields() {
+		chunks = append(chunks, &blockStatsColumnChunk{
+			stats:  columns[field.Name()],
+			typ:    field.Type(),
+			column: i,
+		})
+	}
+	return &blockStatsParticulate{schema: schema, columnChunks: chunks}, true
+}
+
+type blockStatsParticulate struct {
+	schema       *parquet.Schema
+	columnChunks []parquet.ColumnChunk
+}
+
+func (p *blockStatsParticulate) Schema() *parquet.Schema { return p.schema }
+
+func (p *blockStatsParticulate) ColumnChunks() []parquet.ColumnChunk { return p.columnChunks }
+
+// blockStatsColumnChunk is a column chunk that only provides the statistics
+// of a column of a block.
+type blockStatsColumnChunk struct {
+	stats  *blockColumnStats
+	typ    parquet.Type
+	column int
+}
+
+func (c *blockStatsColumnChunk) Type() parquet.Type   { return c.typ }
+func (c *blockStatsColumnChunk) Column() int          { return c.column }
+func (c *blockStatsColumnChunk) Pages() parquet.Pages { return nil }
+func (c *blockStatsColumnChunk) ColumnIndex() (parquet.ColumnIndex, error) {
+	return blockStatsColumnIndex{c}, nil
+}
+func (c *blockStatsColumnChunk) OffsetIndex() (parquet.OffsetIndex, error) { return nil, nil }
+func (c *blockStatsColumnChunk) NumValues() int64                          { return c.stats.NumValues }
+
+func (c *blockStatsColumnChunk) BloomFilter() parquet.BloomFilter {
+	if c.stats.Bloom == nil {
+		return nil
+	}
+	return blockBloomFilter(c.stats.Bloom)
+}
+
+// blockStatsColumnIndex is a column index with a single page spanning the
+// whole block.
+type blockStatsColumnIndex struct {
+	c *blockStatsColumnChunk
+}
+
+func (i blockStatsColumnIndex) NumPages() int       { return 1 }
+func (i blockStatsColumnIndex) NullCount(int) int64 { return i.c.stats.NullCount }
+func (i blockStatsColumnIndex) NullPage(int) bool {
+	return i.c.stats.NullCount == i.c.stats.NumValues
+}
+
+func (i blockStatsColumnIndex) MinValue(int) parquet.Value {
+	if i.c.stats.Min == nil {
+		return parquet.Value{}
+	}
+	return i.c.stats.Kind.Value(i.c.stats.Min)
+}
+
+func (i blockStatsColumnIndex) MaxValue(int) parquet.Value {
+	if i.c.stats.Max == nil {
+		return parquet.Value{}
+	}
+	return i.c.stats.Kind.Value(i.c.stats.Max)
+}
+
+func (i blockStatsColumnIndex) IsAscending() bool  { return false }
+func (i blockStatsColumnIndex) IsDescending() bool { return false }
+
+// blockBloomFilter is the bloom filter of a column of a block.
+type blockBloomFilter []byte
+
+func (f blockBloomFilter) ReadAt(p []byte, off int64) (int, error) {
+	if off >= int64(len(f)) {
+		return 0, io.EOF
+	}
+	n := copy(p, f[off:])
+	if n < len(p) {
+		return n, io.EOF
+	}
+	return n, nil
+}
+
+func (f blockBloomFilter) Size() int64 { return int64(len(f)) }
+
+func (f blockBloomFilter) Check(v parquet.Value) (bool, error) {
+	return bloom.MakeSplitBlockFilter(f).Check(xxhash.Sum64(v.Bytes())), nil
+}
+
+// addSortingKeyBloomFilter adds a bloom filter of the values of the first
+// sorting column of file to its statistics, so that blocks can be skipped for
+// equality filters on the column even if its values are spread across the
+// bounds of many blocks. No filter is added if the column has too many
+// distinct values.
+func addSortingKeyBloomFilter(file *parquet.File, stats *blockStats) error {
+	rowGroups := file.RowGroups()
+	if len(rowGroups) == 0 {
+		return nil
+	}
+	sortingColumns := rowGroups[0].SortingColumns()
+	if len(sortingColumns) == 0 {
+		return nil
+	}
+	leaf, ok := file.Schema().Lookup(sortingColumns[0].Path()...)
+	if !ok || leaf.Node.Type().Kind() == parquet.Boolean {
+		return nil
+	}
+
+	hashes := make(map[uint64]struct{})
+	var values []parquet.Value
+	readValues := func(pages parquet.Pages) error {
+		defer pages.Close()
+		for len(hashes) <= maxBlockBloomFilterValues {
+			p, err := pages.ReadPage()
+			if err != nil {
+				if err == io.EOF {
+					return nil
+				}
+				return fmt.Errorf("read page: %w", err)
+			}
+			if dict := p.Dictionary(); dict != nil {
+				// Only the distinct values are needed.
+				p = dict.Page()
+			}
+
+			n := p.NumValues()
+			if int64(cap(values)) < n {
+				values = make([]parquet.Value, n)
+			}
+			values = values[:n]
+			if _, err := p.Values().ReadValues(values); err != nil && err != io.EOF {
+				return fmt.Errorf("read values: %w", err)
+			}
+			for _, v := range values {
+				if !v.IsNull() {
+					hashes[xxhash.Sum64(v.Bytes())] = struct{}{}
+				}
+			}
+		}
+		return nil
+	}
+	for _, rg := range rowGroups {
+		if err := readValues(rg.ColumnChunks()[leaf.ColumnIndex].Pages()); err != nil {
+			return err
+		}
+		if len(hashes) > maxBlockBloomFilterValues {
+			return nil
+		}
+	}
+
+	numBlocks := bloom.NumSplitBlocksOf(int64(len(hashes)), blockBloomFilterBitsPerValue)
+	filter := bloom.MakeSplitBlockFilter(make([]byte, numBlocks*bloom.BlockSize))
+	for hash := range hashes {
+		filter.Insert(hash)
+	}
+	for i := range stats.Columns {
+		if stats.Columns[i].Name == leaf.Path[0] {
+			stats.Columns[i].Bloom = filter.Bytes()
+		}
+	}
+	return nil
+}
+
+// writeBlockStats writes the statistics of the uploaded block file name next
+// to it. Only the footer of the block is read.
+func (b *DefaultObjstoreBucket) writeBlockStats(ctx context.Context, name string) error {
+	file, err := b.openBlockFooter(ctx, name)
+	if err != nil {
+		return err
+	}
+	if file == nil {
+		return nil
+	}
+	stats, ok := newBlockStats(file)
+	if !ok {
+		return nil
+	}
+	if err := addSortingKeyBloomFilter(file, stats); err != nil {
+		// The statistics are still useful without the bloom filter.
+		level.Warn(b.logger).Log("msg", "failed to build block bloom filter", "block", name, "err", err)
+	}
+
+	data, err := json.Marshal(stats)
+	if err != nil {
+		return err
+	}
+	blockDir := filepath.Dir(name)
+	if err := b.Bucket.Upload(ctx, filepath.Join(blockDir, BlockStatsName), bytes.NewReader(data)); err != nil {
+		return fmt.Errorf("write block stats: %w", err)
+	}
+	b.cacheBlockStats(blockDir, stats)
+	return nil
+}
+
+// deleteBlockStats deletes the statistics of the deleted block file name.
+func (b *DefaultObjstoreBucket) deleteBlockStats(ctx context.Context, name string) error {
+	blockDir := filepath.Dir(name)
+	b.blockStatsMtx.Lock()
+	delete(b.blockStats, blockDir)
+	b.blockStatsMtx.Unlock()
+
+	if err := b.Bucket.Delete(ctx, filepath.Join(blockDir, BlockStatsName)); err != nil && !b.IsObjNotFoundErr(err) {
+		return fmt.Errorf("delete block stats: %w", err)
+	}
+	return nil
+}
+
+// loadBlockStats returns the statistics of the block in blockDir, or nil if
+// the block has none, e.g. because it was written before statistics were
+// introduced.
+func (b *DefaultObjstoreBucket) loadBlockStats(ctx context.Context, blockDir string) (*blockStats, error) {
+	b.blockStatsMtx.Lock()
+	stats, ok := b.blockStats[blockDir]
+	b.blockStatsMtx.Unlock()
+	if ok {
+		return stats, nil
+	}
+
+	stats, err := b.readBlockStats(ctx, blockDir)
+	if err != nil {
+		return nil, err
+	}
+	b.cacheBlockStats(blockDir, stats)
+	return stats, nil
+}
+
+func (b *DefaultObjstoreBucket) readBlockStats(ctx context.Context, blockDir string) (*blockStats, error) {
+	r, err := b.Get(ctx, filepath.Join(blockDir, BlockStatsName))
+	if err != nil {
+		if b.IsObjNotFoundErr(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer r.Close()
+
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	stats := &blockStats{}
+	if err := json.Unmarshal(data, stats); err != nil {
+		// Statistics are only used for pruning, so the block is read
+		// as if it had none.
+		level.Warn(b.logger).Log("msg", "ignoring invalid block stats", "block", blockDir, "err", err)
+		return nil, nil
+	}
+	return stats, nil
+}
+
+func (b *DefaultObjstoreBucket) cacheBlockStats(blockDir string, stats *blockStats) {
+	b.blockStatsMtx.Lock()
+	defer b.blockStatsMtx.Unlock()
+	if b.blockStatsCacheSize <= 0 {
+		return
+	}
+	if len(b.blockStats) >= b.blockStatsCacheSize {
+		// Evict an arbitrary block.
+		for name := range b.blockStats {
+			delete(b.blockStats, name)
+			break
+		}
+	}
+	b.blockStats[blockDir] = stats
+}
+
+// blockMayContainUsefulData returns false if the statistics of the block in
+// blockDir show that none of its rows match filter.
+func (b *DefaultObjstoreBucket) blockMayContainUsefulData(ctx context.Context, blockDir string, filter expr.TrueNegativeFilter) (bool, error) {
+	if _, ok := filter.(*expr.AlwaysTrueFilter); ok {
+		return true, nil
+	}
+
+	stats, err := b.loadBlockStats(ctx, blockDir)
+	if err != nil {
+		return true, err
+	}
+	if stats == nil {
+		return true, nil
+	}
+	p, ok := stats.particulate()
+	if !ok {
+		return true, nil
+	}
+	return filter.Eval(p, false)
+}
@@ -0,0 +1,126 @@
+package frostdb
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"sync"
+
+	"github.com/oklog/ulid/v2"
+	"google.golang.org/protobuf/proto"
+)
+
+// TableInfo describes a table of a database, see DB.Tables.
+type TableInfo struct {
+	// Name is the name of the table.
+	Name string
+	// Open is whether the table is open in the database, i.e. it was created
+	// or recovered since the column store was created. Tables that are not
+	// open only exist in storage.
+	Open bool
+	// Schema is the definition of the table's schema, either a v1alpha1 or a
+	// v1alpha2 schema. For tables that are not open, it is the schema the
+	// newest persisted block was written with, or nil if it is unknown.
+	Schema proto.Message
+	// Blocks is the number of persisted blocks of the table. Only data
+	// sources implementing BlockSchemaReader are counted.
+	Blocks int
+}
+
+// DBs returns the sorted names of the databases of the column store, both
+// the open ones and the ones that only exist in the data sources.
+func (s *ColumnStore) DBs(ctx context.Context) ([]string, error) {
+	names := map[string]struct{}{}
+	for _, name := range s.openDBs() {
+		names[name] = struct{}{}
+	}
+	for _, source := range s.sources {
+		prefixes, err := source.Prefixes(ctx, "")
+		if err != nil {
+			return nil, fmt.Errorf("list databases of %s: %w", source, err)
+		}
+		for _, name := range prefixes {
+			if validateName(name) {
+				names[name] = struct{}{}
+			}
+		}
+	}
+	return sortedNames(names), nil
+}
+
+// Tables returns the tables of the database sorted by name, both the open
+// ones and the ones that only exist in the data sources. The persisted blocks
+// of all tables are listed, and the footer of each block is read, so it
+// should not be called on the hot path.
+func (db *DB) Tables() ([]TableInfo, error) {
+	ctx := context.Background()
+	names := map[string]struct{}{}
+	for _, name := range db.TableNames() {
+		names[name] = struct{}{}
+	}
+	for _, source := range db.sources {
+		prefixes, err := source.Prefixes(ctx, db.name)
+		if err != nil {
+			return nil, fmt.Errorf("list tables of %s: %w", source, err)
+		}
+		for _, name := range prefixes {
+			if validateName(name) {
+				names[name] = struct{}{}
+			}
+		}
+	}
+
+	tables := make([]TableInfo, 0, len(names))
+	for _, name := range sortedNames(names) {
+		info, err := db.tableInfo(ctx, name)
+		if err != nil {
+			return nil, err
+		}
+		tables = append(tables, info)
+	}
+	return tables, nil
+}
+
+func (db *DB) tableInfo(ctx context.Context, name string) (TableInfo, error) {
+	info := TableInfo{Name: name}
+	if table, err := db.GetTable(name); err == nil {
+		info.Open = true
+		if schema := table.schema.Load(); schema != nil {
+			info.Schema = schema.Definition()
+		}
+	}
+
+	var (
+		mtx    sync.Mutex
+		newest ulid.ULID
+	)
+	for _, source := range db.sources {
+		reader, ok := source.(BlockSchemaReader)
+		if !ok {
+			continue
+		}
+		if err := reader.BlockSchemas(ctx, filepath.Join(db.name, name), func(_ context.Context, block ulid.ULID, _ string, def proto.Message) error {
+			mtx.Lock()
+			defer mtx.Unlock()
+			info.Blocks++
+			if !info.Open && def != nil && block.Compare(newest) > 0 {
+				newest = block
+				info.Schema = def
+			}
+			return nil
+		}); err != nil {
+			return TableInfo{}, fmt.Errorf("read blocks of table %s from %s: %w", name, source, err)
+		}
+	}
+	return info, nil
+}
+
+func sortedNames(names map[string]struct{}) []string {
+	sorted := make([]string, 0, len(names))
+	for name := range names {
+		sorted = append(sorted, name)
+	}
+	sort.Strings(sorted)
+	return sorted
+}
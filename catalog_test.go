@@ -0,0 +1,71 @@
+package frostdb
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/thanos-io/objstore"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/polarsignals/frostdb/dynparquet"
+)
+
+func TestCatalog(t *testing.T) {
+	ctx := context.Background()
+	bucket := NewDefaultObjstoreBucket(objstore.NewInMemBucket())
+	def := dynparquet.SampleDefinition()
+
+	c, err := New(WithLogger(newTestLogger(t)), WithReadWriteStorage(bucket))
+	require.NoError(t, err)
+	db, err := c.DB(ctx, "test")
+	require.NoError(t, err)
+	persisted, err := db.Table("persisted", NewTableConfig(def))
+	require.NoError(t, err)
+	for ts := int64(1); ts <= 2; ts++ {
+		insertSampleRecords(ctx, t, persisted, ts)
+		var wg sync.WaitGroup
+		wg.Add(1)
+		require.NoError(t, persisted.RotateBlock(ctx, persisted.ActiveBlock(), WithRotateBlockWaitGroup(&wg)))
+		wg.Wait()
+	}
+	inMemory, err := db.Table("in_memory", NewTableConfig(def))
+	require.NoError(t, err)
+	insertSampleRecords(ctx, t, inMemory, 1)
+
+	dbs, err := c.DBs(ctx)
+	require.NoError(t, err)
+	require.Equal(t, []string{"test"}, dbs)
+	tables, err := db.Tables()
+	require.NoError(t, err)
+	require.Len(t, tables, 2)
+	require.Equal(t, "in_memory", tables[0].Name)
+	require.True(t, tables[0].Open)
+	require.Zero(t, tables[0].Blocks)
+	require.True(t, proto.Equal(def, tables[0].Schema))
+	require.Equal(t, "persisted", tables[1].Name)
+	require.True(t, tables[1].Open)
+	require.Equal(t, 2, tables[1].Blocks)
+	require.NoError(t, c.Close())
+
+	// Databases and tables that only exist in the bucket are listed with the
+	// schema of their blocks.
+	c, err = New(WithLogger(newTestLogger(t)), WithReadWriteStorage(bucket))
+	require.NoError(t, err)
+	defer c.Close()
+	dbs, err = c.DBs(ctx)
+	require.NoError(t, err)
+	require.Equal(t, []string{"test"}, dbs)
+	db, err = c.DB(ctx, "test")
+	require.NoError(t, err)
+	tables, err = db.Tables()
+	require.NoError(t, err)
+	require.Len(t, tables, 2)
+	for _, table := range tables {
+		require.False(t, table.Open)
+		require.True(t, proto.Equal(def, table.Schema))
+	}
+	require.Equal(t, 1, tables[0].Blocks)
+	require.Equal(t, 2, tables[1].Blocks)
+}
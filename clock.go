@@ -0,0 +1,146 @@
+package frostdb
+
+import (
+	"sync"
+	"time"
+)
+
+// Clock provides the current time to time-based features such as retention,
+// block IDs assigned on rotation and periodic background work. It can be
+// replaced with WithClock, e.g. to test these features deterministically or
+// to simulate long time ranges. Durations that are only measured for metrics
+// and logs always use the system clock.
+type Clock interface {
+	Now() time.Time
+	// NewTicker returns a Ticker delivering ticks every d.
+	NewTicker(d time.Duration) Ticker
+}
+
+// Ticker delivers ticks at intervals, see time.Ticker.
+type Ticker interface {
+	C() <-chan time.Time
+	Stop()
+}
+
+// WithClock sets the clock used by time-based features, including the ones of
+// the DefaultObjstoreBucket sources and sinks of the column store such as
+// table leases. The default is the system clock.
+func WithClock(clock Clock) Option {
+	return func(s *ColumnStore) error {
+		s.clock = clock
+		return nil
+	}
+}
+
+// shareClock makes the DefaultObjstoreBucket sources and sinks of the column
+// store use its clock.
+func (s *ColumnStore) shareClock() {
+	for _, source := range s.sources {
+		if b, ok := source.(*DefaultObjstoreBucket); ok {
+			b.clock = s.clock
+		}
+	}
+	for _, sink := range s.sinks {
+		if b, ok := sink.(*DefaultObjstoreBucket); ok {
+			b.clock = s.clock
+		}
+	}
+}
+
+// systemClock is the Clock backed by the time package.
+type systemClock struct{}
+
+func (systemClock) Now() time.Time { return time.Now() }
+
+func (systemClock) NewTicker(d time.Duration) Ticker {
+	return systemTicker{time.NewTicker(d)}
+}
+
+type systemTicker struct {
+	*time.Ticker
+}
+
+func (t systemTicker) C() <-chan time.Time { return t.Ticker.C }
+
+// ManualClock is a Clock that only advances when told to. Tickers fire when
+// the clock is advanced past their next tick.
+type ManualClock struct {
+	mtx     sync.Mutex
+	now     time.Time
+	tickers map[*manualTicker]struct{}
+}
+
+// NewManualClock returns a ManualClock set to now.
+func NewManualClock(now time.Time) *ManualClock {
+	return &ManualClock{
+		now:     now,
+		tickers: make(map[*manualTicker]struct{}),
+	}
+}
+
+func (c *ManualClock) Now() time.Time {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	return c.now
+}
+
+// Set sets the clock to now. Tickers are not fired if the clock is set back.
+func (c *ManualClock) Set(now time.Time) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	c.now = now
+	for t := range c.tickers {
+		t.advance(now)
+	}
+}
+
+// Advance advances the clock by d.
+func (c *ManualClock) Advance(d time.Duration) {
+	c.Set(c.Now().Add(d))
+}
+
+func (c *ManualClock) NewTicker(d time.Duration) Ticker {
+	if d <= 0 {
+		panic("non-positive interval for ManualClock.NewTicker")
+	}
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	t := &manualTicker{
+		clock:    c,
+		c:        make(chan time.Time, 1),
+		interval: d,
+		next:     c.now.Add(d),
+	}
+	c.tickers[t] = struct{}{}
+	return t
+}
+
+type manualTicker struct {
+	clock    *ManualClock
+	c        chan time.Time
+	interval time.Duration
+	next     time.Time
+}
+
+func (t *manualTicker) C() <-chan time.Time { return t.c }
+
+func (t *manualTicker) Stop() {
+	t.clock.mtx.Lock()
+	defer t.clock.mtx.Unlock()
+	delete(t.clock.tickers, t)
+}
+
+// advance fires the ticker if now is past its next tick. Like time.Ticker,
+// ticks are dropped if the receiver falls behind. t.clock.mtx must be held.
+func (t *manualTicker) advance(now time.Time) {
+	if now.Before(t.next) {
+		return
+	}
+	select {
+	case t.c <- now:
+	default:
+	}
+	for !now.Before(t.next) {
+		t.next = t.next.Add(t.interval)
+	}
+}
@@ -0,0 +1,70 @@
+package frostdb
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/polarsignals/frostdb/dynparquet"
+	"github.com/polarsignals/frostdb/index"
+)
+
+func TestManualClock(t *testing.T) {
+	start := time.UnixMilli(0)
+	clock := NewManualClock(start)
+	ticker := clock.NewTicker(time.Minute)
+	defer ticker.Stop()
+
+	clock.Advance(30 * time.Second)
+	require.Equal(t, start.Add(30*time.Second), clock.Now())
+	select {
+	case <-ticker.C():
+		t.Fatal("unexpected tick")
+	default:
+	}
+
+	// Ticks are dropped if the receiver falls behind.
+	clock.Advance(5 * time.Minute)
+	require.Equal(t, start.Add(330*time.Second), <-ticker.C())
+	select {
+	case <-ticker.C():
+		t.Fatal("unexpected tick")
+	default:
+	}
+
+	clock.Advance(30 * time.Second)
+	require.Equal(t, start.Add(6*time.Minute), <-ticker.C())
+}
+
+func TestClock(t *testing.T) {
+	ctx := context.Background()
+	clock := NewManualClock(time.UnixMilli(0).Add(24 * time.Hour))
+	c, db, table := openTestTable(t, []Option{WithClock(clock)}, WithRetention(time.Hour))
+	defer c.Close()
+	require.Equal(t, uint64(clock.Now().UnixMilli()), table.ActiveBlock().ulid.Time())
+
+	// Rotated blocks get later IDs even if the clock did not advance.
+	block := table.ActiveBlock()
+	require.NoError(t, table.RotateBlock(ctx, block, WithRotateBlockSkipPersist()))
+	require.Equal(t, block.ulid.Time()+1, table.ActiveBlock().ulid.Time())
+
+	insertSamples(t, table, dynparquet.Samples{{
+		ExampleType: "cpu",
+		Labels:      map[string]string{"label1": "value1"},
+		Timestamp:   clock.Now().UnixMilli(),
+		Value:       1,
+	}})
+
+	// Parts and background tasks are timestamped with the clock.
+	stats := table.ActiveBlock().Index().Stats()
+	require.Equal(t, index.L0, stats[0].Level)
+	require.Equal(t, clock.Now(), stats[0].Oldest)
+	require.Equal(t, clock.Now(), c.scheduler.now())
+
+	// Retention is enforced relative to the clock.
+	require.Equal(t, int64(1), countRows(t, db, "test"))
+	clock.Advance(2 * time.Hour)
+	require.Equal(t, int64(0), countRows(t, db, "test"))
+}
@@ -12,6 +12,7 @@ import (
 	"path/filepath"
 
 	"github.com/apache/arrow/go/v17/arrow/ipc"
+	"github.com/klauspost/compress/zstd"
 	"github.com/spf13/cobra"
 
 	"github.com/polarsignals/frostdb/dynparquet"
@@ -21,8 +22,10 @@ import (
 var (
 	snapshotMagic = "FDBS"
 
-	snapshotVersion = 1
-	minReadVersion  = snapshotVersion
+	snapshotVersion = 2
+	minReadVersion  = 1
+	// compressedPartsVersion is the first version with zstd compressed parts.
+	compressedPartsVersion = 2
 )
 
 var snapshotCmd = &cobra.Command{
@@ -60,10 +63,15 @@ func inspectSnapshot(path string, size int64, columns []string) error {
 	}
 	defer f.Close()
 
-	footer, err := readFooter(f, size)
+	footer, version, err := readFooter(f, size)
 	if err != nil {
 		return err
 	}
+	dec, err := zstd.NewReader(nil)
+	if err != nil {
+		return err
+	}
+	defer dec.Close()
 
 	for _, tableMeta := range footer.TableMetadata {
 		for _, granuleMeta := range tableMeta.GranuleMetadata {
@@ -74,6 +82,12 @@ func inspectSnapshot(path string, size int64, columns []string) error {
 				if _, err := f.ReadAt(partBytes, startOffset); err != nil {
 					return err
 				}
+				if int(version) >= compressedPartsVersion {
+					partBytes, err = dec.DecodeAll(partBytes, nil)
+					if err != nil {
+						return err
+					}
+				}
 				switch partMeta.Encoding {
 				case snapshotpb.Part_ENCODING_PARQUET:
 					_, err := dynparquet.ReaderFromBytes(partBytes) // TODO: do something with the serialized buffer
@@ -104,19 +118,19 @@ func inspectSnapshot(path string, size int64, columns []string) error {
 }
 
 // Copied from FrostDB directly
-func readFooter(r io.ReaderAt, size int64) (*snapshotpb.FooterData, error) {
+func readFooter(r io.ReaderAt, size int64) (*snapshotpb.FooterData, uint32, error) {
 	buffer := make([]byte, 16)
 	if _, err := r.ReadAt(buffer[:4], 0); err != nil {
-		return nil, err
+		return nil, 0, err
 	}
 	if string(buffer[:4]) != snapshotMagic {
-		return nil, fmt.Errorf("invalid snapshot magic: %q", buffer[:4])
+		return nil, 0, fmt.Errorf("invalid snapshot magic: %q", buffer[:4])
 	}
 	if _, err := r.ReadAt(buffer, size-int64(len(buffer))); err != nil {
-		return nil, err
+		return nil, 0, err
 	}
 	if string(buffer[12:]) != snapshotMagic {
-		return nil, fmt.Errorf("invalid snapshot magic: %q", buffer[4:])
+		return nil, 0, fmt.Errorf("invalid snapshot magic: %q", buffer[4:])
 	}
 
 	// The checksum does not include the last 8 bytes of the file, which is the
@@ -125,22 +139,22 @@ func readFooter(r io.ReaderAt, size int64) (*snapshotpb.FooterData, error) {
 	checksum := binary.LittleEndian.Uint32(buffer[8:12])
 	checksumWriter := newChecksumWriter()
 	if _, err := io.Copy(checksumWriter, io.NewSectionReader(r, 0, size-8)); err != nil {
-		return nil, fmt.Errorf("failed to compute checksum: %w", err)
+		return nil, 0, fmt.Errorf("failed to compute checksum: %w", err)
 	}
 	if checksum != checksumWriter.Sum32() {
-		return nil, fmt.Errorf(
+		return nil, 0, fmt.Errorf(
 			"snapshot file corrupt: invalid checksum: expected %x, got %x", checksum, checksumWriter.Sum32(),
 		)
 	}
 
 	version := binary.LittleEndian.Uint32(buffer[4:8])
 	if int(version) > snapshotVersion {
-		return nil, fmt.Errorf(
+		return nil, 0, fmt.Errorf(
 			"cannot read snapshot with version %d: max version supported: %d", version, snapshotVersion,
 		)
 	}
 	if int(version) < minReadVersion {
-		return nil, fmt.Errorf(
+		return nil, 0, fmt.Errorf(
 			"cannot read snapshot with version %d: min version supported: %d", version, minReadVersion,
 		)
 	}
@@ -148,13 +162,13 @@ func readFooter(r io.ReaderAt, size int64) (*snapshotpb.FooterData, error) {
 	footerSize := binary.LittleEndian.Uint32(buffer[:4])
 	footerBytes := make([]byte, footerSize)
 	if _, err := r.ReadAt(footerBytes, size-(int64(len(buffer))+int64(footerSize))); err != nil {
-		return nil, err
+		return nil, 0, err
 	}
 	footer := &snapshotpb.FooterData{}
 	if err := footer.UnmarshalVT(footerBytes); err != nil {
-		return nil, fmt.Errorf("could not unmarshal footer: %v", err)
+		return nil, 0, fmt.Errorf("could not unmarshal footer: %v", err)
 	}
-	return footer, nil
+	return footer, version, nil
 }
 
 func newChecksumWriter() hash.Hash32 {
@@ -0,0 +1,60 @@
+package frostdb
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/apache/arrow/go/v17/arrow"
+	"google.golang.org/protobuf/proto"
+
+	tablepb "github.com/polarsignals/frostdb/gen/proto/go/frostdb/table/v1alpha1"
+	"github.com/polarsignals/frostdb/query/logicalplan"
+)
+
+// Config returns a copy of the config of the table.
+func (t *Table) Config() *tablepb.TableConfig {
+	return proto.Clone(t.config.Load()).(*tablepb.TableConfig)
+}
+
+// ExportTable calls fn with the records of the table as of a snapshot taken
+// when it is called, and returns the transaction of the snapshot. Writes
+// committed after the snapshot are not exported. Together with the table's
+// Config, the records can be written into a table of another database, e.g.
+// with CopyTable or the Write API of a remote server. Records are released
+// after fn returns.
+func (db *DB) ExportTable(ctx context.Context, name string, fn func(ctx context.Context, r arrow.Record) error) (uint64, error) {
+	table, err := db.GetTable(name)
+	if err != nil {
+		return 0, err
+	}
+
+	tx := db.beginRead()
+	if err := table.Iterator(ctx, tx, db.columnStore.allocator, []logicalplan.Callback{fn}); err != nil {
+		return 0, fmt.Errorf("export table %s: %w", name, err)
+	}
+	return tx, nil
+}
+
+// CopyTable copies the table with the given name as of a snapshot into a table
+// with the same name and config in the target database, which is created if it
+// doesn't exist. The schema, sorting columns and sort orders of the table are
+// preserved. It returns the transaction of the snapshot, so that writes to the
+// source table after it can be replayed, e.g. when re-sharding tenants.
+func (db *DB) CopyTable(ctx context.Context, name string, target *DB) (uint64, error) {
+	if target == db {
+		return 0, fmt.Errorf("cannot copy table %s into its own database", name)
+	}
+	table, err := db.GetTable(name)
+	if err != nil {
+		return 0, err
+	}
+	targetTable, err := target.Table(name, table.Config())
+	if err != nil {
+		return 0, fmt.Errorf("create table %s in database %s: %w", name, target.name, err)
+	}
+
+	return db.ExportTable(ctx, name, func(ctx context.Context, r arrow.Record) error {
+		_, err := targetTable.InsertRecord(ctx, r)
+		return err
+	})
+}
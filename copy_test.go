@@ -0,0 +1,74 @@
+package frostdb
+
+import (
+	"context"
+	"testing"
+
+	"github.com/apache/arrow/go/v17/arrow"
+	"github.com/apache/arrow/go/v17/arrow/memory"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/polarsignals/frostdb/dynparquet"
+	"github.com/polarsignals/frostdb/query"
+)
+
+func TestCopyTable(t *testing.T) {
+	ctx := context.Background()
+	c, err := New(WithLogger(newTestLogger(t)))
+	require.NoError(t, err)
+	defer c.Close()
+
+	src, err := c.DB(ctx, "src")
+	require.NoError(t, err)
+	dst, err := c.DB(ctx, "dst")
+	require.NoError(t, err)
+
+	table, err := src.Table("test", NewTableConfig(dynparquet.SampleDefinition()))
+	require.NoError(t, err)
+	insert := func(samples dynparquet.Samples) {
+		r, err := samples.ToRecord()
+		require.NoError(t, err)
+		defer r.Release()
+		_, err = table.InsertRecord(ctx, r)
+		require.NoError(t, err)
+	}
+	insert(dynparquet.Samples{
+		{ExampleType: "cpu", Labels: map[string]string{"label1": "b"}, Timestamp: 2, Value: 2},
+		{ExampleType: "cpu", Labels: map[string]string{"label1": "a", "label2": "c"}, Timestamp: 1, Value: 1},
+	})
+	insert(dynparquet.Samples{
+		{ExampleType: "cpu", Labels: map[string]string{"label1": "c"}, Timestamp: 3, Value: 3},
+	})
+
+	tx, err := src.CopyTable(ctx, "test", dst)
+	require.NoError(t, err)
+	require.Positive(t, tx)
+
+	// Writes after the snapshot are not copied.
+	insert(dynparquet.Samples{
+		{ExampleType: "cpu", Labels: map[string]string{"label1": "d"}, Timestamp: 4, Value: 4},
+	})
+
+	copied, err := dst.GetTable("test")
+	require.NoError(t, err)
+	require.True(t, proto.Equal(table.Config(), copied.Config()))
+
+	values := map[string]int64{}
+	require.NoError(t, query.NewEngine(memory.DefaultAllocator, dst.TableProvider()).
+		ScanTable("test").
+		Execute(ctx, func(_ context.Context, r arrow.Record) error {
+			label := r.Column(r.Schema().FieldIndices("labels.label1")[0])
+			value := r.Column(r.Schema().FieldIndices("value")[0])
+			for i := 0; i < int(r.NumRows()); i++ {
+				values[label.ValueStr(i)], _ = value.GetOneForMarshal(i).(int64)
+			}
+			return nil
+		}))
+	require.Equal(t, map[string]int64{"a": 1, "b": 2, "c": 3}, values)
+
+	_, err = src.CopyTable(ctx, "test", src)
+	require.Error(t, err)
+	_, err = src.CopyTable(ctx, "unknown", dst)
+	require.Error(t, err)
+}
@@ -10,12 +10,14 @@ import (
 	"os"
 	"path/filepath"
 	"runtime"
+	"slices"
 	"strings"
 	"sync"
 	"sync/atomic"
 	"time"
 
 	"github.com/apache/arrow/go/v17/arrow/ipc"
+	"github.com/apache/arrow/go/v17/arrow/memory"
 	"github.com/apache/arrow/go/v17/arrow/util"
 	"github.com/go-kit/log"
 	"github.com/go-kit/log/level"
@@ -34,7 +36,9 @@ import (
 	walpb "github.com/polarsignals/frostdb/gen/proto/go/frostdb/wal/v1alpha1"
 	"github.com/polarsignals/frostdb/index"
 	"github.com/polarsignals/frostdb/parts"
+	"github.com/polarsignals/frostdb/pqarrow"
 	"github.com/polarsignals/frostdb/query/logicalplan"
+	"github.com/polarsignals/frostdb/storage"
 	"github.com/polarsignals/frostdb/wal"
 )
 
@@ -46,6 +50,10 @@ const (
 	TiB = 1024 * GiB
 )
 
+// DefaultMaxInsertChunkSize is the default maximum size of the chunks an
+// inserted record is split into.
+const DefaultMaxInsertChunkSize = 64 * MiB
+
 type ColumnStore struct {
 	mtx                 sync.RWMutex
 	dbs                 map[string]*DB
@@ -58,22 +66,109 @@ type ColumnStore struct {
 	enableWAL           bool
 	manualBlockRotation bool
 	snapshotTriggerSize int64
+	tailBufferSize      int64
 	metrics             globalMetrics
 	recoveryConcurrency int
 
+	// conversionConcurrency is the maximum number of columns of a row group
+	// that are converted to arrow in parallel when scanning tables.
+	conversionConcurrency int
+
+	// decodeWorkers is the number of workers of decodePool, which decodes the
+	// columns of row groups scanned by all queries. A value <= 0 decodes
+	// columns on the scanning goroutines.
+	decodeWorkers int
+	decodePool    *pqarrow.DecodePool
+
+	// allocator is used for allocations that tables make outside of queries,
+	// e.g. when removing deleted rows from blocks.
+	allocator memory.Allocator
+
+	// walOptions are passed to the WAL of each database.
+	walOptions []wal.Option
+
+	// uploadPartSize and uploadConcurrency configure multipart uploads of
+	// blocks to sinks that implement MultipartUploader.
+	uploadPartSize    int
+	uploadConcurrency int
+
 	// indexDegree is the degree of the btree index (default = 2)
 	indexDegree int
 	// splitSize is the number of new granules that are created when granules are split (default =2)
 	splitSize int
 	// indexConfig is the configuration settings for the lsm index
 	indexConfig []*index.LevelConfig
+	// compactionPolicy decides when and how the levels of the lsm index are
+	// compacted (default = index.LeveledCompactionPolicy)
+	compactionPolicy index.CompactionPolicy
+
+	// walShipper, if set, ships the WAL entries of all databases to a
+	// standby.
+	walShipper *walShipper
 
 	sources []DataSource
 	sinks   []DataSink
+	// readOnly is set if the column store only has read-only storage, see
+	// WithReadOnlyStorage.
+	readOnly bool
+
+	// blockCache, if set, caches the blocks read from DefaultObjstoreBucket
+	// sources on local disk.
+	blockCacheDir      string
+	blockCacheMaxBytes int64
+	blockCache         *storage.LocalCache
 
 	compactAfterRecovery           bool
 	compactAfterRecoveryTableNames []string
 
+	// dynamicColumnLimit is the maximum number of concrete dynamic columns a
+	// table may contain. A value <= 0 disables the limit.
+	dynamicColumnLimit         int
+	dynamicColumnLimitBehavior dynparquet.DynamicColumnLimitBehavior
+
+	// maxInsertChunkSize is the maximum size of the chunks inserted records
+	// are split into. A value <= 0 disables chunking.
+	maxInsertChunkSize int64
+
+	// rowReducers are the reducers tables can merge rows with, by name, see
+	// WithMergeReducer.
+	rowReducers map[string]dynparquet.RowReducer
+
+	// backgroundSnapshotVerification skips the synchronous checksum
+	// validation of snapshots on load and verifies them in the background
+	// instead.
+	backgroundSnapshotVerification bool
+
+	// metricsReporter, if set, is called with all internal metrics every
+	// metricsReportInterval.
+	metricsReporter       MetricsReporter
+	metricsReportInterval time.Duration
+	metricsRegistry       *prometheus.Registry
+	stopMetricsReporter   chan struct{}
+	metricsReporterDone   chan struct{}
+
+	lifecycleHooks LifecycleHooks
+
+	// retentionCheckInterval is the interval at which retention is enforced
+	// for tables with a retention window.
+	retentionCheckInterval time.Duration
+	// blockGCInterval is the interval at which the garbage of the
+	// persisted blocks of tables is collected, see WithBlockGC.
+	blockGCInterval    time.Duration
+	blockGCGracePeriod time.Duration
+
+	// clock provides the current time to time-based features.
+	clock Clock
+
+	// scheduler runs background work such as block persistence, compactions
+	// and snapshots.
+	scheduler *scheduler
+
+	// queryConcurrency is the maximum number of queries executing at the
+	// same time, enforced by queryGate. A value <= 0 disables the limit.
+	queryConcurrency int
+	queryGate        *queryGate
+
 	// testingOptions are options only used for testing purposes.
 	testingOptions struct {
 		disableReclaimDiskSpaceOnSnapshot bool
@@ -87,15 +182,23 @@ func New(
 	options ...Option,
 ) (*ColumnStore, error) {
 	s := &ColumnStore{
-		dbs:                 make(map[string]*DB),
-		dbReplaysInProgress: make(map[string]chan struct{}),
-		reg:                 prometheus.NewRegistry(),
-		logger:              log.NewNopLogger(),
-		tracer:              noop.NewTracerProvider().Tracer(""),
-		indexConfig:         DefaultIndexConfig(),
-		indexDegree:         2,
-		splitSize:           2,
-		activeMemorySize:    512 * MiB,
+		dbs:                    make(map[string]*DB),
+		dbReplaysInProgress:    make(map[string]chan struct{}),
+		reg:                    prometheus.NewRegistry(),
+		logger:                 log.NewNopLogger(),
+		tracer:                 noop.NewTracerProvider().Tracer(""),
+		indexConfig:            DefaultIndexConfig(),
+		indexDegree:            2,
+		splitSize:              2,
+		activeMemorySize:       512 * MiB,
+		retentionCheckInterval: DefaultRetentionCheckInterval,
+		blockGCGracePeriod:     DefaultBlockGCGracePeriod,
+		clock:                  systemClock{},
+		uploadPartSize:         DefaultUploadPartSize,
+		uploadConcurrency:      DefaultUploadConcurrency,
+		scheduler:              newScheduler(),
+		allocator:              memory.DefaultAllocator,
+		maxInsertChunkSize:     DefaultMaxInsertChunkSize,
 	}
 
 	for _, option := range options {
@@ -103,14 +206,36 @@ func New(
 			return nil, err
 		}
 	}
+	s.scheduler.now = s.clock.Now
+	s.shareClock()
+
+	if s.metricsReporter != nil {
+		// Internal metrics are also registered with a private registry, so
+		// that the reporter can gather them regardless of the registerer
+		// provided by the embedder, and without reporting the embedder's
+		// metrics.
+		s.metricsRegistry = prometheus.NewRegistry()
+		s.reg = multiRegisterer{s.reg, s.metricsRegistry}
+	}
 
 	// Register metrics that are updated by the collector.
 	s.reg.MustRegister(&collector{s: s})
 	s.metrics = makeAndRegisterGlobalMetrics(s.reg)
+	s.queryGate = newQueryGate(s.queryConcurrency, s.metrics.queryMetrics)
 
 	if s.enableWAL && s.storagePath == "" {
 		return nil, fmt.Errorf("storage path must be configured if WAL is enabled")
 	}
+	s.readOnly = len(s.sources) > 0 && len(s.sinks) == 0 && !s.enableWAL
+	if s.readOnly && s.walShipper != nil {
+		return nil, fmt.Errorf("WAL shipping cannot be enabled for read-only storage")
+	}
+
+	if s.blockCacheDir != "" {
+		if err := s.enableBlockCache(); err != nil {
+			return nil, err
+		}
+	}
 
 	for _, cfg := range s.indexConfig {
 		if cfg.Type == index.CompactionTypeParquetDisk {
@@ -120,9 +245,30 @@ func New(
 		}
 	}
 
+	if s.decodeWorkers > 0 {
+		s.decodePool = pqarrow.NewDecodePool(s.decodeWorkers)
+	}
+	s.startMetricsReporter()
+
 	if err := s.recoverDBsFromStorage(context.Background()); err != nil {
+		s.stopReportingMetrics()
+		if s.decodePool != nil {
+			s.decodePool.Close()
+		}
 		return nil, err
 	}
+	if s.readOnly {
+		if err := s.openReadOnlyDBs(context.Background()); err != nil {
+			s.stopReportingMetrics()
+			if s.decodePool != nil {
+				s.decodePool.Close()
+			}
+			return nil, err
+		}
+	}
+	if s.walShipper != nil {
+		s.walShipper.start(generateULID(s.clock).String(), s.logger)
+	}
 
 	return s, nil
 }
@@ -177,6 +323,12 @@ func WithReadWriteStorage(ds DataSinkSource) Option {
 	}
 }
 
+// WithReadOnlyStorage adds a data source that is queried but never written
+// to. If the column store has no other storage and the WAL is not enabled,
+// the column store is read-only: all databases and tables found in the data
+// sources are opened for querying when the column store is created, tables
+// have no active blocks, and writes are rejected with ErrReadOnly. This
+// allows running stateless query nodes over shared object storage.
 func WithReadOnlyStorage(ds DataSource) Option {
 	return func(s *ColumnStore) error {
 		s.sources = append(s.sources, ds)
@@ -205,6 +357,52 @@ func WithWAL() Option {
 	}
 }
 
+// WithWALQueueLimit limits the memory used by WAL records that have not been
+// written to disk yet to maxBytes per database. When the limit is exceeded,
+// inserts either block until the WAL caught up or fail with wal.ErrQueueFull,
+// depending on behavior.
+func WithWALQueueLimit(maxBytes int64, behavior wal.QueueFullBehavior) Option {
+	return func(s *ColumnStore) error {
+		if maxBytes <= 0 {
+			return fmt.Errorf("WAL queue limit must be positive, got %d", maxBytes)
+		}
+		s.walOptions = append(s.walOptions, wal.WithQueueLimit(maxBytes, behavior))
+		return nil
+	}
+}
+
+// WithWALFailFast makes inserts fail with an error wrapping
+// wal.ErrWriteFailed once the WAL of a database failed to write to disk, e.g.
+// because the disk became read-only.
+func WithWALFailFast() Option {
+	return func(s *ColumnStore) error {
+		s.walOptions = append(s.walOptions, wal.WithFailFast())
+		return nil
+	}
+}
+
+// WithWALCompression compresses the records written to the WAL of each
+// database with the given codec.
+func WithWALCompression(c wal.Compression) Option {
+	return func(s *ColumnStore) error {
+		s.walOptions = append(s.walOptions, wal.WithCompression(c))
+		return nil
+	}
+}
+
+// WithWALBatching configures the WAL of each database to write queued records
+// to disk every interval, or as soon as they exceed maxBytes if maxBytes is
+// positive. See wal.WithBatching.
+func WithWALBatching(interval time.Duration, maxBytes int64) Option {
+	return func(s *ColumnStore) error {
+		if interval <= 0 {
+			return fmt.Errorf("WAL batch interval must be positive, got %s", interval)
+		}
+		s.walOptions = append(s.walOptions, wal.WithBatching(interval, maxBytes))
+		return nil
+	}
+}
+
 func WithStoragePath(path string) Option {
 	return func(s *ColumnStore) error {
 		s.storagePath = path
@@ -219,6 +417,17 @@ func WithIndexConfig(indexConfig []*index.LevelConfig) Option {
 	}
 }
 
+// WithCompactionPolicy sets the policy deciding when the levels of the
+// index of each table block are compacted, which parts are merged, and into
+// how many parts of the next level, see index.LeveledCompactionPolicy and
+// index.TimeCompactionPolicy.
+func WithCompactionPolicy(policy index.CompactionPolicy) Option {
+	return func(s *ColumnStore) error {
+		s.compactionPolicy = policy
+		return nil
+	}
+}
+
 func WithCompactionAfterRecovery(tableNames []string) Option {
 	return func(s *ColumnStore) error {
 		s.compactAfterRecovery = true
@@ -248,6 +457,18 @@ func WithSnapshotTriggerSize(size int64) Option {
 	}
 }
 
+// WithTailBufferSize specifies a size in bytes of the most recently committed
+// records that each table buffers, so that Table.TailIterator can deliver
+// records committed before it was called. The default is 0, in which case
+// tailing can only resume from the latest transaction that committed records
+// to a table.
+func WithTailBufferSize(size int64) Option {
+	return func(s *ColumnStore) error {
+		s.tailBufferSize = size
+		return nil
+	}
+}
+
 // WithRecoveryConcurrency limits the number of databases that are recovered
 // simultaneously when calling frostdb.New. This helps limit memory usage on
 // recovery.
@@ -258,9 +479,214 @@ func WithRecoveryConcurrency(concurrency int) Option {
 	}
 }
 
+// WithConversionConcurrency converts up to concurrency columns of each row
+// group in parallel when scanning tables, instead of converting them one after
+// the other. Row groups are already converted in parallel by the concurrent
+// callbacks of a table scan, so this mostly helps scans of few, large row
+// groups or scans with low query concurrency.
+func WithConversionConcurrency(concurrency int) Option {
+	return func(s *ColumnStore) error {
+		if concurrency < 0 {
+			return fmt.Errorf("conversion concurrency must not be negative: %d", concurrency)
+		}
+		s.conversionConcurrency = concurrency
+		return nil
+	}
+}
+
+// WithDecodeWorkers decompresses and decodes the columns of the row groups
+// scanned by all queries of the column store on a shared pool of the given
+// number of workers, instead of on the goroutines scanning the row groups.
+// This is the CPU budget of decoding: heavy scans queue for the workers
+// instead of occupying all cores, which keeps the tail latency of other
+// queries and of ingestion low. Columns are decoded in the order their row
+// groups are scanned. WithConversionConcurrency has no effect if the pool is
+// enabled. A value of 0, the default, disables the pool.
+func WithDecodeWorkers(workers int) Option {
+	return func(s *ColumnStore) error {
+		if workers < 0 {
+			return fmt.Errorf("decode workers must not be negative: %d", workers)
+		}
+		s.decodeWorkers = workers
+		return nil
+	}
+}
+
+// WithAllocator sets the allocator tables use for allocations outside of
+// queries, e.g. when removing deleted rows from blocks. Queries allocate from
+// the allocator passed to the query engine. Defaults to
+// memory.DefaultAllocator.
+func WithAllocator(pool memory.Allocator) Option {
+	return func(s *ColumnStore) error {
+		s.allocator = pool
+		return nil
+	}
+}
+
+// WithMultipartUpload configures how blocks are uploaded to data sinks that
+// implement MultipartUploader, such as a DefaultObjstoreBucket configured with
+// StorageWithMultipartUploader. Blocks are split into parts of partSize bytes
+// and up to concurrency parts are uploaded in parallel while the rest of the
+// block is still being serialized. At most concurrency+1 parts are buffered in
+// memory at a time.
+func WithMultipartUpload(partSize, concurrency int) Option {
+	return func(s *ColumnStore) error {
+		if partSize <= 0 {
+			return fmt.Errorf("upload part size must be positive: %d", partSize)
+		}
+		if concurrency <= 0 {
+			return fmt.Errorf("upload concurrency must be positive: %d", concurrency)
+		}
+		s.uploadPartSize = partSize
+		s.uploadConcurrency = concurrency
+		return nil
+	}
+}
+
+// WithLocalBlockCache caches the byte ranges of blocks read from
+// DefaultObjstoreBucket storage in dir, using at most maxBytes of disk space.
+// Least recently used ranges are evicted first. Cached ranges survive
+// restarts, so repeated queries over persisted data do not download the same
+// blocks from the bucket again.
+func WithLocalBlockCache(dir string, maxBytes int64) Option {
+	return func(s *ColumnStore) error {
+		if maxBytes <= 0 {
+			return fmt.Errorf("block cache size must be positive: %d", maxBytes)
+		}
+		s.blockCacheDir = dir
+		s.blockCacheMaxBytes = maxBytes
+		return nil
+	}
+}
+
+// enableBlockCache creates the local block cache and reads the blocks of all
+// DefaultObjstoreBucket sources and sinks through it.
+func (s *ColumnStore) enableBlockCache() error {
+	cache, err := storage.NewLocalCache(s.blockCacheDir, s.blockCacheMaxBytes)
+	if err != nil {
+		return fmt.Errorf("open block cache: %w", err)
+	}
+	s.blockCache = cache
+
+	wrap := func(v any) {
+		b, ok := v.(*DefaultObjstoreBucket)
+		if !ok {
+			return
+		}
+		if _, ok := b.Bucket.(*storage.CachedBucket); ok {
+			// The bucket is both a source and a sink.
+			return
+		}
+		b.Bucket = storage.NewCachedBucket(b.Bucket, cache)
+	}
+	for _, source := range s.sources {
+		wrap(source)
+	}
+	for _, sink := range s.sinks {
+		wrap(sink)
+	}
+	return nil
+}
+
+// WithDynamicColumnLimit limits the number of concrete dynamic columns (e.g.
+// "labels.foo") a table may contain. Extremely wide dynamic schemas can exceed
+// practical parquet limits when merging data, so writes adding columns past
+// the limit are either rejected with a dynparquet.ErrTooManyDynamicColumns
+// error or have the excess columns folded into a map-encoded overflow column
+// (e.g. "labels.__overflow", see dynparquet.DecodeOverflow), depending on the
+// given behavior. Folded columns are not queryable by their own name. The
+// columns of a table are counted from all of its data, including persisted
+// blocks, the first time a write is limited after the table was opened.
+func WithDynamicColumnLimit(limit int, behavior dynparquet.DynamicColumnLimitBehavior) Option {
+	return func(s *ColumnStore) error {
+		s.dynamicColumnLimit = limit
+		s.dynamicColumnLimitBehavior = behavior
+		return nil
+	}
+}
+
+// WithMaxInsertChunkSize sets the maximum size of the chunks inserted records
+// are split into. A single huge record would otherwise be written as one WAL
+// entry and one part, which may exceed parquet row group limits when the part
+// is compacted. The chunks of a record are logged as a single WAL entry and
+// inserted under the same transaction, so they become visible atomically. A
+// size <= 0 disables chunking.
+func WithMaxInsertChunkSize(size int64) Option {
+	return func(s *ColumnStore) error {
+		s.maxInsertChunkSize = size
+		return nil
+	}
+}
+
+// WithRowReducer registers a reducer under the given name, so that tables can
+// merge rows with equal values in all sorting columns with it, see
+// WithMergeReducer. Reducers must be registered before tables using them are
+// created or recovered.
+func WithRowReducer(name string, reduce dynparquet.RowReducer) Option {
+	return func(s *ColumnStore) error {
+		if name == "" {
+			return errors.New("row reducer name must not be empty")
+		}
+		if reduce == nil {
+			return fmt.Errorf("row reducer %q must not be nil", name)
+		}
+		if s.rowReducers == nil {
+			s.rowReducers = map[string]dynparquet.RowReducer{}
+		}
+		s.rowReducers[name] = reduce
+		return nil
+	}
+}
+
+// WithBackgroundSnapshotVerification loads snapshots on recovery without first
+// validating their checksum, which requires reading the whole snapshot file.
+// Instead, the checksum and the integrity of every part are verified in the
+// background once the snapshot is loaded. If verification fails, the database
+// is quarantined and all subsequent reads and writes return an
+// *ErrDBQuarantined error.
+func WithBackgroundSnapshotVerification() Option {
+	return func(s *ColumnStore) error {
+		s.backgroundSnapshotVerification = true
+		return nil
+	}
+}
+
+// WithBackgroundConcurrency limits the number of background tasks of the given
+// work class that run at the same time. Tasks past the limit are queued until
+// running tasks complete. A limit of 0, the default, is unlimited.
+func WithBackgroundConcurrency(class WorkClass, concurrency int) Option {
+	return func(s *ColumnStore) error {
+		if class < 0 || class >= numWorkClasses {
+			return fmt.Errorf("unknown work class: %v", class)
+		}
+		if concurrency < 0 {
+			return fmt.Errorf("background concurrency must not be negative: %d", concurrency)
+		}
+		s.scheduler.budgets[class] = concurrency
+		return nil
+	}
+}
+
+// WithMaxBackgroundConcurrency limits the total number of background tasks
+// that run at the same time. When the limit is reached, queued tasks are
+// started in order of the priority of their work class as running tasks
+// complete. A limit of 0, the default, is unlimited.
+func WithMaxBackgroundConcurrency(concurrency int) Option {
+	return func(s *ColumnStore) error {
+		if concurrency < 0 {
+			return fmt.Errorf("background concurrency must not be negative: %d", concurrency)
+		}
+		s.scheduler.maxConcurrency = concurrency
+		return nil
+	}
+}
+
 // Close persists all data from the columnstore to storage.
 // It is no longer valid to use the coumnstore for reads or writes, and the object should not longer be reused.
 func (s *ColumnStore) Close() error {
+	// The reporter gathers metrics that lock the column store, so it is
+	// stopped before the column store is locked.
+	s.stopReportingMetrics()
 	s.mtx.Lock()
 	defer s.mtx.Unlock()
 	s.metrics.shutdownStarted.Inc()
@@ -282,7 +708,17 @@ func (s *ColumnStore) Close() error {
 		})
 	}
 
-	return errg.Wait()
+	err := errg.Wait()
+	if s.walShipper != nil {
+		// Entries that are still pending are not shipped anymore.
+		s.walShipper.stop()
+	}
+	if s.decodePool != nil {
+		// The pool is closed once all databases are closed, so that no
+		// queries are scanning anymore.
+		s.decodePool.Close()
+	}
+	return err
 }
 
 func (s *ColumnStore) DatabasesDir() string {
@@ -357,10 +793,54 @@ type DB struct {
 	// TxPool is a waiting area for finished transactions that haven't been added to the watermark
 	txPool *TxPool
 
+	// commitCallbacks are invoked once the high watermark passes a txn.
+	commitCallbacksMtx sync.RWMutex
+	commitCallbacks    []CommitCallback
+	// txTables maps in-flight txns to the tables written in them. It is only
+	// populated if commit callbacks are registered.
+	txTables sync.Map
+	// walWatermark is the highest txn durably written to the WAL. It is
+	// math.MaxUint64 if the WAL is disabled.
+	walWatermark atomic.Uint64
+	// notifyMtx guards commit notifications. notifiedTx is the highest txn
+	// queued for the commit callbacks, pendingCommits are the queued
+	// notifications that were not delivered yet and notifying is set while a
+	// goroutine delivers them.
+	notifyMtx      sync.Mutex
+	notifiedTx     uint64
+	pendingCommits []pendingCommit
+	notifying      bool
+
 	compactAfterRecovery           bool
 	compactAfterRecoveryTableNames []string
 
 	snapshotInProgress atomic.Bool
+	// pinnedSnapshots counts the pins of snapshots by txn. Pinned snapshots
+	// are not removed.
+	pinnedSnapshotsMtx sync.Mutex
+	pinnedSnapshots    map[uint64]int
+
+	// quarantined is set if the data of the database failed background
+	// verification.
+	quarantined atomic.Pointer[ErrDBQuarantined]
+	// verifyCancel cancels the background snapshot verification, which is
+	// tracked by verifyWg.
+	verifyCancel context.CancelFunc
+	verifyWg     sync.WaitGroup
+
+	// maintenancePauses counts the pauses of background maintenance.
+	maintenancePauses atomic.Int64
+
+	// meta is the metadata key-value store of the database.
+	metaMtx sync.RWMutex
+	meta    map[string][]byte
+
+	// blockSchemas caches the arrow schemas of persisted blocks.
+	blockSchemas *blockSchemaCache
+
+	// quota limits the resources used by all tables of the database, see
+	// WithDBQuota.
+	quota atomic.Pointer[Quota]
 
 	metrics         snapshotMetrics
 	metricsProvider tableMetricsProvider
@@ -386,6 +866,28 @@ type DataSink interface {
 	Delete(ctx context.Context, name string) error
 }
 
+// MultipartUploader is implemented by data sinks that support uploading an
+// object as multiple independently uploaded parts, such as object storage
+// multipart uploads. Blocks are persisted to such sinks using parallel part
+// uploads.
+type MultipartUploader interface {
+	// NewMultipartUpload starts a multipart upload of the object with the
+	// given name.
+	NewMultipartUpload(ctx context.Context, name string) (MultipartUpload, error)
+}
+
+// MultipartUpload is an in-progress multipart upload of a single object.
+type MultipartUpload interface {
+	// UploadPart uploads the part with the given number. Part numbers start
+	// at 1 and the object is assembled in part number order. UploadPart may
+	// be called concurrently.
+	UploadPart(ctx context.Context, partNumber int, r io.Reader) error
+	// Complete assembles the uploaded parts into the object.
+	Complete(ctx context.Context) error
+	// Abort discards all uploaded parts.
+	Abort(ctx context.Context) error
+}
+
 type DBOption func(*DB) error
 
 func WithCompactionAfterOpen(compact bool, tableNames []string) DBOption {
@@ -457,6 +959,8 @@ func (s *ColumnStore) DB(ctx context.Context, name string, opts ...DBOption) (*D
 		mtx:             &sync.RWMutex{},
 		tables:          map[string]*Table{},
 		roTables:        map[string]*Table{},
+		meta:            map[string][]byte{},
+		blockSchemas:    newBlockSchemaCache(DefaultBlockSchemaCacheSize),
 		logger:          logger,
 		tracer:          s.tracer,
 		wal:             &wal.NopWAL{},
@@ -483,7 +987,11 @@ func (s *ColumnStore) DB(ctx context.Context, name string, opts ...DBOption) (*D
 				return err
 			}
 		}
-		db.txPool = NewTxPool(&db.highWatermark)
+		db.txPool = NewTxPool(&db.highWatermark, WithWatermarkCallback(func(uint64) { db.notifyCommit() }))
+		if !s.enableWAL {
+			// Without a WAL, writes are committed once they are visible.
+			db.walWatermark.Store(math.MaxUint64)
+		}
 		// Wait to start the compactor pool since benchmarks show that WAL
 		// replay is a lot more efficient if it is not competing against
 		// compaction. Additionally, if the CompactAfterRecovery option is
@@ -497,7 +1005,7 @@ func (s *ColumnStore) DB(ctx context.Context, name string, opts ...DBOption) (*D
 				}
 
 				for _, prefix := range prefixes {
-					_, err := db.readOnlyTable(prefix)
+					_, err := db.readOnlyTable(ctx, prefix)
 					if err != nil {
 						return err
 					}
@@ -523,16 +1031,24 @@ func (s *ColumnStore) DB(ctx context.Context, name string, opts ...DBOption) (*D
 				db.wal, err = db.openWAL(
 					ctx,
 					append(
-						[]wal.Option{
+						append([]wal.Option{
 							wal.WithMetrics(s.metrics.metricsForFileWAL(name)),
 							wal.WithStoreMetrics(s.metrics.metricsForWAL(name)),
-						}, s.testingOptions.walTestingOptions...,
+							wal.WithSyncCallback(db.walSynced),
+						}, s.walOptions...), s.testingOptions.walTestingOptions...,
 					)...,
 				)
 				return err
 			}(); err != nil {
 				return err
 			}
+		}
+		if s.walShipper != nil {
+			// Entries replayed above were logged before wrapping the WAL, so
+			// they are not shipped again.
+			db.wal = &shippingWAL{WAL: db.wal, database: name, shipper: s.walShipper}
+		}
+		if s.enableWAL || s.walShipper != nil {
 			// WAL pointers of tables need to be updated to the DB WAL since
 			// they are loaded from object storage and snapshots with a no-op
 			// WAL by default.
@@ -588,8 +1104,8 @@ func (s *ColumnStore) DB(ctx context.Context, name string, opts ...DBOption) (*D
 	return db, nil
 }
 
-// DBs returns all the DB names of this column store.
-func (s *ColumnStore) DBs() []string {
+// openDBs returns the names of the open DBs of this column store.
+func (s *ColumnStore) openDBs() []string {
 	s.mtx.RLock()
 	defer s.mtx.RUnlock()
 	return maps.Keys(s.dbs)
@@ -605,7 +1121,17 @@ func (s *ColumnStore) GetDB(name string) (*DB, error) {
 	return db, nil
 }
 
-func (s *ColumnStore) DropDB(name string) error {
+// DropDB closes the database and deletes its storage directory, including
+// its WAL and snapshots, and removes the series of its metrics. Persisted
+// blocks are only deleted with WithPurgeStorage.
+func (s *ColumnStore) DropDB(name string, options ...DropOption) error {
+	if s.readOnly {
+		return ErrReadOnly
+	}
+	opts := &dropOptions{}
+	for _, o := range options {
+		o(opts)
+	}
 	db, err := s.GetDB(name)
 	if err != nil {
 		return err
@@ -613,10 +1139,19 @@ func (s *ColumnStore) DropDB(name string) error {
 	if err := db.Close(WithClearStorage()); err != nil {
 		return err
 	}
-	s.mtx.Lock()
-	defer s.mtx.Unlock()
-	delete(s.dbs, name)
-	return os.RemoveAll(filepath.Join(s.DatabasesDir(), name))
+	if err := func() error {
+		s.mtx.Lock()
+		defer s.mtx.Unlock()
+		delete(s.dbs, name)
+		return os.RemoveAll(filepath.Join(s.DatabasesDir(), name))
+	}(); err != nil {
+		return err
+	}
+	s.metrics.deleteMetricsForDB(name)
+	if opts.purgeStorage {
+		return db.purgePrefix(context.Background(), name)
+	}
+	return nil
 }
 
 func (db *DB) openWAL(ctx context.Context, opts ...wal.Option) (WAL, error) {
@@ -687,26 +1222,36 @@ func (db *DB) recover(ctx context.Context, wal WAL) error {
 			"snapshot_tx", snapshotTx,
 			"snapshot_load_duration", time.Since(snapshotLoadStart),
 		)
-		if err := db.cleanupSnapshotDir(ctx, snapshotTx); err != nil {
-			// Truncation is best-effort. If it fails, move on.
-			level.Info(db.logger).Log(
-				"msg", "failed to truncate snapshots not equal to loaded snapshot",
-				"err", err,
-				"snapshot_tx", snapshotTx,
-			)
-		}
-		// snapshotTx can correspond to a write at that txn that is contained in
-		// the snapshot. We want the first entry of the WAL to be the subsequent
-		// txn to not replay duplicate writes.
-		if err := wal.Truncate(snapshotTx + 1); err != nil {
-			level.Info(db.logger).Log(
-				"msg", "failed to truncate WAL after loading snapshot",
-				"err", err,
-				"snapshot_tx", snapshotTx,
-			)
+		if db.columnStore.backgroundSnapshotVerification {
+			// Previous snapshots and WAL entries are kept until the loaded
+			// snapshot is verified in case it turns out to be corrupt.
+			db.verifySnapshotInBackground(snapshotTx, wal)
+		} else {
+			if err := db.cleanupSnapshotDir(ctx, snapshotTx); err != nil {
+				// Truncation is best-effort. If it fails, move on.
+				level.Info(db.logger).Log(
+					"msg", "failed to truncate snapshots not equal to loaded snapshot",
+					"err", err,
+					"snapshot_tx", snapshotTx,
+				)
+			}
+			// snapshotTx can correspond to a write at that txn that is contained in
+			// the snapshot. We want the first entry of the WAL to be the subsequent
+			// txn to not replay duplicate writes.
+			if err := db.truncateWAL(wal, snapshotTx+1); err != nil {
+				level.Info(db.logger).Log(
+					"msg", "failed to truncate WAL after loading snapshot",
+					"err", err,
+					"snapshot_tx", snapshotTx,
+				)
+			}
 		}
 	}
 
+	if err := db.loadMetaCheckpoint(snapshotTx); err != nil {
+		return fmt.Errorf("load metadata checkpoint: %w", err)
+	}
+
 	// persistedTables is a map from a table name to the last transaction
 	// persisted.
 	persistedTables := make(map[string]uint64)
@@ -832,12 +1377,16 @@ func (db *DB) recover(ctx context.Context, wal WAL) error {
 				// If schemas are identical from block to block we should we
 				// reuse the previous schema in order to retain pooled memory
 				// for it.
-				schema, err := dynparquet.SchemaFromDefinition(schema)
+				// The schema was updated, the new block is created with
+				// the config it was logged with.
+				config := NewTableConfig(schema, FromConfig(entry.Config))
+				schema, err := tableSchema(schema, config)
 				if err != nil {
 					return fmt.Errorf("initialize schema: %w", err)
 				}
 
-				table.schema = schema
+				table.config.Store(config)
+				table.schema.Store(schema)
 			}
 
 			table.active, err = newTableBlock(table, table.active.minTx, tx, id)
@@ -871,13 +1420,18 @@ func (db *DB) recover(ctx context.Context, wal WAL) error {
 				if err != nil {
 					return fmt.Errorf("create ipc reader: %w", err)
 				}
-				record, err := reader.Read()
-				if err != nil {
+				defer reader.Release()
+				// A write is logged as multiple records if it was split into
+				// chunks, each of which is inserted as a separate part.
+				for reader.Next() {
+					record := reader.Record()
+					record.Retain()
+					size := util.TotalRecordSize(record)
+					table.active.index.InsertPart(parts.NewArrowPart(tx, record, uint64(size), table.schema.Load(), parts.WithCompactionLevel(int(index.L0))))
+				}
+				if err := reader.Err(); err != nil {
 					return fmt.Errorf("read record: %w", err)
 				}
-				defer reader.Release()
-				size := util.TotalRecordSize(record)
-				table.active.index.InsertPart(parts.NewArrowPart(tx, record, uint64(size), table.schema, parts.WithCompactionLevel(int(index.L0))))
 			default:
 				panic("parquet writes are deprecated")
 			}
@@ -890,6 +1444,31 @@ func (db *DB) recover(ctx context.Context, wal WAL) error {
 			return nil
 		case *walpb.Entry_Snapshot_:
 			return nil
+		case *walpb.Entry_Meta_:
+			db.applyMeta(e.Meta)
+			return nil
+		case *walpb.Entry_Delete_:
+			table, err := db.GetTable(e.Delete.TableName)
+			var tableErr ErrTableNotFound
+			if errors.As(err, &tableErr) {
+				// The table's data was persisted and the tombstone with it.
+				return nil
+			}
+			if err != nil {
+				return fmt.Errorf("get table: %w", err)
+			}
+			if err := table.replayTombstone(e.Delete); err != nil {
+				return fmt.Errorf("replay delete: %w", err)
+			}
+			return nil
+		case *walpb.Entry_Truncate_:
+			// The truncated writes are skipped due to the table block
+			// persisted entry logged when the active block was rotated.
+			return nil
+		case nil:
+			// Placeholder for a transaction the WAL rejected, e.g. because
+			// its queue was full.
+			return nil
 		default:
 			return fmt.Errorf("unexpected WAL entry type: %t", e)
 		}
@@ -981,6 +1560,10 @@ func (db *DB) Close(options ...CloseOption) error {
 			// storage. This would avoid a slow WAL replay on startup if we
 			// don't manage to persist in time.
 			table.writeBlock(table.ActiveBlock(), db.tx.Load(), false)
+			// Transaction IDs are reset once storage is dropped below.
+			if err := table.resetTombstones(); err != nil {
+				level.Error(db.logger).Log("msg", "failed to reset tombstones", "table", table.name, "err", err)
+			}
 		}
 	}
 	level.Info(db.logger).Log("msg", "closed all tables")
@@ -990,7 +1573,18 @@ func (db *DB) Close(options ...CloseOption) error {
 	}
 
 	if (shouldPersist || opts.clearStorage) && db.storagePath != "" {
-		if err := db.dropStorage(); err != nil {
+		var keep []string
+		if !opts.clearStorage {
+			// Tombstones still apply to blocks that were persisted before
+			// the corresponding deletes.
+			keep = append(keep, tombstonesPath)
+			// The metadata store is not persisted with blocks.
+			if err := db.checkpointMeta(true); err != nil {
+				return err
+			}
+			keep = append(keep, metaPath)
+		}
+		if err := db.dropStorage(keep...); err != nil {
 			return err
 		}
 		level.Info(db.logger).Log("msg", "cleaned up wal & snapshots")
@@ -1006,15 +1600,40 @@ func (db *DB) closeInternal() error {
 		}
 	}()
 
+	// Background snapshot verification truncates the WAL, so it must be
+	// stopped before the WAL is closed.
+	if db.verifyCancel != nil {
+		db.verifyCancel()
+	}
+	db.verifyWg.Wait()
+
 	if !db.columnStore.enableWAL || db.wal == nil {
 		return nil
 	}
 	return db.wal.Close()
 }
 
+// CheckWALIntegrity reads every record of the database's WAL and reports the
+// ranges of records that are corrupt. The WAL is not modified.
+func (db *DB) CheckWALIntegrity(ctx context.Context) (wal.Report, error) {
+	checker, ok := unwrapWAL(db.wal).(interface {
+		CheckIntegrity(context.Context) (wal.Report, error)
+	})
+	if !db.columnStore.enableWAL || !ok {
+		return wal.Report{}, fmt.Errorf("WAL is not enabled")
+	}
+	return checker.CheckIntegrity(ctx)
+}
+
 func (db *DB) maintainWAL() {
 	if minTx := db.getMinTXPersisted(); minTx > 0 {
-		if err := db.wal.Truncate(minTx); err != nil {
+		// Metadata is not persisted with blocks, so it needs to be
+		// checkpointed before the WAL records containing it are truncated.
+		if err := db.checkpointMeta(false); err != nil {
+			level.Error(db.logger).Log("msg", "failed to checkpoint metadata before WAL truncation", "err", err)
+			return
+		}
+		if err := db.truncateWAL(db.wal, minTx); err != nil {
 			return
 		}
 	}
@@ -1045,7 +1664,7 @@ func (db *DB) reclaimDiskSpace(ctx context.Context, wal WAL) error {
 	// include a potential write at validSnapshotTxn. We don't want this to be
 	// the first entry in the WAL after truncation, given it is already
 	// contained in the snapshot, so Truncate at validSnapshotTxn + 1.
-	return wal.Truncate(validSnapshotTxn + 1)
+	return db.truncateWAL(wal, validSnapshotTxn+1)
 }
 
 func (db *DB) getMinTXPersisted() uint64 {
@@ -1063,7 +1682,7 @@ func (db *DB) getMinTXPersisted() uint64 {
 	return minTx
 }
 
-func (db *DB) readOnlyTable(name string) (*Table, error) {
+func (db *DB) readOnlyTable(ctx context.Context, name string) (*Table, error) {
 	table, ok := db.tables[name]
 	if ok {
 		return table, nil
@@ -1082,6 +1701,22 @@ func (db *DB) readOnlyTable(name string) (*Table, error) {
 		return nil, fmt.Errorf("failed to create table: %w", err)
 	}
 
+	// Read-only tables are queried with the schema of their newest persisted
+	// block.
+	def, err := db.newestBlockSchema(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	if def != nil {
+		config := NewTableConfig(def)
+		schema, err := schemaFromTableConfig(config)
+		if err != nil {
+			return nil, fmt.Errorf("table %s: %w", name, err)
+		}
+		table.config.Store(config)
+		table.schema.Store(schema)
+	}
+
 	db.roTables[name] = table
 	return table, nil
 }
@@ -1100,14 +1735,17 @@ func (db *DB) promoteReadOnlyTableLocked(name string, config *tablepb.TableConfi
 		return nil, err
 	}
 	table.config.Store(config)
-	table.schema = schema
+	table.schema.Store(schema)
 	delete(db.roTables, name)
 	return table, nil
 }
 
 // Table will get or create a new table with the given name and config. If a table already exists with the given name, it will have it's configuration updated.
 func (db *DB) Table(name string, config *tablepb.TableConfig) (*Table, error) {
-	return db.table(name, config, generateULID())
+	if db.columnStore.readOnly {
+		return nil, ErrReadOnly
+	}
+	return db.table(name, config, generateULID(db.columnStore.clock))
 }
 
 func (db *DB) table(name string, config *tablepb.TableConfig, id ulid.ULID) (*Table, error) {
@@ -1117,14 +1755,69 @@ func (db *DB) table(name string, config *tablepb.TableConfig, id ulid.ULID) (*Ta
 	if !validateName(name) {
 		return nil, errors.New("invalid table name")
 	}
+	if err := validateQuotas(config); err != nil {
+		return nil, fmt.Errorf("table %s: %w", name, err)
+	}
 	db.mtx.RLock()
 	table, ok := db.tables[name]
 	db.mtx.RUnlock()
 	if ok {
+		if !sortOrdersEqual(table.config.Load(), config) {
+			return nil, errors.New("sort orders of an existing table cannot be changed")
+		}
+		config = withUpdatedSchema(table.config.Load(), config)
+		if err := checkSchemaCompatibility(table.config.Load(), config); err != nil {
+			return nil, fmt.Errorf("table %s: %w", name, err)
+		}
+		if err := db.ensureSortOrders(name, config); err != nil {
+			return nil, err
+		}
+		schema := table.schema.Load()
+		if config.RetentionMs != 0 && schema != nil {
+			if err := validateRetention(schema, config); err != nil {
+				return nil, err
+			}
+		}
+		if config.MonotonicTimestampsCacheSize != 0 && schema != nil {
+			if err := validateMonotonicTimestamps(schema, config); err != nil {
+				return nil, err
+			}
+		}
+		if config.PartitionDurationMs != 0 && schema != nil {
+			if err := validatePartitioning(schema, config); err != nil {
+				return nil, err
+			}
+		}
 		table.config.Store(config)
+		table.startRetentionLoop()
+		table.startBlockGCLoop()
 		return table, nil
 	}
 
+	// The WAL recording a schema update is removed once all blocks are
+	// persisted, so the schema of the newest persisted block is used if it
+	// is an update of the configured schema, i.e. it compatibly adds columns
+	// the configured schema lacks.
+	if configured := config.GetDeprecatedSchema(); configured != nil {
+		def, err := db.newestBlockSchema(context.Background(), name)
+		if err != nil {
+			return nil, err
+		}
+		if schema, ok := def.(*schemapb.Schema); ok &&
+			dynparquet.CheckCompatibility(configured, schema).Err() == nil &&
+			dynparquet.CheckCompatibility(schema, configured).Err() != nil {
+			config = withUpdatedSchema(&tablepb.TableConfig{
+				Schema: &tablepb.TableConfig_DeprecatedSchema{DeprecatedSchema: schema},
+			}, config)
+		}
+	}
+
+	// The tables storing the sort orders are created first, so that all
+	// inserts into the table are mirrored into them.
+	if err := db.ensureSortOrders(name, config); err != nil {
+		return nil, err
+	}
+
 	db.mtx.Lock()
 	defer db.mtx.Unlock()
 
@@ -1158,7 +1851,7 @@ func (db *DB) table(name string, config *tablepb.TableConfig, id ulid.ULID) (*Ta
 		}
 	}
 
-	tx, _, commit := db.begin()
+	tx, _, commit := db.begin(name)
 	defer commit()
 
 	if err := table.newTableBlock(0, tx, id); err != nil {
@@ -1166,6 +1859,8 @@ func (db *DB) table(name string, config *tablepb.TableConfig, id ulid.ULID) (*Ta
 	}
 
 	db.tables[name] = table
+	table.startRetentionLoop()
+	table.startBlockGCLoop()
 	return table, nil
 }
 
@@ -1225,24 +1920,167 @@ func (p *DBTableProvider) GetTable(name string) (logicalplan.TableReader, error)
 	return nil, fmt.Errorf("table %v not found", name)
 }
 
+// CommitCallback is called with a committed transaction and the names of the
+// tables written to in that transaction.
+type CommitCallback func(tx uint64, tables []string)
+
+// OnCommit registers a callback that is invoked once a transaction is
+// committed: the high watermark has passed it, meaning its writes are visible
+// to readers, and if the WAL is enabled its WAL record was written and synced
+// to disk. This allows embedders to release upstream acknowledgements without
+// polling Wait. Callbacks are invoked in transaction order, but they are
+// called synchronously when the watermark advances or the WAL syncs, so they
+// must not block. Callbacks may write to the database, the commits of those
+// writes are delivered after the callback returned.
+func (db *DB) OnCommit(callback CommitCallback) {
+	db.commitCallbacksMtx.Lock()
+	defer db.commitCallbacksMtx.Unlock()
+	db.commitCallbacks = append(db.commitCallbacks, callback)
+}
+
+func (db *DB) hasCommitCallbacks() bool {
+	db.commitCallbacksMtx.RLock()
+	defer db.commitCallbacksMtx.RUnlock()
+	return len(db.commitCallbacks) > 0
+}
+
+// walSynced is called by the WAL once all records up to and including txn
+// were written and synced to disk.
+func (db *DB) walSynced(txn uint64) {
+	for {
+		mark := db.walWatermark.Load()
+		if txn <= mark {
+			break
+		}
+		if db.walWatermark.CompareAndSwap(mark, txn) {
+			break
+		}
+	}
+	db.notifyCommit()
+}
+
+// pendingCommit is a committed txn whose commit callbacks were not invoked
+// yet.
+type pendingCommit struct {
+	tx     uint64
+	tables []string
+}
+
+// notifyCommit invokes the registered commit callbacks for all txns that are
+// both below the high watermark and durably written to the WAL. It must be
+// called after either of them advanced.
+//
+// The callbacks are invoked without holding notifyMtx, so that callbacks may
+// write to the database themselves. Only one goroutine delivers notifications
+// at a time, which keeps them in txn order; notifications queued while
+// another goroutine is delivering are delivered by that goroutine.
+func (db *DB) notifyCommit() {
+	db.notifyMtx.Lock()
+	committed := min(db.highWatermark.Load(), db.walWatermark.Load())
+	if committed > db.notifiedTx {
+		if db.hasCommitCallbacks() {
+			for txn := db.notifiedTx + 1; txn <= committed; txn++ {
+				var tables []string
+				if v, ok := db.txTables.LoadAndDelete(txn); ok {
+					tables = v.([]string)
+				}
+				db.pendingCommits = append(db.pendingCommits, pendingCommit{tx: txn, tables: tables})
+			}
+		}
+		db.notifiedTx = committed
+	}
+	if db.notifying {
+		db.notifyMtx.Unlock()
+		return
+	}
+	db.notifying = true
+
+	for {
+		pending := db.pendingCommits
+		db.pendingCommits = nil
+		if len(pending) == 0 {
+			db.notifying = false
+			db.notifyMtx.Unlock()
+			return
+		}
+		db.notifyMtx.Unlock()
+
+		// Callbacks are only ever appended, so the callbacks registered so
+		// far can be called without holding the lock, which would otherwise
+		// deadlock callbacks registering other callbacks.
+		db.commitCallbacksMtx.RLock()
+		callbacks := db.commitCallbacks
+		db.commitCallbacksMtx.RUnlock()
+		for _, c := range pending {
+			for _, callback := range callbacks {
+				callback(c.tx, c.tables)
+			}
+		}
+
+		db.notifyMtx.Lock()
+	}
+}
+
+// ErrDBQuarantined is returned by reads and writes to a database whose data
+// failed integrity verification.
+type ErrDBQuarantined struct {
+	DB  string
+	Err error
+}
+
+func (e *ErrDBQuarantined) Error() string {
+	return fmt.Sprintf("db %s is quarantined: %v", e.DB, e.Err)
+}
+
+func (e *ErrDBQuarantined) Unwrap() error {
+	return e.Err
+}
+
+// Quarantined returns a non-nil *ErrDBQuarantined error if the database has
+// been quarantined because its data failed integrity verification.
+func (db *DB) Quarantined() error {
+	if err := db.quarantined.Load(); err != nil {
+		return err
+	}
+	return nil
+}
+
+// quarantine marks the database as quarantined with the given cause. Only the
+// first cause is retained.
+func (db *DB) quarantine(err error) {
+	qErr := &ErrDBQuarantined{DB: db.name, Err: err}
+	if db.quarantined.CompareAndSwap(nil, qErr) {
+		level.Error(db.logger).Log(
+			"msg", "quarantining db",
+			"err", err,
+		)
+	}
+}
+
 // beginRead returns the high watermark. Reads can safely access any write that has a lower or equal tx id than the returned number.
 func (db *DB) beginRead() uint64 {
 	return db.highWatermark.Load()
 }
 
 // begin is an internal function that Tables call to start a transaction for writes.
+// The names of the tables written to in the transaction may optionally be
+// passed so they can be provided to commit callbacks.
 // It returns:
 //
 //	the write tx id
 //	The current high watermark
 //	A function to complete the transaction
-func (db *DB) begin() (uint64, uint64, func()) {
+func (db *DB) begin(tables ...string) (uint64, uint64, func()) {
 	txn := db.tx.Add(1)
 	watermark := db.highWatermark.Load()
+	if len(tables) > 0 && db.hasCommitCallbacks() {
+		db.txTables.Store(txn, tables)
+	}
 	return txn, watermark, func() {
 		if mark := db.highWatermark.Load(); mark+1 == txn {
 			// This is the next consecutive transaction; increase the watermark.
 			db.highWatermark.Store(txn)
+			db.notifyCommit()
 			db.txPool.notifyWatermark()
 			return
 		}
@@ -1276,6 +2114,12 @@ func (db *DB) HighWatermark() uint64 {
 func (db *DB) resetToTxn(txn uint64, wal WAL) {
 	db.tx.Store(txn)
 	db.highWatermark.Store(txn)
+	db.notifyMtx.Lock()
+	db.notifiedTx = txn
+	if db.walWatermark.Load() != math.MaxUint64 {
+		db.walWatermark.Store(txn)
+	}
+	db.notifyMtx.Unlock()
 	if wal != nil {
 		// This call resets the WAL to a zero state so that new records can be
 		// logged.
@@ -1296,7 +2140,7 @@ func validateName(name string) bool {
 
 // dropStorage removes all data from the storage directory, but leaves the empty
 // storage directory.
-func (db *DB) dropStorage() error {
+func (db *DB) dropStorage(keep ...string) error {
 	trashDir := db.trashDir()
 
 	entries, err := os.ReadDir(db.storagePath)
@@ -1323,6 +2167,9 @@ func (db *DB) dropStorage() error {
 		}
 		errs := make([]error, 0, len(entries))
 		for _, e := range entries {
+			if slices.Contains(keep, e.Name()) {
+				continue
+			}
 			if err := os.Rename(filepath.Join(db.storagePath, e.Name()), filepath.Join(tmpPath, e.Name())); err != nil && !os.IsNotExist(err) {
 				errs = append(errs, err)
 			}
@@ -1333,6 +2180,9 @@ func (db *DB) dropStorage() error {
 		// to attempting to remove them with RemoveAll.
 		errs := make([]error, 0, len(entries))
 		for _, e := range entries {
+			if slices.Contains(keep, e.Name()) {
+				continue
+			}
 			if err := os.RemoveAll(filepath.Join(db.storagePath, e.Name())); err != nil {
 				errs = append(errs, err)
 			}
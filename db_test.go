@@ -9,6 +9,7 @@ import (
 	"math/rand"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 	"sync"
 	"testing"
@@ -28,6 +29,8 @@ import (
 	"google.golang.org/protobuf/proto"
 
 	"github.com/polarsignals/frostdb/dynparquet"
+	schemapb "github.com/polarsignals/frostdb/gen/proto/go/frostdb/schema/v1alpha1"
+	schemav2pb "github.com/polarsignals/frostdb/gen/proto/go/frostdb/schema/v1alpha2"
 	walpb "github.com/polarsignals/frostdb/gen/proto/go/frostdb/wal/v1alpha1"
 	"github.com/polarsignals/frostdb/index"
 	"github.com/polarsignals/frostdb/query"
@@ -969,6 +972,337 @@ func Test_DB_TableNotExist(t *testing.T) {
 	require.Error(t, err)
 }
 
+func Test_DB_TableSchemaCompatibility(t *testing.T) {
+	ctx := context.Background()
+	options := []Option{WithReadWriteStorage(NewDefaultObjstoreBucket(objstore.NewInMemBucket()))}
+
+	def := &schemapb.Schema{
+		Name: "test",
+		Columns: []*schemapb.Column{{
+			Name:          "name",
+			StorageLayout: &schemapb.StorageLayout{Type: schemapb.StorageLayout_TYPE_STRING},
+		}, {
+			Name:          "value",
+			StorageLayout: &schemapb.StorageLayout{Type: schemapb.StorageLayout_TYPE_INT64},
+		}},
+		SortingColumns: []*schemapb.SortingColumn{{
+			Name:      "name",
+			Direction: schemapb.SortingColumn_DIRECTION_ASCENDING,
+		}},
+	}
+	// Adding a nullable column is compatible.
+	newDef := proto.Clone(def).(*schemapb.Schema)
+	newDef.Columns = append(newDef.Columns, &schemapb.Column{
+		Name:          "comment",
+		StorageLayout: &schemapb.StorageLayout{Type: schemapb.StorageLayout_TYPE_STRING, Nullable: true},
+	})
+
+	insert := func(t *testing.T, table *Table, name string, value int64, comment *string) {
+		t.Helper()
+		fields := []arrow.Field{
+			{Name: "name", Type: arrow.BinaryTypes.String},
+			{Name: "value", Type: arrow.PrimitiveTypes.Int64},
+		}
+		if comment != nil {
+			fields = append(fields, arrow.Field{Name: "comment", Type: arrow.BinaryTypes.String, Nullable: true})
+		}
+		b := array.NewRecordBuilder(memory.DefaultAllocator, arrow.NewSchema(fields, nil))
+		defer b.Release()
+		b.Field(0).(*array.StringBuilder).Append(name)
+		b.Field(1).(*array.Int64Builder).Append(value)
+		if comment != nil {
+			b.Field(2).(*array.StringBuilder).Append(*comment)
+		}
+		r := b.NewRecord()
+		defer r.Release()
+		_, err := table.InsertRecord(ctx, r)
+		require.NoError(t, err)
+	}
+
+	c, db := openTestDB(t, options...)
+	table, err := db.Table("test", NewTableConfig(def))
+	require.NoError(t, err)
+	insert(t, table, "a", 1, nil)
+
+	// The schema of an open table cannot be changed, even if the change is
+	// compatible.
+	_, err = db.Table("test", NewTableConfig(newDef))
+	require.ErrorIs(t, err, ErrTableSchemaChanged)
+	require.True(t, proto.Equal(def, table.config.Load().GetDeprecatedSchema()))
+
+	// Incompatible changes are reported as such.
+	breaking := proto.Clone(def).(*schemapb.Schema)
+	breaking.Columns = breaking.Columns[:1]
+	_, err = db.Table("test", NewTableConfig(breaking))
+	var incompatible *dynparquet.IncompatibleSchemaError
+	require.ErrorAs(t, err, &incompatible)
+	require.Equal(t, dynparquet.Breaking, incompatible.Change.Compatibility)
+
+	// Changes of v1alpha2 schemas are rejected as well.
+	v2 := &schemav2pb.Schema{
+		Root: &schemav2pb.Group{
+			Name: "test",
+			Nodes: []*schemav2pb.Node{{
+				Type: &schemav2pb.Node_Leaf{Leaf: &schemav2pb.Leaf{
+					Name:          "name",
+					StorageLayout: &schemav2pb.StorageLayout{Type: schemav2pb.StorageLayout_TYPE_STRING},
+				}},
+			}},
+		},
+		SortingColumns: []*schemav2pb.SortingColumn{{
+			Path:      "name",
+			Direction: schemav2pb.SortingColumn_DIRECTION_ASCENDING,
+		}},
+	}
+	_, err = db.Table("v2", NewTableConfig(v2))
+	require.NoError(t, err)
+	_, err = db.Table("v2", NewTableConfig(proto.Clone(v2)))
+	require.NoError(t, err)
+	v2 = proto.Clone(v2).(*schemav2pb.Schema)
+	v2.Root.Nodes = append(v2.Root.Nodes, &schemav2pb.Node{
+		Type: &schemav2pb.Node_Leaf{Leaf: &schemav2pb.Leaf{
+			Name:          "comment",
+			StorageLayout: &schemav2pb.StorageLayout{Type: schemav2pb.StorageLayout_TYPE_STRING, Nullable: true},
+		}},
+	})
+	_, err = db.Table("v2", NewTableConfig(v2))
+	require.ErrorIs(t, err, ErrTableSchemaChanged)
+
+	// The compatible change is applied when the table is opened with the new
+	// schema, and the new column can be written and read along with the data
+	// written with the old schema.
+	require.NoError(t, c.Close())
+	c, db = openTestDB(t, options...)
+	defer c.Close()
+	table, err = db.Table("test", NewTableConfig(newDef))
+	require.NoError(t, err)
+	comment := "hello"
+	insert(t, table, "b", 2, &comment)
+
+	comments := map[string]string{}
+	require.NoError(t, query.NewEngine(memory.DefaultAllocator, db.TableProvider()).
+		ScanTable("test").
+		Execute(ctx, func(_ context.Context, r arrow.Record) error {
+			names := r.Column(r.Schema().FieldIndices("name")[0])
+			idx := r.Schema().FieldIndices("comment")
+			for i := 0; i < int(r.NumRows()); i++ {
+				if len(idx) == 0 || r.Column(idx[0]).IsNull(i) {
+					comments[stringValue(names, i)] = ""
+					continue
+				}
+				comments[stringValue(names, i)] = stringValue(r.Column(idx[0]), i)
+			}
+			return nil
+		}))
+	require.Equal(t, map[string]string{"a": "", "b": "hello"}, comments)
+}
+
+func Test_Table_UpdateSchema(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	options := []Option{
+		WithWAL(),
+		WithStoragePath(dir),
+		WithReadWriteStorage(NewDefaultObjstoreBucket(objstore.NewInMemBucket())),
+	}
+
+	def := &schemapb.Schema{
+		Name: "test",
+		Columns: []*schemapb.Column{{
+			Name:          "name",
+			StorageLayout: &schemapb.StorageLayout{Type: schemapb.StorageLayout_TYPE_STRING},
+		}, {
+			Name:          "value",
+			StorageLayout: &schemapb.StorageLayout{Type: schemapb.StorageLayout_TYPE_INT64},
+		}},
+		SortingColumns: []*schemapb.SortingColumn{{
+			Name:      "name",
+			Direction: schemapb.SortingColumn_DIRECTION_ASCENDING,
+		}},
+	}
+	newDef := proto.Clone(def).(*schemapb.Schema)
+	newDef.Columns = append(newDef.Columns, &schemapb.Column{
+		Name:          "comment",
+		StorageLayout: &schemapb.StorageLayout{Type: schemapb.StorageLayout_TYPE_STRING, Nullable: true},
+	})
+
+	insert := func(t *testing.T, table *Table, name string, comment *string) {
+		t.Helper()
+		fields := []arrow.Field{
+			{Name: "name", Type: arrow.BinaryTypes.String},
+			{Name: "value", Type: arrow.PrimitiveTypes.Int64},
+		}
+		if comment != nil {
+			fields = append(fields, arrow.Field{Name: "comment", Type: arrow.BinaryTypes.String, Nullable: true})
+		}
+		b := array.NewRecordBuilder(memory.DefaultAllocator, arrow.NewSchema(fields, nil))
+		defer b.Release()
+		b.Field(0).(*array.StringBuilder).Append(name)
+		b.Field(1).(*array.Int64Builder).Append(1)
+		if comment != nil {
+			b.Field(2).(*array.StringBuilder).Append(*comment)
+		}
+		r := b.NewRecord()
+		defer r.Release()
+		_, err := table.InsertRecord(ctx, r)
+		require.NoError(t, err)
+	}
+	comments := func(t *testing.T, db *DB) map[string]string {
+		t.Helper()
+		res := map[string]string{}
+		require.NoError(t, query.NewEngine(memory.DefaultAllocator, db.TableProvider()).
+			ScanTable("test").
+			Execute(ctx, func(_ context.Context, r arrow.Record) error {
+				names := r.Column(r.Schema().FieldIndices("name")[0])
+				idx := r.Schema().FieldIndices("comment")
+				for i := 0; i < int(r.NumRows()); i++ {
+					if len(idx) == 0 || r.Column(idx[0]).IsNull(i) {
+						res[stringValue(names, i)] = ""
+						continue
+					}
+					res[stringValue(names, i)] = stringValue(r.Column(idx[0]), i)
+				}
+				return nil
+			}))
+		return res
+	}
+
+	c, db := openTestDB(t, options...)
+	table, err := db.Table("test", NewTableConfig(def))
+	require.NoError(t, err)
+	insert(t, table, "a", nil)
+
+	// Incompatible changes are rejected.
+	breaking := proto.Clone(def).(*schemapb.Schema)
+	breaking.Columns = breaking.Columns[:1]
+	var incompatible *dynparquet.IncompatibleSchemaError
+	require.ErrorAs(t, table.UpdateSchema(breaking), &incompatible)
+	require.True(t, proto.Equal(def, table.config.Load().GetDeprecatedSchema()))
+
+	require.NoError(t, table.UpdateSchema(newDef))
+	require.True(t, proto.Equal(newDef, table.config.Load().GetDeprecatedSchema()))
+	require.True(t, proto.Equal(newDef, table.Schema().Definition()))
+	comment := "hello"
+	insert(t, table, "b", &comment)
+	require.Equal(t, map[string]string{"a": "", "b": "hello"}, comments(t, db))
+
+	// The updated schema is restored from the WAL, and opening the table with
+	// the schema it was created with does not revert the update.
+	require.NoError(t, c.Close())
+	c, db = openTestDB(t, options...)
+	defer c.Close()
+	table, err = db.Table("test", NewTableConfig(def))
+	require.NoError(t, err)
+	require.True(t, proto.Equal(newDef, table.config.Load().GetDeprecatedSchema()))
+	require.Equal(t, map[string]string{"a": "", "b": "hello"}, comments(t, db))
+}
+
+func Test_DB_MapColumn(t *testing.T) {
+	ctx := context.Background()
+	def := &schemav2pb.Schema{
+		Root: &schemav2pb.Group{
+			Name: "test",
+			Nodes: []*schemav2pb.Node{
+				{
+					Type: &schemav2pb.Node_Leaf{Leaf: &schemav2pb.Leaf{
+						Name: "labels",
+						StorageLayout: &schemav2pb.StorageLayout{
+							Type:     schemav2pb.StorageLayout_TYPE_MAP,
+							Nullable: true,
+						},
+					}},
+				},
+				{
+					Type: &schemav2pb.Node_Leaf{Leaf: &schemav2pb.Leaf{
+						Name:          "timestamp",
+						StorageLayout: &schemav2pb.StorageLayout{Type: schemav2pb.StorageLayout_TYPE_INT64},
+					}},
+				},
+			},
+		},
+		SortingColumns: []*schemav2pb.SortingColumn{{
+			Path:      "timestamp",
+			Direction: schemav2pb.SortingColumn_DIRECTION_ASCENDING,
+		}},
+	}
+
+	c, err := New(WithLogger(newTestLogger(t)))
+	require.NoError(t, err)
+	defer c.Close()
+	db, err := c.DB(ctx, "test")
+	require.NoError(t, err)
+	table, err := db.Table("test", NewTableConfig(def))
+	require.NoError(t, err)
+
+	labelsType := arrow.MapOf(arrow.BinaryTypes.Binary, arrow.BinaryTypes.Binary)
+	b := array.NewRecordBuilder(memory.DefaultAllocator, arrow.NewSchema([]arrow.Field{
+		{Name: "labels", Type: labelsType, Nullable: true},
+		{Name: "timestamp", Type: arrow.PrimitiveTypes.Int64},
+	}, nil))
+	defer b.Release()
+	labels := b.Field(0).(*array.MapBuilder)
+	keys := labels.KeyBuilder().(*array.BinaryBuilder)
+	items := labels.ItemBuilder().(*array.BinaryBuilder)
+	for i, set := range []map[string]string{
+		{"job": "api", "instance": "a"},
+		{"job": "db"},
+		nil,
+		{},
+		{"instance": "b"},
+	} {
+		if set == nil {
+			labels.AppendNull()
+		} else {
+			labels.Append(true)
+			for _, k := range []string{"instance", "job"} {
+				if v, ok := set[k]; ok {
+					keys.AppendString(k)
+					items.AppendString(v)
+				}
+			}
+		}
+		b.Field(1).(*array.Int64Builder).Append(int64(i))
+	}
+	r := b.NewRecord()
+	defer r.Release()
+	_, err = table.InsertRecord(ctx, r)
+	require.NoError(t, err)
+
+	engine := query.NewEngine(memory.DefaultAllocator, db.TableProvider())
+	jobs := func(filter logicalplan.Expr) map[int64]string {
+		builder := engine.ScanTable("test")
+		if filter != nil {
+			builder = builder.Filter(filter)
+		}
+		res := map[int64]string{}
+		require.NoError(t, builder.
+			Project(logicalplan.Col("timestamp"), logicalplan.Col("labels").Key("job")).
+			Execute(ctx, func(_ context.Context, r arrow.Record) error {
+				ts := r.Column(r.Schema().FieldIndices("timestamp")[0]).(*array.Int64)
+				job := r.Column(r.Schema().FieldIndices("labels['job']")[0]).(*array.Binary)
+				for i := 0; i < int(r.NumRows()); i++ {
+					res[ts.Value(i)] = ""
+					if job.IsValid(i) {
+						res[ts.Value(i)] = string(job.Value(i))
+					}
+				}
+				return nil
+			}))
+		return res
+	}
+
+	check := func() {
+		require.Equal(t, map[int64]string{0: "api", 1: "db", 2: "", 3: "", 4: ""}, jobs(nil))
+		require.Equal(t, map[int64]string{0: "api"}, jobs(logicalplan.Col("labels").Key("job").Eq(logicalplan.Literal("api"))))
+		require.Equal(t, map[int64]string{0: "api", 1: "db"}, jobs(logicalplan.Col("labels").Key("job").RegexMatch("^(api|db)$")))
+	}
+	check()
+
+	// Map columns are written to and read from parquet once compacted.
+	require.NoError(t, table.EnsureCompaction())
+	check()
+}
+
 func Test_DB_TableWrite_ArrowRecord(t *testing.T) {
 	for _, schema := range []proto.Message{
 		dynparquet.SampleDefinition(),
@@ -1145,6 +1479,70 @@ func Test_DB_ReadOnlyQuery(t *testing.T) {
 	require.NoError(t, err)
 }
 
+func Test_DB_ReadOnlyStorage(t *testing.T) {
+	ctx := context.Background()
+	config := NewTableConfig(
+		dynparquet.SampleDefinition(),
+	)
+	logger := newTestLogger(t)
+	bucket := objstore.NewInMemBucket()
+
+	c, err := New(
+		WithLogger(logger),
+		WithReadWriteStorage(NewDefaultObjstoreBucket(bucket)),
+	)
+	require.NoError(t, err)
+	for _, name := range []string{"db1", "db2"} {
+		db, err := c.DB(ctx, name)
+		require.NoError(t, err)
+		table, err := db.Table("test", config)
+		require.NoError(t, err)
+		r, err := dynparquet.NewTestSamples().ToRecord()
+		require.NoError(t, err)
+		_, err = table.InsertRecord(ctx, r)
+		r.Release()
+		require.NoError(t, err)
+	}
+	require.NoError(t, c.Close())
+
+	c, err = New(
+		WithLogger(logger),
+		WithReadOnlyStorage(NewDefaultObjstoreBucket(bucket)),
+	)
+	require.NoError(t, err)
+	defer c.Close()
+	require.True(t, c.ReadOnly())
+
+	dbs, err := c.DBs(ctx)
+	require.NoError(t, err)
+	require.ElementsMatch(t, []string{"db1", "db2"}, dbs)
+
+	db, err := c.GetDB("db1")
+	require.NoError(t, err)
+	// The schema of tables that only exist in storage is read from their
+	// blocks.
+	reader, err := db.TableProvider().GetTable("test")
+	require.NoError(t, err)
+	require.True(t, proto.Equal(dynparquet.SampleDefinition(), reader.Schema().Definition()))
+	rows := int64(0)
+	require.NoError(t, query.NewEngine(memory.NewGoAllocator(), db.TableProvider()).
+		ScanTable("test").
+		Execute(ctx, func(_ context.Context, r arrow.Record) error {
+			rows += r.NumRows()
+			return nil
+		}))
+	require.Equal(t, int64(len(dynparquet.NewTestSamples())), rows)
+
+	_, err = db.Table("test", config)
+	require.ErrorIs(t, err, ErrReadOnly)
+	require.ErrorIs(t, db.MetaPut(ctx, "key", []byte("value")), ErrReadOnly)
+	r, err := dynparquet.NewTestSamples().ToRecord()
+	require.NoError(t, err)
+	defer r.Release()
+	_, err = reader.(*Table).InsertRecord(ctx, r)
+	require.ErrorIs(t, err, ErrReadOnly)
+}
+
 // TestDBRecover verifies correct DB recovery with both a WAL and snapshots as
 // well as a block rotation (in which case no duplicate data should be in the
 // database).
@@ -2819,6 +3217,160 @@ func TestDBWatermarkBubbling(t *testing.T) {
 	}, time.Second, 10*time.Millisecond)
 }
 
+func TestDBOnCommit(t *testing.T) {
+	c, err := New()
+	require.NoError(t, err)
+	defer c.Close()
+	db, err := c.DB(context.Background(), "test")
+	require.NoError(t, err)
+
+	var mtx sync.Mutex
+	committed := map[uint64][]string{}
+	db.OnCommit(func(tx uint64, tables []string) {
+		require.GreaterOrEqual(t, db.HighWatermark(), tx)
+		mtx.Lock()
+		defer mtx.Unlock()
+		committed[tx] = tables
+	})
+
+	const nTxns = 100
+	var wg sync.WaitGroup
+	wg.Add(nTxns)
+	for i := 0; i < nTxns; i++ {
+		_, _, commit := db.begin(fmt.Sprintf("table%d", i))
+		go func() {
+			defer wg.Done()
+			commit()
+		}()
+	}
+	wg.Wait()
+
+	require.Eventually(t, func() bool {
+		mtx.Lock()
+		defer mtx.Unlock()
+		return len(committed) == nTxns
+	}, time.Second, 10*time.Millisecond)
+	for i := 0; i < nTxns; i++ {
+		require.Equal(t, []string{fmt.Sprintf("table%d", i)}, committed[uint64(i+1)])
+	}
+
+	// Callbacks are called without holding the lock, so they can register
+	// other callbacks.
+	var once sync.Once
+	registered := make(chan struct{})
+	db.OnCommit(func(uint64, []string) {
+		once.Do(func() {
+			db.OnCommit(func(uint64, []string) {})
+			close(registered)
+		})
+	})
+	_, _, commit := db.begin("table")
+	commit()
+	select {
+	case <-registered:
+	case <-time.After(time.Second):
+		t.Fatal("callback was not called")
+	}
+}
+
+func TestDBOnCommitInsert(t *testing.T) {
+	c, err := New()
+	require.NoError(t, err)
+	defer c.Close()
+	db, err := c.DB(context.Background(), "test")
+	require.NoError(t, err)
+	table, err := db.Table("test", NewTableConfig(dynparquet.SampleDefinition()))
+	require.NoError(t, err)
+
+	insert := func() uint64 {
+		r, err := dynparquet.GenerateTestSamples(1).ToRecord()
+		require.NoError(t, err)
+		defer r.Release()
+		tx, err := table.InsertRecord(context.Background(), r)
+		require.NoError(t, err)
+		return tx
+	}
+
+	// A callback inserting into the database must not deadlock. The commit of
+	// its insert is delivered once the callback returned.
+	var mtx sync.Mutex
+	var committed []uint64
+	var once sync.Once
+	callbackTx := make(chan uint64, 1)
+	db.OnCommit(func(tx uint64, _ []string) {
+		mtx.Lock()
+		committed = append(committed, tx)
+		mtx.Unlock()
+		once.Do(func() {
+			callbackTx <- insert()
+		})
+	})
+
+	insert()
+	var tx uint64
+	select {
+	case tx = <-callbackTx:
+	case <-time.After(5 * time.Second):
+		t.Fatal("callback did not return")
+	}
+	require.Eventually(t, func() bool {
+		mtx.Lock()
+		defer mtx.Unlock()
+		return len(committed) > 0 && committed[len(committed)-1] == tx
+	}, time.Second, 10*time.Millisecond)
+	mtx.Lock()
+	defer mtx.Unlock()
+	require.True(t, slices.IsSorted(committed))
+}
+
+func TestDBOnCommitWAL(t *testing.T) {
+	c, err := New(
+		WithLogger(newTestLogger(t)),
+		WithWAL(),
+		WithStoragePath(t.TempDir()),
+	)
+	require.NoError(t, err)
+	defer c.Close()
+	db, err := c.DB(context.Background(), "test")
+	require.NoError(t, err)
+	table, err := db.Table("test", NewTableConfig(dynparquet.SampleDefinition()))
+	require.NoError(t, err)
+
+	// Commit callbacks are only invoked once the txn's WAL record is durable,
+	// and in txn order.
+	var mtx sync.Mutex
+	var committed []uint64
+	var notDurable []uint64
+	db.OnCommit(func(tx uint64, _ []string) {
+		lastIndex, err := db.wal.LastIndex()
+		mtx.Lock()
+		defer mtx.Unlock()
+		if err != nil || lastIndex < tx {
+			notDurable = append(notDurable, tx)
+		}
+		committed = append(committed, tx)
+	})
+
+	var lastTx uint64
+	for i := 0; i < 10; i++ {
+		r, err := dynparquet.GenerateTestSamples(5).ToRecord()
+		require.NoError(t, err)
+		lastTx, err = table.InsertRecord(context.Background(), r)
+		r.Release()
+		require.NoError(t, err)
+	}
+
+	require.Eventually(t, func() bool {
+		mtx.Lock()
+		defer mtx.Unlock()
+		return len(committed) > 0 && committed[len(committed)-1] == lastTx
+	}, 5*time.Second, 10*time.Millisecond)
+	mtx.Lock()
+	defer mtx.Unlock()
+	require.Empty(t, notDurable)
+	require.True(t, slices.IsSorted(committed))
+}
+
 // Test_DB_SnapshotNewerData verifies that newer data that is compacted doesn't cause duplicate or loss of data on replay.
 func Test_DB_SnapshotNewerData(t *testing.T) {
 	t.Parallel()
@@ -3349,3 +3901,96 @@ func Test_DB_Sample(t *testing.T) {
 	require.NoError(t, err)
 	require.Equal(t, sampleSize, rows)
 }
+
+func TestColumnStoreMetricsReporter(t *testing.T) {
+	ctx := context.Background()
+	var (
+		mtx      sync.Mutex
+		reported []Metric
+	)
+	c, err := New(
+		WithLogger(newTestLogger(t)),
+		WithMetricsReporter(func(metrics []Metric) {
+			mtx.Lock()
+			defer mtx.Unlock()
+			reported = metrics
+		}, 10*time.Millisecond),
+	)
+	require.NoError(t, err)
+	defer c.Close()
+
+	db, err := c.DB(ctx, "test")
+	require.NoError(t, err)
+	table, err := db.Table("test", NewTableConfig(dynparquet.SampleDefinition()))
+	require.NoError(t, err)
+
+	samples := dynparquet.NewTestSamples()
+	r, err := samples.ToRecord()
+	require.NoError(t, err)
+	defer r.Release()
+	_, err = table.InsertRecord(ctx, r)
+	require.NoError(t, err)
+
+	findRowsInserted := func() (Metric, bool) {
+		mtx.Lock()
+		defer mtx.Unlock()
+		for _, m := range reported {
+			if m.Name == "frostdb_table_rows_inserted_total" &&
+				m.Labels["db"] == "test" && m.Labels["table"] == "test" {
+				return m, true
+			}
+		}
+		return Metric{}, false
+	}
+
+	// Metrics are reported periodically.
+	require.Eventually(t, func() bool {
+		m, ok := findRowsInserted()
+		return ok && m.Value == float64(len(samples))
+	}, time.Second, 10*time.Millisecond)
+
+	// Metrics can also be reported on demand.
+	_, err = table.InsertRecord(ctx, r)
+	require.NoError(t, err)
+	require.NoError(t, c.ReportMetrics())
+	m, ok := findRowsInserted()
+	require.True(t, ok)
+	require.Equal(t, MetricTypeCounter, m.Type)
+	require.GreaterOrEqual(t, m.Value, float64(2*len(samples)))
+
+	// Metrics are reported when the registry cannot be gathered from, and
+	// the embedder's own metrics are not reported.
+	reg := prometheus.NewRegistry()
+	embedderCounter := prometheus.NewCounter(prometheus.CounterOpts{Name: "embedder_total"})
+	reg.MustRegister(embedderCounter)
+	c2, err := New(
+		WithLogger(newTestLogger(t)),
+		WithRegistry(prometheus.WrapRegistererWithPrefix("test_", reg)),
+		WithMetricsReporter(func(metrics []Metric) {
+			mtx.Lock()
+			defer mtx.Unlock()
+			reported = metrics
+		}, 0),
+	)
+	require.NoError(t, err)
+	defer c2.Close()
+	require.NoError(t, c2.ReportMetrics())
+	mtx.Lock()
+	require.NotEmpty(t, reported)
+	for _, m := range reported {
+		require.NotEqual(t, "embedder_total", m.Name)
+		require.NotContains(t, m.Name, "test_")
+	}
+	mtx.Unlock()
+
+	// The metrics are still registered with the embedder's registry.
+	families, err := reg.Gather()
+	require.NoError(t, err)
+	var found bool
+	for _, f := range families {
+		if f.GetName() == "test_frostdb_shutdown_started" {
+			found = true
+		}
+	}
+	require.True(t, found)
+}
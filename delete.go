@@ -0,0 +1,760 @@
+package frostdb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+
+	"github.com/apache/arrow/go/v17/arrow"
+	"github.com/apache/arrow/go/v17/arrow/memory"
+	"github.com/oklog/ulid/v2"
+	"github.com/parquet-go/parquet-go"
+
+	"github.com/polarsignals/frostdb/dynparquet"
+	storagepb "github.com/polarsignals/frostdb/gen/proto/go/frostdb/storage/v1alpha1"
+	walpb "github.com/polarsignals/frostdb/gen/proto/go/frostdb/wal/v1alpha1"
+	"github.com/polarsignals/frostdb/index"
+	"github.com/polarsignals/frostdb/parts"
+	"github.com/polarsignals/frostdb/pqarrow"
+	"github.com/polarsignals/frostdb/query/expr"
+	"github.com/polarsignals/frostdb/query/exprpb"
+	"github.com/polarsignals/frostdb/query/logicalplan"
+	"github.com/polarsignals/frostdb/query/physicalplan"
+)
+
+const tombstonesPath = "tombstones"
+
+// tombstone marks all rows matching filter that were committed at or before
+// tx as deleted.
+//
+// In-memory data is filtered based on the tx of its parts, and blocks that
+// are persisted after the delete are written without the deleted rows. Blocks
+// with a ULID before block were rotated before the delete, so all of their
+// rows precede it and the filter is applied when they are read from storage.
+type tombstone struct {
+	id     uint64
+	tx     uint64
+	block  ulid.ULID
+	filter logicalplan.Expr
+}
+
+// fileName returns the name of the file the tombstone is persisted in.
+func (ts tombstone) fileName() string {
+	return fmt.Sprintf("%020d-%d-%s", ts.tx, ts.id, ts.block)
+}
+
+func (db *DB) tombstonesDir() string {
+	return filepath.Join(db.storagePath, tombstonesPath)
+}
+
+// BlockRewriter is implemented by data sinks that can rewrite persisted
+// blocks.
+type BlockRewriter interface {
+	// RewriteBlocks calls rewrite for every block under prefix. It returns
+	// the number of rewritten blocks.
+	RewriteBlocks(ctx context.Context, prefix string, rewrite BlockRewriteFunc) (int, error)
+}
+
+// BlockRewriteFunc rewrites the block with the given ID. If it returns true,
+// the block is replaced with the data written to w, or deleted if nothing was
+// written.
+type BlockRewriteFunc func(ctx context.Context, id ulid.ULID, buf *dynparquet.SerializedBuffer, w io.Writer) (bool, error)
+
+// Delete deletes all rows of the table that match the given filter expression
+// and were committed before Delete was called. Deleted rows are hidden from
+// queries immediately and removed from the table's active and pending blocks
+// when they are compacted or persisted.
+//
+// Rows in blocks that have already been persisted are filtered out when they
+// are read, until CompactTombstones rewrites these blocks. On tables that
+// dual-read v1alpha1 data, rows of v1alpha1 blocks are matched against the
+// filter after they are migrated to the current schema. These blocks are not
+// rewritten, so their deleted rows are filtered out on every read and the
+// tombstones are kept as long as such blocks exist.
+//
+// Deletes are logged to the WAL, so that they are replayed on recovery and
+// shipped to standbys.
+func (t *Table) Delete(ctx context.Context, filterExpr logicalplan.Expr) error {
+	if t.db.columnStore.readOnly {
+		return ErrReadOnly
+	}
+	if err := t.db.Quarantined(); err != nil {
+		return err
+	}
+
+	// Validate the filter before storing it, so that an invalid filter
+	// cannot break subsequent reads.
+	if _, err := physicalplan.Filter(t.db.columnStore.allocator, t.tracer, filterExpr); err != nil {
+		return fmt.Errorf("invalid delete filter: %w", err)
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	if err := t.addTombstone(filterExpr); err != nil {
+		return err
+	}
+
+	for _, so := range t.sortOrderTables() {
+		if err := so.Delete(ctx, filterExpr); err != nil {
+			return fmt.Errorf("delete from sort order table %s: %w", so.name, err)
+		}
+	}
+	return nil
+}
+
+// addTombstone logs and adds a tombstone for the given filter. The table lock
+// is held while the tombstone is added, so that the active block cannot be
+// rotated and persisted before the tombstone is visible to the rewrite of its
+// parts.
+func (t *Table) addTombstone(filterExpr logicalplan.Expr) error {
+	t.mtx.RLock()
+	defer t.mtx.RUnlock()
+	if t.closing {
+		return ErrTableClosing
+	}
+
+	t.tombstonesMtx.Lock()
+	defer t.tombstonesMtx.Unlock()
+
+	ts := tombstone{
+		id:     t.nextTombstoneID,
+		tx:     t.db.beginRead(),
+		filter: filterExpr,
+	}
+	if t.active != nil {
+		ts.block = t.active.ulid
+	} else {
+		// All blocks of a read-only table are persisted.
+		ts.block = generateULID(t.db.columnStore.clock)
+	}
+	if err := t.logTombstone(ts); err != nil {
+		return fmt.Errorf("log tombstone: %w", err)
+	}
+	if t.db.storagePath != "" {
+		if err := t.persistTombstone(ts); err != nil {
+			return fmt.Errorf("persist tombstone: %w", err)
+		}
+	}
+	t.tombstones = append(t.tombstones, ts)
+	t.nextTombstoneID++
+	t.dataVersion.Add(1)
+	return nil
+}
+
+// logTombstone logs the given tombstone to the WAL in a new transaction.
+func (t *Table) logTombstone(ts tombstone) error {
+	filter, err := exprpb.ExprToProto(ts.filter)
+	if err != nil {
+		return err
+	}
+	block, err := ts.block.MarshalBinary()
+	if err != nil {
+		return err
+	}
+
+	tx, _, commit := t.db.begin(t.name)
+	defer commit()
+	return t.wal.Log(tx, &walpb.Record{
+		Entry: &walpb.Entry{
+			EntryType: &walpb.Entry_Delete_{
+				Delete: &walpb.Entry_Delete{
+					TableName:   t.name,
+					TombstoneId: ts.id,
+					Tx:          ts.tx,
+					BlockId:     block,
+					Filter:      filter,
+				},
+			},
+		},
+	})
+}
+
+// replayTombstone adds the tombstone of a logged delete during WAL replay.
+// Tombstones that were persisted before are not added again. A tombstone
+// that was removed after it was logged is added again, which is harmless
+// since the rows it deletes have been removed already.
+func (t *Table) replayTombstone(entry *walpb.Entry_Delete) error {
+	filter, err := exprpb.ExprFromProto(entry.Filter)
+	if err != nil {
+		return err
+	}
+	ts := tombstone{
+		id:     entry.TombstoneId,
+		tx:     entry.Tx,
+		filter: filter,
+	}
+	if err := ts.block.UnmarshalBinary(entry.BlockId); err != nil {
+		return err
+	}
+
+	t.tombstonesMtx.Lock()
+	defer t.tombstonesMtx.Unlock()
+	for _, existing := range t.tombstones {
+		if existing.id != ts.id {
+			continue
+		}
+		if existing.tx == ts.tx {
+			return nil
+		}
+		// The ID was reused after the logged tombstone was removed.
+		ts.id = t.nextTombstoneID
+		break
+	}
+	if t.db.storagePath != "" {
+		if err := t.persistTombstone(ts); err != nil {
+			return fmt.Errorf("persist tombstone: %w", err)
+		}
+	}
+	t.tombstones = append(t.tombstones, ts)
+	t.nextTombstoneID = max(t.nextTombstoneID, ts.id+1)
+	return nil
+}
+
+// persistTombstone writes the given tombstone to
+// <db.tombstonesDir>/<table.name>/<tombstone.fileName>.
+func (t *Table) persistTombstone(ts tombstone) error {
+	pb, err := exprpb.ExprToProto(ts.filter)
+	if err != nil {
+		return err
+	}
+	b, err := pb.MarshalVT()
+	if err != nil {
+		return err
+	}
+
+	dir := filepath.Join(t.db.tombstonesDir(), t.name)
+	if err := os.MkdirAll(dir, dirPerms); err != nil {
+		return err
+	}
+	name := filepath.Join(dir, ts.fileName())
+	tmp := name + ".tmp"
+	if err := os.WriteFile(tmp, b, filePerms); err != nil {
+		return err
+	}
+	return os.Rename(tmp, name)
+}
+
+// parseTombstoneFileName parses a file name returned by tombstone.fileName.
+func parseTombstoneFileName(name string) (tombstone, error) {
+	var (
+		ts    tombstone
+		block string
+	)
+	if _, err := fmt.Sscanf(name, "%d-%d-%s", &ts.tx, &ts.id, &block); err != nil {
+		return ts, err
+	}
+	id, err := ulid.Parse(block)
+	if err != nil {
+		return ts, err
+	}
+	ts.block = id
+	return ts, nil
+}
+
+// loadTombstones loads the tombstones persisted for this table.
+func (t *Table) loadTombstones() error {
+	if t.db.storagePath == "" {
+		return nil
+	}
+
+	dir := filepath.Join(t.db.tombstonesDir(), t.name)
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		return err
+	}
+
+	tombstones := make([]tombstone, 0, len(entries))
+	nextID := uint64(0)
+	for _, e := range entries {
+		if e.IsDir() || filepath.Ext(e.Name()) == ".tmp" {
+			continue
+		}
+		ts, err := parseTombstoneFileName(e.Name())
+		if err != nil {
+			return fmt.Errorf("parse tombstone file name %q: %w", e.Name(), err)
+		}
+		b, err := os.ReadFile(filepath.Join(dir, e.Name()))
+		if err != nil {
+			return err
+		}
+		pb := &storagepb.Expr{}
+		if err := pb.UnmarshalVT(b); err != nil {
+			return fmt.Errorf("unmarshal tombstone %q: %w", e.Name(), err)
+		}
+		ts.filter, err = exprpb.ExprFromProto(pb)
+		if err != nil {
+			return fmt.Errorf("tombstone %q: %w", e.Name(), err)
+		}
+		tombstones = append(tombstones, ts)
+		nextID = max(nextID, ts.id+1)
+	}
+	sort.SliceStable(tombstones, func(i, j int) bool {
+		return tombstones[i].id < tombstones[j].id
+	})
+
+	t.tombstonesMtx.Lock()
+	defer t.tombstonesMtx.Unlock()
+	t.tombstones = tombstones
+	t.nextTombstoneID = nextID
+	return nil
+}
+
+// resetTombstones sets the tx of all tombstones to 0, so that they only apply
+// to persisted blocks. This must be called once all in-memory data has been
+// persisted and transaction IDs are not going to be preserved, since
+// tombstones would otherwise apply to new writes after a restart.
+func (t *Table) resetTombstones() error {
+	t.tombstonesMtx.Lock()
+	defer t.tombstonesMtx.Unlock()
+
+	dir := filepath.Join(t.db.tombstonesDir(), t.name)
+	for i, ts := range t.tombstones {
+		t.tombstones[i].tx = 0
+		if t.db.storagePath == "" || ts.tx == 0 {
+			continue
+		}
+		if err := os.Rename(filepath.Join(dir, ts.fileName()), filepath.Join(dir, t.tombstones[i].fileName())); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// CompactTombstones rewrites the persisted blocks of the table that contain
+// rows deleted by Delete without these rows. Tombstones that no longer apply to
+// any data are removed afterwards. All data sinks of the database must
+// implement BlockRewriter.
+func (t *Table) CompactTombstones(ctx context.Context) error {
+	t.tombstonesMtx.RLock()
+	tombstones := slices.Clone(t.tombstones)
+	t.tombstonesMtx.RUnlock()
+
+	if len(tombstones) > 0 {
+		prefix := filepath.Join(t.db.name, t.name)
+		v1alpha1Blocks := false
+		for _, sink := range t.db.sinks {
+			rewriter, ok := sink.(BlockRewriter)
+			if !ok {
+				return fmt.Errorf("data sink %s does not support rewriting blocks", sink)
+			}
+			if _, err := rewriter.RewriteBlocks(ctx, prefix, func(_ context.Context, id ulid.ULID, buf *dynparquet.SerializedBuffer, w io.Writer) (bool, error) {
+				filter := tombstonesFilter(tombstones, func(ts tombstone) bool {
+					return id.Compare(ts.block) < 0
+				})
+				if filter != nil && t.hasV1Alpha1RowGroups(buf) {
+					// Blocks written under a v1alpha1 schema are not
+					// rewritten, so their deleted rows are only filtered out
+					// when they are read.
+					v1alpha1Blocks = true
+					return false, nil
+				}
+				return t.rewriteBlock(filter, buf, w)
+			}); err != nil {
+				return err
+			}
+		}
+		t.db.blockSchemas.invalidate(t.name)
+		if t.db.readOnlySources() {
+			// Blocks of read-only sources still contain the deleted rows.
+			return nil
+		}
+		// The tombstones are kept as long as they are needed to filter the
+		// deleted rows of v1alpha1 blocks.
+		if !v1alpha1Blocks {
+			if err := t.removeTombstones(tombstones); err != nil {
+				return err
+			}
+		}
+	}
+
+	for _, so := range t.sortOrderTables() {
+		if err := so.CompactTombstones(ctx); err != nil {
+			return fmt.Errorf("compact tombstones of sort order table %s: %w", so.name, err)
+		}
+	}
+	return nil
+}
+
+// readOnlySources returns true if the database reads from data sources that
+// are not also data sinks.
+func (db *DB) readOnlySources() bool {
+	for _, source := range db.sources {
+		sink, ok := source.(DataSink)
+		if !ok || !slices.Contains(db.sinks, sink) {
+			return true
+		}
+	}
+	return false
+}
+
+// removeTombstones removes the given tombstones once the persisted blocks they
+// apply to have been rewritten. Tombstones are kept as long as the block that
+// was active when they were added is still in memory, since its parts are
+// only rewritten when it is persisted.
+func (t *Table) removeTombstones(rewritten []tombstone) error {
+	memoryBlocks, _ := t.memoryBlocks()
+	var oldest ulid.ULID
+	for _, block := range memoryBlocks {
+		if oldest.Compare(ulid.ULID{}) == 0 || block.ulid.Compare(oldest) < 0 {
+			oldest = block.ulid
+		}
+		block.pendingReadersWg.Done()
+	}
+
+	remove := make(map[uint64]bool, len(rewritten))
+	for _, ts := range rewritten {
+		if len(memoryBlocks) == 0 || ts.block.Compare(oldest) < 0 {
+			remove[ts.id] = true
+		}
+	}
+
+	t.tombstonesMtx.Lock()
+	defer t.tombstonesMtx.Unlock()
+	dir := filepath.Join(t.db.tombstonesDir(), t.name)
+	kept := t.tombstones[:0]
+	for _, ts := range t.tombstones {
+		if !remove[ts.id] {
+			kept = append(kept, ts)
+			continue
+		}
+		if t.db.storagePath != "" {
+			if err := os.Remove(filepath.Join(dir, ts.fileName())); err != nil && !errors.Is(err, os.ErrNotExist) {
+				return err
+			}
+		}
+	}
+	t.tombstones = kept
+	return nil
+}
+
+// hasTombstones returns true if any rows of the table were deleted.
+func (t *Table) hasTombstones() bool {
+	t.tombstonesMtx.RLock()
+	defer t.tombstonesMtx.RUnlock()
+	return len(t.tombstones) > 0
+}
+
+// deleteFilter returns a filter matching all rows deleted by tombstones that
+// apply to in-memory data written at the given tx, or nil if there are none.
+func (t *Table) deleteFilter(tx uint64) logicalplan.Expr {
+	t.tombstonesMtx.RLock()
+	defer t.tombstonesMtx.RUnlock()
+	return tombstonesFilter(t.tombstones, func(ts tombstone) bool {
+		return ts.tx >= tx
+	})
+}
+
+// blockDeleteFilter returns a filter matching all rows deleted by tombstones
+// that apply to the given persisted block, or nil if there are none. Values
+// of sources that do not identify their block are passed as the zero ULID, to
+// which all tombstones apply.
+func (t *Table) blockDeleteFilter(block ulid.ULID) logicalplan.Expr {
+	t.tombstonesMtx.RLock()
+	defer t.tombstonesMtx.RUnlock()
+	return tombstonesFilter(t.tombstones, func(ts tombstone) bool {
+		return block.Compare(ts.block) < 0
+	})
+}
+
+// tombstonesFilter returns a filter matching all rows deleted by the
+// tombstones for which applies returns true, or nil if there are none.
+func tombstonesFilter(tombstones []tombstone, applies func(tombstone) bool) logicalplan.Expr {
+	var filters []logicalplan.Expr
+	for _, ts := range tombstones {
+		if applies(ts) {
+			filters = append(filters, ts.filter)
+		}
+	}
+	switch len(filters) {
+	case 0:
+		return nil
+	case 1:
+		return filters[0]
+	default:
+		return logicalplan.Or(filters...)
+	}
+}
+
+// rewritePart implements index.PartRewriter by removing all rows deleted by a
+// tombstone from the given part. Parts that only contain data older than the
+// table's retention window are dropped entirely. It returns nil if the part
+// is not affected.
+func (t *Table) rewritePart(p parts.Part) (arrow.Record, error) {
+	if cutoff, ok := t.retentionCutoff(); ok {
+		expired, err := t.partExpired(p, cutoff)
+		if err != nil {
+			return nil, err
+		}
+		if expired {
+			return expiredRecord(), nil
+		}
+	}
+
+	filter := t.deleteFilter(p.TX())
+	if filter == nil {
+		return nil, nil
+	}
+
+	pool := t.db.columnStore.allocator
+	if r := p.Record(); r != nil {
+		return t.removeDeletedRows(pool, filter, r)
+	}
+
+	buf, err := p.AsSerializedBuffer(t.schema.Load())
+	if err != nil {
+		return nil, err
+	}
+	booleanFilter, err := expr.BooleanExpr(filter)
+	if err != nil {
+		return nil, fmt.Errorf("boolean expr: %w", err)
+	}
+	affected := false
+	rowGroups := make([]parquet.RowGroup, 0, buf.NumRowGroups())
+	for i := 0; i < buf.NumRowGroups(); i++ {
+		rg := buf.DynamicRowGroup(i)
+		mayContainDeletedRows, err := booleanFilter.Eval(rg, false)
+		if err != nil {
+			return nil, err
+		}
+		affected = affected || mayContainDeletedRows
+		rowGroups = append(rowGroups, rg)
+	}
+	if !affected {
+		return nil, nil
+	}
+	return t.removeDeletedRowsFromRowGroups(pool, filter, rowGroups...)
+}
+
+// removeDeletedRowsFromV1Alpha1RowGroup converts the given row group written
+// under a v1alpha1 schema to a record of the current schema, like the table
+// iterator does when dual-reading, with all rows matching filter removed. It
+// returns nil if the row group contains no data.
+func (t *Table) removeDeletedRowsFromV1Alpha1RowGroup(pool memory.Allocator, filter logicalplan.Expr, rg parquet.RowGroup) (arrow.Record, error) {
+	converter := pqarrow.NewParquetConverter(pool, logicalplan.IterOptions{})
+	defer converter.Close()
+	if err := converter.Convert(context.Background(), rg, t.schema.Load()); err != nil {
+		return nil, fmt.Errorf("failed to convert row group to arrow record: %v", err)
+	}
+	r := converter.NewRecord()
+	if r == nil {
+		return nil, nil
+	}
+	defer r.Release()
+
+	migrated, err := pqarrow.MigrateRecord(r, t.config.Load().V1Alpha1FieldMapping)
+	if err != nil {
+		return nil, err
+	}
+	defer migrated.Release()
+
+	result, err := physicalplan.RemoveMatchingRows(pool, filter, migrated)
+	if err != nil {
+		return nil, fmt.Errorf("remove deleted rows: %w", err)
+	}
+	return result, nil
+}
+
+// hasV1Alpha1RowGroups returns true if any row group of the given block was
+// written under a v1alpha1 schema.
+func (t *Table) hasV1Alpha1RowGroups(buf *dynparquet.SerializedBuffer) bool {
+	for i := 0; i < buf.NumRowGroups(); i++ {
+		if t.isV1Alpha1RowGroup(buf.DynamicRowGroup(i)) {
+			return true
+		}
+	}
+	return false
+}
+
+// rewriteBlock writes the given block without the rows matching filter to w.
+// Row groups whose statistics show that they contain no matching rows are
+// written as is. It returns false if the block contains no matching rows.
+func (t *Table) rewriteBlock(filter logicalplan.Expr, buf *dynparquet.SerializedBuffer, w io.Writer) (bool, error) {
+	if filter == nil {
+		return false, nil
+	}
+	booleanFilter, err := expr.BooleanExpr(filter)
+	if err != nil {
+		return false, fmt.Errorf("boolean expr: %w", err)
+	}
+	// The schema is loaded once, so that the block is rewritten with a single
+	// schema even if the schema of the table is updated concurrently.
+	schema := t.schema.Load()
+
+	var records []arrow.Record
+	defer func() {
+		for _, r := range records {
+			r.Release()
+		}
+	}()
+	affected := false
+	rowGroups := make([]dynparquet.DynamicRowGroup, 0, buf.NumRowGroups())
+	for i := 0; i < buf.NumRowGroups(); i++ {
+		rg := buf.DynamicRowGroup(i)
+		mayContainDeletedRows, err := booleanFilter.Eval(rg, false)
+		if err != nil {
+			return false, err
+		}
+		if !mayContainDeletedRows {
+			rowGroups = append(rowGroups, rg)
+			continue
+		}
+
+		r, err := t.removeDeletedRowsFromRowGroups(t.db.columnStore.allocator, filter, rg)
+		if err != nil {
+			return false, err
+		}
+		if r == nil {
+			continue
+		}
+		if r.NumRows() == rg.NumRows() {
+			r.Release()
+			rowGroups = append(rowGroups, rg)
+			continue
+		}
+		affected = true
+		if r.NumRows() == 0 {
+			r.Release()
+			continue
+		}
+		records = append(records, r)
+		rbuf, err := parts.NewArrowPart(0, r, 0, schema).AsSerializedBuffer(schema)
+		if err != nil {
+			return false, err
+		}
+		rowGroups = append(rowGroups, rbuf.MultiDynamicRowGroup())
+	}
+	if !affected || len(rowGroups) == 0 {
+		return affected, nil
+	}
+
+	// Row groups of a block are sorted and do not overlap, which is retained
+	// when rows are removed from them.
+	merged, err := schema.MergeDynamicRowGroups(rowGroups, dynparquet.WithAlreadySorted())
+	if err != nil {
+		return false, err
+	}
+	options, err := schemaMetadataOptions(schema)
+	if err != nil {
+		return false, err
+	}
+	layout, err := t.layoutFingerprint()
+	if err != nil {
+		return false, err
+	}
+	if v, ok := buf.ParquetFile().Lookup(BlockLayoutKey); ok && v == layout {
+		// The block keeps its sort order and is written with the current
+		// row group size, so it keeps the current layout.
+		options = append(options, parquet.KeyValueMetadata(BlockLayoutKey, layout))
+	}
+	return true, t.writeMergedRowGroups(w, merged, options...)
+}
+
+// removeDeletedRows returns the record with all rows matching filter removed,
+// or nil if no rows match.
+func (t *Table) removeDeletedRows(pool memory.Allocator, filter logicalplan.Expr, r arrow.Record) (arrow.Record, error) {
+	result, err := physicalplan.RemoveMatchingRows(pool, filter, r)
+	if err != nil {
+		return nil, fmt.Errorf("remove deleted rows: %w", err)
+	}
+	if result == r {
+		result.Release()
+		return nil, nil
+	}
+	return result, nil
+}
+
+// removeDeletedRowsFromRowGroups converts the given row groups to a single
+// arrow record with all rows matching filter removed. It returns nil if the
+// row groups contain no data.
+func (t *Table) removeDeletedRowsFromRowGroups(pool memory.Allocator, filter logicalplan.Expr, rowGroups ...parquet.RowGroup) (arrow.Record, error) {
+	converter := pqarrow.NewParquetConverter(pool, logicalplan.IterOptions{})
+	defer converter.Close()
+	for _, rg := range rowGroups {
+		if err := converter.Convert(context.Background(), rg, t.schema.Load()); err != nil {
+			return nil, fmt.Errorf("failed to convert row group to arrow record: %v", err)
+		}
+	}
+	r := converter.NewRecord()
+	if r == nil {
+		return nil, nil
+	}
+	defer r.Release()
+
+	result, err := physicalplan.RemoveMatchingRows(pool, filter, r)
+	if err != nil {
+		return nil, fmt.Errorf("remove deleted rows: %w", err)
+	}
+	return result, nil
+}
+
+// removeDeletedRowsFromSource removes rows deleted by tombstones that apply
+// to the given block from a value scanned from a data source. Values that are
+// not affected are returned as is, and nil is returned if no rows remain. Row
+// groups are only converted if their statistics show that they may contain
+// deleted rows. Row groups written under a v1alpha1 schema are always
+// converted and migrated to the current schema, since the filter refers to
+// the columns of the current schema.
+func (t *Table) removeDeletedRowsFromSource(pool memory.Allocator, block ulid.ULID, v any) (any, error) {
+	filter := t.blockDeleteFilter(block)
+	if filter == nil {
+		return v, nil
+	}
+
+	var (
+		r   arrow.Record
+		err error
+	)
+	switch v := v.(type) {
+	case arrow.Record:
+		defer v.Release()
+		r, err = physicalplan.RemoveMatchingRows(pool, filter, v)
+	case parquet.RowGroup:
+		if t.isV1Alpha1RowGroup(v) {
+			if rrg, ok := v.(index.ReleaseableRowGroup); ok {
+				defer rrg.Release()
+			}
+			r, err = t.removeDeletedRowsFromV1Alpha1RowGroup(pool, filter, v)
+			break
+		}
+		booleanFilter, err := expr.BooleanExpr(filter)
+		if err != nil {
+			return nil, fmt.Errorf("boolean expr: %w", err)
+		}
+		mayContainDeletedRows, err := booleanFilter.Eval(v, false)
+		if err != nil {
+			return nil, err
+		}
+		if !mayContainDeletedRows {
+			return v, nil
+		}
+		if rrg, ok := v.(index.ReleaseableRowGroup); ok {
+			defer rrg.Release()
+		}
+		r, err = t.removeDeletedRowsFromRowGroups(pool, filter, v)
+		if err != nil {
+			return nil, err
+		}
+	default:
+		return v, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if r == nil {
+		return nil, nil
+	}
+	if r.NumRows() == 0 {
+		r.Release()
+		return nil, nil
+	}
+	return r, nil
+}
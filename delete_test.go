@@ -0,0 +1,201 @@
+package frostdb
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/thanos-io/objstore"
+
+	"github.com/polarsignals/frostdb/dynparquet"
+	"github.com/polarsignals/frostdb/query/logicalplan"
+)
+
+func TestTableDelete(t *testing.T) {
+	ctx := context.Background()
+	samples := dynparquet.Samples{
+		{ExampleType: "cpu", Labels: map[string]string{"label1": "a"}, Timestamp: 1, Value: 1},
+		{ExampleType: "cpu", Labels: map[string]string{"label1": "b"}, Timestamp: 2, Value: 2},
+		{ExampleType: "cpu", Labels: map[string]string{"label1": "a"}, Timestamp: 3, Value: 3},
+		{ExampleType: "cpu", Labels: map[string]string{"label1": "c"}, Timestamp: 4, Value: 4},
+	}
+	deleteFilter := logicalplan.Col("labels.label1").Eq(logicalplan.Literal("a"))
+
+	t.Run("InMemory", func(t *testing.T) {
+		c, table := basicTable(t)
+		defer c.Close()
+
+		insertSamples(t, table, samples)
+		require.NoError(t, table.Delete(ctx, deleteFilter))
+		require.Equal(t, map[string]int{"b": 1, "c": 1}, countRowsBy(t, table.db, "test", "labels.label1"))
+
+		// Rows written after the delete are not deleted.
+		insertSamples(t, table, samples[:1])
+		require.Equal(t, map[string]int{"a": 1, "b": 1, "c": 1}, countRowsBy(t, table.db, "test", "labels.label1"))
+
+		require.NoError(t, table.EnsureCompaction())
+		require.Equal(t, map[string]int{"a": 1, "b": 1, "c": 1}, countRowsBy(t, table.db, "test", "labels.label1"))
+	})
+
+	t.Run("InvalidFilter", func(t *testing.T) {
+		c, table := basicTable(t)
+		defer c.Close()
+
+		require.Error(t, table.Delete(ctx, logicalplan.Col("labels.label1")))
+	})
+
+	t.Run("Persisted", func(t *testing.T) {
+		dir := t.TempDir()
+		bucket := objstore.NewInMemBucket()
+		options := []Option{
+			WithWAL(),
+			WithStoragePath(dir),
+			WithReadWriteStorage(NewDefaultObjstoreBucket(bucket)),
+		}
+
+		c, _, table := openTestTable(t, options)
+		insertSamples(t, table, samples)
+		require.NoError(t, table.Delete(ctx, deleteFilter))
+		// Closing the column store persists the active block without the
+		// deleted rows.
+		require.NoError(t, c.Close())
+
+		c, _, table = openTestTable(t, options)
+		require.Equal(t, map[string]int{"b": 1, "c": 1}, countRowsBy(t, table.db, "test", "labels.label1"))
+
+		// Delete rows of a block that was already persisted.
+		require.NoError(t, table.Delete(ctx, logicalplan.Col("labels.label1").Eq(logicalplan.Literal("b"))))
+		require.Equal(t, map[string]int{"c": 1}, countRowsBy(t, table.db, "test", "labels.label1"))
+		require.NoError(t, c.Close())
+
+		// The tombstones are kept across restarts.
+		entries, err := os.ReadDir(filepath.Join(dir, "databases", "test", tombstonesPath, "test"))
+		require.NoError(t, err)
+		require.Len(t, entries, 2)
+
+		c, _, table = openTestTable(t, options)
+		defer c.Close()
+		require.Equal(t, map[string]int{"c": 1}, countRowsBy(t, table.db, "test", "labels.label1"))
+
+		// Tombstones of a previous session do not apply to new writes.
+		insertSamples(t, table, samples)
+		require.Equal(t, map[string]int{"a": 2, "b": 1, "c": 2}, countRowsBy(t, table.db, "test", "labels.label1"))
+	})
+
+	t.Run("PersistedAfterDelete", func(t *testing.T) {
+		dir := t.TempDir()
+		bucket := objstore.NewInMemBucket()
+		options := []Option{
+			WithWAL(),
+			WithStoragePath(dir),
+			WithReadWriteStorage(NewDefaultObjstoreBucket(bucket)),
+		}
+
+		c, _, table := openTestTable(t, options)
+		insertSamples(t, table, samples)
+		persistActiveBlock(t, table)
+		require.NoError(t, table.Delete(ctx, deleteFilter))
+
+		// Rows written after the delete are kept once they are persisted,
+		// both in the block that was active during the delete and in later
+		// blocks.
+		insertSamples(t, table, samples[:1])
+		persistActiveBlock(t, table)
+		insertSamples(t, table, samples[:1])
+		persistActiveBlock(t, table)
+		expected := map[string]int{"a": 2, "b": 1, "c": 1}
+		require.Equal(t, expected, countRowsBy(t, table.db, "test", "labels.label1"))
+		require.NoError(t, c.Close())
+
+		c, _, table = openTestTable(t, options)
+		defer c.Close()
+		require.Equal(t, expected, countRowsBy(t, table.db, "test", "labels.label1"))
+
+		// Compacting the tombstones rewrites the block persisted before the
+		// delete and removes the tombstone.
+		require.NoError(t, table.CompactTombstones(ctx))
+		require.False(t, table.hasTombstones())
+		entries, err := os.ReadDir(filepath.Join(dir, "databases", "test", tombstonesPath, "test"))
+		require.NoError(t, err)
+		require.Empty(t, entries)
+		require.Equal(t, expected, countRowsBy(t, table.db, "test", "labels.label1"))
+
+		// Blocks are only rewritten if they contain deleted rows.
+		require.NoError(t, table.Delete(ctx, logicalplan.Col("labels.label1").Eq(logicalplan.Literal("d"))))
+		require.NoError(t, table.CompactTombstones(ctx))
+		require.Equal(t, expected, countRowsBy(t, table.db, "test", "labels.label1"))
+	})
+
+	t.Run("TombstonesKeptForActiveBlock", func(t *testing.T) {
+		c, _, table := openTestTable(t, []Option{
+			WithReadWriteStorage(NewDefaultObjstoreBucket(objstore.NewInMemBucket())),
+		})
+		defer c.Close()
+
+		insertSamples(t, table, samples)
+		require.NoError(t, table.Delete(ctx, deleteFilter))
+		// The active block still contains the deleted rows, so the tombstone
+		// must be kept until it is persisted.
+		require.NoError(t, table.CompactTombstones(ctx))
+		require.True(t, table.hasTombstones())
+		require.Equal(t, map[string]int{"b": 1, "c": 1}, countRowsBy(t, table.db, "test", "labels.label1"))
+	})
+
+	t.Run("Replayed", func(t *testing.T) {
+		dir := t.TempDir()
+		options := []Option{
+			WithWAL(),
+			WithStoragePath(dir),
+		}
+
+		c, _, table := openTestTable(t, options)
+		insertSamples(t, table, samples)
+		require.NoError(t, table.Delete(ctx, deleteFilter))
+		require.NoError(t, c.Close())
+
+		// The delete is recovered from the WAL without its tombstone file.
+		require.NoError(t, os.RemoveAll(filepath.Join(dir, "databases", "test", tombstonesPath)))
+		c, _, table = openTestTable(t, options)
+		defer c.Close()
+		require.Equal(t, map[string]int{"b": 1, "c": 1}, countRowsBy(t, table.db, "test", "labels.label1"))
+	})
+
+	t.Run("V1Alpha1", func(t *testing.T) {
+		bucket := NewDefaultObjstoreBucket(objstore.NewInMemBucket())
+		c, _, table := openTestTable(t, []Option{WithReadWriteStorage(bucket)})
+		insertSamples(t, table, samples)
+		persistActiveBlock(t, table)
+		require.NoError(t, c.Close())
+
+		// Read the v1alpha1 block with the equivalent v1alpha2 schema.
+		mapping := dynparquet.FieldMapping{}
+		def, err := dynparquet.MigrateV1Alpha1Schema(dynparquet.SampleDefinition(), mapping, map[string][]string{
+			"labels": {"label1", "label2", "label3", "label4"},
+		})
+		require.NoError(t, err)
+		c, db := openTestDB(t, WithReadWriteStorage(bucket))
+		defer c.Close()
+		table, err = db.Table("test", NewTableConfig(def, WithV1Alpha1DualRead(mapping)))
+		require.NoError(t, err)
+		require.Equal(t, int64(4), countRows(t, db, "test"))
+
+		require.NoError(t, table.Delete(ctx, logicalplan.Col("timestamp").Lt(logicalplan.Literal(int64(3)))))
+		require.Equal(t, int64(2), countRows(t, db, "test"))
+
+		// The v1alpha1 block is not rewritten, so the tombstone is kept to
+		// filter its deleted rows.
+		require.NoError(t, table.CompactTombstones(ctx))
+		require.True(t, table.hasTombstones())
+		require.Equal(t, int64(2), countRows(t, db, "test"))
+	})
+
+	t.Run("ReadOnly", func(t *testing.T) {
+		c, table := basicTable(t)
+		defer c.Close()
+		c.readOnly = true
+
+		require.ErrorIs(t, table.Delete(ctx, deleteFilter), ErrReadOnly)
+	})
+}
@@ -0,0 +1,241 @@
+package frostdb
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/go-kit/log/level"
+	"github.com/thanos-io/objstore"
+)
+
+// PrefixDeleter is implemented by data sinks that can delete all objects of
+// a table or database.
+type PrefixDeleter interface {
+	// DeletePrefix deletes all objects under prefix, which is either the
+	// prefix of a database, "<db>", or of a table, "<db>/<table>". It returns
+	// the number of deleted objects.
+	DeletePrefix(ctx context.Context, prefix string) (int, error)
+}
+
+// DropOption is an option for DB.DropTable and ColumnStore.DropDB.
+type DropOption func(*dropOptions)
+
+type dropOptions struct {
+	purgeStorage bool
+}
+
+// WithPurgeStorage also deletes all objects of the dropped table or database
+// from the data sinks implementing PrefixDeleter. Without it, persisted blocks
+// are kept.
+func WithPurgeStorage() DropOption {
+	return func(o *dropOptions) {
+		o.purgeStorage = true
+	}
+}
+
+// DropTable removes the table, along with the tables storing its sort orders,
+// from the database. Its in-memory data is discarded, the series of its
+// metrics are removed and its tombstones and index files are deleted. If the
+// WAL is enabled, a snapshot that no longer contains the table is taken, and
+// the WAL records and snapshots before it are removed, so that the table is
+// not recovered.
+func (db *DB) DropTable(ctx context.Context, name string, options ...DropOption) error {
+	if db.columnStore.readOnly {
+		return ErrReadOnly
+	}
+	if err := db.Quarantined(); err != nil {
+		return err
+	}
+	opts := &dropOptions{}
+	for _, o := range options {
+		o(opts)
+	}
+
+	table, err := db.GetTable(name)
+	if err != nil {
+		return err
+	}
+	tables := append([]*Table{table}, table.sortOrderTables()...)
+
+	db.mtx.Lock()
+	if db.tables[name] != table {
+		// Dropped concurrently.
+		db.mtx.Unlock()
+		return ErrTableNotFound{TableName: name}
+	}
+	for _, t := range tables {
+		delete(db.tables, t.name)
+		delete(db.roTables, t.name)
+	}
+	db.mtx.Unlock()
+
+	for _, t := range tables {
+		if err := t.drop(ctx); err != nil {
+			return fmt.Errorf("drop table %s: %w", t.name, err)
+		}
+		db.blockSchemas.invalidate(t.name)
+	}
+
+	if db.columnStore.enableWAL {
+		if err := db.snapshotDropped(ctx); err != nil {
+			return err
+		}
+	}
+
+	if opts.purgeStorage {
+		for _, t := range tables {
+			if err := db.purgePrefix(ctx, filepath.Join(db.name, t.name)); err != nil {
+				return err
+			}
+		}
+	}
+	level.Info(db.logger).Log("msg", "dropped table", "table", name)
+	return nil
+}
+
+// drop closes the table after it was removed from its database, discarding
+// its in-memory data and deleting its local files and the series of its
+// metrics.
+func (t *Table) drop(ctx context.Context) error {
+	t.close()
+	if err := waitForBlocks(ctx, t.discardPendingBlocks()); err != nil {
+		return err
+	}
+
+	t.mtx.RLock()
+	active := t.active
+	t.mtx.RUnlock()
+	if active != nil {
+		active.pendingReadersWg.Wait()
+		if err := active.index.Close(); err != nil {
+			level.Error(t.logger).Log("msg", "failed to close index", "err", err)
+		}
+	}
+
+	if t.db.storagePath != "" {
+		for _, dir := range []string{
+			filepath.Join(t.db.tombstonesDir(), t.name),
+			filepath.Join(t.db.indexDir(), t.name),
+		} {
+			if err := os.RemoveAll(dir); err != nil {
+				return err
+			}
+		}
+	}
+	t.db.metricsProvider.deleteMetricsForTable(t.name)
+	return nil
+}
+
+// snapshotDropped takes a snapshot once tables were dropped and removes the
+// WAL records and snapshots before it, which still contain the tables.
+func (db *DB) snapshotDropped(ctx context.Context) error {
+	snapshotted := false
+	db.snapshot(ctx, false, func() {
+		snapshotted = true
+	})
+	if !snapshotted {
+		return fmt.Errorf("failed to snapshot db %s: dropped tables may be recovered from the WAL", db.name)
+	}
+	return db.reclaimDiskSpace(ctx, nil)
+}
+
+// purgePrefix deletes all objects under prefix from the data sinks
+// implementing PrefixDeleter.
+func (db *DB) purgePrefix(ctx context.Context, prefix string) error {
+	for _, sink := range db.sinks {
+		deleter, ok := sink.(PrefixDeleter)
+		if !ok {
+			continue
+		}
+		n, err := deleter.DeletePrefix(ctx, prefix)
+		if err != nil {
+			return fmt.Errorf("purge %s from %s: %w", prefix, sink, err)
+		}
+		level.Debug(db.logger).Log("msg", "purged storage", "prefix", prefix, "sink", sink.String(), "objects", n)
+	}
+	return nil
+}
+
+// DeletePrefix implements the PrefixDeleter interface. The manifests and the
+// lease of a table are deleted along with its blocks, and the table is
+// removed from the block index of its database. If leases are enabled, the
+// lease of a table is acquired first, the tables of a database must not be
+// written by other writers while it is deleted.
+func (b *DefaultObjstoreBucket) DeletePrefix(ctx context.Context, prefix string) (int, error) {
+	prefix = filepath.Clean(prefix)
+	db, table := filepath.Split(prefix)
+	db = filepath.Clean(db)
+	isTable := db != "." && !strings.Contains(db, "/")
+	if isTable && b.leases != nil {
+		if err := b.leases.acquireTable(ctx, b, prefix); err != nil {
+			return 0, err
+		}
+	}
+
+	var names []string
+	dirs := []string{prefix}
+	if isTable {
+		dirs = append(dirs, manifestDir(prefix))
+	}
+	for _, dir := range dirs {
+		if err := b.Bucket.Iter(ctx, dir+"/", func(name string) error {
+			names = append(names, name)
+			return nil
+		}, objstore.WithRecursiveIter); err != nil {
+			return 0, err
+		}
+	}
+	if isTable && b.leases != nil {
+		// The lease is deleted last, so that no other writer takes over
+		// the table while it is deleted.
+		names = append(names, leaseName(prefix))
+	}
+
+	n := 0
+	for _, name := range names {
+		if err := b.Bucket.Delete(ctx, name); err != nil {
+			if b.IsObjNotFoundErr(err) {
+				continue
+			}
+			return n, err
+		}
+		if filepath.Base(name) == "data.parquet" {
+			b.forgetBlock(filepath.Dir(name))
+		}
+		n++
+	}
+
+	b.manifestsMtx.Lock()
+	for name := range b.manifests {
+		if name == prefix || strings.HasPrefix(name, prefix+"/") {
+			delete(b.manifests, name)
+		}
+	}
+	b.manifestsMtx.Unlock()
+	if b.leases != nil {
+		b.leases.forget(prefix)
+	}
+
+	if b.blockIndexEnabled {
+		b.blockIndexesMtx.Lock()
+		defer b.blockIndexesMtx.Unlock()
+		if !isTable {
+			delete(b.blockIndexes, prefix)
+			return n, nil
+		}
+		index, err := b.loadBlockIndex(ctx, db)
+		if err != nil {
+			return n, err
+		}
+		if _, ok := index.tables[table]; ok {
+			delete(index.tables, table)
+			if err := b.writeBlockIndex(ctx, db, index); err != nil {
+				return n, err
+			}
+		}
+	}
+	return n, nil
+}
@@ -0,0 +1,81 @@
+package frostdb
+
+import (
+	"context"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+	"github.com/thanos-io/objstore"
+
+	"github.com/polarsignals/frostdb/dynparquet"
+)
+
+func TestDrop(t *testing.T) {
+	ctx := context.Background()
+	config := NewTableConfig(dynparquet.SampleDefinition())
+	countObjects := func(t *testing.T, bucket objstore.Bucket, prefix string) int {
+		t.Helper()
+		n := 0
+		require.NoError(t, bucket.Iter(ctx, prefix, func(string) error {
+			n++
+			return nil
+		}, objstore.WithRecursiveIter))
+		return n
+	}
+
+	t.Run("Table", func(t *testing.T) {
+		dir := t.TempDir()
+		reg := prometheus.NewRegistry()
+		c, db := openTestDB(t, WithRegistry(reg), WithWAL(), WithStoragePath(dir))
+		dropped, err := db.Table("dropped", config)
+		require.NoError(t, err)
+		kept, err := db.Table("kept", config)
+		require.NoError(t, err)
+		insertSamples(t, dropped, dynparquet.GenerateTestSamples(5))
+		insertSamples(t, kept, dynparquet.GenerateTestSamples(10))
+		require.Equal(t, 2, testutil.CollectAndCount(reg, "frostdb_table_rows_inserted_total"))
+
+		require.NoError(t, db.DropTable(ctx, "dropped"))
+		_, err = db.GetTable("dropped")
+		require.ErrorAs(t, err, &ErrTableNotFound{})
+		require.ErrorAs(t, db.DropTable(ctx, "dropped"), &ErrTableNotFound{})
+		require.Equal(t, []string{"kept"}, db.TableNames())
+		require.Equal(t, 1, testutil.CollectAndCount(reg, "frostdb_table_rows_inserted_total"))
+		require.NoError(t, c.Close())
+
+		// The dropped table is not recovered.
+		c, db = openTestDB(t, WithRegistry(prometheus.NewRegistry()), WithWAL(), WithStoragePath(dir))
+		defer c.Close()
+		require.Equal(t, []string{"kept"}, db.TableNames())
+		require.Equal(t, int64(10), countRows(t, db, "kept"))
+	})
+
+	t.Run("PurgeStorage", func(t *testing.T) {
+		bucket := objstore.NewInMemBucket()
+		reg := prometheus.NewRegistry()
+		c, db := openTestDB(
+			t,
+			WithRegistry(reg),
+			WithReadWriteStorage(NewDefaultObjstoreBucket(bucket, StorageWithManifests(true))),
+		)
+		defer c.Close()
+		for _, name := range []string{"first", "second"} {
+			table, err := db.Table(name, config)
+			require.NoError(t, err)
+			insertSamples(t, table, dynparquet.GenerateTestSamples(10))
+			persistActiveBlock(t, table)
+			require.NotZero(t, countObjects(t, bucket, "test/"+name+"/"))
+		}
+
+		require.NoError(t, db.DropTable(ctx, "first", WithPurgeStorage()))
+		require.Zero(t, countObjects(t, bucket, "test/first/"))
+		require.Zero(t, countObjects(t, bucket, "test/first"+ManifestSuffix+"/"))
+		require.NotZero(t, countObjects(t, bucket, "test/second/"))
+
+		require.NoError(t, c.DropDB("test", WithPurgeStorage()))
+		require.Zero(t, countObjects(t, bucket, ""))
+		require.Zero(t, testutil.CollectAndCount(reg, "frostdb_table_rows_inserted_total"))
+	})
+}
@@ -0,0 +1,153 @@
+package dynparquet
+
+import (
+	"fmt"
+	"strings"
+
+	"google.golang.org/protobuf/proto"
+
+	schemapb "github.com/polarsignals/frostdb/gen/proto/go/frostdb/schema/v1alpha1"
+)
+
+// Compatibility classifies a schema change by what it takes to apply it to a
+// table that already contains data.
+type Compatibility int
+
+const (
+	// Compatible changes can be applied to existing data as is, e.g. adding
+	// a nullable column.
+	Compatible Compatibility = iota
+	// RequiresRewrite changes can only be applied to existing data once it
+	// is rewritten to match the new schema, e.g. changing the sorting
+	// columns.
+	RequiresRewrite
+	// Breaking changes make existing data unreadable with the new schema,
+	// e.g. removing a column or changing its type.
+	Breaking
+)
+
+func (c Compatibility) String() string {
+	switch c {
+	case Compatible:
+		return "compatible"
+	case RequiresRewrite:
+		return "requires rewrite"
+	case Breaking:
+		return "breaking"
+	default:
+		return fmt.Sprintf("Compatibility(%d)", int(c))
+	}
+}
+
+// SchemaChange describes the changes from one schema to another.
+type SchemaChange struct {
+	// Compatibility is the compatibility of the least compatible change.
+	Compatibility Compatibility
+	// Changes describe the individual changes that are not compatible.
+	Changes []string
+}
+
+func (c *SchemaChange) add(compatibility Compatibility, format string, args ...any) {
+	c.Compatibility = max(c.Compatibility, compatibility)
+	c.Changes = append(c.Changes, fmt.Sprintf(format, args...))
+}
+
+// Err returns an *IncompatibleSchemaError if the change is not compatible.
+func (c SchemaChange) Err() error {
+	if c.Compatibility == Compatible {
+		return nil
+	}
+	return &IncompatibleSchemaError{Change: c}
+}
+
+// IncompatibleSchemaError is returned for schema changes that cannot be
+// applied to existing data as is.
+type IncompatibleSchemaError struct {
+	Change SchemaChange
+}
+
+func (e *IncompatibleSchemaError) Error() string {
+	return fmt.Sprintf("schema change %s: %s", e.Change.Compatibility, strings.Join(e.Change.Changes, ", "))
+}
+
+// CheckCompatibility classifies the change from the old to the new schema.
+// Adding nullable or dynamic columns and changing the name of the schema is
+// compatible. Changing the nullability, encoding, compression or prehashing of
+// a column, the sorting columns or the unique primary index requires existing
+// data to be rewritten. Removing columns, changing their type and adding
+// columns that are neither nullable nor dynamic is breaking.
+func CheckCompatibility(old, new *schemapb.Schema) SchemaChange {
+	change := SchemaChange{}
+
+	oldColumns := make(map[string]*schemapb.Column, len(old.Columns))
+	for _, col := range old.Columns {
+		oldColumns[col.Name] = col
+	}
+	newColumns := make(map[string]*schemapb.Column, len(new.Columns))
+	for _, col := range new.Columns {
+		newColumns[col.Name] = col
+	}
+
+	for _, oldCol := range old.Columns {
+		newCol, ok := newColumns[oldCol.Name]
+		if !ok {
+			change.add(Breaking, "column %q removed", oldCol.Name)
+			continue
+		}
+		checkColumnCompatibility(&change, oldCol, newCol)
+	}
+	for _, newCol := range new.Columns {
+		if _, ok := oldColumns[newCol.Name]; ok {
+			continue
+		}
+		if !newCol.Dynamic && !newCol.StorageLayout.GetNullable() {
+			// Existing rows have no value for the column.
+			change.add(Breaking, "required column %q added", newCol.Name)
+		}
+	}
+
+	if !sortingColumnsEqual(old.SortingColumns, new.SortingColumns) {
+		change.add(RequiresRewrite, "sorting columns changed")
+	}
+	if old.UniquePrimaryIndex != new.UniquePrimaryIndex {
+		change.add(RequiresRewrite, "unique primary index changed")
+	}
+	return change
+}
+
+func checkColumnCompatibility(change *SchemaChange, oldCol, newCol *schemapb.Column) {
+	oldLayout, newLayout := oldCol.StorageLayout, newCol.StorageLayout
+	switch {
+	case oldCol.Dynamic != newCol.Dynamic:
+		change.add(Breaking, "column %q dynamic changed", oldCol.Name)
+	case oldLayout.GetType() != newLayout.GetType():
+		change.add(Breaking, "column %q type changed from %s to %s", oldCol.Name, oldLayout.GetType(), newLayout.GetType())
+	case oldLayout.GetRepeated() != newLayout.GetRepeated():
+		change.add(Breaking, "column %q repeated changed", oldCol.Name)
+	}
+
+	if oldLayout.GetNullable() != newLayout.GetNullable() {
+		change.add(RequiresRewrite, "column %q nullable changed", oldCol.Name)
+	}
+	if oldLayout.GetEncoding() != newLayout.GetEncoding() {
+		change.add(RequiresRewrite, "column %q encoding changed from %s to %s", oldCol.Name, oldLayout.GetEncoding(), newLayout.GetEncoding())
+	}
+	if oldLayout.GetCompression() != newLayout.GetCompression() {
+		change.add(RequiresRewrite, "column %q compression changed from %s to %s", oldCol.Name, oldLayout.GetCompression(), newLayout.GetCompression())
+	}
+	if oldCol.Prehash != newCol.Prehash {
+		change.add(RequiresRewrite, "column %q prehash changed", oldCol.Name)
+	}
+}
+
+func sortingColumnsEqual(a, b []*schemapb.SortingColumn) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if !proto.Equal(a[i], b[i]) {
+			return false
+		}
+	}
+	return true
+}
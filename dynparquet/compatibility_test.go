@@ -0,0 +1,121 @@
+package dynparquet
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+
+	schemapb "github.com/polarsignals/frostdb/gen/proto/go/frostdb/schema/v1alpha1"
+)
+
+func TestCheckCompatibility(t *testing.T) {
+	column := func(def *schemapb.Schema, name string) *schemapb.Column {
+		for _, col := range def.Columns {
+			if col.Name == name {
+				return col
+			}
+		}
+		t.Fatalf("column %q not found", name)
+		return nil
+	}
+
+	for _, tc := range []struct {
+		name          string
+		change        func(def *schemapb.Schema)
+		compatibility Compatibility
+	}{{
+		name:          "Unchanged",
+		change:        func(_ *schemapb.Schema) {},
+		compatibility: Compatible,
+	}, {
+		name: "AddNullableColumn",
+		change: func(def *schemapb.Schema) {
+			def.Columns = append(def.Columns, &schemapb.Column{
+				Name:          "comment",
+				StorageLayout: &schemapb.StorageLayout{Type: schemapb.StorageLayout_TYPE_STRING, Nullable: true},
+			})
+		},
+		compatibility: Compatible,
+	}, {
+		name: "AddDynamicColumn",
+		change: func(def *schemapb.Schema) {
+			def.Columns = append(def.Columns, &schemapb.Column{
+				Name:          "attributes",
+				StorageLayout: &schemapb.StorageLayout{Type: schemapb.StorageLayout_TYPE_STRING},
+				Dynamic:       true,
+			})
+		},
+		compatibility: Compatible,
+	}, {
+		name:          "RenameSchema",
+		change:        func(def *schemapb.Schema) { def.Name = "renamed" },
+		compatibility: Compatible,
+	}, {
+		name: "ChangeEncoding",
+		change: func(def *schemapb.Schema) {
+			column(def, "value").StorageLayout.Encoding = schemapb.StorageLayout_ENCODING_DELTA_BINARY_PACKED
+		},
+		compatibility: RequiresRewrite,
+	}, {
+		name: "ChangeCompression",
+		change: func(def *schemapb.Schema) {
+			column(def, "value").StorageLayout.Compression = schemapb.StorageLayout_COMPRESSION_ZSTD
+		},
+		compatibility: RequiresRewrite,
+	}, {
+		name:          "ChangeNullable",
+		change:        func(def *schemapb.Schema) { column(def, "value").StorageLayout.Nullable = true },
+		compatibility: RequiresRewrite,
+	}, {
+		name:          "ChangeSortingColumns",
+		change:        func(def *schemapb.Schema) { def.SortingColumns = def.SortingColumns[1:] },
+		compatibility: RequiresRewrite,
+	}, {
+		name:          "ChangeUniquePrimaryIndex",
+		change:        func(def *schemapb.Schema) { def.UniquePrimaryIndex = true },
+		compatibility: RequiresRewrite,
+	}, {
+		name: "RemoveColumn",
+		change: func(def *schemapb.Schema) {
+			def.Columns = def.Columns[:len(def.Columns)-1]
+		},
+		compatibility: Breaking,
+	}, {
+		name: "ChangeType",
+		change: func(def *schemapb.Schema) {
+			column(def, "value").StorageLayout.Type = schemapb.StorageLayout_TYPE_DOUBLE
+		},
+		compatibility: Breaking,
+	}, {
+		name:          "ChangeDynamic",
+		change:        func(def *schemapb.Schema) { column(def, "labels").Dynamic = false },
+		compatibility: Breaking,
+	}, {
+		name: "AddRequiredColumn",
+		change: func(def *schemapb.Schema) {
+			def.Columns = append(def.Columns, &schemapb.Column{
+				Name:          "comment",
+				StorageLayout: &schemapb.StorageLayout{Type: schemapb.StorageLayout_TYPE_STRING},
+			})
+		},
+		compatibility: Breaking,
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			old := SampleDefinition()
+			def := proto.Clone(old).(*schemapb.Schema)
+			tc.change(def)
+
+			change := CheckCompatibility(old, def)
+			require.Equal(t, tc.compatibility, change.Compatibility)
+			if tc.compatibility == Compatible {
+				require.NoError(t, change.Err())
+				require.Empty(t, change.Changes)
+				return
+			}
+			require.Len(t, change.Changes, 1)
+			var incompatible *IncompatibleSchemaError
+			require.ErrorAs(t, change.Err(), &incompatible)
+		})
+	}
+}
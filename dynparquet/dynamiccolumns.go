@@ -2,12 +2,24 @@ package dynparquet
 
 import (
 	"errors"
+	"fmt"
 	"sort"
 	"strings"
 )
 
 var ErrMalformedDynamicColumns = errors.New("malformed dynamic columns string")
 
+// ErrTooManyDynamicColumns is returned when the number of concrete dynamic
+// columns exceeds the configured limit.
+type ErrTooManyDynamicColumns struct {
+	Columns int
+	Limit   int
+}
+
+func (e ErrTooManyDynamicColumns) Error() string {
+	return fmt.Sprintf("too many dynamic columns: %d exceeds the limit of %d", e.Columns, e.Limit)
+}
+
 func serializeDynamicColumns(dynamicColumns map[string][]string) string {
 	names := make([]string, 0, len(dynamicColumns))
 	var size int
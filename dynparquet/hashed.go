@@ -93,6 +93,10 @@ func HashArray(arr arrow.Array) []uint64 {
 		return hashInt64Array(ar)
 	case *array.Uint64:
 		return hashUint64Array(ar)
+	case *array.Int32:
+		return hashInt32Array(ar)
+	case *array.Timestamp:
+		return hashTimestampArray(ar)
 	case *array.Boolean:
 		return hashBooleanArray(ar)
 	case *array.Dictionary:
@@ -271,6 +275,26 @@ func hashUint64Array(arr *array.Uint64) []uint64 {
 	return res
 }
 
+func hashInt32Array(arr *array.Int32) []uint64 {
+	res := make([]uint64, arr.Len())
+	for i := 0; i < arr.Len(); i++ {
+		if !arr.IsNull(i) {
+			res[i] = uint64(arr.Value(i))
+		}
+	}
+	return res
+}
+
+func hashTimestampArray(arr *array.Timestamp) []uint64 {
+	res := make([]uint64, arr.Len())
+	for i := 0; i < arr.Len(); i++ {
+		if !arr.IsNull(i) {
+			res[i] = uint64(arr.Value(i))
+		}
+	}
+	return res
+}
+
 // RemoveHashedColumns removes the hashed columns from the record.
 func RemoveHashedColumns(r arrow.Record) arrow.Record {
 	cols := make([]arrow.Array, 0, r.Schema().NumFields())
@@ -0,0 +1,145 @@
+package dynparquet
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	schemapb "github.com/polarsignals/frostdb/gen/proto/go/frostdb/schema/v1alpha1"
+	schemav2pb "github.com/polarsignals/frostdb/gen/proto/go/frostdb/schema/v1alpha2"
+)
+
+// FieldMapping maps v1alpha1 column names to v1alpha2 paths. Dots in a path
+// denote nesting, so "labels.label1" refers to the leaf "label1" in the group
+// "labels". Columns without an explicit mapping keep their name. Concrete
+// dynamic columns (e.g. "labels.label1") without an explicit mapping are
+// placed in the group their dynamic column maps to.
+type FieldMapping map[string]string
+
+// Path returns the v1alpha2 path of the given v1alpha1 column name.
+func (m FieldMapping) Path(column string) string {
+	if path, ok := m[column]; ok {
+		return path
+	}
+	if dyn, concrete, ok := strings.Cut(column, "."); ok {
+		if path, ok := m[dyn]; ok {
+			return path + "." + concrete
+		}
+	}
+	return column
+}
+
+// MigrateV1Alpha1Schema converts a v1alpha1 schema definition into an
+// equivalent v1alpha2 (nested) schema definition using the given field
+// mapping. Dynamic columns become groups containing a leaf for each of the
+// concrete column names given in dynamicColumns. Note that v1alpha2 schemas
+// do not support prehashed columns, so the prehash setting is dropped.
+func MigrateV1Alpha1Schema(
+	def *schemapb.Schema,
+	mapping FieldMapping,
+	dynamicColumns map[string][]string,
+) (*schemav2pb.Schema, error) {
+	root := &schemav2pb.Group{Name: def.Name}
+	for _, col := range def.Columns {
+		if col.Dynamic {
+			if _, err := migrationGroup(root, strings.Split(mapping.Path(col.Name), ".")); err != nil {
+				return nil, fmt.Errorf("migrate dynamic column %q: %w", col.Name, err)
+			}
+			for _, name := range dynamicColumns[col.Name] {
+				if err := addMigrationLeaf(root, mapping.Path(col.Name+"."+name), col.StorageLayout); err != nil {
+					return nil, fmt.Errorf("migrate column %q: %w", col.Name+"."+name, err)
+				}
+			}
+			continue
+		}
+		if err := addMigrationLeaf(root, mapping.Path(col.Name), col.StorageLayout); err != nil {
+			return nil, fmt.Errorf("migrate column %q: %w", col.Name, err)
+		}
+	}
+	sortNodes(root)
+
+	sortingColumns := make([]*schemav2pb.SortingColumn, 0, len(def.SortingColumns))
+	for _, col := range def.SortingColumns {
+		sortingColumns = append(sortingColumns, &schemav2pb.SortingColumn{
+			Path:       mapping.Path(col.Name),
+			Direction:  schemav2pb.SortingColumn_Direction(col.Direction),
+			NullsFirst: col.NullsFirst,
+		})
+	}
+
+	return &schemav2pb.Schema{
+		Root:               root,
+		SortingColumns:     sortingColumns,
+		UniquePrimaryIndex: def.UniquePrimaryIndex,
+	}, nil
+}
+
+// migrationGroup returns the group at the given path, creating any missing
+// groups along the way.
+func migrationGroup(root *schemav2pb.Group, path []string) (*schemav2pb.Group, error) {
+	group := root
+	for _, name := range path {
+		var next *schemav2pb.Group
+		for _, node := range group.Nodes {
+			switch n := node.Type.(type) {
+			case *schemav2pb.Node_Group:
+				if n.Group.Name == name {
+					next = n.Group
+				}
+			case *schemav2pb.Node_Leaf:
+				if n.Leaf.Name == name {
+					return nil, fmt.Errorf("%q is both a leaf and a group", name)
+				}
+			}
+		}
+		if next == nil {
+			next = &schemav2pb.Group{Name: name}
+			group.Nodes = append(group.Nodes, &schemav2pb.Node{
+				Type: &schemav2pb.Node_Group{Group: next},
+			})
+		}
+		group = next
+	}
+	return group, nil
+}
+
+func addMigrationLeaf(root *schemav2pb.Group, path string, layout *schemapb.StorageLayout) error {
+	segments := strings.Split(path, ".")
+	group, err := migrationGroup(root, segments[:len(segments)-1])
+	if err != nil {
+		return err
+	}
+	name := segments[len(segments)-1]
+	for _, node := range group.Nodes {
+		if nameFromNodeDef(node) == name {
+			return fmt.Errorf("duplicate path %q", path)
+		}
+	}
+	group.Nodes = append(group.Nodes, &schemav2pb.Node{
+		Type: &schemav2pb.Node_Leaf{
+			Leaf: &schemav2pb.Leaf{
+				Name: name,
+				StorageLayout: &schemav2pb.StorageLayout{
+					Type:        schemav2pb.StorageLayout_Type(layout.Type),
+					Encoding:    schemav2pb.StorageLayout_Encoding(layout.Encoding),
+					Compression: schemav2pb.StorageLayout_Compression(layout.Compression),
+					Nullable:    layout.Nullable,
+					Repeated:    layout.Repeated,
+				},
+			},
+		},
+	})
+	return nil
+}
+
+// sortNodes sorts the nodes of the group and all its subgroups by name.
+func sortNodes(group *schemav2pb.Group) {
+	sort.Slice(group.Nodes, func(i, j int) bool {
+		return nameFromNodeDef(group.Nodes[i]) < nameFromNodeDef(group.Nodes[j])
+	})
+	for _, node := range group.Nodes {
+		if g, ok := node.Type.(*schemav2pb.Node_Group); ok {
+			sortNodes(g.Group)
+		}
+	}
+}
@@ -0,0 +1,71 @@
+package dynparquet
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+
+	schemav2pb "github.com/polarsignals/frostdb/gen/proto/go/frostdb/schema/v1alpha2"
+)
+
+func TestMigrateV1Alpha1Schema(t *testing.T) {
+	def := SampleDefinition()
+	v2, err := MigrateV1Alpha1Schema(
+		def,
+		FieldMapping{"example_type": "type", "labels": "attributes"},
+		map[string][]string{"labels": {"node", "job"}},
+	)
+	require.NoError(t, err)
+
+	stringLayout := &schemav2pb.StorageLayout{
+		Type:     schemav2pb.StorageLayout_TYPE_STRING,
+		Encoding: schemav2pb.StorageLayout_ENCODING_RLE_DICTIONARY,
+	}
+	labelLayout := &schemav2pb.StorageLayout{
+		Type:     schemav2pb.StorageLayout_TYPE_STRING,
+		Encoding: schemav2pb.StorageLayout_ENCODING_RLE_DICTIONARY,
+		Nullable: true,
+	}
+	leaf := func(name string, layout *schemav2pb.StorageLayout) *schemav2pb.Node {
+		return &schemav2pb.Node{Type: &schemav2pb.Node_Leaf{Leaf: &schemav2pb.Leaf{Name: name, StorageLayout: layout}}}
+	}
+	expected := &schemav2pb.Schema{
+		Root: &schemav2pb.Group{
+			Name: "test",
+			Nodes: []*schemav2pb.Node{
+				{Type: &schemav2pb.Node_Group{Group: &schemav2pb.Group{
+					Name: "attributes",
+					Nodes: []*schemav2pb.Node{
+						leaf("job", labelLayout),
+						leaf("node", labelLayout),
+					},
+				}}},
+				leaf("stacktrace", stringLayout),
+				leaf("timestamp", &schemav2pb.StorageLayout{Type: schemav2pb.StorageLayout_TYPE_INT64}),
+				leaf("type", stringLayout),
+				leaf("value", &schemav2pb.StorageLayout{Type: schemav2pb.StorageLayout_TYPE_INT64}),
+			},
+		},
+		SortingColumns: []*schemav2pb.SortingColumn{
+			{Path: "type", Direction: schemav2pb.SortingColumn_DIRECTION_ASCENDING},
+			{Path: "attributes", Direction: schemav2pb.SortingColumn_DIRECTION_ASCENDING, NullsFirst: true},
+			{Path: "timestamp", Direction: schemav2pb.SortingColumn_DIRECTION_ASCENDING},
+			{Path: "stacktrace", Direction: schemav2pb.SortingColumn_DIRECTION_ASCENDING, NullsFirst: true},
+		},
+	}
+	require.True(t, proto.Equal(expected, v2), "expected %v, got %v", expected, v2)
+
+	// The migrated definition must be a valid schema.
+	_, err = SchemaFromDefinition(v2)
+	require.NoError(t, err)
+}
+
+func TestMigrateV1Alpha1SchemaConflict(t *testing.T) {
+	_, err := MigrateV1Alpha1Schema(
+		SampleDefinition(),
+		FieldMapping{"value": "labels"},
+		nil,
+	)
+	require.Error(t, err)
+}
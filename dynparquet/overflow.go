@@ -0,0 +1,191 @@
+package dynparquet
+
+import (
+	"fmt"
+	"net/url"
+	"slices"
+	"strings"
+
+	"github.com/apache/arrow/go/v17/arrow"
+	"github.com/apache/arrow/go/v17/arrow/array"
+	"github.com/apache/arrow/go/v17/arrow/memory"
+)
+
+// OverflowColumnName is the concrete column name that dynamic columns are
+// folded into once a dynamic column limit is exceeded. For example, overflowing
+// columns of the dynamic column "labels" are stored in "labels.__overflow".
+const OverflowColumnName = "__overflow"
+
+// DynamicColumnLimitBehavior defines what happens when the number of concrete
+// dynamic columns exceeds the configured limit.
+type DynamicColumnLimitBehavior int
+
+const (
+	// DynamicColumnLimitError rejects writes that would exceed the limit with
+	// an ErrTooManyDynamicColumns error.
+	DynamicColumnLimitError DynamicColumnLimitBehavior = iota
+	// DynamicColumnLimitOverflow map-encodes the values of any concrete
+	// dynamic columns exceeding the limit into the OverflowColumnName column
+	// of their dynamic column.
+	DynamicColumnLimitOverflow
+)
+
+// EncodeOverflow map-encodes the given key-value pairs into the format used by
+// overflow columns. Keys are sorted so the encoding is deterministic.
+func EncodeOverflow(values map[string]string) string {
+	v := make(url.Values, len(values))
+	for k, val := range values {
+		v.Set(k, val)
+	}
+	return v.Encode()
+}
+
+// DecodeOverflow decodes a value of an overflow column into the key-value pairs
+// it holds.
+func DecodeOverflow(s string) (map[string]string, error) {
+	v, err := url.ParseQuery(s)
+	if err != nil {
+		return nil, fmt.Errorf("decode overflow column: %w", err)
+	}
+	values := make(map[string]string, len(v))
+	for k := range v {
+		values[k] = v.Get(k)
+	}
+	return values, nil
+}
+
+// FoldDynamicColumns returns a record where the given concrete dynamic columns
+// (e.g. "labels.label1") are removed and their non-null values map-encoded into
+// the overflow column of their dynamic column. Existing overflow columns in the
+// record are merged with the folded values. The overflow column has the arrow
+// type of the existing overflow column, or else of the folded columns, so
+// that it matches the type of the dynamic column, which must be a string or
+// binary type. The caller is responsible for releasing the returned record.
+func FoldDynamicColumns(mem memory.Allocator, r arrow.Record, fold map[string]struct{}) (arrow.Record, error) {
+	if len(fold) == 0 {
+		r.Retain()
+		return r, nil
+	}
+
+	// Determine which dynamic columns receive an overflow column.
+	overflowing := map[string]struct{}{}
+	for _, f := range r.Schema().Fields() {
+		if _, ok := fold[f.Name]; ok {
+			dyn, _, _ := strings.Cut(f.Name, ".")
+			overflowing[dyn] = struct{}{}
+		}
+	}
+
+	type overflowGroup struct {
+		typ     arrow.DataType
+		keys    []string
+		columns []arrow.Array
+	}
+	groups := map[string]*overflowGroup{}
+	fields := make([]arrow.Field, 0, r.NumCols())
+	columns := make([]arrow.Array, 0, r.NumCols())
+	for i, f := range r.Schema().Fields() {
+		dyn, concrete, _ := strings.Cut(f.Name, ".")
+		_, folded := fold[f.Name]
+		_, isOverflowing := overflowing[dyn]
+		if !folded && !(isOverflowing && concrete == OverflowColumnName) {
+			fields = append(fields, f)
+			columns = append(columns, r.Column(i))
+			continue
+		}
+
+		g, ok := groups[dyn]
+		if !ok {
+			g = &overflowGroup{}
+			groups[dyn] = g
+		}
+		typ := f.Type
+		if dict, ok := typ.(*arrow.DictionaryType); ok {
+			typ = dict.ValueType
+		}
+		if g.typ == nil || concrete == OverflowColumnName {
+			g.typ = typ
+		}
+		g.keys = append(g.keys, concrete)
+		g.columns = append(g.columns, r.Column(i))
+	}
+	for dyn, g := range groups {
+		switch g.typ.ID() {
+		case arrow.STRING, arrow.BINARY:
+		default:
+			return nil, fmt.Errorf("fold dynamic column %s: values of type %s cannot be map-encoded", dyn, g.typ)
+		}
+	}
+
+	built := make([]arrow.Array, 0, len(groups))
+	defer func() {
+		for _, a := range built {
+			a.Release()
+		}
+	}()
+
+	for dyn, g := range groups {
+		b := array.NewBuilder(mem, g.typ).(interface {
+			array.Builder
+			AppendString(string)
+		})
+		defer b.Release()
+		b.Reserve(int(r.NumRows()))
+		for row := 0; row < int(r.NumRows()); row++ {
+			values := map[string]string{}
+			for j, col := range g.columns {
+				if col.IsNull(row) {
+					continue
+				}
+				if g.keys[j] == OverflowColumnName {
+					existing, err := DecodeOverflow(valueString(col, row))
+					if err != nil {
+						return nil, err
+					}
+					for k, v := range existing {
+						values[k] = v
+					}
+					continue
+				}
+				values[g.keys[j]] = valueString(col, row)
+			}
+			if len(values) == 0 {
+				b.AppendNull()
+				continue
+			}
+			b.AppendString(EncodeOverflow(values))
+		}
+		arr := b.NewArray()
+		built = append(built, arr)
+
+		// Columns of a record are sorted by name, so the overflow column is
+		// inserted before the first column that sorts after it.
+		name := dyn + "." + OverflowColumnName
+		pos := slices.IndexFunc(fields, func(f arrow.Field) bool { return f.Name > name })
+		if pos == -1 {
+			pos = len(fields)
+		}
+		fields = slices.Insert(fields, pos, arrow.Field{
+			Name:     name,
+			Type:     g.typ,
+			Nullable: true,
+		})
+		columns = slices.Insert(columns, pos, arrow.Array(arr))
+	}
+
+	return array.NewRecord(arrow.NewSchema(fields, nil), columns, r.NumRows()), nil
+}
+
+// valueString returns the value at index i of the given array as a string.
+func valueString(arr arrow.Array, i int) string {
+	switch a := arr.(type) {
+	case *array.String:
+		return a.Value(i)
+	case *array.Binary:
+		return string(a.Value(i))
+	case *array.Dictionary:
+		return valueString(a.Dictionary(), a.GetValueIndex(i))
+	default:
+		return arr.ValueStr(i)
+	}
+}
@@ -0,0 +1,95 @@
+package dynparquet
+
+import (
+	"testing"
+
+	"github.com/apache/arrow/go/v17/arrow"
+	"github.com/apache/arrow/go/v17/arrow/array"
+	"github.com/apache/arrow/go/v17/arrow/memory"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFoldDynamicColumns(t *testing.T) {
+	mem := memory.NewCheckedAllocator(memory.NewGoAllocator())
+	defer mem.AssertSize(t, 0)
+
+	schema := arrow.NewSchema([]arrow.Field{
+		{Name: "labels.a", Type: arrow.BinaryTypes.String, Nullable: true},
+		{Name: "labels.b", Type: arrow.BinaryTypes.String, Nullable: true},
+		{Name: "labels.c", Type: arrow.BinaryTypes.String, Nullable: true},
+		{Name: "value", Type: arrow.PrimitiveTypes.Int64},
+	}, nil)
+	b := array.NewRecordBuilder(mem, schema)
+	defer b.Release()
+	b.Field(0).(*array.StringBuilder).AppendValues([]string{"a1", "a2"}, nil)
+	b.Field(1).(*array.StringBuilder).AppendValues([]string{"b1", ""}, []bool{true, false})
+	b.Field(2).(*array.StringBuilder).AppendValues([]string{"", ""}, []bool{false, false})
+	b.Field(3).(*array.Int64Builder).AppendValues([]int64{1, 2}, nil)
+	r := b.NewRecord()
+	defer r.Release()
+
+	folded, err := FoldDynamicColumns(mem, r, map[string]struct{}{
+		"labels.b": {},
+		"labels.c": {},
+	})
+	require.NoError(t, err)
+	defer folded.Release()
+
+	// The overflow column is inserted at its sorted position.
+	require.Equal(t, int64(3), folded.NumCols())
+	require.Equal(t, "labels."+OverflowColumnName, folded.ColumnName(0))
+	require.Equal(t, "labels.a", folded.ColumnName(1))
+	require.Equal(t, "value", folded.ColumnName(2))
+
+	overflow := folded.Column(0).(*array.String)
+	values, err := DecodeOverflow(overflow.Value(0))
+	require.NoError(t, err)
+	require.Equal(t, map[string]string{"b": "b1"}, values)
+	require.True(t, overflow.IsNull(1))
+}
+
+func TestFoldDynamicColumnsBinary(t *testing.T) {
+	mem := memory.NewCheckedAllocator(memory.NewGoAllocator())
+	defer mem.AssertSize(t, 0)
+
+	schema := arrow.NewSchema([]arrow.Field{
+		{Name: "labels.a", Type: arrow.BinaryTypes.Binary, Nullable: true},
+		{Name: "labels.b", Type: arrow.BinaryTypes.Binary, Nullable: true},
+		{Name: "nums.x", Type: arrow.PrimitiveTypes.Int64, Nullable: true},
+	}, nil)
+	b := array.NewRecordBuilder(mem, schema)
+	defer b.Release()
+	b.Field(0).(*array.BinaryBuilder).AppendValues([][]byte{[]byte("a1")}, nil)
+	b.Field(1).(*array.BinaryBuilder).AppendValues([][]byte{[]byte("b1")}, nil)
+	b.Field(2).(*array.Int64Builder).AppendValues([]int64{1}, nil)
+	r := b.NewRecord()
+	defer r.Release()
+
+	// The overflow column has the type of the folded binary columns.
+	folded, err := FoldDynamicColumns(mem, r, map[string]struct{}{"labels.b": {}})
+	require.NoError(t, err)
+	defer folded.Release()
+	require.Equal(t, "labels."+OverflowColumnName, folded.ColumnName(0))
+	require.Equal(t, arrow.BinaryTypes.Binary, folded.Schema().Field(0).Type)
+	values, err := DecodeOverflow(string(folded.Column(0).(*array.Binary).Value(0)))
+	require.NoError(t, err)
+	require.Equal(t, map[string]string{"b": "b1"}, values)
+
+	// Values that are neither strings nor binary cannot be folded.
+	_, err = FoldDynamicColumns(mem, r, map[string]struct{}{"nums.x": {}})
+	require.Error(t, err)
+}
+
+func TestOverflowEncoding(t *testing.T) {
+	input := map[string]string{
+		"b":         "with=equals&ampersand",
+		"a":         "",
+		"unicode ✓": "value",
+	}
+	encoded := EncodeOverflow(input)
+	require.Equal(t, encoded, EncodeOverflow(input))
+
+	output, err := DecodeOverflow(encoded)
+	require.NoError(t, err)
+	require.Equal(t, input, output)
+}
@@ -0,0 +1,356 @@
+package dynparquet
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"math/bits"
+
+	"github.com/parquet-go/parquet-go"
+)
+
+// RowReducer merges rows whose sorting columns are all equal into a single
+// row. The rows are passed in the order the row groups they were read from
+// were given to MergeDynamicRowGroups, so the last row is the most recently
+// inserted one if the row groups are ordered by insertion. All rows, and the
+// returned row, follow the given schema. The reducer may modify and return
+// any of the rows, but it must not retain them.
+type RowReducer func(schema *parquet.Schema, rows []parquet.Row) (parquet.Row, error)
+
+// KeepLatest is a RowReducer that keeps the most recently inserted row.
+func KeepLatest(_ *parquet.Schema, rows []parquet.Row) (parquet.Row, error) {
+	return rows[len(rows)-1], nil
+}
+
+// Aggregation is a function that AggregateRows merges the values of a column
+// with.
+type Aggregation string
+
+const (
+	// AggregationSum sums the values of numeric columns. Values of other
+	// columns are merged like with AggregationLast. Merging fails if a sum
+	// overflows the type of its column.
+	AggregationSum Aggregation = "sum"
+	// AggregationMax keeps the greatest value, ordered by the column's type.
+	AggregationMax Aggregation = "max"
+	// AggregationLast keeps the most recently inserted value. Unlike
+	// KeepLatest, a null value does not replace an earlier value.
+	AggregationLast Aggregation = "last"
+)
+
+// AggregateRows returns a RowReducer that merges the values of each column
+// for which key returns false using agg. Null values are skipped, so a column
+// is only null if it is null in all rows. key is called with the path of each
+// leaf column; the values of key columns are equal in all rows, since rows are
+// only reduced if their sorting columns are equal, and are kept as is. The
+// values of repeated columns are those of the most recently inserted row.
+func AggregateRows(agg Aggregation, key func(path []string) bool) (RowReducer, error) {
+	var merge func(typ parquet.Type, a, b parquet.Value) (parquet.Value, error)
+	switch agg {
+	case AggregationSum:
+		merge = sumValues
+	case AggregationMax:
+		merge = func(typ parquet.Type, a, b parquet.Value) (parquet.Value, error) {
+			if typ.Compare(a, b) > 0 {
+				return a, nil
+			}
+			return b, nil
+		}
+	case AggregationLast:
+		merge = func(_ parquet.Type, _, b parquet.Value) (parquet.Value, error) {
+			return b, nil
+		}
+	default:
+		return nil, fmt.Errorf("unknown aggregation: %q", agg)
+	}
+
+	return func(schema *parquet.Schema, rows []parquet.Row) (parquet.Row, error) {
+		columns := schema.Columns()
+		types := make([]parquet.Type, len(columns))
+		merged := make([]parquet.Value, len(columns))
+		for i, path := range columns {
+			if key(path) {
+				continue
+			}
+			leaf, ok := schema.Lookup(path...)
+			if !ok {
+				return nil, fmt.Errorf("column %v not found", path)
+			}
+			if leaf.MaxRepetitionLevel > 0 {
+				continue
+			}
+			types[i] = leaf.Node.Type()
+		}
+		var err error
+		for _, row := range rows {
+			row.Range(func(i int, values []parquet.Value) bool {
+				if types[i] == nil || len(values) != 1 || values[0].IsNull() {
+					return true
+				}
+				if merged[i].IsNull() {
+					merged[i] = values[0]
+					return true
+				}
+				merged[i], err = merge(types[i], merged[i], values[0])
+				if err != nil {
+					err = fmt.Errorf("column %v: %w", columns[i], err)
+					return false
+				}
+				return true
+			})
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		last := rows[len(rows)-1]
+		reduced := make(parquet.Row, 0, len(last))
+		last.Range(func(i int, values []parquet.Value) bool {
+			if types[i] != nil && !merged[i].IsNull() {
+				values = merged[i : i+1]
+			}
+			reduced = append(reduced, values...)
+			return true
+		})
+		return reduced, nil
+	}, nil
+}
+
+// ErrSumOverflow is returned when the sum of the values of a column merged
+// with AggregationSum overflows the type of the column.
+var ErrSumOverflow = errors.New("sum overflows column type")
+
+// sumValues returns the sum of two non-null values of a column of the given
+// type. Values that are not numeric are not summed, and the most recent value
+// b is returned. Integers are summed as unsigned if the logical type of the
+// column is an unsigned integer.
+func sumValues(typ parquet.Type, a, b parquet.Value) (parquet.Value, error) {
+	var sum parquet.Value
+	switch b.Kind() {
+	case parquet.Int32:
+		if isUnsigned(typ) {
+			s, carry := bits.Add32(a.Uint32(), b.Uint32(), 0)
+			if carry != 0 {
+				return parquet.Value{}, ErrSumOverflow
+			}
+			sum = parquet.Int32Value(int32(s))
+			break
+		}
+		s := a.Int32() + b.Int32()
+		if (s > a.Int32()) != (b.Int32() > 0) {
+			return parquet.Value{}, ErrSumOverflow
+		}
+		sum = parquet.Int32Value(s)
+	case parquet.Int64:
+		if isUnsigned(typ) {
+			s, carry := bits.Add64(a.Uint64(), b.Uint64(), 0)
+			if carry != 0 {
+				return parquet.Value{}, ErrSumOverflow
+			}
+			sum = parquet.Int64Value(int64(s))
+			break
+		}
+		s := a.Int64() + b.Int64()
+		if (s > a.Int64()) != (b.Int64() > 0) {
+			return parquet.Value{}, ErrSumOverflow
+		}
+		sum = parquet.Int64Value(s)
+	case parquet.Float:
+		s := a.Float() + b.Float()
+		if math.IsInf(float64(s), 0) && !math.IsInf(float64(a.Float()), 0) && !math.IsInf(float64(b.Float()), 0) {
+			return parquet.Value{}, ErrSumOverflow
+		}
+		sum = parquet.FloatValue(s)
+	case parquet.Double:
+		s := a.Double() + b.Double()
+		if math.IsInf(s, 0) && !math.IsInf(a.Double(), 0) && !math.IsInf(b.Double(), 0) {
+			return parquet.Value{}, ErrSumOverflow
+		}
+		sum = parquet.DoubleValue(s)
+	default:
+		return b, nil
+	}
+	return sum.Level(b.RepetitionLevel(), b.DefinitionLevel(), b.Column()), nil
+}
+
+// isUnsigned returns whether the logical type of typ is an unsigned integer.
+func isUnsigned(typ parquet.Type) bool {
+	if typ == nil {
+		return false
+	}
+	lt := typ.LogicalType()
+	return lt != nil && lt.Integer != nil && !lt.Integer.IsSigned
+}
+
+// WithRowReducer reduces rows with equal sorting columns to a single row
+// using reduce when merging row groups. Rows of different row groups are
+// passed to reduce in the order of the row groups, and rows of the same row
+// group in the order they are stored, so the row groups should be given
+// ordered from oldest to newest.
+func WithRowReducer(reduce RowReducer) MergeOption {
+	return func(m *mergeOption) {
+		m.reduce = reduce
+	}
+}
+
+// newReducedRowGroup returns a row group with the rows of the given row
+// groups, merged and reduced by the given sorting columns. The row groups must
+// all have the given schema and be sorted by the sorting columns. The reduced
+// rows are buffered, so that the row count and column chunks of the returned
+// row group describe the rows after the reduction.
+func newReducedRowGroup(
+	schema *parquet.Schema,
+	sorting []parquet.SortingColumn,
+	rowGroups []parquet.RowGroup,
+	reduce RowReducer,
+) (parquet.RowGroup, error) {
+	inputs := make([]*bufferedRows, 0, len(rowGroups))
+	for _, rg := range rowGroups {
+		inputs = append(inputs, &bufferedRows{
+			rows: rg.Rows(),
+			buf:  make([]parquet.Row, 64),
+		})
+	}
+	rows := &reducedRows{
+		schema:  schema,
+		inputs:  inputs,
+		compare: schema.Comparator(sorting...),
+		reduce:  reduce,
+	}
+	defer rows.Close()
+
+	buf := parquet.NewBuffer(schema, parquet.SortingRowGroupConfig(parquet.SortingColumns(sorting...)))
+	if _, err := parquet.CopyRows(buf, rows); err != nil {
+		return nil, err
+	}
+	return buf, nil
+}
+
+// bufferedRows allows peeking at the next row of parquet.Rows.
+type bufferedRows struct {
+	rows parquet.Rows
+	buf  []parquet.Row
+	off  int
+	n    int
+	err  error
+}
+
+// peek returns the next row without consuming it. The row is only valid until
+// the next call to next.
+func (b *bufferedRows) peek() (parquet.Row, error) {
+	for b.off == b.n {
+		if b.err != nil {
+			return nil, b.err
+		}
+		b.n, b.err = b.rows.ReadRows(b.buf)
+		b.off = 0
+	}
+	return b.buf[b.off], nil
+}
+
+// next consumes the row returned by peek.
+func (b *bufferedRows) next() {
+	b.off++
+}
+
+// reducedRows merges the rows of its inputs in a stable way, ties are broken
+// by the order of the inputs, and reduces consecutive rows with equal sorting
+// columns.
+type reducedRows struct {
+	schema  *parquet.Schema
+	inputs  []*bufferedRows
+	compare func(parquet.Row, parquet.Row) int
+	reduce  RowReducer
+
+	// ahead is the first row of the next group of equal rows, if it has
+	// already been read.
+	ahead parquet.Row
+	group []parquet.Row
+}
+
+// next returns the least row of all inputs, preferring earlier inputs.
+func (r *reducedRows) next() (parquet.Row, error) {
+	var (
+		least    *bufferedRows
+		leastRow parquet.Row
+	)
+	for _, in := range r.inputs {
+		row, err := in.peek()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				continue
+			}
+			return nil, err
+		}
+		if least == nil || r.compare(row, leastRow) < 0 {
+			least, leastRow = in, row
+		}
+	}
+	if least == nil {
+		return nil, io.EOF
+	}
+	// The row is cloned since the buffer of the input is reused.
+	row := leastRow.Clone()
+	least.next()
+	return row, nil
+}
+
+func (r *reducedRows) ReadRows(rows []parquet.Row) (int, error) {
+	n := 0
+	for n < len(rows) {
+		if r.ahead == nil {
+			row, err := r.next()
+			if err != nil {
+				return n, err
+			}
+			r.ahead = row
+		}
+		r.group = append(r.group[:0], r.ahead)
+		r.ahead = nil
+		for {
+			row, err := r.next()
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			if err != nil {
+				return n, err
+			}
+			if r.compare(row, r.group[0]) != 0 {
+				r.ahead = row
+				break
+			}
+			r.group = append(r.group, row)
+		}
+
+		reduced := r.group[0]
+		if len(r.group) > 1 {
+			var err error
+			reduced, err = r.reduce(r.schema, r.group)
+			if err != nil {
+				return n, fmt.Errorf("reduce rows: %w", err)
+			}
+		}
+		rows[n] = append(rows[n][:0], reduced...)
+		n++
+	}
+	return n, nil
+}
+
+func (r *reducedRows) SeekToRow(_ int64) error {
+	return errors.New("seeking reduced rows is not supported")
+}
+
+func (r *reducedRows) Schema() *parquet.Schema {
+	return r.schema
+}
+
+func (r *reducedRows) Close() error {
+	var lastErr error
+	for _, in := range r.inputs {
+		if err := in.rows.Close(); err != nil {
+			lastErr = err
+		}
+	}
+	return lastErr
+}
@@ -118,6 +118,10 @@ type Schema struct {
 
 	UniquePrimaryIndex bool
 
+	// indexedColumns are concrete columns that bloom filters are written
+	// for in addition to the sorting columns.
+	indexedColumns []string
+
 	writers        *sync.Map
 	buffers        *sync.Map
 	sortingSchemas *sync.Map
@@ -374,9 +378,11 @@ func DefinitionFromParquetFile(file *parquet.File) (*schemapb.Schema, error) {
 
 			// Check if the column is optional
 			nullable := false
+			var logicalType *format.LogicalType
 			for _, node := range schema.Fields() {
 				if node.Name() == name {
 					nullable = node.Optional()
+					logicalType = node.Type().LogicalType()
 				}
 			}
 
@@ -395,7 +401,7 @@ func DefinitionFromParquetFile(file *parquet.File) (*schemapb.Schema, error) {
 
 			columns = append(columns, &schemapb.Column{
 				Name:          split[0],
-				StorageLayout: parquetColumnMetaDataToStorageLayout(col.MetaData, nullable),
+				StorageLayout: parquetColumnMetaDataToStorageLayout(col.MetaData, nullable, logicalType),
 				Dynamic:       isDynamic,
 			})
 		}
@@ -418,7 +424,7 @@ func SchemaFromParquetFile(file *parquet.File) (*Schema, error) {
 	return SchemaFromDefinition(def)
 }
 
-func parquetColumnMetaDataToStorageLayout(metadata format.ColumnMetaData, nullable bool) *schemapb.StorageLayout {
+func parquetColumnMetaDataToStorageLayout(metadata format.ColumnMetaData, nullable bool, logicalType *format.LogicalType) *schemapb.StorageLayout {
 	layout := &schemapb.StorageLayout{
 		Nullable: nullable,
 	}
@@ -446,8 +452,17 @@ func parquetColumnMetaDataToStorageLayout(metadata format.ColumnMetaData, nullab
 	switch metadata.Type {
 	case format.ByteArray:
 		layout.Type = schemapb.StorageLayout_TYPE_STRING
+	case format.Int32:
+		layout.Type = schemapb.StorageLayout_TYPE_INT32
 	case format.Int64:
-		layout.Type = schemapb.StorageLayout_TYPE_INT64
+		switch {
+		case logicalType != nil && logicalType.Integer != nil && !logicalType.Integer.IsSigned:
+			layout.Type = schemapb.StorageLayout_TYPE_UINT64
+		case logicalType != nil && logicalType.Timestamp != nil:
+			layout.Type = timestampStorageLayoutType(logicalType.Timestamp.Unit)
+		default:
+			layout.Type = schemapb.StorageLayout_TYPE_INT64
+		}
 	case format.Double:
 		layout.Type = schemapb.StorageLayout_TYPE_DOUBLE
 	case format.Boolean:
@@ -457,6 +472,19 @@ func parquetColumnMetaDataToStorageLayout(metadata format.ColumnMetaData, nullab
 	return layout
 }
 
+// timestampStorageLayoutType returns the storage layout type of timestamps of
+// the given unit.
+func timestampStorageLayoutType(unit format.TimeUnit) schemapb.StorageLayout_Type {
+	switch {
+	case unit.Millis != nil:
+		return schemapb.StorageLayout_TYPE_TIMESTAMP_MILLIS
+	case unit.Micros != nil:
+		return schemapb.StorageLayout_TYPE_TIMESTAMP_MICROS
+	default:
+		return schemapb.StorageLayout_TYPE_TIMESTAMP_NANOS
+	}
+}
+
 type StorageLayout interface {
 	GetTypeInt32() int32
 	GetRepeated() bool
@@ -506,6 +534,11 @@ func StorageLayoutWrapper(_ *schemav2pb.StorageLayout) StorageLayout {
 }
 
 func storageLayoutToParquetNode(l StorageLayout) (parquet.Node, error) {
+	// Maps only exist in v1alpha2 schemas.
+	if v2, ok := l.(*v2storageLayoutWrapper); ok && v2.GetType() == schemav2pb.StorageLayout_TYPE_MAP {
+		return mapStorageLayoutToParquetNode(l)
+	}
+
 	var node parquet.Node
 	switch l.GetTypeInt32() {
 	case int32(schemapb.StorageLayout_TYPE_STRING):
@@ -520,6 +553,12 @@ func storageLayoutToParquetNode(l StorageLayout) (parquet.Node, error) {
 		node = parquet.Int(32)
 	case int32(schemapb.StorageLayout_TYPE_UINT64):
 		node = parquet.Uint(64)
+	case int32(schemapb.StorageLayout_TYPE_TIMESTAMP_MILLIS):
+		node = parquet.Timestamp(parquet.Millisecond)
+	case int32(schemapb.StorageLayout_TYPE_TIMESTAMP_MICROS):
+		node = parquet.Timestamp(parquet.Microsecond)
+	case int32(schemapb.StorageLayout_TYPE_TIMESTAMP_NANOS):
+		node = parquet.Timestamp(parquet.Nanosecond)
 	default:
 		return nil, fmt.Errorf("unknown storage layout type: %v", l.GetTypeInt32())
 	}
@@ -528,6 +567,45 @@ func storageLayoutToParquetNode(l StorageLayout) (parquet.Node, error) {
 		node = parquet.Optional(node)
 	}
 
+	node, err := encodedParquetNode(l, node)
+	if err != nil {
+		return nil, err
+	}
+
+	if l.GetRepeated() {
+		node = parquet.Repeated(node)
+	}
+
+	return node, nil
+}
+
+// mapStorageLayoutToParquetNode returns the parquet MAP node of string keys to
+// optional string values for a map storage layout. The encoding and
+// compression of the layout apply to both the keys and the values.
+func mapStorageLayoutToParquetNode(l StorageLayout) (parquet.Node, error) {
+	if l.GetRepeated() {
+		return nil, errors.New("map columns cannot be repeated")
+	}
+
+	key, err := encodedParquetNode(l, parquet.String())
+	if err != nil {
+		return nil, err
+	}
+	value, err := encodedParquetNode(l, parquet.Optional(parquet.String()))
+	if err != nil {
+		return nil, err
+	}
+
+	node := parquet.Map(key, value)
+	if l.GetNullable() {
+		node = parquet.Optional(node)
+	}
+	return node, nil
+}
+
+// encodedParquetNode applies the encoding and compression of the storage
+// layout to the leaf node.
+func encodedParquetNode(l StorageLayout, node parquet.Node) (parquet.Node, error) {
 	if l.GetEncodingInt32() != int32(schemapb.StorageLayout_ENCODING_PLAIN_UNSPECIFIED) {
 		enc, err := encodingFromDefinition(l.GetEncodingInt32())
 		if err != nil {
@@ -544,10 +622,6 @@ func storageLayoutToParquetNode(l StorageLayout) (parquet.Node, error) {
 		node = parquet.Compressed(node, comp)
 	}
 
-	if l.GetRepeated() {
-		node = parquet.Repeated(node)
-	}
-
 	return node, nil
 }
 
@@ -646,6 +720,46 @@ func (s *Schema) ColumnByName(name string) (ColumnDefinition, bool) {
 	return s.columns[i], true
 }
 
+// SetIndexedColumns sets additional concrete columns (e.g. "labels.pod") that
+// bloom filters are written for when writing parquet files, so that row
+// groups can be skipped when filtering for equality on these columns. It must
+// be called before the schema is used to write any data.
+func (s *Schema) SetIndexedColumns(columns ...string) error {
+	for _, name := range columns {
+		def, ok := s.ColumnByName(name)
+		if ok && def.Dynamic {
+			return fmt.Errorf("indexed column %q must be a concrete column of a dynamic column", name)
+		}
+		if !ok {
+			def, ok = s.FindDynamicColumnForConcreteColumn(name)
+		}
+		if !ok {
+			return fmt.Errorf("indexed column %q not found in schema", name)
+		}
+		if def.StorageLayout.Type().Kind() == parquet.Boolean {
+			return fmt.Errorf("indexed column %q must not be a boolean column", name)
+		}
+	}
+	s.indexedColumns = columns
+	return nil
+}
+
+// IndexedColumns returns the columns set with SetIndexedColumns.
+func (s *Schema) IndexedColumns() []string {
+	return s.indexedColumns
+}
+
+// HasCompressedColumns returns whether the storage layout of any column
+// specifies a compression codec.
+func (s *Schema) HasCompressedColumns() bool {
+	for _, col := range s.columns {
+		if col.StorageLayout.Compression() != nil {
+			return true
+		}
+	}
+	return false
+}
+
 func (s *Schema) Columns() []ColumnDefinition {
 	return s.columns
 }
@@ -943,6 +1057,12 @@ func (b *Buffer) Sort() {
 	sort.Sort(b.buffer)
 }
 
+// SortStable sorts the rows of the buffer like Sort, but keeps equal rows in
+// the order they were written in.
+func (b *Buffer) SortStable() {
+	sort.Stable(b.buffer)
+}
+
 func (b *Buffer) Clone() (*Buffer, error) {
 	buf := parquet.NewBuffer(
 		b.buffer.Schema(),
@@ -1136,6 +1256,20 @@ func (s *Schema) NewWriter(w io.Writer, dynamicColumns map[string][]string, sort
 			bloomFilterColumns, parquet.SplitBlockFilter(bloomFilterBitsPerValue, col.Path()...),
 		)
 	}
+	for _, name := range s.indexedColumns {
+		if _, ok := ps.Schema.Lookup(name); !ok {
+			// Concrete dynamic column not present in this file.
+			continue
+		}
+		if slices.ContainsFunc(cols, func(col parquet.SortingColumn) bool {
+			return slices.Equal(col.Path(), []string{name})
+		}) {
+			continue
+		}
+		bloomFilterColumns = append(
+			bloomFilterColumns, parquet.SplitBlockFilter(bloomFilterBitsPerValue, name),
+		)
+	}
 
 	writerOptions := []parquet.WriterOption{
 		ps.Schema,
@@ -1310,6 +1444,9 @@ type mergeOption struct {
 	// non-overlapping. This results in a parquet.MultiRowGroup, which is just
 	// a wrapper without the full-scale merging infrastructure.
 	alreadySorted bool
+	// reduce, if set, reduces rows with equal sorting columns to a single
+	// row, see WithRowReducer.
+	reduce RowReducer
 }
 
 type MergeOption func(m *mergeOption)
@@ -1331,16 +1468,16 @@ func WithAlreadySorted() MergeOption {
 // merging all the concrete dynamic column names and generating a superset
 // parquet schema that all given dynamic row groups are compatible with.
 func (s *Schema) MergeDynamicRowGroups(rowGroups []DynamicRowGroup, options ...MergeOption) (DynamicRowGroup, error) {
-	if len(rowGroups) == 1 {
-		return rowGroups[0], nil
-	}
-
 	// Apply options
 	m := &mergeOption{}
 	for _, option := range options {
 		option(m)
 	}
 
+	if len(rowGroups) == 1 && m.reduce == nil {
+		return rowGroups[0], nil
+	}
+
 	dynamicColumns := m.dynamicColumns
 	if dynamicColumns == nil {
 		dynamicColumns = mergeDynamicRowGroupDynamicColumns(rowGroups)
@@ -1363,6 +1500,20 @@ func (s *Schema) MergeDynamicRowGroups(rowGroups []DynamicRowGroup, options ...M
 		))
 	}
 
+	if m.reduce != nil {
+		// A single row group may contain rows with equal sorting columns
+		// too, so it is not returned as is when reducing.
+		reduced, err := newReducedRowGroup(ps.Schema, cols, adapters, m.reduce)
+		if err != nil {
+			return nil, fmt.Errorf("create reduced row group: %w", err)
+		}
+		return &MergedRowGroup{
+			RowGroup: reduced,
+			DynCols:  dynamicColumns,
+			fields:   ps.Schema.Fields(),
+		}, nil
+	}
+
 	var opts []parquet.RowGroupOption
 	if !m.alreadySorted {
 		opts = append(opts, parquet.SortingRowGroupConfig(
@@ -393,3 +393,52 @@ func BenchmarkMergeDynamicColumnSets(b *testing.B) {
 		MergeDynamicColumnSets(sets)
 	}
 }
+
+func TestSchemaIndexedColumns(t *testing.T) {
+	samples := Samples{{
+		ExampleType: "cpu",
+		Labels:      map[string]string{"label1": "value1"},
+		Timestamp:   1,
+		Value:       1,
+	}, {
+		ExampleType: "cpu",
+		Labels:      map[string]string{"label1": "value2"},
+		Timestamp:   2,
+		Value:       3,
+	}}
+
+	// valueBloomFilter writes the samples and returns the bloom filter of the
+	// value column.
+	valueBloomFilter := func(t *testing.T, schema *Schema) parquet.BloomFilter {
+		t.Helper()
+		dbuf, err := ToBuffer(samples, schema)
+		require.NoError(t, err)
+		b := bytes.NewBuffer(nil)
+		require.NoError(t, schema.SerializeBuffer(b, dbuf))
+
+		file, err := parquet.OpenFile(bytes.NewReader(b.Bytes()), int64(b.Len()))
+		require.NoError(t, err)
+		leaf, ok := file.Schema().Lookup("value")
+		require.True(t, ok)
+		return file.RowGroups()[0].ColumnChunks()[leaf.ColumnIndex].BloomFilter()
+	}
+
+	require.Nil(t, valueBloomFilter(t, NewSampleSchema()))
+
+	schema := NewSampleSchema()
+	require.Error(t, schema.SetIndexedColumns("unknown"))
+	require.Error(t, schema.SetIndexedColumns("labels"))
+	require.NoError(t, schema.SetIndexedColumns("value", "labels.label1"))
+	require.Equal(t, []string{"value", "labels.label1"}, schema.IndexedColumns())
+
+	bf := valueBloomFilter(t, schema)
+	require.NotNil(t, bf)
+	ok, err := bf.Check(parquet.ValueOf(int64(3)))
+	require.NoError(t, err)
+	require.True(t, ok)
+	// 2 is within the min/max range of the column chunk but not in the bloom
+	// filter.
+	ok, err = bf.Check(parquet.ValueOf(int64(2)))
+	require.NoError(t, err)
+	require.False(t, ok)
+}
@@ -22,6 +22,59 @@ const (
 	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
 )
 
+// MergePolicy determines how rows with equal values in all sorting columns are merged when the table's data is compacted.
+type MergePolicy int32
+
+const (
+	// Keep all rows.
+	MergePolicy_MERGE_POLICY_KEEP_ALL_UNSPECIFIED MergePolicy = 0
+	// Keep the most recently inserted row.
+	MergePolicy_MERGE_POLICY_KEEP_LATEST MergePolicy = 1
+	// Merge the rows using the reducer named by merge_reducer.
+	MergePolicy_MERGE_POLICY_REDUCE MergePolicy = 2
+)
+
+// Enum value maps for MergePolicy.
+var (
+	MergePolicy_name = map[int32]string{
+		0: "MERGE_POLICY_KEEP_ALL_UNSPECIFIED",
+		1: "MERGE_POLICY_KEEP_LATEST",
+		2: "MERGE_POLICY_REDUCE",
+	}
+	MergePolicy_value = map[string]int32{
+		"MERGE_POLICY_KEEP_ALL_UNSPECIFIED": 0,
+		"MERGE_POLICY_KEEP_LATEST":          1,
+		"MERGE_POLICY_REDUCE":               2,
+	}
+)
+
+func (x MergePolicy) Enum() *MergePolicy {
+	p := new(MergePolicy)
+	*p = x
+	return p
+}
+
+func (x MergePolicy) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (MergePolicy) Descriptor() protoreflect.EnumDescriptor {
+	return file_frostdb_table_v1alpha1_config_proto_enumTypes[0].Descriptor()
+}
+
+func (MergePolicy) Type() protoreflect.EnumType {
+	return &file_frostdb_table_v1alpha1_config_proto_enumTypes[0]
+}
+
+func (x MergePolicy) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use MergePolicy.Descriptor instead.
+func (MergePolicy) EnumDescriptor() ([]byte, []int) {
+	return file_frostdb_table_v1alpha1_config_proto_rawDescGZIP(), []int{0}
+}
+
 // TableConfig is the configuration information for a table.
 type TableConfig struct {
 	state         protoimpl.MessageState
@@ -41,6 +94,46 @@ type TableConfig struct {
 	BlockReaderLimit uint64 `protobuf:"varint,4,opt,name=block_reader_limit,json=blockReaderLimit,proto3" json:"block_reader_limit,omitempty"`
 	// DisableWal disables the write ahead log for this table.
 	DisableWal bool `protobuf:"varint,5,opt,name=disable_wal,json=disableWal,proto3" json:"disable_wal,omitempty"`
+	// RetentionMs is the retention window of the table in milliseconds. Data older than the retention window is dropped. Zero disables retention.
+	RetentionMs uint64 `protobuf:"varint,6,opt,name=retention_ms,json=retentionMs,proto3" json:"retention_ms,omitempty"`
+	// IndexedColumns are additional columns that bloom filters are written for, so that row groups can be skipped when filtering by these columns.
+	IndexedColumns []string `protobuf:"bytes,7,rep,name=indexed_columns,json=indexedColumns,proto3" json:"indexed_columns,omitempty"`
+	// SortOrders are secondary sort orders the table data is additionally maintained in, so that queries can read the layout best matching their filters and groupings.
+	SortOrders []*SortOrder `protobuf:"bytes,8,rep,name=sort_orders,json=sortOrders,proto3" json:"sort_orders,omitempty"`
+	// UnsortedDynamicColumns excludes dynamic columns from the sorting columns of the table, so that they are stored as unsorted payload.
+	UnsortedDynamicColumns bool `protobuf:"varint,9,opt,name=unsorted_dynamic_columns,json=unsortedDynamicColumns,proto3" json:"unsorted_dynamic_columns,omitempty"`
+	// MonotonicTimestampsCacheSize is the number of series whose last inserted timestamp is tracked to detect inserts of rows older than the last row of their series. A series is identified by the values of the sorting columns other than the timestamp. Zero disables tracking.
+	MonotonicTimestampsCacheSize uint64 `protobuf:"varint,10,opt,name=monotonic_timestamps_cache_size,json=monotonicTimestampsCacheSize,proto3" json:"monotonic_timestamps_cache_size,omitempty"`
+	// RejectNonMonotonicTimestamps rejects inserts containing rows older than the last row of their series instead of only counting them.
+	RejectNonMonotonicTimestamps bool `protobuf:"varint,11,opt,name=reject_non_monotonic_timestamps,json=rejectNonMonotonicTimestamps,proto3" json:"reject_non_monotonic_timestamps,omitempty"`
+	// RetentionColumn is the int64 column retention is enforced on. Defaults to "timestamp".
+	RetentionColumn string `protobuf:"bytes,12,opt,name=retention_column,json=retentionColumn,proto3" json:"retention_column,omitempty"`
+	// RetentionColumnUnitNs is the unit of the values of the retention column in nanoseconds, e.g. 1000000 for milliseconds since the Unix epoch. Defaults to milliseconds.
+	RetentionColumnUnitNs uint64 `protobuf:"varint,13,opt,name=retention_column_unit_ns,json=retentionColumnUnitNs,proto3" json:"retention_column_unit_ns,omitempty"`
+	// MonotonicTimestampsColumn is the int64 column monotonic timestamps are enforced on. Defaults to "timestamp".
+	MonotonicTimestampsColumn string `protobuf:"bytes,14,opt,name=monotonic_timestamps_column,json=monotonicTimestampsColumn,proto3" json:"monotonic_timestamps_column,omitempty"`
+	// StorageQuotaBytes is the maximum number of bytes the table may use for persisted blocks and in-memory data. Inserts are rejected once it is exceeded. Zero disables the quota.
+	StorageQuotaBytes uint64 `protobuf:"varint,15,opt,name=storage_quota_bytes,json=storageQuotaBytes,proto3" json:"storage_quota_bytes,omitempty"`
+	// MergePolicy determines how rows with equal values in all sorting columns are merged when the table's data is compacted. It cannot be used with a unique primary index.
+	MergePolicy MergePolicy `protobuf:"varint,16,opt,name=merge_policy,json=mergePolicy,proto3,enum=frostdb.table.v1alpha1.MergePolicy" json:"merge_policy,omitempty"`
+	// MergeReducer is the name of the reducer rows are merged with if the merge policy is MERGE_POLICY_REDUCE. Reducers are registered with the column store.
+	MergeReducer string `protobuf:"bytes,17,opt,name=merge_reducer,json=mergeReducer,proto3" json:"merge_reducer,omitempty"`
+	// ActiveMemoryQuotaBytes is the maximum number of bytes of in-memory data of the table, including blocks that are still being persisted. Inserts are rejected once it is exceeded. Zero disables the quota.
+	ActiveMemoryQuotaBytes uint64 `protobuf:"varint,18,opt,name=active_memory_quota_bytes,json=activeMemoryQuotaBytes,proto3" json:"active_memory_quota_bytes,omitempty"`
+	// BucketQuotaBytes is the maximum number of bytes of persisted blocks of the table. Inserts are rejected once it is exceeded. Zero disables the quota.
+	BucketQuotaBytes uint64 `protobuf:"varint,19,opt,name=bucket_quota_bytes,json=bucketQuotaBytes,proto3" json:"bucket_quota_bytes,omitempty"`
+	// WalQuotaBytes is the maximum number of bytes of inserts into the table that are logged to the write ahead log and not yet covered by a snapshot or a persisted block. Inserts are rejected once it is exceeded. Zero disables the quota.
+	WalQuotaBytes uint64 `protobuf:"varint,20,opt,name=wal_quota_bytes,json=walQuotaBytes,proto3" json:"wal_quota_bytes,omitempty"`
+	// PartitionDurationMs partitions the persisted blocks of the table into time partitions of the given duration by the values of the partition column, e.g. 3600000 for hourly partitions. The active block is rotated when a partition boundary is crossed. Zero disables partitioning.
+	PartitionDurationMs uint64 `protobuf:"varint,21,opt,name=partition_duration_ms,json=partitionDurationMs,proto3" json:"partition_duration_ms,omitempty"`
+	// PartitionColumn is the name of the int64 column blocks are partitioned by. Defaults to "timestamp".
+	PartitionColumn string `protobuf:"bytes,22,opt,name=partition_column,json=partitionColumn,proto3" json:"partition_column,omitempty"`
+	// PartitionColumnUnitNs is the unit of the values of the partition column in nanoseconds. Defaults to milliseconds.
+	PartitionColumnUnitNs uint64 `protobuf:"varint,23,opt,name=partition_column_unit_ns,json=partitionColumnUnitNs,proto3" json:"partition_column_unit_ns,omitempty"`
+	// V1Alpha1DualRead enables reading persisted data written under a v1alpha1 schema from a table using a v1alpha2 schema. The data is reshaped into the v1alpha2 layout using v1alpha1_field_mapping.
+	V1Alpha1DualRead bool `protobuf:"varint,24,opt,name=v1alpha1_dual_read,json=v1alpha1DualRead,proto3" json:"v1alpha1_dual_read,omitempty"`
+	// V1Alpha1FieldMapping maps v1alpha1 column names to v1alpha2 paths when dual-reading. Dots in a path denote nesting. Columns without a mapping keep their name.
+	V1Alpha1FieldMapping map[string]string `protobuf:"bytes,25,rep,name=v1alpha1_field_mapping,json=v1alpha1FieldMapping,proto3" json:"v1alpha1_field_mapping,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
 }
 
 func (x *TableConfig) Reset() {
@@ -117,6 +210,146 @@ func (x *TableConfig) GetDisableWal() bool {
 	return false
 }
 
+func (x *TableConfig) GetRetentionMs() uint64 {
+	if x != nil {
+		return x.RetentionMs
+	}
+	return 0
+}
+
+func (x *TableConfig) GetIndexedColumns() []string {
+	if x != nil {
+		return x.IndexedColumns
+	}
+	return nil
+}
+
+func (x *TableConfig) GetSortOrders() []*SortOrder {
+	if x != nil {
+		return x.SortOrders
+	}
+	return nil
+}
+
+func (x *TableConfig) GetUnsortedDynamicColumns() bool {
+	if x != nil {
+		return x.UnsortedDynamicColumns
+	}
+	return false
+}
+
+func (x *TableConfig) GetMonotonicTimestampsCacheSize() uint64 {
+	if x != nil {
+		return x.MonotonicTimestampsCacheSize
+	}
+	return 0
+}
+
+func (x *TableConfig) GetRejectNonMonotonicTimestamps() bool {
+	if x != nil {
+		return x.RejectNonMonotonicTimestamps
+	}
+	return false
+}
+
+func (x *TableConfig) GetRetentionColumn() string {
+	if x != nil {
+		return x.RetentionColumn
+	}
+	return ""
+}
+
+func (x *TableConfig) GetRetentionColumnUnitNs() uint64 {
+	if x != nil {
+		return x.RetentionColumnUnitNs
+	}
+	return 0
+}
+
+func (x *TableConfig) GetMonotonicTimestampsColumn() string {
+	if x != nil {
+		return x.MonotonicTimestampsColumn
+	}
+	return ""
+}
+
+func (x *TableConfig) GetStorageQuotaBytes() uint64 {
+	if x != nil {
+		return x.StorageQuotaBytes
+	}
+	return 0
+}
+
+func (x *TableConfig) GetMergePolicy() MergePolicy {
+	if x != nil {
+		return x.MergePolicy
+	}
+	return MergePolicy_MERGE_POLICY_KEEP_ALL_UNSPECIFIED
+}
+
+func (x *TableConfig) GetMergeReducer() string {
+	if x != nil {
+		return x.MergeReducer
+	}
+	return ""
+}
+
+func (x *TableConfig) GetActiveMemoryQuotaBytes() uint64 {
+	if x != nil {
+		return x.ActiveMemoryQuotaBytes
+	}
+	return 0
+}
+
+func (x *TableConfig) GetBucketQuotaBytes() uint64 {
+	if x != nil {
+		return x.BucketQuotaBytes
+	}
+	return 0
+}
+
+func (x *TableConfig) GetWalQuotaBytes() uint64 {
+	if x != nil {
+		return x.WalQuotaBytes
+	}
+	return 0
+}
+
+func (x *TableConfig) GetPartitionDurationMs() uint64 {
+	if x != nil {
+		return x.PartitionDurationMs
+	}
+	return 0
+}
+
+func (x *TableConfig) GetPartitionColumn() string {
+	if x != nil {
+		return x.PartitionColumn
+	}
+	return ""
+}
+
+func (x *TableConfig) GetPartitionColumnUnitNs() uint64 {
+	if x != nil {
+		return x.PartitionColumnUnitNs
+	}
+	return 0
+}
+
+func (x *TableConfig) GetV1Alpha1DualRead() bool {
+	if x != nil {
+		return x.V1Alpha1DualRead
+	}
+	return false
+}
+
+func (x *TableConfig) GetV1Alpha1FieldMapping() map[string]string {
+	if x != nil {
+		return x.V1Alpha1FieldMapping
+	}
+	return nil
+}
+
 type isTableConfig_Schema interface {
 	isTableConfig_Schema()
 }
@@ -135,6 +368,64 @@ func (*TableConfig_DeprecatedSchema) isTableConfig_Schema() {}
 
 func (*TableConfig_SchemaV2) isTableConfig_Schema() {}
 
+// SortOrder is a secondary sort order of a table.
+type SortOrder struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// Name of the sort order. It must be unique within the table.
+	Name string `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	// SortingColumns the data is sorted by in this sort order.
+	SortingColumns []*v1alpha2.SortingColumn `protobuf:"bytes,2,rep,name=sorting_columns,json=sortingColumns,proto3" json:"sorting_columns,omitempty"`
+}
+
+func (x *SortOrder) Reset() {
+	*x = SortOrder{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_frostdb_table_v1alpha1_config_proto_msgTypes[1]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *SortOrder) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SortOrder) ProtoMessage() {}
+
+func (x *SortOrder) ProtoReflect() protoreflect.Message {
+	mi := &file_frostdb_table_v1alpha1_config_proto_msgTypes[1]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SortOrder.ProtoReflect.Descriptor instead.
+func (*SortOrder) Descriptor() ([]byte, []int) {
+	return file_frostdb_table_v1alpha1_config_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *SortOrder) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *SortOrder) GetSortingColumns() []*v1alpha2.SortingColumn {
+	if x != nil {
+		return x.SortingColumns
+	}
+	return nil
+}
+
 var File_frostdb_table_v1alpha1_config_proto protoreflect.FileDescriptor
 
 var file_frostdb_table_v1alpha1_config_proto_rawDesc = []byte{
@@ -146,7 +437,7 @@ var file_frostdb_table_v1alpha1_config_proto_rawDesc = []byte{
 	0x61, 0x6c, 0x70, 0x68, 0x61, 0x31, 0x2f, 0x73, 0x63, 0x68, 0x65, 0x6d, 0x61, 0x2e, 0x70, 0x72,
 	0x6f, 0x74, 0x6f, 0x1a, 0x24, 0x66, 0x72, 0x6f, 0x73, 0x74, 0x64, 0x62, 0x2f, 0x73, 0x63, 0x68,
 	0x65, 0x6d, 0x61, 0x2f, 0x76, 0x31, 0x61, 0x6c, 0x70, 0x68, 0x61, 0x32, 0x2f, 0x73, 0x63, 0x68,
-	0x65, 0x6d, 0x61, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x22, 0x9c, 0x02, 0x0a, 0x0b, 0x54, 0x61,
+	0x65, 0x6d, 0x61, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x22, 0xca, 0x0b, 0x0a, 0x0b, 0x54, 0x61,
 	0x62, 0x6c, 0x65, 0x43, 0x6f, 0x6e, 0x66, 0x69, 0x67, 0x12, 0x4e, 0x0a, 0x11, 0x64, 0x65, 0x70,
 	0x72, 0x65, 0x63, 0x61, 0x74, 0x65, 0x64, 0x5f, 0x73, 0x63, 0x68, 0x65, 0x6d, 0x61, 0x18, 0x01,
 	0x20, 0x01, 0x28, 0x0b, 0x32, 0x1f, 0x2e, 0x66, 0x72, 0x6f, 0x73, 0x74, 0x64, 0x62, 0x2e, 0x73,
@@ -163,24 +454,113 @@ var file_frostdb_table_v1alpha1_config_proto_rawDesc = []byte{
 	0x6c, 0x69, 0x6d, 0x69, 0x74, 0x18, 0x04, 0x20, 0x01, 0x28, 0x04, 0x52, 0x10, 0x62, 0x6c, 0x6f,
 	0x63, 0x6b, 0x52, 0x65, 0x61, 0x64, 0x65, 0x72, 0x4c, 0x69, 0x6d, 0x69, 0x74, 0x12, 0x1f, 0x0a,
 	0x0b, 0x64, 0x69, 0x73, 0x61, 0x62, 0x6c, 0x65, 0x5f, 0x77, 0x61, 0x6c, 0x18, 0x05, 0x20, 0x01,
-	0x28, 0x08, 0x52, 0x0a, 0x64, 0x69, 0x73, 0x61, 0x62, 0x6c, 0x65, 0x57, 0x61, 0x6c, 0x42, 0x08,
-	0x0a, 0x06, 0x73, 0x63, 0x68, 0x65, 0x6d, 0x61, 0x42, 0xf6, 0x01, 0x0a, 0x1a, 0x63, 0x6f, 0x6d,
+	0x28, 0x08, 0x52, 0x0a, 0x64, 0x69, 0x73, 0x61, 0x62, 0x6c, 0x65, 0x57, 0x61, 0x6c, 0x12, 0x21,
+	0x0a, 0x0c, 0x72, 0x65, 0x74, 0x65, 0x6e, 0x74, 0x69, 0x6f, 0x6e, 0x5f, 0x6d, 0x73, 0x18, 0x06,
+	0x20, 0x01, 0x28, 0x04, 0x52, 0x0b, 0x72, 0x65, 0x74, 0x65, 0x6e, 0x74, 0x69, 0x6f, 0x6e, 0x4d,
+	0x73, 0x12, 0x27, 0x0a, 0x0f, 0x69, 0x6e, 0x64, 0x65, 0x78, 0x65, 0x64, 0x5f, 0x63, 0x6f, 0x6c,
+	0x75, 0x6d, 0x6e, 0x73, 0x18, 0x07, 0x20, 0x03, 0x28, 0x09, 0x52, 0x0e, 0x69, 0x6e, 0x64, 0x65,
+	0x78, 0x65, 0x64, 0x43, 0x6f, 0x6c, 0x75, 0x6d, 0x6e, 0x73, 0x12, 0x42, 0x0a, 0x0b, 0x73, 0x6f,
+	0x72, 0x74, 0x5f, 0x6f, 0x72, 0x64, 0x65, 0x72, 0x73, 0x18, 0x08, 0x20, 0x03, 0x28, 0x0b, 0x32,
+	0x21, 0x2e, 0x66, 0x72, 0x6f, 0x73, 0x74, 0x64, 0x62, 0x2e, 0x74, 0x61, 0x62, 0x6c, 0x65, 0x2e,
+	0x76, 0x31, 0x61, 0x6c, 0x70, 0x68, 0x61, 0x31, 0x2e, 0x53, 0x6f, 0x72, 0x74, 0x4f, 0x72, 0x64,
+	0x65, 0x72, 0x52, 0x0a, 0x73, 0x6f, 0x72, 0x74, 0x4f, 0x72, 0x64, 0x65, 0x72, 0x73, 0x12, 0x38,
+	0x0a, 0x18, 0x75, 0x6e, 0x73, 0x6f, 0x72, 0x74, 0x65, 0x64, 0x5f, 0x64, 0x79, 0x6e, 0x61, 0x6d,
+	0x69, 0x63, 0x5f, 0x63, 0x6f, 0x6c, 0x75, 0x6d, 0x6e, 0x73, 0x18, 0x09, 0x20, 0x01, 0x28, 0x08,
+	0x52, 0x16, 0x75, 0x6e, 0x73, 0x6f, 0x72, 0x74, 0x65, 0x64, 0x44, 0x79, 0x6e, 0x61, 0x6d, 0x69,
+	0x63, 0x43, 0x6f, 0x6c, 0x75, 0x6d, 0x6e, 0x73, 0x12, 0x45, 0x0a, 0x1f, 0x6d, 0x6f, 0x6e, 0x6f,
+	0x74, 0x6f, 0x6e, 0x69, 0x63, 0x5f, 0x74, 0x69, 0x6d, 0x65, 0x73, 0x74, 0x61, 0x6d, 0x70, 0x73,
+	0x5f, 0x63, 0x61, 0x63, 0x68, 0x65, 0x5f, 0x73, 0x69, 0x7a, 0x65, 0x18, 0x0a, 0x20, 0x01, 0x28,
+	0x04, 0x52, 0x1c, 0x6d, 0x6f, 0x6e, 0x6f, 0x74, 0x6f, 0x6e, 0x69, 0x63, 0x54, 0x69, 0x6d, 0x65,
+	0x73, 0x74, 0x61, 0x6d, 0x70, 0x73, 0x43, 0x61, 0x63, 0x68, 0x65, 0x53, 0x69, 0x7a, 0x65, 0x12,
+	0x45, 0x0a, 0x1f, 0x72, 0x65, 0x6a, 0x65, 0x63, 0x74, 0x5f, 0x6e, 0x6f, 0x6e, 0x5f, 0x6d, 0x6f,
+	0x6e, 0x6f, 0x74, 0x6f, 0x6e, 0x69, 0x63, 0x5f, 0x74, 0x69, 0x6d, 0x65, 0x73, 0x74, 0x61, 0x6d,
+	0x70, 0x73, 0x18, 0x0b, 0x20, 0x01, 0x28, 0x08, 0x52, 0x1c, 0x72, 0x65, 0x6a, 0x65, 0x63, 0x74,
+	0x4e, 0x6f, 0x6e, 0x4d, 0x6f, 0x6e, 0x6f, 0x74, 0x6f, 0x6e, 0x69, 0x63, 0x54, 0x69, 0x6d, 0x65,
+	0x73, 0x74, 0x61, 0x6d, 0x70, 0x73, 0x12, 0x29, 0x0a, 0x10, 0x72, 0x65, 0x74, 0x65, 0x6e, 0x74,
+	0x69, 0x6f, 0x6e, 0x5f, 0x63, 0x6f, 0x6c, 0x75, 0x6d, 0x6e, 0x18, 0x0c, 0x20, 0x01, 0x28, 0x09,
+	0x52, 0x0f, 0x72, 0x65, 0x74, 0x65, 0x6e, 0x74, 0x69, 0x6f, 0x6e, 0x43, 0x6f, 0x6c, 0x75, 0x6d,
+	0x6e, 0x12, 0x37, 0x0a, 0x18, 0x72, 0x65, 0x74, 0x65, 0x6e, 0x74, 0x69, 0x6f, 0x6e, 0x5f, 0x63,
+	0x6f, 0x6c, 0x75, 0x6d, 0x6e, 0x5f, 0x75, 0x6e, 0x69, 0x74, 0x5f, 0x6e, 0x73, 0x18, 0x0d, 0x20,
+	0x01, 0x28, 0x04, 0x52, 0x15, 0x72, 0x65, 0x74, 0x65, 0x6e, 0x74, 0x69, 0x6f, 0x6e, 0x43, 0x6f,
+	0x6c, 0x75, 0x6d, 0x6e, 0x55, 0x6e, 0x69, 0x74, 0x4e, 0x73, 0x12, 0x3e, 0x0a, 0x1b, 0x6d, 0x6f,
+	0x6e, 0x6f, 0x74, 0x6f, 0x6e, 0x69, 0x63, 0x5f, 0x74, 0x69, 0x6d, 0x65, 0x73, 0x74, 0x61, 0x6d,
+	0x70, 0x73, 0x5f, 0x63, 0x6f, 0x6c, 0x75, 0x6d, 0x6e, 0x18, 0x0e, 0x20, 0x01, 0x28, 0x09, 0x52,
+	0x19, 0x6d, 0x6f, 0x6e, 0x6f, 0x74, 0x6f, 0x6e, 0x69, 0x63, 0x54, 0x69, 0x6d, 0x65, 0x73, 0x74,
+	0x61, 0x6d, 0x70, 0x73, 0x43, 0x6f, 0x6c, 0x75, 0x6d, 0x6e, 0x12, 0x2e, 0x0a, 0x13, 0x73, 0x74,
+	0x6f, 0x72, 0x61, 0x67, 0x65, 0x5f, 0x71, 0x75, 0x6f, 0x74, 0x61, 0x5f, 0x62, 0x79, 0x74, 0x65,
+	0x73, 0x18, 0x0f, 0x20, 0x01, 0x28, 0x04, 0x52, 0x11, 0x73, 0x74, 0x6f, 0x72, 0x61, 0x67, 0x65,
+	0x51, 0x75, 0x6f, 0x74, 0x61, 0x42, 0x79, 0x74, 0x65, 0x73, 0x12, 0x46, 0x0a, 0x0c, 0x6d, 0x65,
+	0x72, 0x67, 0x65, 0x5f, 0x70, 0x6f, 0x6c, 0x69, 0x63, 0x79, 0x18, 0x10, 0x20, 0x01, 0x28, 0x0e,
+	0x32, 0x23, 0x2e, 0x66, 0x72, 0x6f, 0x73, 0x74, 0x64, 0x62, 0x2e, 0x74, 0x61, 0x62, 0x6c, 0x65,
+	0x2e, 0x76, 0x31, 0x61, 0x6c, 0x70, 0x68, 0x61, 0x31, 0x2e, 0x4d, 0x65, 0x72, 0x67, 0x65, 0x50,
+	0x6f, 0x6c, 0x69, 0x63, 0x79, 0x52, 0x0b, 0x6d, 0x65, 0x72, 0x67, 0x65, 0x50, 0x6f, 0x6c, 0x69,
+	0x63, 0x79, 0x12, 0x23, 0x0a, 0x0d, 0x6d, 0x65, 0x72, 0x67, 0x65, 0x5f, 0x72, 0x65, 0x64, 0x75,
+	0x63, 0x65, 0x72, 0x18, 0x11, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0c, 0x6d, 0x65, 0x72, 0x67, 0x65,
+	0x52, 0x65, 0x64, 0x75, 0x63, 0x65, 0x72, 0x12, 0x39, 0x0a, 0x19, 0x61, 0x63, 0x74, 0x69, 0x76,
+	0x65, 0x5f, 0x6d, 0x65, 0x6d, 0x6f, 0x72, 0x79, 0x5f, 0x71, 0x75, 0x6f, 0x74, 0x61, 0x5f, 0x62,
+	0x79, 0x74, 0x65, 0x73, 0x18, 0x12, 0x20, 0x01, 0x28, 0x04, 0x52, 0x16, 0x61, 0x63, 0x74, 0x69,
+	0x76, 0x65, 0x4d, 0x65, 0x6d, 0x6f, 0x72, 0x79, 0x51, 0x75, 0x6f, 0x74, 0x61, 0x42, 0x79, 0x74,
+	0x65, 0x73, 0x12, 0x2c, 0x0a, 0x12, 0x62, 0x75, 0x63, 0x6b, 0x65, 0x74, 0x5f, 0x71, 0x75, 0x6f,
+	0x74, 0x61, 0x5f, 0x62, 0x79, 0x74, 0x65, 0x73, 0x18, 0x13, 0x20, 0x01, 0x28, 0x04, 0x52, 0x10,
+	0x62, 0x75, 0x63, 0x6b, 0x65, 0x74, 0x51, 0x75, 0x6f, 0x74, 0x61, 0x42, 0x79, 0x74, 0x65, 0x73,
+	0x12, 0x26, 0x0a, 0x0f, 0x77, 0x61, 0x6c, 0x5f, 0x71, 0x75, 0x6f, 0x74, 0x61, 0x5f, 0x62, 0x79,
+	0x74, 0x65, 0x73, 0x18, 0x14, 0x20, 0x01, 0x28, 0x04, 0x52, 0x0d, 0x77, 0x61, 0x6c, 0x51, 0x75,
+	0x6f, 0x74, 0x61, 0x42, 0x79, 0x74, 0x65, 0x73, 0x12, 0x32, 0x0a, 0x15, 0x70, 0x61, 0x72, 0x74,
+	0x69, 0x74, 0x69, 0x6f, 0x6e, 0x5f, 0x64, 0x75, 0x72, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x5f, 0x6d,
+	0x73, 0x18, 0x15, 0x20, 0x01, 0x28, 0x04, 0x52, 0x13, 0x70, 0x61, 0x72, 0x74, 0x69, 0x74, 0x69,
+	0x6f, 0x6e, 0x44, 0x75, 0x72, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x4d, 0x73, 0x12, 0x29, 0x0a, 0x10,
+	0x70, 0x61, 0x72, 0x74, 0x69, 0x74, 0x69, 0x6f, 0x6e, 0x5f, 0x63, 0x6f, 0x6c, 0x75, 0x6d, 0x6e,
+	0x18, 0x16, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0f, 0x70, 0x61, 0x72, 0x74, 0x69, 0x74, 0x69, 0x6f,
+	0x6e, 0x43, 0x6f, 0x6c, 0x75, 0x6d, 0x6e, 0x12, 0x37, 0x0a, 0x18, 0x70, 0x61, 0x72, 0x74, 0x69,
+	0x74, 0x69, 0x6f, 0x6e, 0x5f, 0x63, 0x6f, 0x6c, 0x75, 0x6d, 0x6e, 0x5f, 0x75, 0x6e, 0x69, 0x74,
+	0x5f, 0x6e, 0x73, 0x18, 0x17, 0x20, 0x01, 0x28, 0x04, 0x52, 0x15, 0x70, 0x61, 0x72, 0x74, 0x69,
+	0x74, 0x69, 0x6f, 0x6e, 0x43, 0x6f, 0x6c, 0x75, 0x6d, 0x6e, 0x55, 0x6e, 0x69, 0x74, 0x4e, 0x73,
+	0x12, 0x2c, 0x0a, 0x12, 0x76, 0x31, 0x61, 0x6c, 0x70, 0x68, 0x61, 0x31, 0x5f, 0x64, 0x75, 0x61,
+	0x6c, 0x5f, 0x72, 0x65, 0x61, 0x64, 0x18, 0x18, 0x20, 0x01, 0x28, 0x08, 0x52, 0x10, 0x76, 0x31,
+	0x61, 0x6c, 0x70, 0x68, 0x61, 0x31, 0x44, 0x75, 0x61, 0x6c, 0x52, 0x65, 0x61, 0x64, 0x12, 0x73,
+	0x0a, 0x16, 0x76, 0x31, 0x61, 0x6c, 0x70, 0x68, 0x61, 0x31, 0x5f, 0x66, 0x69, 0x65, 0x6c, 0x64,
+	0x5f, 0x6d, 0x61, 0x70, 0x70, 0x69, 0x6e, 0x67, 0x18, 0x19, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x3d,
 	0x2e, 0x66, 0x72, 0x6f, 0x73, 0x74, 0x64, 0x62, 0x2e, 0x74, 0x61, 0x62, 0x6c, 0x65, 0x2e, 0x76,
-	0x31, 0x61, 0x6c, 0x70, 0x68, 0x61, 0x31, 0x42, 0x0b, 0x43, 0x6f, 0x6e, 0x66, 0x69, 0x67, 0x50,
-	0x72, 0x6f, 0x74, 0x6f, 0x50, 0x01, 0x5a, 0x51, 0x67, 0x69, 0x74, 0x68, 0x75, 0x62, 0x2e, 0x63,
-	0x6f, 0x6d, 0x2f, 0x70, 0x6f, 0x6c, 0x61, 0x72, 0x73, 0x69, 0x67, 0x6e, 0x61, 0x6c, 0x73, 0x2f,
-	0x66, 0x72, 0x6f, 0x73, 0x74, 0x64, 0x62, 0x2f, 0x67, 0x65, 0x6e, 0x2f, 0x70, 0x72, 0x6f, 0x74,
-	0x6f, 0x2f, 0x67, 0x6f, 0x2f, 0x66, 0x72, 0x6f, 0x73, 0x74, 0x64, 0x62, 0x2f, 0x74, 0x61, 0x62,
-	0x6c, 0x65, 0x2f, 0x76, 0x31, 0x61, 0x6c, 0x70, 0x68, 0x61, 0x31, 0x3b, 0x74, 0x61, 0x62, 0x6c,
-	0x65, 0x76, 0x31, 0x61, 0x6c, 0x70, 0x68, 0x61, 0x31, 0xa2, 0x02, 0x03, 0x46, 0x54, 0x58, 0xaa,
-	0x02, 0x16, 0x46, 0x72, 0x6f, 0x73, 0x74, 0x64, 0x62, 0x2e, 0x54, 0x61, 0x62, 0x6c, 0x65, 0x2e,
-	0x56, 0x31, 0x61, 0x6c, 0x70, 0x68, 0x61, 0x31, 0xca, 0x02, 0x16, 0x46, 0x72, 0x6f, 0x73, 0x74,
-	0x64, 0x62, 0x5c, 0x54, 0x61, 0x62, 0x6c, 0x65, 0x5c, 0x56, 0x31, 0x61, 0x6c, 0x70, 0x68, 0x61,
-	0x31, 0xe2, 0x02, 0x22, 0x46, 0x72, 0x6f, 0x73, 0x74, 0x64, 0x62, 0x5c, 0x54, 0x61, 0x62, 0x6c,
-	0x65, 0x5c, 0x56, 0x31, 0x61, 0x6c, 0x70, 0x68, 0x61, 0x31, 0x5c, 0x47, 0x50, 0x42, 0x4d, 0x65,
-	0x74, 0x61, 0x64, 0x61, 0x74, 0x61, 0xea, 0x02, 0x18, 0x46, 0x72, 0x6f, 0x73, 0x74, 0x64, 0x62,
-	0x3a, 0x3a, 0x54, 0x61, 0x62, 0x6c, 0x65, 0x3a, 0x3a, 0x56, 0x31, 0x61, 0x6c, 0x70, 0x68, 0x61,
-	0x31, 0x62, 0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
+	0x31, 0x61, 0x6c, 0x70, 0x68, 0x61, 0x31, 0x2e, 0x54, 0x61, 0x62, 0x6c, 0x65, 0x43, 0x6f, 0x6e,
+	0x66, 0x69, 0x67, 0x2e, 0x56, 0x31, 0x61, 0x6c, 0x70, 0x68, 0x61, 0x31, 0x46, 0x69, 0x65, 0x6c,
+	0x64, 0x4d, 0x61, 0x70, 0x70, 0x69, 0x6e, 0x67, 0x45, 0x6e, 0x74, 0x72, 0x79, 0x52, 0x14, 0x76,
+	0x31, 0x61, 0x6c, 0x70, 0x68, 0x61, 0x31, 0x46, 0x69, 0x65, 0x6c, 0x64, 0x4d, 0x61, 0x70, 0x70,
+	0x69, 0x6e, 0x67, 0x1a, 0x47, 0x0a, 0x19, 0x56, 0x31, 0x61, 0x6c, 0x70, 0x68, 0x61, 0x31, 0x46,
+	0x69, 0x65, 0x6c, 0x64, 0x4d, 0x61, 0x70, 0x70, 0x69, 0x6e, 0x67, 0x45, 0x6e, 0x74, 0x72, 0x79,
+	0x12, 0x10, 0x0a, 0x03, 0x6b, 0x65, 0x79, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x03, 0x6b,
+	0x65, 0x79, 0x12, 0x14, 0x0a, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28,
+	0x09, 0x52, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x3a, 0x02, 0x38, 0x01, 0x42, 0x08, 0x0a, 0x06,
+	0x73, 0x63, 0x68, 0x65, 0x6d, 0x61, 0x22, 0x70, 0x0a, 0x09, 0x53, 0x6f, 0x72, 0x74, 0x4f, 0x72,
+	0x64, 0x65, 0x72, 0x12, 0x12, 0x0a, 0x04, 0x6e, 0x61, 0x6d, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28,
+	0x09, 0x52, 0x04, 0x6e, 0x61, 0x6d, 0x65, 0x12, 0x4f, 0x0a, 0x0f, 0x73, 0x6f, 0x72, 0x74, 0x69,
+	0x6e, 0x67, 0x5f, 0x63, 0x6f, 0x6c, 0x75, 0x6d, 0x6e, 0x73, 0x18, 0x02, 0x20, 0x03, 0x28, 0x0b,
+	0x32, 0x26, 0x2e, 0x66, 0x72, 0x6f, 0x73, 0x74, 0x64, 0x62, 0x2e, 0x73, 0x63, 0x68, 0x65, 0x6d,
+	0x61, 0x2e, 0x76, 0x31, 0x61, 0x6c, 0x70, 0x68, 0x61, 0x32, 0x2e, 0x53, 0x6f, 0x72, 0x74, 0x69,
+	0x6e, 0x67, 0x43, 0x6f, 0x6c, 0x75, 0x6d, 0x6e, 0x52, 0x0e, 0x73, 0x6f, 0x72, 0x74, 0x69, 0x6e,
+	0x67, 0x43, 0x6f, 0x6c, 0x75, 0x6d, 0x6e, 0x73, 0x2a, 0x6b, 0x0a, 0x0b, 0x4d, 0x65, 0x72, 0x67,
+	0x65, 0x50, 0x6f, 0x6c, 0x69, 0x63, 0x79, 0x12, 0x25, 0x0a, 0x21, 0x4d, 0x45, 0x52, 0x47, 0x45,
+	0x5f, 0x50, 0x4f, 0x4c, 0x49, 0x43, 0x59, 0x5f, 0x4b, 0x45, 0x45, 0x50, 0x5f, 0x41, 0x4c, 0x4c,
+	0x5f, 0x55, 0x4e, 0x53, 0x50, 0x45, 0x43, 0x49, 0x46, 0x49, 0x45, 0x44, 0x10, 0x00, 0x12, 0x1c,
+	0x0a, 0x18, 0x4d, 0x45, 0x52, 0x47, 0x45, 0x5f, 0x50, 0x4f, 0x4c, 0x49, 0x43, 0x59, 0x5f, 0x4b,
+	0x45, 0x45, 0x50, 0x5f, 0x4c, 0x41, 0x54, 0x45, 0x53, 0x54, 0x10, 0x01, 0x12, 0x17, 0x0a, 0x13,
+	0x4d, 0x45, 0x52, 0x47, 0x45, 0x5f, 0x50, 0x4f, 0x4c, 0x49, 0x43, 0x59, 0x5f, 0x52, 0x45, 0x44,
+	0x55, 0x43, 0x45, 0x10, 0x02, 0x42, 0xf6, 0x01, 0x0a, 0x1a, 0x63, 0x6f, 0x6d, 0x2e, 0x66, 0x72,
+	0x6f, 0x73, 0x74, 0x64, 0x62, 0x2e, 0x74, 0x61, 0x62, 0x6c, 0x65, 0x2e, 0x76, 0x31, 0x61, 0x6c,
+	0x70, 0x68, 0x61, 0x31, 0x42, 0x0b, 0x43, 0x6f, 0x6e, 0x66, 0x69, 0x67, 0x50, 0x72, 0x6f, 0x74,
+	0x6f, 0x50, 0x01, 0x5a, 0x51, 0x67, 0x69, 0x74, 0x68, 0x75, 0x62, 0x2e, 0x63, 0x6f, 0x6d, 0x2f,
+	0x70, 0x6f, 0x6c, 0x61, 0x72, 0x73, 0x69, 0x67, 0x6e, 0x61, 0x6c, 0x73, 0x2f, 0x66, 0x72, 0x6f,
+	0x73, 0x74, 0x64, 0x62, 0x2f, 0x67, 0x65, 0x6e, 0x2f, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2f, 0x67,
+	0x6f, 0x2f, 0x66, 0x72, 0x6f, 0x73, 0x74, 0x64, 0x62, 0x2f, 0x74, 0x61, 0x62, 0x6c, 0x65, 0x2f,
+	0x76, 0x31, 0x61, 0x6c, 0x70, 0x68, 0x61, 0x31, 0x3b, 0x74, 0x61, 0x62, 0x6c, 0x65, 0x76, 0x31,
+	0x61, 0x6c, 0x70, 0x68, 0x61, 0x31, 0xa2, 0x02, 0x03, 0x46, 0x54, 0x58, 0xaa, 0x02, 0x16, 0x46,
+	0x72, 0x6f, 0x73, 0x74, 0x64, 0x62, 0x2e, 0x54, 0x61, 0x62, 0x6c, 0x65, 0x2e, 0x56, 0x31, 0x61,
+	0x6c, 0x70, 0x68, 0x61, 0x31, 0xca, 0x02, 0x16, 0x46, 0x72, 0x6f, 0x73, 0x74, 0x64, 0x62, 0x5c,
+	0x54, 0x61, 0x62, 0x6c, 0x65, 0x5c, 0x56, 0x31, 0x61, 0x6c, 0x70, 0x68, 0x61, 0x31, 0xe2, 0x02,
+	0x22, 0x46, 0x72, 0x6f, 0x73, 0x74, 0x64, 0x62, 0x5c, 0x54, 0x61, 0x62, 0x6c, 0x65, 0x5c, 0x56,
+	0x31, 0x61, 0x6c, 0x70, 0x68, 0x61, 0x31, 0x5c, 0x47, 0x50, 0x42, 0x4d, 0x65, 0x74, 0x61, 0x64,
+	0x61, 0x74, 0x61, 0xea, 0x02, 0x18, 0x46, 0x72, 0x6f, 0x73, 0x74, 0x64, 0x62, 0x3a, 0x3a, 0x54,
+	0x61, 0x62, 0x6c, 0x65, 0x3a, 0x3a, 0x56, 0x31, 0x61, 0x6c, 0x70, 0x68, 0x61, 0x31, 0x62, 0x06,
+	0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
 }
 
 var (
@@ -195,20 +575,29 @@ func file_frostdb_table_v1alpha1_config_proto_rawDescGZIP() []byte {
 	return file_frostdb_table_v1alpha1_config_proto_rawDescData
 }
 
-var file_frostdb_table_v1alpha1_config_proto_msgTypes = make([]protoimpl.MessageInfo, 1)
+var file_frostdb_table_v1alpha1_config_proto_enumTypes = make([]protoimpl.EnumInfo, 1)
+var file_frostdb_table_v1alpha1_config_proto_msgTypes = make([]protoimpl.MessageInfo, 3)
 var file_frostdb_table_v1alpha1_config_proto_goTypes = []any{
-	(*TableConfig)(nil),     // 0: frostdb.table.v1alpha1.TableConfig
-	(*v1alpha1.Schema)(nil), // 1: frostdb.schema.v1alpha1.Schema
-	(*v1alpha2.Schema)(nil), // 2: frostdb.schema.v1alpha2.Schema
+	(MergePolicy)(0),               // 0: frostdb.table.v1alpha1.MergePolicy
+	(*TableConfig)(nil),            // 1: frostdb.table.v1alpha1.TableConfig
+	(*SortOrder)(nil),              // 2: frostdb.table.v1alpha1.SortOrder
+	nil,                            // 3: frostdb.table.v1alpha1.TableConfig.V1alpha1FieldMappingEntry
+	(*v1alpha1.Schema)(nil),        // 4: frostdb.schema.v1alpha1.Schema
+	(*v1alpha2.Schema)(nil),        // 5: frostdb.schema.v1alpha2.Schema
+	(*v1alpha2.SortingColumn)(nil), // 6: frostdb.schema.v1alpha2.SortingColumn
 }
 var file_frostdb_table_v1alpha1_config_proto_depIdxs = []int32{
-	1, // 0: frostdb.table.v1alpha1.TableConfig.deprecated_schema:type_name -> frostdb.schema.v1alpha1.Schema
-	2, // 1: frostdb.table.v1alpha1.TableConfig.schema_v2:type_name -> frostdb.schema.v1alpha2.Schema
-	2, // [2:2] is the sub-list for method output_type
-	2, // [2:2] is the sub-list for method input_type
-	2, // [2:2] is the sub-list for extension type_name
-	2, // [2:2] is the sub-list for extension extendee
-	0, // [0:2] is the sub-list for field type_name
+	4, // 0: frostdb.table.v1alpha1.TableConfig.deprecated_schema:type_name -> frostdb.schema.v1alpha1.Schema
+	5, // 1: frostdb.table.v1alpha1.TableConfig.schema_v2:type_name -> frostdb.schema.v1alpha2.Schema
+	2, // 2: frostdb.table.v1alpha1.TableConfig.sort_orders:type_name -> frostdb.table.v1alpha1.SortOrder
+	0, // 3: frostdb.table.v1alpha1.TableConfig.merge_policy:type_name -> frostdb.table.v1alpha1.MergePolicy
+	3, // 4: frostdb.table.v1alpha1.TableConfig.v1alpha1_field_mapping:type_name -> frostdb.table.v1alpha1.TableConfig.V1alpha1FieldMappingEntry
+	6, // 5: frostdb.table.v1alpha1.SortOrder.sorting_columns:type_name -> frostdb.schema.v1alpha2.SortingColumn
+	6, // [6:6] is the sub-list for method output_type
+	6, // [6:6] is the sub-list for method input_type
+	6, // [6:6] is the sub-list for extension type_name
+	6, // [6:6] is the sub-list for extension extendee
+	0, // [0:6] is the sub-list for field type_name
 }
 
 func init() { file_frostdb_table_v1alpha1_config_proto_init() }
@@ -229,6 +618,18 @@ func file_frostdb_table_v1alpha1_config_proto_init() {
 				return nil
 			}
 		}
+		file_frostdb_table_v1alpha1_config_proto_msgTypes[1].Exporter = func(v any, i int) any {
+			switch v := v.(*SortOrder); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
 	}
 	file_frostdb_table_v1alpha1_config_proto_msgTypes[0].OneofWrappers = []any{
 		(*TableConfig_DeprecatedSchema)(nil),
@@ -239,13 +640,14 @@ func file_frostdb_table_v1alpha1_config_proto_init() {
 		File: protoimpl.DescBuilder{
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: file_frostdb_table_v1alpha1_config_proto_rawDesc,
-			NumEnums:      0,
-			NumMessages:   1,
+			NumEnums:      1,
+			NumMessages:   3,
 			NumExtensions: 0,
 			NumServices:   0,
 		},
 		GoTypes:           file_frostdb_table_v1alpha1_config_proto_goTypes,
 		DependencyIndexes: file_frostdb_table_v1alpha1_config_proto_depIdxs,
+		EnumInfos:         file_frostdb_table_v1alpha1_config_proto_enumTypes,
 		MessageInfos:      file_frostdb_table_v1alpha1_config_proto_msgTypes,
 	}.Build()
 	File_frostdb_table_v1alpha1_config_proto = out.File
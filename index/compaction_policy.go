@@ -0,0 +1,124 @@
+package index
+
+import (
+	"time"
+
+	"github.com/polarsignals/frostdb/parts"
+)
+
+// CompactionPolicy decides when a level of an LSM is compacted into the next
+// level, which of its parts are merged, and into how many parts of the next
+// level.
+type CompactionPolicy interface {
+	// ShouldCompact reports whether the level should be compacted. It is
+	// called for L0 after every insert, and for every level but the last
+	// during each compaction.
+	ShouldCompact(state LevelState) bool
+	// Plan returns the parts of the level to merge, grouped by the part of
+	// the next level they are merged into. The candidates are ordered from
+	// the newest to the oldest part, and the groups must be in the same order
+	// and contain the oldest parts of the level, i.e. a suffix of the
+	// candidates, so that the next level only contains parts older than the
+	// ones remaining in the level. Returning no groups skips the compaction.
+	// If the compaction is forced, the groups must contain all candidates.
+	Plan(state LevelState, candidates []CompactionCandidate) [][]CompactionCandidate
+}
+
+// LevelState is the state of a level of the LSM passed to a CompactionPolicy.
+type LevelState struct {
+	Level SentinelType
+	// Size is the size of the parts of the level in bytes.
+	Size int64
+	// MaxSize is the max size of the level configured in its LevelConfig.
+	MaxSize int64
+	// Oldest is the time the oldest part of the level was added to the level,
+	// or the zero time if the level contains no parts.
+	Oldest time.Time
+	// Now is the current time.
+	Now time.Time
+	// Forced reports whether the compaction was forced by
+	// LSM.EnsureCompaction.
+	Forced bool
+}
+
+// CompactionCandidate is a part of a level that can be compacted.
+type CompactionCandidate struct {
+	Part parts.Part
+	// Added is the time the part was added to the level.
+	Added time.Time
+}
+
+// LeveledCompactionPolicy compacts a level once it reaches its max size,
+// merging all of its parts. This is the default policy of an LSM.
+type LeveledCompactionPolicy struct {
+	// TargetPartSize is the size of the parts to merge into one part of the
+	// next level, in bytes of the parts of the level. If 0, all parts are
+	// merged into a single part.
+	TargetPartSize int64
+}
+
+func (p *LeveledCompactionPolicy) ShouldCompact(state LevelState) bool {
+	return state.Size >= state.MaxSize
+}
+
+func (p *LeveledCompactionPolicy) Plan(_ LevelState, candidates []CompactionCandidate) [][]CompactionCandidate {
+	return groupBySize(candidates, p.TargetPartSize)
+}
+
+// TimeCompactionPolicy compacts a level once it reaches its max size, merging
+// all of its parts, or once its oldest part was added more than MaxAge ago,
+// merging all parts that were added more than MaxAge ago. This bounds the
+// time rows spend in small parts of low levels when few rows are inserted.
+type TimeCompactionPolicy struct {
+	MaxAge time.Duration
+	// TargetPartSize is the size of the parts to merge into one part of the
+	// next level, in bytes of the parts of the level. If 0, all parts are
+	// merged into a single part.
+	TargetPartSize int64
+}
+
+func (p *TimeCompactionPolicy) ShouldCompact(state LevelState) bool {
+	if state.Size >= state.MaxSize {
+		return true
+	}
+	return !state.Oldest.IsZero() && state.Now.Sub(state.Oldest) >= p.MaxAge
+}
+
+func (p *TimeCompactionPolicy) Plan(state LevelState, candidates []CompactionCandidate) [][]CompactionCandidate {
+	if state.Forced || state.Size >= state.MaxSize {
+		return groupBySize(candidates, p.TargetPartSize)
+	}
+	i := len(candidates)
+	for i > 0 && state.Now.Sub(candidates[i-1].Added) >= p.MaxAge {
+		i--
+	}
+	return groupBySize(candidates[i:], p.TargetPartSize)
+}
+
+// groupBySize groups consecutive candidates into groups of at least
+// targetSize bytes, except for the last group. If targetSize is 0, all
+// candidates are returned in a single group.
+func groupBySize(candidates []CompactionCandidate, targetSize int64) [][]CompactionCandidate {
+	if len(candidates) == 0 {
+		return nil
+	}
+	if targetSize <= 0 {
+		return [][]CompactionCandidate{candidates}
+	}
+	var (
+		groups [][]CompactionCandidate
+		start  int
+		size   int64
+	)
+	for i, c := range candidates {
+		size += c.Part.Size()
+		if size >= targetSize {
+			groups = append(groups, candidates[start:i+1])
+			start, size = i+1, 0
+		}
+	}
+	if start < len(candidates) {
+		groups = append(groups, candidates[start:])
+	}
+	return groups
+}
@@ -52,13 +52,25 @@ type LSM struct {
 	levels        []Level
 	partList      *Node
 	sizes         []atomic.Int64
+	// oldest holds the time the oldest part of each level was added to the
+	// level, or nil if the level contains no parts.
+	oldest []atomic.Pointer[time.Time]
 
 	// Options
 	logger    log.Logger
 	metrics   *LSMMetrics
 	watermark func() uint64
+	rewrite   PartRewriter
+	paused    func() bool
+	spawn     func(func())
+	now       func() time.Time
+	policy    CompactionPolicy
 }
 
+// PartRewriter returns a rewritten version of the given part's data (e.g. with
+// deleted rows removed), or nil if the part is not affected.
+type PartRewriter func(parts.Part) (arrow.Record, error)
+
 // LSMMetrics are the metrics for an LSM index.
 type LSMMetrics struct {
 	Compactions        *prometheus.CounterVec
@@ -96,6 +108,49 @@ func LSMWithMetrics(metrics *LSMMetrics) LSMOption {
 	}
 }
 
+// LSMWithPartRewriter sets a function that is applied to parts when they are
+// scanned, compacted or rotated.
+func LSMWithPartRewriter(rewrite PartRewriter) LSMOption {
+	return func(l *LSM) {
+		l.rewrite = rewrite
+	}
+}
+
+// LSMWithCompactionPaused sets a function that reports whether compactions
+// triggered by inserts are paused. A compaction skipped while paused is
+// triggered by the next insert once compactions are resumed.
+func LSMWithCompactionPaused(paused func() bool) LSMOption {
+	return func(l *LSM) {
+		l.paused = paused
+	}
+}
+
+// LSMWithCompactionScheduler sets the function used to run compactions
+// triggered by inserts in the background. By default, they are run in a new
+// goroutine.
+func LSMWithCompactionScheduler(spawn func(func())) LSMOption {
+	return func(l *LSM) {
+		l.spawn = spawn
+	}
+}
+
+// LSMWithNow sets the function returning the current time, which is recorded
+// when parts are added to a level. By default, time.Now is used.
+func LSMWithNow(now func() time.Time) LSMOption {
+	return func(l *LSM) {
+		l.now = now
+	}
+}
+
+// LSMWithCompactionPolicy sets the policy deciding when and how levels are
+// compacted. By default, a LeveledCompactionPolicy merging all parts of a
+// level into a single part is used.
+func LSMWithCompactionPolicy(policy CompactionPolicy) LSMOption {
+	return func(l *LSM) {
+		l.policy = policy
+	}
+}
+
 func NewLSMMetrics(reg prometheus.Registerer) *LSMMetrics {
 	return &LSMMetrics{
 		Compactions: promauto.With(reg).NewCounterVec(prometheus.CounterOpts{
@@ -130,9 +185,12 @@ func NewLSM(dir string, schema *dynparquet.Schema, levels []*LevelConfig, waterm
 		maxTXRecoverd: make([]uint64, len(levels)),
 		partList:      NewList(L0),
 		sizes:         make([]atomic.Int64, len(levels)),
+		oldest:        make([]atomic.Pointer[time.Time], len(levels)),
 		compacting:    sync.Mutex{},
 		logger:        log.NewNopLogger(),
 		watermark:     watermark,
+		now:           time.Now,
+		policy:        &LeveledCompactionPolicy{},
 	}
 
 	for _, opt := range options {
@@ -332,17 +390,24 @@ func (l *LSM) MaxLevel() SentinelType {
 func (l *LSM) Add(tx uint64, record arrow.Record) {
 	record.Retain()
 	size := util.TotalRecordSize(record)
-	l.partList.Insert(parts.NewArrowPart(tx, record, uint64(size), l.schema, parts.WithCompactionLevel(int(L0))))
+	now := l.now()
+	l.partList.insert(parts.NewArrowPart(tx, record, uint64(size), l.schema, parts.WithCompactionLevel(int(L0))), now)
+	l.oldest[L0].CompareAndSwap(nil, &now)
 	l0 := l.sizes[L0].Add(int64(size))
 	l.metrics.LevelSize.WithLabelValues(L0.String()).Set(float64(l0))
-	if l0 >= l.levels[L0].MaxSize() {
+	if l.policy.ShouldCompact(l.levelState(L0, false)) && (l.paused == nil || !l.paused()) {
 		if l.compacting.TryLock() {
 			l.compactionWg.Add(1)
-			go func() {
+			compact := func() {
 				defer l.compacting.Unlock()
 				defer l.compactionWg.Done()
 				_ = l.compact(false)
-			}()
+			}
+			if l.spawn != nil {
+				l.spawn(compact)
+			} else {
+				go compact()
+			}
 		}
 	}
 }
@@ -373,11 +438,78 @@ func (l *LSM) InsertPart(part parts.Part) {
 	}
 
 	// Insert the part into the correct level, but do not do this if parts with newer TXs have already been inserted.
-	l.findLevel(level).Insert(part)
+	now := l.now()
+	l.findLevel(level).insert(part, now)
+	l.oldest[level].CompareAndSwap(nil, &now)
 	size := l.sizes[level].Add(int64(part.Size()))
 	l.metrics.LevelSize.WithLabelValues(level.String()).Set(float64(size))
 }
 
+// levelState returns the state of the level passed to the compaction policy.
+func (l *LSM) levelState(level SentinelType, forced bool) LevelState {
+	state := LevelState{
+		Level:   level,
+		Size:    l.sizes[level].Load(),
+		MaxSize: l.levels[level].MaxSize(),
+		Now:     l.now(),
+		Forced:  forced,
+	}
+	if oldest := l.oldest[level].Load(); oldest != nil {
+		state.Oldest = *oldest
+	}
+	return state
+}
+
+// updateOldest recomputes the time the oldest part of the level was added
+// after parts were removed from the level.
+func (l *LSM) updateOldest(level SentinelType) {
+	var oldest *time.Time
+	l.findLevel(level).Iterate(func(node *Node) bool {
+		if node.part == nil {
+			return node.sentinel == level
+		}
+		if oldest == nil || node.created.Before(*oldest) {
+			created := node.created
+			oldest = &created
+		}
+		return true
+	})
+	l.oldest[level].Store(oldest)
+}
+
+// LevelStats are statistics about the parts of a level of the LSM.
+type LevelStats struct {
+	Level SentinelType
+	// Parts is the number of parts in the level.
+	Parts int
+	// Oldest is the time the oldest part of the level was added to the level,
+	// or the zero time if the level contains no parts.
+	Oldest time.Time
+}
+
+// Stats returns the statistics of each level of the LSM. A large number of L0
+// parts or an old L0 part indicates that compaction is falling behind.
+func (l *LSM) Stats() []LevelStats {
+	stats := make([]LevelStats, len(l.levels))
+	for i := range stats {
+		stats[i].Level = SentinelType(i)
+	}
+	var lvl SentinelType
+	l.Iterate(func(node *Node) bool {
+		if node.part == nil {
+			lvl = node.sentinel
+			return true
+		}
+		s := &stats[lvl]
+		s.Parts++
+		if s.Oldest.IsZero() || node.created.Before(s.Oldest) {
+			s.Oldest = node.created
+		}
+		return true
+	})
+	return stats
+}
+
 func (l *LSM) String() string {
 	s := ""
 	for i := range l.sizes {
@@ -399,6 +531,14 @@ func (l *LSM) Iterate(iter func(node *Node) bool) {
 }
 
 func (l *LSM) Scan(ctx context.Context, _ string, _ *dynparquet.Schema, filter logicalplan.Expr, tx uint64, callback func(context.Context, any) error) error {
+	return l.ScanParts(ctx, filter, tx, func(ctx context.Context, v any, _ parts.Part) error {
+		return callback(ctx, v)
+	})
+}
+
+// ScanParts is like Scan, but additionally passes the part each record or row
+// group was read from to the callback.
+func (l *LSM) ScanParts(ctx context.Context, filter logicalplan.Expr, tx uint64, callback func(context.Context, any, parts.Part) error) error {
 	l.RLock()
 	defer l.RUnlock()
 
@@ -417,9 +557,28 @@ func (l *LSM) Scan(ctx context.Context, _ string, _ *dynparquet.Schema, filter l
 			return true
 		}
 
+		if l.rewrite != nil {
+			r, err := l.rewrite(node.part)
+			if err != nil {
+				iterError = err
+				return false
+			}
+			if r != nil {
+				if r.NumRows() == 0 {
+					r.Release()
+					return true
+				}
+				if err := callback(ctx, r, node.part); err != nil {
+					iterError = err
+					return false
+				}
+				return true
+			}
+		}
+
 		if r := node.part.Record(); r != nil {
 			r.Retain()
-			if err := callback(ctx, r); err != nil {
+			if err := callback(ctx, r, node.part); err != nil {
 				iterError = err
 				return false
 			}
@@ -442,7 +601,7 @@ func (l *LSM) Scan(ctx context.Context, _ string, _ *dynparquet.Schema, filter l
 
 			if mayContainUsefulData {
 				node.part.Retain() // Create another reference to this part
-				if err := callback(ctx, &releaseableRowGroup{DynamicRowGroup: rg, release: node.part.Release}); err != nil {
+				if err := callback(ctx, &releaseableRowGroup{DynamicRowGroup: rg, release: node.part.Release}, node.part); err != nil {
 					iterError = err
 					return false
 				}
@@ -523,20 +682,61 @@ func (l *LSM) Rotate(externalWriter func([]parts.Part) (parts.Part, int64, int64
 		return true
 	})
 
-	_, _, _, err := externalWriter(compact)
+	compact, release, err := l.rewriteParts(compact)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	_, _, _, err = externalWriter(compact)
 	return err
 }
 
-// Merge will merge the given level into an arrow record for the next level using the configured Compact function for the given level.
+// rewriteParts applies the configured PartRewriter to the given parts. Parts
+// that are rewritten to zero rows are dropped. The returned function must be
+// called to release the rewritten parts once they are no longer used.
+func (l *LSM) rewriteParts(list []parts.Part) ([]parts.Part, func(), error) {
+	var rewritten []parts.Part
+	release := func() {
+		for _, p := range rewritten {
+			p.Release()
+		}
+	}
+	if l.rewrite == nil {
+		return list, release, nil
+	}
+
+	result := make([]parts.Part, 0, len(list))
+	for _, p := range list {
+		r, err := l.rewrite(p)
+		if err != nil {
+			release()
+			return nil, nil, err
+		}
+		if r == nil {
+			result = append(result, p)
+			continue
+		}
+		if r.NumRows() == 0 {
+			r.Release()
+			continue
+		}
+		np := parts.NewArrowPart(p.TX(), r, uint64(util.TotalRecordSize(r)), l.schema, parts.WithCompactionLevel(p.CompactionLevel()))
+		rewritten = append(rewritten, np)
+		result = append(result, np)
+	}
+	return result, release, nil
+}
+
+// Merge will merge the parts of the given level planned by the compaction policy into parts for the next level using the configured Compact function for the given level.
 // If this is the max level of the LSM an external writer must be provided to write the merged part elsewhere.
-func (l *LSM) merge(level SentinelType) error {
+func (l *LSM) merge(level SentinelType, forced bool) error {
 	if int(level) > len(l.levels) {
 		return fmt.Errorf("level %d does not exist", level)
 	}
 	if int(level) == len(l.levels)-1 {
 		return fmt.Errorf("cannot merge the last level")
 	}
-	l.metrics.Compactions.WithLabelValues(level.String()).Inc()
 
 	compact := l.findLevel(level)
 
@@ -589,48 +789,92 @@ func (l *LSM) merge(level SentinelType) error {
 		return nil
 	}
 
+	candidates := make([]CompactionCandidate, 0, len(nodeList))
+	for _, node := range nodeList {
+		candidates = append(candidates, CompactionCandidate{Part: node.part, Added: node.created})
+	}
+	groups := l.policy.Plan(l.levelState(level, forced), candidates)
+	first, err := validatePlan(candidates, groups, forced)
+	if err != nil {
+		return err
+	}
+	if len(groups) == 0 {
+		return nil
+	}
+	l.metrics.Compactions.WithLabelValues(level.String()).Inc()
+
 	var size int64
 	var compactedSize int64
 	var compacted []parts.Part
-	var err error
-	mergeList := make([]parts.Part, 0, len(nodeList))
-	for _, node := range nodeList {
+	mergeList := make([]parts.Part, 0, len(nodeList)-first)
+	for _, node := range nodeList[first:] {
 		mergeList = append(mergeList, node.part)
 	}
 	s := &Node{
 		sentinel: level + 1,
 	}
-	compacted, size, compactedSize, err = l.levels[level].Compact(mergeList, parts.WithCompactionLevel(int(level)+1))
-	if err != nil {
-		return err
+	for _, group := range groups {
+		groupParts := make([]parts.Part, 0, len(group))
+		for _, c := range group {
+			groupParts = append(groupParts, c.Part)
+		}
+		groupCompacted, groupSize, groupCompactedSize, err := l.compactGroup(level, groupParts)
+		if err != nil {
+			for _, p := range compacted {
+				p.Release()
+			}
+			return err
+		}
+		compacted = append(compacted, groupCompacted...)
+		size += groupSize
+		compactedSize += groupCompactedSize
+	}
+	if l.rewrite != nil {
+		// Rewritten parts may be smaller than the original ones, but the
+		// level size accounts for the original parts.
+		size = 0
+		for _, p := range mergeList {
+			size += p.Size()
+		}
 	}
 
-	// Create new list for the compacted parts.
-	compactedList := &Node{
-		part: compacted[0],
-	}
-	node := compactedList
-	for _, p := range compacted[1:] {
+	// Create new list for the compacted parts. If all rows of the level were
+	// deleted, the sentinel directly points to the rest of the list.
+	now := l.now()
+	node := s
+	for _, p := range compacted {
 		node.next.Store(&Node{
-			part: p,
+			part:    p,
+			created: now,
 		})
 		node = node.next.Load()
 	}
-	s.next.Store(compactedList)
 	if next != nil {
 		node.next.Store(next)
 	}
+	if len(compacted) > 0 {
+		l.oldest[level+1].CompareAndSwap(nil, &now)
+	}
 	l.sizes[level+1].Add(int64(compactedSize))
 	l.metrics.LevelSize.WithLabelValues(SentinelType(level + 1).String()).Set(float64(l.sizes[level+1].Load()))
 
-	// Replace the compacted list with the new list
-	// find the node that points to the first node in our compacted list.
-	node = l.findNode(nodeList[0])
-	for !node.next.CompareAndSwap(nodeList[0], s) {
-		// This can happen at most once in the scenario where a new part is added to the L0 list while we are trying to replace it.
+	if first > 0 {
+		// The newer parts of the level remain in the level. Parts inserted
+		// concurrently are newer than all parts of the level that were
+		// candidates, so the last remaining candidate's next node is only
+		// modified here.
+		nodeList[first-1].next.Store(s)
+	} else {
+		// Replace the compacted list with the new list
+		// find the node that points to the first node in our compacted list.
 		node = l.findNode(nodeList[0])
+		for !node.next.CompareAndSwap(nodeList[0], s) {
+			// This can happen at most once in the scenario where a new part is added to the L0 list while we are trying to replace it.
+			node = l.findNode(nodeList[0])
+		}
 	}
 	l.sizes[level].Add(-int64(size))
+	l.updateOldest(level)
 	l.metrics.LevelSize.WithLabelValues(level.String()).Set(float64(l.sizes[level].Load()))
 
 	// release the old parts
@@ -640,14 +884,56 @@ func (l *LSM) merge(level SentinelType) error {
 	}
 	l.Unlock()
 
-	// Reset the level that was just compacted
-	if level != L0 {
+	// Reset the level that was just compacted, unless parts remain in it.
+	if level != L0 && first == 0 {
 		l.levels[level-1].Reset()
 	}
 
 	return nil
 }
 
+// validatePlan checks that the groups planned by the compaction policy contain
+// the oldest candidates in order, and all candidates if the compaction is
+// forced. It returns the index of the first planned candidate.
+func validatePlan(candidates []CompactionCandidate, groups [][]CompactionCandidate, forced bool) (int, error) {
+	n := 0
+	for _, group := range groups {
+		if len(group) == 0 {
+			return 0, fmt.Errorf("compaction policy planned an empty group")
+		}
+		n += len(group)
+	}
+	first := len(candidates) - n
+	if first < 0 || (forced && first != 0) {
+		return 0, fmt.Errorf("compaction policy planned %d of %d parts", n, len(candidates))
+	}
+	i := first
+	for _, group := range groups {
+		for _, c := range group {
+			if c.Part != candidates[i].Part {
+				return 0, fmt.Errorf("compaction policy did not plan the oldest parts in order")
+			}
+			i++
+		}
+	}
+	return first, nil
+}
+
+// compactGroup compacts the given parts of the level into parts of the next
+// level. It returns the compacted parts and their size before and after the
+// compaction.
+func (l *LSM) compactGroup(level SentinelType, group []parts.Part) ([]parts.Part, int64, int64, error) {
+	toCompact, release, err := l.rewriteParts(group)
+	if err != nil {
+		return nil, 0, 0, err
+	}
+	defer release()
+	if len(toCompact) == 0 {
+		return nil, 0, 0, nil
+	}
+	return l.levels[level].Compact(toCompact, parts.WithCompactionLevel(int(level)+1))
+}
+
 // compact is a cascading compaction routine. It will start at the lowest level and compact until the next level is either the max level or the next level does not exceed the max size.
 // compact can not be run concurrently.
 func (l *LSM) compact(ignoreSizes bool) error {
@@ -657,8 +943,8 @@ func (l *LSM) compact(ignoreSizes bool) error {
 	}()
 
 	for i := 0; i < len(l.levels)-1; i++ {
-		if ignoreSizes || l.sizes[i].Load() >= l.levels[i].MaxSize() {
-			if err := l.merge(SentinelType(i)); err != nil {
+		if ignoreSizes || l.policy.ShouldCompact(l.levelState(SentinelType(i), false)) {
+			if err := l.merge(SentinelType(i), ignoreSizes); err != nil {
 				level.Error(l.logger).Log("msg", "failed to merge level", "level", i, "err", err)
 				return err
 			}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"runtime"
 	"sync/atomic"
+	"time"
 
 	"github.com/polarsignals/frostdb/parts"
 )
@@ -26,6 +27,7 @@ type Node struct {
 	part parts.Part
 
 	sentinel SentinelType // sentinel nodes contain no parts, and are to indicate the start of a new sub list
+	created  time.Time    // the time the part was added to the list
 }
 
 func (n *Node) Part() parts.Part {
@@ -72,14 +74,22 @@ func (n *Node) Sentinel(s SentinelType) *Node {
 // Prepend a node onto the front of the list.
 func (n *Node) Prepend(part parts.Part) *Node {
 	return n.prepend(&Node{
-		part: part,
+		part:    part,
+		created: time.Now(),
 	})
 }
 
 // Insert a Node into the list, in order by Tx.
 func (n *Node) Insert(part parts.Part) {
+	n.insert(part, time.Now())
+}
+
+// insert inserts a Node created at the given time into the list, in order by
+// Tx.
+func (n *Node) insert(part parts.Part, created time.Time) {
 	node := &Node{
-		part: part,
+		part:    part,
+		created: created,
 	}
 	tx := node.part.TX()
 	tryInsert := func() bool {
@@ -108,22 +108,56 @@ func Test_LSM_Basic(t *testing.T) {
 	lsm.Add(2, r)
 	lsm.Add(3, r)
 	check(t, lsm, 3, 0)
-	require.NoError(t, lsm.merge(L0))
+	require.NoError(t, lsm.merge(L0, false))
 	check(t, lsm, 0, 1)
 	lsm.Add(4, r)
 	check(t, lsm, 1, 1)
 	lsm.Add(5, r)
 	check(t, lsm, 2, 1)
-	require.NoError(t, lsm.merge(L0))
+	require.NoError(t, lsm.merge(L0, false))
 	check(t, lsm, 0, 2)
 	lsm.Add(6, r)
 	check(t, lsm, 1, 2)
-	require.NoError(t, lsm.merge(L1))
+	require.NoError(t, lsm.merge(L1, false))
 	check(t, lsm, 1, 1)
-	require.NoError(t, lsm.merge(L0))
+	require.NoError(t, lsm.merge(L0, false))
 	check(t, lsm, 0, 2)
 }
 
+func Test_LSM_Stats(t *testing.T) {
+	t.Parallel()
+	lsm, err := NewLSM("test", nil, []*LevelConfig{
+		{Level: L0, MaxSize: 1024 * 1024 * 1024, Type: CompactionTypeParquetMemory, Compact: compactParts},
+		{Level: L1, MaxSize: 1024 * 1024 * 1024},
+	},
+		func() uint64 { return math.MaxUint64 },
+	)
+	require.NoError(t, err)
+
+	samples := dynparquet.NewTestSamples()
+	r, err := samples.ToRecord()
+	require.NoError(t, err)
+
+	stats := lsm.Stats()
+	require.Len(t, stats, 2)
+	require.Equal(t, 0, stats[L0].Parts)
+	require.True(t, stats[L0].Oldest.IsZero())
+
+	before := time.Now()
+	lsm.Add(1, r)
+	lsm.Add(2, r)
+	stats = lsm.Stats()
+	require.Equal(t, 2, stats[L0].Parts)
+	require.Equal(t, 0, stats[L1].Parts)
+	require.False(t, stats[L0].Oldest.Before(before))
+
+	require.NoError(t, lsm.merge(L0, false))
+	stats = lsm.Stats()
+	require.Equal(t, 0, stats[L0].Parts)
+	require.True(t, stats[L0].Oldest.IsZero())
+	require.Equal(t, 1, stats[L1].Parts)
+}
+
 func Test_LSM_DuplicateSentinel(t *testing.T) {
 	t.Parallel()
 	lsm, err := NewLSM("test", nil, []*LevelConfig{
@@ -143,9 +177,9 @@ func Test_LSM_DuplicateSentinel(t *testing.T) {
 	lsm.Add(2, r)
 	lsm.Add(3, r)
 	check(t, lsm, 3, 0)
-	require.NoError(t, lsm.merge(L0))
+	require.NoError(t, lsm.merge(L0, false))
 	check(t, lsm, 0, 1)
-	require.NoError(t, lsm.merge(L0))
+	require.NoError(t, lsm.merge(L0, false))
 	check(t, lsm, 0, 1)
 }
 
@@ -252,3 +286,81 @@ func Test_LSM_InOrderInsert(t *testing.T) {
 		return 0
 	}))
 }
+
+func Test_LSM_CompactionPolicy(t *testing.T) {
+	t.Parallel()
+	samples := dynparquet.NewTestSamples()
+	r, err := samples.ToRecord()
+	require.NoError(t, err)
+
+	t.Run("time", func(t *testing.T) {
+		now := time.Unix(0, 0)
+		lsm, err := NewLSM("test", nil, []*LevelConfig{
+			{Level: L0, MaxSize: 1024 * 1024 * 1024, Type: CompactionTypeParquetMemory, Compact: compactParts},
+			{Level: L1, MaxSize: 1024 * 1024 * 1024},
+		},
+			func() uint64 { return math.MaxUint64 },
+			LSMWithCompactionPolicy(&TimeCompactionPolicy{MaxAge: 90 * time.Second}),
+			LSMWithNow(func() time.Time { return now }),
+			LSMWithCompactionScheduler(func(compact func()) { compact() }),
+		)
+		require.NoError(t, err)
+
+		lsm.Add(1, r)
+		now = now.Add(time.Minute)
+		lsm.Add(2, r)
+		check(t, lsm, 2, 0)
+
+		// Only the part added more than 90 seconds ago is compacted.
+		now = now.Add(time.Minute)
+		lsm.Add(3, r)
+		check(t, lsm, 2, 1)
+		require.Equal(t, time.Unix(0, 0).Add(time.Minute), lsm.levelState(L0, false).Oldest)
+	})
+
+	t.Run("leveled", func(t *testing.T) {
+		lsm, err := NewLSM("test", nil, []*LevelConfig{
+			{Level: L0, MaxSize: 1024 * 1024 * 1024, Type: CompactionTypeParquetMemory, Compact: compactParts},
+			{Level: L1, MaxSize: 1024 * 1024 * 1024},
+		},
+			func() uint64 { return math.MaxUint64 },
+			LSMWithCompactionPolicy(&LeveledCompactionPolicy{TargetPartSize: 1}),
+		)
+		require.NoError(t, err)
+
+		lsm.Add(1, r)
+		lsm.Add(2, r)
+		lsm.Add(3, r)
+		require.NoError(t, lsm.EnsureCompaction())
+		// Every part reaches the target size on its own.
+		check(t, lsm, 0, 3)
+		require.True(t, lsm.levelState(L0, false).Oldest.IsZero())
+	})
+
+	t.Run("invalid plan", func(t *testing.T) {
+		lsm, err := NewLSM("test", nil, []*LevelConfig{
+			{Level: L0, MaxSize: 1024 * 1024 * 1024, Type: CompactionTypeParquetMemory, Compact: compactParts},
+			{Level: L1, MaxSize: 1024 * 1024 * 1024},
+		},
+			func() uint64 { return math.MaxUint64 },
+			LSMWithCompactionPolicy(planFunc(func(_ LevelState, candidates []CompactionCandidate) [][]CompactionCandidate {
+				return [][]CompactionCandidate{candidates[:1]}
+			})),
+		)
+		require.NoError(t, err)
+
+		lsm.Add(1, r)
+		lsm.Add(2, r)
+		// The newest part is planned without the older part.
+		require.Error(t, lsm.merge(L0, false))
+		check(t, lsm, 2, 0)
+	})
+}
+
+type planFunc func(LevelState, []CompactionCandidate) [][]CompactionCandidate
+
+func (f planFunc) ShouldCompact(LevelState) bool { return false }
+
+func (f planFunc) Plan(state LevelState, candidates []CompactionCandidate) [][]CompactionCandidate {
+	return f(state, candidates)
+}
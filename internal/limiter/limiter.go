@@ -0,0 +1,133 @@
+// Package limiter provides a limit on the number of operations that run at the
+// same time, which admits waiting operations in the order they arrived.
+package limiter
+
+import (
+	"container/list"
+	"context"
+	"sync"
+)
+
+// Limiter limits the number of operations running at the same time.
+// Operations that can't start right away wait in a FIFO queue, so that bursts
+// of operations are served in the order they arrived. A Limiter is safe for
+// concurrent use.
+type Limiter struct {
+	limit int
+
+	mtx     sync.Mutex
+	running int
+	// waiters are the channels of the queued operations in the order they
+	// arrived. A channel is closed when its operation is admitted.
+	waiters list.List
+}
+
+// New returns a Limiter allowing limit operations to run at the same time. A
+// limit <= 0 does not limit the number of operations.
+func New(limit int) *Limiter {
+	return &Limiter{limit: limit}
+}
+
+// TryAcquire admits an operation if it can start right away, i.e. if fewer
+// than limit operations are running and none are queued. The returned
+// function must be called once the operation completed.
+func (l *Limiter) TryAcquire() (func(), bool) {
+	if l.limit <= 0 {
+		return func() {}, true
+	}
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+	if l.running < l.limit && l.waiters.Len() == 0 {
+		l.running++
+		return l.releaseFunc(), true
+	}
+	return nil, false
+}
+
+// Acquire waits until the operation may start or ctx is done, in which case
+// the error of ctx is returned. The returned function must be called once the
+// operation completed. Calling it more than once has no effect.
+func (l *Limiter) Acquire(ctx context.Context) (func(), error) {
+	if release, ok := l.TryAcquire(); ok {
+		return release, nil
+	}
+
+	l.mtx.Lock()
+	admit := make(chan struct{})
+	elem := l.waiters.PushBack(admit)
+	l.mtx.Unlock()
+
+	select {
+	case <-admit:
+		return l.releaseFunc(), nil
+	case <-ctx.Done():
+		l.mtx.Lock()
+		defer l.mtx.Unlock()
+		select {
+		case <-admit:
+			// The operation was admitted concurrently, pass its slot on.
+			l.releaseLocked()
+		default:
+			l.waiters.Remove(elem)
+		}
+		return nil, ctx.Err()
+	}
+}
+
+// admittedKey is the context key marking a context as belonging to an
+// operation admitted by the limiter.
+type admittedKey struct {
+	limiter *Limiter
+}
+
+// WithAdmitted returns a copy of ctx marking the operation it belongs to as
+// admitted by the limiter, so that operations it runs, e.g. subqueries, don't
+// take another slot of the limiter. Other limiters still admit the operation,
+// so limiters that compose, e.g. one per engine and one per store, each hold
+// their own limit.
+func (l *Limiter) WithAdmitted(ctx context.Context) context.Context {
+	return context.WithValue(ctx, admittedKey{limiter: l}, struct{}{})
+}
+
+// Admitted reports whether ctx belongs to an operation that was already
+// admitted by the limiter, see WithAdmitted.
+func (l *Limiter) Admitted(ctx context.Context) bool {
+	return ctx.Value(admittedKey{limiter: l}) != nil
+}
+
+// Running returns the number of operations running.
+func (l *Limiter) Running() int {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+	return l.running
+}
+
+// Queued returns the number of operations waiting to start.
+func (l *Limiter) Queued() int {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+	return l.waiters.Len()
+}
+
+func (l *Limiter) releaseFunc() func() {
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			l.mtx.Lock()
+			defer l.mtx.Unlock()
+			l.releaseLocked()
+		})
+	}
+}
+
+// releaseLocked hands the slot of a completed operation to the longest
+// waiting operation, if any. l.mtx must be held.
+func (l *Limiter) releaseLocked() {
+	front := l.waiters.Front()
+	if front == nil {
+		l.running--
+		return
+	}
+	l.waiters.Remove(front)
+	close(front.Value.(chan struct{}))
+}
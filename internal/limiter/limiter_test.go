@@ -0,0 +1,64 @@
+package limiter_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/polarsignals/frostdb/internal/limiter"
+)
+
+func TestLimiter(t *testing.T) {
+	ctx := context.Background()
+	l := limiter.New(1)
+
+	release, err := l.Acquire(ctx)
+	require.NoError(t, err)
+	_, ok := l.TryAcquire()
+	require.False(t, ok)
+
+	// Operations past the limit are admitted in the order they arrived.
+	order := make(chan int, 2)
+	for i := 0; i < 2; i++ {
+		go func(i int) {
+			release, err := l.Acquire(ctx)
+			if err != nil {
+				order <- -1
+				return
+			}
+			order <- i
+			release()
+		}(i)
+		require.Eventually(t, func() bool {
+			return l.Queued() == i+1
+		}, time.Second, time.Millisecond)
+	}
+
+	// Waiting stops when the context is done.
+	canceledCtx, cancel := context.WithTimeout(ctx, time.Millisecond)
+	defer cancel()
+	_, err = l.Acquire(canceledCtx)
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+	require.Equal(t, 2, l.Queued())
+
+	release()
+	// Releasing twice does not free another slot.
+	release()
+	require.Equal(t, 0, <-order)
+	require.Equal(t, 1, <-order)
+
+	require.Eventually(t, func() bool {
+		return l.Running() == 0
+	}, time.Second, time.Millisecond)
+	require.Zero(t, l.Queued())
+}
+
+func TestLimiterUnlimited(t *testing.T) {
+	l := limiter.New(0)
+	for i := 0; i < 3; i++ {
+		_, ok := l.TryAcquire()
+		require.True(t, ok)
+	}
+}
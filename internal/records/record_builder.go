@@ -7,6 +7,7 @@ import (
 	"sort"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/apache/arrow/go/v17/arrow"
 	"github.com/apache/arrow/go/v17/arrow/array"
@@ -202,7 +203,7 @@ func NewBuild[T any](mem memory.Allocator) *Build[T] {
 				typ = arrow.ListOf(typ)
 				fr.build = newFieldBuild(typ, mem, name, true)
 			}
-		case reflect.Int64, reflect.Float64, reflect.Bool, reflect.String, reflect.Uint64:
+		case reflect.Int64, reflect.Int32, reflect.Float64, reflect.Bool, reflect.String, reflect.Uint64, reflect.Struct:
 			typ, styp = baseType(fty, dictionary)
 			fr.typ = styp
 			fr.nullable = nullable
@@ -493,6 +494,8 @@ func (m *mapFieldBuilder) get(name string, size int) fieldBuilder {
 	return f
 }
 
+var timeType = reflect.TypeOf(time.Time{})
+
 func baseType(fty reflect.Type, dictionary bool) (typ arrow.DataType, sty schemapb.StorageLayout_Type) {
 	for fty.Kind() == reflect.Ptr {
 		fty = fty.Elem()
@@ -501,6 +504,9 @@ func baseType(fty reflect.Type, dictionary bool) (typ arrow.DataType, sty schema
 	case reflect.Int64:
 		typ = arrow.PrimitiveTypes.Int64
 		sty = schemapb.StorageLayout_TYPE_INT64
+	case reflect.Int32:
+		typ = arrow.PrimitiveTypes.Int32
+		sty = schemapb.StorageLayout_TYPE_INT32
 	case reflect.Float64:
 		typ = arrow.PrimitiveTypes.Float64
 		sty = schemapb.StorageLayout_TYPE_DOUBLE
@@ -513,6 +519,12 @@ func baseType(fty reflect.Type, dictionary bool) (typ arrow.DataType, sty schema
 	case reflect.Uint64:
 		typ = arrow.PrimitiveTypes.Uint64
 		sty = schemapb.StorageLayout_TYPE_UINT64
+	case reflect.Struct:
+		if fty != timeType {
+			panic("frostdb/dynschema: " + fty.String() + " is npt supported")
+		}
+		typ = &arrow.TimestampType{Unit: arrow.Nanosecond, TimeZone: "UTC"}
+		sty = schemapb.StorageLayout_TYPE_TIMESTAMP_NANOS
 	default:
 		panic("frostdb/dynschema: " + fty.String() + " is npt supported")
 	}
@@ -572,6 +584,18 @@ func newFieldBuild(dt arrow.DataType, mem memory.Allocator, name string, nullabl
 			}
 			return e.Append(v.Int())
 		}
+	case *array.Int32Builder:
+		f.buildFunc = func(v reflect.Value) error {
+			if nullable {
+				if v.IsNil() {
+					e.AppendNull()
+					return nil
+				}
+				v = v.Elem()
+			}
+			e.Append(int32(v.Int()))
+			return nil
+		}
 	case *array.Uint64Builder:
 		f.buildFunc = func(v reflect.Value) error {
 			if nullable {
@@ -584,6 +608,18 @@ func newFieldBuild(dt arrow.DataType, mem memory.Allocator, name string, nullabl
 			e.Append(v.Uint())
 			return nil
 		}
+	case *array.TimestampBuilder:
+		f.buildFunc = func(v reflect.Value) error {
+			if nullable {
+				if v.IsNil() {
+					e.AppendNull()
+					return nil
+				}
+				v = v.Elem()
+			}
+			e.Append(arrow.Timestamp(v.Interface().(time.Time).UnixNano()))
+			return nil
+		}
 	case *array.Uint64DictionaryBuilder:
 		f.buildFunc = func(v reflect.Value) error {
 			if nullable {
@@ -678,6 +714,19 @@ func newFieldBuild(dt arrow.DataType, mem memory.Allocator, name string, nullabl
 				build.Reserve(v.Len())
 				return applyInt(v, build.Append)
 			}
+		case *array.Int32Builder:
+			f.buildFunc = func(v reflect.Value) error {
+				if v.IsNil() {
+					e.AppendNull()
+					return nil
+				}
+				e.Append(true)
+				build.Reserve(v.Len())
+				return applyInt(v, func(i int64) error {
+					build.Append(int32(i))
+					return nil
+				})
+			}
 		case *array.Uint64Builder:
 			f.buildFunc = func(v reflect.Value) error {
 				if v.IsNil() {
@@ -0,0 +1,318 @@
+package frostdb
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-kit/log/level"
+	"github.com/oklog/ulid/v2"
+
+	"github.com/polarsignals/frostdb/storage"
+)
+
+// LeaseSuffix is the suffix of the lease objects of tables, which are stored
+// next to the tables as "<db>/<table>.lease".
+const LeaseSuffix = ".lease"
+
+// DefaultLeaseTTL is the default duration a table lease is valid for without
+// being renewed.
+const DefaultLeaseTTL = time.Minute
+
+// LeaseHeldError is returned when uploading or deleting the blocks of a table
+// whose lease is held by another writer.
+type LeaseHeldError struct {
+	// Table is the prefix of the table, "<db>/<table>".
+	Table string
+	// Owner identifies the writer holding the lease.
+	Owner string
+	// Expires is the time the lease expires unless it is renewed.
+	Expires time.Time
+}
+
+func (e *LeaseHeldError) Error() string {
+	return fmt.Sprintf("lease of table %s is held by %s until %s", e.Table, e.Owner, e.Expires.Format(time.RFC3339))
+}
+
+// ErrPreconditionFailed is returned by ConditionalBucket.UploadIfVersion if
+// the object was modified.
+var ErrPreconditionFailed = errors.New("precondition failed")
+
+// ConditionalBucket is implemented by buckets that support conditional
+// writes, e.g. using the ETag or generation of objects. Table leases are
+// acquired atomically on such buckets.
+type ConditionalBucket interface {
+	// GetWithVersion returns a reader for the object with the given name and
+	// its current version.
+	GetWithVersion(ctx context.Context, name string) (io.ReadCloser, string, error)
+	// UploadIfVersion uploads the object with the given name only if its
+	// current version is version, or if it doesn't exist if version is
+	// empty. Otherwise it returns ErrPreconditionFailed.
+	UploadIfVersion(ctx context.Context, name string, r io.Reader, version string) error
+}
+
+// conditionalBucket returns the ConditionalBucket wrapped by the given
+// bucket, if any.
+func conditionalBucket(b storage.Bucket) (ConditionalBucket, bool) {
+	var unwrapped any = b
+	for {
+		if c, ok := unwrapped.(ConditionalBucket); ok {
+			return c, true
+		}
+		switch w := unwrapped.(type) {
+		case *storage.CachedBucket:
+			unwrapped = w.Bucket
+		case *storage.BucketReaderAt:
+			unwrapped = w.Bucket
+		default:
+			return nil, false
+		}
+	}
+}
+
+// StorageWithLeases enables table leases, so that multiple column stores
+// writing to the same bucket cannot both write the blocks of a table. Before
+// the blocks of a table are uploaded or deleted, the bucket checks that it
+// still holds the lease of the table, a lock object that is valid for ttl, and
+// acquires or renews it once half of ttl passed. The lease carries a fencing
+// token that is incremented whenever the lease changes owner, a writer whose
+// token no longer matches the lease object lost the lease. If the lease is
+// held by another writer, the upload or delete fails with a LeaseHeldError.
+// A lease that is not renewed, e.g. because its writer crashed, can be taken
+// over once it expired.
+//
+// owner must identify the writer, an empty owner generates a random one.
+// Leases are acquired with conditional writes if the bucket implements
+// ConditionalBucket. Otherwise acquiring a lease is verified by reading it
+// back, which cannot rule out that two writers acquire it at the same time.
+// Leases expire according to the Clock of the column store using the bucket,
+// so clocks of the writers must roughly agree. ttl must be longer than it
+// takes to upload a block.
+func StorageWithLeases(owner string, ttl time.Duration) DefaultObjstoreBucketOption {
+	return func(b *DefaultObjstoreBucket) {
+		if owner == "" {
+			owner = ulid.Make().String()
+		}
+		if ttl <= 0 {
+			ttl = DefaultLeaseTTL
+		}
+		b.leases = &tableLeases{
+			owner: owner,
+			ttl:   ttl,
+			held:  make(map[string]tableLease),
+		}
+	}
+}
+
+// ReleaseLeases releases the table leases held by the bucket, so that other
+// writers can take them over without waiting for them to expire. It should
+// only be called once the column store writing to the bucket is closed.
+func (b *DefaultObjstoreBucket) ReleaseLeases(ctx context.Context) error {
+	if b.leases == nil {
+		return nil
+	}
+	return b.leases.releaseAll(ctx, b)
+}
+
+// tableLease is the content of the lease object of a table. Token is a
+// fencing token that is incremented whenever the lease changes owner.
+type tableLease struct {
+	Owner   string    `json:"owner"`
+	Token   uint64    `json:"token"`
+	Expires time.Time `json:"expires"`
+}
+
+// tableLeases tracks the table leases held by a bucket. The lease objects are
+// read before every upload or delete, so that a lease that was taken over is
+// noticed before the next mutation rather than once it is renewed.
+type tableLeases struct {
+	owner string
+	ttl   time.Duration
+
+	mtx sync.Mutex
+	// held maps the prefixes of tables to the leases held on them.
+	held map[string]tableLease
+}
+
+func leaseName(table string) string {
+	return table + LeaseSuffix
+}
+
+// acquire acquires or renews the lease of the table of the given block.
+// Names that are not blocks don't need a lease.
+func (l *tableLeases) acquire(ctx context.Context, b *DefaultObjstoreBucket, name string) error {
+	db, table, _, ok := parseBlockName(name)
+	if !ok {
+		return nil
+	}
+	return l.acquireTable(ctx, b, filepath.Join(db, table))
+}
+
+// acquireTable checks, acquires or renews the lease of the table with the
+// given prefix.
+func (l *tableLeases) acquireTable(ctx context.Context, b *DefaultObjstoreBucket, prefix string) error {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+	now := b.clock.Now()
+	held, ok := l.held[prefix]
+
+	current, version, exists, err := readLease(ctx, b, prefix)
+	if err != nil {
+		return err
+	}
+	if ok && (!exists || current.Owner != l.owner || current.Token != held.Token) {
+		// The fencing token changed, the lease was lost since it was last
+		// checked.
+		level.Warn(b.logger).Log("msg", "lost table lease", "table", prefix, "token", held.Token)
+		delete(l.held, prefix)
+		ok = false
+	}
+	if ok && now.Before(current.Expires.Add(-l.ttl/2)) {
+		return nil
+	}
+
+	next := tableLease{Owner: l.owner, Expires: now.Add(l.ttl)}
+	switch {
+	case !exists:
+		next.Token = 1
+	case current.Owner != l.owner && now.Before(current.Expires):
+		delete(l.held, prefix)
+		return &LeaseHeldError{Table: prefix, Owner: current.Owner, Expires: current.Expires}
+	case ok:
+		// Renewal of the lease held.
+		next.Token = current.Token
+	default:
+		// The lease expired, or it was taken over since it was last held.
+		next.Token = current.Token + 1
+	}
+
+	data, err := json.Marshal(next)
+	if err != nil {
+		return err
+	}
+	if cb, conditional := conditionalBucket(b.Bucket); conditional {
+		err := cb.UploadIfVersion(ctx, leaseName(prefix), bytes.NewReader(data), version)
+		if errors.Is(err, ErrPreconditionFailed) {
+			// Another writer wrote the lease concurrently.
+			delete(l.held, prefix)
+			written, _, _, err := readLease(ctx, b, prefix)
+			if err != nil {
+				return err
+			}
+			return &LeaseHeldError{Table: prefix, Owner: written.Owner, Expires: written.Expires}
+		}
+		if err != nil {
+			return fmt.Errorf("failed to write lease of table %s: %w", prefix, err)
+		}
+	} else {
+		if err := b.Bucket.Upload(ctx, leaseName(prefix), bytes.NewReader(data)); err != nil {
+			return fmt.Errorf("failed to write lease of table %s: %w", prefix, err)
+		}
+		// Another writer may have written the lease concurrently, the last
+		// write wins.
+		written, _, _, err := readLease(ctx, b, prefix)
+		if err != nil {
+			return err
+		}
+		if written.Owner != l.owner || written.Token != next.Token {
+			delete(l.held, prefix)
+			return &LeaseHeldError{Table: prefix, Owner: written.Owner, Expires: written.Expires}
+		}
+	}
+	if !ok || held.Token != next.Token {
+		level.Info(b.logger).Log("msg", "acquired table lease", "table", prefix, "token", next.Token)
+	}
+	l.held[prefix] = next
+	return nil
+}
+
+// holds returns whether the lease of the table with the given prefix is held
+// by the bucket, renewing it if necessary. Unlike acquireTable, it never
+// acquires a lease that is not held, so that writers that don't write the
+// table don't take its lease over.
+func (l *tableLeases) holds(ctx context.Context, b *DefaultObjstoreBucket, prefix string) (bool, error) {
+	l.mtx.Lock()
+	_, ok := l.held[prefix]
+	l.mtx.Unlock()
+	if !ok {
+		return false, nil
+	}
+	err := l.acquireTable(ctx, b, prefix)
+	var held *LeaseHeldError
+	if errors.As(err, &held) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// forget removes the leases of the table, or of all tables of the database,
+// with the given prefix from the held leases after their lease objects were
+// deleted.
+func (l *tableLeases) forget(prefix string) {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+	for table := range l.held {
+		if table == prefix || strings.HasPrefix(table, prefix+"/") {
+			delete(l.held, table)
+		}
+	}
+}
+
+// releaseAll deletes the lease objects of all leases held.
+func (l *tableLeases) releaseAll(ctx context.Context, b *DefaultObjstoreBucket) error {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+	for prefix, held := range l.held {
+		current, _, exists, err := readLease(ctx, b, prefix)
+		if err != nil {
+			return err
+		}
+		if exists && current.Owner == l.owner && current.Token == held.Token {
+			if err := b.Bucket.Delete(ctx, leaseName(prefix)); err != nil && !b.IsObjNotFoundErr(err) {
+				return err
+			}
+		}
+		delete(l.held, prefix)
+	}
+	return nil
+}
+
+// readLease reads the lease of the table with the given prefix, along with
+// the version of the lease object if the bucket supports conditional writes.
+// Corrupt leases are treated as expired.
+func readLease(ctx context.Context, b *DefaultObjstoreBucket, prefix string) (tableLease, string, bool, error) {
+	var (
+		r       io.ReadCloser
+		version string
+		err     error
+	)
+	if cb, ok := conditionalBucket(b.Bucket); ok {
+		r, version, err = cb.GetWithVersion(ctx, leaseName(prefix))
+	} else {
+		r, err = b.Bucket.Get(ctx, leaseName(prefix))
+	}
+	if err != nil {
+		if b.IsObjNotFoundErr(err) {
+			return tableLease{}, "", false, nil
+		}
+		return tableLease{}, "", false, fmt.Errorf("failed to read lease of table %s: %w", prefix, err)
+	}
+	defer r.Close()
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return tableLease{}, "", false, fmt.Errorf("failed to read lease of table %s: %w", prefix, err)
+	}
+	var lease tableLease
+	if err := json.Unmarshal(data, &lease); err != nil {
+		level.Warn(b.logger).Log("msg", "ignoring corrupt table lease", "table", prefix, "err", err)
+		return tableLease{}, version, true, nil
+	}
+	return lease, version, true, nil
+}
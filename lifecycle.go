@@ -0,0 +1,65 @@
+package frostdb
+
+// SnapshotEvent describes a snapshot that was written to the snapshots
+// directory of a database.
+type SnapshotEvent struct {
+	Database string
+	// Tx is the transaction the snapshot was taken at. It contains all writes
+	// up to and including Tx, so the WAL records up to Tx may be truncated
+	// once the snapshot was written.
+	Tx uint64
+	// Path is the path of the snapshot file.
+	Path string
+}
+
+// WALTruncateEvent describes the truncation of the WAL of a database.
+type WALTruncateEvent struct {
+	Database string
+	// FirstTx is the first transaction kept in the WAL. All records before it
+	// are removed.
+	FirstTx uint64
+}
+
+// LifecycleHooks are called on events of the lifecycle of the databases of a
+// ColumnStore. They allow external tooling, e.g. for backups, to copy the files
+// of a database before they are removed. Hooks are called synchronously from
+// the goroutine causing the event, so they must not block for long and must be
+// safe for concurrent use. A nil hook is not called.
+type LifecycleHooks struct {
+	// OnSnapshot is called once a snapshot was written. The WAL records the
+	// snapshot replaces, as well as older snapshots, are only removed after
+	// OnSnapshot returns.
+	OnSnapshot func(SnapshotEvent)
+	// OnWALTruncate is called before the records of a WAL preceding
+	// FirstTx are removed. The records are removed asynchronously once
+	// OnWALTruncate returns.
+	OnWALTruncate func(WALTruncateEvent)
+}
+
+// WithLifecycleHooks sets the hooks called on lifecycle events of the
+// databases of the column store.
+func WithLifecycleHooks(hooks LifecycleHooks) Option {
+	return func(s *ColumnStore) error {
+		s.lifecycleHooks = hooks
+		return nil
+	}
+}
+
+func (h LifecycleHooks) snapshot(e SnapshotEvent) {
+	if h.OnSnapshot != nil {
+		h.OnSnapshot(e)
+	}
+}
+
+func (h LifecycleHooks) walTruncate(e WALTruncateEvent) {
+	if h.OnWALTruncate != nil {
+		h.OnWALTruncate(e)
+	}
+}
+
+// truncateWAL truncates the given WAL of the database so that tx is its first
+// record, after notifying the lifecycle hooks.
+func (db *DB) truncateWAL(wal WAL, tx uint64) error {
+	db.columnStore.lifecycleHooks.walTruncate(WALTruncateEvent{Database: db.name, FirstTx: tx})
+	return wal.Truncate(tx)
+}
@@ -0,0 +1,63 @@
+package frostdb
+
+import (
+	"context"
+	"os"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/polarsignals/frostdb/dynparquet"
+)
+
+func TestLifecycleHooks(t *testing.T) {
+	ctx := context.Background()
+
+	var (
+		mtx    sync.Mutex
+		events []any
+	)
+	c, err := New(
+		WithLogger(newTestLogger(t)),
+		WithWAL(),
+		WithStoragePath(t.TempDir()),
+		WithLifecycleHooks(LifecycleHooks{
+			OnSnapshot: func(e SnapshotEvent) {
+				mtx.Lock()
+				defer mtx.Unlock()
+				// The snapshot can be copied before the WAL is truncated.
+				_, err := os.Stat(e.Path)
+				require.NoError(t, err)
+				events = append(events, e)
+			},
+			OnWALTruncate: func(e WALTruncateEvent) {
+				mtx.Lock()
+				defer mtx.Unlock()
+				events = append(events, e)
+			},
+		}),
+	)
+	require.NoError(t, err)
+	defer c.Close()
+
+	db, err := c.DB(ctx, "test")
+	require.NoError(t, err)
+	table, err := db.Table("test", NewTableConfig(dynparquet.SampleDefinition()))
+	require.NoError(t, err)
+	r, err := dynparquet.NewTestSamples().ToRecord()
+	require.NoError(t, err)
+	defer r.Release()
+	_, err = table.InsertRecord(ctx, r)
+	require.NoError(t, err)
+
+	require.NoError(t, db.Snapshot(ctx))
+
+	mtx.Lock()
+	defer mtx.Unlock()
+	require.Len(t, events, 2)
+	snapshot, ok := events[0].(SnapshotEvent)
+	require.True(t, ok, "expected snapshot event, got %T", events[0])
+	require.Equal(t, "test", snapshot.Database)
+	require.Equal(t, WALTruncateEvent{Database: "test", FirstTx: snapshot.Tx + 1}, events[1])
+}
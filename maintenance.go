@@ -0,0 +1,61 @@
+package frostdb
+
+import (
+	"context"
+	"sync"
+
+	"github.com/go-kit/log/level"
+)
+
+// PauseMaintenance pauses the background maintenance of the database, i.e.
+// the compaction of in-memory data, the rotation and persistence of blocks,
+// size-triggered snapshots and retention enforcement, until the returned
+// function is called. This avoids IO contention while e.g. a large export or
+// backup query runs. Pauses nest, maintenance resumes once all of them are
+// released.
+//
+// While maintenance is paused, blocks grow beyond the active memory size, so
+// memory usage grows with the data written in the meantime. Blocks that
+// exceeded the active memory size are rotated when maintenance resumes.
+func (db *DB) PauseMaintenance() (resume func()) {
+	db.maintenancePauses.Add(1)
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			if db.maintenancePauses.Add(-1) == 0 {
+				db.resumeMaintenance()
+			}
+		})
+	}
+}
+
+// MaintenancePaused returns whether the background maintenance of the
+// database is paused.
+func (db *DB) MaintenancePaused() bool {
+	return db.maintenancePauses.Load() > 0
+}
+
+// resumeMaintenance rotates the active blocks that exceeded the active memory
+// size while maintenance was paused.
+func (db *DB) resumeMaintenance() {
+	if db.columnStore.manualBlockRotation {
+		return
+	}
+
+	db.mtx.RLock()
+	tables := make([]*Table, 0, len(db.tables))
+	for _, table := range db.tables {
+		tables = append(tables, table)
+	}
+	db.mtx.RUnlock()
+
+	for _, table := range tables {
+		block := table.ActiveBlock()
+		if block == nil || block.Size() < db.columnStore.activeMemorySize {
+			continue
+		}
+		if err := table.RotateBlock(context.Background(), block); err != nil {
+			level.Error(db.logger).Log("msg", "failed to rotate block after maintenance pause", "table", table.name, "err", err)
+		}
+	}
+}
@@ -0,0 +1,56 @@
+package frostdb
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/polarsignals/frostdb/dynparquet"
+)
+
+func TestPauseMaintenance(t *testing.T) {
+	ctx := context.Background()
+	c, err := New(
+		WithLogger(newTestLogger(t)),
+		WithActiveMemorySize(1*KiB),
+	)
+	require.NoError(t, err)
+	defer c.Close()
+
+	db, err := c.DB(ctx, "test")
+	require.NoError(t, err)
+	table, err := db.Table("test", NewTableConfig(dynparquet.SampleDefinition()))
+	require.NoError(t, err)
+
+	insert := func() {
+		r, err := dynparquet.GenerateTestSamples(100).ToRecord()
+		require.NoError(t, err)
+		defer r.Release()
+		_, err = table.InsertRecord(ctx, r)
+		require.NoError(t, err)
+	}
+
+	resume := db.PauseMaintenance()
+	resumeNested := db.PauseMaintenance()
+	require.True(t, db.MaintenancePaused())
+
+	// The active block is not rotated while maintenance is paused, even
+	// though it exceeds the active memory size.
+	block := table.ActiveBlock()
+	insert()
+	insert()
+	require.Same(t, block, table.ActiveBlock())
+	require.Greater(t, block.Size(), int64(1*KiB))
+
+	resumeNested()
+	// Resuming twice has no effect.
+	resumeNested()
+	require.True(t, db.MaintenancePaused())
+	require.Same(t, block, table.ActiveBlock())
+
+	// The oversized block is rotated once all pauses are released.
+	resume()
+	require.False(t, db.MaintenancePaused())
+	require.NotSame(t, block, table.ActiveBlock())
+}
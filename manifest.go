@@ -0,0 +1,465 @@
+package frostdb
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-kit/log/level"
+	"github.com/oklog/ulid/v2"
+	"github.com/thanos-io/objstore"
+)
+
+// ManifestSuffix is the suffix of the directory, relative to the prefix of a
+// database, that holds the manifest versions of a table, i.e.
+// "<db>/<table>.manifest/<version>".
+const ManifestSuffix = ".manifest"
+
+// manifestCommitAttempts is the number of times committing a manifest version
+// is attempted if another writer committed the same version concurrently.
+const manifestCommitAttempts = 3
+
+// manifestRetainedVersions is the number of manifest versions of a table
+// that are kept, so that readers that listed an older version can still
+// read it.
+const manifestRetainedVersions = 2
+
+// StorageWithManifests enables maintaining a manifest per table in the
+// bucket. A manifest lists the committed blocks of a table and is replaced by
+// writing a new version of it whenever blocks are uploaded or deleted through
+// the DefaultObjstoreBucket. Scans read the latest manifest instead of
+// listing the blocks of a table, so that blocks that were only partially
+// uploaded, e.g. because the writer crashed, are never read. Such orphaned
+// blocks are removed by DeleteOrphanedBlocks. Tables without a manifest are
+// listed, and their first manifest includes the blocks found.
+//
+// Manifest versions are written with conditional writes if the bucket
+// implements ConditionalBucket, so that a version committed by another writer
+// is never overwritten. Otherwise only a single writer may write the blocks
+// of a table, see StorageWithLeases.
+func StorageWithManifests(enabled bool) DefaultObjstoreBucketOption {
+	return func(b *DefaultObjstoreBucket) {
+		b.manifestsEnabled = enabled
+	}
+}
+
+// tableManifest is the in-memory representation of a version of a table's
+// manifest. Blocks of a time partition are prefixed with the directory of
+// the partition, e.g. "timestamp=0_3600000/<ulid>". A manifest with version
+// 0 was built by listing the bucket and isn't written yet.
+type tableManifest struct {
+	version uint64
+	blocks  map[string]struct{}
+	// superseded maps the blocks that were replaced or deleted, but whose
+	// objects still exist, to the time they were superseded. They are
+	// deleted by the garbage collection once no query reads them anymore,
+	// see CollectGarbage.
+	superseded map[string]time.Time
+}
+
+func newTableManifest(version uint64) *tableManifest {
+	return &tableManifest{
+		version:    version,
+		blocks:     map[string]struct{}{},
+		superseded: map[string]time.Time{},
+	}
+}
+
+// next returns a copy of the manifest with the next version.
+func (m *tableManifest) next() *tableManifest {
+	next := newTableManifest(m.version + 1)
+	for block := range m.blocks {
+		next.blocks[block] = struct{}{}
+	}
+	for block, at := range m.superseded {
+		next.superseded[block] = at
+	}
+	return next
+}
+
+func (m *tableManifest) sortedBlocks() []string {
+	blocks := make([]string, 0, len(m.blocks))
+	for block := range m.blocks {
+		blocks = append(blocks, block)
+	}
+	sort.Strings(blocks)
+	return blocks
+}
+
+// MarshalText encodes the manifest as one block per line. Superseded blocks
+// are followed by the Unix time in milliseconds they were superseded at.
+func (m *tableManifest) MarshalText() ([]byte, error) {
+	var buf bytes.Buffer
+	for _, block := range m.sortedBlocks() {
+		buf.WriteString(block)
+		buf.WriteByte('\n')
+	}
+	superseded := make([]string, 0, len(m.superseded))
+	for block := range m.superseded {
+		superseded = append(superseded, block)
+	}
+	sort.Strings(superseded)
+	for _, block := range superseded {
+		buf.WriteString(block)
+		buf.WriteByte(' ')
+		buf.WriteString(strconv.FormatInt(m.superseded[block].UnixMilli(), 10))
+		buf.WriteByte('\n')
+	}
+	return buf.Bytes(), nil
+}
+
+func (m *tableManifest) UnmarshalText(data []byte) error {
+	s := bufio.NewScanner(bytes.NewReader(data))
+	for s.Scan() {
+		line := s.Text()
+		if line == "" {
+			continue
+		}
+		block, supersededAt, superseded := strings.Cut(line, " ")
+		id := block
+		if partition, rest, ok := strings.Cut(block, "/"); ok {
+			if _, ok := parsePartitionDir(partition); !ok {
+				return fmt.Errorf("invalid manifest entry %q", line)
+			}
+			id = rest
+		}
+		if _, err := ulid.Parse(id); err != nil {
+			return fmt.Errorf("invalid manifest entry %q: %w", line, err)
+		}
+		if !superseded {
+			m.blocks[block] = struct{}{}
+			continue
+		}
+		ms, err := strconv.ParseInt(supersededAt, 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid manifest entry %q: %w", line, err)
+		}
+		m.superseded[block] = time.UnixMilli(ms)
+	}
+	return s.Err()
+}
+
+func manifestDir(table string) string {
+	return table + ManifestSuffix
+}
+
+// manifestName returns the name of a manifest version. Versions are zero
+// padded, so that they are listed in order.
+func manifestName(table string, version uint64) string {
+	return filepath.Join(manifestDir(table), fmt.Sprintf("%020d", version))
+}
+
+// manifestVersions returns the sorted versions of the manifest of the table
+// with the given prefix.
+func (b *DefaultObjstoreBucket) manifestVersions(ctx context.Context, table string) ([]uint64, error) {
+	var versions []uint64
+	if err := b.Bucket.Iter(ctx, manifestDir(table), func(name string) error {
+		version, err := strconv.ParseUint(filepath.Base(name), 10, 64)
+		if err != nil {
+			return nil
+		}
+		versions = append(versions, version)
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	sort.Slice(versions, func(i, j int) bool { return versions[i] < versions[j] })
+	return versions, nil
+}
+
+// loadManifest returns the latest manifest of the table with the given
+// prefix. The manifest is only read from the bucket if a newer version than
+// the loaded one was written. If the table has no manifest yet, one is built
+// by listing the bucket. manifestsMtx must be held.
+func (b *DefaultObjstoreBucket) loadManifest(ctx context.Context, table string) (*tableManifest, error) {
+	versions, err := b.manifestVersions(ctx, table)
+	if err != nil {
+		return nil, err
+	}
+	if len(versions) == 0 {
+		manifest := newTableManifest(0)
+		if err := b.listPartitionedBlocks(ctx, table, nil, func(blockDir string) error {
+			block, err := filepath.Rel(table, filepath.Clean(blockDir))
+			if err != nil {
+				return err
+			}
+			if _, err := ulid.Parse(filepath.Base(block)); err == nil {
+				manifest.blocks[block] = struct{}{}
+			}
+			return nil
+		}); err != nil {
+			return nil, err
+		}
+		return manifest, nil
+	}
+
+	latest := versions[len(versions)-1]
+	if manifest, ok := b.manifests[table]; ok && manifest.version == latest {
+		return manifest, nil
+	}
+	r, err := b.Bucket.Get(ctx, manifestName(table, latest))
+	if err != nil {
+		return nil, fmt.Errorf("read manifest: %w", err)
+	}
+	defer r.Close()
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("read manifest: %w", err)
+	}
+	manifest := newTableManifest(latest)
+	if err := manifest.UnmarshalText(data); err != nil {
+		return nil, err
+	}
+	b.manifests[table] = manifest
+	return manifest, nil
+}
+
+// updateManifest commits a new version of the manifest of the table of the
+// given block, with the block added or removed.
+func (b *DefaultObjstoreBucket) updateManifest(ctx context.Context, name string, add bool) error {
+	if !b.manifestsEnabled {
+		return nil
+	}
+	db, table, block, ok := parseBlockName(name)
+	if !ok {
+		return nil
+	}
+	return b.commitManifest(ctx, filepath.Join(db, table), func(m *tableManifest) bool {
+		_, exists := m.blocks[block]
+		if add {
+			m.blocks[block] = struct{}{}
+			return !exists
+		}
+		_, superseded := m.superseded[block]
+		delete(m.blocks, block)
+		delete(m.superseded, block)
+		return exists || superseded
+	})
+}
+
+// commitManifest commits a new version of the manifest of the table with the
+// given prefix, which is changed by update. No version is committed if update
+// returns false and the table already has a manifest.
+func (b *DefaultObjstoreBucket) commitManifest(ctx context.Context, prefix string, update func(*tableManifest) bool) error {
+	b.manifestsMtx.Lock()
+	defer b.manifestsMtx.Unlock()
+
+	var next *tableManifest
+	for attempt := 1; ; attempt++ {
+		manifest, err := b.loadManifest(ctx, prefix)
+		if err != nil {
+			return err
+		}
+		next = manifest.next()
+		if !update(next) && manifest.version != 0 {
+			return nil
+		}
+		data, err := next.MarshalText()
+		if err != nil {
+			return err
+		}
+		err = b.uploadManifest(ctx, manifestName(prefix, next.version), data)
+		if errors.Is(err, ErrPreconditionFailed) && attempt < manifestCommitAttempts {
+			// Another writer committed the version, the update is applied
+			// to it instead.
+			level.Warn(b.logger).Log("msg", "manifest version committed concurrently", "table", prefix, "version", next.version)
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("write manifest: %w", err)
+		}
+		break
+	}
+	b.manifests[prefix] = next
+
+	// Older versions are only removed once the new version is committed.
+	if next.version > manifestRetainedVersions {
+		for version := next.version - manifestRetainedVersions; version > 0; version-- {
+			if err := b.Bucket.Delete(ctx, manifestName(prefix, version)); err != nil {
+				if !b.IsObjNotFoundErr(err) {
+					level.Warn(b.logger).Log("msg", "failed to delete manifest version", "table", prefix, "version", version, "err", err)
+				}
+				break
+			}
+		}
+	}
+	return nil
+}
+
+// uploadManifest writes the manifest version with the given name. If the
+// bucket supports conditional writes, it fails with ErrPreconditionFailed if
+// the version already exists.
+func (b *DefaultObjstoreBucket) uploadManifest(ctx context.Context, name string, data []byte) error {
+	if cb, ok := conditionalBucket(b.Bucket); ok {
+		return cb.UploadIfVersion(ctx, name, bytes.NewReader(data), "")
+	}
+	return b.Bucket.Upload(ctx, name, bytes.NewReader(data))
+}
+
+// DeleteOrphanedBlocks deletes the blocks under the prefix of a table that
+// are not listed in the manifest of the table and whose objects were
+// uploaded more than minAge ago, e.g. blocks that were only partially
+// uploaded because the writer crashed. The age of a block is determined by
+// the last modification time of its objects rather than its ID, since the
+// ID of a block may predate its upload. Blocks uploaded through the bucket
+// are never deleted before their commit to the manifest finished, but blocks
+// uploaded by other writers are only protected by minAge, which must be
+// longer than it takes to upload a block. With leases enabled, the lease of
+// the table is acquired first. It returns the number of blocks deleted.
+// Manifests must be enabled, see StorageWithManifests.
+func (b *DefaultObjstoreBucket) DeleteOrphanedBlocks(ctx context.Context, prefix string, minAge time.Duration) (int, error) {
+	if !b.manifestsEnabled {
+		return 0, fmt.Errorf("manifests are not enabled")
+	}
+	prefix = filepath.Clean(prefix)
+	if b.leases != nil {
+		if err := b.leases.acquireTable(ctx, b, prefix); err != nil {
+			return 0, err
+		}
+	}
+	stats, err := b.deleteOrphanedBlocks(ctx, prefix, minAge)
+	return stats.Blocks, err
+}
+
+func (b *DefaultObjstoreBucket) deleteOrphanedBlocks(ctx context.Context, prefix string, minAge time.Duration) (BlockGCStats, error) {
+	b.manifestsMtx.Lock()
+	manifest, err := b.loadManifest(ctx, prefix)
+	b.manifestsMtx.Unlock()
+	if err != nil {
+		return BlockGCStats{}, err
+	}
+	if manifest.version == 0 {
+		// Without a manifest, all blocks are committed.
+		return BlockGCStats{}, nil
+	}
+
+	cutoff := b.clock.Now().Add(-minAge)
+	var unlisted []string
+	if err := b.listPartitionedBlocks(ctx, prefix, nil, func(blockDir string) error {
+		block, err := filepath.Rel(prefix, filepath.Clean(blockDir))
+		if err != nil {
+			return err
+		}
+		if _, err := ulid.Parse(filepath.Base(block)); err != nil {
+			return nil
+		}
+		if _, ok := manifest.blocks[block]; ok {
+			return nil
+		}
+		if _, ok := manifest.superseded[block]; ok {
+			return nil
+		}
+		unlisted = append(unlisted, filepath.Join(prefix, block))
+		return nil
+	}); err != nil {
+		return BlockGCStats{}, err
+	}
+
+	orphaned := unlisted[:0]
+	for _, blockDir := range unlisted {
+		if b.isCommitting(blockDir) {
+			continue
+		}
+		uploaded, ok, err := b.blockUploadedAt(ctx, blockDir)
+		if err != nil {
+			return BlockGCStats{}, err
+		}
+		if !ok || !uploaded.Before(cutoff) {
+			// The block may still be uploading.
+			continue
+		}
+		orphaned = append(orphaned, blockDir)
+	}
+	if len(orphaned) == 0 {
+		return BlockGCStats{}, nil
+	}
+
+	// Blocks whose commit finished since the manifest was loaded are listed
+	// in the latest version.
+	b.manifestsMtx.Lock()
+	manifest, err = b.loadManifest(ctx, prefix)
+	b.manifestsMtx.Unlock()
+	if err != nil {
+		return BlockGCStats{}, err
+	}
+
+	var stats BlockGCStats
+	for _, blockDir := range orphaned {
+		block, err := filepath.Rel(prefix, blockDir)
+		if err != nil {
+			return stats, err
+		}
+		if _, ok := manifest.blocks[block]; ok {
+			continue
+		}
+		n, err := b.deleteBlockObjects(ctx, blockDir)
+		if err != nil {
+			return stats, err
+		}
+		stats.Blocks++
+		stats.Bytes += n
+		level.Info(b.logger).Log("msg", "deleted orphaned block", "block", blockDir)
+	}
+	return stats, nil
+}
+
+// startCommit marks the block of the given object as being uploaded until
+// the returned function is called, which must be after the block is
+// committed to the manifest of its table.
+func (b *DefaultObjstoreBucket) startCommit(name string) func() {
+	if _, _, _, ok := parseBlockName(name); !ok {
+		return func() {}
+	}
+	blockDir := filepath.Dir(name)
+	b.committingMtx.Lock()
+	b.committing[blockDir]++
+	b.committingMtx.Unlock()
+	return func() {
+		b.committingMtx.Lock()
+		defer b.committingMtx.Unlock()
+		if b.committing[blockDir]--; b.committing[blockDir] == 0 {
+			delete(b.committing, blockDir)
+		}
+	}
+}
+
+// isCommitting returns whether an upload of the block in the given directory
+// is not committed to the manifest of its table yet.
+func (b *DefaultObjstoreBucket) isCommitting(blockDir string) bool {
+	b.committingMtx.Lock()
+	defer b.committingMtx.Unlock()
+	return b.committing[blockDir] > 0
+}
+
+// blockUploadedAt returns the last modification time of the objects of the
+// block in the given directory. It returns false if the block has no
+// objects.
+func (b *DefaultObjstoreBucket) blockUploadedAt(ctx context.Context, blockDir string) (time.Time, bool, error) {
+	var (
+		uploaded time.Time
+		found    bool
+	)
+	err := b.Bucket.Iter(ctx, blockDir, func(name string) error {
+		attrs, err := b.Bucket.Attributes(ctx, name)
+		if err != nil {
+			if b.IsObjNotFoundErr(err) {
+				return nil
+			}
+			return err
+		}
+		found = true
+		if attrs.LastModified.After(uploaded) {
+			uploaded = attrs.LastModified
+		}
+		return nil
+	}, objstore.WithRecursiveIter)
+	return uploaded, found, err
+}
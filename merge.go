@@ -0,0 +1,254 @@
+package frostdb
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+
+	"github.com/apache/arrow/go/v17/arrow"
+	"github.com/parquet-go/parquet-go"
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/polarsignals/frostdb/dynparquet"
+	tablepb "github.com/polarsignals/frostdb/gen/proto/go/frostdb/table/v1alpha1"
+	"github.com/polarsignals/frostdb/parts"
+	"github.com/polarsignals/frostdb/pqarrow"
+	"github.com/polarsignals/frostdb/query/logicalplan"
+)
+
+// mergeReducer returns the reducer that rows with equal sorting columns are
+// merged with according to the merge policy of the given table config, or nil
+// if all rows are kept.
+func (s *ColumnStore) mergeReducer(schema *dynparquet.Schema, config *tablepb.TableConfig) (dynparquet.RowReducer, error) {
+	var reduce dynparquet.RowReducer
+	switch policy := config.GetMergePolicy(); policy {
+	case tablepb.MergePolicy_MERGE_POLICY_KEEP_ALL_UNSPECIFIED:
+		return nil, nil
+	case tablepb.MergePolicy_MERGE_POLICY_KEEP_LATEST:
+		reduce = dynparquet.KeepLatest
+	case tablepb.MergePolicy_MERGE_POLICY_REDUCE:
+		var ok bool
+		reduce, ok = s.rowReducers[config.GetMergeReducer()]
+		if !ok {
+			// Reducers registered with the column store take precedence
+			// over the built-in aggregations.
+			var err error
+			reduce, err = dynparquet.AggregateRows(dynparquet.Aggregation(config.GetMergeReducer()), isSortingColumn(schema))
+			if err != nil {
+				return nil, fmt.Errorf("merge reducer %q: %w", config.GetMergeReducer(), err)
+			}
+		}
+	default:
+		return nil, fmt.Errorf("unknown merge policy: %s", policy)
+	}
+
+	if schema.UniquePrimaryIndex {
+		// The unique primary index already drops all but one of the rows,
+		// without regard to which was inserted last.
+		return nil, errors.New("merge policies cannot be used with a unique primary index")
+	}
+	return reduce, nil
+}
+
+// isSortingColumn returns a function reporting whether the leaf column at the
+// given path is a sorting column of the schema, or a concrete column of a
+// dynamic sorting column.
+func isSortingColumn(schema *dynparquet.Schema) func(path []string) bool {
+	names := map[string]struct{}{}
+	for _, col := range schema.SortingColumns() {
+		names[col.ColumnName()] = struct{}{}
+	}
+	return func(path []string) bool {
+		for i := range path {
+			if _, ok := names[strings.Join(path[:i+1], ".")]; ok {
+				return true
+			}
+		}
+		if def, ok := schema.FindDynamicColumnForConcreteColumn(path[0]); ok {
+			_, ok = names[def.Name]
+			return ok
+		}
+		return false
+	}
+}
+
+// mergeFilter returns the filter that the parts of an in-memory block can be
+// scanned with before their rows are merged, or nil if all parts must be
+// scanned. Rows are only merged with rows whose sorting columns are equal, so
+// a filter on sorting columns alone matches either all or none of the rows
+// merged together. A filter on other columns could skip rows that change the
+// merged row.
+func mergeFilter(schema *dynparquet.Schema, filter logicalplan.Expr) logicalplan.Expr {
+	if filter == nil {
+		return nil
+	}
+	sorting := isSortingColumn(schema)
+	for _, expr := range filter.ColumnsUsedExprs() {
+		col, ok := expr.(*logicalplan.Column)
+		if !ok || !sorting([]string{col.Name()}) {
+			return nil
+		}
+	}
+	return filter
+}
+
+// countMergedRows wraps reduce to count the rows it merges away, i.e. all but
+// one of the rows it is passed. It returns nil if reduce is nil.
+func countMergedRows(reduce dynparquet.RowReducer, merged prometheus.Counter) dynparquet.RowReducer {
+	if reduce == nil {
+		return nil
+	}
+	return func(schema *parquet.Schema, rows []parquet.Row) (parquet.Row, error) {
+		row, err := reduce(schema, rows)
+		if err == nil && len(rows) > 1 {
+			merged.Add(float64(len(rows) - 1))
+		}
+		return row, err
+	}
+}
+
+// reduceParts compacts the given parts into a Parquet file written to w,
+// merging rows with equal sorting columns using the table's merge reducer.
+func (t *Table) reduceParts(w io.Writer, compact []parts.Part, options ...parquet.WriterOption) error {
+	// Parts are merged from oldest to newest, so that the reducer is passed
+	// rows in the order they were inserted in. A compacted part has the
+	// transaction of the newest part it was compacted from.
+	compact = slices.Clone(compact)
+	slices.SortStableFunc(compact, func(a, b parts.Part) int {
+		return cmp.Compare(a.TX(), b.TX())
+	})
+
+	schema := t.schema.Load()
+	bufs := make([]dynparquet.DynamicRowGroup, 0, len(compact))
+	for _, p := range compact {
+		if r := p.Record(); r != nil {
+			rg, err := sortedRecordRowGroup(schema, r)
+			if err != nil {
+				return err
+			}
+			bufs = append(bufs, rg)
+			continue
+		}
+		buf, err := p.AsSerializedBuffer(schema)
+		if err != nil {
+			return err
+		}
+		bufs = append(bufs, buf.MultiDynamicRowGroup())
+	}
+
+	merged, err := schema.MergeDynamicRowGroups(bufs, dynparquet.WithRowReducer(countMergedRows(t.mergeReducer, t.metrics.rowsMerged)))
+	if err != nil {
+		return err
+	}
+	return t.writeMergedRowGroups(w, merged, options...)
+}
+
+// sortedRecordRowGroup returns the rows of the given inserted record sorted by
+// the sorting columns of schema. Inserted records are not sorted, but rows are
+// only merged with the rows they are sorted next to. Equal rows keep the order
+// they were inserted in.
+func sortedRecordRowGroup(schema *dynparquet.Schema, r arrow.Record) (dynparquet.DynamicRowGroup, error) {
+	buf, err := pqarrow.SerializeRecord(r, schema)
+	if err != nil {
+		return nil, err
+	}
+	sorted, err := schema.NewBuffer(buf.DynamicColumns())
+	if err != nil {
+		return nil, err
+	}
+	if _, err := sorted.WriteRowGroup(buf.MultiDynamicRowGroup()); err != nil {
+		return nil, err
+	}
+	sorted.SortStable()
+	return sorted, nil
+}
+
+// scanMergedBlock sends the rows of the block's parts visible at tx to send,
+// merged with the table's merge reducer, so that queries see the rows as they
+// will be once the block is compacted. Parts are only skipped by the query's
+// filter if it is a filter on sorting columns, see mergeFilter, since a row
+// may otherwise be merged with rows that do not match it.
+func (t *Table) scanMergedBlock(ctx context.Context, block *TableBlock, tx uint64, filterExpr logicalplan.Expr, provenance bool, send func(context.Context, any) error) error {
+	type scanned struct {
+		tx       uint64
+		rowGroup dynparquet.DynamicRowGroup
+	}
+	var (
+		values  []any
+		scans   []scanned
+		newest  uint64
+		schema  = t.schema.Load()
+		release = func() {
+			for _, v := range values {
+				releaseScanValue(v)
+			}
+		}
+	)
+	defer release()
+	if err := block.index.ScanParts(ctx, mergeFilter(schema, filterExpr), tx, func(_ context.Context, v any, part parts.Part) error {
+		values = append(values, v)
+		var rowGroup dynparquet.DynamicRowGroup
+		switch v := v.(type) {
+		case arrow.Record:
+			rg, err := sortedRecordRowGroup(schema, v)
+			if err != nil {
+				return err
+			}
+			rowGroup = rg
+		case dynparquet.DynamicRowGroup:
+			rowGroup = v
+		default:
+			return fmt.Errorf("unknown scan value type: %T", v)
+		}
+		scans = append(scans, scanned{tx: part.TX(), rowGroup: rowGroup})
+		newest = max(newest, part.TX())
+		return nil
+	}); err != nil {
+		return err
+	}
+	if len(scans) == 0 {
+		return nil
+	}
+
+	slices.SortStableFunc(scans, func(a, b scanned) int {
+		return cmp.Compare(a.tx, b.tx)
+	})
+	rowGroups := make([]dynparquet.DynamicRowGroup, 0, len(scans))
+	for _, s := range scans {
+		rowGroups = append(rowGroups, s.rowGroup)
+	}
+	merged, err := schema.MergeDynamicRowGroups(rowGroups, dynparquet.WithRowReducer(t.mergeReducer))
+	if err != nil {
+		return err
+	}
+	var w bytes.Buffer
+	if err := t.writeMergedRowGroups(&w, merged); err != nil {
+		return err
+	}
+	release()
+	values = nil
+
+	buf, err := dynparquet.ReaderFromBytes(w.Bytes())
+	if err != nil {
+		return err
+	}
+	for i := 0; i < buf.NumRowGroups(); i++ {
+		var v any = buf.DynamicRowGroup(i)
+		if provenance {
+			v = provenanceValue{value: v, provenance: Provenance{
+				Source: ProvenanceSourceMemory,
+				Block:  block.ulid,
+				Tx:     newest,
+			}}
+		}
+		if err := send(ctx, v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,268 @@
+package frostdb
+
+import (
+	"context"
+	"errors"
+	"math"
+	"testing"
+
+	"github.com/apache/arrow/go/v17/arrow"
+	"github.com/apache/arrow/go/v17/arrow/array"
+	"github.com/apache/arrow/go/v17/arrow/memory"
+	"github.com/parquet-go/parquet-go"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+
+	"github.com/polarsignals/frostdb/dynparquet"
+	schemapb "github.com/polarsignals/frostdb/gen/proto/go/frostdb/schema/v1alpha1"
+	"github.com/polarsignals/frostdb/query"
+	"github.com/polarsignals/frostdb/query/logicalplan"
+)
+
+func TestMergePolicy(t *testing.T) {
+	ctx := context.Background()
+	sum := func(schema *parquet.Schema, rows []parquet.Row) (parquet.Row, error) {
+		leaf, ok := schema.Lookup("value")
+		if !ok {
+			return nil, errors.New("value column not found")
+		}
+		total := int64(0)
+		for _, row := range rows {
+			total += row[leaf.ColumnIndex].Int64()
+		}
+		row := rows[len(rows)-1]
+		row[leaf.ColumnIndex] = parquet.Int64Value(total).Level(0, 0, leaf.ColumnIndex)
+		return row, nil
+	}
+
+	for _, tc := range []struct {
+		name     string
+		options  []TableOption
+		expected map[int64]int64
+	}{{
+		name:     "KeepAll",
+		expected: map[int64]int64{1: 6, 2: 30},
+	}, {
+		name:     "KeepLatest",
+		options:  []TableOption{WithMergeKeepLatest()},
+		expected: map[int64]int64{1: 3, 2: 20},
+	}, {
+		name:     "Deduplication",
+		options:  []TableOption{WithDeduplication()},
+		expected: map[int64]int64{1: 3, 2: 20},
+	}, {
+		name:     "Reducer",
+		options:  []TableOption{WithMergeReducer("total")},
+		expected: map[int64]int64{1: 6, 2: 30},
+	}, {
+		name:     "AggregationSum",
+		options:  []TableOption{WithMergeAggregation(dynparquet.AggregationSum)},
+		expected: map[int64]int64{1: 6, 2: 30},
+	}, {
+		name:     "AggregationMax",
+		options:  []TableOption{WithMergeAggregation(dynparquet.AggregationMax)},
+		expected: map[int64]int64{1: 3, 2: 20},
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			c, err := New(
+				WithLogger(newTestLogger(t)),
+				WithRowReducer("total", sum),
+			)
+			require.NoError(t, err)
+			defer c.Close()
+			db, err := c.DB(ctx, "test")
+			require.NoError(t, err)
+			table, err := db.Table("test", NewTableConfig(dynparquet.SampleDefinition(), tc.options...))
+			require.NoError(t, err)
+
+			// Rows with equal timestamps have equal sorting columns.
+			for _, samples := range []dynparquet.Samples{{
+				{ExampleType: "cpu", Labels: map[string]string{"label1": "a"}, Timestamp: 1, Value: 1},
+				{ExampleType: "cpu", Labels: map[string]string{"label1": "a"}, Timestamp: 2, Value: 10},
+			}, {
+				{ExampleType: "cpu", Labels: map[string]string{"label1": "a"}, Timestamp: 1, Value: 2},
+			}, {
+				{ExampleType: "cpu", Labels: map[string]string{"label1": "a"}, Timestamp: 2, Value: 20},
+				{ExampleType: "cpu", Labels: map[string]string{"label1": "a"}, Timestamp: 1, Value: 3},
+			}} {
+				r, err := samples.ToRecord()
+				require.NoError(t, err)
+				_, err = table.InsertRecord(ctx, r)
+				r.Release()
+				require.NoError(t, err)
+			}
+
+			values := func(filter ...logicalplan.Expr) (int, map[int64]int64) {
+				rows := 0
+				values := map[int64]int64{}
+				builder := query.NewEngine(memory.NewGoAllocator(), db.TableProvider()).ScanTable("test")
+				if len(filter) > 0 {
+					builder = builder.Filter(filter[0]).Project(logicalplan.Col("timestamp"), logicalplan.Col("value"))
+				}
+				require.NoError(t, builder.Execute(ctx, func(_ context.Context, r arrow.Record) error {
+					if r.NumRows() == 0 {
+						return nil
+					}
+					timestamps := r.Column(r.Schema().FieldIndices("timestamp")[0]).(*array.Int64)
+					vals := r.Column(r.Schema().FieldIndices("value")[0]).(*array.Int64)
+					for i := 0; i < int(r.NumRows()); i++ {
+						values[timestamps.Value(i)] += vals.Value(i)
+					}
+					rows += int(r.NumRows())
+					return nil
+				}))
+				return rows, values
+			}
+
+			expectedRows := 2
+			if len(tc.options) == 0 {
+				expectedRows = 5
+			}
+			rowsMerged := func() float64 {
+				return testutil.ToFloat64(c.metrics.tableMetrics.rowsMerged.WithLabelValues("test", "test"))
+			}
+
+			// Queries merge the rows of in-memory blocks before they are
+			// compacted.
+			rows, result := values()
+			require.Equal(t, tc.expected, result)
+			require.Equal(t, expectedRows, rows)
+			require.Zero(t, rowsMerged())
+
+			// Filters on sorting columns skip parts before rows are merged,
+			// filters on other columns are applied to the merged rows.
+			_, result = values(logicalplan.Col("timestamp").Eq(logicalplan.Literal(int64(1))))
+			require.Equal(t, map[int64]int64{1: tc.expected[1]}, result)
+			_, result = values(logicalplan.Col("value").Eq(logicalplan.Literal(tc.expected[2])))
+			if len(tc.options) > 0 {
+				require.Equal(t, map[int64]int64{2: tc.expected[2]}, result)
+			}
+
+			require.NoError(t, table.EnsureCompaction())
+			rows, result = values()
+			require.Equal(t, tc.expected, result)
+			require.Equal(t, expectedRows, rows)
+			require.Equal(t, float64(5-expectedRows), rowsMerged())
+		})
+	}
+
+	t.Run("Invalid", func(t *testing.T) {
+		c, err := New(WithLogger(newTestLogger(t)))
+		require.NoError(t, err)
+		defer c.Close()
+		db, err := c.DB(ctx, "test")
+		require.NoError(t, err)
+
+		_, err = db.Table("unknown", NewTableConfig(dynparquet.SampleDefinition(), WithMergeReducer("total")))
+		require.Error(t, err)
+		_, err = db.Table("aggregation", NewTableConfig(dynparquet.SampleDefinition(), WithMergeAggregation("avg")))
+		require.ErrorContains(t, err, "unknown aggregation")
+		_, err = db.Table("unique", NewTableConfig(dynparquet.SampleDefinition(), WithMergeKeepLatest(), WithUniquePrimaryIndex(true)))
+		require.Error(t, err)
+	})
+}
+
+func TestMergeAggregationOverflow(t *testing.T) {
+	ctx := context.Background()
+	c, err := New(WithLogger(newTestLogger(t)))
+	require.NoError(t, err)
+	defer c.Close()
+	db, err := c.DB(ctx, "test")
+	require.NoError(t, err)
+	table, err := db.Table("test", NewTableConfig(dynparquet.SampleDefinition(), WithMergeAggregation(dynparquet.AggregationSum)))
+	require.NoError(t, err)
+
+	for _, value := range []int64{math.MaxInt64, 1} {
+		r, err := dynparquet.Samples{
+			{ExampleType: "cpu", Labels: map[string]string{"label1": "a"}, Timestamp: 1, Value: value},
+		}.ToRecord()
+		require.NoError(t, err)
+		_, err = table.InsertRecord(ctx, r)
+		r.Release()
+		require.NoError(t, err)
+	}
+
+	err = query.NewEngine(memory.NewGoAllocator(), db.TableProvider()).
+		ScanTable("test").
+		Execute(ctx, func(context.Context, arrow.Record) error { return nil })
+	require.ErrorIs(t, err, dynparquet.ErrSumOverflow)
+}
+
+func TestMergeAggregationUnsigned(t *testing.T) {
+	schema := &schemapb.Schema{
+		Name: "unsigned",
+		Columns: []*schemapb.Column{{
+			Name:          "name",
+			StorageLayout: &schemapb.StorageLayout{Type: schemapb.StorageLayout_TYPE_STRING},
+		}, {
+			Name:          "total",
+			StorageLayout: &schemapb.StorageLayout{Type: schemapb.StorageLayout_TYPE_UINT64},
+		}},
+		SortingColumns: []*schemapb.SortingColumn{{
+			Name:      "name",
+			Direction: schemapb.SortingColumn_DIRECTION_ASCENDING,
+		}},
+	}
+	c, db := openTestDB(t)
+	defer c.Close()
+	table, err := db.Table("test", NewTableConfig(schema, WithMergeAggregation(dynparquet.AggregationSum)))
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	mem := memory.NewGoAllocator()
+	insert := func(total uint64) {
+		bldr := array.NewRecordBuilder(mem, arrow.NewSchema([]arrow.Field{
+			{Name: "name", Type: arrow.BinaryTypes.String},
+			{Name: "total", Type: arrow.PrimitiveTypes.Uint64},
+		}, nil))
+		defer bldr.Release()
+		bldr.Field(0).(*array.StringBuilder).Append("a")
+		bldr.Field(1).(*array.Uint64Builder).Append(total)
+		r := bldr.NewRecord()
+		defer r.Release()
+		_, err := table.InsertRecord(ctx, r)
+		require.NoError(t, err)
+	}
+	engine := query.NewEngine(mem, db.TableProvider())
+	total := func() (uint64, error) {
+		var total uint64
+		err := engine.ScanTable("test").Execute(ctx, func(_ context.Context, r arrow.Record) error {
+			totals := r.Column(r.Schema().FieldIndices("total")[0]).(*array.Uint64)
+			for i := 0; i < totals.Len(); i++ {
+				total = totals.Value(i)
+			}
+			return nil
+		})
+		return total, err
+	}
+
+	// Sums at or above 2^63 don't overflow unsigned columns.
+	insert(math.MaxInt64)
+	insert(1)
+	sum, err := total()
+	require.NoError(t, err)
+	require.Equal(t, uint64(1)<<63, sum)
+
+	insert(math.MaxUint64 - 1<<63)
+	sum, err = total()
+	require.NoError(t, err)
+	require.Equal(t, uint64(math.MaxUint64), sum)
+
+	insert(1)
+	_, err = total()
+	require.ErrorIs(t, err, dynparquet.ErrSumOverflow)
+}
+
+func TestMergeFilter(t *testing.T) {
+	schema := dynparquet.SampleDefinition()
+	s, err := dynparquet.SchemaFromDefinition(schema)
+	require.NoError(t, err)
+
+	sorting := logicalplan.And(
+		logicalplan.Col("labels.label1").Eq(logicalplan.Literal("a")),
+		logicalplan.Col("timestamp").Gt(logicalplan.Literal(int64(1))),
+	)
+	require.Equal(t, sorting, mergeFilter(s, sorting))
+	require.Nil(t, mergeFilter(s, logicalplan.And(sorting, logicalplan.Col("value").Gt(logicalplan.Literal(int64(1))))))
+	require.Nil(t, mergeFilter(s, nil))
+}
@@ -0,0 +1,219 @@
+package frostdb
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	snapshotpb "github.com/polarsignals/frostdb/gen/proto/go/frostdb/snapshot/v1alpha1"
+	walpb "github.com/polarsignals/frostdb/gen/proto/go/frostdb/wal/v1alpha1"
+)
+
+// metaPath is the name of the file in the storage path of a database that
+// contains the latest checkpoint of the metadata store.
+const metaPath = "meta"
+
+func (db *DB) metaFile() string {
+	return filepath.Join(db.storagePath, metaPath)
+}
+
+// MetaPut stores value under key in the metadata store of the database. The
+// metadata store is meant for small amounts of application metadata (e.g.
+// ingestion cursors or schema versions) that should have the same durability
+// and recovery semantics as the data of the database. Every update is its own
+// transaction, is logged to the WAL and is included in snapshots.
+func (db *DB) MetaPut(ctx context.Context, key string, value []byte) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if db.columnStore.readOnly {
+		return ErrReadOnly
+	}
+	// Copy the value so that callers are free to reuse it.
+	return db.logMeta(&walpb.Entry_Meta{Key: key, Value: append([]byte{}, value...)})
+}
+
+// MetaDelete removes key from the metadata store of the database. Deleting a
+// key that doesn't exist is not an error.
+func (db *DB) MetaDelete(ctx context.Context, key string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if db.columnStore.readOnly {
+		return ErrReadOnly
+	}
+	return db.logMeta(&walpb.Entry_Meta{Key: key, Delete: true})
+}
+
+// MetaGet returns the value stored under key in the metadata store of the
+// database and whether the key exists. The returned value must not be
+// modified.
+func (db *DB) MetaGet(key string) ([]byte, bool) {
+	db.metaMtx.RLock()
+	defer db.metaMtx.RUnlock()
+	value, ok := db.meta[key]
+	return value, ok
+}
+
+// MetaKeys returns the sorted keys of the metadata store of the database.
+func (db *DB) MetaKeys() []string {
+	db.metaMtx.RLock()
+	defer db.metaMtx.RUnlock()
+	keys := make([]string, 0, len(db.meta))
+	for key := range db.meta {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// logMeta logs the metadata update in a new transaction and applies it. The
+// metadata mutex is held for the duration of the transaction so that updates
+// are applied in txn order.
+func (db *DB) logMeta(entry *walpb.Entry_Meta) error {
+	db.metaMtx.Lock()
+	defer db.metaMtx.Unlock()
+
+	tx, _, commit := db.begin()
+	defer commit()
+
+	if err := db.wal.Log(tx, &walpb.Record{
+		Entry: &walpb.Entry{
+			EntryType: &walpb.Entry_Meta_{Meta: entry},
+		},
+	}); err != nil {
+		return err
+	}
+
+	db.applyMetaLocked(entry)
+	return nil
+}
+
+// applyMeta applies a metadata update, e.g. during WAL replay. Updates are
+// absolute, so replaying updates that are already reflected in the metadata
+// store is harmless as long as they are replayed in order.
+func (db *DB) applyMeta(entry *walpb.Entry_Meta) {
+	db.metaMtx.Lock()
+	defer db.metaMtx.Unlock()
+	db.applyMetaLocked(entry)
+}
+
+func (db *DB) applyMetaLocked(entry *walpb.Entry_Meta) {
+	if entry.Delete {
+		delete(db.meta, entry.Key)
+		return
+	}
+	db.meta[entry.Key] = entry.Value
+}
+
+// metaEntriesLocked returns the metadata store of the database in its
+// snapshot representation. metaMtx must be held.
+func (db *DB) metaEntriesLocked() []*snapshotpb.MetaEntry {
+	entries := make([]*snapshotpb.MetaEntry, 0, len(db.meta))
+	for key, value := range db.meta {
+		entries = append(entries, &snapshotpb.MetaEntry{Key: key, Value: value})
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Key < entries[j].Key
+	})
+	return entries
+}
+
+// snapshotMeta returns the metadata store of the database in its snapshot
+// representation.
+func (db *DB) snapshotMeta() []*snapshotpb.MetaEntry {
+	db.metaMtx.RLock()
+	defer db.metaMtx.RUnlock()
+	return db.metaEntriesLocked()
+}
+
+// loadSnapshotMeta replaces the metadata store of the database with the
+// given entries.
+func (db *DB) loadSnapshotMeta(entries []*snapshotpb.MetaEntry) {
+	db.metaMtx.Lock()
+	defer db.metaMtx.Unlock()
+	db.meta = make(map[string][]byte, len(entries))
+	for _, e := range entries {
+		db.meta[e.Key] = e.Value
+	}
+}
+
+// checkpointMeta writes the metadata store of the database to the metadata
+// file. The checkpoint is necessary whenever WAL records are removed without
+// a snapshot, e.g. when the WAL is truncated after a block is persisted or
+// when the storage is dropped on close. The file is removed if the metadata
+// store is empty. If resetTx is set, the checkpoint is recorded to precede all
+// transactions, since txns restart from zero after the storage is dropped.
+//
+// The file contains the txn of the checkpoint as a little-endian uint64
+// followed by the metadata entries encoded as a FooterData message.
+func (db *DB) checkpointMeta(resetTx bool) error {
+	if db.storagePath == "" || !db.columnStore.enableWAL {
+		return nil
+	}
+
+	db.metaMtx.Lock()
+	defer db.metaMtx.Unlock()
+
+	if len(db.meta) == 0 {
+		if err := os.Remove(db.metaFile()); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+		return nil
+	}
+
+	// No metadata transactions are in flight while metaMtx is held, so the
+	// metadata store reflects all transactions up to and including tx.
+	tx := db.tx.Load()
+	if resetTx {
+		tx = 0
+	}
+	b, err := (&snapshotpb.FooterData{Meta: db.metaEntriesLocked()}).MarshalVT()
+	if err != nil {
+		return err
+	}
+	b = append(binary.LittleEndian.AppendUint64(nil, tx), b...)
+
+	if err := os.MkdirAll(db.storagePath, dirPerms); err != nil {
+		return err
+	}
+	tmp := db.metaFile() + ".tmp"
+	if err := os.WriteFile(tmp, b, filePerms); err != nil {
+		return err
+	}
+	return os.Rename(tmp, db.metaFile())
+}
+
+// loadMetaCheckpoint loads the metadata file if no snapshot was loaded or if
+// it is more recent than the snapshot loaded at snapshotTx. WAL records that
+// follow the snapshot are replayed on top of the checkpoint.
+func (db *DB) loadMetaCheckpoint(snapshotTx uint64) error {
+	if db.storagePath == "" {
+		return nil
+	}
+
+	b, err := os.ReadFile(db.metaFile())
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		return err
+	}
+	if len(b) < 8 {
+		return fmt.Errorf("invalid metadata file: size %d", len(b))
+	}
+	if tx := binary.LittleEndian.Uint64(b[:8]); snapshotTx != 0 && tx <= snapshotTx {
+		return nil
+	}
+
+	footer := &snapshotpb.FooterData{}
+	if err := footer.UnmarshalVT(b[8:]); err != nil {
+		return fmt.Errorf("invalid metadata file: %w", err)
+	}
+	db.loadSnapshotMeta(footer.Meta)
+	return nil
+}
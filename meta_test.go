@@ -0,0 +1,116 @@
+package frostdb
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/thanos-io/objstore"
+
+	"github.com/polarsignals/frostdb/dynparquet"
+)
+
+func TestDBMeta(t *testing.T) {
+	ctx := context.Background()
+
+	writeMeta := func(t *testing.T, db *DB) {
+		require.NoError(t, db.MetaPut(ctx, "cursor", []byte("1")))
+		require.NoError(t, db.MetaPut(ctx, "version", []byte("v1")))
+		require.NoError(t, db.MetaPut(ctx, "flag", []byte("on")))
+		require.NoError(t, db.MetaDelete(ctx, "flag"))
+		require.NoError(t, db.MetaPut(ctx, "cursor", []byte("2")))
+	}
+
+	requireMeta := func(t *testing.T, db *DB) {
+		require.Equal(t, []string{"cursor", "version"}, db.MetaKeys())
+		v, ok := db.MetaGet("cursor")
+		require.True(t, ok)
+		require.Equal(t, []byte("2"), v)
+		v, ok = db.MetaGet("version")
+		require.True(t, ok)
+		require.Equal(t, []byte("v1"), v)
+		_, ok = db.MetaGet("flag")
+		require.False(t, ok)
+	}
+
+	for _, tc := range []struct {
+		name string
+		opts []Option
+		// write is called after the metadata has been written.
+		write func(t *testing.T, db *DB)
+	}{
+		{
+			name: "WAL",
+		},
+		{
+			name: "Snapshot",
+			opts: []Option{WithSnapshotTriggerSize(1 * GiB)},
+		},
+		{
+			name: "Persisted",
+			opts: []Option{WithReadWriteStorage(NewDefaultObjstoreBucket(objstore.NewInMemBucket()))},
+		},
+		{
+			name: "Truncated",
+			opts: []Option{
+				WithReadWriteStorage(NewDefaultObjstoreBucket(objstore.NewInMemBucket())),
+				WithManualBlockRotation(),
+			},
+			write: func(t *testing.T, db *DB) {
+				table, err := db.Table("test", NewTableConfig(dynparquet.SampleDefinition()))
+				require.NoError(t, err)
+				r, err := dynparquet.NewTestSamples().ToRecord()
+				require.NoError(t, err)
+				_, err = table.InsertRecord(ctx, r)
+				require.NoError(t, err)
+				require.NoError(t, table.RotateBlock(ctx, table.ActiveBlock()))
+				// Persisting the block truncates the WAL, so the metadata
+				// must be checkpointed.
+				require.Eventually(t, func() bool {
+					_, err := os.Stat(db.metaFile())
+					return err == nil
+				}, time.Second, 10*time.Millisecond)
+			},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			dir := t.TempDir()
+			opts := append([]Option{
+				WithLogger(newTestLogger(t)),
+				WithWAL(),
+				WithStoragePath(dir),
+			}, tc.opts...)
+
+			c, err := New(opts...)
+			require.NoError(t, err)
+			db, err := c.DB(ctx, "test")
+			require.NoError(t, err)
+
+			writeMeta(t, db)
+			requireMeta(t, db)
+			if tc.write != nil {
+				tc.write(t, db)
+			}
+			require.NoError(t, c.Close())
+
+			c, err = New(opts...)
+			require.NoError(t, err)
+			db, err = c.DB(ctx, "test")
+			require.NoError(t, err)
+			requireMeta(t, db)
+
+			// Updates after recovery are retained as well.
+			require.NoError(t, db.MetaDelete(ctx, "version"))
+			require.NoError(t, c.Close())
+
+			c, err = New(opts...)
+			require.NoError(t, err)
+			db, err = c.DB(ctx, "test")
+			require.NoError(t, err)
+			require.Equal(t, []string{"cursor"}, db.MetaKeys())
+			require.NoError(t, c.Close())
+		})
+	}
+}
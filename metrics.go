@@ -21,6 +21,41 @@ var (
 		"Size of the active table block in bytes.",
 		[]string{"db", "table"}, nil,
 	)
+	descPersistedBytes = prometheus.NewDesc(
+		"frostdb_table_persisted_bytes",
+		"Size of the persisted blocks of the table in bytes.",
+		[]string{"db", "table"}, nil,
+	)
+	descLevelParts = prometheus.NewDesc(
+		"frostdb_lsm_level_parts",
+		"Number of parts in the level of the active table block index.",
+		[]string{"db", "table", "level"}, nil,
+	)
+	descOldestUncompactedPartAge = prometheus.NewDesc(
+		"frostdb_lsm_oldest_uncompacted_part_age_seconds",
+		"Age of the oldest L0 part of the active table block index that has not been compacted yet, or 0 if there is none.",
+		[]string{"db", "table"}, nil,
+	)
+	descBlockCacheHits = prometheus.NewDesc(
+		"frostdb_block_cache_hits_total",
+		"Number of block byte ranges read from the local block cache.",
+		nil, nil,
+	)
+	descBlockCacheMisses = prometheus.NewDesc(
+		"frostdb_block_cache_misses_total",
+		"Number of block byte ranges downloaded from storage because they were not in the local block cache.",
+		nil, nil,
+	)
+	descBlockCacheEvictions = prometheus.NewDesc(
+		"frostdb_block_cache_evictions_total",
+		"Number of block byte ranges evicted from the local block cache.",
+		nil, nil,
+	)
+	descBlockCacheBytes = prometheus.NewDesc(
+		"frostdb_block_cache_bytes",
+		"Size of the local block cache in bytes.",
+		nil, nil,
+	)
 )
 
 // collector is a custom prometheus collector that exports metrics from live
@@ -34,10 +69,24 @@ var _ prometheus.Collector = (*collector)(nil)
 func (c *collector) Describe(ch chan<- *prometheus.Desc) {
 	ch <- descTxHighWatermark
 	ch <- descActiveBlockSize
+	ch <- descPersistedBytes
+	ch <- descLevelParts
+	ch <- descOldestUncompactedPartAge
+	ch <- descBlockCacheHits
+	ch <- descBlockCacheMisses
+	ch <- descBlockCacheEvictions
+	ch <- descBlockCacheBytes
 }
 
 func (c *collector) Collect(ch chan<- prometheus.Metric) {
-	for _, dbName := range c.s.DBs() {
+	if c.s.blockCache != nil {
+		stats := c.s.blockCache.Stats()
+		ch <- prometheus.MustNewConstMetric(descBlockCacheHits, prometheus.CounterValue, float64(stats.Hits))
+		ch <- prometheus.MustNewConstMetric(descBlockCacheMisses, prometheus.CounterValue, float64(stats.Misses))
+		ch <- prometheus.MustNewConstMetric(descBlockCacheEvictions, prometheus.CounterValue, float64(stats.Evictions))
+		ch <- prometheus.MustNewConstMetric(descBlockCacheBytes, prometheus.GaugeValue, float64(stats.Bytes))
+	}
+	for _, dbName := range c.s.openDBs() {
 		db, err := c.s.GetDB(dbName)
 		if err != nil {
 			continue
@@ -48,11 +97,23 @@ func (c *collector) Collect(ch chan<- prometheus.Metric) {
 			if err != nil {
 				continue
 			}
+			ch <- prometheus.MustNewConstMetric(descPersistedBytes, prometheus.GaugeValue, float64(table.Stats().PersistedBytes), dbName, tableName)
 			activeBlock := table.ActiveBlock()
 			if activeBlock == nil {
 				continue
 			}
 			ch <- prometheus.MustNewConstMetric(descActiveBlockSize, prometheus.GaugeValue, float64(activeBlock.Size()), dbName, tableName)
+			for _, stats := range activeBlock.Index().Stats() {
+				ch <- prometheus.MustNewConstMetric(descLevelParts, prometheus.GaugeValue, float64(stats.Parts), dbName, tableName, stats.Level.String())
+				if stats.Level != index.L0 {
+					continue
+				}
+				var age float64
+				if !stats.Oldest.IsZero() {
+					age = c.s.clock.Now().Sub(stats.Oldest).Seconds()
+				}
+				ch <- prometheus.MustNewConstMetric(descOldestUncompactedPartAge, prometheus.GaugeValue, age, dbName, tableName)
+			}
 		}
 	}
 }
@@ -64,6 +125,7 @@ type globalMetrics struct {
 	shutdownDuration  prometheus.Histogram
 	shutdownStarted   prometheus.Counter
 	shutdownCompleted prometheus.Counter
+	queryMetrics      queryGateMetrics
 	dbMetrics         struct {
 		snapshotMetrics struct {
 			snapshotsTotal            *prometheus.CounterVec
@@ -88,17 +150,25 @@ type globalMetrics struct {
 			walRepairsLostRecords *prometheus.CounterVec
 			walCloseTimeouts      *prometheus.CounterVec
 			walQueueSize          *prometheus.GaugeVec
+			walQueueBytes         *prometheus.GaugeVec
+			walQueueFull          *prometheus.CounterVec
+			walCorruptions        *prometheus.CounterVec
 		}
 	}
 	tableMetrics struct {
 		blockPersisted       *prometheus.CounterVec
 		blockRotated         *prometheus.CounterVec
 		rowsInserted         *prometheus.CounterVec
+		rowsMerged           *prometheus.CounterVec
 		rowBytesInserted     *prometheus.CounterVec
 		zeroRowsInserted     *prometheus.CounterVec
+		nonMonotonicRows     *prometheus.CounterVec
 		rowInsertSize        *prometheus.HistogramVec
 		lastCompletedBlockTx *prometheus.GaugeVec
 		numParts             *prometheus.GaugeVec
+		blocksCollected      *prometheus.CounterVec
+		bytesReclaimed       *prometheus.CounterVec
+		writerRowsDropped    *prometheus.CounterVec
 		indexMetrics         struct {
 			compactions        *prometheus.CounterVec
 			levelSize          *prometheus.GaugeVec
@@ -131,6 +201,24 @@ func makeAndRegisterGlobalMetrics(unwrappedReg prometheus.Registerer) globalMetr
 		}),
 	}
 
+	// Query metrics.
+	{
+		reg := prometheus.WrapRegistererWithPrefix("frostdb_query_", unwrappedReg)
+		m.queryMetrics.queueDuration = promauto.With(reg).NewHistogram(prometheus.HistogramOpts{
+			Name:    "queue_duration_seconds",
+			Help:    "Time queries waited for a query slot before executing.",
+			Buckets: prometheus.ExponentialBuckets(0.001, 4, 8),
+		})
+		m.queryMetrics.queued = promauto.With(reg).NewGauge(prometheus.GaugeOpts{
+			Name: "queued",
+			Help: "Number of queries waiting for a query slot.",
+		})
+		m.queryMetrics.running = promauto.With(reg).NewGauge(prometheus.GaugeOpts{
+			Name: "running",
+			Help: "Number of queries holding a query slot. Only tracked if the query concurrency is limited.",
+		})
+	}
+
 	// DB metrics.
 	{
 		// Snapshot metrics.
@@ -228,6 +316,18 @@ func makeAndRegisterGlobalMetrics(unwrappedReg prometheus.Registerer) globalMetr
 				Name: "queue_size",
 				Help: "The number of unprocessed requests in the WAL queue",
 			}, makeLabelsForDBMetric())
+			m.dbMetrics.fileWalMetrics.walQueueBytes = promauto.With(reg).NewGaugeVec(prometheus.GaugeOpts{
+				Name: "queue_bytes",
+				Help: "The number of bytes of unprocessed requests in the WAL queue",
+			}, makeLabelsForDBMetric())
+			m.dbMetrics.fileWalMetrics.walQueueFull = promauto.With(reg).NewCounterVec(prometheus.CounterOpts{
+				Name: "queue_full_total",
+				Help: "The number of log requests that were blocked or rejected because the WAL queue was full",
+			}, makeLabelsForDBMetric())
+			m.dbMetrics.fileWalMetrics.walCorruptions = promauto.With(reg).NewCounterVec(prometheus.CounterOpts{
+				Name: "corruptions_total",
+				Help: "The number of corrupt WAL records found, by type of corruption",
+			}, makeLabelsForDBMetric("type"))
 		}
 	}
 
@@ -246,6 +346,10 @@ func makeAndRegisterGlobalMetrics(unwrappedReg prometheus.Registerer) globalMetr
 			Name: "rows_inserted_total",
 			Help: "Number of rows inserted into table.",
 		}, makeLabelsForTablesMetrics())
+		m.tableMetrics.rowsMerged = promauto.With(reg).NewCounterVec(prometheus.CounterOpts{
+			Name: "rows_merged_total",
+			Help: "Number of rows dropped or merged into other rows with equal sorting columns by the table's merge policy.",
+		}, makeLabelsForTablesMetrics())
 		m.tableMetrics.rowBytesInserted = promauto.With(reg).NewCounterVec(prometheus.CounterOpts{
 			Name: "row_bytes_inserted_total",
 			Help: "Number of bytes inserted into table.",
@@ -254,6 +358,10 @@ func makeAndRegisterGlobalMetrics(unwrappedReg prometheus.Registerer) globalMetr
 			Name: "zero_rows_inserted_total",
 			Help: "Number of times it was attempted to insert zero rows into the table.",
 		}, makeLabelsForTablesMetrics())
+		m.tableMetrics.nonMonotonicRows = promauto.With(reg).NewCounterVec(prometheus.CounterOpts{
+			Name: "non_monotonic_rows_total",
+			Help: "Number of inserted rows whose timestamp is older than the last inserted row of their series.",
+		}, makeLabelsForTablesMetrics())
 		m.tableMetrics.rowInsertSize = promauto.With(reg).NewHistogramVec(prometheus.HistogramOpts{
 			Name:    "row_insert_size",
 			Help:    "Size of batch inserts into table.",
@@ -267,6 +375,18 @@ func makeAndRegisterGlobalMetrics(unwrappedReg prometheus.Registerer) globalMetr
 			Name: "num_parts",
 			Help: "Number of parts currently active.",
 		}, makeLabelsForTablesMetrics())
+		m.tableMetrics.blocksCollected = promauto.With(reg).NewCounterVec(prometheus.CounterOpts{
+			Name: "blocks_collected_total",
+			Help: "Number of superseded or orphaned persisted blocks deleted by the garbage collection.",
+		}, makeLabelsForTablesMetrics())
+		m.tableMetrics.bytesReclaimed = promauto.With(reg).NewCounterVec(prometheus.CounterOpts{
+			Name: "blocks_collected_bytes_total",
+			Help: "Total size of the persisted blocks deleted by the garbage collection.",
+		}, makeLabelsForTablesMetrics())
+		m.tableMetrics.writerRowsDropped = promauto.With(reg).NewCounterVec(prometheus.CounterOpts{
+			Name: "writer_rows_dropped_total",
+			Help: "Number of rows buffered by table writers that were dropped because flushing them failed.",
+		}, makeLabelsForTablesMetrics())
 
 		// LSM metrics.
 		{
@@ -314,11 +434,16 @@ type tableMetrics struct {
 	blockPersisted       prometheus.Counter
 	blockRotated         prometheus.Counter
 	rowsInserted         prometheus.Counter
+	rowsMerged           prometheus.Counter
 	rowBytesInserted     prometheus.Counter
 	zeroRowsInserted     prometheus.Counter
+	nonMonotonicRows     prometheus.Counter
 	rowInsertSize        prometheus.Observer
 	lastCompletedBlockTx prometheus.Gauge
 	numParts             prometheus.Gauge
+	blocksCollected      prometheus.Counter
+	bytesReclaimed       prometheus.Counter
+	writerRowsDropped    prometheus.Counter
 
 	indexMetrics index.LSMMetrics
 }
@@ -328,11 +453,16 @@ func (p tableMetricsProvider) metricsForTable(tableName string) tableMetrics {
 		blockPersisted:       p.m.tableMetrics.blockPersisted.WithLabelValues(p.dbName, tableName),
 		blockRotated:         p.m.tableMetrics.blockRotated.WithLabelValues(p.dbName, tableName),
 		rowsInserted:         p.m.tableMetrics.rowsInserted.WithLabelValues(p.dbName, tableName),
+		rowsMerged:           p.m.tableMetrics.rowsMerged.WithLabelValues(p.dbName, tableName),
 		rowBytesInserted:     p.m.tableMetrics.rowBytesInserted.WithLabelValues(p.dbName, tableName),
 		zeroRowsInserted:     p.m.tableMetrics.zeroRowsInserted.WithLabelValues(p.dbName, tableName),
+		nonMonotonicRows:     p.m.tableMetrics.nonMonotonicRows.WithLabelValues(p.dbName, tableName),
 		rowInsertSize:        p.m.tableMetrics.rowInsertSize.WithLabelValues(p.dbName, tableName),
 		lastCompletedBlockTx: p.m.tableMetrics.lastCompletedBlockTx.WithLabelValues(p.dbName, tableName),
 		numParts:             p.m.tableMetrics.numParts.WithLabelValues(p.dbName, tableName),
+		blocksCollected:      p.m.tableMetrics.blocksCollected.WithLabelValues(p.dbName, tableName),
+		bytesReclaimed:       p.m.tableMetrics.bytesReclaimed.WithLabelValues(p.dbName, tableName),
+		writerRowsDropped:    p.m.tableMetrics.writerRowsDropped.WithLabelValues(p.dbName, tableName),
 		indexMetrics: index.LSMMetrics{
 			Compactions:        p.m.tableMetrics.indexMetrics.compactions.MustCurryWith(prometheus.Labels{"db": p.dbName, "table": tableName}),
 			LevelSize:          p.m.tableMetrics.indexMetrics.levelSize.MustCurryWith(prometheus.Labels{"db": p.dbName, "table": tableName}),
@@ -341,6 +471,73 @@ func (p tableMetricsProvider) metricsForTable(tableName string) tableMetrics {
 	}
 }
 
+// deleteMetricsForTable removes the series of the table's metrics, e.g. once
+// the table was dropped.
+func (p tableMetricsProvider) deleteMetricsForTable(tableName string) {
+	p.m.deleteTableSeries(prometheus.Labels{"db": p.dbName, "table": tableName})
+}
+
+// partialMatchDeleter is implemented by all metric vectors.
+type partialMatchDeleter interface {
+	DeletePartialMatch(labels prometheus.Labels) int
+}
+
+// deleteMetricsForDB removes the series of the metrics of the database and
+// all of its tables, e.g. once the database was dropped.
+func (m globalMetrics) deleteMetricsForDB(dbName string) {
+	labels := prometheus.Labels{"db": dbName}
+	for _, vec := range []partialMatchDeleter{
+		m.dbMetrics.snapshotMetrics.snapshotsTotal,
+		m.dbMetrics.snapshotMetrics.snapshotFileSizeBytes,
+		m.dbMetrics.snapshotMetrics.snapshotDurationHistogram,
+		m.dbMetrics.walMetrics.bytesWritten,
+		m.dbMetrics.walMetrics.entriesWritten,
+		m.dbMetrics.walMetrics.appends,
+		m.dbMetrics.walMetrics.entryBytesRead,
+		m.dbMetrics.walMetrics.entriesRead,
+		m.dbMetrics.walMetrics.segmentRotations,
+		m.dbMetrics.walMetrics.entriesTruncated,
+		m.dbMetrics.walMetrics.truncations,
+		m.dbMetrics.walMetrics.lastSegmentAgeSeconds,
+		m.dbMetrics.fileWalMetrics.failedLogs,
+		m.dbMetrics.fileWalMetrics.lastTruncationAt,
+		m.dbMetrics.fileWalMetrics.walRepairs,
+		m.dbMetrics.fileWalMetrics.walRepairsLostRecords,
+		m.dbMetrics.fileWalMetrics.walCloseTimeouts,
+		m.dbMetrics.fileWalMetrics.walQueueSize,
+		m.dbMetrics.fileWalMetrics.walQueueBytes,
+		m.dbMetrics.fileWalMetrics.walQueueFull,
+		m.dbMetrics.fileWalMetrics.walCorruptions,
+	} {
+		vec.DeletePartialMatch(labels)
+	}
+	m.deleteTableSeries(labels)
+}
+
+// deleteTableSeries removes the series of all table metrics matching labels.
+func (m globalMetrics) deleteTableSeries(labels prometheus.Labels) {
+	for _, vec := range []partialMatchDeleter{
+		m.tableMetrics.blockPersisted,
+		m.tableMetrics.blockRotated,
+		m.tableMetrics.rowsInserted,
+		m.tableMetrics.rowsMerged,
+		m.tableMetrics.rowBytesInserted,
+		m.tableMetrics.zeroRowsInserted,
+		m.tableMetrics.nonMonotonicRows,
+		m.tableMetrics.rowInsertSize,
+		m.tableMetrics.lastCompletedBlockTx,
+		m.tableMetrics.numParts,
+		m.tableMetrics.blocksCollected,
+		m.tableMetrics.bytesReclaimed,
+		m.tableMetrics.writerRowsDropped,
+		m.tableMetrics.indexMetrics.compactions,
+		m.tableMetrics.indexMetrics.levelSize,
+		m.tableMetrics.indexMetrics.compactionDuration,
+	} {
+		vec.DeletePartialMatch(labels)
+	}
+}
+
 func (m globalMetrics) metricsForWAL(dbName string) *wal.Metrics {
 	return &wal.Metrics{
 		BytesWritten:          m.dbMetrics.walMetrics.bytesWritten.WithLabelValues(dbName),
@@ -363,5 +560,8 @@ func (m globalMetrics) metricsForFileWAL(dbName string) *filewal.Metrics {
 		WalRepairsLostRecords: m.dbMetrics.fileWalMetrics.walRepairsLostRecords.WithLabelValues(dbName),
 		WalCloseTimeouts:      m.dbMetrics.fileWalMetrics.walCloseTimeouts.WithLabelValues(dbName),
 		WalQueueSize:          m.dbMetrics.fileWalMetrics.walQueueSize.WithLabelValues(dbName),
+		WalQueueBytes:         m.dbMetrics.fileWalMetrics.walQueueBytes.WithLabelValues(dbName),
+		WalQueueFull:          m.dbMetrics.fileWalMetrics.walQueueFull.WithLabelValues(dbName),
+		WalCorruptions:        m.dbMetrics.fileWalMetrics.walCorruptions.MustCurryWith(prometheus.Labels{"db": dbName}),
 	}
 }
@@ -0,0 +1,188 @@
+package frostdb
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/go-kit/log/level"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+// MetricType is the type of a metric reported to a MetricsReporter.
+type MetricType int
+
+const (
+	MetricTypeCounter MetricType = iota
+	MetricTypeGauge
+	MetricTypeHistogram
+)
+
+func (t MetricType) String() string {
+	switch t {
+	case MetricTypeCounter:
+		return "counter"
+	case MetricTypeGauge:
+		return "gauge"
+	case MetricTypeHistogram:
+		return "histogram"
+	default:
+		return fmt.Sprintf("MetricType(%d)", int(t))
+	}
+}
+
+// Metric is a single sample of an internal metric (e.g. rows inserted into a
+// table, LSM compactions, or WAL queue size).
+type Metric struct {
+	Name   string
+	Type   MetricType
+	Labels map[string]string
+	// Value is the value of a counter or gauge. For histograms, Value is the
+	// sum of all observations.
+	Value float64
+	// Count is the number of observations of a histogram.
+	Count uint64
+}
+
+// MetricsReporter is called with the current value of all internal metrics.
+// It allows exporting metrics to systems such as statsd or OpenTelemetry
+// without the embedder having to use a prometheus.Registerer.
+type MetricsReporter func(metrics []Metric)
+
+// WithMetricsReporter reports all internal metrics to the given reporter every
+// interval. If interval is 0, metrics are only reported when
+// ColumnStore.ReportMetrics is called. Only the column store's own metrics are
+// reported, and they are still registered with the registry provided via
+// WithRegistry.
+func WithMetricsReporter(reporter MetricsReporter, interval time.Duration) Option {
+	return func(s *ColumnStore) error {
+		s.metricsReporter = reporter
+		s.metricsReportInterval = interval
+		return nil
+	}
+}
+
+// startMetricsReporter starts reporting metrics in the background if a
+// reporter and an interval are configured.
+func (s *ColumnStore) startMetricsReporter() {
+	if s.metricsReporter == nil || s.metricsReportInterval <= 0 {
+		return
+	}
+
+	s.stopMetricsReporter = make(chan struct{})
+	s.metricsReporterDone = make(chan struct{})
+	go func() {
+		defer close(s.metricsReporterDone)
+		ticker := s.clock.NewTicker(s.metricsReportInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-s.stopMetricsReporter:
+				return
+			case <-ticker.C():
+				if err := s.ReportMetrics(); err != nil {
+					level.Warn(s.logger).Log("msg", "failed to report metrics", "err", err)
+				}
+			}
+		}
+	}()
+}
+
+// stopReportingMetrics stops the background metrics reporter, if any, and
+// waits for it to exit.
+func (s *ColumnStore) stopReportingMetrics() {
+	if s.stopMetricsReporter == nil {
+		return
+	}
+	close(s.stopMetricsReporter)
+	<-s.metricsReporterDone
+	s.stopMetricsReporter = nil
+}
+
+// ReportMetrics gathers all internal metrics and synchronously calls the
+// MetricsReporter configured with WithMetricsReporter. It is a no-op if no
+// reporter is configured.
+func (s *ColumnStore) ReportMetrics() error {
+	if s.metricsReporter == nil {
+		return nil
+	}
+	families, err := s.metricsRegistry.Gather()
+	if err != nil {
+		return fmt.Errorf("gather metrics: %w", err)
+	}
+	s.metricsReporter(metricsFromFamilies(families))
+	return nil
+}
+
+// metricsFromFamilies converts gathered prometheus metric families into
+// Metrics. Summaries are reported like histograms, and untyped metrics like
+// gauges.
+func metricsFromFamilies(families []*dto.MetricFamily) []Metric {
+	var metrics []Metric
+	for _, family := range families {
+		for _, m := range family.GetMetric() {
+			labels := make(map[string]string, len(m.GetLabel()))
+			for _, l := range m.GetLabel() {
+				labels[l.GetName()] = l.GetValue()
+			}
+			metric := Metric{
+				Name:   family.GetName(),
+				Labels: labels,
+			}
+			switch family.GetType() {
+			case dto.MetricType_COUNTER:
+				metric.Type = MetricTypeCounter
+				metric.Value = m.GetCounter().GetValue()
+			case dto.MetricType_GAUGE:
+				metric.Type = MetricTypeGauge
+				metric.Value = m.GetGauge().GetValue()
+			case dto.MetricType_HISTOGRAM, dto.MetricType_GAUGE_HISTOGRAM:
+				metric.Type = MetricTypeHistogram
+				metric.Value = m.GetHistogram().GetSampleSum()
+				metric.Count = m.GetHistogram().GetSampleCount()
+			case dto.MetricType_SUMMARY:
+				metric.Type = MetricTypeHistogram
+				metric.Value = m.GetSummary().GetSampleSum()
+				metric.Count = m.GetSummary().GetSampleCount()
+			default:
+				metric.Type = MetricTypeGauge
+				metric.Value = m.GetUntyped().GetValue()
+			}
+			metrics = append(metrics, metric)
+		}
+	}
+	return metrics
+}
+
+// multiRegisterer registers collectors with all of its registerers.
+type multiRegisterer []prometheus.Registerer
+
+func (r multiRegisterer) Register(c prometheus.Collector) error {
+	for i, reg := range r {
+		if err := reg.Register(c); err != nil {
+			for _, reg := range r[:i] {
+				reg.Unregister(c)
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+func (r multiRegisterer) MustRegister(cs ...prometheus.Collector) {
+	for _, c := range cs {
+		if err := r.Register(c); err != nil {
+			panic(err)
+		}
+	}
+}
+
+func (r multiRegisterer) Unregister(c prometheus.Collector) bool {
+	unregistered := false
+	for _, reg := range r {
+		if reg.Unregister(c) {
+			unregistered = true
+		}
+	}
+	return unregistered
+}
@@ -0,0 +1,267 @@
+package frostdb
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/go-kit/log/level"
+	"github.com/oklog/ulid/v2"
+	"github.com/parquet-go/parquet-go"
+
+	"github.com/polarsignals/frostdb/dynparquet"
+)
+
+// BlockLayoutKey is the Parquet key-value metadata key holding the fingerprint
+// of the layout a block was written with, i.e. its schema, including the
+// sorting columns, and its row group size.
+const BlockLayoutKey = "frostdb.layout"
+
+// layoutFingerprint returns the fingerprint of the layout that blocks of the
+// table are currently written with.
+func (t *Table) layoutFingerprint() (string, error) {
+	fingerprint, err := SchemaFingerprint(t.schema.Load().Definition())
+	if err != nil {
+		return "", fmt.Errorf("fingerprint schema: %w", err)
+	}
+	h := sha256.New()
+	h.Write([]byte(fingerprint))
+	h.Write([]byte{0})
+	h.Write([]byte(strconv.FormatUint(t.config.Load().RowGroupSize, 10)))
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// blockMetadataOptions returns the writer options that record the schema and
+// the layout of the table in the metadata of a block written with the
+// current settings.
+func (t *Table) blockMetadataOptions() ([]parquet.WriterOption, error) {
+	options, err := schemaMetadataOptions(t.schema.Load())
+	if err != nil {
+		return nil, err
+	}
+	layout, err := t.layoutFingerprint()
+	if err != nil {
+		return nil, err
+	}
+	return append(options, parquet.KeyValueMetadata(BlockLayoutKey, layout)), nil
+}
+
+// MigrationProgress reports the progress of a Migration.
+type MigrationProgress struct {
+	// Examined is the number of persisted blocks examined so far.
+	Examined int
+	// Rewritten is the number of blocks rewritten with the current layout.
+	Rewritten int
+	// Done is true once the migration stopped, see Err for why.
+	Done bool
+	// Err is the error the migration failed with, if any. It is
+	// context.Canceled if the migration was canceled.
+	Err error
+}
+
+// MigrationOption configures a Migration.
+type MigrationOption func(*Migration)
+
+// WithMigrationRate throttles a migration to rewrite at most one block per
+// interval, limiting the IO it competes with queries and ingestion for. By
+// default blocks are rewritten back to back.
+func WithMigrationRate(interval time.Duration) MigrationOption {
+	return func(m *Migration) {
+		m.interval = interval
+	}
+}
+
+// Migration is a background job rewriting the persisted blocks of a table
+// that were written with different settings, see Table.Migrate.
+type Migration struct {
+	interval time.Duration
+	cancel   context.CancelFunc
+	done     chan struct{}
+
+	mtx      sync.Mutex
+	progress MigrationProgress
+}
+
+// Progress returns the progress of the migration.
+func (m *Migration) Progress() MigrationProgress {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+	return m.progress
+}
+
+// Wait waits for the migration to stop and returns the error it failed with,
+// if any.
+func (m *Migration) Wait() error {
+	<-m.done
+	return m.Progress().Err
+}
+
+// Cancel stops the migration after the block currently being rewritten and
+// waits for it to stop. Blocks rewritten so far keep the current layout, the
+// remaining blocks are rewritten by the next migration.
+func (m *Migration) Cancel() {
+	m.cancel()
+	<-m.done
+}
+
+func (m *Migration) update(fn func(p *MigrationProgress)) {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+	fn(&m.progress)
+}
+
+// Migrate starts rewriting the persisted blocks of the table, and of its sort
+// order tables, whose layout differs from the layout new blocks are written
+// with, e.g. because the row group size or the sorting columns of the table
+// changed since they were written. Rewritten blocks are re-sorted and split
+// into row groups according to the current settings. Blocks that don't record
+// their layout, since they were written by an older version, are rewritten as
+// well, except for v1alpha1 blocks which are left as is.
+//
+// The migration runs in the background with the lowest priority of all
+// background work and can be observed and stopped through the returned
+// Migration. It stops when ctx is canceled. All data sinks of the database
+// must implement BlockRewriter. Blocks are replaced in place, so it is safe
+// to restart an interrupted migration.
+func (t *Table) Migrate(ctx context.Context, options ...MigrationOption) (*Migration, error) {
+	if err := t.checkBlockRewriters(); err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	m := &Migration{
+		cancel: cancel,
+		done:   make(chan struct{}),
+	}
+	for _, option := range options {
+		option(m)
+	}
+
+	t.db.columnStore.scheduler.Go(WorkMigration, t.db.name+"/"+t.name, func() {
+		defer close(m.done)
+		defer cancel()
+
+		var throttle <-chan time.Time
+		if m.interval > 0 {
+			ticker := t.db.columnStore.clock.NewTicker(m.interval)
+			defer ticker.Stop()
+			throttle = ticker.C()
+		}
+
+		err := t.migrate(ctx, m, throttle)
+		if err != nil && !errors.Is(err, context.Canceled) {
+			level.Warn(t.logger).Log("msg", "failed to migrate blocks", "table", t.name, "err", err)
+		}
+		m.update(func(p *MigrationProgress) {
+			p.Done = true
+			p.Err = err
+		})
+	})
+	return m, nil
+}
+
+// checkBlockRewriters returns an error if a data sink of the database does not
+// implement BlockRewriter.
+func (t *Table) checkBlockRewriters() error {
+	for _, sink := range t.db.sinks {
+		if _, ok := sink.(BlockRewriter); !ok {
+			return fmt.Errorf("data sink %s does not support rewriting blocks", sink)
+		}
+	}
+	return nil
+}
+
+// migrate rewrites the blocks of the table and its sort order tables whose
+// layout is outdated. If throttle is not nil, a value is received from it
+// before each block is rewritten.
+func (t *Table) migrate(ctx context.Context, m *Migration, throttle <-chan time.Time) error {
+	layout, err := t.layoutFingerprint()
+	if err != nil {
+		return err
+	}
+
+	prefix := filepath.Join(t.db.name, t.name)
+	for _, sink := range t.db.sinks {
+		rewriter := sink.(BlockRewriter)
+		_, err := rewriter.RewriteBlocks(ctx, prefix, func(ctx context.Context, _ ulid.ULID, buf *dynparquet.SerializedBuffer, w io.Writer) (bool, error) {
+			if err := ctx.Err(); err != nil {
+				return false, err
+			}
+			m.update(func(p *MigrationProgress) { p.Examined++ })
+			if !t.needsMigration(buf, layout) {
+				return false, nil
+			}
+			if throttle != nil {
+				select {
+				case <-ctx.Done():
+					return false, ctx.Err()
+				case <-throttle:
+				}
+			}
+			if err := t.migrateBlock(buf, w); err != nil {
+				return false, err
+			}
+			m.update(func(p *MigrationProgress) { p.Rewritten++ })
+			return true, nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+	t.db.blockSchemas.invalidate(t.name)
+
+	for _, so := range t.sortOrderTables() {
+		if err := so.migrate(ctx, m, throttle); err != nil {
+			return fmt.Errorf("migrate sort order table %s: %w", so.name, err)
+		}
+	}
+	return nil
+}
+
+// needsMigration returns true if the given block was not written with the
+// given layout and can be rewritten.
+func (t *Table) needsMigration(buf *dynparquet.SerializedBuffer, layout string) bool {
+	if v, ok := buf.ParquetFile().Lookup(BlockLayoutKey); ok && v == layout {
+		return false
+	}
+	if t.hasV1Alpha1RowGroups(buf) {
+		return false
+	}
+	return buf.NumRows() > 0
+}
+
+// migrateBlock writes the rows of the given block to w with the current
+// layout of the table. Columns the current schema doesn't have are dropped
+// and columns the block doesn't have are null.
+func (t *Table) migrateBlock(buf *dynparquet.SerializedBuffer, w io.Writer) error {
+	sorted, err := t.schema.Load().NewBuffer(buf.DynamicColumns())
+	if err != nil {
+		return err
+	}
+	conv, err := parquet.Convert(sorted.Schema(), buf.ParquetFile().Schema())
+	if err != nil {
+		return fmt.Errorf("convert block schema: %w", err)
+	}
+
+	rows := buf.MultiDynamicRowGroup().Rows()
+	defer rows.Close()
+	if _, err := parquet.CopyRows(sorted, parquet.ConvertRowReader(rows, conv)); err != nil {
+		return err
+	}
+	// The block is sorted by the sorting columns it was written with, which
+	// may differ from the current ones.
+	sorted.Sort()
+
+	options, err := t.blockMetadataOptions()
+	if err != nil {
+		return err
+	}
+	return t.writeMergedRowGroups(w, sorted, options...)
+}
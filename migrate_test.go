@@ -0,0 +1,171 @@
+package frostdb
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/parquet-go/parquet-go"
+	"github.com/stretchr/testify/require"
+	"github.com/thanos-io/objstore"
+
+	"github.com/polarsignals/frostdb/dynparquet"
+	schemapb "github.com/polarsignals/frostdb/gen/proto/go/frostdb/schema/v1alpha1"
+)
+
+func TestTableMigrate(t *testing.T) {
+	ctx := context.Background()
+	bucket := objstore.NewInMemBucket()
+	c, err := New(WithLogger(newTestLogger(t)), WithReadWriteStorage(NewDefaultObjstoreBucket(bucket)))
+	require.NoError(t, err)
+	defer c.Close()
+	db, err := c.DB(ctx, "test")
+	require.NoError(t, err)
+	table, err := db.Table("test", NewTableConfig(dynparquet.SampleDefinition(), WithRowGroupSize(2)))
+	require.NoError(t, err)
+
+	insertSampleRecords(ctx, t, table, 5, 4, 3, 2, 1)
+	var wg sync.WaitGroup
+	wg.Add(1)
+	require.NoError(t, table.RotateBlock(ctx, table.ActiveBlock(), WithRotateBlockWaitGroup(&wg)))
+	wg.Wait()
+
+	// openBlock returns the only persisted block.
+	openBlock := func() *parquet.File {
+		t.Helper()
+		var files []*parquet.File
+		for name, data := range bucket.Objects() {
+			if !strings.HasSuffix(name, "data.parquet") {
+				continue
+			}
+			file, err := parquet.OpenFile(bytes.NewReader(data), int64(len(data)))
+			require.NoError(t, err)
+			files = append(files, file)
+		}
+		require.Len(t, files, 1)
+		return files[0]
+	}
+	migrate := func(options ...MigrationOption) MigrationProgress {
+		t.Helper()
+		m, err := table.Migrate(ctx, options...)
+		require.NoError(t, err)
+		require.NoError(t, m.Wait())
+		return m.Progress()
+	}
+	require.Len(t, openBlock().RowGroups(), 3)
+
+	// Blocks written with the current settings are not rewritten.
+	require.Equal(t, MigrationProgress{Examined: 1, Done: true}, migrate())
+
+	_, err = db.Table("test", NewTableConfig(dynparquet.SampleDefinition(), WithRowGroupSize(4)))
+	require.NoError(t, err)
+	require.Equal(t, MigrationProgress{Examined: 1, Rewritten: 1, Done: true}, migrate(WithMigrationRate(time.Millisecond)))
+
+	file := openBlock()
+	require.Len(t, file.RowGroups(), 2)
+	require.Equal(t, int64(5), file.NumRows())
+	layout, ok := file.Lookup(BlockLayoutKey)
+	require.True(t, ok)
+	expected, err := table.layoutFingerprint()
+	require.NoError(t, err)
+	require.Equal(t, expected, layout)
+	require.Equal(t, MigrationProgress{Examined: 1, Done: true}, migrate())
+
+	t.Run("Cancel", func(t *testing.T) {
+		_, err = db.Table("test", NewTableConfig(dynparquet.SampleDefinition()))
+		require.NoError(t, err)
+		m, err := table.Migrate(ctx, WithMigrationRate(time.Hour))
+		require.NoError(t, err)
+		m.Cancel()
+		require.ErrorIs(t, m.Wait(), context.Canceled)
+		progress := m.Progress()
+		require.True(t, progress.Done)
+		require.Zero(t, progress.Rewritten)
+		require.Len(t, openBlock().RowGroups(), 2)
+	})
+}
+
+func TestTableUpdateSortingColumns(t *testing.T) {
+	ctx := context.Background()
+	bucket := objstore.NewInMemBucket()
+	c, err := New(WithLogger(newTestLogger(t)), WithReadWriteStorage(NewDefaultObjstoreBucket(bucket)))
+	require.NoError(t, err)
+	defer c.Close()
+	db, err := c.DB(ctx, "test")
+	require.NoError(t, err)
+	table, err := db.Table("test", NewTableConfig(dynparquet.SampleDefinition()))
+	require.NoError(t, err)
+
+	insertSampleRecords(ctx, t, table, 3, 1, 2)
+	var wg sync.WaitGroup
+	wg.Add(1)
+	require.NoError(t, table.RotateBlock(ctx, table.ActiveBlock(), WithRotateBlockWaitGroup(&wg)))
+	wg.Wait()
+	// The active block is persisted after the sorting columns changed.
+	insertSampleRecords(ctx, t, table, 5, 6, 4)
+
+	// timestamps returns the timestamps of the persisted blocks, in the
+	// order they are stored in.
+	timestamps := func() [][]int64 {
+		t.Helper()
+		var res [][]int64
+		for name, data := range bucket.Objects() {
+			if !strings.HasSuffix(name, "data.parquet") {
+				continue
+			}
+			file, err := parquet.OpenFile(bytes.NewReader(data), int64(len(data)))
+			require.NoError(t, err)
+			col, ok := file.Schema().Lookup("timestamp")
+			require.True(t, ok)
+			var ts []int64
+			for _, rg := range file.RowGroups() {
+				rows := rg.Rows()
+				buf := make([]parquet.Row, rg.NumRows())
+				n, err := rows.ReadRows(buf)
+				if err != nil && !errors.Is(err, io.EOF) {
+					require.NoError(t, err)
+				}
+				require.NoError(t, rows.Close())
+				for _, row := range buf[:n] {
+					for _, v := range row {
+						if v.Column() == col.ColumnIndex {
+							ts = append(ts, v.Int64())
+						}
+					}
+				}
+			}
+			res = append(res, ts)
+		}
+		return res
+	}
+
+	_, err = table.UpdateSortingColumns(ctx, []*schemapb.SortingColumn{{
+		Name:      "missing",
+		Direction: schemapb.SortingColumn_DIRECTION_ASCENDING,
+	}})
+	require.Error(t, err)
+
+	m, err := table.UpdateSortingColumns(ctx, []*schemapb.SortingColumn{{
+		Name:      "timestamp",
+		Direction: schemapb.SortingColumn_DIRECTION_DESCENDING,
+	}})
+	require.NoError(t, err)
+	require.NoError(t, m.Wait())
+	require.Equal(t, "timestamp", table.Schema().SortingColumns()[0].ColumnName())
+	require.Eventually(t, func() bool {
+		return len(timestamps()) == 2
+	}, time.Second, 10*time.Millisecond)
+
+	require.ElementsMatch(t, [][]int64{{3, 2, 1}, {6, 5, 4}}, timestamps())
+	require.Equal(t, MigrationProgress{Examined: 2, Done: true}, func() MigrationProgress {
+		m, err := table.Migrate(ctx)
+		require.NoError(t, err)
+		require.NoError(t, m.Wait())
+		return m.Progress()
+	}())
+}
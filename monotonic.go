@@ -0,0 +1,313 @@
+package frostdb
+
+import (
+	"container/list"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/apache/arrow/go/v17/arrow"
+	"github.com/apache/arrow/go/v17/arrow/array"
+	"github.com/go-kit/log/level"
+	"github.com/parquet-go/parquet-go"
+
+	"github.com/polarsignals/frostdb/dynparquet"
+	tablepb "github.com/polarsignals/frostdb/gen/proto/go/frostdb/table/v1alpha1"
+)
+
+// MonotonicTimestampBehavior determines what happens to inserts containing
+// rows older than the last inserted row of their series, see
+// WithMonotonicTimestamps.
+type MonotonicTimestampBehavior int
+
+const (
+	// MonotonicTimestampsFlag inserts the rows and counts them in the
+	// frostdb_table_non_monotonic_rows_total metric.
+	MonotonicTimestampsFlag MonotonicTimestampBehavior = iota
+	// MonotonicTimestampsReject rejects the whole insert with an
+	// ErrNonMonotonicTimestamp error.
+	MonotonicTimestampsReject
+)
+
+// defaultMonotonicTimestampColumn is the column monotonic timestamps are
+// enforced on unless configured otherwise with WithMonotonicTimestampsColumn.
+const defaultMonotonicTimestampColumn = "timestamp"
+
+// monotonicTimestampColumn returns the column monotonic timestamps are
+// enforced on according to the given config.
+func monotonicTimestampColumn(config *tablepb.TableConfig) string {
+	if column := config.GetMonotonicTimestampsColumn(); column != "" {
+		return column
+	}
+	return defaultMonotonicTimestampColumn
+}
+
+// ErrNonMonotonicTimestamp is returned by inserts into tables rejecting
+// non-monotonic timestamps if a row is older than the last inserted row of
+// its series.
+type ErrNonMonotonicTimestamp struct {
+	// Series identifies the series of the row by the values of its sorting
+	// columns.
+	Series    string
+	Timestamp int64
+	// Last is the timestamp of the last inserted row of the series.
+	Last int64
+}
+
+func (e ErrNonMonotonicTimestamp) Error() string {
+	return fmt.Sprintf("timestamp %d of series {%s} is older than its last timestamp %d", e.Timestamp, e.Series, e.Last)
+}
+
+// validateMonotonicTimestamps checks that monotonic timestamps can be
+// enforced for the given schema and config.
+func validateMonotonicTimestamps(schema *dynparquet.Schema, config *tablepb.TableConfig) error {
+	column := monotonicTimestampColumn(config)
+	def, ok := schema.ColumnByName(column)
+	if !ok {
+		return fmt.Errorf("monotonic timestamps require a %q column", column)
+	}
+	if def.Dynamic || def.StorageLayout.Type().Kind() != parquet.Int64 {
+		return fmt.Errorf("monotonic timestamps require %q to be a non-dynamic int64 column", column)
+	}
+	return nil
+}
+
+// seriesTracker is a bounded LRU cache of the last timestamp of series.
+type seriesTracker struct {
+	mtx     sync.Mutex
+	size    int
+	lru     *list.List
+	entries map[string]*list.Element
+	// version is incremented whenever the last timestamp of a series is
+	// set, so that a reservation can tell whether it was overridden.
+	version uint64
+}
+
+type seriesEntry struct {
+	series  string
+	last    int64
+	version uint64
+}
+
+func newSeriesTracker(size int) *seriesTracker {
+	return &seriesTracker{
+		size:    size,
+		lru:     list.New(),
+		entries: make(map[string]*list.Element),
+	}
+}
+
+// lastLocked returns the last timestamp of the given series. s.mtx must be held.
+func (s *seriesTracker) lastLocked(series string) (int64, bool) {
+	e, ok := s.entries[series]
+	if !ok {
+		return 0, false
+	}
+	return e.Value.(*seriesEntry).last, true
+}
+
+// versionLocked returns the version the last timestamp of the given series
+// was set with. s.mtx must be held.
+func (s *seriesTracker) versionLocked(series string) (uint64, bool) {
+	e, ok := s.entries[series]
+	if !ok {
+		return 0, false
+	}
+	return e.Value.(*seriesEntry).version, true
+}
+
+// updateLocked sets the last timestamp of the given series and returns the
+// version it was set with. s.mtx must be held.
+func (s *seriesTracker) updateLocked(series string, ts int64) uint64 {
+	s.version++
+	if e, ok := s.entries[series]; ok {
+		entry := e.Value.(*seriesEntry)
+		entry.last = ts
+		entry.version = s.version
+		s.lru.MoveToFront(e)
+		return s.version
+	}
+	s.entries[series] = s.lru.PushFront(&seriesEntry{series: series, last: ts, version: s.version})
+	if s.lru.Len() > s.size {
+		oldest := s.lru.Back()
+		s.lru.Remove(oldest)
+		delete(s.entries, oldest.Value.(*seriesEntry).series)
+	}
+	return s.version
+}
+
+// removeLocked forgets the last timestamp of the given series. s.mtx must be
+// held.
+func (s *seriesTracker) removeLocked(series string) {
+	if e, ok := s.entries[series]; ok {
+		s.lru.Remove(e)
+		delete(s.entries, series)
+	}
+}
+
+// monotonicTimestampTracker returns the series tracker of the table, or nil if
+// the table does not enforce monotonic timestamps.
+func (t *Table) monotonicTimestampTracker() *seriesTracker {
+	size := int(t.config.Load().MonotonicTimestampsCacheSize)
+	t.seriesTrackerMtx.Lock()
+	defer t.seriesTrackerMtx.Unlock()
+	if size == 0 {
+		t.seriesTracker = nil
+		return nil
+	}
+	if t.seriesTracker == nil || t.seriesTracker.size != size {
+		t.seriesTracker = newSeriesTracker(size)
+	}
+	return t.seriesTracker
+}
+
+// checkMonotonicTimestamps checks that the rows of the given record are not
+// older than the last inserted rows of their series if the table enforces
+// monotonic timestamps. Rows of the same series within the record are checked
+// against each other as well. If the table rejects non-monotonic timestamps
+// and a row is older, an ErrNonMonotonicTimestamp is returned. Otherwise the
+// new last timestamps of the series are reserved right away, so that
+// concurrent inserts of the same series are checked against them. The
+// returned function must be called with whether the record was inserted, and
+// rolls the reservations back if it wasn't. Inserts that were rejected
+// because of a reservation that is rolled back are not retried.
+func (t *Table) checkMonotonicTimestamps(record arrow.Record) (func(inserted bool), error) {
+	tracker := t.monotonicTimestampTracker()
+	if tracker == nil || record.NumRows() == 0 {
+		return func(bool) {}, nil
+	}
+	config := t.config.Load()
+	reject := config.RejectNonMonotonicTimestamps
+	column := monotonicTimestampColumn(config)
+
+	indices := record.Schema().FieldIndices(column)
+	if len(indices) != 1 {
+		return func(bool) {}, nil
+	}
+	timestamps, ok := record.Column(indices[0]).(*array.Int64)
+	if !ok {
+		return func(bool) {}, nil
+	}
+
+	sortingColumns := make(map[string]struct{})
+	for _, def := range t.schema.Load().ColumnDefinitionsForSortingColumns() {
+		sortingColumns[def.Name] = struct{}{}
+	}
+	type keyColumn struct {
+		name string
+		arr  arrow.Array
+	}
+	var keyColumns []keyColumn
+	for i, f := range record.Schema().Fields() {
+		if f.Name == column {
+			continue
+		}
+		// Concrete columns, e.g. labels.label1, belong to the series of
+		// their dynamic column.
+		def, ok := t.schema.Load().FindColumn(f.Name)
+		if !ok {
+			def, ok = t.schema.Load().FindDynamicColumnForConcreteColumn(f.Name)
+		}
+		if !ok {
+			continue
+		}
+		if _, ok := sortingColumns[def.Name]; !ok {
+			continue
+		}
+		keyColumns = append(keyColumns, keyColumn{name: f.Name, arr: record.Column(i)})
+	}
+
+	var sb strings.Builder
+	seriesOf := func(row int) string {
+		sb.Reset()
+		for _, col := range keyColumns {
+			if col.arr.IsNull(row) {
+				// Null and absent dynamic columns identify the same series.
+				continue
+			}
+			if sb.Len() > 0 {
+				sb.WriteString(", ")
+			}
+			sb.WriteString(col.name)
+			sb.WriteByte('=')
+			sb.WriteString(strconv.Quote(col.arr.ValueStr(row)))
+		}
+		return sb.String()
+	}
+
+	tracker.mtx.Lock()
+	defer tracker.mtx.Unlock()
+
+	// updates holds the last timestamp of the series in the record.
+	updates := make(map[string]int64)
+	nonMonotonic := 0
+	var firstErr error
+	for i := 0; i < timestamps.Len(); i++ {
+		if timestamps.IsNull(i) {
+			continue
+		}
+		ts := timestamps.Value(i)
+		series := seriesOf(i)
+		last, ok := updates[series]
+		if !ok {
+			last, ok = tracker.lastLocked(series)
+		}
+		if ok && ts < last {
+			nonMonotonic++
+			if firstErr == nil {
+				firstErr = ErrNonMonotonicTimestamp{Series: series, Timestamp: ts, Last: last}
+			}
+			if reject {
+				return nil, firstErr
+			}
+			continue
+		}
+		updates[series] = ts
+	}
+
+	type reservation struct {
+		version uint64
+		// prev is the last timestamp of the series before the
+		// reservation, if existed.
+		prev    int64
+		existed bool
+	}
+	reservations := make(map[string]reservation, len(updates))
+	for series, ts := range updates {
+		prev, existed := tracker.lastLocked(series)
+		reservations[series] = reservation{
+			version: tracker.updateLocked(series, ts),
+			prev:    prev,
+			existed: existed,
+		}
+	}
+
+	return func(inserted bool) {
+		if !inserted {
+			tracker.mtx.Lock()
+			defer tracker.mtx.Unlock()
+			for series, r := range reservations {
+				// Reservations of later inserts of the series are kept.
+				if version, ok := tracker.versionLocked(series); !ok || version != r.version {
+					continue
+				}
+				if r.existed {
+					tracker.updateLocked(series, r.prev)
+				} else {
+					tracker.removeLocked(series)
+				}
+			}
+			return
+		}
+		if nonMonotonic > 0 {
+			t.metrics.nonMonotonicRows.Add(float64(nonMonotonic))
+			level.Debug(t.logger).Log(
+				"msg", "inserted rows with non-monotonic timestamps",
+				"table", t.name,
+				"rows", nonMonotonic,
+				"err", firstErr,
+			)
+		}
+	}, nil
+}
@@ -0,0 +1,241 @@
+package frostdb
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/apache/arrow/go/v17/arrow"
+	"github.com/apache/arrow/go/v17/arrow/array"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+
+	"github.com/polarsignals/frostdb/dynparquet"
+)
+
+func TestMonotonicTimestamps(t *testing.T) {
+	ctx := context.Background()
+
+	insert := func(table *Table, label string, timestamps ...int64) error {
+		samples := make(dynparquet.Samples, 0, len(timestamps))
+		for _, ts := range timestamps {
+			samples = append(samples, dynparquet.Sample{
+				ExampleType: "ex",
+				Labels:      map[string]string{"label1": label},
+				Timestamp:   ts,
+			})
+		}
+		r, err := samples.ToRecord()
+		require.NoError(t, err)
+		defer r.Release()
+		_, err = table.InsertRecord(ctx, r)
+		return err
+	}
+
+	newTable := func(t *testing.T, options ...TableOption) *Table {
+		c, err := New(WithLogger(newTestLogger(t)))
+		require.NoError(t, err)
+		t.Cleanup(func() { c.Close() })
+		db, err := c.DB(ctx, "test")
+		require.NoError(t, err)
+		table, err := db.Table("test", NewTableConfig(dynparquet.SampleDefinition(), options...))
+		require.NoError(t, err)
+		return table
+	}
+
+	t.Run("Reject", func(t *testing.T) {
+		table := newTable(t, WithMonotonicTimestamps(10, MonotonicTimestampsReject))
+		require.NoError(t, insert(table, "a", 1, 2))
+		require.NoError(t, insert(table, "b", 1))
+		// Equal timestamps are allowed.
+		require.NoError(t, insert(table, "a", 2, 3))
+
+		var nonMonotonic ErrNonMonotonicTimestamp
+		require.ErrorAs(t, insert(table, "a", 4, 2), &nonMonotonic)
+		require.Equal(t, int64(2), nonMonotonic.Timestamp)
+		require.Equal(t, int64(4), nonMonotonic.Last)
+		require.Contains(t, nonMonotonic.Series, `labels.label1="a"`)
+
+		// The rejected insert did not update the last timestamp.
+		require.NoError(t, insert(table, "a", 3))
+		require.ErrorAs(t, insert(table, "b", 0), &nonMonotonic)
+	})
+
+	t.Run("RetryAfterQuotaExceeded", func(t *testing.T) {
+		table := newTable(t, WithMonotonicTimestamps(10, MonotonicTimestampsReject), WithStorageQuota(1))
+		// The quota is checked before inserting, so the first insert
+		// succeeds.
+		require.NoError(t, insert(table, "a", 1))
+		require.ErrorAs(t, insert(table, "a", 2, 3), &ErrQuotaExceeded{})
+
+		// The failed insert did not update the last timestamp, so it can be
+		// retried once the quota allows it.
+		_, err := table.db.Table("test", NewTableConfig(
+			dynparquet.SampleDefinition(),
+			WithMonotonicTimestamps(10, MonotonicTimestampsReject),
+		))
+		require.NoError(t, err)
+		require.NoError(t, insert(table, "a", 2, 3))
+	})
+
+	t.Run("Reservation", func(t *testing.T) {
+		table := newTable(t, WithMonotonicTimestamps(10, MonotonicTimestampsReject))
+		record := func(ts int64) arrow.Record {
+			r, err := dynparquet.Samples{{
+				ExampleType: "ex",
+				Labels:      map[string]string{"label1": "a"},
+				Timestamp:   ts,
+			}}.ToRecord()
+			require.NoError(t, err)
+			t.Cleanup(r.Release)
+			return r
+		}
+
+		// A checked record reserves its timestamps before it is inserted,
+		// so a concurrent insert is checked against them.
+		commit, err := table.checkMonotonicTimestamps(record(5))
+		require.NoError(t, err)
+		var nonMonotonic ErrNonMonotonicTimestamp
+		_, err = table.checkMonotonicTimestamps(record(3))
+		require.ErrorAs(t, err, &nonMonotonic)
+		require.Equal(t, int64(5), nonMonotonic.Last)
+
+		// The reservation is rolled back if the record was not inserted.
+		commit(false)
+		commit, err = table.checkMonotonicTimestamps(record(3))
+		require.NoError(t, err)
+		commit(true)
+		require.ErrorAs(t, insert(table, "a", 2), &nonMonotonic)
+	})
+
+	t.Run("Concurrent", func(t *testing.T) {
+		table := newTable(t, WithMonotonicTimestamps(10, MonotonicTimestampsReject))
+		var (
+			next     atomic.Int64
+			mtx      sync.Mutex
+			accepted int64
+		)
+		var wg sync.WaitGroup
+		for i := 0; i < 8; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for j := 0; j < 20; j++ {
+					ts := next.Add(1)
+					err := insert(table, "a", ts)
+					if err != nil {
+						if !errors.As(err, &ErrNonMonotonicTimestamp{}) {
+							t.Error(err)
+						}
+						continue
+					}
+					mtx.Lock()
+					accepted = max(accepted, ts)
+					mtx.Unlock()
+				}
+			}()
+		}
+		wg.Wait()
+
+		// Every accepted row was checked against the rows accepted before
+		// it, so the last timestamp of the series is the greatest one.
+		var nonMonotonic ErrNonMonotonicTimestamp
+		require.ErrorAs(t, insert(table, "a", accepted-1), &nonMonotonic)
+		require.Equal(t, accepted, nonMonotonic.Last)
+	})
+
+	t.Run("Flag", func(t *testing.T) {
+		table := newTable(t, WithMonotonicTimestamps(10, MonotonicTimestampsFlag))
+		require.NoError(t, insert(table, "a", 2))
+		require.NoError(t, insert(table, "a", 1, 3))
+		require.Equal(t, float64(1), testutil.ToFloat64(table.metrics.nonMonotonicRows))
+	})
+
+	t.Run("Eviction", func(t *testing.T) {
+		table := newTable(t, WithMonotonicTimestamps(1, MonotonicTimestampsReject))
+		require.NoError(t, insert(table, "a", 2))
+		require.NoError(t, insert(table, "b", 2))
+		// Series a was evicted from the cache.
+		require.NoError(t, insert(table, "a", 1))
+	})
+
+	t.Run("InvalidSchema", func(t *testing.T) {
+		c, err := New(WithLogger(newTestLogger(t)))
+		require.NoError(t, err)
+		defer c.Close()
+		db, err := c.DB(ctx, "test")
+		require.NoError(t, err)
+		def := dynparquet.SampleDefinition()
+		for _, col := range def.Columns {
+			if col.Name == "timestamp" {
+				col.Name = "ts"
+			}
+		}
+		for _, col := range def.SortingColumns {
+			if col.Name == "timestamp" {
+				col.Name = "ts"
+			}
+		}
+		_, err = db.Table("test", NewTableConfig(def, WithMonotonicTimestamps(10, MonotonicTimestampsReject)))
+		require.Error(t, err)
+		_, err = db.Table("test", NewTableConfig(def,
+			WithMonotonicTimestamps(10, MonotonicTimestampsReject),
+			WithMonotonicTimestampsColumn("missing"),
+		))
+		require.ErrorContains(t, err, `"missing"`)
+	})
+
+	t.Run("Column", func(t *testing.T) {
+		c, err := New(WithLogger(newTestLogger(t)))
+		require.NoError(t, err)
+		defer c.Close()
+		db, err := c.DB(ctx, "test")
+		require.NoError(t, err)
+		def := dynparquet.SampleDefinition()
+		for _, col := range def.Columns {
+			if col.Name == "timestamp" {
+				col.Name = "ts"
+			}
+		}
+		for _, col := range def.SortingColumns {
+			if col.Name == "timestamp" {
+				col.Name = "ts"
+			}
+		}
+		table, err := db.Table("test", NewTableConfig(def,
+			WithMonotonicTimestamps(10, MonotonicTimestampsReject),
+			WithMonotonicTimestampsColumn("ts"),
+		))
+		require.NoError(t, err)
+
+		insertTS := func(timestamps ...int64) error {
+			samples := make(dynparquet.Samples, 0, len(timestamps))
+			for _, ts := range timestamps {
+				samples = append(samples, dynparquet.Sample{
+					ExampleType: "ex",
+					Labels:      map[string]string{"label1": "a"},
+					Timestamp:   ts,
+				})
+			}
+			r, err := samples.ToRecord()
+			require.NoError(t, err)
+			defer r.Release()
+			fields := r.Schema().Fields()
+			for i := range fields {
+				if fields[i].Name == "timestamp" {
+					fields[i].Name = "ts"
+				}
+			}
+			renamed := array.NewRecord(arrow.NewSchema(fields, nil), r.Columns(), r.NumRows())
+			defer renamed.Release()
+			_, err = table.InsertRecord(ctx, renamed)
+			return err
+		}
+		require.NoError(t, insertTS(2))
+		var nonMonotonic ErrNonMonotonicTimestamp
+		require.ErrorAs(t, insertTS(1), &nonMonotonic)
+		require.NotContains(t, nonMonotonic.Series, "ts=")
+	})
+}
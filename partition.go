@@ -0,0 +1,387 @@
+package frostdb
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/apache/arrow/go/v17/arrow/scalar"
+	"github.com/oklog/ulid/v2"
+	"github.com/parquet-go/parquet-go"
+
+	"github.com/polarsignals/frostdb/dynparquet"
+	tablepb "github.com/polarsignals/frostdb/gen/proto/go/frostdb/table/v1alpha1"
+	"github.com/polarsignals/frostdb/query/logicalplan"
+)
+
+const (
+	// defaultPartitionColumn is the column blocks are partitioned by unless
+	// configured otherwise with WithPartitionColumn.
+	defaultPartitionColumn = "timestamp"
+	// defaultPartitionUnit is the unit of the values of the partition column
+	// unless configured otherwise with WithPartitionColumn.
+	defaultPartitionUnit = time.Millisecond
+)
+
+// WithTimePartitioning partitions the persisted blocks of the table into time
+// partitions of the given duration, e.g. time.Hour, by the values of the
+// partition column, see WithPartitionColumn. The active block is rotated when
+// the clock crosses a partition boundary, and a block containing rows of
+// several partitions, e.g. late data, is persisted as one block per
+// partition. The blocks of a partition are stored under a directory of the
+// table's prefix named after the column and the bounds of the partition,
+// e.g. "timestamp=1700000000000_1700003600000", so that scans skip whole
+// partitions that cannot match the filter of a query without listing them.
+func WithTimePartitioning(duration time.Duration) TableOption {
+	return func(config *tablepb.TableConfig) error {
+		if duration <= 0 {
+			return fmt.Errorf("partition duration must be positive: %s", duration)
+		}
+		if duration%time.Millisecond != 0 {
+			return fmt.Errorf("partition duration must be a multiple of a millisecond: %s", duration)
+		}
+		config.PartitionDurationMs = uint64(duration.Milliseconds())
+		return nil
+	}
+}
+
+// WithPartitionColumn partitions blocks configured with WithTimePartitioning
+// by the given column, whose values are interpreted as multiples of unit since
+// the Unix epoch. The column must be a non-dynamic, non-nullable int64 column
+// of the table's schema.
+func WithPartitionColumn(column string, unit time.Duration) TableOption {
+	return func(config *tablepb.TableConfig) error {
+		if column == "" {
+			return errors.New("partition column must not be empty")
+		}
+		if strings.ContainsAny(column, "/"+partitionSeparator) {
+			return fmt.Errorf("partition column must not contain %q or %q: %s", "/", partitionSeparator, column)
+		}
+		if unit <= 0 {
+			return fmt.Errorf("partition column unit must be positive: %s", unit)
+		}
+		config.PartitionColumn = column
+		config.PartitionColumnUnitNs = uint64(unit)
+		return nil
+	}
+}
+
+// partitioning is the time partitioning of the blocks of a table.
+type partitioning struct {
+	column string
+	unit   time.Duration
+	// duration is the duration of a partition in multiples of unit.
+	duration int64
+}
+
+// tablePartitioning returns the partitioning configured by config. The second
+// return value is false if partitioning is disabled.
+func tablePartitioning(config *tablepb.TableConfig) (partitioning, bool) {
+	if config.GetPartitionDurationMs() == 0 {
+		return partitioning{}, false
+	}
+	p := partitioning{
+		column: config.GetPartitionColumn(),
+		unit:   time.Duration(config.GetPartitionColumnUnitNs()),
+	}
+	if p.column == "" {
+		p.column = defaultPartitionColumn
+	}
+	if p.unit <= 0 {
+		p.unit = defaultPartitionUnit
+	}
+	p.duration = int64(time.Duration(config.GetPartitionDurationMs()) * time.Millisecond / p.unit)
+	if p.duration <= 0 {
+		p.duration = 1
+	}
+	return p, true
+}
+
+// validatePartitioning checks that blocks can be partitioned for the given
+// schema and config.
+func validatePartitioning(schema *dynparquet.Schema, config *tablepb.TableConfig) error {
+	p, _ := tablePartitioning(config)
+	def, ok := schema.ColumnByName(p.column)
+	if !ok {
+		return fmt.Errorf("partitioning requires a %q column", p.column)
+	}
+	if def.Dynamic || def.StorageLayout.Optional() || def.StorageLayout.Type().Kind() != parquet.Int64 {
+		return fmt.Errorf("partitioning requires %q to be a non-dynamic, non-nullable int64 column", p.column)
+	}
+	return nil
+}
+
+// partitionOf returns the partition containing the given value of the
+// partition column.
+func (p partitioning) partitionOf(value int64) timePartition {
+	start := value - value%p.duration
+	if value%p.duration < 0 {
+		start -= p.duration
+	}
+	end := start + p.duration
+	if end < start {
+		end = math.MaxInt64
+	}
+	return timePartition{column: p.column, start: start, end: end}
+}
+
+// partitionAt returns the partition containing the given time.
+func (p partitioning) partitionAt(t time.Time) timePartition {
+	return p.partitionOf(t.UnixNano() / p.unit.Nanoseconds())
+}
+
+// partitionSeparator separates the column from the bounds of a partition in
+// the name of its directory.
+const partitionSeparator = "="
+
+// timePartition is a time partition of the persisted blocks of a table. The
+// blocks of a partition only contain rows whose value of the partition
+// column is in [start, end).
+type timePartition struct {
+	column     string
+	start, end int64
+}
+
+// dir returns the name of the directory the blocks of the partition are
+// stored in, relative to the prefix of the table.
+func (p timePartition) dir() string {
+	return p.column + partitionSeparator + strconv.FormatInt(p.start, 10) + "_" + strconv.FormatInt(p.end, 10)
+}
+
+// parsePartitionDir parses the name of the directory of a partition.
+func parsePartitionDir(name string) (timePartition, bool) {
+	column, bounds, ok := strings.Cut(name, partitionSeparator)
+	if !ok || column == "" {
+		return timePartition{}, false
+	}
+	start, end, ok := strings.Cut(bounds, "_")
+	if !ok {
+		return timePartition{}, false
+	}
+	p := timePartition{column: column}
+	var err error
+	if p.start, err = strconv.ParseInt(start, 10, 64); err != nil {
+		return timePartition{}, false
+	}
+	if p.end, err = strconv.ParseInt(end, 10, 64); err != nil {
+		return timePartition{}, false
+	}
+	return p, true
+}
+
+// mayMatch returns false if no row of the partition can match filter, based
+// on the comparisons of the partition column with literals that filter
+// requires to hold.
+func (p timePartition) mayMatch(filter logicalplan.Expr) bool {
+	lower, upper := columnBounds(filter, p.column)
+	return lower < p.end && upper >= p.start
+}
+
+// columnBounds returns the inclusive bounds of the values of the given int64
+// column that filter can match.
+func columnBounds(filter logicalplan.Expr, column string) (int64, int64) {
+	lower, upper := int64(math.MinInt64), int64(math.MaxInt64)
+	e, ok := filter.(*logicalplan.BinaryExpr)
+	if !ok {
+		return lower, upper
+	}
+	switch e.Op {
+	case logicalplan.OpAnd:
+		l1, u1 := columnBounds(e.Left, column)
+		l2, u2 := columnBounds(e.Right, column)
+		return max(l1, l2), min(u1, u2)
+	case logicalplan.OpOr:
+		l1, u1 := columnBounds(e.Left, column)
+		l2, u2 := columnBounds(e.Right, column)
+		return min(l1, l2), max(u1, u2)
+	}
+
+	op := e.Op
+	col, ok := e.Left.(*logicalplan.Column)
+	lit, litOk := e.Right.(*logicalplan.LiteralExpr)
+	if !ok || !litOk {
+		// The comparison may be written the other way around, e.g. 5 < x.
+		col, ok = e.Right.(*logicalplan.Column)
+		lit, litOk = e.Left.(*logicalplan.LiteralExpr)
+		if !ok || !litOk {
+			return lower, upper
+		}
+		op = flipComparison(op)
+	}
+	if col.ColumnName != column {
+		return lower, upper
+	}
+	var v int64
+	switch s := lit.Value.(type) {
+	case *scalar.Int64:
+		v = s.Value
+	case *scalar.Timestamp:
+		v = int64(s.Value)
+	default:
+		return lower, upper
+	}
+	switch op {
+	case logicalplan.OpEq:
+		return v, v
+	case logicalplan.OpGt:
+		if v == math.MaxInt64 {
+			return 0, -1
+		}
+		return v + 1, upper
+	case logicalplan.OpGtEq:
+		return v, upper
+	case logicalplan.OpLt:
+		if v == math.MinInt64 {
+			return 0, -1
+		}
+		return lower, v - 1
+	case logicalplan.OpLtEq:
+		return lower, v
+	}
+	return lower, upper
+}
+
+// flipComparison returns the comparison that holds for swapped operands.
+func flipComparison(op logicalplan.Op) logicalplan.Op {
+	switch op {
+	case logicalplan.OpGt:
+		return logicalplan.OpLt
+	case logicalplan.OpGtEq:
+		return logicalplan.OpLtEq
+	case logicalplan.OpLt:
+		return logicalplan.OpGt
+	case logicalplan.OpLtEq:
+		return logicalplan.OpGtEq
+	}
+	return op
+}
+
+// crossedPartitionBoundary returns whether the clock crossed a partition
+// boundary since the given block was created.
+func (t *Table) crossedPartitionBoundary(block *TableBlock) bool {
+	p, ok := tablePartitioning(t.config.Load())
+	if !ok {
+		return false
+	}
+	created := p.partitionAt(ulid.Time(block.ulid.Time()))
+	return p.partitionAt(t.db.columnStore.clock.Now()) != created
+}
+
+// persistPartitioned serializes the block and uploads the rows of each of the
+// partitions it contains as a separate block to the partition's directory.
+// The blocks share the timestamp of the block's ULID, so that they are
+// ordered like the block relative to other blocks.
+func (t *TableBlock) persistPartitioned(ctx context.Context, sink DataSink, p partitioning) error {
+	var b bytes.Buffer
+	if err := t.Serialize(&b); err != nil {
+		return fmt.Errorf("failed to serialize block: %w", err)
+	}
+	if b.Len() == 0 {
+		return nil
+	}
+	buf, err := dynparquet.ReaderFromBytes(b.Bytes())
+	if err != nil {
+		return err
+	}
+	partitions, err := t.table.partitionRows(buf, p)
+	if err != nil {
+		return fmt.Errorf("failed to partition block: %w", err)
+	}
+
+	options, err := t.table.blockMetadataOptions()
+	if err != nil {
+		return err
+	}
+	for i, partition := range sortedPartitions(partitions) {
+		id := t.ulid
+		if i > 0 {
+			id = ulid.MustNew(t.ulid.Time(), ulid.DefaultEntropy())
+		}
+		fileName := filepath.Join(t.table.db.name, t.table.name, partition.dir(), id.String(), "data.parquet")
+		size, err := t.table.uploadBlock(ctx, sink, fileName, func(w io.Writer) error {
+			if err := t.table.writeMergedRowGroups(w, partitions[partition], options...); err != nil {
+				return fmt.Errorf("partition %s: %w", partition.dir(), err)
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		t.table.persistedBytes.Add(size)
+		t.table.db.blockSchemas.invalidateBlock(t.table.name, id)
+	}
+	return nil
+}
+
+// partitionRows splits the rows of buf by their partition. The rows of each
+// partition keep the order they have in buf.
+func (t *Table) partitionRows(buf *dynparquet.SerializedBuffer, p partitioning) (map[timePartition]*dynparquet.Buffer, error) {
+	schema := t.schema.Load()
+	target, err := schema.NewBuffer(buf.DynamicColumns())
+	if err != nil {
+		return nil, err
+	}
+	leaf, ok := target.Schema().Lookup(p.column)
+	if !ok {
+		return nil, fmt.Errorf("partition column %q not found", p.column)
+	}
+	conv, err := parquet.Convert(target.Schema(), buf.ParquetFile().Schema())
+	if err != nil {
+		return nil, fmt.Errorf("convert block schema: %w", err)
+	}
+
+	partitions := map[timePartition]*dynparquet.Buffer{}
+	blockRows := buf.MultiDynamicRowGroup().Rows()
+	defer blockRows.Close()
+	rows := parquet.ConvertRowReader(blockRows, conv)
+	batch := make([]parquet.Row, 64)
+	for {
+		n, readErr := rows.ReadRows(batch)
+		for _, row := range batch[:n] {
+			var value int64
+			for _, v := range row {
+				if v.Column() == leaf.ColumnIndex {
+					value = v.Int64()
+					break
+				}
+			}
+			partition := p.partitionOf(value)
+			pb, ok := partitions[partition]
+			if !ok {
+				pb, err = schema.NewBuffer(buf.DynamicColumns())
+				if err != nil {
+					return nil, err
+				}
+				partitions[partition] = pb
+			}
+			if _, err := pb.WriteRows([]parquet.Row{row}); err != nil {
+				return nil, err
+			}
+		}
+		if errors.Is(readErr, io.EOF) {
+			return partitions, nil
+		}
+		if readErr != nil {
+			return nil, readErr
+		}
+	}
+}
+
+func sortedPartitions(partitions map[timePartition]*dynparquet.Buffer) []timePartition {
+	sorted := make([]timePartition, 0, len(partitions))
+	for partition := range partitions {
+		sorted = append(sorted, partition)
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].start < sorted[j].start
+	})
+	return sorted
+}
@@ -0,0 +1,164 @@
+package frostdb
+
+import (
+	"context"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/apache/arrow/go/v17/arrow"
+	"github.com/apache/arrow/go/v17/arrow/array"
+	"github.com/apache/arrow/go/v17/arrow/memory"
+	"github.com/stretchr/testify/require"
+	"github.com/thanos-io/objstore"
+
+	"github.com/polarsignals/frostdb/dynparquet"
+	"github.com/polarsignals/frostdb/query"
+	"github.com/polarsignals/frostdb/query/logicalplan"
+)
+
+// iterRecordingBucket records the directories listed in the bucket.
+type iterRecordingBucket struct {
+	objstore.Bucket
+
+	mtx  sync.Mutex
+	dirs []string
+}
+
+func (b *iterRecordingBucket) Iter(ctx context.Context, dir string, f func(string) error, options ...objstore.IterOption) error {
+	b.mtx.Lock()
+	b.dirs = append(b.dirs, dir)
+	b.mtx.Unlock()
+	return b.Bucket.Iter(ctx, dir, f, options...)
+}
+
+func TestTimePartitioning(t *testing.T) {
+	ctx := context.Background()
+	hour := time.Hour.Milliseconds()
+	clock := NewManualClock(time.UnixMilli(30 * time.Minute.Milliseconds()))
+	bucket := &iterRecordingBucket{Bucket: objstore.NewInMemBucket()}
+	sinksource := NewDefaultObjstoreBucket(bucket)
+
+	c, err := New(
+		WithLogger(newTestLogger(t)),
+		WithClock(clock),
+		WithReadWriteStorage(sinksource),
+	)
+	require.NoError(t, err)
+	db, err := c.DB(ctx, "test")
+	require.NoError(t, err)
+	table, err := db.Table("test", NewTableConfig(dynparquet.SampleDefinition(), WithTimePartitioning(time.Hour)))
+	require.NoError(t, err)
+
+	insert := func(timestamps ...int64) {
+		t.Helper()
+		samples := make(dynparquet.Samples, 0, len(timestamps))
+		for _, ts := range timestamps {
+			samples = append(samples, dynparquet.Sample{
+				ExampleType: "cpu",
+				Labels:      map[string]string{"node": "test"},
+				Timestamp:   ts,
+				Value:       ts,
+			})
+		}
+		r, err := samples.ToRecord()
+		require.NoError(t, err)
+		defer r.Release()
+		_, err = table.InsertRecord(ctx, r)
+		require.NoError(t, err)
+	}
+
+	// The first block contains rows of the first two partitions.
+	insert(1, 2, hour+1)
+	first := table.ActiveBlock()
+	// Crossing a partition boundary rotates the active block.
+	clock.Advance(time.Hour)
+	insert(hour + 2)
+	require.NotEqual(t, first, table.ActiveBlock())
+	// The rotated block is persisted in the background.
+	require.Eventually(t, func() bool {
+		table.mtx.RLock()
+		defer table.mtx.RUnlock()
+		return len(table.pendingBlocks) == 0
+	}, time.Second, time.Millisecond)
+	require.NoError(t, c.Close())
+
+	blocks := map[string]int{}
+	require.NoError(t, bucket.Iter(ctx, "test/test", func(name string) error {
+		if strings.HasSuffix(name, "/data.parquet") {
+			blocks[filepath.Base(filepath.Dir(filepath.Dir(name)))]++
+		}
+		return nil
+	}, objstore.WithRecursiveIter))
+	require.Equal(t, map[string]int{
+		"timestamp=0_3600000":       1,
+		"timestamp=3600000_7200000": 2,
+	}, blocks)
+
+	// Blocks created at or after the active block are read from memory, so
+	// the reopened table's active block must be newer than the persisted
+	// blocks.
+	clock.Advance(time.Millisecond)
+	c, err = New(
+		WithLogger(newTestLogger(t)),
+		WithClock(clock),
+		WithReadWriteStorage(sinksource),
+	)
+	require.NoError(t, err)
+	defer c.Close()
+	db, err = c.DB(ctx, "test")
+	require.NoError(t, err)
+	_, err = db.Table("test", NewTableConfig(dynparquet.SampleDefinition(), WithTimePartitioning(time.Hour)))
+	require.NoError(t, err)
+
+	timestamps := func(filter logicalplan.Expr) []int64 {
+		t.Helper()
+		var values []int64
+		require.NoError(t, query.NewEngine(memory.DefaultAllocator, db.TableProvider()).
+			ScanTable("test").
+			Filter(filter).
+			Execute(ctx, func(_ context.Context, r arrow.Record) error {
+				col := r.Column(r.Schema().FieldIndices("timestamp")[0]).(*array.Int64)
+				values = append(values, col.Int64Values()...)
+				return nil
+			}))
+		sort.Slice(values, func(i, j int) bool { return values[i] < values[j] })
+		return values
+	}
+
+	require.Equal(t, []int64{1, 2, hour + 1, hour + 2}, timestamps(logicalplan.Col("timestamp").GtEq(logicalplan.Literal(int64(0)))))
+
+	// The first partition is not listed when it cannot match the filter.
+	bucket.mtx.Lock()
+	bucket.dirs = nil
+	bucket.mtx.Unlock()
+	require.Equal(t, []int64{hour + 1, hour + 2}, timestamps(logicalplan.Col("timestamp").GtEq(logicalplan.Literal(hour))))
+	bucket.mtx.Lock()
+	defer bucket.mtx.Unlock()
+	require.NotContains(t, bucket.dirs, "test/test/timestamp=0_3600000/")
+	require.Contains(t, bucket.dirs, "test/test/timestamp=3600000_7200000/")
+}
+
+func TestColumnBounds(t *testing.T) {
+	ts := logicalplan.Col("timestamp")
+	for _, tc := range []struct {
+		name         string
+		filter       logicalplan.Expr
+		lower, upper int64
+	}{
+		{name: "GtEq", filter: ts.GtEq(logicalplan.Literal(int64(10))), lower: 10, upper: 1<<63 - 1},
+		{name: "Lt", filter: ts.Lt(logicalplan.Literal(int64(10))), lower: -1 << 63, upper: 9},
+		{name: "And", filter: logicalplan.And(ts.Gt(logicalplan.Literal(int64(10))), ts.LtEq(logicalplan.Literal(int64(20)))), lower: 11, upper: 20},
+		{name: "Or", filter: logicalplan.Or(ts.Eq(logicalplan.Literal(int64(10))), ts.Eq(logicalplan.Literal(int64(20)))), lower: 10, upper: 20},
+		{name: "OtherColumn", filter: logicalplan.Col("value").Eq(logicalplan.Literal(int64(10))), lower: -1 << 63, upper: 1<<63 - 1},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			lower, upper := columnBounds(tc.filter, "timestamp")
+			require.Equal(t, tc.lower, lower)
+			require.Equal(t, tc.upper, upper)
+		})
+	}
+}
@@ -7,11 +7,13 @@ import (
 	"io"
 	"sort"
 	"sync"
+	"sync/atomic"
 
 	"github.com/apache/arrow/go/v17/arrow"
 	"github.com/apache/arrow/go/v17/arrow/array"
 	"github.com/apache/arrow/go/v17/arrow/memory"
 	"github.com/parquet-go/parquet-go"
+	"golang.org/x/sync/errgroup"
 
 	"github.com/polarsignals/frostdb/dynparquet"
 	"github.com/polarsignals/frostdb/pqarrow/builder"
@@ -20,8 +22,20 @@ import (
 	"github.com/polarsignals/frostdb/query/logicalplan"
 )
 
+// ArrowSchemaRowGroup is implemented by row groups that resolve their own
+// arrow schema, e.g. to cache the schemas of row groups that are read
+// repeatedly. ArrowSchema must return the same schema as
+// ParquetSchemaToArrowSchema does for the schema of the row group.
+type ArrowSchemaRowGroup interface {
+	parquet.RowGroup
+	ArrowSchema(ctx context.Context, s *dynparquet.Schema, options logicalplan.IterOptions) (*arrow.Schema, error)
+}
+
 // ParquetRowGroupToArrowSchema converts a parquet row group to an arrow schema.
 func ParquetRowGroupToArrowSchema(ctx context.Context, rg parquet.RowGroup, s *dynparquet.Schema, options logicalplan.IterOptions) (*arrow.Schema, error) {
+	if rg, ok := rg.(ArrowSchemaRowGroup); ok {
+		return rg.ArrowSchema(ctx, s, options)
+	}
 	return ParquetSchemaToArrowSchema(ctx, rg.Schema(), s, options)
 }
 
@@ -46,6 +60,9 @@ func ParquetSchemaToArrowSchema(ctx context.Context, schema *parquet.Schema, s *
 				}
 			}
 		}
+		if options.DictionaryEncode {
+			dictionaryEncodeFields(fields)
+		}
 		return arrow.NewSchema(fields, nil), nil
 	}
 
@@ -59,6 +76,9 @@ func ParquetSchemaToArrowSchema(ctx context.Context, schema *parquet.Schema, s *
 			fields = append(fields, f)
 		}
 	}
+	if options.DictionaryEncode {
+		dictionaryEncodeFields(fields)
+	}
 
 	if len(options.DistinctColumns) > 0 {
 		for _, distinctExpr := range options.DistinctColumns {
@@ -95,6 +115,14 @@ func ParquetSchemaToArrowSchema(ctx context.Context, schema *parquet.Schema, s *
 	return arrow.NewSchema(fields, nil), nil
 }
 
+// dictionaryEncodeFields changes the types of binary fields, and lists of them,
+// to dictionary types.
+func dictionaryEncodeFields(fields []arrow.Field) {
+	for i := range fields {
+		fields[i].Type = convert.DictionaryEncoded(fields[i].Type)
+	}
+}
+
 type exprTypeFinder struct {
 	s *dynparquet.Schema
 }
@@ -228,11 +256,42 @@ type ParquetConverter struct {
 	// scratchValues is an array of parquet.Values that is reused during
 	// decoding to avoid allocations.
 	scratchValues []parquet.Value
+
+	// concurrency is the maximum number of columns that are converted in
+	// parallel. workerScratchValues holds the scratchValues of each worker.
+	concurrency         int
+	workerScratchValues [][]parquet.Value
+	// decodePool, if set, decodes the columns instead of the converting
+	// goroutine.
+	decodePool *DecodePool
+}
+
+// ConverterOption configures a ParquetConverter.
+type ConverterOption func(*ParquetConverter)
+
+// WithConcurrency converts up to concurrency columns of a row group in
+// parallel. Each column is written to its own builder, so the result is the
+// same as converting the columns serially. Values <= 1 convert serially.
+func WithConcurrency(concurrency int) ConverterOption {
+	return func(c *ParquetConverter) {
+		c.concurrency = concurrency
+	}
+}
+
+// WithDecodePool decodes the columns of converted row groups on the workers of
+// the given pool, which may be shared by many converters, instead of on the
+// goroutine calling Convert. The columns of a row group are decoded in
+// parallel as far as the pool allows, the concurrency option is ignored.
+func WithDecodePool(p *DecodePool) ConverterOption {
+	return func(c *ParquetConverter) {
+		c.decodePool = p
+	}
 }
 
 func NewParquetConverter(
 	pool memory.Allocator,
 	iterOpts logicalplan.IterOptions,
+	options ...ConverterOption,
 ) *ParquetConverter {
 	c := &ParquetConverter{
 		mode:             normal,
@@ -240,6 +299,9 @@ func NewParquetConverter(
 		iterOpts:         iterOpts,
 		distinctColInfos: make([]*distinctColInfo, len(iterOpts.DistinctColumns)),
 	}
+	for _, option := range options {
+		option(c)
+	}
 
 	if iterOpts.Filter == nil && len(iterOpts.DistinctColumns) != 0 {
 		simpleDistinctExprs := true
@@ -331,19 +393,30 @@ func (c *ParquetConverter) Convert(ctx context.Context, rg parquet.RowGroup, s *
 		// If we get here, we couldn't use the fast path.
 	}
 
-	for _, w := range c.writers {
-		for _, col := range w.colIdx {
-			select {
-			case <-ctx.Done():
-				return ctx.Err()
-			default:
-				if err := c.writeColumnToArray(
-					parquetFields[w.fieldIdx],
-					parquetColumns[col],
-					false,
-					w.writer,
-				); err != nil {
-					return fmt.Errorf("convert parquet column to arrow array: %w", err)
+	if c.decodePool != nil {
+		if err := c.writeColumnsWithPool(ctx, parquetFields, parquetColumns); err != nil {
+			return err
+		}
+	} else if c.concurrency > 1 && len(c.writers) > 1 {
+		if err := c.writeColumnsConcurrently(ctx, parquetFields, parquetColumns); err != nil {
+			return err
+		}
+	} else {
+		for _, w := range c.writers {
+			for _, col := range w.colIdx {
+				select {
+				case <-ctx.Done():
+					return ctx.Err()
+				default:
+					if err := c.writeColumnToArray(
+						parquetFields[w.fieldIdx],
+						parquetColumns[col],
+						false,
+						w.writer,
+						&c.scratchValues,
+					); err != nil {
+						return fmt.Errorf("convert parquet column to arrow array: %w", err)
+					}
 				}
 			}
 		}
@@ -372,6 +445,106 @@ func (c *ParquetConverter) Convert(ctx context.Context, rg parquet.RowGroup, s *
 	return nil
 }
 
+// writeColumnsConcurrently writes the columns of all writers using up to
+// c.concurrency workers. The columns of a single writer are written by the
+// same worker in order, since they are written to the same builder.
+func (c *ParquetConverter) writeColumnsConcurrently(
+	ctx context.Context,
+	parquetFields []parquet.Field,
+	parquetColumns []parquet.ColumnChunk,
+) error {
+	workers := min(c.concurrency, len(c.writers))
+	for len(c.workerScratchValues) < workers {
+		c.workerScratchValues = append(c.workerScratchValues, nil)
+	}
+
+	var next atomic.Int64
+	errg, ctx := errgroup.WithContext(ctx)
+	for i := 0; i < workers; i++ {
+		scratchValues := &c.workerScratchValues[i]
+		errg.Go(func() error {
+			for {
+				idx := int(next.Add(1) - 1)
+				if idx >= len(c.writers) {
+					return nil
+				}
+				w := c.writers[idx]
+				for _, col := range w.colIdx {
+					if err := ctx.Err(); err != nil {
+						return err
+					}
+					if err := c.writeColumnToArray(
+						parquetFields[w.fieldIdx],
+						parquetColumns[col],
+						false,
+						w.writer,
+						scratchValues,
+					); err != nil {
+						return fmt.Errorf("convert parquet column to arrow array: %w", err)
+					}
+				}
+			}
+		})
+	}
+	return errg.Wait()
+}
+
+// writeColumnsWithPool writes the columns of all writers on the workers of
+// c.decodePool. The columns of a single writer are written by a single task in
+// order, since they are written to the same builder.
+func (c *ParquetConverter) writeColumnsWithPool(
+	ctx context.Context,
+	parquetFields []parquet.Field,
+	parquetColumns []parquet.ColumnChunk,
+) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		wg       sync.WaitGroup
+		errOnce  sync.Once
+		firstErr error
+	)
+	setErr := func(err error) {
+		errOnce.Do(func() {
+			firstErr = err
+			cancel()
+		})
+	}
+	for _, w := range c.writers {
+		w := w
+		wg.Add(1)
+		err := c.decodePool.submit(ctx, func(scratchValues *[]parquet.Value) {
+			defer wg.Done()
+			for _, col := range w.colIdx {
+				if err := ctx.Err(); err != nil {
+					setErr(err)
+					return
+				}
+				if err := c.writeColumnToArray(
+					parquetFields[w.fieldIdx],
+					parquetColumns[col],
+					false,
+					w.writer,
+					scratchValues,
+				); err != nil {
+					setErr(fmt.Errorf("convert parquet column to arrow array: %w", err))
+					return
+				}
+			}
+		})
+		if err != nil {
+			wg.Done()
+			setErr(err)
+			break
+		}
+	}
+	// The submitted tasks write to the builders of the converter, so they
+	// must finish before returning, even on error.
+	wg.Wait()
+	return firstErr
+}
+
 func (c *ParquetConverter) Fields() []builder.ColumnBuilder {
 	if c.builder == nil {
 		return nil
@@ -436,7 +609,7 @@ func (c *ParquetConverter) schemaChanged(parquetFields []parquet.Field) error {
 			continue
 		}
 
-		newWriter, err := convert.GetWriter(i, field)
+		newWriter, err := convert.GetWriterForType(i, field, c.outputSchema.Field(indices[0]).Type)
 		if err != nil {
 			return err
 		}
@@ -622,6 +795,7 @@ func (c *ParquetConverter) writeDistinctSingleColumn(
 			columnChunk,
 			true,
 			info.w,
+			&c.scratchValues,
 		); err != nil {
 			return false, err
 		}
@@ -654,7 +828,7 @@ func rowBasedParquetRowGroupToArrowRecord(
 	// Create arrow writers from arrow and parquet schema
 	writers := make([]writer.ValueWriter, len(parquetFields))
 	for i, field := range builder.Fields() {
-		newValueWriter, err := convert.GetWriter(i, parquetFields[i])
+		newValueWriter, err := convert.GetWriterForType(i, parquetFields[i], schema.Field(i).Type)
 		if err != nil {
 			return err
 		}
@@ -713,6 +887,7 @@ func (c *ParquetConverter) writeColumnToArray(
 	columnChunk parquet.ColumnChunk,
 	dictionaryOnly bool,
 	w writer.ValueWriter,
+	scratchValues *[]parquet.Value,
 ) error {
 	repeated := n.Repeated()
 	if !repeated && dictionaryOnly {
@@ -805,18 +980,18 @@ func (c *ParquetConverter) writeColumnToArray(
 
 		// Write values using the slow path.
 		n := p.NumValues()
-		if int64(cap(c.scratchValues)) < n {
-			c.scratchValues = make([]parquet.Value, n)
+		if int64(cap(*scratchValues)) < n {
+			*scratchValues = make([]parquet.Value, n)
 		}
-		c.scratchValues = c.scratchValues[:n]
+		*scratchValues = (*scratchValues)[:n]
 
 		// We're reading all values in the page so we always expect an io.EOF.
 		reader := p.Values()
-		if _, err := reader.ReadValues(c.scratchValues); err != nil && err != io.EOF {
+		if _, err := reader.ReadValues(*scratchValues); err != nil && err != io.EOF {
 			return fmt.Errorf("read values: %w", err)
 		}
 
-		w.Write(c.scratchValues)
+		w.Write(*scratchValues)
 	}
 
 	return nil
@@ -1055,6 +1230,24 @@ func copyArrToBuilder(builder builder.ColumnBuilder, arr arrow.Array, toCopy int
 				b.UnsafeAppend(arr.Value(i))
 			}
 		}
+	case *array.Int32:
+		b := builder.(*array.Int32Builder)
+		for i := 0; i < toCopy; i++ {
+			if arr.IsNull(i) {
+				b.UnsafeAppendBoolToBitmap(false)
+			} else {
+				b.UnsafeAppend(arr.Value(i))
+			}
+		}
+	case *array.Timestamp:
+		b := builder.(*array.TimestampBuilder)
+		for i := 0; i < toCopy; i++ {
+			if arr.IsNull(i) {
+				b.UnsafeAppendBoolToBitmap(false)
+			} else {
+				b.UnsafeAppend(arr.Value(i))
+			}
+		}
 	case *array.Float64:
 		b := builder.(*array.Float64Builder)
 		for i := 0; i < toCopy; i++ {
@@ -1111,6 +1304,10 @@ func repeatLastValue(
 		repeatInt64Array(builder.(*array.Int64Builder), arr, count)
 	case *array.Uint64:
 		repeatUint64Array(builder.(*array.Uint64Builder), arr, count)
+	case *array.Int32:
+		repeatInt32Array(builder.(*array.Int32Builder), arr, count)
+	case *array.Timestamp:
+		repeatTimestampArray(builder.(*array.TimestampBuilder), arr, count)
 	case *array.Float64:
 		repeatFloat64Array(builder.(*array.Float64Builder), arr, count)
 	case *array.Dictionary:
@@ -1173,6 +1370,32 @@ func repeatUint64Array(
 	b.AppendValues(vals, nil)
 }
 
+func repeatInt32Array(
+	b *array.Int32Builder,
+	arr *array.Int32,
+	count int,
+) {
+	val := arr.Value(arr.Len() - 1)
+	vals := make([]int32, count)
+	for i := 0; i < count; i++ {
+		vals[i] = val
+	}
+	b.AppendValues(vals, nil)
+}
+
+func repeatTimestampArray(
+	b *array.TimestampBuilder,
+	arr *array.Timestamp,
+	count int,
+) {
+	val := arr.Value(arr.Len() - 1)
+	vals := make([]arrow.Timestamp, count)
+	for i := 0; i < count; i++ {
+		vals[i] = val
+	}
+	b.AppendValues(vals, nil)
+}
+
 func repeatFloat64Array(
 	b *array.Float64Builder,
 	arr *array.Float64,
@@ -145,6 +145,43 @@ func TestDifferentSchemasToArrow(t *testing.T) {
 	}
 }
 
+func TestParquetConverterConcurrency(t *testing.T) {
+	dynSchema := dynparquet.NewSampleSchema()
+	buf, err := dynparquet.ToBuffer(dynparquet.GenerateTestSamples(100), dynSchema)
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	alloc := memory.NewCheckedAllocator(memory.DefaultAllocator)
+	defer alloc.AssertSize(t, 0)
+
+	convert := func(options ...ConverterOption) arrow.Record {
+		c := NewParquetConverter(alloc, logicalplan.IterOptions{}, options...)
+		defer c.Close()
+		for i := 0; i < 3; i++ {
+			require.NoError(t, c.Convert(ctx, buf, dynSchema))
+		}
+		return c.NewRecord()
+	}
+
+	serial := convert()
+	defer serial.Release()
+	concurrent := convert(WithConcurrency(4))
+	defer concurrent.Release()
+
+	require.Equal(t, int64(300), concurrent.NumRows())
+	require.True(t, array.RecordEqual(serial, concurrent))
+
+	pool := NewDecodePool(2)
+	pooled := convert(WithDecodePool(pool))
+	defer pooled.Release()
+	require.True(t, array.RecordEqual(serial, pooled))
+
+	pool.Close()
+	c := NewParquetConverter(alloc, logicalplan.IterOptions{}, WithDecodePool(pool))
+	defer c.Close()
+	require.ErrorIs(t, c.Convert(ctx, buf, dynSchema), ErrDecodePoolClosed)
+}
+
 func TestMergeToArrow(t *testing.T) {
 	dynSchema := dynparquet.NewSampleSchema()
 
@@ -44,6 +44,26 @@ type SortingColumn struct {
 	NullsFirst bool
 }
 
+// IsSortableType reports whether SortRecord can sort columns of type t.
+func IsSortableType(t arrow.DataType) bool {
+	if dict, ok := t.(*arrow.DictionaryType); ok {
+		switch dict.ValueType.ID() {
+		case arrow.STRING, arrow.BINARY, arrow.FIXED_SIZE_BINARY:
+			return true
+		default:
+			return false
+		}
+	}
+	switch t.ID() {
+	case arrow.INT16, arrow.INT32, arrow.INT64,
+		arrow.UINT16, arrow.UINT32, arrow.UINT64,
+		arrow.FLOAT64, arrow.STRING, arrow.BINARY, arrow.TIMESTAMP:
+		return true
+	default:
+		return false
+	}
+}
+
 // SortRecord sorts given arrow.Record by columns. Returns *array.Int32 of
 // indices to sorted rows or record r.
 //
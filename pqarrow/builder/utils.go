@@ -34,6 +34,12 @@ func RollbackPrevious(cb ColumnBuilder) error {
 		b.ResetToLength(b.Len() - 1)
 	case *array.Int64Builder:
 		b.Resize(b.Len() - 1)
+	case *array.Int32Builder:
+		b.Resize(b.Len() - 1)
+	case *array.Uint64Builder:
+		b.Resize(b.Len() - 1)
+	case *array.TimestampBuilder:
+		b.Resize(b.Len() - 1)
 
 	case *array.StringBuilder:
 		b.Resize(b.Len() - 1)
@@ -72,6 +78,8 @@ func AppendValue(cb ColumnBuilder, arr arrow.Array, i int) error {
 		b.Append(arr.(*array.Float64).Value(i))
 	case *array.Uint64Builder:
 		b.Append(arr.(*array.Uint64).Value(i))
+	case *array.TimestampBuilder:
+		b.Append(arr.(*array.Timestamp).Value(i))
 	case *array.StringBuilder:
 		b.Append(arr.(*array.String).Value(i))
 	case *array.BinaryBuilder:
@@ -95,6 +103,14 @@ func AppendValue(cb ColumnBuilder, arr arrow.Array, i int) error {
 			default:
 				return fmt.Errorf("dictionary type %T unsupported", dict)
 			}
+		case *array.Binary:
+			if err := b.Append(a.Value(i)); err != nil {
+				return err
+			}
+		case *array.String:
+			if err := b.AppendString(a.Value(i)); err != nil {
+				return err
+			}
 		default:
 			return fmt.Errorf("non-dictionary array %T provided for dictionary builder", a)
 		}
@@ -80,9 +80,20 @@ func ParquetNodeToType(n parquet.Node) (arrow.DataType, error) {
 				} else {
 					dt = &arrow.Uint64Type{}
 				}
+			case 32:
+				if !lt.Integer.IsSigned {
+					return nil, errors.New("unsupported unsigned int bit width")
+				}
+				dt = &arrow.Int32Type{}
 			default:
 				return nil, errors.New("unsupported int bit width")
 			}
+		case lt.Timestamp != nil:
+			typ := &arrow.TimestampType{Unit: timeUnit(lt.Timestamp.Unit)}
+			if lt.Timestamp.IsAdjustedToUTC {
+				typ.TimeZone = "UTC"
+			}
+			dt = typ
 		default:
 			return nil, errors.New("unsupported logical type: " + n.Type().String())
 		}
@@ -107,6 +118,13 @@ func GetWriter(offset int, n parquet.Node) (writer.NewWriterFunc, error) {
 	if err != nil {
 		return nil, err
 	}
+	return GetWriterForType(offset, n, dt)
+}
+
+// GetWriterForType creates a value writer that writes the values of a parquet
+// node to a builder of type dt, e.g. to write the values of a plain encoded
+// binary column to a dictionary builder, see DictionaryEncoded.
+func GetWriterForType(offset int, n parquet.Node, dt arrow.DataType) (writer.NewWriterFunc, error) {
 
 	list := false
 	if typ, ok := dt.(*arrow.ListType); ok {
@@ -123,8 +141,12 @@ func GetWriter(offset int, n parquet.Node) (writer.NewWriterFunc, error) {
 		wr = writer.NewInt64ValueWriter
 	case *arrow.Uint64Type:
 		wr = writer.NewUint64ValueWriter
+	case *arrow.Int32Type:
+		wr = writer.NewInt32ValueWriter
+	case *arrow.TimestampType:
+		wr = writer.NewTimestampValueWriter
 	case *arrow.MapType:
-		wr = writer.NewMapWriter
+		wr = writer.NewMapWriter(n.Optional())
 	case *arrow.StructType:
 		wr = writer.NewStructWriterFromOffset(offset)
 	case *arrow.BooleanType:
@@ -146,6 +168,38 @@ func GetWriter(offset int, n parquet.Node) (writer.NewWriterFunc, error) {
 	return wr, nil
 }
 
+// DictionaryEncoded returns the dictionary encoded type of dt if dt is a
+// binary or string type, or a list of them. Other types are returned as is.
+func DictionaryEncoded(dt arrow.DataType) arrow.DataType {
+	switch t := dt.(type) {
+	case *arrow.BinaryType, *arrow.StringType:
+		return &arrow.DictionaryType{
+			IndexType: &arrow.Uint32Type{},
+			ValueType: dt,
+		}
+	case *arrow.ListType:
+		elem := DictionaryEncoded(t.Elem())
+		if elem == t.Elem() {
+			return dt
+		}
+		return arrow.ListOf(elem)
+	default:
+		return dt
+	}
+}
+
+// timeUnit returns the arrow time unit of a parquet time unit.
+func timeUnit(unit format.TimeUnit) arrow.TimeUnit {
+	switch {
+	case unit.Millis != nil:
+		return arrow.Millisecond
+	case unit.Micros != nil:
+		return arrow.Microsecond
+	default:
+		return arrow.Nanosecond
+	}
+}
+
 // https://github.com/apache/parquet-format/blob/master/LogicalTypes.md#maps
 func hasMapFields(n parquet.Node) bool {
 	// toplevel group requiredto be repeated group key_value with
@@ -27,6 +27,14 @@ func TestParquetNodeToType(t *testing.T) {
 			parquetNode: parquet.Uint(64),
 			arrowType:   &arrow.Uint64Type{},
 		},
+		{
+			parquetNode: parquet.Int(32),
+			arrowType:   &arrow.Int32Type{},
+		},
+		{
+			parquetNode: parquet.Timestamp(parquet.Millisecond),
+			arrowType:   &arrow.TimestampType{Unit: arrow.Millisecond, TimeZone: "UTC"},
+		},
 		{
 			parquetNode: parquet.Leaf(parquet.BooleanType),
 			arrowType:   &arrow.BooleanType{},
@@ -61,9 +69,13 @@ func TestParquetNodeToType(t *testing.T) {
 		msg         string
 	}{
 		{
-			parquetNode: parquet.Int(32),
+			parquetNode: parquet.Int(16),
 			msg:         "unsupported int bit width",
 		},
+		{
+			parquetNode: parquet.Uint(32),
+			msg:         "unsupported unsigned int bit width",
+		},
 		{
 			parquetNode: parquet.Leaf(parquet.Int96Type),
 			msg:         "unsupported type: INT96",
@@ -110,10 +122,6 @@ func TestParquetNodeToType(t *testing.T) {
 			parquetNode: parquet.Time(parquet.Millisecond),
 			msg:         "unsupported logical type: TIME(isAdjustedToUTC=true,unit=MILLIS)",
 		},
-		{
-			parquetNode: parquet.Timestamp(parquet.Millisecond),
-			msg:         "unsupported logical type: TIMESTAMP(isAdjustedToUTC=true,unit=MILLIS)",
-		},
 		// nullType is unexported by parquet-go/parquet-go.
 	}
 	for _, c := range errCases {
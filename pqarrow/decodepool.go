@@ -0,0 +1,78 @@
+package pqarrow
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/parquet-go/parquet-go"
+)
+
+// ErrDecodePoolClosed is returned when converting a row group with a
+// DecodePool that was closed.
+var ErrDecodePoolClosed = errors.New("decode pool closed")
+
+// decodeTask decodes columns using the scratch values of the worker running
+// it.
+type decodeTask func(scratchValues *[]parquet.Value)
+
+// DecodePool is a fixed number of workers decompressing and decoding the
+// column chunks of the row groups converted by all ParquetConverters sharing
+// it, see WithDecodePool. This bounds the CPU spent on decoding across all
+// concurrent scans, so that a few heavy scans can't occupy all cores.
+// Columns are decoded in the order they are submitted.
+type DecodePool struct {
+	tasks     chan decodeTask
+	done      chan struct{}
+	closeOnce sync.Once
+	wg        sync.WaitGroup
+}
+
+// NewDecodePool starts a DecodePool with the given number of workers. It must
+// be closed once no longer used.
+func NewDecodePool(workers int) *DecodePool {
+	p := &DecodePool{
+		tasks: make(chan decodeTask),
+		done:  make(chan struct{}),
+	}
+	p.wg.Add(workers)
+	for i := 0; i < workers; i++ {
+		go p.work()
+	}
+	return p
+}
+
+func (p *DecodePool) work() {
+	defer p.wg.Done()
+	var scratchValues []parquet.Value
+	for {
+		select {
+		case <-p.done:
+			return
+		case task := <-p.tasks:
+			task(&scratchValues)
+		}
+	}
+}
+
+// submit waits until a worker picks up the task.
+func (p *DecodePool) submit(ctx context.Context, task decodeTask) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-p.done:
+		return ErrDecodePoolClosed
+	case p.tasks <- task:
+		return nil
+	}
+}
+
+// Close stops the workers once they finished their current task. Conversions
+// submitting columns afterwards fail with ErrDecodePoolClosed. Closing a
+// closed pool has no effect.
+func (p *DecodePool) Close() {
+	p.closeOnce.Do(func() {
+		close(p.done)
+	})
+	p.wg.Wait()
+}
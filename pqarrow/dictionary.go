@@ -0,0 +1,119 @@
+package pqarrow
+
+import (
+	"fmt"
+
+	"github.com/apache/arrow/go/v17/arrow"
+	"github.com/apache/arrow/go/v17/arrow/array"
+	"github.com/apache/arrow/go/v17/arrow/memory"
+
+	"github.com/polarsignals/frostdb/pqarrow/convert"
+)
+
+// DictionaryEncode returns r with its binary and string columns, and lists of
+// them, dictionary encoded, which reduces the memory used by columns with few
+// distinct values. The record is returned as is if it has no such columns.
+// The caller is responsible for releasing the returned record.
+func DictionaryEncode(mem memory.Allocator, r arrow.Record) (arrow.Record, error) {
+	fields := make([]arrow.Field, 0, r.NumCols())
+	cols := make([]arrow.Array, 0, r.NumCols())
+	defer func() {
+		for _, col := range cols {
+			col.Release()
+		}
+	}()
+
+	encoded := false
+	for i, col := range r.Columns() {
+		field := r.Schema().Field(i)
+		dt := convert.DictionaryEncoded(field.Type)
+		if dt == field.Type {
+			col.Retain()
+			fields = append(fields, field)
+			cols = append(cols, col)
+			continue
+		}
+
+		arr, err := dictionaryEncodeArray(mem, dt, col)
+		if err != nil {
+			return nil, fmt.Errorf("dictionary encode column %q: %w", field.Name, err)
+		}
+		field.Type = dt
+		fields = append(fields, field)
+		cols = append(cols, arr)
+		encoded = true
+	}
+
+	if !encoded {
+		r.Retain()
+		return r, nil
+	}
+	md := r.Schema().Metadata()
+	return array.NewRecord(arrow.NewSchema(fields, &md), cols, r.NumRows()), nil
+}
+
+// dictionaryEncodeArray converts arr to an array of the dictionary encoded
+// type dt.
+func dictionaryEncodeArray(mem memory.Allocator, dt arrow.DataType, arr arrow.Array) (arrow.Array, error) {
+	b := array.NewBuilder(mem, dt)
+	defer b.Release()
+
+	switch a := arr.(type) {
+	case *array.List:
+		lb := b.(*array.ListBuilder)
+		vb, ok := lb.ValueBuilder().(*array.BinaryDictionaryBuilder)
+		if !ok {
+			return nil, fmt.Errorf("unsupported list value type %s", a.DataType())
+		}
+		lb.Reserve(a.Len())
+		for i := 0; i < a.Len(); i++ {
+			if a.IsNull(i) {
+				lb.AppendNull()
+				continue
+			}
+			lb.Append(true)
+			start, end := a.ValueOffsets(i)
+			if err := appendDictionaryValues(vb, a.ListValues(), int(start), int(end)); err != nil {
+				return nil, err
+			}
+		}
+	default:
+		db, ok := b.(*array.BinaryDictionaryBuilder)
+		if !ok {
+			return nil, fmt.Errorf("unsupported type %s", a.DataType())
+		}
+		if err := appendDictionaryValues(db, a, 0, a.Len()); err != nil {
+			return nil, err
+		}
+	}
+	return b.NewArray(), nil
+}
+
+// appendDictionaryValues appends the values of arr from start to end to b.
+func appendDictionaryValues(b *array.BinaryDictionaryBuilder, arr arrow.Array, start, end int) error {
+	switch a := arr.(type) {
+	case *array.Binary:
+		for i := start; i < end; i++ {
+			if a.IsNull(i) {
+				b.AppendNull()
+				continue
+			}
+			if err := b.Append(a.Value(i)); err != nil {
+				return err
+			}
+		}
+	case *array.String:
+		for i := start; i < end; i++ {
+			if a.IsNull(i) {
+				b.AppendNull()
+				continue
+			}
+			if err := b.AppendString(a.Value(i)); err != nil {
+				return err
+			}
+		}
+	default:
+		return fmt.Errorf("unsupported type %s", arr.DataType())
+	}
+	return nil
+}
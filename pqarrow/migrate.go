@@ -0,0 +1,104 @@
+package pqarrow
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/apache/arrow/go/v17/arrow"
+	"github.com/apache/arrow/go/v17/arrow/array"
+
+	"github.com/polarsignals/frostdb/dynparquet"
+)
+
+// migrationNode is a node in the tree of columns built when migrating a
+// record read from a v1alpha1 block into the shape of a v1alpha2 schema.
+type migrationNode struct {
+	field    arrow.Field
+	array    arrow.Array
+	children map[string]*migrationNode
+}
+
+// MigrateRecord reshapes a record read from data written under a v1alpha1
+// schema into the shape of the equivalent v1alpha2 schema using the given
+// field mapping. Flat columns mapped to nested paths (e.g. the concrete
+// dynamic column "labels.label1") are grouped into struct columns, which
+// allows data written under both schema versions to be read together. The
+// caller is responsible for releasing the returned record.
+func MigrateRecord(r arrow.Record, mapping dynparquet.FieldMapping) (arrow.Record, error) {
+	root := &migrationNode{children: map[string]*migrationNode{}}
+	for i, f := range r.Schema().Fields() {
+		segments := strings.Split(mapping.Path(f.Name), ".")
+		node := root
+		for _, name := range segments[:len(segments)-1] {
+			child, ok := node.children[name]
+			if !ok {
+				child = &migrationNode{children: map[string]*migrationNode{}}
+				node.children[name] = child
+			}
+			if child.array != nil {
+				return nil, fmt.Errorf("migrate column %q: %q is both a leaf and a group", f.Name, name)
+			}
+			node = child
+		}
+		name := segments[len(segments)-1]
+		if _, ok := node.children[name]; ok {
+			return nil, fmt.Errorf("migrate column %q: duplicate path", f.Name)
+		}
+		field := f
+		field.Name = name
+		node.children[name] = &migrationNode{field: field, array: r.Column(i)}
+	}
+
+	var built []arrow.Array
+	defer func() {
+		for _, a := range built {
+			a.Release()
+		}
+	}()
+
+	var build func(name string, n *migrationNode) (arrow.Field, arrow.Array, error)
+	build = func(name string, n *migrationNode) (arrow.Field, arrow.Array, error) {
+		if n.array != nil {
+			return n.field, n.array, nil
+		}
+		names := sortedChildren(n)
+		arrays := make([]arrow.Array, 0, len(names))
+		for _, child := range names {
+			_, arr, err := build(child, n.children[child])
+			if err != nil {
+				return arrow.Field{}, nil, err
+			}
+			arrays = append(arrays, arr)
+		}
+		arr, err := array.NewStructArray(arrays, names)
+		if err != nil {
+			return arrow.Field{}, nil, err
+		}
+		built = append(built, arr)
+		return arrow.Field{Name: name, Type: arr.DataType()}, arr, nil
+	}
+
+	names := sortedChildren(root)
+	fields := make([]arrow.Field, 0, len(names))
+	columns := make([]arrow.Array, 0, len(names))
+	for _, name := range names {
+		field, arr, err := build(name, root.children[name])
+		if err != nil {
+			return nil, err
+		}
+		fields = append(fields, field)
+		columns = append(columns, arr)
+	}
+
+	return array.NewRecord(arrow.NewSchema(fields, nil), columns, r.NumRows()), nil
+}
+
+func sortedChildren(n *migrationNode) []string {
+	names := make([]string, 0, len(n.children))
+	for name := range n.children {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,50 @@
+package pqarrow
+
+import (
+	"testing"
+
+	"github.com/apache/arrow/go/v17/arrow"
+	"github.com/apache/arrow/go/v17/arrow/array"
+	"github.com/apache/arrow/go/v17/arrow/memory"
+	"github.com/stretchr/testify/require"
+
+	"github.com/polarsignals/frostdb/dynparquet"
+)
+
+func TestMigrateRecord(t *testing.T) {
+	mem := memory.NewCheckedAllocator(memory.NewGoAllocator())
+	defer mem.AssertSize(t, 0)
+
+	schema := arrow.NewSchema([]arrow.Field{
+		{Name: "example_type", Type: arrow.BinaryTypes.String},
+		{Name: "labels.label1", Type: arrow.BinaryTypes.String, Nullable: true},
+		{Name: "labels.label2", Type: arrow.BinaryTypes.String, Nullable: true},
+		{Name: "value", Type: arrow.PrimitiveTypes.Int64},
+	}, nil)
+	b := array.NewRecordBuilder(mem, schema)
+	defer b.Release()
+	b.Field(0).(*array.StringBuilder).AppendValues([]string{"cpu", "memory"}, nil)
+	b.Field(1).(*array.StringBuilder).AppendValues([]string{"a", ""}, []bool{true, false})
+	b.Field(2).(*array.StringBuilder).AppendValues([]string{"b", "c"}, nil)
+	b.Field(3).(*array.Int64Builder).AppendValues([]int64{1, 2}, nil)
+	r := b.NewRecord()
+	defer r.Release()
+
+	migrated, err := MigrateRecord(r, dynparquet.FieldMapping{"example_type": "type"})
+	require.NoError(t, err)
+	defer migrated.Release()
+
+	require.Equal(t, int64(2), migrated.NumRows())
+	require.Equal(t, int64(3), migrated.NumCols())
+	require.Equal(t, "labels", migrated.ColumnName(0))
+	require.Equal(t, "type", migrated.ColumnName(1))
+	require.Equal(t, "value", migrated.ColumnName(2))
+
+	labels := migrated.Column(0).(*array.Struct)
+	labelsType := labels.DataType().(*arrow.StructType)
+	require.Equal(t, "label1", labelsType.Field(0).Name)
+	require.Equal(t, "label2", labelsType.Field(1).Name)
+	require.Equal(t, "a", labels.Field(0).(*array.String).Value(0))
+	require.True(t, labels.Field(0).IsNull(1))
+	require.Equal(t, "c", labels.Field(1).(*array.String).Value(1))
+}
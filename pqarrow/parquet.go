@@ -13,6 +13,7 @@ import (
 	"github.com/parquet-go/parquet-go"
 
 	"github.com/polarsignals/frostdb/dynparquet"
+	"github.com/polarsignals/frostdb/pqarrow/convert"
 )
 
 func ArrowScalarToParquetValue(sc scalar.Scalar) (parquet.Value, error) {
@@ -25,6 +26,14 @@ func ArrowScalarToParquetValue(sc scalar.Scalar) (parquet.Value, error) {
 		return parquet.ValueOf(s.Value), nil
 	case *scalar.Uint64:
 		return parquet.ValueOf(s.Value), nil
+	case *scalar.Uint32:
+		return parquet.ValueOf(s.Value), nil
+	case *scalar.Float64:
+		return parquet.ValueOf(s.Value), nil
+	case *scalar.Float32:
+		return parquet.ValueOf(s.Value), nil
+	case *scalar.Binary:
+		return parquet.ValueOf(s.Data()), nil
 	case *scalar.FixedSizeBinary:
 		width := s.Type.(*arrow.FixedSizeBinaryType).ByteWidth
 		v := [16]byte{}
@@ -32,6 +41,8 @@ func ArrowScalarToParquetValue(sc scalar.Scalar) (parquet.Value, error) {
 		return parquet.ValueOf(v), nil
 	case *scalar.Boolean:
 		return parquet.ValueOf(s.Value), nil
+	case *scalar.Timestamp:
+		return parquet.Int64Value(int64(s.Value)), nil
 	case *scalar.Null:
 		return parquet.NullValue(), nil
 	case nil:
@@ -87,6 +98,7 @@ func recordToRows(w dynparquet.ParquetWriter, record arrow.Record, recordStart,
 	schema := record.Schema()
 	row := make(parquet.Row, len(finalFields))
 	writers := make([]arrowToParquet, len(finalFields))
+	leaf := 0
 	for i := range writers {
 		f := finalFields[i]
 		name := f.Name()
@@ -94,33 +106,57 @@ func recordToRows(w dynparquet.ParquetWriter, record arrow.Record, recordStart,
 		if f.Optional() {
 			def = 1
 		}
+		// The values of a field are written to its leaf columns, of which
+		// fields of nested types such as maps have more than one. Other
+		// fields have a single leaf column, so their node, which may be
+		// incomplete, is not inspected.
+		col := leaf
 		idx := schema.FieldIndices(name)
 		if len(idx) == 0 {
-			writers[i] = writeNull(i)
+			leaves := numLeaves(f)
+			leaf += leaves
+			writers[i] = writeNull(col, leaves)
 			continue
 		}
 		column := record.Column(idx[0])
+		if _, ok := column.(*array.Map); ok {
+			leaf += numLeaves(f)
+		} else {
+			leaf++
+		}
 		switch a := column.(type) {
+		case *array.Map:
+			ms, err := writeMap(def, col, recordStart, a)
+			if err != nil {
+				return err
+			}
+			writers[i] = ms
 		case *array.List:
-			ls, err := writeList(def, i, recordStart, a)
+			ls, err := writeList(def, col, recordStart, a)
 			if err != nil {
 				return err
 			}
 			writers[i] = ls
 		case *array.Dictionary:
-			writers[i] = writeDictionary(def, i, recordStart, a)
+			writers[i] = writeDictionary(def, col, recordStart, a)
 		case *array.Int32:
-			writers[i] = writeInt32(def, i, recordStart, a)
+			writers[i] = writeInt32(def, col, recordStart, a)
 		case *array.Uint64:
-			writers[i] = writeUint64(def, i, recordStart, a)
+			writers[i] = writeUint64(def, col, recordStart, a)
 		case *array.Int64:
-			writers[i] = writeInt64(def, i, recordStart, a)
+			writers[i] = writeInt64(def, col, recordStart, a)
+		case *array.Timestamp:
+			ts, err := writeTimestamp(def, col, recordStart, a, f)
+			if err != nil {
+				return err
+			}
+			writers[i] = ts
 		case *array.String:
-			writers[i] = writeString(def, i, recordStart, a)
+			writers[i] = writeString(def, col, recordStart, a)
 		case *array.Binary:
-			writers[i] = writeBinary(def, i, recordStart, a)
+			writers[i] = writeBinary(def, col, recordStart, a)
 		default:
-			writers[i] = writeGeneral(def, i, recordStart, a)
+			writers[i] = writeGeneral(def, col, recordStart, a)
 		}
 	}
 	rows := make([]parquet.Row, 1)
@@ -190,6 +226,36 @@ func writeInt32(def, column, startIdx int, a *array.Int32) arrowToParquet {
 	}
 }
 
+// writeTimestamp writes the timestamps of a to the timestamp column of field,
+// converting them to the unit of the column.
+func writeTimestamp(def, column, startIdx int, a *array.Timestamp, field parquet.Field) (arrowToParquet, error) {
+	dt, err := convert.ParquetNodeToType(field)
+	if err != nil {
+		return nil, err
+	}
+	typ, ok := dt.(*arrow.TimestampType)
+	if !ok {
+		return nil, fmt.Errorf("cannot write timestamps to column %s of type %s", field.Name(), dt)
+	}
+	from, to := a.DataType().(*arrow.TimestampType).Unit.Multiplier(), typ.Unit.Multiplier()
+	value := func(v arrow.Timestamp) int64 {
+		if from >= to {
+			return int64(v) * int64(from/to)
+		}
+		return int64(v) / int64(to/from)
+	}
+	return func(w parquet.Row, row int) parquet.Row {
+		if a.IsNull(row + startIdx) {
+			return append(w,
+				parquet.Value{}.Level(0, 0, column),
+			)
+		}
+		return append(w,
+			parquet.Int64Value(value(a.Value(row+startIdx))).Level(0, def, column),
+		)
+	}, nil
+}
+
 func writeBinary(def, column, startIdx int, a *array.Binary) arrowToParquet {
 	return func(w parquet.Row, row int) parquet.Row {
 		if a.IsNull(row + startIdx) {
@@ -311,9 +377,91 @@ func writeListOf(def, column, startIdx int, a *array.List, value func(idx int) p
 	}
 }
 
-func writeNull(column int) arrowToParquet {
+// writeMap writes the entries of a map to its key and value columns, which
+// are the leaf columns column and column+1. A map without entries is written
+// as a single value at the definition level of the map in both columns.
+func writeMap(def, column, startIdx int, a *array.Map) (arrowToParquet, error) {
+	key, err := byteArrayValues(a.Keys())
+	if err != nil {
+		return nil, fmt.Errorf("map keys: %w", err)
+	}
+	items := a.Items()
+	item, err := byteArrayValues(items)
+	if err != nil {
+		return nil, fmt.Errorf("map items: %w", err)
+	}
+	return func(w parquet.Row, row int) parquet.Row {
+		if a.IsNull(row + startIdx) {
+			return append(w,
+				parquet.Value{}.Level(0, 0, column),
+				parquet.Value{}.Level(0, 0, column+1),
+			)
+		}
+		start, end := a.ValueOffsets(row + startIdx)
+		if start == end {
+			return append(w,
+				parquet.Value{}.Level(0, def, column),
+				parquet.Value{}.Level(0, def, column+1),
+			)
+		}
+		for k := start; k < end; k++ {
+			rep := 0
+			if k != start {
+				rep = 1
+			}
+			w = append(w, key(int(k)).Level(rep, def+1, column))
+		}
+		for k := start; k < end; k++ {
+			rep := 0
+			if k != start {
+				rep = 1
+			}
+			if items.IsNull(int(k)) {
+				w = append(w, parquet.Value{}.Level(rep, def+1, column+1))
+				continue
+			}
+			w = append(w, item(int(k)).Level(rep, def+2, column+1))
+		}
+		return w
+	}, nil
+}
+
+// byteArrayValues returns a function that returns the value at an index of a
+// string, binary or dictionary array as a parquet byte array value.
+func byteArrayValues(a arrow.Array) (func(idx int) parquet.Value, error) {
+	switch e := a.(type) {
+	case *array.String:
+		return func(idx int) parquet.Value {
+			return parquet.ByteArrayValue([]byte(e.Value(idx)))
+		}, nil
+	case *array.Binary:
+		return func(idx int) parquet.Value {
+			return parquet.ByteArrayValue(e.Value(idx))
+		}, nil
+	case *array.Dictionary:
+		switch d := e.Dictionary().(type) {
+		case *array.Binary:
+			return func(idx int) parquet.Value {
+				return parquet.ByteArrayValue(d.Value(e.GetValueIndex(idx)))
+			}, nil
+		case *array.String:
+			return func(idx int) parquet.Value {
+				return parquet.ByteArrayValue([]byte(d.Value(e.GetValueIndex(idx))))
+			}, nil
+		default:
+			return nil, fmt.Errorf("dictionary not of expected type: %T", d)
+		}
+	default:
+		return nil, fmt.Errorf("not of expected type: %T", e)
+	}
+}
+
+func writeNull(column, leaves int) arrowToParquet {
 	return func(w parquet.Row, _ int) parquet.Row {
-		return append(w, parquet.Value{}.Level(0, 0, column))
+		for i := 0; i < leaves; i++ {
+			w = append(w, parquet.Value{}.Level(0, 0, column+i))
+		}
+		return w
 	}
 }
 
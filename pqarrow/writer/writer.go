@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"io"
 
+	"github.com/apache/arrow/go/v17/arrow"
 	"github.com/apache/arrow/go/v17/arrow/array"
 	"github.com/parquet-go/parquet-go"
 
@@ -113,6 +114,52 @@ func (w *uint64ValueWriter) Write(values []parquet.Value) {
 	}
 }
 
+type int32ValueWriter struct {
+	b *array.Int32Builder
+}
+
+func NewInt32ValueWriter(b builder.ColumnBuilder, numValues int) ValueWriter {
+	res := &int32ValueWriter{
+		b: b.(*array.Int32Builder),
+	}
+	res.b.Reserve(numValues)
+	return res
+}
+
+func (w *int32ValueWriter) Write(values []parquet.Value) {
+	for _, v := range values {
+		if v.IsNull() {
+			w.b.AppendNull()
+		} else {
+			w.b.Append(v.Int32())
+		}
+	}
+}
+
+type timestampValueWriter struct {
+	b *array.TimestampBuilder
+}
+
+// NewTimestampValueWriter writes the int64 values of a parquet timestamp
+// column. The unit of the builder must match the unit of the column.
+func NewTimestampValueWriter(b builder.ColumnBuilder, numValues int) ValueWriter {
+	res := &timestampValueWriter{
+		b: b.(*array.TimestampBuilder),
+	}
+	res.b.Reserve(numValues)
+	return res
+}
+
+func (w *timestampValueWriter) Write(values []parquet.Value) {
+	for _, v := range values {
+		if v.IsNull() {
+			w.b.AppendNull()
+		} else {
+			w.b.Append(arrow.Timestamp(v.Int64()))
+		}
+	}
+}
+
 type repeatedValueWriter struct {
 	b      *builder.ListBuilder
 	values ValueWriter
@@ -307,6 +354,30 @@ func (s *structWriter) findLeafBuilder(searchIndex, currentIndex int, builder ar
 			}
 			return 1, true
 		}
+	case *array.Int32Builder:
+		if searchIndex == currentIndex {
+			for _, v := range values {
+				switch v.IsNull() {
+				case true:
+					b.AppendNull()
+				default:
+					b.Append(v.Int32())
+				}
+			}
+			return 1, true
+		}
+	case *array.TimestampBuilder:
+		if searchIndex == currentIndex {
+			for _, v := range values {
+				switch v.IsNull() {
+				case true:
+					b.AppendNull()
+				default:
+					b.Append(arrow.Timestamp(v.Int64()))
+				}
+			}
+			return 1, true
+		}
 	case *array.Float64Builder:
 		if searchIndex == currentIndex {
 			for _, v := range values {
@@ -366,16 +437,97 @@ func (s *structWriter) findLeafBuilder(searchIndex, currentIndex int, builder ar
 
 type mapWriter struct {
 	b *array.MapBuilder
+	// def is the definition level of a map without entries. It is 1 for
+	// nullable maps, whose definition level 0 denotes a null map.
+	def int
+	// keyColumn is the index of the key column of the map, which is written
+	// before its value column. It is -1 until the first values are written.
+	keyColumn int
 }
 
-func NewMapWriter(b builder.ColumnBuilder, _ int) ValueWriter {
-	return &mapWriter{
-		b: b.(*array.MapBuilder),
+// NewMapWriter returns a writer for the key and value columns of a parquet
+// MAP node, e.g. a map storage layout of a v1alpha2 schema.
+func NewMapWriter(nullable bool) NewWriterFunc {
+	return func(b builder.ColumnBuilder, _ int) ValueWriter {
+		m := &mapWriter{
+			b:         b.(*array.MapBuilder),
+			keyColumn: -1,
+		}
+		if nullable {
+			m.def = 1
+		}
+		return m
 	}
 }
 
-func (m *mapWriter) Write(_ []parquet.Value) {
-	panic("not implemented")
+func (m *mapWriter) Write(values []parquet.Value) {
+	if len(values) == 0 {
+		return
+	}
+	if m.keyColumn == -1 {
+		m.keyColumn = values[0].Column()
+	}
+
+	if values[0].Column() == m.keyColumn {
+		// The key column determines the maps and their entries.
+		for _, v := range values {
+			if v.RepetitionLevel() == 0 {
+				if v.DefinitionLevel() < m.def {
+					m.b.AppendNull()
+					continue
+				}
+				m.b.Append(true)
+			}
+			if v.DefinitionLevel() > m.def {
+				appendParquetValue(m.b.KeyBuilder(), v)
+			}
+		}
+		return
+	}
+
+	for _, v := range values {
+		switch {
+		case v.DefinitionLevel() <= m.def:
+			// Null or empty map, there is no entry.
+		case v.DefinitionLevel() == m.def+1:
+			m.b.ItemBuilder().AppendNull()
+		default:
+			appendParquetValue(m.b.ItemBuilder(), v)
+		}
+	}
+}
+
+// appendParquetValue appends a parquet value to a builder of a primitive
+// arrow type.
+func appendParquetValue(b array.Builder, v parquet.Value) {
+	if v.IsNull() {
+		b.AppendNull()
+		return
+	}
+	switch b := b.(type) {
+	case *array.BinaryBuilder:
+		b.Append(v.ByteArray())
+	case *array.StringBuilder:
+		b.Append(string(v.ByteArray()))
+	case *array.BinaryDictionaryBuilder:
+		if err := b.Append(v.ByteArray()); err != nil {
+			panic("failed to append to dictionary")
+		}
+	case *array.Int64Builder:
+		b.Append(v.Int64())
+	case *array.Uint64Builder:
+		b.Append(v.Uint64())
+	case *array.Int32Builder:
+		b.Append(v.Int32())
+	case *array.TimestampBuilder:
+		b.Append(arrow.Timestamp(v.Int64()))
+	case *array.Float64Builder:
+		b.Append(v.Double())
+	case *array.BooleanBuilder:
+		b.Append(v.Boolean())
+	default:
+		panic(fmt.Sprintf("unsuported value type: %v", b))
+	}
 }
 
 type dictionaryValueWriter struct {
@@ -0,0 +1,121 @@
+package frostdb
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/apache/arrow/go/v17/arrow"
+	"github.com/apache/arrow/go/v17/arrow/array"
+	"github.com/apache/arrow/go/v17/arrow/compute"
+	"github.com/apache/arrow/go/v17/arrow/memory"
+
+	"github.com/polarsignals/frostdb/pqarrow/arrowutils"
+	"github.com/polarsignals/frostdb/query/logicalplan"
+	"github.com/polarsignals/frostdb/query/physicalplan"
+)
+
+// RowView is a view of a single row of a record, passed to the predicate of
+// Table.IteratorWithPredicate. A RowView is only valid for the duration of the
+// predicate call.
+type RowView struct {
+	record arrow.Record
+	row    int
+}
+
+// Schema returns the schema of the record the row belongs to.
+func (v RowView) Schema() *arrow.Schema {
+	return v.record.Schema()
+}
+
+// Value returns the value of the given column in the row, or nil if the value
+// is null or the record does not contain the column. Values are of the Go
+// type corresponding to the column type, e.g. int64 for Int64 columns and
+// string for String columns. Binary columns are returned as []byte, which
+// must not be retained after the predicate returns.
+func (v RowView) Value(column string) any {
+	indices := v.record.Schema().FieldIndices(column)
+	if len(indices) != 1 {
+		return nil
+	}
+	arr := v.record.Column(indices[0])
+	if arr.IsNull(v.row) {
+		return nil
+	}
+	return arr.GetOneForMarshal(v.row)
+}
+
+// IteratorWithPredicate iterates over the rows of the table at the given
+// transaction and calls callback with the rows for which predicate returns
+// true. It is an escape hatch for conditions that cannot be expressed as
+// logicalplan expressions. The filter passed with logicalplan.WithFilter, if
+// any, is applied to the rows first, so predicate is only called for rows
+// matching the filter.
+//
+// The predicate is evaluated row by row in Go, so iterating with a predicate
+// is considerably slower than filtering with expressions. Prefer expressing
+// as much of the condition as possible as a filter. Callback is called
+// sequentially and the records are released after callback returns.
+func (t *Table) IteratorWithPredicate(
+	ctx context.Context,
+	tx uint64,
+	pool memory.Allocator,
+	predicate func(RowView) bool,
+	callback func(ctx context.Context, r arrow.Record) error,
+	options ...logicalplan.Option,
+) error {
+	iterOpts := &logicalplan.IterOptions{}
+	for _, opt := range options {
+		opt(iterOpts)
+	}
+
+	output := &physicalplan.OutputPlan{}
+	output.SetNextCallback(func(ctx context.Context, r arrow.Record) error {
+		return filterRowsWithPredicate(ctx, pool, r, predicate, callback)
+	})
+	var plan physicalplan.PhysicalPlan = output
+	if iterOpts.Filter != nil {
+		filter, err := physicalplan.Filter(pool, t.tracer, iterOpts.Filter)
+		if err != nil {
+			return fmt.Errorf("invalid filter: %w", err)
+		}
+		filter.SetNext(output)
+		plan = filter
+	}
+	defer plan.Close()
+
+	return t.Iterator(ctx, tx, pool, []logicalplan.Callback{plan.Callback}, options...)
+}
+
+// filterRowsWithPredicate calls callback with the rows of r for which
+// predicate returns true, if any.
+func filterRowsWithPredicate(
+	ctx context.Context,
+	pool memory.Allocator,
+	r arrow.Record,
+	predicate func(RowView) bool,
+	callback func(ctx context.Context, r arrow.Record) error,
+) error {
+	indices := array.NewInt32Builder(pool)
+	defer indices.Release()
+	numRows := int(r.NumRows())
+	for i := 0; i < numRows; i++ {
+		if predicate(RowView{record: r, row: i}) {
+			indices.Append(int32(i))
+		}
+	}
+	switch indices.Len() {
+	case 0:
+		return nil
+	case numRows:
+		return callback(ctx, r)
+	}
+
+	arr := indices.NewInt32Array()
+	defer arr.Release()
+	filtered, err := arrowutils.Take(compute.WithAllocator(ctx, pool), r, arr)
+	if err != nil {
+		return err
+	}
+	defer filtered.Release()
+	return callback(ctx, filtered)
+}
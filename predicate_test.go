@@ -0,0 +1,64 @@
+package frostdb
+
+import (
+	"context"
+	"testing"
+
+	"github.com/apache/arrow/go/v17/arrow"
+	"github.com/apache/arrow/go/v17/arrow/array"
+	"github.com/apache/arrow/go/v17/arrow/memory"
+	"github.com/stretchr/testify/require"
+
+	"github.com/polarsignals/frostdb/dynparquet"
+	"github.com/polarsignals/frostdb/query/logicalplan"
+)
+
+func TestTableIteratorWithPredicate(t *testing.T) {
+	ctx := context.Background()
+	c, err := New(WithLogger(newTestLogger(t)))
+	require.NoError(t, err)
+	defer c.Close()
+	db, err := c.DB(ctx, "test")
+	require.NoError(t, err)
+	table, err := db.Table("test", NewTableConfig(dynparquet.SampleDefinition()))
+	require.NoError(t, err)
+
+	insertSampleRecords(ctx, t, table, 1, 2, 3, 4, 5, 6)
+
+	iterate := func(t *testing.T, predicate func(RowView) bool, options ...logicalplan.Option) []int64 {
+		t.Helper()
+		var timestamps []int64
+		require.NoError(t, table.View(ctx, func(ctx context.Context, tx uint64) error {
+			return table.IteratorWithPredicate(ctx, tx, memory.DefaultAllocator, predicate, func(_ context.Context, r arrow.Record) error {
+				idx := r.Schema().FieldIndices("timestamp")
+				require.Len(t, idx, 1)
+				timestamps = append(timestamps, r.Column(idx[0]).(*array.Int64).Int64Values()...)
+				return nil
+			}, options...)
+		}))
+		return timestamps
+	}
+
+	even := func(v RowView) bool {
+		ts, ok := v.Value("timestamp").(int64)
+		return ok && ts%2 == 0
+	}
+
+	t.Run("Predicate", func(t *testing.T) {
+		require.ElementsMatch(t, []int64{2, 4, 6}, iterate(t, even))
+	})
+
+	t.Run("Filter", func(t *testing.T) {
+		require.ElementsMatch(t, []int64{4, 6}, iterate(
+			t,
+			even,
+			logicalplan.WithFilter(logicalplan.Col("timestamp").Gt(logicalplan.Literal(int64(3)))),
+		))
+	})
+
+	t.Run("MissingColumn", func(t *testing.T) {
+		require.Empty(t, iterate(t, func(v RowView) bool {
+			return v.Value("missing") != nil
+		}))
+	})
+}
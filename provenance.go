@@ -0,0 +1,122 @@
+package frostdb
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/apache/arrow/go/v17/arrow"
+	"github.com/apache/arrow/go/v17/arrow/array"
+	"github.com/oklog/ulid/v2"
+
+	"github.com/polarsignals/frostdb/index"
+)
+
+// Schema metadata keys used to annotate records with their provenance when
+// reading with logicalplan.WithProvenance (or physicalplan.WithProvenance
+// through the query engine).
+const (
+	ProvenanceSourceKey = "frostdb.provenance.source"
+	ProvenanceBlockKey  = "frostdb.provenance.block"
+	ProvenanceTxKey     = "frostdb.provenance.tx"
+)
+
+// ProvenanceSource is the storage location type rows were read from.
+type ProvenanceSource string
+
+const (
+	// ProvenanceSourceMemory indicates rows were read from an in-memory
+	// (active or pending) table block.
+	ProvenanceSourceMemory ProvenanceSource = "memory"
+	// ProvenanceSourceBucket indicates rows were read from a block persisted
+	// to a data source such as an object storage bucket.
+	ProvenanceSourceBucket ProvenanceSource = "bucket"
+)
+
+// Provenance describes where the rows of a record were read from.
+type Provenance struct {
+	Source ProvenanceSource
+	// Block is the ULID of the table block the rows were read from. It is
+	// zero if the data source does not expose block IDs.
+	Block ulid.ULID
+	// Tx is the transaction of the part the rows were read from. It is only
+	// set for rows read from memory.
+	Tx uint64
+}
+
+// ProvenanceFromRecord returns the provenance a record was annotated with.
+// The second return value is false if the record has no provenance metadata.
+func ProvenanceFromRecord(r arrow.Record) (Provenance, bool) {
+	md := r.Schema().Metadata()
+	source, ok := md.GetValue(ProvenanceSourceKey)
+	if !ok {
+		return Provenance{}, false
+	}
+
+	p := Provenance{Source: ProvenanceSource(source)}
+	if block, ok := md.GetValue(ProvenanceBlockKey); ok {
+		if id, err := ulid.Parse(block); err == nil {
+			p.Block = id
+		}
+	}
+	if tx, ok := md.GetValue(ProvenanceTxKey); ok {
+		if v, err := strconv.ParseUint(tx, 10, 64); err == nil {
+			p.Tx = v
+		}
+	}
+	return p, true
+}
+
+// annotate returns a new record with the same columns as r and the
+// provenance added to its schema metadata.
+func (p Provenance) annotate(r arrow.Record) arrow.Record {
+	keys := []string{ProvenanceSourceKey}
+	values := []string{string(p.Source)}
+	if p.Block != (ulid.ULID{}) {
+		keys = append(keys, ProvenanceBlockKey)
+		values = append(values, p.Block.String())
+	}
+	if p.Source == ProvenanceSourceMemory {
+		keys = append(keys, ProvenanceTxKey)
+		values = append(values, strconv.FormatUint(p.Tx, 10))
+	}
+
+	md := arrow.NewMetadata(keys, values)
+	return array.NewRecord(arrow.NewSchema(r.Schema().Fields(), &md), r.Columns(), r.NumRows())
+}
+
+// provenanceValue wraps a record or row group sent from collectRowGroups to
+// the iterator with its provenance.
+type provenanceValue struct {
+	value      any
+	provenance Provenance
+}
+
+func (v provenanceValue) release() {
+	releaseScanValue(v.value)
+}
+
+// releaseScanValue releases a record or row group that was scanned but not
+// consumed.
+func releaseScanValue(v any) {
+	switch v := v.(type) {
+	case provenanceValue:
+		v.release()
+	case index.ReleaseableRowGroup:
+		v.Release()
+	case arrow.Record:
+		v.Release()
+	}
+}
+
+type blockIDContextKey struct{}
+
+// contextWithBlockID returns a context carrying the ID of the block that is
+// being scanned, so that scan callbacks can attribute row groups to blocks.
+func contextWithBlockID(ctx context.Context, id ulid.ULID) context.Context {
+	return context.WithValue(ctx, blockIDContextKey{}, id)
+}
+
+func blockIDFromContext(ctx context.Context) ulid.ULID {
+	id, _ := ctx.Value(blockIDContextKey{}).(ulid.ULID)
+	return id
+}
@@ -0,0 +1,65 @@
+package frostdb
+
+import (
+	"context"
+	"testing"
+
+	"github.com/apache/arrow/go/v17/arrow"
+	"github.com/apache/arrow/go/v17/arrow/memory"
+	"github.com/oklog/ulid/v2"
+	"github.com/stretchr/testify/require"
+	"github.com/thanos-io/objstore"
+
+	"github.com/polarsignals/frostdb/dynparquet"
+	"github.com/polarsignals/frostdb/query"
+	"github.com/polarsignals/frostdb/query/logicalplan"
+	"github.com/polarsignals/frostdb/query/physicalplan"
+)
+
+func TestProvenance(t *testing.T) {
+	ctx := context.Background()
+	options := []Option{WithReadWriteStorage(NewDefaultObjstoreBucket(objstore.NewInMemBucket()))}
+	// scan returns the provenance of all records returned by a query that
+	// projects the value column.
+	scan := func(t *testing.T, db *DB) []Provenance {
+		t.Helper()
+		var provenances []Provenance
+		engine := query.NewEngine(
+			memory.DefaultAllocator,
+			db.TableProvider(),
+			query.WithPhysicalplanOptions(physicalplan.WithProvenance()),
+		)
+		require.NoError(t, engine.ScanTable("test").
+			Project(logicalplan.Col("value")).
+			Execute(ctx, func(_ context.Context, r arrow.Record) error {
+				p, ok := ProvenanceFromRecord(r)
+				require.True(t, ok)
+				provenances = append(provenances, p)
+				return nil
+			}))
+		return provenances
+	}
+
+	c, db, table := openTestTable(t, options)
+	tx := insertSamples(t, table, dynparquet.NewTestSamples())
+
+	block := table.ActiveBlock().ulid
+	require.Equal(t, []Provenance{{
+		Source: ProvenanceSourceMemory,
+		Block:  block,
+		Tx:     tx,
+	}}, scan(t, db))
+
+	persistActiveBlock(t, table)
+	require.NoError(t, c.Close())
+	c, db, _ = openTestTable(t, options)
+	defer c.Close()
+
+	provenances := scan(t, db)
+	require.NotEmpty(t, provenances)
+	for _, p := range provenances {
+		require.Equal(t, ProvenanceSourceBucket, p.Source)
+		require.Equal(t, block, p.Block)
+		require.NotEqual(t, ulid.ULID{}, p.Block)
+	}
+}
@@ -0,0 +1,112 @@
+package query
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/polarsignals/frostdb/internal/limiter"
+)
+
+// ErrQueryQueueTimeout is returned by queries of an engine configured with
+// WithMaxConcurrentQueries that could not start within the queue timeout.
+var ErrQueryQueueTimeout = errors.New("query: timed out waiting for a query slot")
+
+// WithMaxConcurrentQueries bounds the number of queries the engine executes
+// concurrently to n. Excess queries wait in FIFO order until a running query
+// finishes, their context is done or the timeout configured with
+// WithQueueTimeout expires. A value <= 0 does not bound the number of queries.
+//
+// A query takes a single slot for its whole execution, including reading the
+// data versions of its tables for the result cache. Tables that limit reads
+// themselves, such as those of a column store configured with
+// frostdb.WithQueryConcurrency, still limit the admitted query, so a query
+// takes a slot of the engine before it takes a slot of the store.
+func WithMaxConcurrentQueries(n int) Option {
+	return func(e *LocalEngine) {
+		e.maxConcurrentQueries = n
+	}
+}
+
+// WithQueueTimeout sets the maximum duration queries wait for a slot if the
+// engine is configured with WithMaxConcurrentQueries. Queries that do not get
+// a slot in time fail with ErrQueryQueueTimeout. A timeout <= 0 rejects
+// queries right away if the maximum number of queries is running. By default,
+// queries wait until their context is done.
+func WithQueueTimeout(d time.Duration) Option {
+	return func(e *LocalEngine) {
+		e.queueTimeout = &d
+	}
+}
+
+// admissionQueue bounds the number of concurrently executing queries.
+// Queries that can't start right away are admitted in the order they arrived.
+type admissionQueue struct {
+	limiter *limiter.Limiter
+	// timeout is the maximum duration to wait for a slot, or nil to wait
+	// until the context of the query is done.
+	timeout *time.Duration
+}
+
+func newAdmissionQueue(maxConcurrentQueries int, timeout *time.Duration) *admissionQueue {
+	return &admissionQueue{
+		limiter: limiter.New(maxConcurrentQueries),
+		timeout: timeout,
+	}
+}
+
+// admit waits for a query slot unless the query was already admitted by the
+// engine, e.g. because it runs within another query. It returns the context to execute the
+// query with, marked as admitted, and a function that releases the slot.
+func (q *admissionQueue) admit(ctx context.Context) (context.Context, func(), error) {
+	if q == nil || q.limiter.Admitted(ctx) {
+		return ctx, func() {}, nil
+	}
+	release, err := q.acquire(ctx)
+	if err != nil {
+		return ctx, nil, err
+	}
+	return q.limiter.WithAdmitted(ctx), release, nil
+}
+
+// acquire waits for a query slot. The returned function releases the slot.
+func (q *admissionQueue) acquire(ctx context.Context) (func(), error) {
+	if release, ok := q.limiter.TryAcquire(); ok {
+		return release, nil
+	}
+	if q.timeout == nil {
+		return q.limiter.Acquire(ctx)
+	}
+	if *q.timeout <= 0 {
+		return nil, ErrQueryQueueTimeout
+	}
+
+	waitCtx, cancel := context.WithTimeout(ctx, *q.timeout)
+	defer cancel()
+	release, err := q.limiter.Acquire(waitCtx)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		return nil, ErrQueryQueueTimeout
+	}
+	return release, nil
+}
+
+// QueryStats are the numbers of running and queued queries of an engine.
+type QueryStats struct {
+	Running int
+	Queued  int
+}
+
+// QueryStats returns the numbers of running and queued queries. Both are 0 if
+// the engine does not bound the number of concurrent queries.
+func (e *LocalEngine) QueryStats() QueryStats {
+	if e.admission == nil {
+		return QueryStats{}
+	}
+	return QueryStats{
+		Running: e.admission.limiter.Running(),
+		Queued:  e.admission.limiter.Queued(),
+	}
+}
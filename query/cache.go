@@ -0,0 +1,437 @@
+package query
+
+import (
+	"container/list"
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/apache/arrow/go/v17/arrow"
+	"github.com/apache/arrow/go/v17/arrow/array"
+	"github.com/apache/arrow/go/v17/arrow/memory"
+	"github.com/apache/arrow/go/v17/arrow/util"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/polarsignals/frostdb/query/logicalplan"
+)
+
+// ResultCache caches the results of queries, so that identical queries of
+// unchanged data, e.g. of dashboards, are not executed again. Results are
+// keyed by the logical plan of the query and the version of the data of all
+// tables it reads, see logicalplan.DataVersioner. Queries reading tables that
+// are not versioned are not cached. The least recently used results are
+// evicted once the cached results exceed the cache's size.
+//
+// Since the options of the engine are not part of the key, a cache must only
+// be used by a single engine, see WithResultCache.
+type ResultCache struct {
+	maxBytes int64
+	ttl      time.Duration
+	now      func() time.Time
+	pool     memory.Allocator
+	reg      prometheus.Registerer
+
+	mtx     sync.Mutex
+	entries map[string]*list.Element
+	// lru orders the entries from most to least recently used.
+	lru   *list.List
+	bytes int64
+
+	hits      prometheus.Counter
+	misses    prometheus.Counter
+	evictions prometheus.Counter
+}
+
+type ResultCacheOption func(*ResultCache)
+
+// WithResultCacheRegistry registers the metrics of the cache with reg.
+func WithResultCacheRegistry(reg prometheus.Registerer) ResultCacheOption {
+	return func(c *ResultCache) {
+		c.reg = reg
+	}
+}
+
+// WithResultCacheTTL evicts results once they were cached for the given
+// duration, regardless of whether the data they were computed from changed.
+// A value <= 0, the default, keeps results until they are evicted to make
+// room for other results.
+func WithResultCacheTTL(ttl time.Duration) ResultCacheOption {
+	return func(c *ResultCache) {
+		c.ttl = ttl
+	}
+}
+
+// WithResultCacheNow sets the function returning the current time, which
+// the TTL of results is measured with, e.g. the Now method of the Clock of
+// the column store the cached queries read. By default, time.Now is used.
+func WithResultCacheNow(now func() time.Time) ResultCacheOption {
+	return func(c *ResultCache) {
+		c.now = now
+	}
+}
+
+// NewResultCache returns a cache holding results of up to maxBytes bytes in
+// total. Results larger than maxBytes are not cached.
+func NewResultCache(maxBytes int64, options ...ResultCacheOption) *ResultCache {
+	c := &ResultCache{
+		maxBytes: maxBytes,
+		now:      time.Now,
+		pool:     memory.NewGoAllocator(),
+		reg:      prometheus.NewRegistry(),
+		entries:  map[string]*list.Element{},
+		lru:      list.New(),
+	}
+	for _, option := range options {
+		option(c)
+	}
+
+	c.hits = promauto.With(c.reg).NewCounter(prometheus.CounterOpts{
+		Name: "frostdb_result_cache_hits_total",
+		Help: "Number of queries whose result was served from the result cache.",
+	})
+	c.misses = promauto.With(c.reg).NewCounter(prometheus.CounterOpts{
+		Name: "frostdb_result_cache_misses_total",
+		Help: "Number of cacheable queries whose result was not in the result cache.",
+	})
+	c.evictions = promauto.With(c.reg).NewCounter(prometheus.CounterOpts{
+		Name: "frostdb_result_cache_evictions_total",
+		Help: "Number of results evicted from the result cache.",
+	})
+	promauto.With(c.reg).NewGaugeFunc(prometheus.GaugeOpts{
+		Name: "frostdb_result_cache_size_bytes",
+		Help: "Total size of the results in the result cache.",
+	}, func() float64 {
+		c.mtx.Lock()
+		defer c.mtx.Unlock()
+		return float64(c.bytes)
+	})
+	promauto.With(c.reg).NewGaugeFunc(prometheus.GaugeOpts{
+		Name: "frostdb_result_cache_max_size_bytes",
+		Help: "Maximum total size of the results in the result cache.",
+	}, func() float64 {
+		return float64(c.maxBytes)
+	})
+	return c
+}
+
+// WithResultCache serves the results of queries from the given cache, see
+// ResultCache. Results of queries that are not served from the cache are
+// copied into it. A query may see data committed after the version it is
+// cached under, which is at most as recent as the data of later queries.
+func WithResultCache(cache *ResultCache) Option {
+	return func(e *LocalEngine) {
+		e.resultCache = cache
+	}
+}
+
+// Len returns the number of cached results.
+func (c *ResultCache) Len() int {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	return len(c.entries)
+}
+
+// Purge evicts all cached results.
+func (c *ResultCache) Purge() {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	for c.lru.Len() > 0 {
+		c.evictLocked(c.lru.Back())
+	}
+}
+
+type resultCacheEntry struct {
+	key     string
+	records []arrow.Record
+	bytes   int64
+	created time.Time
+}
+
+func (e *resultCacheEntry) release() {
+	for _, r := range e.records {
+		r.Release()
+	}
+	e.records = nil
+}
+
+// get returns the records of the result cached under key, which must be
+// released once they were used.
+func (c *ResultCache) get(key string) ([]arrow.Record, bool) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	elem, ok := c.entries[key]
+	if !ok {
+		c.misses.Inc()
+		return nil, false
+	}
+	entry := elem.Value.(*resultCacheEntry)
+	if c.ttl > 0 && c.now().Sub(entry.created) >= c.ttl {
+		c.evictLocked(elem)
+		c.misses.Inc()
+		return nil, false
+	}
+	c.hits.Inc()
+	c.lru.MoveToFront(elem)
+	for _, r := range entry.records {
+		r.Retain()
+	}
+	return entry.records, true
+}
+
+// put caches the entry, evicting the least recently used results to make
+// room for it.
+func (c *ResultCache) put(entry *resultCacheEntry) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	if elem, ok := c.entries[entry.key]; ok {
+		// A concurrent query cached the result first.
+		c.evictLocked(elem)
+	}
+	for c.bytes+entry.bytes > c.maxBytes && c.lru.Len() > 0 {
+		c.evictLocked(c.lru.Back())
+		c.evictions.Inc()
+	}
+	entry.created = c.now()
+	c.entries[entry.key] = c.lru.PushFront(entry)
+	c.bytes += entry.bytes
+}
+
+func (c *ResultCache) evictLocked(elem *list.Element) {
+	entry := c.lru.Remove(elem).(*resultCacheEntry)
+	delete(c.entries, entry.key)
+	c.bytes -= entry.bytes
+	entry.release()
+}
+
+// resultCollector copies the records of a query's result so that the result
+// can be cached once the query succeeded. Records may be added concurrently.
+type resultCollector struct {
+	cache *ResultCache
+
+	mtx   sync.Mutex
+	entry *resultCacheEntry
+	// tooLarge is set once the result exceeds the size of the cache, in which
+	// case it is not cached.
+	tooLarge bool
+}
+
+func (c *resultCollector) add(r arrow.Record) error {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	if c.tooLarge {
+		return nil
+	}
+	size := util.TotalRecordSize(r)
+	if c.entry.bytes+size > c.cache.maxBytes {
+		c.tooLarge = true
+		c.entry.release()
+		return nil
+	}
+	copied, err := copyRecord(c.cache.pool, r)
+	if err != nil {
+		return fmt.Errorf("copy record for result cache: %w", err)
+	}
+	c.entry.records = append(c.entry.records, copied)
+	c.entry.bytes += size
+	return nil
+}
+
+// done caches the collected result if the query succeeded, and releases it
+// otherwise.
+func (c *resultCollector) done(err error) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	if err != nil || c.tooLarge {
+		c.entry.release()
+		return
+	}
+	c.cache.put(c.entry)
+}
+
+// copyRecord copies the record into memory allocated by mem, so that it does
+// not hold on to the memory of the query that produced it.
+func copyRecord(mem memory.Allocator, r arrow.Record) (arrow.Record, error) {
+	cols := make([]arrow.Array, 0, r.NumCols())
+	defer func() {
+		for _, col := range cols {
+			col.Release()
+		}
+	}()
+	for _, col := range r.Columns() {
+		copied, err := array.Concatenate([]arrow.Array{col}, mem)
+		if err != nil {
+			return nil, err
+		}
+		cols = append(cols, copied)
+	}
+	return array.NewRecord(r.Schema(), cols, r.NumRows()), nil
+}
+
+// executeCached executes the query, serving its result from the result cache
+// if it was cached.
+func (b LocalQueryBuilder) executeCached(ctx context.Context, callback func(ctx context.Context, r arrow.Record) error) error {
+	key, ok, err := b.resultCacheKey(ctx)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return b.execute(ctx, callback)
+	}
+
+	if records, ok := b.resultCache.get(key); ok {
+		trace.SpanFromContext(ctx).SetAttributes(attribute.Bool("resultCacheHit", true))
+		defer func() {
+			for _, r := range records {
+				r.Release()
+			}
+		}()
+		for _, r := range records {
+			if err := callback(ctx, r); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	collector := &resultCollector{
+		cache: b.resultCache,
+		entry: &resultCacheEntry{key: key},
+	}
+	err = b.execute(ctx, func(ctx context.Context, r arrow.Record) error {
+		if err := collector.add(r); err != nil {
+			return err
+		}
+		return callback(ctx, r)
+	})
+	collector.done(err)
+	return err
+}
+
+// resultCacheKey returns the key the result of the query is cached under,
+// which consists of the logical plan and the data versions of all tables the
+// query reads. It returns false if any of the tables is not versioned.
+func (b LocalQueryBuilder) resultCacheKey(ctx context.Context) (string, bool, error) {
+	plan, err := b.planBuilder.Build()
+	if err != nil {
+		return "", false, err
+	}
+
+	var key strings.Builder
+	var scans []scannedTable
+	writePlanKey(&key, plan, &scans)
+	for _, scan := range scans {
+		table, err := scan.provider.GetTable(scan.name)
+		if err != nil {
+			return "", false, err
+		}
+		versioner, ok := table.(logicalplan.DataVersioner)
+		if !ok {
+			return "", false, nil
+		}
+		var version string
+		if err := table.View(ctx, func(_ context.Context, tx uint64) error {
+			version, ok = versioner.DataVersion(tx)
+			return nil
+		}); err != nil {
+			return "", false, err
+		}
+		if !ok {
+			return "", false, nil
+		}
+		fmt.Fprintf(&key, "\nversion %s %s", scan.name, version)
+	}
+	return key.String(), true, nil
+}
+
+// scannedTable is a table read by a query.
+type scannedTable struct {
+	provider logicalplan.TableProvider
+	name     string
+}
+
+// writePlanKey writes a representation of the plan to key that is equal for
+// plans producing equal results from equal data, and appends the tables the
+// plan reads to scans.
+func writePlanKey(key *strings.Builder, plan *logicalplan.LogicalPlan, scans *[]scannedTable) {
+	for ; plan != nil; plan = plan.Input {
+		switch {
+		case plan.TableScan != nil:
+			key.WriteString("TableScan " + plan.TableScan.TableName)
+			*scans = append(*scans, scannedTable{plan.TableScan.TableProvider, plan.TableScan.TableName})
+		case plan.SchemaScan != nil:
+			key.WriteString("SchemaScan " + plan.SchemaScan.TableName)
+			*scans = append(*scans, scannedTable{plan.SchemaScan.TableProvider, plan.SchemaScan.TableName})
+		case plan.Filter != nil:
+			key.WriteString("Filter")
+			writeExprKeys(key, plan.Filter.Expr)
+		case plan.Distinct != nil:
+			key.WriteString("Distinct")
+			writeExprKeys(key, plan.Distinct.Exprs...)
+		case plan.Projection != nil:
+			key.WriteString("Projection")
+			writeExprKeys(key, plan.Projection.Exprs...)
+		case plan.Aggregation != nil:
+			key.WriteString("Aggregation")
+			for _, agg := range plan.Aggregation.AggExprs {
+				writeExprKeys(key, agg)
+			}
+			key.WriteString(" Group")
+			writeExprKeys(key, plan.Aggregation.GroupExprs...)
+		case plan.Limit != nil:
+			key.WriteString("Limit")
+			writeExprKeys(key, plan.Limit.Expr, plan.Limit.Offset)
+		case plan.Sample != nil:
+			key.WriteString("Sample")
+			writeExprKeys(key, plan.Sample.Expr, plan.Sample.Limit)
+		case plan.OrderBy != nil:
+			key.WriteString("OrderBy")
+			writeExprKeys(key, plan.OrderBy.Exprs...)
+		case plan.Join != nil:
+			key.WriteString("Join")
+			writeExprKeys(key, plan.Join.On...)
+			key.WriteString(" Right(")
+			writePlanKey(key, plan.Join.Right, scans)
+			key.WriteString(")")
+		}
+		key.WriteString("\n")
+	}
+}
+
+// writeExprKeys writes the expressions to key. Since expressions print
+// literals without their type, the types of all literals are written too.
+func writeExprKeys(key *strings.Builder, exprs ...logicalplan.Expr) {
+	for _, expr := range exprs {
+		if expr == nil {
+			key.WriteString(" <nil>")
+			continue
+		}
+		fmt.Fprintf(key, " %T(%s", expr, expr.String())
+		expr.Accept(literalTypeWriter{key: key})
+		key.WriteString(")")
+	}
+}
+
+// literalTypeWriter writes the types of the literals of an expression.
+type literalTypeWriter struct {
+	key *strings.Builder
+}
+
+func (w literalTypeWriter) PreVisit(expr logicalplan.Expr) bool {
+	if lit, ok := expr.(*logicalplan.LiteralExpr); ok && lit.Value != nil {
+		w.key.WriteString(" " + lit.Value.DataType().String())
+	}
+	return true
+}
+
+func (w literalTypeWriter) Visit(_ logicalplan.Expr) bool {
+	return true
+}
+
+func (w literalTypeWriter) PostVisit(_ logicalplan.Expr) bool {
+	return true
+}
@@ -2,10 +2,12 @@ package query
 
 import (
 	"context"
+	"time"
 
 	"go.opentelemetry.io/otel/trace/noop"
 
 	"github.com/apache/arrow/go/v17/arrow"
+	"github.com/apache/arrow/go/v17/arrow/array"
 	"github.com/apache/arrow/go/v17/arrow/memory"
 	"go.opentelemetry.io/otel/trace"
 
@@ -15,13 +17,18 @@ import (
 
 type Builder interface {
 	Aggregate(aggExpr []*logicalplan.AggregationFunction, groupExprs []logicalplan.Expr) Builder
+	AggregateExprs(aggExprs []logicalplan.Expr, groupExprs []logicalplan.Expr) Builder
 	Filter(expr logicalplan.Expr) Builder
 	Distinct(expr ...logicalplan.Expr) Builder
 	Project(projections ...logicalplan.Expr) Builder
 	Limit(expr logicalplan.Expr) Builder
+	Offset(expr logicalplan.Expr) Builder
+	OrderBy(exprs ...logicalplan.Expr) Builder
 	Execute(ctx context.Context, callback func(ctx context.Context, r arrow.Record) error) error
 	Explain(ctx context.Context) (string, error)
+	Count(ctx context.Context) (int64, error)
 	Sample(size, limitInBytes int64) Builder
+	Join(other Builder, on ...logicalplan.Expr) Builder
 }
 
 type LocalEngine struct {
@@ -29,6 +36,14 @@ type LocalEngine struct {
 	tracer        trace.Tracer
 	tableProvider logicalplan.TableProvider
 	execOpts      []physicalplan.Option
+
+	maxConcurrentQueries int
+	queueTimeout         *time.Duration
+	// admission bounds the number of concurrent queries if
+	// maxConcurrentQueries is positive.
+	admission *admissionQueue
+	// resultCache, if set, caches the results of queries.
+	resultCache *ResultCache
 }
 
 type Option func(*LocalEngine)
@@ -41,10 +56,34 @@ func WithTracer(tracer trace.Tracer) Option {
 
 func WithPhysicalplanOptions(opts ...physicalplan.Option) Option {
 	return func(e *LocalEngine) {
-		e.execOpts = opts
+		e.execOpts = append(e.execOpts, opts...)
 	}
 }
 
+// WithAllocationTracking enables the allocation tracking debug mode for all
+// queries of the engine. Queries that leak memory return a
+// *physicalplan.LeakError that reports which operators allocated the leaked
+// memory. See physicalplan.WithAllocationTracking.
+func WithAllocationTracking() Option {
+	return WithPhysicalplanOptions(physicalplan.WithAllocationTracking())
+}
+
+// WithTimeOrderedResults makes all queries of the engine return their results
+// in ascending order of the given time column, merging the results of all
+// scanned blocks, so that consumers can process results incrementally in
+// time order. See physicalplan.WithTimeOrderedResults.
+func WithTimeOrderedResults(column string) Option {
+	return WithPhysicalplanOptions(physicalplan.WithTimeOrderedResults(column))
+}
+
+// WithDictionaryEncoding makes all queries of the engine produce dictionary
+// encoded columns for binary columns, such as labels, which reduces the memory
+// used by queries over columns with few distinct values. See
+// physicalplan.WithDictionaryEncoding.
+func WithDictionaryEncoding() Option {
+	return WithPhysicalplanOptions(physicalplan.WithDictionaryEncoding())
+}
+
 func NewEngine(
 	pool memory.Allocator,
 	tableProvider logicalplan.TableProvider,
@@ -59,15 +98,25 @@ func NewEngine(
 	for _, option := range options {
 		option(e)
 	}
+	if e.maxConcurrentQueries > 0 {
+		e.admission = newAdmissionQueue(e.maxConcurrentQueries, e.queueTimeout)
+	}
 
 	return e
 }
 
+// TableProvider returns the table provider the engine reads tables from.
+func (e *LocalEngine) TableProvider() logicalplan.TableProvider {
+	return e.tableProvider
+}
+
 type LocalQueryBuilder struct {
 	pool        memory.Allocator
 	tracer      trace.Tracer
 	planBuilder logicalplan.Builder
 	execOpts    []physicalplan.Option
+	admission   *admissionQueue
+	resultCache *ResultCache
 }
 
 func (e *LocalEngine) ScanTable(name string) Builder {
@@ -76,6 +125,8 @@ func (e *LocalEngine) ScanTable(name string) Builder {
 		tracer:      e.tracer,
 		planBuilder: (&logicalplan.Builder{}).Scan(e.tableProvider, name),
 		execOpts:    e.execOpts,
+		admission:   e.admission,
+		resultCache: e.resultCache,
 	}
 }
 
@@ -85,6 +136,8 @@ func (e *LocalEngine) ScanSchema(name string) Builder {
 		tracer:      e.tracer,
 		planBuilder: (&logicalplan.Builder{}).ScanSchema(e.tableProvider, name),
 		execOpts:    e.execOpts,
+		admission:   e.admission,
+		resultCache: e.resultCache,
 	}
 }
 
@@ -97,6 +150,24 @@ func (b LocalQueryBuilder) Aggregate(
 		tracer:      b.tracer,
 		planBuilder: b.planBuilder.Aggregate(aggExpr, groupExprs),
 		execOpts:    b.execOpts,
+		admission:   b.admission,
+		resultCache: b.resultCache,
+	}
+}
+
+// AggregateExprs is like Aggregate, but the aggregations may be aliased, see
+// logicalplan.Builder.AggregateExprs.
+func (b LocalQueryBuilder) AggregateExprs(
+	aggExprs []logicalplan.Expr,
+	groupExprs []logicalplan.Expr,
+) Builder {
+	return LocalQueryBuilder{
+		pool:        b.pool,
+		tracer:      b.tracer,
+		planBuilder: b.planBuilder.AggregateExprs(aggExprs, groupExprs),
+		execOpts:    b.execOpts,
+		admission:   b.admission,
+		resultCache: b.resultCache,
 	}
 }
 
@@ -108,9 +179,14 @@ func (b LocalQueryBuilder) Filter(
 		tracer:      b.tracer,
 		planBuilder: b.planBuilder.Filter(expr),
 		execOpts:    b.execOpts,
+		admission:   b.admission,
+		resultCache: b.resultCache,
 	}
 }
 
+// Distinct returns the distinct rows of the given expressions. Distincts whose
+// state exceeds the memory limit configured with physicalplan.WithDistinctSpill
+// spill to disk.
 func (b LocalQueryBuilder) Distinct(
 	expr ...logicalplan.Expr,
 ) Builder {
@@ -119,6 +195,8 @@ func (b LocalQueryBuilder) Distinct(
 		tracer:      b.tracer,
 		planBuilder: b.planBuilder.Distinct(expr...),
 		execOpts:    b.execOpts,
+		admission:   b.admission,
+		resultCache: b.resultCache,
 	}
 }
 
@@ -130,6 +208,8 @@ func (b LocalQueryBuilder) Project(
 		tracer:      b.tracer,
 		planBuilder: b.planBuilder.Project(projections...),
 		execOpts:    b.execOpts,
+		admission:   b.admission,
+		resultCache: b.resultCache,
 	}
 }
 
@@ -141,6 +221,41 @@ func (b LocalQueryBuilder) Limit(
 		tracer:      b.tracer,
 		planBuilder: b.planBuilder.Limit(expr),
 		execOpts:    b.execOpts,
+		admission:   b.admission,
+		resultCache: b.resultCache,
+	}
+}
+
+// Offset skips the given number of rows. An offset directly following or
+// preceding a limit is applied before the limit.
+func (b LocalQueryBuilder) Offset(
+	expr logicalplan.Expr,
+) Builder {
+	return LocalQueryBuilder{
+		pool:        b.pool,
+		tracer:      b.tracer,
+		planBuilder: b.planBuilder.Offset(expr),
+		execOpts:    b.execOpts,
+		admission:   b.admission,
+		resultCache: b.resultCache,
+	}
+}
+
+// OrderBy sorts the result by the given expressions. Expressions can be
+// wrapped in logicalplan.Asc or logicalplan.Desc to specify the sort
+// direction, the default being ascending. Nulls are sorted last. Sorts that
+// exceed the memory limit configured with physicalplan.WithSortSpill spill to
+// disk.
+func (b LocalQueryBuilder) OrderBy(
+	exprs ...logicalplan.Expr,
+) Builder {
+	return LocalQueryBuilder{
+		pool:        b.pool,
+		tracer:      b.tracer,
+		planBuilder: b.planBuilder.OrderBy(exprs...),
+		execOpts:    b.execOpts,
+		admission:   b.admission,
+		resultCache: b.resultCache,
 	}
 }
 
@@ -152,6 +267,34 @@ func (b LocalQueryBuilder) Sample(
 		tracer:      b.tracer,
 		planBuilder: b.planBuilder.Sample(logicalplan.Literal(size), logicalplan.Literal(limitInBytes)),
 		execOpts:    b.execOpts,
+		admission:   b.admission,
+		resultCache: b.resultCache,
+	}
+}
+
+// Join joins the rows of the query with the rows of other, which must be a
+// query of the same engine, e.g. to combine the samples of one table with the
+// metadata stored in another. Rows are joined on the given expressions, which
+// are either columns that both queries produce or equalities of a column of
+// the query with a column of other, e.g. Col("id").Eq(Col("sample_id")). Only
+// rows whose join columns are equal and not null are returned (an inner join).
+// The rows of other are held in memory while the query executes, so other
+// should be the smaller of the two.
+func (b LocalQueryBuilder) Join(
+	other Builder,
+	on ...logicalplan.Expr,
+) Builder {
+	var right logicalplan.Builder
+	if o, ok := other.(LocalQueryBuilder); ok {
+		right = o.planBuilder
+	}
+	return LocalQueryBuilder{
+		pool:        b.pool,
+		tracer:      b.tracer,
+		planBuilder: b.planBuilder.Join(right, on...),
+		execOpts:    b.execOpts,
+		admission:   b.admission,
+		resultCache: b.resultCache,
 	}
 }
 
@@ -159,6 +302,24 @@ func (b LocalQueryBuilder) Execute(ctx context.Context, callback func(ctx contex
 	ctx, span := b.tracer.Start(ctx, "LocalQueryBuilder/Execute")
 	defer span.End()
 
+	ctx, release, err := b.admission.admit(ctx)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	if b.resultCache != nil {
+		return b.executeCached(ctx, callback)
+	}
+	return b.execute(ctx, callback)
+}
+
+// execute executes the query. The query must have been admitted.
+func (b LocalQueryBuilder) execute(ctx context.Context, callback func(ctx context.Context, r arrow.Record) error) error {
+	if ok, err := b.executeCount(ctx, callback); ok || err != nil {
+		return err
+	}
+
 	phyPlan, err := b.buildPhysical(ctx)
 	if err != nil {
 		return err
@@ -167,6 +328,43 @@ func (b LocalQueryBuilder) Execute(ctx context.Context, callback func(ctx contex
 	return phyPlan.Execute(ctx, b.pool, callback)
 }
 
+// Count returns the number of rows the query produces. Counting the rows of a
+// table scan without any further operators is answered from the table's
+// metadata if the table implements logicalplan.RowCounter, so no columns are
+// read. Other queries are executed and their rows counted. Either way, the
+// count is admitted like any other query, see WithMaxConcurrentQueries.
+func (b LocalQueryBuilder) Count(ctx context.Context) (int64, error) {
+	ctx, span := b.tracer.Start(ctx, "LocalQueryBuilder/Count")
+	defer span.End()
+
+	ctx, release, err := b.admission.admit(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer release()
+
+	logicalPlan, err := b.planBuilder.Build()
+	if err != nil {
+		return 0, err
+	}
+
+	if logicalPlan.Input == nil && logicalPlan.TableScan != nil {
+		count, ok, err := b.rowCount(ctx, logicalPlan.TableScan)
+		if ok || err != nil {
+			return count, err
+		}
+	}
+
+	var count int64
+	if err := b.Execute(ctx, func(_ context.Context, r arrow.Record) error {
+		count += r.NumRows()
+		return nil
+	}); err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
 func (b LocalQueryBuilder) Explain(ctx context.Context) (string, error) {
 	phyPlan, err := b.buildPhysical(ctx)
 	if err != nil {
@@ -175,6 +373,87 @@ func (b LocalQueryBuilder) Explain(ctx context.Context) (string, error) {
 	return phyPlan.DrawString(), nil
 }
 
+// rowCount returns the number of rows of the scanned table if the table
+// implements logicalplan.RowCounter.
+func (b LocalQueryBuilder) rowCount(ctx context.Context, scan *logicalplan.TableScan) (int64, bool, error) {
+	table, err := scan.TableProvider.GetTable(scan.TableName)
+	if err != nil {
+		return 0, false, err
+	}
+	counter, ok := table.(logicalplan.RowCounter)
+	if !ok {
+		return 0, false, nil
+	}
+
+	var count int64
+	if err := table.View(ctx, func(ctx context.Context, tx uint64) error {
+		count, err = counter.RowCount(
+			ctx,
+			tx,
+			logicalplan.WithReadMode(physicalplan.ReadModeFromOptions(b.execOpts...)),
+		)
+		return err
+	}); err != nil {
+		return 0, false, err
+	}
+	return count, true, nil
+}
+
+// executeCount answers a count of a concrete column of a table scan without
+// any filters or grouping from the metadata of the table if the table
+// implements logicalplan.RowCounter, so no columns are read. Counts include
+// nulls, so the count of any concrete column is the number of rows of the
+// table. It returns false if the query was not answered.
+func (b LocalQueryBuilder) executeCount(ctx context.Context, callback func(ctx context.Context, r arrow.Record) error) (bool, error) {
+	logicalPlan, err := b.planBuilder.Build()
+	if err != nil {
+		return false, err
+	}
+
+	agg := logicalPlan.Aggregation
+	if agg == nil || len(agg.GroupExprs) > 0 || len(agg.AggExprs) != 1 ||
+		agg.AggExprs[0].Func != logicalplan.AggFuncCount {
+		return false, nil
+	}
+	col, ok := agg.AggExprs[0].Expr.(*logicalplan.Column)
+	if !ok {
+		return false, nil
+	}
+	scan := logicalPlan.Input
+	if scan == nil || scan.Input != nil || scan.TableScan == nil {
+		return false, nil
+	}
+	schema := scan.InputSchema()
+	if schema == nil {
+		return false, nil
+	}
+	if def, ok := schema.ColumnByName(col.ColumnName); !ok || def.Dynamic {
+		return false, nil
+	}
+
+	count, ok, err := b.rowCount(ctx, scan.TableScan)
+	if !ok || err != nil {
+		return false, err
+	}
+	if count == 0 {
+		// Aggregations don't produce any rows for empty inputs.
+		return true, nil
+	}
+
+	builder := array.NewInt64Builder(b.pool)
+	defer builder.Release()
+	builder.Append(count)
+	arr := builder.NewArray()
+	defer arr.Release()
+	r := array.NewRecord(
+		arrow.NewSchema([]arrow.Field{{Name: agg.AggExprs[0].Name(), Type: arrow.PrimitiveTypes.Int64}}, nil),
+		[]arrow.Array{arr},
+		1,
+	)
+	defer r.Release()
+	return true, callback(ctx, r)
+}
+
 func (b LocalQueryBuilder) buildPhysical(ctx context.Context) (*physicalplan.OutputPlan, error) {
 	logicalPlan, err := b.planBuilder.Build()
 	if err != nil {
@@ -2,7 +2,11 @@ package query
 
 import (
 	"context"
+	"fmt"
+	"math"
+	"sort"
 	"testing"
+	"time"
 
 	"github.com/apache/arrow/go/v17/arrow"
 	"github.com/apache/arrow/go/v17/arrow/array"
@@ -12,6 +16,7 @@ import (
 	"github.com/polarsignals/frostdb/dynparquet"
 	schemapb "github.com/polarsignals/frostdb/gen/proto/go/frostdb/schema/v1alpha1"
 	"github.com/polarsignals/frostdb/query/logicalplan"
+	"github.com/polarsignals/frostdb/query/physicalplan"
 )
 
 func TestUniqueAggregation(t *testing.T) {
@@ -133,6 +138,267 @@ func TestAndAggregation(t *testing.T) {
 	require.True(t, ran)
 }
 
+func TestAggregateExprs(t *testing.T) {
+	mem := memory.NewCheckedAllocator(memory.NewGoAllocator())
+	defer mem.AssertSize(t, 0)
+
+	schema, err := dynparquet.SchemaFromDefinition(&schemapb.Schema{
+		Name: "test",
+		Columns: []*schemapb.Column{{
+			Name: "value",
+			StorageLayout: &schemapb.StorageLayout{
+				Type: schemapb.StorageLayout_TYPE_INT64,
+			},
+		}, {
+			Name: "timestamp",
+			StorageLayout: &schemapb.StorageLayout{
+				Type: schemapb.StorageLayout_TYPE_INT64,
+			},
+		}},
+	})
+	require.NoError(t, err)
+
+	rb := array.NewRecordBuilder(mem, arrow.NewSchema([]arrow.Field{{
+		Name: "value",
+		Type: arrow.PrimitiveTypes.Int64,
+	}, {
+		Name: "timestamp",
+		Type: arrow.PrimitiveTypes.Int64,
+	}}, nil))
+	defer rb.Release()
+
+	rb.Field(0).(*array.Int64Builder).AppendValues([]int64{1, 2, 3, 4}, nil)
+	rb.Field(1).(*array.Int64Builder).AppendValues([]int64{1, 1, 3, 3}, nil)
+
+	r := rb.NewRecord()
+	defer r.Release()
+
+	engine := NewEngine(mem, &FakeTableProvider{
+		Tables: map[string]logicalplan.TableReader{
+			"test": &FakeTableReader{
+				FrostdbSchema: schema,
+				Records:       []arrow.Record{r},
+			},
+		},
+	})
+	value := logicalplan.Col("value")
+	ran := false
+	err = engine.ScanTable("test").
+		AggregateExprs(
+			[]logicalplan.Expr{
+				logicalplan.Sum(value).Alias("s"),
+				logicalplan.Max(value).Alias("m"),
+				logicalplan.Avg(value).Alias("a"),
+				logicalplan.Count(value),
+			},
+			[]logicalplan.Expr{logicalplan.Col("timestamp")},
+		).
+		Execute(context.Background(), func(_ context.Context, r arrow.Record) error {
+			column := func(name string) []int64 {
+				indices := r.Schema().FieldIndices(name)
+				require.Len(t, indices, 1, name)
+				return r.Column(indices[0]).(*array.Int64).Int64Values()
+			}
+			require.Equal(t, 5, int(r.NumCols()))
+			require.Equal(t, []int64{1, 3}, column("timestamp"))
+			require.Equal(t, []int64{3, 7}, column("s"))
+			require.Equal(t, []int64{2, 4}, column("m"))
+			require.Equal(t, []int64{1, 3}, column("a"))
+			require.Equal(t, []int64{2, 2}, column("count(value)"))
+			ran = true
+			return nil
+		})
+	require.NoError(t, err)
+	require.True(t, ran)
+
+	// Only aggregation functions can be aggregated.
+	_, err = engine.ScanTable("test").
+		AggregateExprs(
+			[]logicalplan.Expr{value.Alias("v")},
+			nil,
+		).
+		Explain(context.Background())
+	require.ErrorContains(t, err, "is not an aggregation function")
+}
+
+func TestStatisticalAggregations(t *testing.T) {
+	mem := memory.NewCheckedAllocator(memory.NewGoAllocator())
+	defer mem.AssertSize(t, 0)
+
+	schema, err := dynparquet.SchemaFromDefinition(&schemapb.Schema{
+		Name: "test",
+		Columns: []*schemapb.Column{{
+			Name: "value",
+			StorageLayout: &schemapb.StorageLayout{
+				Type: schemapb.StorageLayout_TYPE_INT64,
+			},
+		}, {
+			Name: "timestamp",
+			StorageLayout: &schemapb.StorageLayout{
+				Type: schemapb.StorageLayout_TYPE_INT64,
+			},
+		}},
+	})
+	require.NoError(t, err)
+
+	rb := array.NewRecordBuilder(mem, arrow.NewSchema([]arrow.Field{{
+		Name: "value",
+		Type: arrow.PrimitiveTypes.Int64,
+	}, {
+		Name: "timestamp",
+		Type: arrow.PrimitiveTypes.Int64,
+	}}, nil))
+	defer rb.Release()
+
+	rb.Field(0).(*array.Int64Builder).AppendValues([]int64{1, 2, 3, 4, 2, 4, 4, 4, 5, 5, 7, 9}, nil)
+	rb.Field(1).(*array.Int64Builder).AppendValues([]int64{1, 1, 1, 1, 2, 2, 2, 2, 2, 2, 2, 2}, nil)
+
+	r := rb.NewRecord()
+	defer r.Release()
+
+	engine := NewEngine(mem, &FakeTableProvider{
+		Tables: map[string]logicalplan.TableReader{
+			"test": &FakeTableReader{
+				FrostdbSchema: schema,
+				Records:       []arrow.Record{r},
+			},
+		},
+	})
+	value := logicalplan.Col("value")
+	type result struct {
+		stddev, variance, median, max float64
+	}
+	results := map[int64]result{}
+	err = engine.ScanTable("test").
+		Aggregate(
+			[]*logicalplan.AggregationFunction{
+				logicalplan.Stddev(value),
+				logicalplan.Variance(value),
+				logicalplan.Quantile(value, 0.5),
+				logicalplan.Quantile(value, 1),
+			},
+			[]logicalplan.Expr{logicalplan.Col("timestamp")},
+		).
+		Execute(context.Background(), func(_ context.Context, r arrow.Record) error {
+			column := func(name string) arrow.Array {
+				indices := r.Schema().FieldIndices(name)
+				require.Len(t, indices, 1, name)
+				return r.Column(indices[0])
+			}
+			timestamps := column("timestamp").(*array.Int64)
+			for i := 0; i < timestamps.Len(); i++ {
+				results[timestamps.Value(i)] = result{
+					stddev:   column("stddev(value)").(*array.Float64).Value(i),
+					variance: column("variance(value)").(*array.Float64).Value(i),
+					median:   column("quantile(0.5, value)").(*array.Float64).Value(i),
+					max:      column("quantile(1, value)").(*array.Float64).Value(i),
+				}
+			}
+			return nil
+		})
+	require.NoError(t, err)
+	expected := map[int64]result{
+		1: {stddev: math.Sqrt(1.25), variance: 1.25, median: 2.5, max: 4},
+		2: {stddev: 2, variance: 4, median: 4.5, max: 9},
+	}
+	require.Len(t, results, len(expected))
+	for timestamp, e := range expected {
+		res := results[timestamp]
+		require.InDelta(t, e.stddev, res.stddev, 1e-9)
+		require.InDelta(t, e.variance, res.variance, 1e-9)
+		require.InDelta(t, e.median, res.median, 1e-9)
+		require.InDelta(t, e.max, res.max, 1e-9)
+	}
+
+	_, err = engine.ScanTable("test").
+		Aggregate(
+			[]*logicalplan.AggregationFunction{logicalplan.Quantile(value, 2)},
+			nil,
+		).
+		Explain(context.Background())
+	require.ErrorContains(t, err, "is not between 0 and 1")
+}
+
+func TestWindowAggregation(t *testing.T) {
+	mem := memory.NewCheckedAllocator(memory.NewGoAllocator())
+	defer mem.AssertSize(t, 0)
+
+	schema, err := dynparquet.SchemaFromDefinition(&schemapb.Schema{
+		Name: "test",
+		Columns: []*schemapb.Column{{
+			Name: "value",
+			StorageLayout: &schemapb.StorageLayout{
+				Type: schemapb.StorageLayout_TYPE_INT64,
+			},
+		}, {
+			Name: "timestamp",
+			StorageLayout: &schemapb.StorageLayout{
+				Type: schemapb.StorageLayout_TYPE_INT64,
+			},
+		}},
+	})
+	require.NoError(t, err)
+
+	rb := array.NewRecordBuilder(mem, arrow.NewSchema([]arrow.Field{{
+		Name: "value",
+		Type: arrow.PrimitiveTypes.Int64,
+	}, {
+		Name:     "timestamp",
+		Type:     arrow.PrimitiveTypes.Int64,
+		Nullable: true,
+	}}, nil))
+	defer rb.Release()
+
+	rb.Field(0).(*array.Int64Builder).AppendValues([]int64{1, 2, 3, 4, 5, 100}, nil)
+	rb.Field(1).(*array.Int64Builder).AppendValues([]int64{0, 1000, 2000, 3000, 0, 0}, []bool{true, true, true, true, true, false})
+
+	r := rb.NewRecord()
+	defer r.Release()
+
+	engine := NewEngine(mem, &FakeTableProvider{
+		Tables: map[string]logicalplan.TableReader{
+			"test": &FakeTableReader{
+				FrostdbSchema: schema,
+				Records:       []arrow.Record{r},
+			},
+		},
+	})
+
+	// Windows of two seconds every second contain each timestamp twice, rows
+	// with a null timestamp are not part of any window.
+	window := logicalplan.Window(logicalplan.Col("timestamp"), 2*time.Second, time.Second)
+	sums := map[int64]int64{}
+	err = engine.ScanTable("test").
+		Aggregate(
+			[]*logicalplan.AggregationFunction{logicalplan.Sum(logicalplan.Col("value"))},
+			[]logicalplan.Expr{window},
+		).
+		Execute(context.Background(), func(_ context.Context, r arrow.Record) error {
+			starts := r.Column(r.Schema().FieldIndices(window.Name())[0]).(*array.Int64)
+			values := r.Column(r.Schema().FieldIndices("sum(value)")[0]).(*array.Int64)
+			for i := 0; i < starts.Len(); i++ {
+				sums[starts.Value(i)] = values.Value(i)
+			}
+			return nil
+		})
+	require.NoError(t, err)
+	require.Equal(t, map[int64]int64{
+		-1000: 6,
+		0:     8,
+		1000:  5,
+		2000:  7,
+		3000:  4,
+	}, sums)
+
+	_, err = engine.ScanTable("test").
+		Aggregate(
+			[]*logicalplan.AggregationFunction{logicalplan.Sum(logicalplan.Col("value"))},
+			[]logicalplan.Expr{logicalplan.Window(logicalplan.Col("timestamp"), time.Second, 0)},
+		).
+		Explain(context.Background())
+	require.ErrorContains(t, err, "must be positive")
+}
+
 func TestIfProjection(t *testing.T) {
 	mem := memory.NewCheckedAllocator(memory.NewGoAllocator())
 	defer mem.AssertSize(t, 0)
@@ -237,3 +503,392 @@ func TestIsNullProjection(t *testing.T) {
 	require.NoError(t, err)
 	require.True(t, ran)
 }
+
+// cancelableTableReader is a FakeTableReader that stops iterating once its
+// context is canceled and counts the records it read.
+type cancelableTableReader struct {
+	*FakeTableReader
+	read int
+}
+
+func (r *cancelableTableReader) Iterator(
+	ctx context.Context,
+	_ uint64,
+	_ memory.Allocator,
+	callbacks []logicalplan.Callback,
+	_ ...logicalplan.Option,
+) error {
+	for i, rec := range r.Records {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		r.read++
+		if err := callbacks[i%len(callbacks)](ctx, rec); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func TestLimitOffset(t *testing.T) {
+	mem := memory.NewCheckedAllocator(memory.NewGoAllocator())
+	defer mem.AssertSize(t, 0)
+
+	schema, err := dynparquet.SchemaFromDefinition(&schemapb.Schema{
+		Name: "test",
+		Columns: []*schemapb.Column{{
+			Name: "value",
+			StorageLayout: &schemapb.StorageLayout{
+				Type: schemapb.StorageLayout_TYPE_INT64,
+			},
+		}},
+	})
+	require.NoError(t, err)
+
+	const numRecords = 100
+	records := make([]arrow.Record, 0, numRecords)
+	for i := 0; i < numRecords; i++ {
+		b := array.NewInt64Builder(mem)
+		for j := 0; j < 10; j++ {
+			b.Append(int64(i*10 + j))
+		}
+		arr := b.NewArray()
+		records = append(records, array.NewRecord(
+			arrow.NewSchema([]arrow.Field{{Name: "value", Type: arrow.PrimitiveTypes.Int64}}, nil),
+			[]arrow.Array{arr},
+			10,
+		))
+		arr.Release()
+		b.Release()
+	}
+	defer func() {
+		for _, r := range records {
+			r.Release()
+		}
+	}()
+
+	reader := &cancelableTableReader{
+		FakeTableReader: &FakeTableReader{FrostdbSchema: schema, Records: records},
+	}
+	var values []int64
+	require.NoError(t, NewEngine(mem, &FakeTableProvider{
+		Tables: map[string]logicalplan.TableReader{"test": reader},
+	}).ScanTable("test").
+		Limit(logicalplan.Literal(int64(15))).
+		Offset(logicalplan.Literal(int64(5))).
+		Execute(context.Background(), func(_ context.Context, r arrow.Record) error {
+			values = append(values, r.Column(0).(*array.Int64).Int64Values()...)
+			return nil
+		}))
+
+	expected := make([]int64, 0, 15)
+	for i := int64(5); i < 20; i++ {
+		expected = append(expected, i)
+	}
+	require.Equal(t, expected, values)
+	// The scan stops once the limit is reached.
+	require.Less(t, reader.read, numRecords)
+}
+
+func TestAllocationTracking(t *testing.T) {
+	mem := memory.NewCheckedAllocator(memory.NewGoAllocator())
+	defer mem.AssertSize(t, 0)
+
+	schema, err := dynparquet.SchemaFromDefinition(&schemapb.Schema{
+		Name: "test",
+		Columns: []*schemapb.Column{{
+			Name: "example",
+			StorageLayout: &schemapb.StorageLayout{
+				Type: schemapb.StorageLayout_TYPE_INT64,
+			},
+		}},
+	})
+	require.NoError(t, err)
+
+	rb := array.NewRecordBuilder(mem, arrow.NewSchema([]arrow.Field{{
+		Name: "example",
+		Type: arrow.PrimitiveTypes.Int64,
+	}}, nil))
+	defer rb.Release()
+	rb.Field(0).(*array.Int64Builder).AppendValues([]int64{1, 2, 3}, nil)
+	r := rb.NewRecord()
+	defer r.Release()
+
+	engine := NewEngine(mem, &FakeTableProvider{
+		Tables: map[string]logicalplan.TableReader{
+			"test": &FakeTableReader{
+				FrostdbSchema: schema,
+				Records:       []arrow.Record{r},
+			},
+		},
+	}, WithAllocationTracking())
+	query := engine.ScanTable("test").
+		Project(logicalplan.Add(logicalplan.Col("example"), logicalplan.Literal(int64(1))).Alias("plus_one"))
+
+	// Queries that release all memory succeed.
+	require.NoError(t, query.Execute(context.Background(), func(_ context.Context, _ arrow.Record) error {
+		return nil
+	}))
+
+	// The leak is attributed to the operator that allocated the retained
+	// record.
+	var leaked []arrow.Record
+	err = query.Execute(context.Background(), func(_ context.Context, r arrow.Record) error {
+		r.Retain()
+		leaked = append(leaked, r)
+		return nil
+	})
+	leakErr := &physicalplan.LeakError{}
+	require.ErrorAs(t, err, &leakErr)
+	require.Len(t, leakErr.Leaks, 1)
+	require.Equal(t, "Projection", leakErr.Leaks[0].Operator)
+	require.Greater(t, leakErr.Leaks[0].Bytes, 0)
+	for _, r := range leaked {
+		r.Release()
+	}
+}
+
+func TestJoin(t *testing.T) {
+	mem := memory.NewCheckedAllocator(memory.NewGoAllocator())
+	defer mem.AssertSize(t, 0)
+
+	int64Layout := &schemapb.StorageLayout{Type: schemapb.StorageLayout_TYPE_INT64, Nullable: true}
+	stringLayout := &schemapb.StorageLayout{Type: schemapb.StorageLayout_TYPE_STRING}
+	samplesSchema, err := dynparquet.SchemaFromDefinition(&schemapb.Schema{
+		Name: "samples",
+		Columns: []*schemapb.Column{
+			{Name: "id", StorageLayout: int64Layout},
+			{Name: "value", StorageLayout: int64Layout},
+		},
+	})
+	require.NoError(t, err)
+	metadataSchema, err := dynparquet.SchemaFromDefinition(&schemapb.Schema{
+		Name: "metadata",
+		Columns: []*schemapb.Column{
+			{Name: "sample_id", StorageLayout: int64Layout},
+			{Name: "name", StorageLayout: stringLayout},
+		},
+	})
+	require.NoError(t, err)
+
+	samplesBuilder := array.NewRecordBuilder(mem, arrow.NewSchema([]arrow.Field{
+		{Name: "id", Type: arrow.PrimitiveTypes.Int64, Nullable: true},
+		{Name: "value", Type: arrow.PrimitiveTypes.Int64, Nullable: true},
+	}, nil))
+	defer samplesBuilder.Release()
+	samplesBuilder.Field(0).(*array.Int64Builder).AppendValues([]int64{1, 2, 3, 0}, []bool{true, true, true, false})
+	samplesBuilder.Field(1).(*array.Int64Builder).AppendValues([]int64{10, 20, 30, 40}, nil)
+	samples := samplesBuilder.NewRecord()
+	defer samples.Release()
+
+	metadataBuilder := array.NewRecordBuilder(mem, arrow.NewSchema([]arrow.Field{
+		{Name: "sample_id", Type: arrow.PrimitiveTypes.Int64, Nullable: true},
+		{Name: "name", Type: arrow.BinaryTypes.String},
+	}, nil))
+	defer metadataBuilder.Release()
+	metadataBuilder.Field(0).(*array.Int64Builder).AppendValues([]int64{1, 2, 2, 4, 0}, []bool{true, true, true, true, false})
+	metadataBuilder.Field(1).(*array.StringBuilder).AppendValues([]string{"a", "b", "c", "d", "e"}, nil)
+	metadata := metadataBuilder.NewRecord()
+	defer metadata.Release()
+
+	engine := NewEngine(mem, &FakeTableProvider{
+		Tables: map[string]logicalplan.TableReader{
+			"samples":  &FakeTableReader{FrostdbSchema: samplesSchema, Records: []arrow.Record{samples}},
+			"metadata": &FakeTableReader{FrostdbSchema: metadataSchema, Records: []arrow.Record{metadata}},
+		},
+	}, WithAllocationTracking())
+
+	// rows returns the joined rows as "id value sample_id name".
+	rows := func(t *testing.T, query Builder) []string {
+		t.Helper()
+		var res []string
+		require.NoError(t, query.Execute(context.Background(), func(_ context.Context, r arrow.Record) error {
+			require.Equal(t, []string{"id", "value", "sample_id", "name"}, fieldNames(r))
+			for i := 0; i < int(r.NumRows()); i++ {
+				res = append(res, fmt.Sprintf(
+					"%s %s %s %s",
+					r.Column(0).ValueStr(i), r.Column(1).ValueStr(i), r.Column(2).ValueStr(i), r.Column(3).ValueStr(i),
+				))
+			}
+			return nil
+		}))
+		sort.Strings(res)
+		return res
+	}
+
+	require.Equal(t, []string{
+		"1 10 1 a",
+		"2 20 2 b",
+		"2 20 2 c",
+	}, rows(t, engine.ScanTable("samples").Join(
+		engine.ScanTable("metadata"),
+		logicalplan.Col("id").Eq(logicalplan.Col("sample_id")),
+	)))
+
+	// Operators on top of the join see the columns of both inputs.
+	require.Equal(t, []string{
+		"2 20 2 b",
+	}, rows(t, engine.ScanTable("samples").Join(
+		engine.ScanTable("metadata").Filter(logicalplan.Col("name").NotEq(logicalplan.Literal("c"))),
+		logicalplan.Col("id").Eq(logicalplan.Col("sample_id")),
+	).Filter(logicalplan.Col("value").Gt(logicalplan.Literal(int64(10))))))
+
+	// Columns joined by name are only returned once.
+	var names [][]string
+	require.NoError(t, engine.ScanTable("samples").
+		Join(engine.ScanTable("samples").Project(logicalplan.Col("id")), logicalplan.Col("id")).
+		Execute(context.Background(), func(_ context.Context, r arrow.Record) error {
+			names = append(names, fieldNames(r))
+			return nil
+		}))
+	require.Equal(t, [][]string{{"id", "value"}}, names)
+
+	// Other columns must not exist in both inputs.
+	require.ErrorContains(t, engine.ScanTable("samples").
+		Join(
+			engine.ScanTable("samples").Project(logicalplan.Col("id"), logicalplan.Col("id").Alias("value")),
+			logicalplan.Col("id"),
+		).
+		Execute(context.Background(), func(_ context.Context, _ arrow.Record) error {
+			return nil
+		}), "exists in both inputs")
+
+	// Joins require column equalities.
+	require.Error(t, engine.ScanTable("samples").
+		Join(engine.ScanTable("metadata"), logicalplan.Col("id").Gt(logicalplan.Col("sample_id"))).
+		Execute(context.Background(), func(_ context.Context, _ arrow.Record) error {
+			return nil
+		}))
+}
+
+func TestMaxConcurrentQueries(t *testing.T) {
+	mem := memory.NewGoAllocator()
+	schema, err := dynparquet.SchemaFromDefinition(&schemapb.Schema{
+		Name: "test",
+		Columns: []*schemapb.Column{{
+			Name: "example",
+			StorageLayout: &schemapb.StorageLayout{
+				Type: schemapb.StorageLayout_TYPE_INT64,
+			},
+		}},
+	})
+	require.NoError(t, err)
+
+	rb := array.NewRecordBuilder(mem, arrow.NewSchema([]arrow.Field{{
+		Name: "example",
+		Type: arrow.PrimitiveTypes.Int64,
+	}}, nil))
+	defer rb.Release()
+	rb.Field(0).(*array.Int64Builder).AppendValues([]int64{1, 2, 3}, nil)
+	r := rb.NewRecord()
+	defer r.Release()
+
+	newEngine := func(options ...Option) *LocalEngine {
+		return NewEngine(mem, &FakeTableProvider{
+			Tables: map[string]logicalplan.TableReader{
+				"test": rowCountingTableReader{&FakeTableReader{
+					FrostdbSchema: schema,
+					Records:       []arrow.Record{r},
+				}},
+			},
+		}, append([]Option{WithMaxConcurrentQueries(1)}, options...)...)
+	}
+	noop := func(_ context.Context, _ arrow.Record) error { return nil }
+
+	// runBlocking starts a query that holds the only query slot until the
+	// returned function is called.
+	runBlocking := func(engine *LocalEngine) func() {
+		started := make(chan struct{})
+		unblock := make(chan struct{})
+		done := make(chan error)
+		go func() {
+			once := false
+			done <- engine.ScanTable("test").Execute(context.Background(), func(_ context.Context, _ arrow.Record) error {
+				if !once {
+					once = true
+					close(started)
+					<-unblock
+				}
+				return nil
+			})
+		}()
+		<-started
+		return func() {
+			close(unblock)
+			require.NoError(t, <-done)
+		}
+	}
+
+	t.Run("Reject", func(t *testing.T) {
+		engine := newEngine(WithQueueTimeout(0))
+		finish := runBlocking(engine)
+		require.Equal(t, QueryStats{Running: 1}, engine.QueryStats())
+		require.ErrorIs(t, engine.ScanTable("test").Execute(context.Background(), noop), ErrQueryQueueTimeout)
+		// Counts answered from metadata are admitted like other queries.
+		_, err := engine.ScanTable("test").Count(context.Background())
+		require.ErrorIs(t, err, ErrQueryQueueTimeout)
+		finish()
+		require.Equal(t, QueryStats{}, engine.QueryStats())
+		require.NoError(t, engine.ScanTable("test").Execute(context.Background(), noop))
+	})
+
+	t.Run("Timeout", func(t *testing.T) {
+		engine := newEngine(WithQueueTimeout(10 * time.Millisecond))
+		finish := runBlocking(engine)
+		defer finish()
+		require.ErrorIs(t, engine.ScanTable("test").Execute(context.Background(), noop), ErrQueryQueueTimeout)
+	})
+
+	t.Run("Queue", func(t *testing.T) {
+		engine := newEngine()
+		finish := runBlocking(engine)
+		done := make(chan error)
+		go func() {
+			done <- engine.ScanTable("test").Execute(context.Background(), noop)
+		}()
+		require.Eventually(t, func() bool {
+			return engine.QueryStats() == QueryStats{Running: 1, Queued: 1}
+		}, time.Second, time.Millisecond)
+		finish()
+		require.NoError(t, <-done)
+	})
+
+	t.Run("Canceled", func(t *testing.T) {
+		engine := newEngine()
+		finish := runBlocking(engine)
+		defer finish()
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		require.ErrorIs(t, engine.ScanTable("test").Execute(ctx, noop), context.Canceled)
+	})
+
+	t.Run("Nested", func(t *testing.T) {
+		// Queries executed by a running query do not take another slot.
+		engine := newEngine(WithQueueTimeout(0))
+		require.NoError(t, engine.ScanTable("test").Execute(context.Background(), func(ctx context.Context, _ arrow.Record) error {
+			return engine.ScanTable("test").Execute(ctx, noop)
+		}))
+	})
+}
+
+// rowCountingTableReader is a FakeTableReader that counts its rows from
+// metadata.
+type rowCountingTableReader struct {
+	*FakeTableReader
+}
+
+func (r rowCountingTableReader) RowCount(_ context.Context, _ uint64, _ ...logicalplan.Option) (int64, error) {
+	var n int64
+	for _, rec := range r.Records {
+		n += rec.NumRows()
+	}
+	return n, nil
+}
+
+func fieldNames(r arrow.Record) []string {
+	names := make([]string, 0, r.NumCols())
+	for _, f := range r.Schema().Fields() {
+		names = append(names, f.Name)
+	}
+	return names
+}
@@ -0,0 +1,88 @@
+package expr
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/apache/arrow/go/v17/arrow/scalar"
+	"github.com/parquet-go/parquet-go"
+
+	"github.com/polarsignals/frostdb/pqarrow"
+	"github.com/polarsignals/frostdb/query/logicalplan"
+)
+
+// AnyColumnExpr prunes particulates for expressions matching any concrete
+// column of a dynamic column. Only the concrete columns present in the
+// particulate are consulted, using the bloom filters and min/max statistics of
+// their column chunks for equality and their dictionaries for regular
+// expressions, so a particulate is ruled out if none of its concrete columns
+// may contain the value.
+type AnyColumnExpr struct {
+	Column string
+	// Exactly one of Value and Regexp is set.
+	Value  parquet.Value
+	Regexp *RegexpExpr
+}
+
+func anyColumnExpr(expr *logicalplan.AnyColumnExpr) (TrueNegativeFilter, error) {
+	literal, ok := expr.Value.(*logicalplan.LiteralExpr)
+	if !ok {
+		// Let the execution engine evaluate the expression.
+		return &AlwaysTrueFilter{}, nil
+	}
+
+	e := &AnyColumnExpr{Column: expr.Column.ColumnName}
+	switch expr.Op {
+	case logicalplan.OpEq:
+		value, err := pqarrow.ArrowScalarToParquetValue(literal.Value)
+		if err != nil {
+			return nil, err
+		}
+		e.Value = value
+	case logicalplan.OpRegexMatch:
+		s, ok := literal.Value.(*scalar.String)
+		if !ok {
+			return nil, fmt.Errorf("regex must be a string, got %T", literal.Value)
+		}
+		re, err := regexp.Compile(string(s.Data()))
+		if err != nil {
+			return nil, err
+		}
+		e.Regexp = &RegexpExpr{Right: re}
+	default:
+		return &AlwaysTrueFilter{}, nil
+	}
+	return e, nil
+}
+
+func (e *AnyColumnExpr) Eval(p Particulate, ignoreMissingCols bool) (bool, error) {
+	prefix := e.Column + "."
+	found := false
+	for i, field := range p.Schema().Fields() {
+		if !strings.HasPrefix(field.Name(), prefix) {
+			continue
+		}
+		found = true
+
+		chunk := p.ColumnChunks()[i]
+		var (
+			mayMatch bool
+			err      error
+		)
+		if e.Regexp != nil {
+			mayMatch, err = e.Regexp.evalDictionary(chunk)
+		} else {
+			mayMatch, err = BinaryScalarOperation(chunk, e.Value, logicalplan.OpEq)
+		}
+		if err != nil {
+			return true, err
+		}
+		if mayMatch {
+			return true, nil
+		}
+	}
+	// Without any concrete column no row can match, unless the particulate
+	// only contains a subset of the columns.
+	return !found && ignoreMissingCols, nil
+}
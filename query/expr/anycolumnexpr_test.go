@@ -0,0 +1,58 @@
+package expr
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/parquet-go/parquet-go"
+	"github.com/stretchr/testify/require"
+
+	"github.com/polarsignals/frostdb/query/logicalplan"
+)
+
+func TestAnyColumnExpr(t *testing.T) {
+	type row struct {
+		Container string `parquet:"labels.container,dict"`
+		Namespace string `parquet:"labels.namespace,dict"`
+		Value     int64  `parquet:"value"`
+	}
+
+	buf := bytes.NewBuffer(nil)
+	w := parquet.NewGenericWriter[row](buf, parquet.BloomFilters(
+		parquet.SplitBlockFilter(10, "labels.container"),
+		parquet.SplitBlockFilter(10, "labels.namespace"),
+	))
+	_, err := w.Write([]row{
+		{Container: "api", Namespace: "dev", Value: 1},
+		{Container: "db", Namespace: "prod", Value: 3},
+	})
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+
+	f, err := parquet.OpenFile(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	require.NoError(t, err)
+	rg := f.RowGroups()[0]
+
+	labels := logicalplan.DynCol("labels")
+	for _, tc := range []struct {
+		name     string
+		expr     logicalplan.Expr
+		expected bool
+	}{
+		{name: "Eq", expr: labels.AnyEq(logicalplan.Literal("prod")), expected: true},
+		// "cache" is within the min/max of labels.container, only its bloom
+		// filter excludes it.
+		{name: "EqBloomFilter", expr: labels.AnyEq(logicalplan.Literal("cache")), expected: false},
+		{name: "RegexMatch", expr: labels.AnyRegexMatch("^pro"), expected: true},
+		{name: "RegexNoMatch", expr: labels.AnyRegexMatch("^staging$"), expected: false},
+		{name: "MissingDynamicColumn", expr: logicalplan.DynCol("missing").AnyEq(logicalplan.Literal("api")), expected: false},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			filter, err := BooleanExpr(tc.expr)
+			require.NoError(t, err)
+			mayMatch, err := filter.Eval(rg, false)
+			require.NoError(t, err)
+			require.Equal(t, tc.expected, mayMatch)
+		})
+	}
+}
@@ -0,0 +1,100 @@
+package expr
+
+import (
+	"github.com/parquet-go/parquet-go"
+
+	"github.com/polarsignals/frostdb/query/logicalplan"
+)
+
+// BinaryColumnExpr compares two columns of the same row.
+type BinaryColumnExpr struct {
+	Left  *ColumnRef
+	Op    logicalplan.Op
+	Right *ColumnRef
+}
+
+func (e BinaryColumnExpr) Eval(p Particulate, ignoreMissingCols bool) (bool, error) {
+	leftData, leftExists, err := e.Left.Column(p)
+	if err != nil {
+		return false, err
+	}
+
+	rightData, rightExists, err := e.Right.Column(p)
+	if err != nil {
+		return false, err
+	}
+
+	if !leftExists || !rightExists {
+		// A missing column only holds nulls and nothing compares to NULL.
+		return ignoreMissingCols, nil
+	}
+
+	return BinaryColumnOperation(leftData, rightData, e.Op)
+}
+
+// BinaryColumnOperation applies the given operator between the values of two
+// column chunks of the same row group. If BinaryColumnOperation returns true,
+// it means that the operator may be satisfied by at least one row of the row
+// group. If it returns false, it means that the operator will definitely not
+// be satisfied by any row.
+func BinaryColumnOperation(left, right parquet.ColumnChunk, operator logicalplan.Op) (bool, error) {
+	leftColumnIndex, err := left.ColumnIndex()
+	if err != nil {
+		return true, err
+	}
+	rightColumnIndex, err := right.ColumnIndex()
+	if err != nil {
+		return true, err
+	}
+
+	if NullCount(leftColumnIndex) == left.NumValues() || NullCount(rightColumnIndex) == right.NumValues() {
+		// Every row has a null on at least one side, which never compares.
+		return false, nil
+	}
+
+	t := left.Type()
+	if !sameOrdering(t, right.Type()) {
+		// The min/max values of the columns are not comparable with each
+		// other, let the execution engine evaluate the expression.
+		return true, nil
+	}
+
+	leftMin, leftMax := columnMin(t, leftColumnIndex), columnMax(t, leftColumnIndex)
+	rightMin, rightMax := columnMin(t, rightColumnIndex), columnMax(t, rightColumnIndex)
+	if leftMin.IsNull() || leftMax.IsNull() || rightMin.IsNull() || rightMax.IsNull() {
+		return true, nil
+	}
+
+	switch operator {
+	case logicalplan.OpEq:
+		// The value ranges of both columns must overlap.
+		return compareValues(t, leftMin, rightMax) <= 0 && compareValues(t, rightMin, leftMax) <= 0, nil
+	case logicalplan.OpNotEq:
+		// Only if both columns hold the same single value no row can match.
+		return compareValues(t, leftMin, leftMax) != 0 ||
+			compareValues(t, rightMin, rightMax) != 0 ||
+			compareValues(t, leftMin, rightMin) != 0, nil
+	case logicalplan.OpLt:
+		return compareValues(t, leftMin, rightMax) < 0, nil
+	case logicalplan.OpLtEq:
+		return compareValues(t, leftMin, rightMax) <= 0, nil
+	case logicalplan.OpGt:
+		return compareValues(t, leftMax, rightMin) > 0, nil
+	case logicalplan.OpGtEq:
+		return compareValues(t, leftMax, rightMin) >= 0, nil
+	default:
+		return true, nil
+	}
+}
+
+// sameOrdering returns whether values of the two types are ordered the same
+// way, so that values of one can be compared with values of the other.
+func sameOrdering(a, b parquet.Type) bool {
+	if a.Kind() != b.Kind() {
+		return false
+	}
+	aInt, bInt := a.LogicalType(), b.LogicalType()
+	aSigned := aInt == nil || aInt.Integer == nil || aInt.Integer.IsSigned
+	bSigned := bInt == nil || bInt.Integer == nil || bInt.Integer.IsSigned
+	return aSigned == bSigned
+}
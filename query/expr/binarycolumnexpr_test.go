@@ -0,0 +1,66 @@
+package expr
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/parquet-go/parquet-go"
+	"github.com/stretchr/testify/require"
+
+	"github.com/polarsignals/frostdb/query/logicalplan"
+)
+
+func TestBinaryColumnExpr(t *testing.T) {
+	type row struct {
+		Value    int64   `parquet:"value"`
+		Baseline int64   `parquet:"baseline"`
+		Constant int64   `parquet:"constant"`
+		Float    float64 `parquet:"float"`
+		Name     string  `parquet:"name"`
+		Other    string  `parquet:"other"`
+		Null     *int64  `parquet:"null,optional"`
+	}
+
+	buf := bytes.NewBuffer(nil)
+	w := parquet.NewGenericWriter[row](buf)
+	_, err := w.Write([]row{
+		{Value: 1, Baseline: 10, Constant: 7, Float: 1, Name: "a", Other: "x"},
+		{Value: 5, Baseline: 20, Constant: 7, Float: 2, Name: "c", Other: "y"},
+	})
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+
+	f, err := parquet.OpenFile(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	require.NoError(t, err)
+	rg := f.RowGroups()[0]
+
+	for _, tc := range []struct {
+		name     string
+		expr     logicalplan.Expr
+		expected bool
+	}{
+		{name: "Gt", expr: logicalplan.Col("value").Gt(logicalplan.Col("baseline")), expected: false},
+		{name: "GtEq", expr: logicalplan.Col("baseline").GtEq(logicalplan.Col("value")), expected: true},
+		{name: "Lt", expr: logicalplan.Col("value").Lt(logicalplan.Col("baseline")), expected: true},
+		{name: "LtEq", expr: logicalplan.Col("baseline").LtEq(logicalplan.Col("value")), expected: false},
+		{name: "EqDisjoint", expr: logicalplan.Col("value").Eq(logicalplan.Col("baseline")), expected: false},
+		{name: "EqOutOfRange", expr: logicalplan.Col("value").Eq(logicalplan.Col("constant")), expected: false},
+		{name: "EqSelf", expr: logicalplan.Col("value").Eq(logicalplan.Col("value")), expected: true},
+		{name: "NotEq", expr: logicalplan.Col("value").NotEq(logicalplan.Col("constant")), expected: true},
+		{name: "NotEqConstant", expr: logicalplan.Col("constant").NotEq(logicalplan.Col("constant")), expected: false},
+		{name: "StringGt", expr: logicalplan.Col("name").Gt(logicalplan.Col("other")), expected: false},
+		{name: "StringLt", expr: logicalplan.Col("name").Lt(logicalplan.Col("other")), expected: true},
+		// Columns of different types are not pruned.
+		{name: "DifferentTypes", expr: logicalplan.Col("value").Gt(logicalplan.Col("float")), expected: true},
+		{name: "AllNulls", expr: logicalplan.Col("value").Eq(logicalplan.Col("null")), expected: false},
+		{name: "MissingColumn", expr: logicalplan.Col("value").Eq(logicalplan.Col("missing")), expected: false},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			filter, err := BooleanExpr(tc.expr)
+			require.NoError(t, err)
+			mayMatch, err := filter.Eval(rg, false)
+			require.NoError(t, err)
+			require.Equal(t, tc.expected, mayMatch)
+		})
+	}
+}
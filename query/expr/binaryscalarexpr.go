@@ -3,7 +3,10 @@ package expr
 import (
 	"errors"
 	"fmt"
+	"math"
+	"time"
 
+	"github.com/apache/arrow/go/v17/arrow"
 	"github.com/parquet-go/parquet-go"
 
 	"github.com/polarsignals/frostdb/query/logicalplan"
@@ -37,6 +40,9 @@ type BinaryScalarExpr struct {
 	Left  *ColumnRef
 	Op    logicalplan.Op
 	Right parquet.Value
+	// RightUnit is the unit of Right if it is a timestamp. It is converted to
+	// the unit of timestamp columns before comparing it to their values.
+	RightUnit *arrow.TimeUnit
 }
 
 func (e BinaryScalarExpr) Eval(p Particulate, ignoreMissingCol bool) (bool, error) {
@@ -74,7 +80,52 @@ func (e BinaryScalarExpr) Eval(p Particulate, ignoreMissingCol bool) (bool, erro
 		return false, nil
 	}
 
-	return BinaryScalarOperation(leftData, e.Right, e.Op)
+	right := e.Right
+	if e.RightUnit != nil && !right.IsNull() {
+		var ok bool
+		right, ok = convertTimestamp(right, *e.RightUnit, leftData.Type())
+		if !ok {
+			// The timestamp is more precise than the values of the column
+			// chunk, let the execution engine evaluate the expression.
+			return true, nil
+		}
+	}
+	return BinaryScalarOperation(leftData, right, e.Op)
+}
+
+// convertTimestamp converts the timestamp v of the given unit to the unit of
+// the timestamp type t. It returns false if v cannot be represented exactly in
+// the unit of t. Values of other types are returned unchanged.
+func convertTimestamp(v parquet.Value, unit arrow.TimeUnit, t parquet.Type) (parquet.Value, bool) {
+	lt := t.LogicalType()
+	if lt == nil || lt.Timestamp == nil {
+		return v, true
+	}
+
+	var to time.Duration
+	switch {
+	case lt.Timestamp.Unit.Millis != nil:
+		to = time.Millisecond
+	case lt.Timestamp.Unit.Micros != nil:
+		to = time.Microsecond
+	default:
+		to = time.Nanosecond
+	}
+	from := unit.Multiplier()
+
+	ts := v.Int64()
+	if from >= to {
+		factor := int64(from / to)
+		if ts > math.MaxInt64/factor || ts < math.MinInt64/factor {
+			return v, false
+		}
+		return parquet.Int64Value(ts * factor), true
+	}
+	factor := int64(to / from)
+	if ts%factor != 0 {
+		return v, false
+	}
+	return parquet.Int64Value(ts / factor), true
 }
 
 var ErrUnsupportedBinaryOperation = errors.New("unsupported binary operation")
@@ -91,6 +142,18 @@ func BinaryScalarOperation(left parquet.ColumnChunk, right parquet.Value, operat
 	}
 	numNulls := NullCount(leftColumnIndex)
 	fullOfNulls := numNulls == left.NumValues()
+
+	leftType := left.Type()
+	if !right.IsNull() {
+		var ok bool
+		right, ok = coerceValue(right, leftType)
+		if !ok {
+			// The value cannot be compared to the values of the column chunk,
+			// let the execution engine evaluate the expression.
+			return true, nil
+		}
+	}
+
 	if operator == logicalplan.OpEq {
 		if right.IsNull() {
 			return numNulls > 0, nil
@@ -104,7 +167,11 @@ func BinaryScalarOperation(left parquet.ColumnChunk, right parquet.Value, operat
 		bloomFilter := left.BloomFilter()
 		if bloomFilter == nil {
 			// If there is no bloom filter then we cannot make a statement about true negative, instead check the min max values of the column chunk
-			return compare(right, Max(leftColumnIndex)) <= 0 && compare(right, Min(leftColumnIndex)) >= 0, nil
+			minValue, maxValue := columnMin(leftType, leftColumnIndex), columnMax(leftType, leftColumnIndex)
+			if minValue.IsNull() || maxValue.IsNull() {
+				return true, nil
+			}
+			return compareValues(leftType, right, maxValue) <= 0 && compareValues(leftType, right, minValue) >= 0, nil
 		}
 
 		ok, err := bloomFilter.Check(right)
@@ -129,7 +196,7 @@ func BinaryScalarOperation(left parquet.ColumnChunk, right parquet.Value, operat
 		return true, nil
 	}
 
-	if numNulls == left.NumValues() {
+	if fullOfNulls {
 		// In this case min/max values are meaningless and not comparable to the
 		// right expression, so we can automatically discard the column chunk.
 		return false, nil
@@ -137,46 +204,165 @@ func BinaryScalarOperation(left parquet.ColumnChunk, right parquet.Value, operat
 
 	switch operator {
 	case logicalplan.OpLtEq:
-		minValue := Min(leftColumnIndex)
+		minValue := columnMin(leftType, leftColumnIndex)
 		if minValue.IsNull() {
 			// If min is null, we don't know what the non-null min value is, so
 			// we need to let the execution engine scan this column chunk
 			// further.
 			return true, nil
 		}
-		return compare(minValue, right) <= 0, nil
+		return compareValues(leftType, minValue, right) <= 0, nil
 	case logicalplan.OpLt:
-		minValue := Min(leftColumnIndex)
+		minValue := columnMin(leftType, leftColumnIndex)
 		if minValue.IsNull() {
 			// If min is null, we don't know what the non-null min value is, so
 			// we need to let the execution engine scan this column chunk
 			// further.
 			return true, nil
 		}
-		return compare(minValue, right) < 0, nil
+		return compareValues(leftType, minValue, right) < 0, nil
 	case logicalplan.OpGt:
-		maxValue := Max(leftColumnIndex)
+		maxValue := columnMax(leftType, leftColumnIndex)
 		if maxValue.IsNull() {
 			// If max is null, we don't know what the non-null max value is, so
 			// we need to let the execution engine scan this column chunk
 			// further.
 			return true, nil
 		}
-		return compare(maxValue, right) > 0, nil
+		return compareValues(leftType, maxValue, right) > 0, nil
 	case logicalplan.OpGtEq:
-		maxValue := Max(leftColumnIndex)
+		maxValue := columnMax(leftType, leftColumnIndex)
 		if maxValue.IsNull() {
 			// If max is null, we don't know what the non-null max value is, so
 			// we need to let the execution engine scan this column chunk
 			// further.
 			return true, nil
 		}
-		return compare(maxValue, right) >= 0, nil
+		return compareValues(leftType, maxValue, right) >= 0, nil
 	default:
 		return true, nil
 	}
 }
 
+// coerceValue converts v to the physical type of values of type t, so that v
+// can be compared to them. It returns false if v cannot be converted without
+// changing the result of comparisons, e.g. if a float with a fractional part
+// is compared to integers.
+func coerceValue(v parquet.Value, t parquet.Type) (parquet.Value, bool) {
+	if t == nil || v.Kind() == t.Kind() {
+		return v, true
+	}
+
+	switch t.Kind() {
+	case parquet.Int32:
+		switch v.Kind() {
+		case parquet.Int64:
+			i := v.Int64()
+			if i < math.MinInt32 || i > math.MaxInt32 {
+				return v, false
+			}
+			return parquet.ValueOf(int32(i)), true
+		case parquet.Float, parquet.Double:
+			f := v.Double()
+			if v.Kind() == parquet.Float {
+				f = float64(v.Float())
+			}
+			if f != math.Trunc(f) || f < math.MinInt32 || f > math.MaxInt32 {
+				return v, false
+			}
+			return parquet.ValueOf(int32(f)), true
+		}
+	case parquet.Int64:
+		switch v.Kind() {
+		case parquet.Int32:
+			return parquet.ValueOf(int64(v.Int32())), true
+		case parquet.Float, parquet.Double:
+			f := v.Double()
+			if v.Kind() == parquet.Float {
+				f = float64(v.Float())
+			}
+			// 2^63 is the smallest float64 exceeding the range of int64.
+			if f != math.Trunc(f) || f < math.MinInt64 || f >= math.MaxInt64 {
+				return v, false
+			}
+			return parquet.ValueOf(int64(f)), true
+		}
+	case parquet.Float, parquet.Double:
+		var f float64
+		switch v.Kind() {
+		case parquet.Int32:
+			f = float64(v.Int32())
+		case parquet.Int64:
+			i := v.Int64()
+			f = float64(i)
+			if f >= math.MaxInt64 || int64(f) != i {
+				// Not exactly representable as a float.
+				return v, false
+			}
+		case parquet.Float:
+			f = float64(v.Float())
+		case parquet.Double:
+			f = v.Double()
+		default:
+			return v, false
+		}
+		if t.Kind() == parquet.Double {
+			return parquet.ValueOf(f), true
+		}
+		if float64(float32(f)) != f {
+			return v, false
+		}
+		return parquet.ValueOf(float32(f)), true
+	case parquet.ByteArray, parquet.FixedLenByteArray:
+		switch v.Kind() {
+		case parquet.ByteArray, parquet.FixedLenByteArray:
+			return v, true
+		}
+	}
+	return v, false
+}
+
+// compareValues compares two values of type t, taking its logical type (e.g.
+// unsigned integers) into account. It falls back to compare if t is nil.
+func compareValues(t parquet.Type, v1, v2 parquet.Value) int {
+	if t == nil {
+		return compare(v1, v2)
+	}
+	return t.Compare(v1, v2)
+}
+
+// columnMin is like Min, but compares values using the column type t.
+func columnMin(t parquet.Type, columnIndex parquet.ColumnIndex) parquet.Value {
+	minV := columnIndex.MinValue(0)
+	for i := 1; i < columnIndex.NumPages(); i++ {
+		v := columnIndex.MinValue(i)
+		if minV.IsNull() {
+			minV = v
+			continue
+		}
+		if !v.IsNull() && compareValues(t, minV, v) == 1 {
+			minV = v
+		}
+	}
+	return minV
+}
+
+// columnMax is like Max, but compares values using the column type t.
+func columnMax(t parquet.Type, columnIndex parquet.ColumnIndex) parquet.Value {
+	maxV := columnIndex.MaxValue(0)
+	for i := 1; i < columnIndex.NumPages(); i++ {
+		v := columnIndex.MaxValue(i)
+		if maxV.IsNull() {
+			maxV = v
+			continue
+		}
+		if !v.IsNull() && compareValues(t, maxV, v) == -1 {
+			maxV = v
+		}
+	}
+	return maxV
+}
+
 // Min returns the minimum value found in the column chunk across all pages.
 func Min(columnIndex parquet.ColumnIndex) parquet.Value {
 	minV := columnIndex.MinValue(0)
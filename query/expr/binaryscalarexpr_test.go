@@ -1,6 +1,7 @@
 package expr
 
 import (
+	"bytes"
 	"testing"
 
 	"github.com/parquet-go/parquet-go"
@@ -211,3 +212,58 @@ func TestBinaryScalarOperation(t *testing.T) {
 		})
 	}
 }
+
+func TestBinaryScalarExprTypes(t *testing.T) {
+	type row struct {
+		Float  float64 `parquet:"float"`
+		Int    int64   `parquet:"int"`
+		Uint   uint64  `parquet:"uint"`
+		Bool   bool    `parquet:"bool"`
+		String string  `parquet:"string"`
+	}
+
+	buf := bytes.NewBuffer(nil)
+	w := parquet.NewGenericWriter[row](buf)
+	_, err := w.Write([]row{
+		{Float: 1.5, Int: 1, Uint: 1<<63 + 1, Bool: true, String: "b"},
+		{Float: 2.5, Int: 3, Uint: 1<<63 + 5, Bool: true, String: "d"},
+	})
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+
+	f, err := parquet.OpenFile(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	require.NoError(t, err)
+	rg := f.RowGroups()[0]
+
+	for _, tc := range []struct {
+		name     string
+		expr     logicalplan.Expr
+		expected bool
+	}{
+		{name: "FloatGt", expr: logicalplan.Col("float").Gt(logicalplan.Literal(2.5)), expected: false},
+		{name: "FloatGtEq", expr: logicalplan.Col("float").GtEq(logicalplan.Literal(2.5)), expected: true},
+		{name: "FloatLt", expr: logicalplan.Col("float").Lt(logicalplan.Literal(1.5)), expected: false},
+		{name: "FloatEqInRange", expr: logicalplan.Col("float").Eq(logicalplan.Literal(2.0)), expected: true},
+		{name: "FloatGtInt", expr: logicalplan.Col("float").Gt(logicalplan.Literal(int64(3))), expected: false},
+		{name: "FloatGtIntInRange", expr: logicalplan.Col("float").Gt(logicalplan.Literal(int64(2))), expected: true},
+		{name: "IntGtFloat", expr: logicalplan.Col("int").Gt(logicalplan.Literal(3.0)), expected: false},
+		// Fractional floats are not coerced to integers.
+		{name: "IntGtFractionalFloat", expr: logicalplan.Col("int").Gt(logicalplan.Literal(3.5)), expected: true},
+		{name: "UintGt", expr: logicalplan.Col("uint").Gt(logicalplan.Literal(uint64(1<<63 + 5))), expected: false},
+		{name: "UintLt", expr: logicalplan.Col("uint").Lt(logicalplan.Literal(uint64(1 << 63))), expected: false},
+		{name: "UintGtEq", expr: logicalplan.Col("uint").GtEq(logicalplan.Literal(uint64(1<<63 + 5))), expected: true},
+		{name: "BoolEqFalse", expr: logicalplan.Col("bool").Eq(logicalplan.Literal(false)), expected: false},
+		{name: "BoolEqTrue", expr: logicalplan.Col("bool").Eq(logicalplan.Literal(true)), expected: true},
+		{name: "StringLt", expr: logicalplan.Col("string").Lt(logicalplan.Literal("b")), expected: false},
+		{name: "StringGtEq", expr: logicalplan.Col("string").GtEq(logicalplan.Literal("d")), expected: true},
+		{name: "BinaryGt", expr: logicalplan.Col("string").Gt(logicalplan.Literal([]byte("d"))), expected: false},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			filter, err := BooleanExpr(tc.expr)
+			require.NoError(t, err)
+			mayMatch, err := filter.Eval(rg, false)
+			require.NoError(t, err)
+			require.Equal(t, tc.expected, mayMatch)
+		})
+	}
+}
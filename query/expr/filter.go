@@ -7,6 +7,7 @@ import (
 	"sync"
 	"sync/atomic"
 
+	"github.com/apache/arrow/go/v17/arrow"
 	"github.com/parquet-go/parquet-go"
 
 	"github.com/polarsignals/frostdb/pqarrow"
@@ -47,6 +48,16 @@ func (f *AlwaysTrueFilter) Eval(_ Particulate, _ bool) (bool, error) {
 }
 
 func binaryBooleanExpr(expr *logicalplan.BinaryExpr) (TrueNegativeFilter, error) {
+	switch expr.Left.(type) {
+	case *logicalplan.MapKeyExpr:
+		// There are no statistics of the values of individual map keys.
+		return &AlwaysTrueFilter{}, nil
+	case *logicalplan.StringFuncExpr:
+		// Statistics of a column don't bound the results of functions
+		// applied to it.
+		return &AlwaysTrueFilter{}, nil
+	}
+
 	switch expr.Op {
 	case logicalplan.OpNotEq:
 		fallthrough
@@ -76,12 +87,18 @@ func binaryBooleanExpr(expr *logicalplan.BinaryExpr) (TrueNegativeFilter, error)
 
 		var (
 			rightValue parquet.Value
+			rightUnit  *arrow.TimeUnit
+			rightFound bool
 			err        error
 		)
 		expr.Right.Accept(PreExprVisitorFunc(func(expr logicalplan.Expr) bool {
 			switch e := expr.(type) {
 			case *logicalplan.LiteralExpr:
 				rightValue, err = pqarrow.ArrowScalarToParquetValue(e.Value)
+				if typ, ok := e.Value.DataType().(*arrow.TimestampType); ok {
+					rightUnit = &typ.Unit
+				}
+				rightFound = true
 				return false
 			}
 			return true
@@ -90,12 +107,36 @@ func binaryBooleanExpr(expr *logicalplan.BinaryExpr) (TrueNegativeFilter, error)
 		if err != nil {
 			return nil, err
 		}
+		if !rightFound {
+			var rightColumnRef *ColumnRef
+			expr.Right.Accept(PreExprVisitorFunc(func(expr logicalplan.Expr) bool {
+				switch e := expr.(type) {
+				case *logicalplan.Column:
+					rightColumnRef = &ColumnRef{
+						ColumnName: e.ColumnName,
+					}
+					return false
+				}
+				return true
+			}))
+			if rightColumnRef == nil {
+				return &AlwaysTrueFilter{}, nil
+			}
+			return &BinaryColumnExpr{
+				Left:  leftColumnRef,
+				Op:    expr.Op,
+				Right: rightColumnRef,
+			}, nil
+		}
 
 		return &BinaryScalarExpr{
-			Left:  leftColumnRef,
-			Op:    expr.Op,
-			Right: rightValue,
+			Left:      leftColumnRef,
+			Op:        expr.Op,
+			Right:     rightValue,
+			RightUnit: rightUnit,
 		}, nil
+	case logicalplan.OpRegexMatch, logicalplan.OpRegexNotMatch:
+		return regexpExpr(expr)
 	case logicalplan.OpAnd:
 		left, err := BooleanExpr(expr.Left)
 		if err != nil {
@@ -256,6 +297,12 @@ func BooleanExpr(expr logicalplan.Expr) (TrueNegativeFilter, error) {
 	switch e := expr.(type) {
 	case *logicalplan.BinaryExpr:
 		return binaryBooleanExpr(e)
+	case *logicalplan.InExpr:
+		return inExpr(e)
+	case *logicalplan.AnyColumnExpr:
+		return anyColumnExpr(e)
+	case *logicalplan.IsNullExpr:
+		return isNullExpr(e)
 	case *logicalplan.AggregationFunction:
 		// NOTE: Aggregations are optimized in the case of no grouping columns
 		// or other filters.
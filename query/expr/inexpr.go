@@ -0,0 +1,67 @@
+package expr
+
+import (
+	"errors"
+
+	"github.com/polarsignals/frostdb/pqarrow"
+	"github.com/polarsignals/frostdb/query/logicalplan"
+)
+
+// InExpr prunes particulates for IN and NOT IN list expressions. IN is
+// evaluated as equality against each value, so bloom filters and min/max
+// statistics are used for every value of the list. NOT IN is evaluated as
+// the conjunction of inequalities against each value.
+type InExpr struct {
+	Values []*BinaryScalarExpr
+	Not    bool
+}
+
+func inExpr(expr *logicalplan.InExpr) (TrueNegativeFilter, error) {
+	column, ok := expr.Expr.(*logicalplan.Column)
+	if !ok {
+		return nil, errors.New("left side of in expression must be a column")
+	}
+
+	op := logicalplan.OpEq
+	if expr.Not {
+		op = logicalplan.OpNotEq
+	}
+
+	values := make([]*BinaryScalarExpr, 0, len(expr.Values))
+	for _, v := range expr.Values {
+		literal, ok := v.(*logicalplan.LiteralExpr)
+		if !ok {
+			// Let the execution engine evaluate the expression.
+			return &AlwaysTrueFilter{}, nil
+		}
+		value, err := pqarrow.ArrowScalarToParquetValue(literal.Value)
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, &BinaryScalarExpr{
+			Left:  &ColumnRef{ColumnName: column.ColumnName},
+			Op:    op,
+			Right: value,
+		})
+	}
+
+	return &InExpr{
+		Values: values,
+		Not:    expr.Not,
+	}, nil
+}
+
+func (e *InExpr) Eval(p Particulate, ignoreMissingCols bool) (bool, error) {
+	for _, v := range e.Values {
+		mayMatch, err := v.Eval(p, ignoreMissingCols)
+		if err != nil {
+			return true, err
+		}
+		// IN may match as soon as any equality may match, NOT IN can't
+		// match as soon as any inequality can't.
+		if mayMatch != e.Not {
+			return mayMatch, nil
+		}
+	}
+	return e.Not, nil
+}
@@ -0,0 +1,58 @@
+package expr
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/parquet-go/parquet-go"
+	"github.com/stretchr/testify/require"
+
+	"github.com/polarsignals/frostdb/query/logicalplan"
+)
+
+func TestInExpr(t *testing.T) {
+	type row struct {
+		Region string `parquet:"region,dict"`
+		Value  int64  `parquet:"value"`
+	}
+
+	buf := bytes.NewBuffer(nil)
+	w := parquet.NewGenericWriter[row](buf, parquet.BloomFilters(
+		parquet.SplitBlockFilter(10, "region"),
+	))
+	_, err := w.Write([]row{
+		{Region: "eu-west", Value: 1},
+		{Region: "us-east", Value: 3},
+	})
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+
+	f, err := parquet.OpenFile(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	require.NoError(t, err)
+	rg := f.RowGroups()[0]
+
+	for _, tc := range []struct {
+		name     string
+		expr     logicalplan.Expr
+		expected bool
+	}{
+		{name: "In", expr: logicalplan.Col("region").In(logicalplan.Literal("ap-south"), logicalplan.Literal("us-east")), expected: true},
+		// "sa-east" is within min/max, only the bloom filter excludes it.
+		{name: "InBloomFilter", expr: logicalplan.Col("region").In(logicalplan.Literal("ap-south"), logicalplan.Literal("sa-east")), expected: false},
+		{name: "InMinMax", expr: logicalplan.Col("value").In(logicalplan.Literal(int64(0)), logicalplan.Literal(int64(4))), expected: false},
+		{name: "InMinMaxMatch", expr: logicalplan.Col("value").In(logicalplan.Literal(int64(0)), logicalplan.Literal(int64(2))), expected: true},
+		{name: "NotIn", expr: logicalplan.Col("region").NotIn(logicalplan.Literal("us-east")), expected: true},
+		{name: "InAnd", expr: logicalplan.And(
+			logicalplan.Col("value").Gt(logicalplan.Literal(int64(0))),
+			logicalplan.Col("region").In(logicalplan.Literal("ap-south")),
+		), expected: false},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			filter, err := BooleanExpr(tc.expr)
+			require.NoError(t, err)
+			mayMatch, err := filter.Eval(rg, false)
+			require.NoError(t, err)
+			require.Equal(t, tc.expected, mayMatch)
+		})
+	}
+}
@@ -0,0 +1,47 @@
+package expr
+
+import (
+	"errors"
+
+	"github.com/polarsignals/frostdb/query/logicalplan"
+)
+
+// IsNullExpr prunes particulates for IS NULL and IS NOT NULL expressions using
+// the null counts of the pages of the column.
+type IsNullExpr struct {
+	Left *ColumnRef
+	Not  bool
+}
+
+func isNullExpr(expr *logicalplan.IsNullExpr) (TrueNegativeFilter, error) {
+	column, ok := expr.Expr.(*logicalplan.Column)
+	if !ok {
+		return nil, errors.New("is null expression must be on a column")
+	}
+	return &IsNullExpr{
+		Left: &ColumnRef{ColumnName: column.ColumnName},
+		Not:  expr.Not,
+	}, nil
+}
+
+func (e *IsNullExpr) Eval(p Particulate, ignoreMissingCols bool) (bool, error) {
+	leftData, exists, err := e.Left.Column(p)
+	if err != nil {
+		return false, err
+	}
+
+	if !exists {
+		// A missing column is null in every row.
+		return !e.Not || ignoreMissingCols, nil
+	}
+
+	index, err := leftData.ColumnIndex()
+	if err != nil {
+		return true, err
+	}
+	numNulls := NullCount(index)
+	if e.Not {
+		return numNulls < leftData.NumValues(), nil
+	}
+	return numNulls > 0, nil
+}
@@ -0,0 +1,56 @@
+package expr
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/parquet-go/parquet-go"
+	"github.com/stretchr/testify/require"
+
+	"github.com/polarsignals/frostdb/query/logicalplan"
+)
+
+func TestIsNullExpr(t *testing.T) {
+	type row struct {
+		Some  *string `parquet:"some,optional"`
+		None  *string `parquet:"none,optional"`
+		Value int64   `parquet:"value"`
+	}
+
+	a := "a"
+	buf := bytes.NewBuffer(nil)
+	w := parquet.NewGenericWriter[row](buf)
+	_, err := w.Write([]row{
+		{Some: &a, Value: 1},
+		{Value: 2},
+	})
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+
+	f, err := parquet.OpenFile(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	require.NoError(t, err)
+	rg := f.RowGroups()[0]
+
+	for _, tc := range []struct {
+		name     string
+		expr     logicalplan.Expr
+		expected bool
+	}{
+		{name: "SomeNulls", expr: logicalplan.Col("some").IsNull(), expected: true},
+		{name: "SomeNullsNot", expr: logicalplan.Col("some").IsNotNull(), expected: true},
+		{name: "AllNulls", expr: logicalplan.Col("none").IsNull(), expected: true},
+		{name: "AllNullsNot", expr: logicalplan.Col("none").IsNotNull(), expected: false},
+		{name: "NoNulls", expr: logicalplan.Col("value").IsNull(), expected: false},
+		{name: "NoNullsNot", expr: logicalplan.Col("value").IsNotNull(), expected: true},
+		{name: "Missing", expr: logicalplan.Col("missing").IsNull(), expected: true},
+		{name: "MissingNot", expr: logicalplan.Col("missing").IsNotNull(), expected: false},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			filter, err := BooleanExpr(tc.expr)
+			require.NoError(t, err)
+			mayMatch, err := filter.Eval(rg, false)
+			require.NoError(t, err)
+			require.Equal(t, tc.expected, mayMatch)
+		})
+	}
+}
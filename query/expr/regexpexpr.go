@@ -0,0 +1,104 @@
+package expr
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+
+	"github.com/apache/arrow/go/v17/arrow/scalar"
+	"github.com/parquet-go/parquet-go"
+
+	"github.com/polarsignals/frostdb/query/logicalplan"
+)
+
+// RegexpExpr is a TrueNegativeFilter for regex match and regex not match
+// expressions. Column chunks are ruled out using their dictionary: a
+// dictionary-encoded column chunk shares a single dictionary between all of
+// its pages, so if no dictionary entry satisfies the expression, no page of
+// the column chunk can contain a matching row. The regular expression only
+// runs once per dictionary entry.
+type RegexpExpr struct {
+	Left     *ColumnRef
+	Right    *regexp.Regexp
+	NotMatch bool
+}
+
+func regexpExpr(expr *logicalplan.BinaryExpr) (TrueNegativeFilter, error) {
+	left, ok := expr.Left.(*logicalplan.Column)
+	if !ok {
+		return &AlwaysTrueFilter{}, nil
+	}
+	right, ok := expr.Right.(*logicalplan.LiteralExpr)
+	if !ok {
+		return &AlwaysTrueFilter{}, nil
+	}
+	s, ok := right.Value.(*scalar.String)
+	if !ok {
+		return nil, fmt.Errorf("regex must be a string, got %T", right.Value)
+	}
+	re, err := regexp.Compile(string(s.Data()))
+	if err != nil {
+		return nil, err
+	}
+
+	return &RegexpExpr{
+		Left:     &ColumnRef{ColumnName: left.ColumnName},
+		Right:    re,
+		NotMatch: expr.Op == logicalplan.OpRegexNotMatch,
+	}, nil
+}
+
+func (e *RegexpExpr) Eval(p Particulate, ignoreMissingCols bool) (bool, error) {
+	chunk, exists, err := e.Left.Column(p)
+	if err != nil {
+		return false, err
+	}
+	if !exists {
+		if ignoreMissingCols {
+			return true, nil
+		}
+		// Rows of a missing column are evaluated as empty values.
+		return e.Right.Match(nil) != e.NotMatch, nil
+	}
+
+	return e.evalDictionary(chunk)
+}
+
+// evalDictionary returns false if no entry of the column chunk's dictionary
+// satisfies the expression. Null values never satisfy it. Column chunks that
+// are not dictionary-encoded may always contain matching rows.
+func (e *RegexpExpr) evalDictionary(chunk parquet.ColumnChunk) (bool, error) {
+	if chunk.NumValues() == 0 {
+		return false, nil
+	}
+
+	pages := chunk.Pages()
+	if pages == nil {
+		return true, nil
+	}
+	defer pages.Close()
+
+	page, err := pages.ReadPage()
+	if err != nil {
+		if err == io.EOF {
+			return false, nil
+		}
+		return true, fmt.Errorf("read page: %w", err)
+	}
+	defer parquet.Release(page)
+
+	dict := page.Dictionary()
+	if dict == nil {
+		return true, nil
+	}
+	if kind := dict.Type().Kind(); kind != parquet.ByteArray && kind != parquet.FixedLenByteArray {
+		return true, nil
+	}
+
+	for i := 0; i < dict.Len(); i++ {
+		if e.Right.Match(dict.Index(int32(i)).ByteArray()) != e.NotMatch {
+			return true, nil
+		}
+	}
+	return false, nil
+}
@@ -0,0 +1,82 @@
+package expr
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/parquet-go/parquet-go"
+	"github.com/stretchr/testify/require"
+
+	"github.com/polarsignals/frostdb/query/logicalplan"
+)
+
+func TestRegexpExpr(t *testing.T) {
+	type row struct {
+		Dict  string `parquet:"dict,dict"`
+		Plain string `parquet:"plain"`
+	}
+
+	buf := bytes.NewBuffer(nil)
+	w := parquet.NewGenericWriter[row](buf)
+	_, err := w.Write([]row{
+		{Dict: "foo", Plain: "foo"},
+		{Dict: "bar", Plain: "bar"},
+		{Dict: "foo", Plain: "foo"},
+	})
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+
+	f, err := parquet.OpenFile(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	require.NoError(t, err)
+	rg := f.RowGroups()[0]
+
+	for _, tc := range []struct {
+		name     string
+		expr     logicalplan.Expr
+		expected bool
+	}{
+		{
+			name:     "Match",
+			expr:     logicalplan.Col("dict").RegexMatch("^fo+$"),
+			expected: true,
+		},
+		{
+			name:     "NoMatch",
+			expr:     logicalplan.Col("dict").RegexMatch("^baz$"),
+			expected: false,
+		},
+		{
+			name:     "NotMatch",
+			expr:     logicalplan.Col("dict").RegexNotMatch("^foo$"),
+			expected: true,
+		},
+		{
+			name:     "NotMatchAll",
+			expr:     logicalplan.Col("dict").RegexNotMatch("^(foo|bar)$"),
+			expected: false,
+		},
+		{
+			name:     "NotDictionaryEncoded",
+			expr:     logicalplan.Col("plain").RegexMatch("^baz$"),
+			expected: true,
+		},
+		{
+			name:     "MissingColumnEmptyMatch",
+			expr:     logicalplan.Col("missing").RegexMatch("^$"),
+			expected: true,
+		},
+		{
+			name:     "MissingColumn",
+			expr:     logicalplan.Col("missing").RegexMatch("^foo$"),
+			expected: false,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			filter, err := BooleanExpr(tc.expr)
+			require.NoError(t, err)
+			mayMatch, err := filter.Eval(rg, false)
+			require.NoError(t, err)
+			require.Equal(t, tc.expected, mayMatch)
+		})
+	}
+}
@@ -138,6 +138,20 @@ func (b Builder) Limit(expr Expr) Builder {
 		return b
 	}
 
+	if b.plan != nil && b.plan.Limit != nil && b.plan.Limit.Expr == nil {
+		// Limit the rows remaining after a previously added offset.
+		return Builder{
+			err: b.err,
+			plan: &LogicalPlan{
+				Input: b.plan.Input,
+				Limit: &Limit{
+					Expr:   expr,
+					Offset: b.plan.Limit.Offset,
+				},
+			},
+		}
+	}
+
 	return Builder{
 		err: b.err,
 		plan: &LogicalPlan{
@@ -149,6 +163,25 @@ func (b Builder) Limit(expr Expr) Builder {
 	}
 }
 
+// OrderBy sorts the result by the given expressions. Expressions can be
+// wrapped in Asc or Desc to specify the sort direction, the default being
+// ascending. Nulls are sorted last.
+func (b Builder) OrderBy(exprs ...Expr) Builder {
+	if len(exprs) == 0 {
+		return b
+	}
+
+	return Builder{
+		err: b.err,
+		plan: &LogicalPlan{
+			Input: b.plan,
+			OrderBy: &OrderBy{
+				Exprs: exprs,
+			},
+		},
+	}
+}
+
 func (b Builder) Aggregate(
 	aggExpr []*AggregationFunction,
 	groupExprs []Expr,
@@ -168,7 +201,18 @@ func (b Builder) Aggregate(
 			needsPostProcessing = true
 		}
 
-		resolvedAggExpr = append(resolvedAggExpr, resolvedAggregations...)
+		// Aggregations are computed once even if requested multiple times,
+		// e.g. by sum(value) and avg(value), which is resolved to a sum and
+		// a count.
+	resolved:
+		for _, resolvedAgg := range resolvedAggregations {
+			for _, existing := range resolvedAggExpr {
+				if existing.Equal(resolvedAgg) {
+					continue resolved
+				}
+			}
+			resolvedAggExpr = append(resolvedAggExpr, resolvedAgg)
+		}
 		projectExprs = append(projectExprs, projections...)
 	}
 
@@ -178,7 +222,7 @@ func (b Builder) Aggregate(
 			plan: &LogicalPlan{
 				Aggregation: &Aggregation{
 					GroupExprs: groupExprs,
-					AggExprs:   aggExpr,
+					AggExprs:   resolvedAggExpr,
 				},
 				Input: b.plan,
 			},
@@ -202,6 +246,50 @@ func (b Builder) Aggregate(
 	}
 }
 
+// AggregateExprs is like Aggregate, but the aggregations may be aliased, e.g.
+// Sum(Col("value")).Alias("s"), in which case the result columns are renamed
+// by a projection of the group and aggregation columns. All aggregations are
+// computed per group in a single pass, even if they aggregate the same column.
+func (b Builder) AggregateExprs(
+	aggExprs []Expr,
+	groupExprs []Expr,
+) Builder {
+	aggs := make([]*AggregationFunction, 0, len(aggExprs))
+	projections := make([]Expr, 0, len(groupExprs)+len(aggExprs))
+	projections = append(projections, groupExprs...)
+	aliased := false
+
+	var err error
+	for _, expr := range aggExprs {
+		switch e := expr.(type) {
+		case *AggregationFunction:
+			aggs = append(aggs, e)
+			projections = append(projections, e)
+		case *AliasExpr:
+			agg, ok := e.Expr.(*AggregationFunction)
+			if !ok {
+				err = errors.Join(err, fmt.Errorf("aggregate: %s is not an aggregation function", e.Expr))
+				continue
+			}
+			aggs = append(aggs, agg)
+			projections = append(projections, e)
+			aliased = true
+		default:
+			err = errors.Join(err, fmt.Errorf("aggregate: %s is not an aggregation function", expr))
+		}
+	}
+
+	aggregated := b.Aggregate(aggs, groupExprs)
+	if err != nil {
+		aggregated.err = errors.Join(aggregated.err, err)
+		return aggregated
+	}
+	if !aliased {
+		return aggregated
+	}
+	return aggregated.Project(projections...)
+}
+
 func resolveAggregation(plan *LogicalPlan, agg *AggregationFunction) ([]*AggregationFunction, []Expr, bool, error) {
 	switch agg.Func {
 	case AggFuncAvg:
@@ -237,6 +325,38 @@ func resolveAggregation(plan *LogicalPlan, agg *AggregationFunction) ([]*Aggrega
 	}
 }
 
+// Offset skips the given number of rows. Like in SQL, an offset directly
+// following or preceding a limit is applied before the limit, so that
+// Limit(10).Offset(20) returns rows 20 to 29.
+func (b Builder) Offset(expr Expr) Builder {
+	if expr == nil {
+		return b
+	}
+
+	if b.plan != nil && b.plan.Limit != nil && b.plan.Limit.Offset == nil {
+		return Builder{
+			err: b.err,
+			plan: &LogicalPlan{
+				Input: b.plan.Input,
+				Limit: &Limit{
+					Expr:   b.plan.Limit.Expr,
+					Offset: expr,
+				},
+			},
+		}
+	}
+
+	return Builder{
+		err: b.err,
+		plan: &LogicalPlan{
+			Input: b.plan,
+			Limit: &Limit{
+				Offset: expr,
+			},
+		},
+	}
+}
+
 func (b Builder) Sample(expr, limit Expr) Builder {
 	if expr == nil || limit == nil {
 		return b
@@ -254,6 +374,26 @@ func (b Builder) Sample(expr, limit Expr) Builder {
 	}
 }
 
+// Join joins the rows of the plan with the rows of the plan of right. See
+// Join for the expressions rows can be joined on.
+func (b Builder) Join(right Builder, on ...Expr) Builder {
+	err := errors.Join(b.err, right.err)
+	if right.plan == nil {
+		err = errors.Join(err, errors.New("join: right input must not be empty"))
+	}
+
+	return Builder{
+		err: err,
+		plan: &LogicalPlan{
+			Input: b.plan,
+			Join: &Join{
+				Right: right.plan,
+				On:    on,
+			},
+		},
+	}
+}
+
 func (b Builder) Build() (*LogicalPlan, error) {
 	if b.err != nil {
 		return nil, b.err
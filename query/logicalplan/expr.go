@@ -3,6 +3,8 @@ package logicalplan
 import (
 	"errors"
 	"fmt"
+	"regexp"
+	"strconv"
 	"strings"
 	"time"
 
@@ -30,6 +32,7 @@ const (
 	OpDiv
 	OpContains
 	OpNotContains
+	OpNullSafeEq
 )
 
 func (o Op) String() string {
@@ -66,6 +69,8 @@ func (o Op) String() string {
 		return "contains"
 	case OpNotContains:
 		return "not contains"
+	case OpNullSafeEq:
+		return "<=>"
 	default:
 		panic("unknown operator")
 	}
@@ -164,20 +169,54 @@ func (e *BinaryExpr) DataType(l ExprTypeFinder) (arrow.DataType, error) {
 		return nil, fmt.Errorf("right operand: %w", err)
 	}
 
+	switch e.Op {
+	case OpAdd, OpSub, OpMul, OpDiv:
+		return ArithmeticType(leftType, rightType)
+	case OpNullSafeEq:
+		if isNullType(leftType) || isNullType(rightType) {
+			return arrow.FixedWidthTypes.Boolean, nil
+		}
+	}
+
 	if !arrow.TypeEqual(leftType, rightType) {
 		return nil, fmt.Errorf("left and right operands must be of the same type, got %s and %s", leftType, rightType)
 	}
 
 	switch e.Op {
-	case OpEq, OpNotEq, OpLt, OpLtEq, OpGt, OpGtEq, OpAnd, OpOr:
+	case OpEq, OpNotEq, OpLt, OpLtEq, OpGt, OpGtEq, OpAnd, OpOr, OpNullSafeEq:
 		return arrow.FixedWidthTypes.Boolean, nil
-	case OpAdd, OpSub, OpMul, OpDiv:
-		return leftType, nil
 	default:
 		return nil, errors.New("unknown operator")
 	}
 }
 
+// ArithmeticType returns the type of the result of arithmetic on operands of
+// the given types. Arithmetic is supported on int32, int64, uint64 and float64
+// operands, and operands of different types are converted to a common type:
+// float64 if either operand is a float64, int64 if the operands are of
+// different integer types, and the type of the operands otherwise.
+func ArithmeticType(left, right arrow.DataType) (arrow.DataType, error) {
+	for _, t := range []arrow.DataType{left, right} {
+		if t == nil {
+			return nil, errors.New("arithmetic operand type is unknown")
+		}
+		switch t.ID() {
+		case arrow.INT32, arrow.INT64, arrow.UINT64, arrow.FLOAT64:
+		default:
+			return nil, fmt.Errorf("unsupported arithmetic operand type %s", t)
+		}
+	}
+
+	switch {
+	case left.ID() == arrow.FLOAT64 || right.ID() == arrow.FLOAT64:
+		return arrow.PrimitiveTypes.Float64, nil
+	case left.ID() != right.ID():
+		return arrow.PrimitiveTypes.Int64, nil
+	default:
+		return left, nil
+	}
+}
+
 func (e *BinaryExpr) Name() string {
 	return e.Left.Name() + " " + e.Op.String() + " " + e.Right.Name()
 }
@@ -362,6 +401,13 @@ func (c *Column) Eq(e Expr) *BinaryExpr {
 	}
 }
 
+// NullSafeEq is like Eq, except that null equals null and is not equal to
+// any value, so comparing to Literal(nil) matches the rows where the column is
+// null or missing.
+func (c *Column) NullSafeEq(e Expr) *BinaryExpr {
+	return NullSafeEq(c, e)
+}
+
 func (c *Column) NotEq(e Expr) *BinaryExpr {
 	return &BinaryExpr{
 		Left:  c,
@@ -434,6 +480,29 @@ func (c *Column) ContainsNot(pattern string) *BinaryExpr {
 	}
 }
 
+func (c *Column) In(values ...Expr) *InExpr {
+	return &InExpr{
+		Expr:   c,
+		Values: values,
+	}
+}
+
+func (c *Column) NotIn(values ...Expr) *InExpr {
+	return &InExpr{
+		Expr:   c,
+		Values: values,
+		Not:    true,
+	}
+}
+
+func (c *Column) IsNull() *IsNullExpr {
+	return IsNull(c)
+}
+
+func (c *Column) IsNotNull() *IsNullExpr {
+	return IsNotNull(c)
+}
+
 func Col(name string) *Column {
 	return &Column{ColumnName: name}
 }
@@ -442,6 +511,9 @@ func And(exprs ...Expr) Expr {
 	return and(exprs)
 }
 
+// Add returns an expression adding right to left. Arithmetic expressions
+// result in null if either operand is null, and fail the query if the result
+// of integer arithmetic overflows. See ArithmeticType for the supported types.
 func Add(left, right Expr) *BinaryExpr {
 	return &BinaryExpr{
 		Left:  left,
@@ -450,6 +522,17 @@ func Add(left, right Expr) *BinaryExpr {
 	}
 }
 
+// NullSafeEq returns whether left and right are equal, treating null as equal
+// to null and as not equal to any value. Unlike the result of Eq, the result
+// is never null.
+func NullSafeEq(left, right Expr) *BinaryExpr {
+	return &BinaryExpr{
+		Left:  left,
+		Op:    OpNullSafeEq,
+		Right: right,
+	}
+}
+
 func Sub(left, right Expr) *BinaryExpr {
 	return &BinaryExpr{
 		Left:  left,
@@ -466,6 +549,8 @@ func Mul(left, right Expr) *BinaryExpr {
 	}
 }
 
+// Div returns an expression dividing left by right. Integer division
+// truncates towards zero, and dividing by zero results in null.
 func Div(left, right Expr) *BinaryExpr {
 	return &BinaryExpr{
 		Left:  left,
@@ -610,7 +695,18 @@ func (e *LiteralExpr) Computed() bool {
 	return false
 }
 
+// Literal returns a literal of the value v. A time.Time is a UTC timestamp
+// with nanosecond precision, which is compared to timestamp columns of any
+// unit.
 func Literal(v interface{}) *LiteralExpr {
+	if t, ok := v.(time.Time); ok {
+		return &LiteralExpr{
+			Value: scalar.NewTimestampScalar(
+				arrow.Timestamp(t.UnixNano()),
+				&arrow.TimestampType{Unit: arrow.Nanosecond, TimeZone: "UTC"},
+			),
+		}
+	}
 	return &LiteralExpr{
 		Value: scalar.MakeScalar(v),
 	}
@@ -648,6 +744,9 @@ func (e *LiteralExpr) MatchColumn(columnName string) bool {
 type AggregationFunction struct {
 	Func AggFunc
 	Expr Expr
+	// Quantile is the quantile computed by AggFuncQuantile aggregations,
+	// between 0 and 1.
+	Quantile float64
 }
 
 func (f *AggregationFunction) Equal(other Expr) bool {
@@ -657,7 +756,7 @@ func (f *AggregationFunction) Equal(other Expr) bool {
 	}
 
 	if agg, ok := other.(*AggregationFunction); ok {
-		return f.Func == agg.Func && f.Expr.Equal(agg.Expr)
+		return f.Func == agg.Func && f.Quantile == agg.Quantile && f.Expr.Equal(agg.Expr)
 	}
 
 	return false
@@ -665,13 +764,19 @@ func (f *AggregationFunction) Equal(other Expr) bool {
 
 func (f *AggregationFunction) Clone() Expr {
 	return &AggregationFunction{
-		Func: f.Func,
-		Expr: f.Expr.Clone(),
+		Func:     f.Func,
+		Expr:     f.Expr.Clone(),
+		Quantile: f.Quantile,
 	}
 }
 
 func (f *AggregationFunction) DataType(l ExprTypeFinder) (arrow.DataType, error) {
-	return f.Expr.DataType(l)
+	switch f.Func {
+	case AggFuncStddev, AggFuncVariance, AggFuncQuantile:
+		return arrow.PrimitiveTypes.Float64, nil
+	default:
+		return f.Expr.DataType(l)
+	}
 }
 
 func (f *AggregationFunction) Accept(visitor Visitor) bool {
@@ -698,6 +803,9 @@ func (f *AggregationFunction) Computed() bool {
 }
 
 func (f *AggregationFunction) Name() string {
+	if f.Func == AggFuncQuantile {
+		return f.Func.String() + "(" + strconv.FormatFloat(f.Quantile, 'f', -1, 64) + ", " + f.Expr.Name() + ")"
+	}
 	return f.Func.String() + "(" + f.Expr.Name() + ")"
 }
 
@@ -726,6 +834,13 @@ const (
 	AggFuncAvg
 	AggFuncUnique
 	AggFuncAnd
+	// AggFuncStddev computes the population standard deviation.
+	AggFuncStddev
+	// AggFuncVariance computes the population variance.
+	AggFuncVariance
+	// AggFuncQuantile computes an approximation of a quantile using a
+	// t-digest, see Quantile.
+	AggFuncQuantile
 )
 
 func (f AggFunc) String() string {
@@ -744,6 +859,12 @@ func (f AggFunc) String() string {
 		return "unique"
 	case AggFuncAnd:
 		return "and"
+	case AggFuncStddev:
+		return "stddev"
+	case AggFuncVariance:
+		return "variance"
+	case AggFuncQuantile:
+		return "quantile"
 	default:
 		panic("unknown aggregation function")
 	}
@@ -798,14 +919,52 @@ func Avg(expr Expr) *AggregationFunction {
 	}
 }
 
+// Stddev computes the population standard deviation of the expression.
+func Stddev(expr Expr) *AggregationFunction {
+	return &AggregationFunction{
+		Func: AggFuncStddev,
+		Expr: expr,
+	}
+}
+
+// Variance computes the population variance of the expression.
+func Variance(expr Expr) *AggregationFunction {
+	return &AggregationFunction{
+		Func: AggFuncVariance,
+		Expr: expr,
+	}
+}
+
+// Quantile computes the given quantile of the expression, e.g. 0.99 for the
+// 99th percentile. The quantile is approximated with a t-digest, whose error
+// is smallest for quantiles close to 0 and 1, so that the result of merging
+// partial aggregations does not depend on the number of values aggregated.
+func Quantile(expr Expr, quantile float64) *AggregationFunction {
+	return &AggregationFunction{
+		Func:     AggFuncQuantile,
+		Expr:     expr,
+		Quantile: quantile,
+	}
+}
+
 func IsNull(expr Expr) *IsNullExpr {
 	return &IsNullExpr{
 		Expr: expr,
 	}
 }
 
+func IsNotNull(expr Expr) *IsNullExpr {
+	return &IsNullExpr{
+		Expr: expr,
+		Not:  true,
+	}
+}
+
+// IsNullExpr is true for rows where the expression is null, or not null if
+// Not is set.
 type IsNullExpr struct {
 	Expr Expr
+	Not  bool
 }
 
 func (e *IsNullExpr) Equal(other Expr) bool {
@@ -815,7 +974,7 @@ func (e *IsNullExpr) Equal(other Expr) bool {
 	}
 
 	if isNull, ok := other.(*IsNullExpr); ok {
-		return e.Expr.Equal(isNull.Expr)
+		return e.Not == isNull.Not && e.Expr.Equal(isNull.Expr)
 	}
 
 	return false
@@ -824,6 +983,7 @@ func (e *IsNullExpr) Equal(other Expr) bool {
 func (e *IsNullExpr) Clone() Expr {
 	return &IsNullExpr{
 		Expr: e.Expr.Clone(),
+		Not:  e.Not,
 	}
 }
 
@@ -860,6 +1020,9 @@ func (e *IsNullExpr) Computed() bool {
 }
 
 func (e *IsNullExpr) Name() string {
+	if e.Not {
+		return "isnotnull(" + e.Expr.Name() + ")"
+	}
 	return "isnull(" + e.Expr.Name() + ")"
 }
 
@@ -877,6 +1040,358 @@ func (e *IsNullExpr) MatchPath(path string) bool {
 	return strings.HasPrefix(e.Name(), path)
 }
 
+// InExpr is true for rows where the expression equals any of the values, or
+// none of them if Not is set.
+type InExpr struct {
+	Expr   Expr
+	Values []Expr
+	Not    bool
+}
+
+func (e *InExpr) Equal(other Expr) bool {
+	if other == nil {
+		// if both are nil, they are equal
+		return e == nil
+	}
+
+	in, ok := other.(*InExpr)
+	if !ok || e.Not != in.Not || len(e.Values) != len(in.Values) || !e.Expr.Equal(in.Expr) {
+		return false
+	}
+	for i := range e.Values {
+		if !e.Values[i].Equal(in.Values[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+func (e *InExpr) Clone() Expr {
+	values := make([]Expr, len(e.Values))
+	for i, v := range e.Values {
+		values[i] = v.Clone()
+	}
+	return &InExpr{
+		Expr:   e.Expr.Clone(),
+		Values: values,
+		Not:    e.Not,
+	}
+}
+
+func (e *InExpr) DataType(l ExprTypeFinder) (arrow.DataType, error) {
+	_, err := e.Expr.DataType(l)
+	if err != nil {
+		return nil, err
+	}
+
+	return arrow.FixedWidthTypes.Boolean, nil
+}
+
+func (e *InExpr) Accept(visitor Visitor) bool {
+	continu := visitor.PreVisit(e)
+	if !continu {
+		return false
+	}
+
+	continu = e.Expr.Accept(visitor)
+	if !continu {
+		return false
+	}
+
+	continu = visitor.Visit(e)
+	if !continu {
+		return false
+	}
+
+	for _, v := range e.Values {
+		continu = v.Accept(visitor)
+		if !continu {
+			return false
+		}
+	}
+
+	return visitor.PostVisit(e)
+}
+
+func (e *InExpr) Computed() bool {
+	return true
+}
+
+func (e *InExpr) Name() string {
+	values := make([]string, len(e.Values))
+	for i, v := range e.Values {
+		values[i] = v.Name()
+	}
+	op := " in "
+	if e.Not {
+		op = " not in "
+	}
+	return e.Expr.Name() + op + "(" + strings.Join(values, ", ") + ")"
+}
+
+func (e *InExpr) String() string { return e.Name() }
+
+func (e *InExpr) ColumnsUsedExprs() []Expr {
+	return e.Expr.ColumnsUsedExprs()
+}
+
+func (e *InExpr) MatchColumn(columnName string) bool {
+	return e.Name() == columnName
+}
+
+func (e *InExpr) MatchPath(path string) bool {
+	return strings.HasPrefix(e.Name(), path)
+}
+
+// AnyColumnExpr is true for the rows in which any of the concrete columns of a
+// dynamic column is equal to or matches the value, e.g. the rows with any
+// label equal to "foo". Unlike an OR of comparisons of each concrete column,
+// the concrete columns don't need to be known when planning and are resolved
+// per row group and record. Null values never satisfy the expression.
+type AnyColumnExpr struct {
+	Column *DynamicColumn
+	// Op is either OpEq or OpRegexMatch.
+	Op    Op
+	Value Expr
+}
+
+// AnyEq returns an expression matching the rows in which any concrete column
+// of the dynamic column is equal to e.
+func (c *DynamicColumn) AnyEq(e Expr) *AnyColumnExpr {
+	return &AnyColumnExpr{
+		Column: c,
+		Op:     OpEq,
+		Value:  e,
+	}
+}
+
+// AnyRegexMatch returns an expression matching the rows in which any concrete
+// column of the dynamic column matches the regular expression.
+func (c *DynamicColumn) AnyRegexMatch(pattern string) *AnyColumnExpr {
+	return &AnyColumnExpr{
+		Column: c,
+		Op:     OpRegexMatch,
+		Value:  Literal(pattern),
+	}
+}
+
+func (e *AnyColumnExpr) Equal(other Expr) bool {
+	if other == nil {
+		// if both are nil, they are equal
+		return e == nil
+	}
+
+	if o, ok := other.(*AnyColumnExpr); ok {
+		return e.Op == o.Op && e.Column.Equal(o.Column) && e.Value.Equal(o.Value)
+	}
+
+	return false
+}
+
+func (e *AnyColumnExpr) Clone() Expr {
+	return &AnyColumnExpr{
+		Column: e.Column.Clone().(*DynamicColumn),
+		Op:     e.Op,
+		Value:  e.Value.Clone(),
+	}
+}
+
+func (e *AnyColumnExpr) DataType(_ ExprTypeFinder) (arrow.DataType, error) {
+	return arrow.FixedWidthTypes.Boolean, nil
+}
+
+func (e *AnyColumnExpr) Accept(visitor Visitor) bool {
+	continu := visitor.PreVisit(e)
+	if !continu {
+		return false
+	}
+
+	continu = e.Column.Accept(visitor)
+	if !continu {
+		return false
+	}
+
+	continu = visitor.Visit(e)
+	if !continu {
+		return false
+	}
+
+	continu = e.Value.Accept(visitor)
+	if !continu {
+		return false
+	}
+
+	return visitor.PostVisit(e)
+}
+
+func (e *AnyColumnExpr) Computed() bool {
+	return true
+}
+
+func (e *AnyColumnExpr) Name() string {
+	return "any(" + e.Column.Name() + ") " + e.Op.String() + " " + e.Value.Name()
+}
+
+func (e *AnyColumnExpr) String() string { return e.Name() }
+
+func (e *AnyColumnExpr) ColumnsUsedExprs() []Expr {
+	return e.Column.ColumnsUsedExprs()
+}
+
+func (e *AnyColumnExpr) MatchColumn(columnName string) bool {
+	return e.Name() == columnName
+}
+
+func (e *AnyColumnExpr) MatchPath(path string) bool {
+	return strings.HasPrefix(e.Name(), path)
+}
+
+// TimeUnit is a calendar unit that timestamps can be truncated to.
+type TimeUnit uint32
+
+const (
+	TimeUnitUnknown TimeUnit = iota
+	TimeUnitHour
+	TimeUnitDay
+	// TimeUnitWeek truncates to the start of the week, weeks start on Monday.
+	TimeUnitWeek
+	TimeUnitMonth
+)
+
+func (u TimeUnit) String() string {
+	switch u {
+	case TimeUnitHour:
+		return "hour"
+	case TimeUnitDay:
+		return "day"
+	case TimeUnitWeek:
+		return "week"
+	case TimeUnitMonth:
+		return "month"
+	default:
+		return "unknown"
+	}
+}
+
+// DateTrunc truncates the timestamps of expr to the start of the given
+// calendar unit in the given time zone. If loc is nil, UTC is used. Unlike
+// dividing timestamps by a duration, day, week and month boundaries are
+// computed in local time, so they are correct for time zones that are not
+// aligned with UTC or observe daylight saving time.
+//
+// Int64 timestamps are interpreted as milliseconds since the epoch, use
+// DateTruncExpr.WithPrecision for other precisions. Arrow timestamps use the
+// unit of their type.
+func DateTrunc(expr Expr, unit TimeUnit, loc *time.Location) *DateTruncExpr {
+	if loc == nil {
+		loc = time.UTC
+	}
+	return &DateTruncExpr{
+		Expr:      expr,
+		Unit:      unit,
+		Location:  loc,
+		Precision: arrow.Millisecond,
+	}
+}
+
+type DateTruncExpr struct {
+	Expr     Expr
+	Unit     TimeUnit
+	Location *time.Location
+	// Precision is the precision of int64 timestamps.
+	Precision arrow.TimeUnit
+}
+
+// WithPrecision sets the precision int64 timestamps are interpreted with.
+func (e *DateTruncExpr) WithPrecision(precision arrow.TimeUnit) *DateTruncExpr {
+	e.Precision = precision
+	return e
+}
+
+func (e *DateTruncExpr) Equal(other Expr) bool {
+	if other == nil {
+		// if both are nil, they are equal
+		return e == nil
+	}
+
+	if d, ok := other.(*DateTruncExpr); ok {
+		return e.Unit == d.Unit &&
+			e.Location.String() == d.Location.String() &&
+			e.Precision == d.Precision &&
+			e.Expr.Equal(d.Expr)
+	}
+
+	return false
+}
+
+func (e *DateTruncExpr) Clone() Expr {
+	return &DateTruncExpr{
+		Expr:      e.Expr.Clone(),
+		Unit:      e.Unit,
+		Location:  e.Location,
+		Precision: e.Precision,
+	}
+}
+
+func (e *DateTruncExpr) DataType(l ExprTypeFinder) (arrow.DataType, error) {
+	t, err := e.Expr.DataType(l)
+	if err != nil {
+		return nil, fmt.Errorf("date_trunc type: %w", err)
+	}
+
+	switch t.(type) {
+	case *arrow.Int64Type, *arrow.TimestampType:
+		return t, nil
+	default:
+		return nil, fmt.Errorf("date_trunc: unsupported type %s, expected int64 or timestamp", t)
+	}
+}
+
+func (e *DateTruncExpr) Accept(visitor Visitor) bool {
+	continu := visitor.PreVisit(e)
+	if !continu {
+		return false
+	}
+
+	continu = e.Expr.Accept(visitor)
+	if !continu {
+		return false
+	}
+
+	continu = visitor.Visit(e)
+	if !continu {
+		return false
+	}
+
+	return visitor.PostVisit(e)
+}
+
+func (e *DateTruncExpr) Computed() bool {
+	return true
+}
+
+func (e *DateTruncExpr) Name() string {
+	return "date_trunc(" + e.Unit.String() + ", " + e.Expr.Name() + ", " + e.Location.String() + ")"
+}
+
+func (e *DateTruncExpr) String() string { return e.Name() }
+
+func (e *DateTruncExpr) ColumnsUsedExprs() []Expr {
+	return e.Expr.ColumnsUsedExprs()
+}
+
+func (e *DateTruncExpr) MatchColumn(columnName string) bool {
+	return e.Name() == columnName
+}
+
+func (e *DateTruncExpr) MatchPath(path string) bool {
+	return strings.HasPrefix(e.Name(), path)
+}
+
+func (e *DateTruncExpr) Alias(alias string) *AliasExpr {
+	return &AliasExpr{Expr: e, Alias: alias}
+}
+
 func If(cond, then, els Expr) *IfExpr {
 	return &IfExpr{
 		Cond: cond,
@@ -1136,7 +1651,672 @@ func (d *DurationExpr) Value() time.Duration {
 	return d.duration
 }
 
-type AllExpr struct{}
+// Window assigns the timestamps of expr to windows of the given size that
+// start every step, aligned to multiples of step since the epoch. Used as a
+// group expression of an aggregation, each row is aggregated into every window
+// containing it, so the aggregation emits one row per group and window, which
+// is identified by its start. Windows overlap if step is smaller than size,
+// e.g. 5 minute windows evaluated every minute, and a step equal to the size
+// results in consecutive windows like DurationExpr.
+//
+// Int64 timestamps are interpreted as milliseconds since the epoch, use
+// WindowExpr.WithPrecision for other precisions. Arrow timestamps use the unit
+// of their type.
+func Window(expr Expr, size, step time.Duration) *WindowExpr {
+	return &WindowExpr{
+		Expr:      expr,
+		Size:      size,
+		Step:      step,
+		Precision: arrow.Millisecond,
+	}
+}
+
+type WindowExpr struct {
+	Expr Expr
+	Size time.Duration
+	Step time.Duration
+	// Precision is the precision of int64 timestamps.
+	Precision arrow.TimeUnit
+}
+
+// WithPrecision sets the precision int64 timestamps are interpreted with.
+func (e *WindowExpr) WithPrecision(precision arrow.TimeUnit) *WindowExpr {
+	e.Precision = precision
+	return e
+}
+
+func (e *WindowExpr) Equal(other Expr) bool {
+	if other == nil {
+		// if both are nil, they are equal
+		return e == nil
+	}
+
+	if w, ok := other.(*WindowExpr); ok {
+		return e.Size == w.Size &&
+			e.Step == w.Step &&
+			e.Precision == w.Precision &&
+			e.Expr.Equal(w.Expr)
+	}
+
+	return false
+}
+
+func (e *WindowExpr) Clone() Expr {
+	return &WindowExpr{
+		Expr:      e.Expr.Clone(),
+		Size:      e.Size,
+		Step:      e.Step,
+		Precision: e.Precision,
+	}
+}
+
+func (e *WindowExpr) DataType(l ExprTypeFinder) (arrow.DataType, error) {
+	t, err := e.Expr.DataType(l)
+	if err != nil {
+		return nil, fmt.Errorf("window type: %w", err)
+	}
+
+	switch t.(type) {
+	case *arrow.Int64Type, *arrow.TimestampType:
+		return t, nil
+	default:
+		return nil, fmt.Errorf("window: unsupported type %s, expected int64 or timestamp", t)
+	}
+}
+
+func (e *WindowExpr) Accept(visitor Visitor) bool {
+	continu := visitor.PreVisit(e)
+	if !continu {
+		return false
+	}
+
+	continu = e.Expr.Accept(visitor)
+	if !continu {
+		return false
+	}
+
+	continu = visitor.Visit(e)
+	if !continu {
+		return false
+	}
+
+	return visitor.PostVisit(e)
+}
+
+func (e *WindowExpr) Computed() bool {
+	return true
+}
+
+func (e *WindowExpr) Name() string {
+	return "window(" + e.Expr.Name() + ", " + e.Size.String() + ", " + e.Step.String() + ")"
+}
+
+func (e *WindowExpr) String() string { return e.Name() }
+
+func (e *WindowExpr) ColumnsUsedExprs() []Expr {
+	return e.Expr.ColumnsUsedExprs()
+}
+
+func (e *WindowExpr) MatchColumn(columnName string) bool {
+	return e.Name() == columnName
+}
+
+func (e *WindowExpr) MatchPath(path string) bool {
+	return strings.HasPrefix(e.Name(), path)
+}
+
+func (e *WindowExpr) Alias(alias string) *AliasExpr {
+	return &AliasExpr{Expr: e, Alias: alias}
+}
+
+// MapKey returns the value of key in the map of expr, e.g. a column with a map
+// storage layout, like labels['job']. The value is null for rows whose map
+// does not contain the key.
+func MapKey(expr Expr, key string) *MapKeyExpr {
+	return &MapKeyExpr{Expr: expr, Key: key}
+}
+
+// Key returns the value of key in the map of the column, see MapKey.
+func (c *Column) Key(key string) *MapKeyExpr {
+	return MapKey(c, key)
+}
+
+type MapKeyExpr struct {
+	Expr Expr
+	Key  string
+}
+
+func (e *MapKeyExpr) Equal(other Expr) bool {
+	if other == nil {
+		// if both are nil, they are equal
+		return e == nil
+	}
+
+	if m, ok := other.(*MapKeyExpr); ok {
+		return e.Key == m.Key && e.Expr.Equal(m.Expr)
+	}
+
+	return false
+}
+
+func (e *MapKeyExpr) Clone() Expr {
+	return &MapKeyExpr{
+		Expr: e.Expr.Clone(),
+		Key:  e.Key,
+	}
+}
+
+func (e *MapKeyExpr) DataType(l ExprTypeFinder) (arrow.DataType, error) {
+	t, err := e.Expr.DataType(l)
+	if err != nil {
+		return nil, fmt.Errorf("map key type: %w", err)
+	}
+
+	m, ok := t.(*arrow.MapType)
+	if !ok {
+		return nil, fmt.Errorf("map key: unsupported type %s, expected map", t)
+	}
+	return m.ItemType(), nil
+}
+
+func (e *MapKeyExpr) Accept(visitor Visitor) bool {
+	continu := visitor.PreVisit(e)
+	if !continu {
+		return false
+	}
+
+	continu = e.Expr.Accept(visitor)
+	if !continu {
+		return false
+	}
+
+	continu = visitor.Visit(e)
+	if !continu {
+		return false
+	}
+
+	return visitor.PostVisit(e)
+}
+
+func (e *MapKeyExpr) Computed() bool {
+	return true
+}
+
+func (e *MapKeyExpr) Name() string {
+	return e.Expr.Name() + "['" + e.Key + "']"
+}
+
+func (e *MapKeyExpr) String() string { return e.Name() }
+
+func (e *MapKeyExpr) ColumnsUsedExprs() []Expr {
+	return e.Expr.ColumnsUsedExprs()
+}
+
+func (e *MapKeyExpr) MatchColumn(columnName string) bool {
+	return e.Name() == columnName
+}
+
+func (e *MapKeyExpr) MatchPath(path string) bool {
+	return strings.HasPrefix(e.Name(), path)
+}
+
+func (e *MapKeyExpr) Alias(alias string) *AliasExpr {
+	return &AliasExpr{Expr: e, Alias: alias}
+}
+
+func (e *MapKeyExpr) Eq(v Expr) *BinaryExpr {
+	return &BinaryExpr{
+		Left:  e,
+		Op:    OpEq,
+		Right: v,
+	}
+}
+
+func (e *MapKeyExpr) NotEq(v Expr) *BinaryExpr {
+	return &BinaryExpr{
+		Left:  e,
+		Op:    OpNotEq,
+		Right: v,
+	}
+}
+
+func (e *MapKeyExpr) RegexMatch(pattern string) *BinaryExpr {
+	return &BinaryExpr{
+		Left:  e,
+		Op:    OpRegexMatch,
+		Right: Literal(pattern),
+	}
+}
+
+func (e *MapKeyExpr) RegexNotMatch(pattern string) *BinaryExpr {
+	return &BinaryExpr{
+		Left:  e,
+		Op:    OpRegexNotMatch,
+		Right: Literal(pattern),
+	}
+}
+
+// StringFunc is a scalar function on strings, see StringFuncExpr.
+type StringFunc uint32
+
+const (
+	StringFuncUnknown StringFunc = iota
+	StringFuncConcat
+	StringFuncLower
+	StringFuncUpper
+	StringFuncTrimPrefix
+	StringFuncSubstring
+	StringFuncRegexExtract
+)
+
+func (f StringFunc) String() string {
+	switch f {
+	case StringFuncConcat:
+		return "concat"
+	case StringFuncLower:
+		return "lower"
+	case StringFuncUpper:
+		return "upper"
+	case StringFuncTrimPrefix:
+		return "trim_prefix"
+	case StringFuncSubstring:
+		return "substring"
+	case StringFuncRegexExtract:
+		return "regex_extract"
+	default:
+		return "unknown"
+	}
+}
+
+// Concat returns the concatenation of the strings of exprs, e.g. columns or
+// string literals. The result is null if any of the strings is null.
+func Concat(exprs ...Expr) *StringFuncExpr {
+	return &StringFuncExpr{Func: StringFuncConcat, Args: exprs}
+}
+
+// Lower returns the string of expr with all Unicode letters mapped to their
+// lower case.
+func Lower(expr Expr) *StringFuncExpr {
+	return &StringFuncExpr{Func: StringFuncLower, Args: []Expr{expr}}
+}
+
+// Upper returns the string of expr with all Unicode letters mapped to their
+// upper case.
+func Upper(expr Expr) *StringFuncExpr {
+	return &StringFuncExpr{Func: StringFuncUpper, Args: []Expr{expr}}
+}
+
+// TrimPrefix returns the string of expr without the leading prefix. Strings
+// that don't start with prefix are returned unchanged.
+func TrimPrefix(expr Expr, prefix string) *StringFuncExpr {
+	return &StringFuncExpr{Func: StringFuncTrimPrefix, Args: []Expr{expr, Literal(prefix)}}
+}
+
+// Substring returns at most length characters of the string of expr, starting
+// at the character at start. As in SQL, the first character is at start 1.
+// The result is empty if the string has less than start characters.
+func Substring(expr Expr, start, length int) *StringFuncExpr {
+	return &StringFuncExpr{Func: StringFuncSubstring, Args: []Expr{expr, Literal(int64(start)), Literal(int64(length))}}
+}
+
+// RegexExtract returns the text matched by the given capture group of the
+// leftmost match of pattern in the string of expr, group 0 being the entire
+// match. The result is null if the pattern does not match.
+func RegexExtract(expr Expr, pattern string, group int) *StringFuncExpr {
+	return &StringFuncExpr{Func: StringFuncRegexExtract, Args: []Expr{expr, Literal(pattern), Literal(int64(group))}}
+}
+
+// StringFuncExpr applies a scalar string function to its arguments. The first
+// argument is the string the function is applied to, further arguments are
+// literal parameters of the function, except for concat whose arguments are
+// all concatenated. The result of a row is null if its string is null.
+type StringFuncExpr struct {
+	Func StringFunc
+	Args []Expr
+}
+
+func (e *StringFuncExpr) Equal(other Expr) bool {
+	if other == nil {
+		// if both are nil, they are equal
+		return e == nil
+	}
+
+	f, ok := other.(*StringFuncExpr)
+	if !ok || e.Func != f.Func || len(e.Args) != len(f.Args) {
+		return false
+	}
+	for i := range e.Args {
+		if !e.Args[i].Equal(f.Args[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+func (e *StringFuncExpr) Clone() Expr {
+	args := make([]Expr, 0, len(e.Args))
+	for _, arg := range e.Args {
+		args = append(args, arg.Clone())
+	}
+	return &StringFuncExpr{
+		Func: e.Func,
+		Args: args,
+	}
+}
+
+func (e *StringFuncExpr) DataType(l ExprTypeFinder) (arrow.DataType, error) {
+	if err := e.ValidateArgs(); err != nil {
+		return nil, err
+	}
+	for _, arg := range e.StringArgs() {
+		t, err := arg.DataType(l)
+		if err != nil {
+			return nil, fmt.Errorf("%s type: %w", e.Func, err)
+		}
+		if !isStringType(t) {
+			return nil, fmt.Errorf("%s: unsupported type %s, expected string", e.Func, t)
+		}
+	}
+	return arrow.BinaryTypes.String, nil
+}
+
+// StringArgs returns the arguments of the function that are strings the
+// function is applied to, as opposed to its parameters.
+func (e *StringFuncExpr) StringArgs() []Expr {
+	if e.Func == StringFuncConcat || len(e.Args) == 0 {
+		return e.Args
+	}
+	return e.Args[:1]
+}
+
+// ValidateArgs validates the number of arguments of the function and the
+// types and values of its literal parameters.
+func (e *StringFuncExpr) ValidateArgs() error {
+	var params []arrow.DataType
+	switch e.Func {
+	case StringFuncConcat:
+		if len(e.Args) == 0 {
+			return errors.New("concat: expected at least one argument")
+		}
+		return nil
+	case StringFuncLower, StringFuncUpper:
+	case StringFuncTrimPrefix:
+		params = []arrow.DataType{arrow.BinaryTypes.String}
+	case StringFuncSubstring:
+		params = []arrow.DataType{arrow.PrimitiveTypes.Int64, arrow.PrimitiveTypes.Int64}
+	case StringFuncRegexExtract:
+		params = []arrow.DataType{arrow.BinaryTypes.String, arrow.PrimitiveTypes.Int64}
+	default:
+		return fmt.Errorf("unknown string function %d", e.Func)
+	}
+	if len(e.Args) != len(params)+1 {
+		return fmt.Errorf("%s: expected %d arguments, got %d", e.Func, len(params)+1, len(e.Args))
+	}
+	for i, param := range params {
+		lit, ok := e.Args[i+1].(*LiteralExpr)
+		if !ok || !lit.Value.IsValid() || !arrow.TypeEqual(lit.Value.DataType(), param) {
+			return fmt.Errorf("%s: argument %d must be a %s literal", e.Func, i+2, param)
+		}
+	}
+
+	switch e.Func {
+	case StringFuncSubstring:
+		start := e.Args[1].(*LiteralExpr).Value.(*scalar.Int64).Value
+		length := e.Args[2].(*LiteralExpr).Value.(*scalar.Int64).Value
+		if start < 1 || length < 0 {
+			return fmt.Errorf("substring: invalid start %d or length %d", start, length)
+		}
+	case StringFuncRegexExtract:
+		pattern := string(e.Args[1].(*LiteralExpr).Value.(*scalar.String).Data())
+		group := e.Args[2].(*LiteralExpr).Value.(*scalar.Int64).Value
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return fmt.Errorf("regex_extract: invalid pattern: %w", err)
+		}
+		if group < 0 || group > int64(re.NumSubexp()) {
+			return fmt.Errorf("regex_extract: pattern has no group %d", group)
+		}
+	}
+	return nil
+}
+
+// isStringType returns whether t holds strings, possibly dictionary encoded.
+func isStringType(t arrow.DataType) bool {
+	if dict, ok := t.(*arrow.DictionaryType); ok {
+		t = dict.ValueType
+	}
+	switch t.ID() {
+	case arrow.STRING, arrow.BINARY:
+		return true
+	default:
+		return false
+	}
+}
+
+func (e *StringFuncExpr) Accept(visitor Visitor) bool {
+	continu := visitor.PreVisit(e)
+	if !continu {
+		return false
+	}
+
+	for _, arg := range e.Args {
+		continu = arg.Accept(visitor)
+		if !continu {
+			return false
+		}
+	}
+
+	continu = visitor.Visit(e)
+	if !continu {
+		return false
+	}
+
+	return visitor.PostVisit(e)
+}
+
+func (e *StringFuncExpr) Computed() bool {
+	return true
+}
+
+func (e *StringFuncExpr) Name() string {
+	names := make([]string, 0, len(e.Args))
+	for _, arg := range e.Args {
+		names = append(names, arg.Name())
+	}
+	return e.Func.String() + "(" + strings.Join(names, ", ") + ")"
+}
+
+func (e *StringFuncExpr) String() string { return e.Name() }
+
+func (e *StringFuncExpr) ColumnsUsedExprs() []Expr {
+	var columns []Expr
+	for _, arg := range e.Args {
+		columns = append(columns, arg.ColumnsUsedExprs()...)
+	}
+	return columns
+}
+
+func (e *StringFuncExpr) MatchColumn(columnName string) bool {
+	return e.Name() == columnName
+}
+
+func (e *StringFuncExpr) MatchPath(path string) bool {
+	return strings.HasPrefix(e.Name(), path)
+}
+
+func (e *StringFuncExpr) Alias(alias string) *AliasExpr {
+	return &AliasExpr{Expr: e, Alias: alias}
+}
+
+func (e *StringFuncExpr) Eq(v Expr) *BinaryExpr {
+	return &BinaryExpr{
+		Left:  e,
+		Op:    OpEq,
+		Right: v,
+	}
+}
+
+func (e *StringFuncExpr) NotEq(v Expr) *BinaryExpr {
+	return &BinaryExpr{
+		Left:  e,
+		Op:    OpNotEq,
+		Right: v,
+	}
+}
+
+func (e *StringFuncExpr) RegexMatch(pattern string) *BinaryExpr {
+	return &BinaryExpr{
+		Left:  e,
+		Op:    OpRegexMatch,
+		Right: Literal(pattern),
+	}
+}
+
+// Coalesce returns the value of the first of exprs that is not null, e.g.
+// Coalesce(Col("labels.job"), Literal("unknown")) substitutes "unknown" for
+// rows without a job label. A column missing from a record is null in every
+// row. The exprs must be of the same type, except that strings and binaries,
+// possibly dictionary encoded, may be mixed and result in a string.
+func Coalesce(exprs ...Expr) *CoalesceExpr {
+	return &CoalesceExpr{Exprs: exprs}
+}
+
+type CoalesceExpr struct {
+	Exprs []Expr
+}
+
+func (e *CoalesceExpr) Equal(other Expr) bool {
+	if other == nil {
+		// if both are nil, they are equal
+		return e == nil
+	}
+
+	c, ok := other.(*CoalesceExpr)
+	if !ok || len(e.Exprs) != len(c.Exprs) {
+		return false
+	}
+	for i := range e.Exprs {
+		if !e.Exprs[i].Equal(c.Exprs[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+func (e *CoalesceExpr) Clone() Expr {
+	exprs := make([]Expr, 0, len(e.Exprs))
+	for _, expr := range e.Exprs {
+		exprs = append(exprs, expr.Clone())
+	}
+	return &CoalesceExpr{Exprs: exprs}
+}
+
+func (e *CoalesceExpr) DataType(l ExprTypeFinder) (arrow.DataType, error) {
+	if len(e.Exprs) == 0 {
+		return nil, errors.New("coalesce: expected at least one argument")
+	}
+
+	types := make([]arrow.DataType, 0, len(e.Exprs))
+	for _, expr := range e.Exprs {
+		t, err := expr.DataType(l)
+		if err != nil {
+			return nil, fmt.Errorf("coalesce type: %w", err)
+		}
+		if !isNullType(t) {
+			types = append(types, t)
+		}
+	}
+	return CoalesceType(types)
+}
+
+// CoalesceType returns the type of the result of coalescing values of the
+// given types, which must not be null types.
+func CoalesceType(types []arrow.DataType) (arrow.DataType, error) {
+	if len(types) == 0 {
+		return arrow.Null, nil
+	}
+
+	allStrings := true
+	for _, t := range types {
+		allStrings = allStrings && isStringType(t)
+	}
+	if allStrings {
+		if len(types) == 1 {
+			return types[0], nil
+		}
+		return arrow.BinaryTypes.String, nil
+	}
+
+	for _, t := range types[1:] {
+		if !arrow.TypeEqual(t, types[0]) {
+			return nil, fmt.Errorf("coalesce: arguments must be of the same type, got %s and %s", types[0], t)
+		}
+	}
+	return types[0], nil
+}
+
+// isNullType returns whether t is the type of values that are always null,
+// e.g. of Literal(nil).
+func isNullType(t arrow.DataType) bool {
+	return t != nil && t.ID() == arrow.NULL
+}
+
+func (e *CoalesceExpr) Accept(visitor Visitor) bool {
+	continu := visitor.PreVisit(e)
+	if !continu {
+		return false
+	}
+
+	for _, expr := range e.Exprs {
+		continu = expr.Accept(visitor)
+		if !continu {
+			return false
+		}
+	}
+
+	continu = visitor.Visit(e)
+	if !continu {
+		return false
+	}
+
+	return visitor.PostVisit(e)
+}
+
+func (e *CoalesceExpr) Computed() bool {
+	return true
+}
+
+func (e *CoalesceExpr) Name() string {
+	names := make([]string, 0, len(e.Exprs))
+	for _, expr := range e.Exprs {
+		names = append(names, expr.Name())
+	}
+	return "coalesce(" + strings.Join(names, ", ") + ")"
+}
+
+func (e *CoalesceExpr) String() string { return e.Name() }
+
+func (e *CoalesceExpr) ColumnsUsedExprs() []Expr {
+	var columns []Expr
+	for _, expr := range e.Exprs {
+		columns = append(columns, expr.ColumnsUsedExprs()...)
+	}
+	return columns
+}
+
+func (e *CoalesceExpr) MatchColumn(columnName string) bool {
+	return e.Name() == columnName
+}
+
+func (e *CoalesceExpr) MatchPath(path string) bool {
+	return strings.HasPrefix(e.Name(), path)
+}
+
+func (e *CoalesceExpr) Alias(alias string) *AliasExpr {
+	return &AliasExpr{Expr: e, Alias: alias}
+}
+
+type AllExpr struct{}
 
 func All() *AllExpr {
 	return &AllExpr{}
@@ -1224,3 +2404,69 @@ func (n *NotExpr) MatchColumn(columnName string) bool { return !n.Expr.MatchColu
 func (n *NotExpr) MatchPath(path string) bool         { return !n.Expr.MatchPath(path) }
 func (n *NotExpr) Computed() bool                     { return false }
 func (n *NotExpr) Clone() Expr                        { return &NotExpr{Expr: n.Expr} }
+
+// SortExpr specifies the direction an expression is sorted in by OrderBy.
+// Expressions passed to OrderBy without a SortExpr are sorted in ascending
+// order.
+type SortExpr struct {
+	Expr       Expr
+	Descending bool
+}
+
+// Asc sorts by the given expression in ascending order.
+func Asc(expr Expr) *SortExpr {
+	return &SortExpr{Expr: expr}
+}
+
+// Desc sorts by the given expression in descending order.
+func Desc(expr Expr) *SortExpr {
+	return &SortExpr{Expr: expr, Descending: true}
+}
+
+func (s *SortExpr) Equal(other Expr) bool {
+	if other == nil {
+		// if both are nil, they are equal
+		return s == nil
+	}
+
+	if sort, ok := other.(*SortExpr); ok {
+		return s.Descending == sort.Descending && s.Expr.Equal(sort.Expr)
+	}
+
+	return false
+}
+
+func (s *SortExpr) DataType(l ExprTypeFinder) (arrow.DataType, error) {
+	return s.Expr.DataType(l)
+}
+
+func (s *SortExpr) Accept(visitor Visitor) bool {
+	continu := visitor.PreVisit(s)
+	if !continu {
+		return false
+	}
+
+	continu = s.Expr.Accept(visitor)
+	if !continu {
+		return false
+	}
+
+	return visitor.PostVisit(s)
+}
+
+func (s *SortExpr) Name() string { return s.Expr.Name() }
+
+func (s *SortExpr) String() string {
+	if s.Descending {
+		return s.Expr.String() + " desc"
+	}
+	return s.Expr.String() + " asc"
+}
+
+func (s *SortExpr) ColumnsUsedExprs() []Expr           { return s.Expr.ColumnsUsedExprs() }
+func (s *SortExpr) MatchColumn(columnName string) bool { return s.Expr.MatchColumn(columnName) }
+func (s *SortExpr) MatchPath(path string) bool         { return s.Expr.MatchPath(path) }
+func (s *SortExpr) Computed() bool                     { return s.Expr.Computed() }
+func (s *SortExpr) Clone() Expr {
+	return &SortExpr{Expr: s.Expr.Clone(), Descending: s.Descending}
+}
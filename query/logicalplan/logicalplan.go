@@ -26,6 +26,8 @@ type LogicalPlan struct {
 	Aggregation *Aggregation
 	Limit       *Limit
 	Sample      *Sample
+	OrderBy     *OrderBy
+	Join        *Join
 }
 
 // Callback is a function that is called throughout a chain of operators
@@ -38,7 +40,17 @@ type IterOptions struct {
 	Projection         []Expr
 	Filter             Expr
 	DistinctColumns    []Expr
-	ReadMode           ReadMode
+	// GroupBy are the columns the query groups by. Tables maintaining several
+	// sort orders use them, together with the filter, to pick the sort order
+	// to read.
+	GroupBy  []Expr
+	ReadMode ReadMode
+	// Provenance indicates that records should be annotated with metadata
+	// describing where their rows were read from.
+	Provenance bool
+	// DictionaryEncode indicates that binary columns should be read as
+	// dictionary encoded columns.
+	DictionaryEncode bool
 }
 
 type Option func(opts *IterOptions)
@@ -49,6 +61,21 @@ func WithReadMode(m ReadMode) Option {
 	}
 }
 
+func WithProvenance() Option {
+	return func(opts *IterOptions) {
+		opts.Provenance = true
+	}
+}
+
+// WithDictionaryEncoding reads binary columns, and lists of them, as
+// dictionary encoded columns, even if they are not dictionary encoded in
+// storage.
+func WithDictionaryEncoding() Option {
+	return func(opts *IterOptions) {
+		opts.DictionaryEncode = true
+	}
+}
+
 func WithPhysicalProjection(e ...Expr) Option {
 	return func(opts *IterOptions) {
 		opts.PhysicalProjection = append(opts.PhysicalProjection, e...)
@@ -73,6 +100,12 @@ func WithDistinctColumns(e ...Expr) Option {
 	}
 }
 
+func WithGroupBy(e ...Expr) Option {
+	return func(opts *IterOptions) {
+		opts.GroupBy = append(opts.GroupBy, e...)
+	}
+}
+
 func (plan *LogicalPlan) String() string {
 	return plan.string(0)
 }
@@ -92,11 +125,18 @@ func (plan *LogicalPlan) string(indent int) string {
 		res = plan.Aggregation.String()
 	case plan.Distinct != nil:
 		res = plan.Distinct.String()
+	case plan.OrderBy != nil:
+		res = plan.OrderBy.String()
+	case plan.Join != nil:
+		res = plan.Join.String()
 	default:
 		res = "Unknown LogicalPlan"
 	}
 
 	res = strings.Repeat("  ", indent) + res
+	if plan.Join != nil && plan.Join.Right != nil {
+		res += "\n" + plan.Join.Right.string(indent+1)
+	}
 	if plan.Input != nil {
 		res += "\n" + plan.Input.string(indent+1)
 	}
@@ -145,7 +185,7 @@ func (plan *LogicalPlan) DataTypeForExpr(expr Expr) (arrow.DataType, error) {
 				return arrow.PrimitiveTypes.Int64, nil
 			}
 
-			return agg.Expr.DataType(plan.Input)
+			return agg.DataType(plan.Input)
 		}
 
 		t, err := expr.DataType(plan.Input)
@@ -167,6 +207,24 @@ func (plan *LogicalPlan) DataTypeForExpr(expr Expr) (arrow.DataType, error) {
 			return nil, fmt.Errorf("data type for expr %v within Sample: %w", expr, err)
 		}
 
+		return t, nil
+	case plan.OrderBy != nil:
+		t, err := plan.Input.DataTypeForExpr(expr)
+		if err != nil {
+			return nil, fmt.Errorf("data type for expr %v within OrderBy: %w", expr, err)
+		}
+
+		return t, nil
+	case plan.Join != nil:
+		t, err := plan.Input.DataTypeForExpr(expr)
+		if err == nil {
+			return t, nil
+		}
+		t, err = plan.Join.Right.DataTypeForExpr(expr)
+		if err != nil {
+			return nil, fmt.Errorf("data type for expr %v within Join: %w", expr, err)
+		}
+
 		return t, nil
 	default:
 		return nil, fmt.Errorf("unknown logical plan")
@@ -236,6 +294,23 @@ type TableReader interface {
 	) error
 	Schema() *dynparquet.Schema
 }
+
+// RowCounter is implemented by tables that can count their rows at the given
+// transaction from metadata, without reading any columns.
+type RowCounter interface {
+	RowCount(ctx context.Context, tx uint64, options ...Option) (int64, error)
+}
+
+// DataVersioner is implemented by tables that can identify the data they
+// return at a transaction, which allows the results of queries reading them to
+// be cached.
+type DataVersioner interface {
+	// DataVersion returns the version of the data the table returns when read
+	// at tx. Reads with equal versions return equal data. It returns false if
+	// the data may change without the version changing.
+	DataVersion(tx uint64) (string, bool)
+}
+
 type TableProvider interface {
 	GetTable(name string) (TableReader, error)
 }
@@ -256,11 +331,22 @@ type TableScan struct {
 	// Distinct describes the columns that are to be distinct.
 	Distinct []Expr
 
+	// GroupBy describes the columns the query groups the scanned data by.
+	GroupBy []Expr
+
 	// Projection is the list of columns that are to be projected.
 	Projection []Expr
 
 	// ReadMode indicates the mode to use when reading.
 	ReadMode ReadMode
+
+	// Provenance indicates whether scanned records are annotated with
+	// metadata describing where their rows were read from.
+	Provenance bool
+
+	// DictionaryEncode indicates whether binary columns are scanned as
+	// dictionary encoded columns.
+	DictionaryEncode bool
 }
 
 func (scan *TableScan) DataTypeForExpr(expr Expr) (arrow.DataType, error) {
@@ -328,7 +414,8 @@ func (scan *TableScan) String() string {
 		" Table: " + scan.TableName +
 		" Projection: " + fmt.Sprint(scan.Projection) +
 		" Filter: " + fmt.Sprint(scan.Filter) +
-		" Distinct: " + fmt.Sprint(scan.Distinct)
+		" Distinct: " + fmt.Sprint(scan.Distinct) +
+		" GroupBy: " + fmt.Sprint(scan.GroupBy)
 }
 
 type ReadMode int
@@ -416,11 +503,20 @@ func (a *Aggregation) String() string {
 }
 
 type Limit struct {
+	// Expr is the maximum number of rows to return. If nil, all rows after
+	// the offset are returned.
 	Expr Expr
+	// Offset is the number of rows to skip before rows are returned. If nil,
+	// no rows are skipped.
+	Offset Expr
 }
 
 func (l *Limit) String() string {
-	return "Limit" + " Expr: " + fmt.Sprint(l.Expr)
+	res := "Limit" + " Expr: " + fmt.Sprint(l.Expr)
+	if l.Offset != nil {
+		res += " Offset: " + fmt.Sprint(l.Offset)
+	}
+	return res
 }
 
 type Sample struct {
@@ -431,3 +527,51 @@ type Sample struct {
 func (s *Sample) String() string {
 	return "Sample" + " Expr: " + fmt.Sprint(s.Expr)
 }
+
+type OrderBy struct {
+	Exprs []Expr
+}
+
+func (o *OrderBy) String() string {
+	return "OrderBy" + " Exprs: " + fmt.Sprint(o.Exprs)
+}
+
+// Join is an inner equi-join of its input with the rows of Right. Each
+// expression of On is either a column that both inputs have, or an equality
+// of a column of the input with a column of Right, e.g.
+// Col("id").Eq(Col("sample_id")). Rows match if all their join columns are
+// equal and not null. Joined rows contain the columns of both inputs, except
+// that the columns of Right that were joined by name are omitted.
+type Join struct {
+	Right *LogicalPlan
+	On    []Expr
+}
+
+func (j *Join) String() string {
+	return "Join" + " On: " + fmt.Sprint(j.On)
+}
+
+// Keys returns the columns of the input and of Right that rows are joined by.
+// It returns false if an expression of On is not a valid join expression.
+func (j *Join) Keys() (left, right []*Column, ok bool) {
+	left = make([]*Column, 0, len(j.On))
+	right = make([]*Column, 0, len(j.On))
+	for _, expr := range j.On {
+		switch e := expr.(type) {
+		case *Column:
+			left = append(left, e)
+			right = append(right, e)
+		case *BinaryExpr:
+			l, lok := e.Left.(*Column)
+			r, rok := e.Right.(*Column)
+			if e.Op != OpEq || !lok || !rok {
+				return nil, nil, false
+			}
+			left = append(left, l)
+			right = append(right, r)
+		default:
+			return nil, nil, false
+		}
+	}
+	return left, right, true
+}
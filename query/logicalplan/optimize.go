@@ -1,5 +1,12 @@
 package logicalplan
 
+import (
+	"cmp"
+	"math/big"
+
+	"github.com/apache/arrow/go/v17/arrow/scalar"
+)
+
 var hashedMatch = "hashed"
 
 type Optimizer interface {
@@ -8,6 +15,7 @@ type Optimizer interface {
 
 func DefaultOptimizers() []Optimizer {
 	return []Optimizer{
+		&ExprSimplification{},
 		&PhysicalProjectionPushDown{
 			defaultProjections: []Expr{
 				Not(DynCol(hashedMatch)),
@@ -15,7 +23,9 @@ func DefaultOptimizers() []Optimizer {
 		},
 		&FilterPushDown{},
 		&DistinctPushDown{},
+		&GroupByPushDown{},
 		&AggFuncPushDown{},
+		&JoinOptimization{},
 	}
 }
 
@@ -42,6 +52,14 @@ func (p *PhysicalProjectionPushDown) optimize(plan *LogicalPlan, columnsUsedExpr
 	case plan.Filter != nil:
 		p.defaultProjections = []Expr{}
 		columnsUsedExprs = append(columnsUsedExprs, plan.Filter.Expr.ColumnsUsedExprs()...)
+	case plan.OrderBy != nil:
+		// Sorting needs its columns in addition to the ones used by
+		// subsequent layers, if these restrict the columns read at all.
+		if len(columnsUsedExprs) > 0 {
+			for _, expr := range plan.OrderBy.Exprs {
+				columnsUsedExprs = append(columnsUsedExprs, expr.ColumnsUsedExprs()...)
+			}
+		}
 	case plan.Distinct != nil:
 		// distinct is projecting so we need to reset
 		columnsUsedExprs = []Expr{}
@@ -49,7 +67,10 @@ func (p *PhysicalProjectionPushDown) optimize(plan *LogicalPlan, columnsUsedExpr
 			columnsUsedExprs = append(columnsUsedExprs, expr.ColumnsUsedExprs()...)
 		}
 	case plan.Projection != nil:
-		// projections are is projecting so we need to reset
+		// projections are is projecting so we need to reset. Only the
+		// columns used by the projected expressions are read, computed
+		// expressions are evaluated on top of them.
+		p.defaultProjections = []Expr{}
 		columnsUsedExprs = []Expr{}
 		for _, expr := range plan.Projection.Exprs {
 			columnsUsedExprs = append(columnsUsedExprs, expr.ColumnsUsedExprs()...)
@@ -65,6 +86,15 @@ func (p *PhysicalProjectionPushDown) optimize(plan *LogicalPlan, columnsUsedExpr
 		}
 		p.defaultProjections = []Expr{}
 		columnsUsedExprs = append(columnsUsedExprs, DynCol(hashedMatch))
+	case plan.Join != nil:
+		// The input needs its join columns in addition to the ones used by
+		// subsequent layers, if these restrict the columns read at all.
+		if len(columnsUsedExprs) > 0 {
+			left, _, _ := plan.Join.Keys()
+			for _, col := range left {
+				columnsUsedExprs = append(columnsUsedExprs, col)
+			}
+		}
 	}
 
 	if plan.Input != nil {
@@ -97,6 +127,9 @@ func (p *FilterPushDown) optimize(plan *LogicalPlan, exprs []Expr) {
 		}
 	case plan.Filter != nil:
 		exprs = append(exprs, plan.Filter.Expr)
+	case plan.Join != nil:
+		// Filters may refer to columns of the right input of the join.
+		exprs = nil
 	}
 
 	if plan.Input != nil {
@@ -156,6 +189,39 @@ func (p *DistinctPushDown) optimize(plan *LogicalPlan, distinctColumns []Expr) {
 	}
 }
 
+// GroupByPushDown optimizer pushes the columns an aggregation groups by down
+// to the table scan, so that tables maintaining several sort orders can read
+// the sort order that groups equal values together. Group columns are only
+// pushed down through filters, since other plans may change what the columns
+// refer to. It modifies the plan in place.
+type GroupByPushDown struct{}
+
+func (p *GroupByPushDown) Optimize(plan *LogicalPlan) *LogicalPlan {
+	p.optimize(plan, nil)
+	return plan
+}
+
+func (p *GroupByPushDown) optimize(plan *LogicalPlan, groupColumns []Expr) {
+	switch {
+	case plan.TableScan != nil:
+		if len(groupColumns) > 0 {
+			plan.TableScan.GroupBy = groupColumns
+		}
+	case plan.Aggregation != nil:
+		groupColumns = []Expr{}
+		for _, expr := range plan.Aggregation.GroupExprs {
+			groupColumns = append(groupColumns, expr.ColumnsUsedExprs()...)
+		}
+	case plan.Filter != nil:
+	default:
+		groupColumns = []Expr{}
+	}
+
+	if plan.Input != nil {
+		p.optimize(plan.Input, groupColumns)
+	}
+}
+
 // AggFuncPushDown optimizer tries to push down an aggregation function operator
 // to the table provider. This can be done in the case of some aggregation
 // functions on global aggregations (i.e. no group by) without filters.
@@ -191,3 +257,312 @@ func (p *AggFuncPushDown) optimize(plan *LogicalPlan, filterExpr Expr) {
 		p.optimize(plan.Input, filterExpr)
 	}
 }
+
+// JoinOptimization optimizer optimizes the right inputs of joins, which the
+// other optimizers don't traverse, as plans of their own. It modifies the plan
+// in place.
+type JoinOptimization struct{}
+
+func (p *JoinOptimization) Optimize(plan *LogicalPlan) *LogicalPlan {
+	for node := plan; node != nil; node = node.Input {
+		if node.Join == nil || node.Join.Right == nil {
+			continue
+		}
+		for _, optimizer := range DefaultOptimizers() {
+			node.Join.Right = optimizer.Optimize(node.Join.Right)
+		}
+	}
+	return plan
+}
+
+// ExprSimplification optimizer simplifies the expressions of a plan before
+// any other optimizer runs, as plans built by higher-level layers often
+// contain duplicated or redundant expressions that each cost conversion work.
+// Filter expressions are constant folded and duplicate conjuncts removed (e.g.
+// `x AND x` becomes `x`), adjacent filters are merged and filters that are
+// always true are removed. Duplicate projection expressions are removed and
+// adjacent projections are merged if the outer projection only selects
+// columns produced by the inner one. It modifies the plan in place.
+type ExprSimplification struct{}
+
+func (p *ExprSimplification) Optimize(plan *LogicalPlan) *LogicalPlan {
+	return p.optimize(plan)
+}
+
+func (p *ExprSimplification) optimize(plan *LogicalPlan) *LogicalPlan {
+	if plan == nil {
+		return nil
+	}
+	plan.Input = p.optimize(plan.Input)
+
+	switch {
+	case plan.Filter != nil:
+		expr := SimplifyExpr(plan.Filter.Expr)
+		if lit, ok := expr.(*LiteralExpr); ok {
+			if b, ok := lit.Value.(*scalar.Boolean); ok && b.Valid && b.Value {
+				// The filter is always true.
+				return plan.Input
+			}
+			// Filters that are always false (or don't evaluate to a
+			// boolean) are left untouched, physical filters require a
+			// column to be filtered.
+			expr = plan.Filter.Expr
+		}
+		if plan.Input != nil && plan.Input.Filter != nil {
+			expr = and(dedupeExprs(append(
+				splitConjunction(plan.Input.Filter.Expr),
+				splitConjunction(expr)...,
+			)))
+			plan.Input = plan.Input.Input
+		}
+		plan.Filter.Expr = expr
+	case plan.Projection != nil:
+		plan.Projection.Exprs = dedupeExprs(plan.Projection.Exprs)
+		if plan.Input != nil && plan.Input.Projection != nil {
+			if merged, ok := mergeProjections(plan.Projection.Exprs, plan.Input.Projection.Exprs); ok {
+				plan.Projection.Exprs = merged
+				plan.Input = plan.Input.Input
+			}
+		}
+	}
+
+	return plan
+}
+
+// mergeProjections returns the expressions of a single projection equivalent
+// to projecting outer on top of inner. This is only possible if every outer
+// expression selects the output of an inner expression.
+func mergeProjections(outer, inner []Expr) ([]Expr, bool) {
+	merged := make([]Expr, 0, len(outer))
+	for _, o := range outer {
+		found := false
+		for _, i := range inner {
+			col, isCol := o.(*Column)
+			if o.Equal(i) || (isCol && col.ColumnName == i.Name()) {
+				merged = append(merged, i)
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, false
+		}
+	}
+	return merged, true
+}
+
+// SimplifyExpr returns a simplified version of the given expression. Binary
+// expressions of literals are constant folded, boolean literals are
+// eliminated from conjunctions and disjunctions, and duplicate operands of
+// conjunctions and disjunctions are removed.
+func SimplifyExpr(expr Expr) Expr {
+	switch e := expr.(type) {
+	case *BinaryExpr:
+		left := SimplifyExpr(e.Left)
+		right := SimplifyExpr(e.Right)
+		switch e.Op {
+		case OpAnd:
+			if b, ok := boolLiteral(left); ok {
+				if b {
+					return right
+				}
+				return left
+			}
+			if b, ok := boolLiteral(right); ok {
+				if b {
+					return left
+				}
+				return right
+			}
+			if left.Equal(right) {
+				return left
+			}
+			conjuncts := append(splitConjunction(left), splitConjunction(right)...)
+			if deduped := dedupeExprs(conjuncts); len(deduped) < len(conjuncts) {
+				return and(deduped)
+			}
+		case OpOr:
+			if b, ok := boolLiteral(left); ok {
+				if b {
+					return left
+				}
+				return right
+			}
+			if b, ok := boolLiteral(right); ok {
+				if b {
+					return right
+				}
+				return left
+			}
+			if left.Equal(right) {
+				return left
+			}
+		default:
+			if folded, ok := foldConstant(left, e.Op, right); ok {
+				return folded
+			}
+		}
+		return &BinaryExpr{Left: left, Op: e.Op, Right: right}
+	case *NotExpr:
+		inner := SimplifyExpr(e.Expr)
+		if b, ok := boolLiteral(inner); ok {
+			return Literal(!b)
+		}
+		return &NotExpr{Expr: inner}
+	default:
+		return expr
+	}
+}
+
+// splitConjunction returns the operands of the given (possibly nested)
+// conjunction.
+func splitConjunction(expr Expr) []Expr {
+	if e, ok := expr.(*BinaryExpr); ok && e.Op == OpAnd {
+		return append(splitConjunction(e.Left), splitConjunction(e.Right)...)
+	}
+	return []Expr{expr}
+}
+
+// dedupeExprs returns the given expressions without duplicates, preserving
+// the order of first occurrence.
+func dedupeExprs(exprs []Expr) []Expr {
+	deduped := make([]Expr, 0, len(exprs))
+outer:
+	for _, expr := range exprs {
+		for _, seen := range deduped {
+			if seen.Equal(expr) {
+				continue outer
+			}
+		}
+		deduped = append(deduped, expr)
+	}
+	return deduped
+}
+
+// boolLiteral returns the value of the given expression if it is a non-null
+// boolean literal.
+func boolLiteral(expr Expr) (bool, bool) {
+	lit, ok := expr.(*LiteralExpr)
+	if !ok {
+		return false, false
+	}
+	b, ok := lit.Value.(*scalar.Boolean)
+	if !ok || !b.Valid {
+		return false, false
+	}
+	return b.Value, true
+}
+
+// foldConstant evaluates a binary expression of two non-null literals of the
+// same type. It returns false if the expression cannot be folded.
+func foldConstant(left Expr, op Op, right Expr) (Expr, bool) {
+	l, ok := left.(*LiteralExpr)
+	if !ok || !l.Value.IsValid() {
+		return nil, false
+	}
+	r, ok := right.(*LiteralExpr)
+	if !ok || !r.Value.IsValid() {
+		return nil, false
+	}
+
+	switch lv := l.Value.(type) {
+	case *scalar.Int64:
+		rv, ok := r.Value.(*scalar.Int64)
+		if !ok {
+			return nil, false
+		}
+		if folded, ok := foldArithmetic(lv.Value, op, rv.Value); ok {
+			return folded, true
+		}
+		return foldComparison(lv.Value, op, rv.Value)
+	case *scalar.Float64:
+		rv, ok := r.Value.(*scalar.Float64)
+		if !ok {
+			return nil, false
+		}
+		if folded, ok := foldArithmetic(lv.Value, op, rv.Value); ok {
+			return folded, true
+		}
+		return foldComparison(lv.Value, op, rv.Value)
+	case *scalar.String:
+		rv, ok := r.Value.(*scalar.String)
+		if !ok {
+			return nil, false
+		}
+		return foldComparison(string(lv.Value.Bytes()), op, string(rv.Value.Bytes()))
+	case *scalar.Boolean:
+		rv, ok := r.Value.(*scalar.Boolean)
+		if !ok {
+			return nil, false
+		}
+		switch op {
+		case OpEq:
+			return Literal(lv.Value == rv.Value), true
+		case OpNotEq:
+			return Literal(lv.Value != rv.Value), true
+		}
+	}
+	return nil, false
+}
+
+func foldArithmetic[T int64 | float64](l T, op Op, r T) (Expr, bool) {
+	if l, ok := any(l).(int64); ok && overflowsInt64(l, op, any(r).(int64)) {
+		// Overflows are left to fail the query when it is executed.
+		return nil, false
+	}
+	switch op {
+	case OpAdd:
+		return Literal(l + r), true
+	case OpSub:
+		return Literal(l - r), true
+	case OpMul:
+		return Literal(l * r), true
+	case OpDiv:
+		if r == 0 {
+			return nil, false
+		}
+		return Literal(l / r), true
+	default:
+		return nil, false
+	}
+}
+
+// overflowsInt64 reports whether the result of the arithmetic operation op on
+// l and r does not fit into an int64.
+func overflowsInt64(l int64, op Op, r int64) bool {
+	res, y := big.NewInt(l), big.NewInt(r)
+	switch op {
+	case OpAdd:
+		res.Add(res, y)
+	case OpSub:
+		res.Sub(res, y)
+	case OpMul:
+		res.Mul(res, y)
+	case OpDiv:
+		if r == 0 {
+			return false
+		}
+		res.Quo(res, y)
+	}
+	return !res.IsInt64()
+}
+
+func foldComparison[T cmp.Ordered](l T, op Op, r T) (Expr, bool) {
+	c := cmp.Compare(l, r)
+	switch op {
+	case OpEq:
+		return Literal(c == 0), true
+	case OpNotEq:
+		return Literal(c != 0), true
+	case OpLt:
+		return Literal(c < 0), true
+	case OpLtEq:
+		return Literal(c <= 0), true
+	case OpGt:
+		return Literal(c > 0), true
+	case OpGtEq:
+		return Literal(c >= 0), true
+	default:
+		return nil, false
+	}
+}
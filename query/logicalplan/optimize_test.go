@@ -62,6 +62,29 @@ func TestOptimizeDistinctPushDown(t *testing.T) {
 	)
 }
 
+func TestOptimizeGroupByPushDown(t *testing.T) {
+	tableProvider := &mockTableProvider{schema: dynparquet.NewSampleSchema()}
+	p, err := (&Builder{}).
+		Scan(tableProvider, "table1").
+		Filter(Col("labels.test").Eq(Literal("abc"))).
+		Aggregate(
+			[]*AggregationFunction{Sum(Col("value"))},
+			[]Expr{Col("stacktrace")},
+		).
+		Project(Col("stacktrace"), Sum(Col("value")).Alias("value_sum")).
+		Build()
+	require.NoError(t, err)
+
+	optimizer := &GroupByPushDown{}
+	p = optimizer.Optimize(p)
+
+	require.Equal(t,
+		[]Expr{&Column{ColumnName: "stacktrace"}},
+		// Projection -> Aggregate -> Filter -> TableScan
+		p.Input.Input.Input.TableScan.GroupBy,
+	)
+}
+
 func TestOptimizeFilterPushDown(t *testing.T) {
 	tableProvider := &mockTableProvider{schema: dynparquet.NewSampleSchema()}
 	p, err := (&Builder{}).
@@ -150,6 +173,28 @@ func TestProjectionPushDownOfDistinct(t *testing.T) {
 	require.Equal(t, []Expr{DynCol("labels")}, p.Input.Input.TableScan.PhysicalProjection)
 }
 
+func TestProjectionPushDownOfComputedExprs(t *testing.T) {
+	p, err := (&Builder{}).
+		Scan(&mockTableProvider{schema: dynparquet.NewSampleSchema()}, "table1").
+		Project(
+			Mul(Col("value"), Literal(int64(2))).Alias("double"),
+			DynCol("labels"),
+		).
+		Build()
+	require.NoError(t, err)
+
+	p = (&PhysicalProjectionPushDown{
+		defaultProjections: []Expr{Not(DynCol(hashedMatch))},
+	}).Optimize(p)
+
+	// The default projection reading all columns must not be added, only the
+	// columns used by the projection are needed.
+	require.Equal(t, []Expr{
+		Col("value"),
+		DynCol("labels"),
+	}, p.Input.TableScan.PhysicalProjection)
+}
+
 func TestAllOptimizers(t *testing.T) {
 	tableProvider := &mockTableProvider{schema: dynparquet.NewSampleSchema()}
 	p, err := (&Builder{}).
@@ -189,6 +234,8 @@ func TestAllOptimizers(t *testing.T) {
 				Value: scalar.MakeScalar("abc"),
 			},
 		},
+		// The aggregation's group columns are pushed down to the scan.
+		GroupBy: []Expr{&Column{ColumnName: "stacktrace"}},
 	},
 		// Aggregate -> Filter -> Projection -> TableScan
 		p.Input.Input.Input.TableScan,
@@ -268,3 +315,117 @@ func TestAggFuncPushDown(t *testing.T) {
 		)
 	})
 }
+
+func TestSimplifyExpr(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		expr     Expr
+		expected Expr
+	}{
+		{
+			name:     "DuplicateConjunction",
+			expr:     And(Col("a").Eq(Literal(1)), Col("a").Eq(Literal(1))),
+			expected: Col("a").Eq(Literal(1)),
+		},
+		{
+			name:     "NestedDuplicateConjunction",
+			expr:     And(Col("a").Eq(Literal(1)), Col("b").Eq(Literal(2)), Col("a").Eq(Literal(1))),
+			expected: And(Col("a").Eq(Literal(1)), Col("b").Eq(Literal(2))),
+		},
+		{
+			name:     "DuplicateDisjunction",
+			expr:     Or(Col("a").Eq(Literal(1)), Col("a").Eq(Literal(1))),
+			expected: Col("a").Eq(Literal(1)),
+		},
+		{
+			name:     "ConstantFoldComparison",
+			expr:     And(Col("a").Eq(Literal(1)), &BinaryExpr{Left: Literal(1), Op: OpLt, Right: Literal(2)}),
+			expected: Col("a").Eq(Literal(1)),
+		},
+		{
+			name:     "ConstantFoldArithmetic",
+			expr:     Col("a").Gt(Add(Literal(int64(1)), Mul(Literal(int64(2)), Literal(int64(3))))),
+			expected: Col("a").Gt(Literal(int64(7))),
+		},
+		{
+			name:     "ConstantFoldFalseConjunction",
+			expr:     And(Col("a").Eq(Literal(1)), &BinaryExpr{Left: Literal("a"), Op: OpEq, Right: Literal("b")}),
+			expected: Literal(false),
+		},
+		{
+			name:     "ConstantFoldTrueDisjunction",
+			expr:     Or(Col("a").Eq(Literal(1)), Not(Literal(false))),
+			expected: Literal(true),
+		},
+		{
+			name:     "DivisionByZeroNotFolded",
+			expr:     Div(Literal(int64(1)), Literal(int64(0))),
+			expected: Div(Literal(int64(1)), Literal(int64(0))),
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			require.True(t, tc.expected.Equal(SimplifyExpr(tc.expr)), "got %s", SimplifyExpr(tc.expr))
+		})
+	}
+}
+
+func TestOptimizeExprSimplification(t *testing.T) {
+	t.Run("MergeFilters", func(t *testing.T) {
+		tableProvider := &mockTableProvider{schema: dynparquet.NewSampleSchema()}
+		p, err := (&Builder{}).
+			Scan(tableProvider, "table1").
+			Filter(Col("labels.test").Eq(Literal("abc"))).
+			Filter(And(Col("labels.test").Eq(Literal("abc")), Col("timestamp").Gt(Literal(1)))).
+			Build()
+		require.NoError(t, err)
+
+		p = (&ExprSimplification{}).Optimize(p)
+		require.NotNil(t, p.Filter)
+		require.True(t,
+			And(Col("labels.test").Eq(Literal("abc")), Col("timestamp").Gt(Literal(1))).Equal(p.Filter.Expr),
+			"got %s", p.Filter.Expr,
+		)
+		require.NotNil(t, p.Input.TableScan)
+	})
+	t.Run("RemoveTrueFilter", func(t *testing.T) {
+		tableProvider := &mockTableProvider{schema: dynparquet.NewSampleSchema()}
+		p, err := (&Builder{}).
+			Scan(tableProvider, "table1").
+			Filter(Or(Col("labels.test").Eq(Literal("abc")), Literal(true))).
+			Build()
+		require.NoError(t, err)
+
+		p = (&ExprSimplification{}).Optimize(p)
+		require.NotNil(t, p.TableScan)
+	})
+	t.Run("MergeProjections", func(t *testing.T) {
+		tableProvider := &mockTableProvider{schema: dynparquet.NewSampleSchema()}
+		p, err := (&Builder{}).
+			Scan(tableProvider, "table1").
+			Project(Col("stacktrace"), Col("value").Alias("v"), Col("timestamp")).
+			Project(Col("v"), Col("stacktrace"), Col("stacktrace")).
+			Build()
+		require.NoError(t, err)
+
+		p = (&ExprSimplification{}).Optimize(p)
+		require.NotNil(t, p.Projection)
+		require.True(t, exprsEqual(
+			[]Expr{Col("value").Alias("v"), Col("stacktrace")},
+			p.Projection.Exprs,
+		), "got %s", p.Projection.Exprs)
+		require.NotNil(t, p.Input.TableScan)
+	})
+	t.Run("DontMergeComputedProjections", func(t *testing.T) {
+		tableProvider := &mockTableProvider{schema: dynparquet.NewSampleSchema()}
+		p, err := (&Builder{}).
+			Scan(tableProvider, "table1").
+			Project(Col("value"), Col("timestamp")).
+			Project(Add(Col("value"), Col("timestamp"))).
+			Build()
+		require.NoError(t, err)
+
+		p = (&ExprSimplification{}).Optimize(p)
+		require.NotNil(t, p.Projection)
+		require.NotNil(t, p.Input.Projection)
+	})
+}
@@ -3,11 +3,16 @@ package logicalplan
 import (
 	"fmt"
 	"reflect"
+	"regexp"
 	"strings"
 
 	"github.com/apache/arrow/go/v17/arrow"
 	"github.com/apache/arrow/go/v17/arrow/scalar"
+	"github.com/parquet-go/parquet-go"
 	"github.com/parquet-go/parquet-go/format"
+
+	"github.com/polarsignals/frostdb/dynparquet"
+	"github.com/polarsignals/frostdb/pqarrow/arrowutils"
 )
 
 // PlanValidationError is the error representing a logical plan that is not valid.
@@ -79,6 +84,10 @@ func Validate(plan *LogicalPlan) error {
 			err = nil
 		case plan.Aggregation != nil:
 			err = ValidateAggregation(plan)
+		case plan.Join != nil:
+			err = ValidateJoin(plan)
+		case plan.OrderBy != nil:
+			err = ValidateOrderBy(plan)
 		}
 	}
 
@@ -125,10 +134,16 @@ func ValidateSingleFieldSet(plan *LogicalPlan) *PlanValidationError {
 	if plan.Sample != nil {
 		fieldsSet = append(fieldsSet, 7)
 	}
+	if plan.OrderBy != nil {
+		fieldsSet = append(fieldsSet, 8)
+	}
+	if plan.Join != nil {
+		fieldsSet = append(fieldsSet, 9)
+	}
 
 	if len(fieldsSet) != 1 {
 		fieldsFound := make([]string, 0)
-		fields := []string{"SchemaScan", "TableScan", "Filter", "Distinct", "Projection", "Aggregation", "Limit", "Sample"}
+		fields := []string{"SchemaScan", "TableScan", "Filter", "Distinct", "Projection", "Aggregation", "Limit", "Sample", "OrderBy", "Join"}
 		for _, i := range fieldsSet {
 			fieldsFound = append(fieldsFound, fields[i])
 		}
@@ -250,6 +265,47 @@ func ValidateAggregation(plan *LogicalPlan) *PlanValidationError {
 		}
 	}
 
+	if groupExprError := ValidateAggregationGroupExprs(plan); groupExprError != nil {
+		return &PlanValidationError{
+			plan:     plan,
+			message:  "invalid aggregation",
+			children: []*ExprValidationError{groupExprError},
+		}
+	}
+
+	return nil
+}
+
+// ValidateAggregationGroupExprs validates the windows an aggregation groups
+// by. Every row is aggregated into each window containing it, which is only
+// well-defined for a single window expression.
+func ValidateAggregationGroupExprs(plan *LogicalPlan) *ExprValidationError {
+	windows := 0
+	for _, expr := range plan.Aggregation.GroupExprs {
+		w, ok := expr.(*WindowExpr)
+		if !ok {
+			continue
+		}
+		windows++
+		if windows > 1 {
+			return &ExprValidationError{
+				expr:    w,
+				message: "invalid window: cannot group by more than one window",
+			}
+		}
+		if w.Size <= 0 || w.Step <= 0 {
+			return &ExprValidationError{
+				expr:    w,
+				message: fmt.Sprintf("invalid window: size %s and step %s must be positive", w.Size, w.Step),
+			}
+		}
+		if _, err := w.DataType(plan.Input); err != nil {
+			return &ExprValidationError{
+				expr:    w,
+				message: err.Error(),
+			}
+		}
+	}
 	return nil
 }
 
@@ -275,7 +331,14 @@ func ValidateAggregationExpr(plan *LogicalPlan) *ExprValidationError {
 		}
 
 		switch expr.Func {
-		case AggFuncSum, AggFuncMin, AggFuncMax, AggFuncCount, AggFuncAvg, AggFuncUnique:
+		case AggFuncSum, AggFuncMin, AggFuncMax, AggFuncCount, AggFuncAvg, AggFuncUnique,
+			AggFuncStddev, AggFuncVariance, AggFuncQuantile:
+			if expr.Func == AggFuncQuantile && !(expr.Quantile >= 0 && expr.Quantile <= 1) {
+				return &ExprValidationError{
+					expr:    expr,
+					message: fmt.Sprintf("invalid aggregation: quantile %v is not between 0 and 1", expr.Quantile),
+				}
+			}
 			switch t {
 			case
 				arrow.PrimitiveTypes.Int64,
@@ -301,6 +364,73 @@ func ValidateAggregationExpr(plan *LogicalPlan) *ExprValidationError {
 	return nil
 }
 
+// ValidateOrderBy validates that the expressions of the logical plan's order
+// by step are of a type that can be sorted. Expressions whose type cannot be
+// determined, such as columns missing from the schema, are sorted as nulls
+// and therefore allowed.
+func ValidateOrderBy(plan *LogicalPlan) *PlanValidationError {
+	children := make([]*ExprValidationError, 0)
+	for _, expr := range plan.OrderBy.Exprs {
+		t, err := expr.DataType(plan.Input)
+		if err != nil || t == nil {
+			continue
+		}
+		if !arrowutils.IsSortableType(t) {
+			children = append(children, &ExprValidationError{
+				expr:    expr,
+				message: fmt.Errorf("expression type %s is not supported", t).Error(),
+			})
+		}
+	}
+	if len(children) > 0 {
+		return &PlanValidationError{
+			plan:     plan,
+			message:  "invalid order by",
+			children: children,
+		}
+	}
+	return nil
+}
+
+// ValidateJoin validates the logical plan's join step.
+func ValidateJoin(plan *LogicalPlan) *PlanValidationError {
+	if plan.Input == nil || plan.Join.Right == nil {
+		return &PlanValidationError{
+			plan:    plan,
+			message: "invalid join: both inputs must be set",
+		}
+	}
+
+	if len(plan.Join.On) == 0 {
+		return &PlanValidationError{
+			plan:    plan,
+			message: "invalid join: at least one join expression is required",
+		}
+	}
+
+	if _, _, ok := plan.Join.Keys(); !ok {
+		return &PlanValidationError{
+			plan:    plan,
+			message: "invalid join: join expressions must be columns or equalities of two columns",
+		}
+	}
+
+	if err := Validate(plan.Join.Right); err != nil {
+		rightErr, ok := err.(*PlanValidationError)
+		if !ok {
+			// if we are here it is a bug in the code
+			panic(fmt.Sprintf("Unexpected error: %v expected a PlanValidationError", err))
+		}
+		return &PlanValidationError{
+			plan:    plan,
+			message: "invalid join: invalid right input",
+			input:   rightErr,
+		}
+	}
+
+	return nil
+}
+
 // ValidateInput validates that the current logical plans input is valid.
 // It returns nil if the plan has no input.
 func ValidateInput(plan *LogicalPlan) *PlanValidationError {
@@ -336,9 +466,30 @@ func ValidateFilterExpr(plan *LogicalPlan, e Expr) *ExprValidationError {
 	case *BinaryExpr:
 		err := ValidateFilterBinaryExpr(plan, expr)
 		return err
+	case *InExpr:
+		return ValidateFilterInExpr(plan, expr)
+	case *AnyColumnExpr:
+		return ValidateFilterAnyColumnExpr(plan, expr)
+	case *IsNullExpr:
+		if _, ok := expr.Expr.(*Column); !ok {
+			return &ExprValidationError{
+				message: "is null expression must be on a column",
+				expr:    expr,
+			}
+		}
+		return nil
+	case *LiteralExpr:
+		// Boolean literals are valid filters, e.g. the result of simplifying
+		// an expression that is always true.
+		if _, ok := expr.Value.(*scalar.Boolean); ok {
+			return nil
+		}
 	}
 
-	return nil
+	return &ExprValidationError{
+		message: fmt.Sprintf("unsupported filter expression: %s is not a boolean expression", e.String()),
+		expr:    e,
+	}
 }
 
 // ValidateFilterBinaryExpr validates the filter's binary expression.
@@ -347,6 +498,14 @@ func ValidateFilterBinaryExpr(plan *LogicalPlan, expr *BinaryExpr) *ExprValidati
 		return ValidateFilterAndBinaryExpr(plan, expr)
 	}
 
+	switch expr.Op {
+	case OpAdd, OpSub, OpMul, OpDiv:
+		return &ExprValidationError{
+			message: fmt.Sprintf("unsupported filter expression: operator %s does not produce a boolean", expr.Op),
+			expr:    expr,
+		}
+	}
+
 	// try to find the column expression on the left side of the binary expression
 	leftColumnFinder := newTypeFinder((*Column)(nil))
 	expr.Left.Accept(&leftColumnFinder)
@@ -356,31 +515,307 @@ func ValidateFilterBinaryExpr(plan *LogicalPlan, expr *BinaryExpr) *ExprValidati
 			expr:    expr,
 		}
 	}
+	columnExpr := leftColumnFinder.result.(*Column)
+
+	mapKey, isMapKey := expr.Left.(*MapKeyExpr)
+	if isMapKey {
+		if err := ValidateMapKeyExpr(plan, mapKey); err != nil {
+			return err
+		}
+	}
+	stringFunc, isStringFunc := expr.Left.(*StringFuncExpr)
+	if isStringFunc {
+		if err := ValidateStringFuncExpr(plan, stringFunc); err != nil {
+			return err
+		}
+	}
+
+	// try to find the literal on the other side of the expression
+	rightLiteralFinder := newTypeFinder((*LiteralExpr)(nil))
+	expr.Right.Accept(&rightLiteralFinder)
+	if rightLiteralFinder.result == nil {
+		rightColumnFinder := newTypeFinder((*Column)(nil))
+		expr.Right.Accept(&rightColumnFinder)
+		if rightColumnFinder.result == nil {
+			return &ExprValidationError{
+				message: "right side of binary expression must be a literal or a column",
+				expr:    expr,
+			}
+		}
+		if isMapKey || isStringFunc {
+			return nil
+		}
+		return ValidateFilterColumnComparison(plan, expr, columnExpr, rightColumnFinder.result.(*Column))
+	}
+	literalExpr := rightLiteralFinder.result.(*LiteralExpr)
+	if expr.Op == OpNullSafeEq && !literalExpr.Value.IsValid() {
+		// Any column can be compared to null.
+		return nil
+	}
+
+	switch expr.Op {
+	case OpRegexMatch, OpRegexNotMatch:
+		s, ok := literalExpr.Value.(*scalar.String)
+		if !ok {
+			return &ExprValidationError{
+				message: fmt.Sprintf("regex match requires a string pattern, got %s", literalExpr.Value.DataType()),
+				expr:    expr,
+			}
+		}
+		if _, err := regexp.Compile(string(s.Data())); err != nil {
+			return &ExprValidationError{
+				message: fmt.Sprintf("invalid regex pattern: %v", err),
+				expr:    expr,
+			}
+		}
+	case OpContains, OpNotContains:
+		switch literalExpr.Value.(type) {
+		case *scalar.String, *scalar.Binary:
+		default:
+			return &ExprValidationError{
+				message: fmt.Sprintf("contains requires a string pattern, got %s", literalExpr.Value.DataType()),
+				expr:    expr,
+			}
+		}
+	}
+
+	if isMapKey || isStringFunc {
+		// Map values and the results of string functions are strings.
+		if err := ValidateComparingTypes(&format.LogicalType{UTF8: &format.StringType{}}, literalExpr.Value); err != nil {
+			err.expr = expr
+			return err
+		}
+		return nil
+	}
 
 	// try to find the column in the schema
-	columnExpr := leftColumnFinder.result.(*Column)
-	schema := plan.InputSchema()
-	if schema != nil {
-		column, found := schema.ColumnByName(columnExpr.ColumnName)
-		if found {
-			// try to find the literal on the other side of the expression
-			rightLiteralFinder := newTypeFinder((*LiteralExpr)(nil))
-			expr.Right.Accept(&rightLiteralFinder)
-			if rightLiteralFinder.result != nil {
-				// ensure that the column type is compatible with the literal being compared to it
-				t := column.StorageLayout.Type()
-				literalExpr := rightLiteralFinder.result.(*LiteralExpr)
-				if err := ValidateComparingTypes(t.LogicalType(), literalExpr.Value); err != nil {
-					err.expr = expr
-					return err
-				}
+	column, found := filterColumnByName(plan.InputSchema(), columnExpr.ColumnName)
+	if !found {
+		return nil
+	}
+
+	t := column.StorageLayout.Type()
+	switch expr.Op {
+	case OpRegexMatch, OpRegexNotMatch, OpContains, OpNotContains:
+		if !isBinaryKind(t.Kind()) {
+			return &ExprValidationError{
+				message: fmt.Sprintf("operator %s is only supported on string columns, column %q is of type %s", expr.Op, columnExpr.ColumnName, t),
+				expr:    expr,
 			}
 		}
+		return nil
+	}
+
+	// ensure that the column type is compatible with the literal being compared to it
+	if err := ValidateComparingTypes(t.LogicalType(), literalExpr.Value); err != nil {
+		err.expr = expr
+		return err
 	}
 
 	return nil
 }
 
+// ValidateMapKeyExpr validates that a map key expression accesses a map
+// column.
+func ValidateMapKeyExpr(plan *LogicalPlan, expr *MapKeyExpr) *ExprValidationError {
+	columnExpr, ok := expr.Expr.(*Column)
+	if !ok {
+		return &ExprValidationError{
+			message: "map key expression must be on a column",
+			expr:    expr,
+		}
+	}
+
+	column, found := filterColumnByName(plan.InputSchema(), columnExpr.ColumnName)
+	if !found {
+		return nil
+	}
+	if lt := column.StorageLayout.Type().LogicalType(); lt == nil || lt.Map == nil {
+		return &ExprValidationError{
+			message: fmt.Sprintf("map key expression on column %q which is not a map", columnExpr.ColumnName),
+			expr:    expr,
+		}
+	}
+	return nil
+}
+
+// ValidateStringFuncExpr validates the arguments of a string function and
+// that the columns it is applied to are string columns.
+func ValidateStringFuncExpr(plan *LogicalPlan, expr *StringFuncExpr) *ExprValidationError {
+	if err := expr.ValidateArgs(); err != nil {
+		return &ExprValidationError{
+			message: err.Error(),
+			expr:    expr,
+		}
+	}
+
+	for _, arg := range expr.StringArgs() {
+		columnExpr, ok := arg.(*Column)
+		if !ok {
+			continue
+		}
+		column, found := filterColumnByName(plan.InputSchema(), columnExpr.ColumnName)
+		if !found {
+			continue
+		}
+		if t := column.StorageLayout.Type(); !isBinaryKind(t.Kind()) {
+			return &ExprValidationError{
+				message: fmt.Sprintf("%s is only supported on string columns, column %q is of type %s", expr.Func, columnExpr.ColumnName, t),
+				expr:    expr,
+			}
+		}
+	}
+	return nil
+}
+
+// ValidateFilterInExpr validates the filter's IN list expression.
+func ValidateFilterInExpr(plan *LogicalPlan, expr *InExpr) *ExprValidationError {
+	columnExpr, ok := expr.Expr.(*Column)
+	if !ok {
+		return &ExprValidationError{
+			message: "left side of in expression must be a column",
+			expr:    expr,
+		}
+	}
+
+	if len(expr.Values) == 0 {
+		return &ExprValidationError{
+			message: "in expression must have at least one value",
+			expr:    expr,
+		}
+	}
+
+	column, found := filterColumnByName(plan.InputSchema(), columnExpr.ColumnName)
+	for _, v := range expr.Values {
+		literalExpr, ok := v.(*LiteralExpr)
+		if !ok {
+			return &ExprValidationError{
+				message: fmt.Sprintf("values of in expression must be literals, got %s", v.String()),
+				expr:    expr,
+			}
+		}
+		if !found {
+			continue
+		}
+		// ensure that the column type is compatible with the literals being compared to it
+		if err := ValidateComparingTypes(column.StorageLayout.Type().LogicalType(), literalExpr.Value); err != nil {
+			err.expr = expr
+			return err
+		}
+	}
+
+	return nil
+}
+
+// ValidateFilterAnyColumnExpr validates the filter's expression matching any
+// concrete column of a dynamic column.
+func ValidateFilterAnyColumnExpr(plan *LogicalPlan, expr *AnyColumnExpr) *ExprValidationError {
+	literalExpr, ok := expr.Value.(*LiteralExpr)
+	if !ok {
+		return &ExprValidationError{
+			message: fmt.Sprintf("value of any column expression must be a literal, got %s", expr.Value.String()),
+			expr:    expr,
+		}
+	}
+
+	switch expr.Op {
+	case OpEq:
+		schema := plan.InputSchema()
+		if schema == nil {
+			return nil
+		}
+		column, found := schema.FindDynamicColumn(expr.Column.ColumnName)
+		if !found {
+			return nil
+		}
+		if err := ValidateComparingTypes(column.StorageLayout.Type().LogicalType(), literalExpr.Value); err != nil {
+			err.expr = expr
+			return err
+		}
+		return nil
+	case OpRegexMatch:
+		pattern, ok := literalExpr.Value.(*scalar.String)
+		if !ok {
+			return &ExprValidationError{
+				message: fmt.Sprintf("regex pattern must be a string, got %s", literalExpr.Value.DataType()),
+				expr:    expr,
+			}
+		}
+		if _, err := regexp.Compile(string(pattern.Data())); err != nil {
+			return &ExprValidationError{
+				message: fmt.Sprintf("invalid regex pattern: %v", err),
+				expr:    expr,
+			}
+		}
+		return nil
+	default:
+		return &ExprValidationError{
+			message: fmt.Sprintf("unsupported filter expression: operator %s cannot be applied to any column", expr.Op),
+			expr:    expr,
+		}
+	}
+}
+
+// ValidateFilterColumnComparison validates a filter's binary expression that
+// compares two columns of the same row.
+func ValidateFilterColumnComparison(plan *LogicalPlan, expr *BinaryExpr, left, right *Column) *ExprValidationError {
+	switch expr.Op {
+	case OpEq, OpNotEq, OpLt, OpLtEq, OpGt, OpGtEq, OpNullSafeEq:
+	default:
+		return &ExprValidationError{
+			message: fmt.Sprintf("unsupported filter expression: operator %s cannot compare two columns", expr.Op),
+			expr:    expr,
+		}
+	}
+
+	schema := plan.InputSchema()
+	leftColumn, leftFound := filterColumnByName(schema, left.ColumnName)
+	rightColumn, rightFound := filterColumnByName(schema, right.ColumnName)
+	if !leftFound || !rightFound {
+		return nil
+	}
+
+	leftKind := leftColumn.StorageLayout.Type().Kind()
+	rightKind := rightColumn.StorageLayout.Type().Kind()
+	if kindClass(leftKind) != kindClass(rightKind) {
+		return &ExprValidationError{
+			message: fmt.Sprintf("incompatible types: column %q of type %s cannot be compared with column %q of type %s", left.ColumnName, leftKind, right.ColumnName, rightKind),
+			expr:    expr,
+		}
+	}
+
+	return nil
+}
+
+// filterColumnByName looks up the definition of a column referenced by a
+// filter, which may be a concrete column of a dynamic column.
+func filterColumnByName(schema *dynparquet.Schema, name string) (dynparquet.ColumnDefinition, bool) {
+	if schema == nil {
+		return dynparquet.ColumnDefinition{}, false
+	}
+	if def, ok := schema.ColumnByName(name); ok {
+		return def, true
+	}
+	return schema.FindDynamicColumnForConcreteColumn(name)
+}
+
+func isBinaryKind(k parquet.Kind) bool {
+	return k == parquet.ByteArray || k == parquet.FixedLenByteArray
+}
+
+// kindClass groups parquet kinds whose values can be compared with each
+// other.
+func kindClass(k parquet.Kind) string {
+	switch k {
+	case parquet.Boolean:
+		return "boolean"
+	case parquet.ByteArray, parquet.FixedLenByteArray:
+		return "binary"
+	default:
+		return "numeric"
+	}
+}
+
 // ValidateComparingTypes validates if the types being compared by a binary expression are compatible.
 func ValidateComparingTypes(columnType *format.LogicalType, literal scalar.Scalar) *ExprValidationError {
 	switch {
@@ -416,6 +851,15 @@ func ValidateComparingTypes(columnType *format.LogicalType, literal scalar.Scala
 				message: "incompatible types: numeric column cannot be compared with string literal",
 			}
 		}
+	// if the column is a timestamp type, it can only be compared to timestamps
+	case columnType.Timestamp != nil:
+		switch literal.(type) {
+		case *scalar.Timestamp, *scalar.Null:
+		default:
+			return &ExprValidationError{
+				message: fmt.Sprintf("incompatible types: timestamp column cannot be compared with %s literal", literal.DataType()),
+			}
+		}
 	}
 	return nil
 }
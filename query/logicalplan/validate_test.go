@@ -7,6 +7,7 @@ import (
 	"github.com/stretchr/testify/require"
 
 	"github.com/polarsignals/frostdb/dynparquet"
+	schemapb "github.com/polarsignals/frostdb/gen/proto/go/frostdb/schema/v1alpha1"
 )
 
 func TestOnlyOneFieldCanBeSet(t *testing.T) {
@@ -205,3 +206,150 @@ func TestFilterAndExprEvaluatesEachAndedRule(t *testing.T) {
 	rightErr := exprErr.children[1]
 	require.True(t, strings.HasPrefix(rightErr.message, "left side of binary expression must be a column"))
 }
+
+func TestFilterRejectsUnsupportedExprs(t *testing.T) {
+	for _, testCase := range []struct {
+		name   string
+		expr   Expr
+		errMsg string
+	}{
+		{
+			name:   "NotBoolean",
+			expr:   Col("value"),
+			errMsg: "unsupported filter expression: value is not a boolean expression",
+		},
+		{
+			name:   "NotBooleanLiteral",
+			expr:   Literal(int64(1)),
+			errMsg: "unsupported filter expression: 1 is not a boolean expression",
+		},
+		{
+			name:   "Arithmetic",
+			expr:   Add(Col("value"), Literal(int64(1))),
+			errMsg: "unsupported filter expression: operator + does not produce a boolean",
+		},
+		{
+			name:   "RegexOnIntColumn",
+			expr:   Col("value").RegexMatch("1.*"),
+			errMsg: "operator =~ is only supported on string columns",
+		},
+		{
+			name:   "InvalidRegex",
+			expr:   Col("example_type").RegexMatch("(cpu"),
+			errMsg: "invalid regex pattern",
+		},
+		{
+			name:   "RegexWithIntPattern",
+			expr:   &BinaryExpr{Left: Col("example_type"), Op: OpRegexMatch, Right: Literal(int64(1))},
+			errMsg: "regex match requires a string pattern",
+		},
+		{
+			name:   "ContainsOnIntColumn",
+			expr:   Col("timestamp").Contains("1"),
+			errMsg: "operator contains is only supported on string columns",
+		},
+		{
+			name:   "ColumnsOfIncompatibleTypes",
+			expr:   Col("value").Gt(Col("example_type")),
+			errMsg: "incompatible types: column \"value\" of type INT64 cannot be compared with column \"example_type\"",
+		},
+		{
+			name:   "RegexBetweenColumns",
+			expr:   &BinaryExpr{Left: Col("example_type"), Op: OpRegexMatch, Right: Col("stacktrace")},
+			errMsg: "unsupported filter expression: operator =~ cannot compare two columns",
+		},
+	} {
+		t.Run(testCase.name, func(t *testing.T) {
+			_, err := (&Builder{}).
+				Scan(&mockTableProvider{dynparquet.NewSampleSchema()}, "table1").
+				Filter(testCase.expr).
+				Build()
+
+			require.NotNil(t, err)
+			planErr, ok := err.(*PlanValidationError)
+			require.True(t, ok)
+			require.True(t, strings.HasPrefix(planErr.message, "invalid filter"))
+			require.Len(t, planErr.children, 1)
+			require.True(t, strings.HasPrefix(planErr.children[0].message, testCase.errMsg), planErr.children[0].message)
+		})
+	}
+}
+
+func TestFilterAllowsColumnComparisons(t *testing.T) {
+	for _, expr := range []Expr{
+		Col("value").Gt(Col("timestamp")),
+		Col("labels.label1").Eq(Col("example_type")),
+		Col("example_type").RegexMatch("cpu.*"),
+		Col("labels.label1").Contains("value"),
+	} {
+		_, err := (&Builder{}).
+			Scan(&mockTableProvider{dynparquet.NewSampleSchema()}, "table1").
+			Filter(expr).
+			Build()
+		require.NoError(t, err, expr.String())
+	}
+}
+
+func TestFilterInExpr(t *testing.T) {
+	build := func(expr Expr) error {
+		_, err := (&Builder{}).
+			Scan(&mockTableProvider{dynparquet.NewSampleSchema()}, "table1").
+			Filter(expr).
+			Build()
+		return err
+	}
+
+	require.NoError(t, build(Col("labels.label1").In(Literal("a"), Literal("b"))))
+	require.NoError(t, build(Col("timestamp").NotIn(Literal(int64(1)))))
+
+	for _, testCase := range []struct {
+		expr   Expr
+		errMsg string
+	}{
+		{expr: Col("timestamp").In(Literal("a")), errMsg: "incompatible types"},
+		{expr: Col("example_type").In(), errMsg: "in expression must have at least one value"},
+		{expr: Col("example_type").In(Col("stacktrace")), errMsg: "values of in expression must be literals"},
+	} {
+		err := build(testCase.expr)
+		require.NotNil(t, err)
+		planErr, ok := err.(*PlanValidationError)
+		require.True(t, ok)
+		require.Len(t, planErr.children, 1)
+		require.True(t, strings.HasPrefix(planErr.children[0].message, testCase.errMsg), planErr.children[0].message)
+	}
+}
+
+func TestOrderByRejectsUnsortableTypes(t *testing.T) {
+	schema, err := dynparquet.SchemaFromDefinition(&schemapb.Schema{
+		Name: "test",
+		Columns: []*schemapb.Column{{
+			Name: "flag",
+			StorageLayout: &schemapb.StorageLayout{
+				Type: schemapb.StorageLayout_TYPE_BOOL,
+			},
+		}, {
+			Name: "value",
+			StorageLayout: &schemapb.StorageLayout{
+				Type: schemapb.StorageLayout_TYPE_INT64,
+			},
+		}},
+	})
+	require.NoError(t, err)
+
+	_, err = (&Builder{}).
+		Scan(&mockTableProvider{schema}, "table1").
+		OrderBy(Col("value"), Col("missing")).
+		Build()
+	require.NoError(t, err)
+
+	_, err = (&Builder{}).
+		Scan(&mockTableProvider{schema}, "table1").
+		OrderBy(Col("value"), Desc(Col("flag"))).
+		Build()
+	require.Error(t, err)
+	planErr, ok := err.(*PlanValidationError)
+	require.True(t, ok)
+	require.Equal(t, "invalid order by", planErr.message)
+	require.Len(t, planErr.children, 1)
+	require.Contains(t, planErr.children[0].message, "expression type bool is not supported")
+}
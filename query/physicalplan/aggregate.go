@@ -46,6 +46,7 @@ func Aggregate(
 
 		aggregation.resultName = expr.Name()
 		aggregation.function = expr.Func
+		aggregation.quantile = expr.Quantile
 		aggregation.expr = expr.Expr
 
 		aggregations = append(aggregations, aggregation)
@@ -106,7 +107,13 @@ type Aggregation struct {
 	dynamic    bool // dynamic indicates that this aggregation is performed against a dynamic column.
 	resultName string
 	function   logicalplan.AggFunc
-	arrays     []builder.ColumnBuilder // TODO: These can actually live outside this struct and be shared. Only at the very end will they be read by each column and then aggregated separately.
+	quantile   float64 // quantile is the quantile computed by quantile aggregations.
+	arrays     []builder.ColumnBuilder
+	// shared indicates that the aggregation does not buffer the values of its
+	// groups itself, but aggregates the arrays of the aggregation at index
+	// source, which aggregates the same expression.
+	shared bool
+	source int
 }
 
 type AggregationFunction interface {
@@ -119,6 +126,7 @@ type HashAggregate struct {
 	groupByColumnMatchers []logicalplan.Expr
 	hashSeed              maphash.Seed
 	next                  PhysicalPlan
+	stats                 StatsCollector
 	// Indicate is this is the last aggregation or
 	// if this is a aggregation with another aggregation to follow after synchronizing.
 	finalStage bool
@@ -175,6 +183,22 @@ func NewHashAggregate(
 		}
 	}
 
+	if !finalStage {
+		// Multiple aggregations of the same expression, e.g. the sum and the
+		// max of a column, buffer the values of each group only once. The
+		// final stage aggregates the differently named results of the
+		// previous stage, so it cannot share them.
+		for i := range static {
+			for j := 0; j < i; j++ {
+				if !static[j].shared && static[j].expr.Equal(static[i].expr) {
+					static[i].shared = true
+					static[i].source = j
+					break
+				}
+			}
+		}
+	}
+
 	return &HashAggregate{
 		pool:   pool,
 		tracer: tracer,
@@ -240,6 +264,12 @@ func (a *HashAggregate) Draw() *Diagram {
 	return &Diagram{Details: details, Child: child}
 }
 
+func (a *HashAggregate) Name() string { return "HashAggregate" }
+
+func (a *HashAggregate) Children() []Operator { return nextOperators(a.next) }
+
+func (a *HashAggregate) Stats() OperatorStats { return a.stats.Stats() }
+
 // Go translation of boost's hash_combine function. Read here why these values
 // are used and good choices: https://stackoverflow.com/questions/35985960/c-why-is-boosthash-combine-the-best-way-to-combine-hash-values
 func hashCombine(lhs, rhs uint64) uint64 {
@@ -261,6 +291,7 @@ func (u *uint64HashCombine) hashCombine(rhs uint64) uint64 {
 }
 
 func (a *HashAggregate) Callback(_ context.Context, r arrow.Record) error {
+	a.stats.Observe(r)
 	// Generates high volume of spans. Comment out if needed during development.
 	// ctx, span := a.tracer.Start(ctx, "HashAggregate/Callback")
 	// defer span.End()
@@ -312,8 +343,9 @@ func (a *HashAggregate) Callback(_ context.Context, r arrow.Record) error {
 						aggregate.aggregations = append(aggregate.aggregations, Aggregation{
 							expr:       logicalplan.Col(field.Name),
 							dynamic:    true,
-							resultName: resultNameWithConcreteColumn(col.function, field.Name),
+							resultName: resultNameWithConcreteColumn(col.function, col.quantile, field.Name),
 							function:   col.function,
+							quantile:   col.quantile,
 						})
 						aggregate.dynamicAggregationsConverted[field.Name] = struct{}{}
 					}
@@ -327,6 +359,7 @@ func (a *HashAggregate) Callback(_ context.Context, r arrow.Record) error {
 							dynamic:    true,
 							resultName: field.Name, // Don't rename the column yet, we'll do that in the final stage. Dynamic aggregations can't match agains't the pre-computed name.
 							function:   col.function,
+							quantile:   col.quantile,
 						})
 						aggregate.dynamicAggregationsConverted[field.Name] = struct{}{}
 					}
@@ -412,6 +445,9 @@ func (a *HashAggregate) Callback(_ context.Context, r arrow.Record) error {
 		if !ok {
 			aggregate = a.aggregates[len(a.aggregates)-1]
 			for j, col := range columnToAggregate {
+				if aggregate.aggregations[j].shared {
+					continue
+				}
 				agg := builder.NewBuilder(a.pool, col.DataType())
 				aggregate.aggregations[j].arrays = append(aggregate.aggregations[j].arrays, agg)
 			}
@@ -431,6 +467,9 @@ func (a *HashAggregate) Callback(_ context.Context, r arrow.Record) error {
 				// Max size reached, rollback the aggregation creation and create new aggregate
 				aggregate.rowCount--
 				for j := range columnToAggregate {
+					if aggregate.aggregations[j].shared {
+						continue
+					}
 					l := len(aggregate.aggregations[j].arrays)
 					aggregate.aggregations[j].arrays = aggregate.aggregations[j].arrays[:l-1]
 				}
@@ -442,6 +481,9 @@ func (a *HashAggregate) Callback(_ context.Context, r arrow.Record) error {
 						expr:       agg.expr,
 						resultName: agg.resultName,
 						function:   agg.function,
+						quantile:   agg.quantile,
+						shared:     agg.shared,
+						source:     agg.source,
 					})
 				}
 				a.aggregates = append(a.aggregates, &hashAggregate{
@@ -452,6 +494,9 @@ func (a *HashAggregate) Callback(_ context.Context, r arrow.Record) error {
 
 				aggregate = a.aggregates[len(a.aggregates)-1]
 				for j, col := range columnToAggregate {
+					if aggregate.aggregations[j].shared {
+						continue
+					}
 					agg := builder.NewBuilder(a.pool, col.DataType())
 					aggregate.aggregations[j].arrays = append(aggregate.aggregations[j].arrays, agg)
 				}
@@ -473,6 +518,10 @@ func (a *HashAggregate) Callback(_ context.Context, r arrow.Record) error {
 				// This is a dynamic aggregation that had no match.
 				continue
 			}
+			if a.aggregates[tuple.aggregate].aggregations[j].shared {
+				// The values are appended to the source aggregation.
+				continue
+			}
 			if a.aggregates[tuple.aggregate].aggregations[j].arrays == nil {
 				// This can happen with dynamic column aggregations without
 				// groupings. The group exists, but the array to append to does
@@ -597,16 +646,34 @@ func (a *HashAggregate) finishAggregate(ctx context.Context, aggIdx int, aggrega
 	// Rename to clarity upon appending aggregations later
 	aggregateFields := groupByFields
 
-	for _, aggregation := range aggregate.aggregations {
-		arr := make([]arrow.Array, 0, numRows)
+	// values holds the arrays of the values of each group per aggregation,
+	// which aggregations sharing their values read as well.
+	values := make([][]arrow.Array, len(aggregate.aggregations))
+	defer func() {
+		for _, arrs := range values {
+			for _, arr := range arrs {
+				arr.Release()
+			}
+		}
+	}()
+	for i, aggregation := range aggregate.aggregations {
+		if aggregation.shared {
+			continue
+		}
+		arrs := make([]arrow.Array, 0, numRows)
 		for _, a := range aggregation.arrays {
-			arr = append(arr, a.NewArray())
+			arrs = append(arrs, a.NewArray())
 		}
+		values[i] = arrs
+	}
 
-		aggregateArray, err := runAggregation(a.finalStage, aggregation.function, a.pool, arr)
-		for _, a := range arr {
-			a.Release()
+	for i, aggregation := range aggregate.aggregations {
+		arr := values[i]
+		if aggregation.shared {
+			arr = values[aggregation.source]
 		}
+
+		aggregateArray, err := aggregation.run(a.finalStage, a.pool, arr)
 		if err != nil {
 			return fmt.Errorf("aggregate batched arrays: %w", err)
 		}
@@ -949,6 +1016,21 @@ func (a *CountAggregation) Aggregate(pool memory.Allocator, arrs []arrow.Array)
 	return res.NewArray(), nil
 }
 
+// run aggregates the values of each group. Statistical aggregations are run
+// separately, since their functions depend on the stage and the quantile.
+func (a Aggregation) run(finalStage bool, pool memory.Allocator, arrs []arrow.Array) (arrow.Array, error) {
+	var aggFunc AggregationFunction
+	switch a.function {
+	case logicalplan.AggFuncStddev, logicalplan.AggFuncVariance:
+		aggFunc = &VarianceAggregation{Stddev: a.function == logicalplan.AggFuncStddev, FinalStage: finalStage}
+	case logicalplan.AggFuncQuantile:
+		aggFunc = &QuantileAggregation{Quantile: a.quantile, FinalStage: finalStage}
+	default:
+		return runAggregation(finalStage, a.function, pool, arrs)
+	}
+	return aggFunc.Aggregate(pool, arrs)
+}
+
 // runAggregation is a helper to run the given aggregation function given
 // the set of values. It is aware of the final stage and chooses the aggregation
 // function appropriately.
@@ -970,7 +1052,7 @@ func runAggregation(finalStage bool, fn logicalplan.AggFunc, pool memory.Allocat
 	return aggFunc.Aggregate(pool, arrs)
 }
 
-func resultNameWithConcreteColumn(function logicalplan.AggFunc, col string) string {
+func resultNameWithConcreteColumn(function logicalplan.AggFunc, quantile float64, col string) string {
 	switch function {
 	case logicalplan.AggFuncSum:
 		return logicalplan.Sum(logicalplan.Col(col)).Name()
@@ -982,6 +1064,12 @@ func resultNameWithConcreteColumn(function logicalplan.AggFunc, col string) stri
 		return logicalplan.Count(logicalplan.Col(col)).Name()
 	case logicalplan.AggFuncAvg:
 		return logicalplan.Avg(logicalplan.Col(col)).Name()
+	case logicalplan.AggFuncStddev:
+		return logicalplan.Stddev(logicalplan.Col(col)).Name()
+	case logicalplan.AggFuncVariance:
+		return logicalplan.Variance(logicalplan.Col(col)).Name()
+	case logicalplan.AggFuncQuantile:
+		return logicalplan.Quantile(logicalplan.Col(col), quantile).Name()
 	default:
 		return ""
 	}
@@ -14,6 +14,7 @@ import (
 	"github.com/apache/arrow/go/v17/arrow/memory"
 	"github.com/stretchr/testify/require"
 
+	"github.com/polarsignals/frostdb/dynparquet"
 	"github.com/polarsignals/frostdb/query/logicalplan"
 )
 
@@ -116,3 +117,57 @@ func Test_Aggregate_ArrayOverflow(t *testing.T) {
 	require.NoError(t, agg.Finish(ctx))
 	require.Equal(t, int64(n*rows), totalRows)
 }
+
+func TestHashAggregateSharedValues(t *testing.T) {
+	ctx := context.Background()
+	allocator := memory.NewCheckedAllocator(memory.NewGoAllocator())
+	defer allocator.AssertSize(t, 0)
+
+	agg := NewHashAggregate(
+		allocator,
+		noop.NewTracerProvider().Tracer(""),
+		[]Aggregation{
+			{expr: logicalplan.Col("value"), resultName: "sum", function: logicalplan.AggFuncSum},
+			{expr: logicalplan.Col("value"), resultName: "max", function: logicalplan.AggFuncMax},
+		},
+		[]logicalplan.Expr{logicalplan.Col("group")},
+		maphash.MakeSeed(),
+		false,
+	)
+
+	results := map[string][]int64{}
+	agg.SetNext(&OutputPlan{
+		callback: func(_ context.Context, r arrow.Record) error {
+			for i, field := range r.Schema().Fields() {
+				if dynparquet.IsHashedColumn(field.Name) {
+					continue
+				}
+				results[field.Name] = append(results[field.Name], r.Column(i).(*array.Int64).Int64Values()...)
+			}
+			return nil
+		},
+	})
+
+	b := array.NewRecordBuilder(allocator, arrow.NewSchema([]arrow.Field{
+		{Name: "group", Type: arrow.PrimitiveTypes.Int64},
+		{Name: "value", Type: arrow.PrimitiveTypes.Int64},
+	}, nil))
+	defer b.Release()
+	b.Field(0).(*array.Int64Builder).AppendValues([]int64{1, 1, 2}, nil)
+	b.Field(1).(*array.Int64Builder).AppendValues([]int64{1, 2, 5}, nil)
+	r := b.NewRecord()
+	defer r.Release()
+
+	require.NoError(t, agg.Callback(ctx, r))
+	// The values of each group are only buffered by the first aggregation.
+	require.Len(t, agg.aggregates[0].aggregations[0].arrays, 2)
+	require.Empty(t, agg.aggregates[0].aggregations[1].arrays)
+
+	require.NoError(t, agg.Finish(ctx))
+	agg.Close()
+	require.Equal(t, map[string][]int64{
+		"group": {1, 2},
+		"sum":   {3, 5},
+		"max":   {2, 5},
+	}, results)
+}
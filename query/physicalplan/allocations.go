@@ -0,0 +1,150 @@
+package physicalplan
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"unsafe"
+
+	"github.com/apache/arrow/go/v17/arrow/memory"
+)
+
+// WithAllocationTracking enables a debug mode in which every operator of the
+// plan allocates memory through its own allocator, so that memory that is not
+// released by the end of the query can be attributed to the operator that
+// allocated it. If memory is leaked, Execute returns a *LeakError. Memory is
+// also reported as leaked if the callback of the query retains records
+// without releasing them. Tracking allocations is expensive, so this mode is
+// meant for tests and debugging.
+func WithAllocationTracking() Option {
+	return func(o *execOptions) {
+		o.allocationTracking = true
+	}
+}
+
+// Leak describes the memory an operator allocated during a query that was not
+// released by the end of the query.
+type Leak struct {
+	// Operator is the name of the operator that allocated the memory.
+	Operator    string
+	Allocations int
+	Bytes       int
+}
+
+// LeakError is returned by queries executed with WithAllocationTracking if
+// memory was leaked.
+type LeakError struct {
+	// Leaks are sorted by operator.
+	Leaks []Leak
+}
+
+func (e *LeakError) Error() string {
+	leaks := make([]string, 0, len(e.Leaks))
+	for _, l := range e.Leaks {
+		leaks = append(leaks, fmt.Sprintf("%s: %d bytes in %d allocations", l.Operator, l.Bytes, l.Allocations))
+	}
+	return fmt.Sprintf("memory leaked by query: %s", strings.Join(leaks, ", "))
+}
+
+type allocation struct {
+	operator string
+	size     int
+}
+
+// allocationTracker tracks the outstanding allocations of a query by the
+// operator that made them.
+type allocationTracker struct {
+	mtx    sync.Mutex
+	allocs map[uintptr]allocation
+}
+
+func newAllocationTracker() *allocationTracker {
+	return &allocationTracker{allocs: make(map[uintptr]allocation)}
+}
+
+// allocator returns an allocator that attributes allocations made through it
+// to operator. If t is nil, pool is returned.
+func (t *allocationTracker) allocator(pool memory.Allocator, operator string) memory.Allocator {
+	if t == nil {
+		return pool
+	}
+	return &trackingAllocator{
+		Allocator: pool,
+		tracker:   t,
+		operator:  operator,
+	}
+}
+
+func (t *allocationTracker) add(b []byte, operator string) {
+	if len(b) == 0 {
+		return
+	}
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+	t.allocs[uintptr(unsafe.Pointer(&b[0]))] = allocation{operator: operator, size: len(b)}
+}
+
+func (t *allocationTracker) remove(b []byte) {
+	if len(b) == 0 {
+		return
+	}
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+	delete(t.allocs, uintptr(unsafe.Pointer(&b[0])))
+}
+
+// leaks returns a *LeakError describing the outstanding allocations, or nil
+// if all memory was released.
+func (t *allocationTracker) leaks() error {
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+	if len(t.allocs) == 0 {
+		return nil
+	}
+
+	byOperator := make(map[string]*Leak)
+	for _, a := range t.allocs {
+		l, ok := byOperator[a.operator]
+		if !ok {
+			l = &Leak{Operator: a.operator}
+			byOperator[a.operator] = l
+		}
+		l.Allocations++
+		l.Bytes += a.size
+	}
+
+	err := &LeakError{Leaks: make([]Leak, 0, len(byOperator))}
+	for _, l := range byOperator {
+		err.Leaks = append(err.Leaks, *l)
+	}
+	sort.Slice(err.Leaks, func(i, j int) bool {
+		return err.Leaks[i].Operator < err.Leaks[j].Operator
+	})
+	return err
+}
+
+// trackingAllocator records the allocations of an operator with its tracker.
+type trackingAllocator struct {
+	memory.Allocator
+	tracker  *allocationTracker
+	operator string
+}
+
+func (a *trackingAllocator) Allocate(size int) []byte {
+	b := a.Allocator.Allocate(size)
+	a.tracker.add(b, a.operator)
+	return b
+}
+
+func (a *trackingAllocator) Reallocate(size int, b []byte) []byte {
+	a.tracker.remove(b)
+	b = a.Allocator.Reallocate(size, b)
+	a.tracker.add(b, a.operator)
+	return b
+}
+
+func (a *trackingAllocator) Free(b []byte) {
+	a.tracker.remove(b)
+	a.Allocator.Free(b)
+}
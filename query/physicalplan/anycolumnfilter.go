@@ -0,0 +1,80 @@
+package physicalplan
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/apache/arrow/go/v17/arrow"
+	"github.com/apache/arrow/go/v17/arrow/scalar"
+
+	"github.com/polarsignals/frostdb/query/logicalplan"
+)
+
+// AnyColumnFilter matches the rows in which any concrete column of a dynamic
+// column is equal to a value or matches a regular expression. The concrete
+// columns are the columns of each record that belong to the dynamic column.
+type AnyColumnFilter struct {
+	column string
+	// Exactly one of value and regexp is set.
+	value  scalar.Scalar
+	regexp *regexp.Regexp
+}
+
+func anyColumnBooleanExpr(expr *logicalplan.AnyColumnExpr) (BooleanExpression, error) {
+	literal, ok := expr.Value.(*logicalplan.LiteralExpr)
+	if !ok {
+		return nil, fmt.Errorf("any column expression value %s: %w", expr.Value.String(), ErrUnsupportedBooleanExpression)
+	}
+
+	f := &AnyColumnFilter{column: expr.Column.ColumnName}
+	switch expr.Op {
+	case logicalplan.OpEq:
+		f.value = literal.Value
+	case logicalplan.OpRegexMatch:
+		pattern, ok := literal.Value.(*scalar.String)
+		if !ok {
+			return nil, fmt.Errorf("regex pattern must be a string, got %s", literal.Value.DataType())
+		}
+		re, err := regexp.Compile(string(pattern.Data()))
+		if err != nil {
+			return nil, err
+		}
+		f.regexp = re
+	default:
+		return nil, fmt.Errorf("any column expr %s: %w", expr.Op.String(), ErrUnsupportedBooleanExpression)
+	}
+	return f, nil
+}
+
+func (f *AnyColumnFilter) Eval(r arrow.Record) (*Bitmap, error) {
+	res := NewBitmap()
+	prefix := f.column + "."
+	for i, field := range r.Schema().Fields() {
+		if !strings.HasPrefix(field.Name, prefix) {
+			continue
+		}
+
+		var (
+			matches *Bitmap
+			err     error
+		)
+		if f.regexp != nil {
+			matches, err = ArrayScalarRegexMatch(r.Column(i), f.regexp)
+		} else {
+			matches, err = BinaryScalarOperation(r.Column(i), f.value, logicalplan.OpEq)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("column %s: %w", field.Name, err)
+		}
+		res.Or(matches)
+	}
+	return res, nil
+}
+
+func (f *AnyColumnFilter) String() string {
+	if f.regexp != nil {
+		return fmt.Sprintf("any(%s) =~ \"%s\"", f.column, f.regexp.String())
+	}
+	return fmt.Sprintf("any(%s) == %s", f.column, f.value.String())
+}
@@ -0,0 +1,84 @@
+package physicalplan
+
+import (
+	"testing"
+
+	"github.com/apache/arrow/go/v17/arrow"
+	"github.com/apache/arrow/go/v17/arrow/array"
+	"github.com/apache/arrow/go/v17/arrow/memory"
+	"github.com/stretchr/testify/require"
+
+	"github.com/polarsignals/frostdb/query/logicalplan"
+)
+
+func TestAnyColumnFilter(t *testing.T) {
+	mem := memory.NewCheckedAllocator(memory.DefaultAllocator)
+	defer mem.AssertSize(t, 0)
+
+	dictType := &arrow.DictionaryType{IndexType: arrow.PrimitiveTypes.Uint32, ValueType: arrow.BinaryTypes.Binary}
+	schema := arrow.NewSchema([]arrow.Field{
+		{Name: "labels.container", Type: dictType, Nullable: true},
+		{Name: "labels.namespace", Type: dictType, Nullable: true},
+		{Name: "name", Type: arrow.BinaryTypes.String},
+	}, nil)
+
+	rb := array.NewRecordBuilder(mem, schema)
+	defer rb.Release()
+	container := rb.Field(0).(*array.BinaryDictionaryBuilder)
+	require.NoError(t, container.AppendString("api"))
+	container.AppendNull()
+	require.NoError(t, container.AppendString("db"))
+	container.AppendNull()
+	namespace := rb.Field(1).(*array.BinaryDictionaryBuilder)
+	require.NoError(t, namespace.AppendString("prod"))
+	require.NoError(t, namespace.AppendString("api"))
+	namespace.AppendNull()
+	require.NoError(t, namespace.AppendString("dev"))
+	rb.Field(2).(*array.StringBuilder).AppendValues([]string{"api", "api", "api", "api"}, nil)
+	r := rb.NewRecord()
+	defer r.Release()
+
+	labels := logicalplan.DynCol("labels")
+	for _, tc := range []struct {
+		name     string
+		expr     logicalplan.Expr
+		expected []uint32
+	}{
+		{
+			name:     "Eq",
+			expr:     labels.AnyEq(logicalplan.Literal("api")),
+			expected: []uint32{0, 1},
+		},
+		{
+			name:     "EqNoMatch",
+			expr:     labels.AnyEq(logicalplan.Literal("staging")),
+			expected: []uint32{},
+		},
+		{
+			name:     "RegexMatch",
+			expr:     labels.AnyRegexMatch("^d"),
+			expected: []uint32{2, 3},
+		},
+		{
+			name:     "MissingDynamicColumn",
+			expr:     logicalplan.DynCol("missing").AnyRegexMatch(".*"),
+			expected: []uint32{},
+		},
+		{
+			name: "And",
+			expr: logicalplan.And(
+				labels.AnyEq(logicalplan.Literal("api")),
+				labels.AnyEq(logicalplan.Literal("prod")),
+			),
+			expected: []uint32{0},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			expr, err := booleanExpr(tc.expr)
+			require.NoError(t, err)
+			res, err := expr.Eval(r)
+			require.NoError(t, err)
+			require.Equal(t, tc.expected, res.ToArray())
+		})
+	}
+}
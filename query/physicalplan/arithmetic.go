@@ -0,0 +1,253 @@
+package physicalplan
+
+import (
+	"errors"
+	"fmt"
+	"math/bits"
+
+	"github.com/apache/arrow/go/v17/arrow"
+	"github.com/apache/arrow/go/v17/arrow/array"
+	"github.com/apache/arrow/go/v17/arrow/memory"
+
+	"github.com/polarsignals/frostdb/query/logicalplan"
+)
+
+// ErrIntegerOverflow is returned when the result of integer arithmetic does
+// not fit into its type.
+var ErrIntegerOverflow = errors.New("integer overflow")
+
+type number interface {
+	int32 | int64 | uint64 | float64
+}
+
+type numberBuilder[T number] interface {
+	array.Builder
+	Append(T)
+}
+
+// arithmetic evaluates op on the values of left and right, which must be of
+// equal length. The operands are converted to their arithmetic type, see
+// logicalplan.ArithmeticType. A row is null if either operand is null or if it
+// is divided by zero, and ErrIntegerOverflow is returned if the result of a
+// row overflows.
+func arithmetic(mem memory.Allocator, op logicalplan.Op, left, right arrow.Array) (arrow.Array, error) {
+	if left.Len() != right.Len() {
+		return nil, fmt.Errorf("operands must have the same length, got %d and %d", left.Len(), right.Len())
+	}
+	dt, err := logicalplan.ArithmeticType(left.DataType(), right.DataType())
+	if err != nil {
+		return nil, err
+	}
+
+	switch dt.ID() {
+	case arrow.INT32:
+		fn, err := signedArithmetic[int32](op)
+		if err != nil {
+			return nil, err
+		}
+		return evalArithmetic[int32](array.NewInt32Builder(mem), fn, left, right)
+	case arrow.INT64:
+		fn, err := signedArithmetic[int64](op)
+		if err != nil {
+			return nil, err
+		}
+		return evalArithmetic[int64](array.NewInt64Builder(mem), fn, left, right)
+	case arrow.UINT64:
+		fn, err := unsignedArithmetic(op)
+		if err != nil {
+			return nil, err
+		}
+		return evalArithmetic[uint64](array.NewUint64Builder(mem), fn, left, right)
+	case arrow.FLOAT64:
+		fn, err := floatArithmetic(op)
+		if err != nil {
+			return nil, err
+		}
+		return evalArithmetic[float64](array.NewFloat64Builder(mem), fn, left, right)
+	default:
+		return nil, fmt.Errorf("unsupported arithmetic type %s", dt)
+	}
+}
+
+// evalArithmetic appends the results of fn for each row of left and right to
+// b. A result is null if fn returns false.
+func evalArithmetic[T number](
+	b numberBuilder[T],
+	fn func(l, r T) (T, bool, error),
+	left, right arrow.Array,
+) (arrow.Array, error) {
+	defer b.Release()
+
+	leftValue, err := operand[T](left)
+	if err != nil {
+		return nil, err
+	}
+	rightValue, err := operand[T](right)
+	if err != nil {
+		return nil, err
+	}
+
+	b.Reserve(left.Len())
+	for i := 0; i < left.Len(); i++ {
+		if left.IsNull(i) || right.IsNull(i) {
+			b.AppendNull()
+			continue
+		}
+		l, err := leftValue(i)
+		if err != nil {
+			return nil, err
+		}
+		r, err := rightValue(i)
+		if err != nil {
+			return nil, err
+		}
+		v, ok, err := fn(l, r)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			b.AppendNull()
+			continue
+		}
+		b.Append(v)
+	}
+	return b.NewArray(), nil
+}
+
+// operand returns a function that returns the value of arr at an index
+// converted to T, which must be the arithmetic type of arr and the other
+// operand.
+func operand[T number](arr arrow.Array) (func(i int) (T, error), error) {
+	switch arr := arr.(type) {
+	case *array.Int32:
+		return func(i int) (T, error) { return T(arr.Value(i)), nil }, nil
+	case *array.Int64:
+		return func(i int) (T, error) { return T(arr.Value(i)), nil }, nil
+	case *array.Uint64:
+		return func(i int) (T, error) {
+			v := T(arr.Value(i))
+			if v < 0 {
+				// The value does not fit into int64.
+				return 0, ErrIntegerOverflow
+			}
+			return v, nil
+		}, nil
+	case *array.Float64:
+		return func(i int) (T, error) { return T(arr.Value(i)), nil }, nil
+	default:
+		return nil, fmt.Errorf("unsupported arithmetic operand type %s", arr.DataType())
+	}
+}
+
+// signedArithmetic returns the function evaluating op on signed integers,
+// which reports overflows with ErrIntegerOverflow. Integer division truncates
+// towards zero.
+func signedArithmetic[T int32 | int64](op logicalplan.Op) (func(l, r T) (T, bool, error), error) {
+	// isMin reports whether v is the minimum value of T, the only negative
+	// value that is its own negation.
+	isMin := func(v T) bool { return v < 0 && -v == v }
+
+	switch op {
+	case logicalplan.OpAdd:
+		return func(l, r T) (T, bool, error) {
+			v := l + r
+			if (v > l) != (r > 0) {
+				return 0, false, ErrIntegerOverflow
+			}
+			return v, true, nil
+		}, nil
+	case logicalplan.OpSub:
+		return func(l, r T) (T, bool, error) {
+			v := l - r
+			if (v < l) != (r > 0) {
+				return 0, false, ErrIntegerOverflow
+			}
+			return v, true, nil
+		}, nil
+	case logicalplan.OpMul:
+		return func(l, r T) (T, bool, error) {
+			if l == 0 || r == 0 {
+				return 0, true, nil
+			}
+			v := l * r
+			if (l == -1 && isMin(r)) || (r == -1 && isMin(l)) || v/r != l {
+				return 0, false, ErrIntegerOverflow
+			}
+			return v, true, nil
+		}, nil
+	case logicalplan.OpDiv:
+		return func(l, r T) (T, bool, error) {
+			if r == 0 {
+				return 0, false, nil
+			}
+			if r == -1 && isMin(l) {
+				return 0, false, ErrIntegerOverflow
+			}
+			return l / r, true, nil
+		}, nil
+	default:
+		return nil, fmt.Errorf("unsupported arithmetic operator %s", op)
+	}
+}
+
+// unsignedArithmetic returns the function evaluating op on unsigned integers,
+// which reports overflows with ErrIntegerOverflow.
+func unsignedArithmetic(op logicalplan.Op) (func(l, r uint64) (uint64, bool, error), error) {
+	switch op {
+	case logicalplan.OpAdd:
+		return func(l, r uint64) (uint64, bool, error) {
+			v, carry := bits.Add64(l, r, 0)
+			if carry != 0 {
+				return 0, false, ErrIntegerOverflow
+			}
+			return v, true, nil
+		}, nil
+	case logicalplan.OpSub:
+		return func(l, r uint64) (uint64, bool, error) {
+			v, borrow := bits.Sub64(l, r, 0)
+			if borrow != 0 {
+				return 0, false, ErrIntegerOverflow
+			}
+			return v, true, nil
+		}, nil
+	case logicalplan.OpMul:
+		return func(l, r uint64) (uint64, bool, error) {
+			hi, v := bits.Mul64(l, r)
+			if hi != 0 {
+				return 0, false, ErrIntegerOverflow
+			}
+			return v, true, nil
+		}, nil
+	case logicalplan.OpDiv:
+		return func(l, r uint64) (uint64, bool, error) {
+			if r == 0 {
+				return 0, false, nil
+			}
+			return l / r, true, nil
+		}, nil
+	default:
+		return nil, fmt.Errorf("unsupported arithmetic operator %s", op)
+	}
+}
+
+// floatArithmetic returns the function evaluating op on floats. Results out
+// of range are infinite as usual.
+func floatArithmetic(op logicalplan.Op) (func(l, r float64) (float64, bool, error), error) {
+	switch op {
+	case logicalplan.OpAdd:
+		return func(l, r float64) (float64, bool, error) { return l + r, true, nil }, nil
+	case logicalplan.OpSub:
+		return func(l, r float64) (float64, bool, error) { return l - r, true, nil }, nil
+	case logicalplan.OpMul:
+		return func(l, r float64) (float64, bool, error) { return l * r, true, nil }, nil
+	case logicalplan.OpDiv:
+		return func(l, r float64) (float64, bool, error) {
+			if r == 0 {
+				return 0, false, nil
+			}
+			return l / r, true, nil
+		}, nil
+	default:
+		return nil, fmt.Errorf("unsupported arithmetic operator %s", op)
+	}
+}
@@ -0,0 +1,103 @@
+package physicalplan
+
+import (
+	"math"
+	"testing"
+
+	"github.com/apache/arrow/go/v17/arrow"
+	"github.com/apache/arrow/go/v17/arrow/array"
+	"github.com/apache/arrow/go/v17/arrow/memory"
+	"github.com/stretchr/testify/require"
+
+	"github.com/polarsignals/frostdb/query/logicalplan"
+)
+
+func TestArithmeticProjection(t *testing.T) {
+	mem := memory.NewCheckedAllocator(memory.DefaultAllocator)
+	defer mem.AssertSize(t, 0)
+
+	values := array.NewInt64Builder(mem)
+	defer values.Release()
+	values.AppendValues([]int64{10, 9, 5, 7}, []bool{true, true, true, false})
+	durations := array.NewInt32Builder(mem)
+	defer durations.Release()
+	durations.AppendValues([]int32{2, 2, 0, 1}, nil)
+	ratios := array.NewFloat64Builder(mem)
+	defer ratios.Release()
+	ratios.AppendValues([]float64{0.5, 2, 1, 1}, nil)
+
+	r := array.NewRecord(
+		arrow.NewSchema([]arrow.Field{
+			{Name: "value", Type: arrow.PrimitiveTypes.Int64, Nullable: true},
+			{Name: "duration", Type: arrow.PrimitiveTypes.Int32},
+			{Name: "ratio", Type: arrow.PrimitiveTypes.Float64},
+		}, nil),
+		[]arrow.Array{values.NewArray(), durations.NewArray(), ratios.NewArray()},
+		4,
+	)
+	defer r.Release()
+	for _, col := range r.Columns() {
+		col.Release()
+	}
+
+	project := func(t *testing.T, expr logicalplan.Expr) (arrow.Field, arrow.Array) {
+		t.Helper()
+		p, err := projectionFromExpr(expr)
+		require.NoError(t, err)
+		fields, cols, err := p.Project(mem, r)
+		require.NoError(t, err)
+		require.Len(t, cols, 1)
+		return fields[0], cols[0]
+	}
+
+	t.Run("Div", func(t *testing.T) {
+		// Integer operands of different types are converted to int64,
+		// division by zero and null operands result in null.
+		field, arr := project(t, logicalplan.Div(logicalplan.Col("value"), logicalplan.Col("duration")).Alias("rate"))
+		defer arr.Release()
+		require.Equal(t, "rate", field.Name)
+		require.Equal(t, arrow.PrimitiveTypes.Int64, field.Type)
+		res := arr.(*array.Int64)
+		require.Equal(t, int64(5), res.Value(0))
+		require.Equal(t, int64(4), res.Value(1))
+		require.True(t, res.IsNull(2))
+		require.True(t, res.IsNull(3))
+	})
+
+	t.Run("Float", func(t *testing.T) {
+		field, arr := project(t, logicalplan.Mul(logicalplan.Col("value"), logicalplan.Col("ratio")))
+		defer arr.Release()
+		require.Equal(t, arrow.PrimitiveTypes.Float64, field.Type)
+		res := arr.(*array.Float64)
+		require.Equal(t, []float64{5, 18, 5}, res.Float64Values()[:3])
+		require.True(t, res.IsNull(3))
+	})
+
+	t.Run("Literal", func(t *testing.T) {
+		_, arr := project(t, logicalplan.Sub(logicalplan.Col("duration"), logicalplan.Literal(int32(1))))
+		defer arr.Release()
+		require.Equal(t, []int32{1, 1, -1, 0}, arr.(*array.Int32).Int32Values())
+	})
+
+	t.Run("Overflow", func(t *testing.T) {
+		for _, expr := range []logicalplan.Expr{
+			logicalplan.Add(logicalplan.Col("value"), logicalplan.Literal(int64(math.MaxInt64))),
+			logicalplan.Mul(logicalplan.Col("duration"), logicalplan.Literal(int32(math.MaxInt32))),
+			logicalplan.Div(logicalplan.Literal(int64(math.MinInt64)), logicalplan.Literal(int64(-1))),
+			logicalplan.Sub(logicalplan.Literal(uint64(0)), logicalplan.Literal(uint64(1))),
+			logicalplan.Add(logicalplan.Col("value"), logicalplan.Literal(uint64(math.MaxUint64))),
+		} {
+			p, err := projectionFromExpr(expr)
+			require.NoError(t, err)
+			_, _, err = p.Project(mem, r)
+			require.ErrorIs(t, err, ErrIntegerOverflow, expr.String())
+		}
+	})
+
+	t.Run("UnsupportedType", func(t *testing.T) {
+		p, err := projectionFromExpr(logicalplan.Add(logicalplan.Col("value"), logicalplan.Literal("a")))
+		require.NoError(t, err)
+		_, _, err = p.Project(mem, r)
+		require.Error(t, err)
+	})
+}
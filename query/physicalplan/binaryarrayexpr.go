@@ -0,0 +1,101 @@
+package physicalplan
+
+import (
+	"fmt"
+
+	"github.com/apache/arrow/go/v17/arrow"
+	"github.com/apache/arrow/go/v17/arrow/array"
+	"github.com/apache/arrow/go/v17/arrow/compute"
+
+	"github.com/polarsignals/frostdb/query/logicalplan"
+)
+
+// BinaryArrayExpr compares two columns of the same record row by row.
+type BinaryArrayExpr struct {
+	Left  *ArrayRef
+	Op    logicalplan.Op
+	Right *ArrayRef
+}
+
+func (e BinaryArrayExpr) Eval(r arrow.Record) (*Bitmap, error) {
+	leftData, leftExists, err := e.Left.ArrowArray(r)
+	if err != nil {
+		return nil, err
+	}
+
+	rightData, rightExists, err := e.Right.ArrowArray(r)
+	if err != nil {
+		return nil, err
+	}
+
+	if !leftExists || !rightExists {
+		// A missing column only holds nulls and nothing compares to NULL.
+		return NewBitmap(), nil
+	}
+
+	return BinaryArrayOperation(leftData, rightData, e.Op)
+}
+
+func (e BinaryArrayExpr) String() string {
+	return e.Left.String() + " " + e.Op.String() + " " + e.Right.String()
+}
+
+// BinaryArrayOperation evaluates a comparison operator between two arrays of
+// the same length. Rows where either side is null never match.
+func BinaryArrayOperation(left, right arrow.Array, operator logicalplan.Op) (*Bitmap, error) {
+	switch operator {
+	case logicalplan.OpEq, logicalplan.OpNotEq, logicalplan.OpLt, logicalplan.OpLtEq, logicalplan.OpGt, logicalplan.OpGtEq:
+	default:
+		return nil, fmt.Errorf("operator %s: %w", operator, ErrUnsupportedBinaryOperation)
+	}
+
+	if left.Len() != right.Len() {
+		return nil, fmt.Errorf("cannot compare arrays of different lengths %d and %d", left.Len(), right.Len())
+	}
+
+	if l, ok := left.(*array.Boolean); ok {
+		if r, ok := right.(*array.Boolean); ok {
+			return BooleanArrayArrayCompare(l, r, operator)
+		}
+	}
+
+	// Dictionaries are decoded and numeric types are cast to a common type by
+	// the compute functions.
+	leftData := compute.NewDatum(left)
+	defer leftData.Release()
+	rightData := compute.NewDatum(right)
+	defer rightData.Release()
+	return computeBitmap(operator.ArrowString(), leftData, rightData)
+}
+
+// BooleanArrayArrayCompare evaluates a comparison operator between two
+// boolean arrays, where false is ordered before true.
+func BooleanArrayArrayCompare(left, right *array.Boolean, operator logicalplan.Op) (*Bitmap, error) {
+	toInt := func(b bool) int {
+		if b {
+			return 1
+		}
+		return 0
+	}
+
+	res := NewBitmap()
+	for i := 0; i < left.Len(); i++ {
+		if left.IsNull(i) || right.IsNull(i) {
+			continue
+		}
+		cmp := toInt(left.Value(i)) - toInt(right.Value(i))
+		var matched bool
+		switch operator {
+		case logicalplan.OpEq:
+			matched = cmp == 0
+		case logicalplan.OpNotEq:
+			matched = cmp != 0
+		default:
+			matched = compareResult(cmp, operator)
+		}
+		if matched {
+			res.AddInt(i)
+		}
+	}
+	return res, nil
+}
@@ -0,0 +1,72 @@
+package physicalplan
+
+import (
+	"testing"
+
+	"github.com/apache/arrow/go/v17/arrow"
+	"github.com/apache/arrow/go/v17/arrow/array"
+	"github.com/apache/arrow/go/v17/arrow/memory"
+	"github.com/stretchr/testify/require"
+
+	"github.com/polarsignals/frostdb/query/logicalplan"
+)
+
+func TestBinaryArrayExpr(t *testing.T) {
+	mem := memory.NewCheckedAllocator(memory.DefaultAllocator)
+	defer mem.AssertSize(t, 0)
+
+	schema := arrow.NewSchema([]arrow.Field{
+		{Name: "value", Type: arrow.PrimitiveTypes.Int64, Nullable: true},
+		{Name: "baseline", Type: arrow.PrimitiveTypes.Float64},
+		{Name: "name", Type: &arrow.DictionaryType{IndexType: arrow.PrimitiveTypes.Uint32, ValueType: arrow.BinaryTypes.String}},
+		{Name: "other", Type: arrow.BinaryTypes.String},
+		{Name: "left", Type: arrow.FixedWidthTypes.Boolean},
+		{Name: "right", Type: arrow.FixedWidthTypes.Boolean},
+	}, nil)
+
+	rb := array.NewRecordBuilder(mem, schema)
+	defer rb.Release()
+	rb.Field(0).(*array.Int64Builder).AppendValues([]int64{1, 5, 3, 0}, []bool{true, true, true, false})
+	rb.Field(1).(*array.Float64Builder).AppendValues([]float64{2, 5, 1.5, 1}, nil)
+	for _, v := range []string{"a", "b", "c", "d"} {
+		require.NoError(t, rb.Field(2).(*array.BinaryDictionaryBuilder).AppendString(v))
+	}
+	rb.Field(3).(*array.StringBuilder).AppendValues([]string{"b", "b", "a", "d"}, nil)
+	rb.Field(4).(*array.BooleanBuilder).AppendValues([]bool{true, false, true, false}, nil)
+	rb.Field(5).(*array.BooleanBuilder).AppendValues([]bool{false, false, true, true}, nil)
+	r := rb.NewRecord()
+	defer r.Release()
+
+	for _, tc := range []struct {
+		name     string
+		expr     logicalplan.Expr
+		expected []uint32
+	}{
+		{name: "IntGtFloat", expr: logicalplan.Col("value").Gt(logicalplan.Col("baseline")), expected: []uint32{2}},
+		{name: "IntEqFloat", expr: logicalplan.Col("value").Eq(logicalplan.Col("baseline")), expected: []uint32{1}},
+		{name: "IntLtEqFloat", expr: logicalplan.Col("value").LtEq(logicalplan.Col("baseline")), expected: []uint32{0, 1}},
+		{name: "DictionaryEqString", expr: logicalplan.Col("name").Eq(logicalplan.Col("other")), expected: []uint32{1, 3}},
+		{name: "DictionaryGtString", expr: logicalplan.Col("name").Gt(logicalplan.Col("other")), expected: []uint32{2}},
+		{name: "BoolGt", expr: logicalplan.Col("left").Gt(logicalplan.Col("right")), expected: []uint32{0}},
+		{name: "BoolNotEq", expr: logicalplan.Col("left").NotEq(logicalplan.Col("right")), expected: []uint32{0, 3}},
+		{name: "MissingColumn", expr: logicalplan.Col("value").Eq(logicalplan.Col("missing")), expected: []uint32{}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			expr, err := booleanExpr(tc.expr)
+			require.NoError(t, err)
+			_, ok := expr.(*BinaryArrayExpr)
+			require.True(t, ok)
+
+			res, err := expr.Eval(r)
+			require.NoError(t, err)
+			require.Equal(t, tc.expected, res.ToArray())
+		})
+	}
+
+	_, err := booleanExpr(&logicalplan.BinaryExpr{
+		Left:  logicalplan.Col("name"),
+		Op:    logicalplan.OpRegexMatch,
+		Right: logicalplan.Col("other"),
+	})
+	require.ErrorIs(t, err, ErrUnsupportedBooleanExpression)
+}
@@ -10,6 +10,7 @@ import (
 	"github.com/apache/arrow/go/v17/arrow"
 	"github.com/apache/arrow/go/v17/arrow/array"
 	"github.com/apache/arrow/go/v17/arrow/compute"
+	"github.com/apache/arrow/go/v17/arrow/memory"
 	"github.com/apache/arrow/go/v17/arrow/scalar"
 
 	"github.com/polarsignals/frostdb/query/logicalplan"
@@ -17,18 +18,71 @@ import (
 
 type ArrayRef struct {
 	ColumnName string
+	// MapKey, if set, references the values of the key in the maps of the
+	// column instead of the column itself.
+	MapKey *string
+
+	// projection, if set, computes the referenced values from the record,
+	// e.g. the results of a string function.
+	projection columnProjection
+}
+
+// mapKeyArrayRef returns a reference to the values of a map key expression on
+// a column.
+func mapKeyArrayRef(expr *logicalplan.MapKeyExpr) (*ArrayRef, error) {
+	column, ok := expr.Expr.(*logicalplan.Column)
+	if !ok {
+		return nil, fmt.Errorf("map key expression %s must be on a column", expr)
+	}
+	key := expr.Key
+	return &ArrayRef{ColumnName: column.ColumnName, MapKey: &key}, nil
+}
+
+// stringFuncArrayRef returns a reference to the results of a string function.
+func stringFuncArrayRef(expr *logicalplan.StringFuncExpr) (*ArrayRef, error) {
+	p, err := newStringFuncProjection(expr)
+	if err != nil {
+		return nil, err
+	}
+	return &ArrayRef{ColumnName: expr.Name(), projection: p}, nil
 }
 
 func (a *ArrayRef) ArrowArray(r arrow.Record) (arrow.Array, bool, error) {
+	if a.projection != nil {
+		// Callers don't release the returned array, so the values are
+		// allocated with the Go allocator.
+		_, cols, err := a.projection.Project(memory.DefaultAllocator, r)
+		if err != nil {
+			return nil, false, err
+		}
+		if len(cols) == 0 {
+			return nil, false, nil
+		}
+		return cols[0], true, nil
+	}
+
 	fields := r.Schema().FieldIndices(a.ColumnName)
 	if len(fields) != 1 {
 		return nil, false, nil
 	}
 
+	if a.MapKey != nil {
+		// Callers don't release the returned array, so the values are
+		// allocated with the Go allocator.
+		values, err := mapKeyValues(memory.DefaultAllocator, r.Column(fields[0]), *a.MapKey)
+		if err != nil {
+			return nil, false, err
+		}
+		return values, true, nil
+	}
+
 	return r.Column(fields[0]), true, nil
 }
 
 func (a *ArrayRef) String() string {
+	if a.MapKey != nil {
+		return a.ColumnName + "['" + *a.MapKey + "']"
+	}
 	return a.ColumnName
 }
 
@@ -103,9 +157,16 @@ func BinaryScalarOperation(left arrow.Array, right scalar.Scalar, operator logic
 			return DictionaryArrayScalarEqual(arr, right)
 		case logicalplan.OpNotEq:
 			return DictionaryArrayScalarNotEqual(arr, right)
+		case logicalplan.OpLt, logicalplan.OpLtEq, logicalplan.OpGt, logicalplan.OpGtEq:
+			return DictionaryArrayScalarCompare(arr, right, operator)
 		default:
 			return nil, fmt.Errorf("unsupported operator: %v", operator)
 		}
+	case *array.Boolean:
+		switch operator {
+		case logicalplan.OpLt, logicalplan.OpLtEq, logicalplan.OpGt, logicalplan.OpGtEq:
+			return BooleanArrayScalarCompare(arr, right, operator)
+		}
 	}
 
 	switch leftType.(type) {
@@ -121,9 +182,15 @@ func ArrayScalarCompute(funcName string, left arrow.Array, right scalar.Scalar)
 	defer leftData.Release()
 	rightData := compute.NewDatum(right)
 	defer rightData.Release()
+	return computeBitmap(funcName, leftData, rightData)
+}
+
+// computeBitmap calls the given boolean compute function and returns a bitmap
+// of the rows the function evaluated to true for.
+func computeBitmap(funcName string, leftData, rightData compute.Datum) (*Bitmap, error) {
 	equalsResult, err := compute.CallFunction(context.TODO(), funcName, nil, leftData, rightData)
 	if err != nil {
-		if errors.Unwrap(err).Error() == "not implemented" {
+		if errors.Is(err, arrow.ErrNotImplemented) {
 			return nil, ErrUnsupportedBinaryOperation
 		}
 		return nil, fmt.Errorf("error calling equal function: %w", err)
@@ -151,6 +218,103 @@ func ArrayScalarCompute(funcName string, left arrow.Array, right scalar.Scalar)
 	return res, nil
 }
 
+// compareResult returns whether the result of comparing a value to the right
+// hand side of the operator, as returned by bytes.Compare, satisfies the
+// operator.
+func compareResult(cmp int, operator logicalplan.Op) bool {
+	switch operator {
+	case logicalplan.OpLt:
+		return cmp < 0
+	case logicalplan.OpLtEq:
+		return cmp <= 0
+	case logicalplan.OpGt:
+		return cmp > 0
+	case logicalplan.OpGtEq:
+		return cmp >= 0
+	default:
+		return false
+	}
+}
+
+// DictionaryArrayScalarCompare evaluates an ordering operator between a
+// dictionary array of binary or string values and a binary or string scalar.
+// The comparison is evaluated once per dictionary entry.
+func DictionaryArrayScalarCompare(left *array.Dictionary, right scalar.Scalar, operator logicalplan.Op) (*Bitmap, error) {
+	var data []byte
+	switch r := right.(type) {
+	case *scalar.Binary:
+		data = r.Data()
+	case *scalar.String:
+		data = r.Data()
+	default:
+		return nil, fmt.Errorf("unsupported scalar type for dictionary comparison: %T", right)
+	}
+
+	res := NewBitmap()
+	if !right.IsValid() {
+		// Nothing compares to NULL.
+		return res, nil
+	}
+
+	var value func(i int) []byte
+	switch dict := left.Dictionary().(type) {
+	case *array.Binary:
+		value = dict.Value
+	case *array.String:
+		value = func(i int) []byte { return unsafeStringToBytes(dict.Value(i)) }
+	default:
+		return nil, fmt.Errorf("unsupported dictionary type: %T", dict)
+	}
+
+	results := make(map[int]bool)
+	for i := 0; i < left.Len(); i++ {
+		if left.IsNull(i) {
+			continue
+		}
+		idx := left.GetValueIndex(i)
+		matched, ok := results[idx]
+		if !ok {
+			matched = compareResult(bytes.Compare(value(idx), data), operator)
+			results[idx] = matched
+		}
+		if matched {
+			res.Add(uint32(i))
+		}
+	}
+	return res, nil
+}
+
+// BooleanArrayScalarCompare evaluates an ordering operator between a boolean
+// array and a boolean scalar, where false is ordered before true.
+func BooleanArrayScalarCompare(left *array.Boolean, right scalar.Scalar, operator logicalplan.Op) (*Bitmap, error) {
+	r, ok := right.(*scalar.Boolean)
+	if !ok {
+		return nil, fmt.Errorf("unsupported scalar type for boolean comparison: %T", right)
+	}
+
+	res := NewBitmap()
+	if !r.IsValid() {
+		// Nothing compares to NULL.
+		return res, nil
+	}
+
+	toInt := func(b bool) int {
+		if b {
+			return 1
+		}
+		return 0
+	}
+	for i := 0; i < left.Len(); i++ {
+		if left.IsNull(i) {
+			continue
+		}
+		if compareResult(toInt(left.Value(i))-toInt(r.Value), operator) {
+			res.AddInt(i)
+		}
+	}
+	return res, nil
+}
+
 func DictionaryArrayScalarNotEqual(left *array.Dictionary, right scalar.Scalar) (*Bitmap, error) {
 	res := NewBitmap()
 	var data []byte
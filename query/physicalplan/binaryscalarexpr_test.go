@@ -126,3 +126,55 @@ func Test_ArrayScalarCompute_Leak(t *testing.T) {
 	_, err := ArrayScalarCompute("equal", arr, s)
 	require.NoError(t, err)
 }
+
+func TestBinaryScalarOperationTypes(t *testing.T) {
+	mem := memory.NewCheckedAllocator(memory.DefaultAllocator)
+	defer mem.AssertSize(t, 0)
+
+	fb := array.NewFloat64Builder(mem)
+	defer fb.Release()
+	fb.AppendValues([]float64{0.5, 1.5, 2.5}, nil)
+	floats := fb.NewArray()
+	defer floats.Release()
+
+	bb := array.NewBooleanBuilder(mem)
+	defer bb.Release()
+	bb.AppendValues([]bool{true, false, true}, nil)
+	bools := bb.NewArray()
+	defer bools.Release()
+
+	db := array.NewDictionaryBuilder(mem, &arrow.DictionaryType{
+		IndexType: arrow.PrimitiveTypes.Uint32,
+		ValueType: arrow.BinaryTypes.String,
+	}).(*array.BinaryDictionaryBuilder)
+	defer db.Release()
+	require.NoError(t, db.AppendString("b"))
+	require.NoError(t, db.AppendString("a"))
+	db.AppendNull()
+	require.NoError(t, db.AppendString("c"))
+	dict := db.NewArray()
+	defer dict.Release()
+
+	for _, tc := range []struct {
+		name     string
+		left     arrow.Array
+		op       logicalplan.Op
+		right    scalar.Scalar
+		expected []uint32
+	}{
+		{name: "FloatGtFloat", left: floats, op: logicalplan.OpGt, right: scalar.NewFloat64Scalar(1.5), expected: []uint32{2}},
+		{name: "FloatEqFloat", left: floats, op: logicalplan.OpEq, right: scalar.NewFloat64Scalar(1.5), expected: []uint32{1}},
+		{name: "FloatLtInt", left: floats, op: logicalplan.OpLt, right: scalar.NewInt64Scalar(1), expected: []uint32{0}},
+		{name: "BoolEq", left: bools, op: logicalplan.OpEq, right: scalar.NewBooleanScalar(true), expected: []uint32{0, 2}},
+		{name: "BoolLt", left: bools, op: logicalplan.OpLt, right: scalar.NewBooleanScalar(true), expected: []uint32{1}},
+		{name: "BoolGtEq", left: bools, op: logicalplan.OpGtEq, right: scalar.NewBooleanScalar(false), expected: []uint32{0, 1, 2}},
+		{name: "DictionaryGt", left: dict, op: logicalplan.OpGt, right: scalar.NewStringScalar("a"), expected: []uint32{0, 3}},
+		{name: "DictionaryLtEq", left: dict, op: logicalplan.OpLtEq, right: scalar.NewStringScalar("b"), expected: []uint32{0, 1}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			res, err := BinaryScalarOperation(tc.left, tc.right, tc.op)
+			require.NoError(t, err)
+			require.Equal(t, tc.expected, res.ToArray())
+		})
+	}
+}
@@ -0,0 +1,171 @@
+package physicalplan
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/apache/arrow/go/v17/arrow"
+	"github.com/apache/arrow/go/v17/arrow/array"
+	"github.com/apache/arrow/go/v17/arrow/compute"
+	"github.com/apache/arrow/go/v17/arrow/memory"
+
+	"github.com/polarsignals/frostdb/query/logicalplan"
+)
+
+type coalesceProjection struct {
+	expr *logicalplan.CoalesceExpr
+	args []columnProjection
+}
+
+func newCoalesceProjection(expr *logicalplan.CoalesceExpr) (coalesceProjection, error) {
+	if len(expr.Exprs) == 0 {
+		return coalesceProjection{}, errors.New("coalesce: expected at least one argument")
+	}
+	args := make([]columnProjection, 0, len(expr.Exprs))
+	for _, e := range expr.Exprs {
+		p, err := projectionFromExpr(e)
+		if err != nil {
+			return coalesceProjection{}, fmt.Errorf("projection for coalesce argument: %w", err)
+		}
+		args = append(args, p)
+	}
+	return coalesceProjection{
+		expr: expr,
+		args: args,
+	}, nil
+}
+
+func (p coalesceProjection) Name() string {
+	return p.expr.Name()
+}
+
+func (p coalesceProjection) String() string {
+	return p.expr.Name()
+}
+
+func (p coalesceProjection) Project(mem memory.Allocator, ar arrow.Record) ([]arrow.Field, []arrow.Array, error) {
+	cols := make([]arrow.Array, 0, len(p.args))
+	defer func() {
+		for _, arr := range cols {
+			arr.Release()
+		}
+	}()
+	for _, arg := range p.args {
+		fields, arrs, err := arg.Project(mem, ar)
+		if err != nil {
+			return nil, nil, err
+		}
+		if len(fields) == 0 {
+			// A column that is not part of the record is null in every row.
+			continue
+		}
+		if len(fields) != 1 || len(fields) != len(arrs) {
+			for _, arr := range arrs {
+				arr.Release()
+			}
+			return nil, nil, fmt.Errorf("invalid projection for coalesce: expected 1 field and array, got %d fields %d arrays", len(fields), len(arrs))
+		}
+		if arrs[0].DataType().ID() == arrow.NULL {
+			arrs[0].Release()
+			continue
+		}
+		cols = append(cols, arrs[0])
+	}
+	if len(cols) == 0 {
+		// All arguments are null, which is the same as a missing column.
+		return nil, nil, nil
+	}
+
+	res, err := coalesce(mem, cols)
+	if err != nil {
+		return nil, nil, fmt.Errorf("coalesce: %w", err)
+	}
+	return []arrow.Field{{
+		Name:     p.expr.Name(),
+		Type:     res.DataType(),
+		Nullable: true,
+	}}, []arrow.Array{res}, nil
+}
+
+// coalesce returns the first non-null value of cols in each row. The arrays
+// must be of equal length and of types accepted by logicalplan.CoalesceType.
+func coalesce(mem memory.Allocator, cols []arrow.Array) (arrow.Array, error) {
+	types := make([]arrow.DataType, 0, len(cols))
+	for _, col := range cols {
+		if col.Len() != cols[0].Len() {
+			return nil, fmt.Errorf("arguments must have the same length, got %d and %d", cols[0].Len(), col.Len())
+		}
+		types = append(types, col.DataType())
+	}
+	t, err := logicalplan.CoalesceType(types)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, col := range cols {
+		if !arrow.TypeEqual(col.DataType(), t) {
+			// The arguments are differently encoded strings.
+			return coalesceStrings(mem, cols)
+		}
+	}
+
+	if cols[0].NullN() == 0 || len(cols) == 1 {
+		// The first argument has a value in every row.
+		cols[0].Retain()
+		return cols[0], nil
+	}
+
+	// The arguments are concatenated, so that the value of argument j in row i
+	// is taken at j*n+i.
+	concatenated, err := array.Concatenate(cols, mem)
+	if err != nil {
+		return nil, err
+	}
+	defer concatenated.Release()
+
+	n := cols[0].Len()
+	indices := array.NewInt64Builder(mem)
+	defer indices.Release()
+	indices.Reserve(n)
+rows:
+	for i := 0; i < n; i++ {
+		for j, col := range cols {
+			if col.IsValid(i) {
+				indices.UnsafeAppend(int64(j*n + i))
+				continue rows
+			}
+		}
+		indices.UnsafeAppendBoolToBitmap(false)
+	}
+	idx := indices.NewArray()
+	defer idx.Release()
+	return compute.TakeArray(compute.WithAllocator(context.Background(), mem), concatenated, idx)
+}
+
+// coalesceStrings returns the first non-null string of cols in each row.
+func coalesceStrings(mem memory.Allocator, cols []arrow.Array) (arrow.Array, error) {
+	values := make([]func(i int) string, 0, len(cols))
+	for _, col := range cols {
+		v, err := stringValues(col)
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, v)
+	}
+
+	b := array.NewStringBuilder(mem)
+	defer b.Release()
+	b.Reserve(cols[0].Len())
+rows:
+	for i := 0; i < cols[0].Len(); i++ {
+		for j, col := range cols {
+			if col.IsValid(i) {
+				b.Append(values[j](i))
+				continue rows
+			}
+		}
+		b.AppendNull()
+	}
+	return b.NewArray(), nil
+}
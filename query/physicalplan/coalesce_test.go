@@ -0,0 +1,183 @@
+package physicalplan
+
+import (
+	"testing"
+
+	"github.com/apache/arrow/go/v17/arrow"
+	"github.com/apache/arrow/go/v17/arrow/array"
+	"github.com/apache/arrow/go/v17/arrow/memory"
+	"github.com/stretchr/testify/require"
+
+	"github.com/polarsignals/frostdb/query/logicalplan"
+)
+
+func TestCoalesce(t *testing.T) {
+	mem := memory.NewCheckedAllocator(memory.DefaultAllocator)
+	defer mem.AssertSize(t, 0)
+
+	schema := arrow.NewSchema([]arrow.Field{
+		{Name: "labels.job", Type: &arrow.DictionaryType{IndexType: arrow.PrimitiveTypes.Uint32, ValueType: arrow.BinaryTypes.Binary}, Nullable: true},
+		{Name: "labels.service", Type: arrow.BinaryTypes.String, Nullable: true},
+		{Name: "a", Type: arrow.PrimitiveTypes.Int64, Nullable: true},
+		{Name: "b", Type: arrow.PrimitiveTypes.Int64, Nullable: true},
+	}, nil)
+	rb := array.NewRecordBuilder(mem, schema)
+	defer rb.Release()
+	job := rb.Field(0).(*array.BinaryDictionaryBuilder)
+	require.NoError(t, job.AppendString("api"))
+	job.AppendNull()
+	job.AppendNull()
+	require.NoError(t, job.AppendString("db"))
+	rb.Field(1).(*array.StringBuilder).AppendValues([]string{"", "web", "", ""}, []bool{false, true, false, false})
+	rb.Field(2).(*array.Int64Builder).AppendValues([]int64{1, 0, 0, 4}, []bool{true, false, false, true})
+	rb.Field(3).(*array.Int64Builder).AppendValues([]int64{10, 20, 0, 40}, []bool{true, true, false, true})
+	r := rb.NewRecord()
+	defer r.Release()
+
+	t.Run("Strings", func(t *testing.T) {
+		for _, tc := range []struct {
+			name     string
+			expr     logicalplan.Expr
+			expected []*string
+		}{{
+			name:     "Default",
+			expr:     logicalplan.Coalesce(logicalplan.Col("labels.job"), logicalplan.Literal("unknown")),
+			expected: nullableStrings("api", "unknown", "unknown", "db"),
+		}, {
+			name:     "Columns",
+			expr:     logicalplan.Coalesce(logicalplan.Col("labels.job"), logicalplan.Col("labels.service")),
+			expected: nullableStrings("api", "web", nil, "db"),
+		}, {
+			name:     "MissingColumn",
+			expr:     logicalplan.Coalesce(logicalplan.Col("labels.missing"), logicalplan.Literal(nil), logicalplan.Col("labels.service"), logicalplan.Literal("unknown")),
+			expected: nullableStrings("unknown", "web", "unknown", "unknown"),
+		}} {
+			t.Run(tc.name, func(t *testing.T) {
+				p, err := projectionFromExpr(tc.expr)
+				require.NoError(t, err)
+				fields, cols, err := p.Project(mem, r)
+				require.NoError(t, err)
+				require.Len(t, cols, 1)
+				defer cols[0].Release()
+				require.Equal(t, tc.expr.Name(), fields[0].Name)
+
+				values, err := stringValues(cols[0])
+				require.NoError(t, err)
+				require.Equal(t, len(tc.expected), cols[0].Len())
+				for i, v := range tc.expected {
+					if v == nil {
+						require.True(t, cols[0].IsNull(i), "row %d", i)
+						continue
+					}
+					require.Equal(t, *v, values(i), "row %d", i)
+				}
+			})
+		}
+	})
+
+	t.Run("Int64", func(t *testing.T) {
+		p, err := projectionFromExpr(logicalplan.Coalesce(logicalplan.Col("a"), logicalplan.Col("b")))
+		require.NoError(t, err)
+		_, cols, err := p.Project(mem, r)
+		require.NoError(t, err)
+		require.Len(t, cols, 1)
+		defer cols[0].Release()
+
+		res := cols[0].(*array.Int64)
+		require.Equal(t, int64(1), res.Value(0))
+		require.Equal(t, int64(20), res.Value(1))
+		require.True(t, res.IsNull(2))
+		require.Equal(t, int64(4), res.Value(3))
+	})
+
+	t.Run("AllMissing", func(t *testing.T) {
+		p, err := projectionFromExpr(logicalplan.Coalesce(logicalplan.Col("missing"), logicalplan.Literal(nil)))
+		require.NoError(t, err)
+		fields, cols, err := p.Project(mem, r)
+		require.NoError(t, err)
+		require.Empty(t, fields)
+		require.Empty(t, cols)
+	})
+
+	t.Run("MixedTypes", func(t *testing.T) {
+		p, err := projectionFromExpr(logicalplan.Coalesce(logicalplan.Col("a"), logicalplan.Literal("unknown")))
+		require.NoError(t, err)
+		_, _, err = p.Project(mem, r)
+		require.Error(t, err)
+	})
+}
+
+func TestNullSafeEq(t *testing.T) {
+	mem := memory.NewCheckedAllocator(memory.DefaultAllocator)
+	defer mem.AssertSize(t, 0)
+
+	schema := arrow.NewSchema([]arrow.Field{
+		{Name: "labels.job", Type: &arrow.DictionaryType{IndexType: arrow.PrimitiveTypes.Uint32, ValueType: arrow.BinaryTypes.Binary}, Nullable: true},
+		{Name: "a", Type: arrow.PrimitiveTypes.Int64, Nullable: true},
+		{Name: "b", Type: arrow.PrimitiveTypes.Int64, Nullable: true},
+	}, nil)
+	rb := array.NewRecordBuilder(mem, schema)
+	defer rb.Release()
+	job := rb.Field(0).(*array.BinaryDictionaryBuilder)
+	require.NoError(t, job.AppendString("api"))
+	job.AppendNull()
+	require.NoError(t, job.AppendString(""))
+	require.NoError(t, job.AppendString("db"))
+	rb.Field(1).(*array.Int64Builder).AppendValues([]int64{1, 0, 3, 0}, []bool{true, false, true, false})
+	rb.Field(2).(*array.Int64Builder).AppendValues([]int64{1, 0, 4, 4}, []bool{true, false, true, true})
+	r := rb.NewRecord()
+	defer r.Release()
+
+	for _, tc := range []struct {
+		name     string
+		expr     logicalplan.Expr
+		expected []uint32
+	}{{
+		name:     "Value",
+		expr:     logicalplan.Col("labels.job").NullSafeEq(logicalplan.Literal("api")),
+		expected: []uint32{0},
+	}, {
+		name:     "Null",
+		expr:     logicalplan.Col("labels.job").NullSafeEq(logicalplan.Literal(nil)),
+		expected: []uint32{1},
+	}, {
+		name:     "MissingColumnValue",
+		expr:     logicalplan.Col("labels.missing").NullSafeEq(logicalplan.Literal("")),
+		expected: []uint32{},
+	}, {
+		name:     "MissingColumnNull",
+		expr:     logicalplan.Col("labels.missing").NullSafeEq(logicalplan.Literal(nil)),
+		expected: []uint32{0, 1, 2, 3},
+	}, {
+		name:     "Columns",
+		expr:     logicalplan.Col("a").NullSafeEq(logicalplan.Col("b")),
+		expected: []uint32{0, 1},
+	}, {
+		name:     "MissingColumn",
+		expr:     logicalplan.Col("a").NullSafeEq(logicalplan.Col("missing")),
+		expected: []uint32{1, 3},
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			f, err := booleanExpr(tc.expr)
+			require.NoError(t, err)
+			bm, err := f.Eval(r)
+			require.NoError(t, err)
+			require.Equal(t, tc.expected, bm.ToArray())
+		})
+	}
+
+	t.Run("Projection", func(t *testing.T) {
+		expr := logicalplan.Col("labels.job").NullSafeEq(logicalplan.Literal(nil))
+		p, err := projectionFromExpr(expr)
+		require.NoError(t, err)
+		_, cols, err := p.Project(mem, r)
+		require.NoError(t, err)
+		require.Len(t, cols, 1)
+		defer cols[0].Release()
+		res := cols[0].(*array.Boolean)
+		require.Zero(t, res.NullN())
+		for i, expected := range []bool{false, true, false, false} {
+			require.Equal(t, expected, res.Value(i), "row %d", i)
+		}
+	})
+}
@@ -0,0 +1,180 @@
+package physicalplan
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/apache/arrow/go/v17/arrow"
+	"github.com/apache/arrow/go/v17/arrow/array"
+	"github.com/apache/arrow/go/v17/arrow/memory"
+
+	"github.com/polarsignals/frostdb/query/logicalplan"
+)
+
+type dateTruncProjection struct {
+	expr *logicalplan.DateTruncExpr
+	p    columnProjection
+}
+
+func (p dateTruncProjection) Name() string {
+	return p.expr.Name()
+}
+
+func (p dateTruncProjection) String() string {
+	return p.expr.Name()
+}
+
+func (p dateTruncProjection) Project(mem memory.Allocator, ar arrow.Record) ([]arrow.Field, []arrow.Array, error) {
+	fields, cols, err := p.p.Project(mem, ar)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer func() {
+		for _, arr := range cols {
+			arr.Release()
+		}
+	}()
+
+	if len(fields) != 1 || len(fields) != len(cols) {
+		return nil, nil, fmt.Errorf("invalid projection for date_trunc: expected 1 field and array, got %d fields %d arrays", len(fields), len(cols))
+	}
+
+	var valid []bool
+	if cols[0].NullN() > 0 {
+		valid = make([]bool, cols[0].Len())
+		for i := range valid {
+			valid[i] = cols[0].IsValid(i)
+		}
+	}
+
+	var res arrow.Array
+	switch c := cols[0].(type) {
+	case *array.Int64:
+		t, err := newDateTruncator(p.expr.Unit, p.expr.Location, p.expr.Precision)
+		if err != nil {
+			return nil, nil, err
+		}
+		b := array.NewInt64Builder(mem)
+		defer b.Release()
+		b.AppendValues(t.truncateAll(c.Int64Values(), valid), valid)
+		res = b.NewArray()
+	case *array.Timestamp:
+		typ := c.DataType().(*arrow.TimestampType)
+		t, err := newDateTruncator(p.expr.Unit, p.expr.Location, typ.Unit)
+		if err != nil {
+			return nil, nil, err
+		}
+		values := make([]int64, c.Len())
+		for i, v := range c.TimestampValues() {
+			values[i] = int64(v)
+		}
+		truncated := t.truncateAll(values, valid)
+		timestamps := make([]arrow.Timestamp, len(truncated))
+		for i, v := range truncated {
+			timestamps[i] = arrow.Timestamp(v)
+		}
+		b := array.NewTimestampBuilder(mem, typ)
+		defer b.Release()
+		b.AppendValues(timestamps, valid)
+		res = b.NewArray()
+	default:
+		return nil, nil, fmt.Errorf("date_trunc: unsupported type %s, expected int64 or timestamp", cols[0].DataType())
+	}
+
+	return []arrow.Field{{
+		Name:     p.expr.Name(),
+		Type:     res.DataType(),
+		Nullable: fields[0].Nullable,
+		Metadata: fields[0].Metadata,
+	}}, []arrow.Array{res}, nil
+}
+
+// dateTruncator truncates timestamps to calendar units in a time zone.
+type dateTruncator struct {
+	unit logicalplan.TimeUnit
+	loc  *time.Location
+	// nanos is the number of nanoseconds per timestamp unit.
+	nanos int64
+
+	// [start, end) is the most recently computed bucket. Timestamps are
+	// usually sorted or clustered, so most timestamps fall into the previous
+	// bucket and no time zone computations are needed to truncate them.
+	start, end int64
+}
+
+func newDateTruncator(unit logicalplan.TimeUnit, loc *time.Location, precision arrow.TimeUnit) (*dateTruncator, error) {
+	switch unit {
+	case logicalplan.TimeUnitHour, logicalplan.TimeUnitDay, logicalplan.TimeUnitWeek, logicalplan.TimeUnitMonth:
+	default:
+		return nil, fmt.Errorf("date_trunc: unsupported time unit %s", unit)
+	}
+	if loc == nil {
+		loc = time.UTC
+	}
+	return &dateTruncator{
+		unit:  unit,
+		loc:   loc,
+		nanos: int64(precision.Multiplier()),
+	}, nil
+}
+
+// truncateAll truncates all values. If valid is non-nil, values that are not
+// valid are skipped and set to 0.
+func (d *dateTruncator) truncateAll(values []int64, valid []bool) []int64 {
+	res := make([]int64, len(values))
+	for i, v := range values {
+		if valid != nil && !valid[i] {
+			continue
+		}
+		res[i] = d.truncate(v)
+	}
+	return res
+}
+
+func (d *dateTruncator) truncate(v int64) int64 {
+	if v >= d.start && v < d.end {
+		return d.start
+	}
+
+	t := time.Unix(0, v*d.nanos).In(d.loc)
+	start, end := d.bucket(t)
+	d.start = floorDiv(start.UnixNano(), d.nanos)
+	d.end = floorDiv(end.UnixNano(), d.nanos)
+	return d.start
+}
+
+// bucket returns the bounds of the bucket containing t. If the bucket can not
+// be reused for other timestamps, end is equal to start.
+func (d *dateTruncator) bucket(t time.Time) (time.Time, time.Time) {
+	y, m, day := t.Date()
+	switch d.unit {
+	case logicalplan.TimeUnitHour:
+		// Truncate in local time using the offset in effect at t, so that
+		// the repeated hour of a daylight saving time transition is
+		// bucketed correctly.
+		_, offset := t.Zone()
+		local := t.Unix() + int64(offset)
+		start := time.Unix(floorDiv(local, 3600)*3600-int64(offset), 0).In(d.loc)
+		end := start.Add(time.Hour)
+		if _, endOffset := end.Add(-time.Nanosecond).Zone(); endOffset != offset {
+			return start, start
+		}
+		return start, end
+	case logicalplan.TimeUnitDay:
+		return time.Date(y, m, day, 0, 0, 0, 0, d.loc), time.Date(y, m, day+1, 0, 0, 0, 0, d.loc)
+	case logicalplan.TimeUnitWeek:
+		day -= (int(t.Weekday()) + 6) % 7 // Weeks start on Monday.
+		return time.Date(y, m, day, 0, 0, 0, 0, d.loc), time.Date(y, m, day+7, 0, 0, 0, 0, d.loc)
+	default: // logicalplan.TimeUnitMonth
+		return time.Date(y, m, 1, 0, 0, 0, 0, d.loc), time.Date(y, m+1, 1, 0, 0, 0, 0, d.loc)
+	}
+}
+
+// floorDiv returns a/b rounded towards negative infinity.
+func floorDiv(a, b int64) int64 {
+	q := a / b
+	if a%b != 0 && (a < 0) != (b < 0) {
+		q--
+	}
+	return q
+}
@@ -0,0 +1,131 @@
+package physicalplan
+
+import (
+	"testing"
+	"time"
+	_ "time/tzdata"
+
+	"github.com/apache/arrow/go/v17/arrow"
+	"github.com/apache/arrow/go/v17/arrow/array"
+	"github.com/apache/arrow/go/v17/arrow/memory"
+	"github.com/stretchr/testify/require"
+
+	"github.com/polarsignals/frostdb/query/logicalplan"
+)
+
+func TestDateTrunc(t *testing.T) {
+	newYork, err := time.LoadLocation("America/New_York")
+	require.NoError(t, err)
+	kolkata, err := time.LoadLocation("Asia/Kolkata")
+	require.NoError(t, err)
+
+	utc := func(s string) time.Time {
+		ts, err := time.Parse(time.RFC3339, s)
+		require.NoError(t, err)
+		return ts
+	}
+
+	for _, tc := range []struct {
+		name     string
+		unit     logicalplan.TimeUnit
+		loc      *time.Location
+		input    []string
+		expected []string
+	}{
+		{
+			// The UTC day boundary is not the local day boundary.
+			name:     "DayNewYork",
+			unit:     logicalplan.TimeUnitDay,
+			loc:      newYork,
+			input:    []string{"2024-03-10T03:30:00Z", "2024-03-10T12:00:00Z", "2024-03-10T23:00:00Z"},
+			expected: []string{"2024-03-09T05:00:00Z", "2024-03-10T05:00:00Z", "2024-03-10T05:00:00Z"},
+		},
+		{
+			// Local hours start at half past the UTC hour.
+			name:     "HourKolkata",
+			unit:     logicalplan.TimeUnitHour,
+			loc:      kolkata,
+			input:    []string{"2024-01-01T10:15:00Z", "2024-01-01T10:45:00Z"},
+			expected: []string{"2024-01-01T09:30:00Z", "2024-01-01T10:30:00Z"},
+		},
+		{
+			// 01:30 occurs twice when daylight saving time ends.
+			name:     "HourDSTEnd",
+			unit:     logicalplan.TimeUnitHour,
+			loc:      newYork,
+			input:    []string{"2024-11-03T05:30:00Z", "2024-11-03T06:30:00Z"},
+			expected: []string{"2024-11-03T05:00:00Z", "2024-11-03T06:00:00Z"},
+		},
+		{
+			name:     "Week",
+			unit:     logicalplan.TimeUnitWeek,
+			loc:      time.UTC,
+			input:    []string{"2024-01-03T10:00:00Z", "2024-01-07T23:59:59Z", "2024-01-08T00:00:00Z"},
+			expected: []string{"2024-01-01T00:00:00Z", "2024-01-01T00:00:00Z", "2024-01-08T00:00:00Z"},
+		},
+		{
+			name:     "MonthNewYork",
+			unit:     logicalplan.TimeUnitMonth,
+			loc:      newYork,
+			input:    []string{"2024-02-01T02:00:00Z", "2024-02-01T06:00:00Z"},
+			expected: []string{"2024-01-01T05:00:00Z", "2024-02-01T05:00:00Z"},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			b := array.NewInt64Builder(memory.DefaultAllocator)
+			defer b.Release()
+			for _, s := range tc.input {
+				b.Append(utc(s).UnixMilli())
+			}
+			b.AppendNull()
+			arr := b.NewArray()
+			defer arr.Release()
+
+			r := array.NewRecord(
+				arrow.NewSchema([]arrow.Field{{Name: "timestamp", Type: arrow.PrimitiveTypes.Int64, Nullable: true}}, nil),
+				[]arrow.Array{arr},
+				int64(arr.Len()),
+			)
+			defer r.Release()
+
+			expr := logicalplan.DateTrunc(logicalplan.Col("timestamp"), tc.unit, tc.loc)
+			p, err := projectionFromExpr(expr)
+			require.NoError(t, err)
+			fields, cols, err := p.Project(memory.DefaultAllocator, r)
+			require.NoError(t, err)
+			require.Len(t, cols, 1)
+			defer cols[0].Release()
+			require.Equal(t, expr.Name(), fields[0].Name)
+
+			res := cols[0].(*array.Int64)
+			for i, s := range tc.expected {
+				require.Equal(t, utc(s).UTC(), time.UnixMilli(res.Value(i)).UTC(), "row %d", i)
+			}
+			require.True(t, res.IsNull(len(tc.expected)))
+		})
+	}
+
+	t.Run("Timestamp", func(t *testing.T) {
+		typ := &arrow.TimestampType{Unit: arrow.Nanosecond}
+		b := array.NewTimestampBuilder(memory.DefaultAllocator, typ)
+		defer b.Release()
+		b.Append(arrow.Timestamp(utc("2024-03-10T03:30:00Z").UnixNano()))
+		arr := b.NewArray()
+		defer arr.Release()
+
+		r := array.NewRecord(
+			arrow.NewSchema([]arrow.Field{{Name: "timestamp", Type: typ}}, nil),
+			[]arrow.Array{arr},
+			int64(arr.Len()),
+		)
+		defer r.Release()
+
+		p, err := projectionFromExpr(logicalplan.DateTrunc(logicalplan.Col("timestamp"), logicalplan.TimeUnitDay, newYork))
+		require.NoError(t, err)
+		_, cols, err := p.Project(memory.DefaultAllocator, r)
+		require.NoError(t, err)
+		require.Len(t, cols, 1)
+		defer cols[0].Release()
+		require.Equal(t, arrow.Timestamp(utc("2024-03-09T05:00:00Z").UnixNano()), cols[0].(*array.Timestamp).Value(0))
+	})
+}
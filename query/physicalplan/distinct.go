@@ -9,24 +9,60 @@ import (
 
 	"github.com/apache/arrow/go/v17/arrow"
 	"github.com/apache/arrow/go/v17/arrow/array"
+	"github.com/apache/arrow/go/v17/arrow/compute"
 	"github.com/apache/arrow/go/v17/arrow/memory"
 	"github.com/apache/arrow/go/v17/arrow/scalar"
 	"go.opentelemetry.io/otel/trace"
 
 	"github.com/polarsignals/frostdb/dynparquet"
+	"github.com/polarsignals/frostdb/pqarrow/arrowutils"
 	"github.com/polarsignals/frostdb/pqarrow/builder"
 	"github.com/polarsignals/frostdb/query/logicalplan"
 )
 
+// DefaultDistinctMemoryLimit is the approximate number of bytes a distinct
+// uses to track the rows it has seen before spilling to disk.
+const DefaultDistinctMemoryLimit = 256 << 20
+
+// distinctEntryBytes is the approximate number of bytes used to track a seen
+// row.
+const distinctEntryBytes = 32
+
+// distinctBatchSize is the maximum number of rows of the records spilled when
+// a distinct starts spilling.
+const distinctBatchSize = 8192
+
+// Columns added to the rows spilled by a distinct. Spilled rows are sorted by
+// their hash, and the rows that were already emitted before spilling started
+// are recorded as markers sorted before all other rows of the same hash.
+const (
+	distinctHashColumn   = "__distinct_hash"
+	distinctMarkerColumn = "__distinct_emitted"
+)
+
+// Distinction emits the distinct rows of the given columns. Rows are emitted
+// as soon as they are first seen, which requires remembering the hash of
+// every emitted row. Once the seen rows exceed the memory limit, the
+// Distinction starts spilling: the hashes of all emitted rows and all rows
+// received from then on are passed to a Sorter spilling sorted runs to disk,
+// and the rows that were not emitted yet are deduplicated and emitted on
+// Finish by merging the runs in hash order.
 type Distinction struct {
 	pool     memory.Allocator
 	tracer   trace.Tracer
 	next     PhysicalPlan
+	stats    StatsCollector
 	columns  []logicalplan.Expr
 	hashSeed maphash.Seed
 
+	memoryLimit int64
+	spillDir    string
+
 	mtx  *sync.RWMutex
 	seen map[uint64]struct{}
+	// spill sorts the spilled rows by hash. It is nil until the seen rows
+	// exceed the memory limit.
+	spill *Sorter
 }
 
 func (d *Distinction) Draw() *Diagram {
@@ -43,12 +79,24 @@ func (d *Distinction) Draw() *Diagram {
 	return &Diagram{Details: fmt.Sprintf("Distinction (%s)", strings.Join(columns, ",")), Child: child}
 }
 
-func Distinct(pool memory.Allocator, tracer trace.Tracer, columns []logicalplan.Expr) *Distinction {
+func (d *Distinction) Name() string { return "Distinction" }
+
+func (d *Distinction) Children() []Operator { return nextOperators(d.next) }
+
+func (d *Distinction) Stats() OperatorStats { return d.stats.Stats() }
+
+// Distinct returns a Distinction of the given columns. Once the seen rows use
+// more than approximately memoryLimit bytes, rows are spilled to files in
+// spillDir, or the default temporary directory if it is empty. A
+// memoryLimit <= 0 disables spilling.
+func Distinct(pool memory.Allocator, tracer trace.Tracer, columns []logicalplan.Expr, memoryLimit int64, spillDir string) *Distinction {
 	return &Distinction{
-		pool:     pool,
-		tracer:   tracer,
-		columns:  columns,
-		hashSeed: maphash.MakeSeed(),
+		pool:        pool,
+		tracer:      tracer,
+		columns:     columns,
+		hashSeed:    maphash.MakeSeed(),
+		memoryLimit: memoryLimit,
+		spillDir:    spillDir,
 
 		mtx:  &sync.RWMutex{},
 		seen: make(map[uint64]struct{}),
@@ -60,14 +108,26 @@ func (d *Distinction) SetNext(plan PhysicalPlan) {
 }
 
 func (d *Distinction) Finish(ctx context.Context) error {
+	d.mtx.Lock()
+	spill := d.spill
+	d.mtx.Unlock()
+	if spill != nil {
+		// The spill merges the spilled runs and finishes the next plan.
+		return spill.Finish(ctx)
+	}
 	return d.next.Finish(ctx)
 }
 
 func (d *Distinction) Close() {
+	if d.spill != nil {
+		// Removes the spilled runs, the merge does not close the next plan.
+		d.spill.Close()
+	}
 	d.next.Close()
 }
 
 func (d *Distinction) Callback(ctx context.Context, r arrow.Record) error {
+	d.stats.Observe(r)
 	// Generates high volume of spans. Comment out if needed during development.
 	// ctx, span := d.tracer.Start(ctx, "Distinction/Callback")
 	// defer span.End()
@@ -105,6 +165,7 @@ func (d *Distinction) Callback(ctx context.Context, r arrow.Record) error {
 		colHashes[i] = dynparquet.HashArray(arr)
 	}
 
+	hashes := make([]uint64, numRows)
 	for i := 0; i < numRows; i++ {
 		hash := uint64(0)
 		for j := range colHashes {
@@ -120,7 +181,17 @@ func (d *Distinction) Callback(ctx context.Context, r arrow.Record) error {
 				),
 			)
 		}
+		hashes[i] = hash
+	}
 
+	d.mtx.RLock()
+	spilling := d.spill != nil
+	d.mtx.RUnlock()
+	if spilling {
+		return d.spillRows(ctx, distinctFields, distinctArrays, hashes)
+	}
+
+	for i, hash := range hashes {
 		d.mtx.RLock()
 		if _, ok := d.seen[hash]; ok {
 			d.mtx.RUnlock()
@@ -141,6 +212,17 @@ func (d *Distinction) Callback(ctx context.Context, r arrow.Record) error {
 		d.mtx.Unlock()
 	}
 
+	if d.memoryLimit > 0 {
+		d.mtx.Lock()
+		if d.spill == nil && int64(len(d.seen))*distinctEntryBytes > d.memoryLimit {
+			if err := d.startSpilling(ctx); err != nil {
+				d.mtx.Unlock()
+				return err
+			}
+		}
+		d.mtx.Unlock()
+	}
+
 	if rows == 0 {
 		// No need to call anything further down the chain, no new values were
 		// seen so we can skip.
@@ -168,3 +250,181 @@ func (d *Distinction) Callback(ctx context.Context, r arrow.Record) error {
 	defer distinctRecord.Release()
 	return d.next.Callback(ctx, distinctRecord)
 }
+
+// startSpilling passes the hashes of all emitted rows to a new spill as
+// markers and resets the seen rows. d.mtx must be held.
+func (d *Distinction) startSpilling(ctx context.Context) error {
+	d.spill = Sort(
+		d.pool,
+		d.tracer,
+		[]logicalplan.Expr{
+			logicalplan.Col(distinctHashColumn),
+			// Markers are not null, so they are sorted first.
+			logicalplan.Col(distinctMarkerColumn),
+		},
+		d.memoryLimit,
+		d.spillDir,
+	)
+	d.spill.SetNext(&distinctMerge{pool: d.pool, next: d.next})
+
+	schema := arrow.NewSchema([]arrow.Field{
+		{Name: distinctHashColumn, Type: arrow.PrimitiveTypes.Uint64},
+		{Name: distinctMarkerColumn, Type: arrow.PrimitiveTypes.Int64, Nullable: true},
+	}, nil)
+	b := array.NewRecordBuilder(d.pool, schema)
+	defer b.Release()
+	hashes := b.Field(0).(*array.Uint64Builder)
+	markers := b.Field(1).(*array.Int64Builder)
+	flush := func() error {
+		r := b.NewRecord()
+		defer r.Release()
+		return d.spill.Callback(ctx, r)
+	}
+	for hash := range d.seen {
+		hashes.Append(hash)
+		markers.Append(0)
+		if hashes.Len() == distinctBatchSize {
+			if err := flush(); err != nil {
+				return err
+			}
+		}
+	}
+	if hashes.Len() > 0 {
+		if err := flush(); err != nil {
+			return err
+		}
+	}
+	d.seen = make(map[uint64]struct{})
+	return nil
+}
+
+// spillRows passes the rows with the given hashes to the spill. The seen rows
+// are only used to skip duplicates before spilling them, and are reset once
+// they exceed the memory limit.
+func (d *Distinction) spillRows(ctx context.Context, fields []arrow.Field, arrays []arrow.Array, hashes []uint64) error {
+	d.mtx.Lock()
+	defer d.mtx.Unlock()
+
+	indices := array.NewInt32Builder(d.pool)
+	defer indices.Release()
+	hashBuilder := array.NewUint64Builder(d.pool)
+	defer hashBuilder.Release()
+	for i, hash := range hashes {
+		hashBuilder.Append(hash)
+		if _, ok := d.seen[hash]; ok {
+			continue
+		}
+		d.seen[hash] = struct{}{}
+		indices.Append(int32(i))
+	}
+	if indices.Len() == 0 {
+		return nil
+	}
+	if int64(len(d.seen))*distinctEntryBytes > d.memoryLimit {
+		// The rows are deduplicated when merging the spilled runs.
+		d.seen = make(map[uint64]struct{})
+	}
+
+	hashArr := hashBuilder.NewArray()
+	defer hashArr.Release()
+	r := array.NewRecord(
+		arrow.NewSchema(append(fields[:len(fields):len(fields)], arrow.Field{Name: distinctHashColumn, Type: arrow.PrimitiveTypes.Uint64}), nil),
+		append(arrays[:len(arrays):len(arrays)], hashArr),
+		int64(len(hashes)),
+	)
+	defer r.Release()
+
+	idx := indices.NewInt32Array()
+	defer idx.Release()
+	spilled, err := arrowutils.Take(compute.WithAllocator(ctx, d.pool), r, idx)
+	if err != nil {
+		return err
+	}
+	defer spilled.Release()
+	return d.spill.Callback(ctx, spilled)
+}
+
+// distinctMerge receives the spilled rows of a Distinction sorted by hash and
+// emits the first row of each hash unless it was emitted before spilling
+// started.
+type distinctMerge struct {
+	pool  memory.Allocator
+	next  PhysicalPlan
+	stats StatsCollector
+
+	last    uint64
+	hasLast bool
+}
+
+func (m *distinctMerge) Callback(ctx context.Context, r arrow.Record) error {
+	m.stats.Observe(r)
+	hashIdx := r.Schema().FieldIndices(distinctHashColumn)
+	if len(hashIdx) != 1 {
+		return fmt.Errorf("spilled distinct rows are missing the %s column", distinctHashColumn)
+	}
+	hashes := r.Column(hashIdx[0]).(*array.Uint64)
+	var markers arrow.Array
+	if idx := r.Schema().FieldIndices(distinctMarkerColumn); len(idx) == 1 {
+		markers = r.Column(idx[0])
+	}
+
+	indices := array.NewInt32Builder(m.pool)
+	defer indices.Release()
+	for i := 0; i < hashes.Len(); i++ {
+		hash := hashes.Value(i)
+		if m.hasLast && hash == m.last {
+			continue
+		}
+		m.last, m.hasLast = hash, true
+		if markers != nil && markers.IsValid(i) {
+			// Emitted before spilling started.
+			continue
+		}
+		indices.Append(int32(i))
+	}
+	if indices.Len() == 0 {
+		return nil
+	}
+
+	fields := make([]arrow.Field, 0, r.Schema().NumFields())
+	cols := make([]arrow.Array, 0, r.Schema().NumFields())
+	for i, field := range r.Schema().Fields() {
+		if field.Name == distinctHashColumn || field.Name == distinctMarkerColumn {
+			continue
+		}
+		fields = append(fields, field)
+		cols = append(cols, r.Column(i))
+	}
+	projected := array.NewRecord(arrow.NewSchema(fields, nil), cols, r.NumRows())
+	defer projected.Release()
+
+	idx := indices.NewInt32Array()
+	defer idx.Release()
+	res, err := arrowutils.Take(compute.WithAllocator(ctx, m.pool), projected, idx)
+	if err != nil {
+		return err
+	}
+	defer res.Release()
+	return m.next.Callback(ctx, res)
+}
+
+func (m *distinctMerge) Finish(ctx context.Context) error {
+	return m.next.Finish(ctx)
+}
+
+func (m *distinctMerge) SetNext(next PhysicalPlan) {
+	m.next = next
+}
+
+func (m *distinctMerge) Draw() *Diagram {
+	return nil
+}
+
+func (m *distinctMerge) Name() string { return "DistinctMerge" }
+
+func (m *distinctMerge) Children() []Operator { return nextOperators(m.next) }
+
+func (m *distinctMerge) Stats() OperatorStats { return m.stats.Stats() }
+
+// Close does not close the next plan, which is closed by the Distinction.
+func (m *distinctMerge) Close() {}
@@ -0,0 +1,91 @@
+package physicalplan
+
+import (
+	"context"
+	"os"
+	"sort"
+	"testing"
+
+	"github.com/apache/arrow/go/v17/arrow"
+	"github.com/apache/arrow/go/v17/arrow/array"
+	"github.com/apache/arrow/go/v17/arrow/memory"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/trace/noop"
+
+	"github.com/polarsignals/frostdb/query/logicalplan"
+)
+
+func TestDistinction(t *testing.T) {
+	ctx := context.Background()
+	mem := memory.DefaultAllocator
+
+	newRecord := func(values ...string) arrow.Record {
+		b := array.NewStringBuilder(mem)
+		defer b.Release()
+		b.AppendValues(values, nil)
+		arr := b.NewArray()
+		defer arr.Release()
+		return array.NewRecord(
+			arrow.NewSchema([]arrow.Field{{Name: "a", Type: arrow.BinaryTypes.String}}, nil),
+			[]arrow.Array{arr},
+			int64(len(values)),
+		)
+	}
+	inputs := [][]string{
+		{"x", "y", "z"},
+		{"x", "w", "w"},
+		{"w", "v", "y"},
+		{"u", "v"},
+	}
+
+	for _, spill := range []bool{false, true} {
+		name := "InMemory"
+		if spill {
+			name = "Spill"
+		}
+		t.Run(name, func(t *testing.T) {
+			dir := t.TempDir()
+			memoryLimit := int64(0)
+			if spill {
+				// Spill once more than two rows were seen.
+				memoryLimit = 2 * distinctEntryBytes
+			}
+			d := Distinct(mem, noop.NewTracerProvider().Tracer(""), []logicalplan.Expr{logicalplan.Col("a")}, memoryLimit, dir)
+
+			var got []string
+			d.SetNext(&OutputPlan{
+				callback: func(_ context.Context, r arrow.Record) error {
+					require.Equal(t, 1, r.Schema().NumFields())
+					col := r.Column(0).(*array.String)
+					for i := 0; i < col.Len(); i++ {
+						got = append(got, col.Value(i))
+					}
+					return nil
+				},
+			})
+
+			for _, values := range inputs {
+				r := newRecord(values...)
+				require.NoError(t, d.Callback(ctx, r))
+				r.Release()
+			}
+			if spill {
+				entries, err := os.ReadDir(dir)
+				require.NoError(t, err)
+				require.NotEmpty(t, entries)
+				// Only the rows seen before spilling are emitted right away.
+				require.Equal(t, []string{"x", "y", "z"}, got)
+			}
+			require.NoError(t, d.Finish(ctx))
+			d.Close()
+
+			sort.Strings(got)
+			require.Equal(t, []string{"u", "v", "w", "x", "y", "z"}, got)
+
+			// Spilled runs are removed once the distinct is finished.
+			entries, err := os.ReadDir(dir)
+			require.NoError(t, err)
+			require.Empty(t, entries)
+		})
+	}
+}
@@ -21,6 +21,7 @@ type PredicateFilter struct {
 	tracer     trace.Tracer
 	filterExpr BooleanExpression
 	next       PhysicalPlan
+	stats      StatsCollector
 }
 
 func (f *PredicateFilter) Draw() *Diagram {
@@ -32,6 +33,12 @@ func (f *PredicateFilter) Draw() *Diagram {
 	return &Diagram{Details: details, Child: child}
 }
 
+func (f *PredicateFilter) Name() string { return "PredicateFilter" }
+
+func (f *PredicateFilter) Children() []Operator { return nextOperators(f.next) }
+
+func (f *PredicateFilter) Stats() OperatorStats { return f.stats.Stats() }
+
 type Bitmap = roaring.Bitmap
 
 func NewBitmap() *Bitmap {
@@ -76,8 +83,12 @@ func binaryBooleanExpr(expr *logicalplan.BinaryExpr) (BooleanExpression, error)
 		logicalplan.OpMul,
 		logicalplan.OpDiv,
 		logicalplan.OpContains,
-		logicalplan.OpNotContains:
-		var leftColumnRef *ArrayRef
+		logicalplan.OpNotContains,
+		logicalplan.OpNullSafeEq:
+		var (
+			leftColumnRef *ArrayRef
+			err           error
+		)
 		expr.Left.Accept(PreExprVisitorFunc(func(expr logicalplan.Expr) bool {
 			switch e := expr.(type) {
 			case *logicalplan.Column:
@@ -85,26 +96,86 @@ func binaryBooleanExpr(expr *logicalplan.BinaryExpr) (BooleanExpression, error)
 					ColumnName: e.ColumnName,
 				}
 				return false
+			case *logicalplan.MapKeyExpr:
+				leftColumnRef, err = mapKeyArrayRef(e)
+				return false
+			case *logicalplan.StringFuncExpr:
+				leftColumnRef, err = stringFuncArrayRef(e)
+				return false
 			}
 			return true
 		}))
+		if err != nil {
+			return nil, err
+		}
 		if leftColumnRef == nil {
 			return nil, errors.New("left side of binary expression must be a column")
 		}
 
-		var rightScalar scalar.Scalar
+		var (
+			rightScalar    scalar.Scalar
+			rightColumnRef *ArrayRef
+		)
 		expr.Right.Accept(PreExprVisitorFunc(func(expr logicalplan.Expr) bool {
 			switch e := expr.(type) {
 			case *logicalplan.LiteralExpr:
 				rightScalar = e.Value
 				return false
+			case *logicalplan.Column:
+				rightColumnRef = &ArrayRef{
+					ColumnName: e.ColumnName,
+				}
+				return false
+			case *logicalplan.MapKeyExpr:
+				rightColumnRef, err = mapKeyArrayRef(e)
+				return false
+			case *logicalplan.StringFuncExpr:
+				rightColumnRef, err = stringFuncArrayRef(e)
+				return false
 			}
 			return true
 		}))
+		if err != nil {
+			return nil, err
+		}
+		if rightScalar == nil {
+			if rightColumnRef == nil {
+				return nil, errors.New("right side of binary expression must be a literal or a column")
+			}
+			switch expr.Op {
+			case logicalplan.OpNullSafeEq:
+				return &NullSafeEqFilter{
+					left:        leftColumnRef,
+					rightColumn: rightColumnRef,
+				}, nil
+			case logicalplan.OpEq,
+				logicalplan.OpNotEq,
+				logicalplan.OpLt,
+				logicalplan.OpLtEq,
+				logicalplan.OpGt,
+				logicalplan.OpGtEq:
+				return &BinaryArrayExpr{
+					Left:  leftColumnRef,
+					Op:    expr.Op,
+					Right: rightColumnRef,
+				}, nil
+			default:
+				return nil, fmt.Errorf("binary expr %s between two columns: %w", expr.Op.String(), ErrUnsupportedBooleanExpression)
+			}
+		}
 
 		switch expr.Op {
+		case logicalplan.OpNullSafeEq:
+			return &NullSafeEqFilter{
+				left:  leftColumnRef,
+				right: rightScalar,
+			}, nil
 		case logicalplan.OpRegexMatch:
-			regexp, err := regexp.Compile(string(rightScalar.(*scalar.String).Data()))
+			pattern, ok := rightScalar.(*scalar.String)
+			if !ok {
+				return nil, fmt.Errorf("regex pattern must be a string, got %s", rightScalar.DataType())
+			}
+			regexp, err := regexp.Compile(string(pattern.Data()))
 			if err != nil {
 				return nil, err
 			}
@@ -113,7 +184,11 @@ func binaryBooleanExpr(expr *logicalplan.BinaryExpr) (BooleanExpression, error)
 				right: regexp,
 			}, nil
 		case logicalplan.OpRegexNotMatch:
-			regexp, err := regexp.Compile(string(rightScalar.(*scalar.String).Data()))
+			pattern, ok := rightScalar.(*scalar.String)
+			if !ok {
+				return nil, fmt.Errorf("regex pattern must be a string, got %s", rightScalar.DataType())
+			}
+			regexp, err := regexp.Compile(string(pattern.Data()))
 			if err != nil {
 				return nil, err
 			}
@@ -222,11 +297,46 @@ func booleanExpr(expr logicalplan.Expr) (BooleanExpression, error) {
 	switch e := expr.(type) {
 	case *logicalplan.BinaryExpr:
 		return binaryBooleanExpr(e)
+	case *logicalplan.InExpr:
+		return inBooleanExpr(e)
+	case *logicalplan.AnyColumnExpr:
+		return anyColumnBooleanExpr(e)
+	case *logicalplan.IsNullExpr:
+		column, ok := e.Expr.(*logicalplan.Column)
+		if !ok {
+			return nil, errors.New("is null expression must be on a column")
+		}
+		return &IsNullFilter{
+			left: &ArrayRef{ColumnName: column.ColumnName},
+			not:  e.Not,
+		}, nil
 	default:
 		return nil, ErrUnsupportedBooleanExpression
 	}
 }
 
+func inBooleanExpr(expr *logicalplan.InExpr) (BooleanExpression, error) {
+	column, ok := expr.Expr.(*logicalplan.Column)
+	if !ok {
+		return nil, errors.New("left side of in expression must be a column")
+	}
+
+	values := make([]scalar.Scalar, 0, len(expr.Values))
+	for _, v := range expr.Values {
+		literal, ok := v.(*logicalplan.LiteralExpr)
+		if !ok {
+			return nil, fmt.Errorf("in expression value %s: %w", v.String(), ErrUnsupportedBooleanExpression)
+		}
+		values = append(values, literal.Value)
+	}
+
+	return &InExpr{
+		Left:   &ArrayRef{ColumnName: column.ColumnName},
+		Values: values,
+		Not:    expr.Not,
+	}, nil
+}
+
 func Filter(pool memory.Allocator, tracer trace.Tracer, filterExpr logicalplan.Expr) (*PredicateFilter, error) {
 	expr, err := booleanExpr(filterExpr)
 	if err != nil {
@@ -253,6 +363,7 @@ func (f *PredicateFilter) Close() {
 }
 
 func (f *PredicateFilter) Callback(ctx context.Context, r arrow.Record) error {
+	f.stats.Observe(r)
 	// Generates high volume of spans. Comment out if needed during development.
 	// ctx, span := f.tracer.Start(ctx, "PredicateFilter/Callback")
 	// defer span.End()
@@ -273,6 +384,33 @@ func (f *PredicateFilter) Finish(ctx context.Context) error {
 	return f.next.Finish(ctx)
 }
 
+// RemoveMatchingRows returns a record containing all rows of the given record
+// the filter expression does not match. The caller is responsible for
+// releasing the returned record.
+func RemoveMatchingRows(pool memory.Allocator, filterExpr logicalplan.Expr, ar arrow.Record) (arrow.Record, error) {
+	expr, err := booleanExpr(filterExpr)
+	if err != nil {
+		return nil, fmt.Errorf("create bool expr: %w", err)
+	}
+
+	matches, err := expr.Eval(ar)
+	if err != nil {
+		return nil, err
+	}
+	if matches.IsEmpty() {
+		ar.Retain()
+		return ar, nil
+	}
+
+	bitmap := NewBitmap()
+	bitmap.AddRange(0, uint64(ar.NumRows()))
+	bitmap.AndNot(matches)
+	if bitmap.IsEmpty() {
+		return ar.NewSlice(0, 0), nil
+	}
+	return filterByBitmap(pool, bitmap, ar)
+}
+
 func filter(pool memory.Allocator, filterExpr BooleanExpression, ar arrow.Record) (arrow.Record, bool, error) {
 	bitmap, err := filterExpr.Eval(ar)
 	if err != nil {
@@ -283,6 +421,16 @@ func filter(pool memory.Allocator, filterExpr BooleanExpression, ar arrow.Record
 		return nil, true, nil
 	}
 
+	r, err := filterByBitmap(pool, bitmap, ar)
+	if err != nil {
+		return nil, true, err
+	}
+	return r, false, nil
+}
+
+// filterByBitmap returns a record containing the rows of the given record
+// that are set in the bitmap.
+func filterByBitmap(pool memory.Allocator, bitmap *Bitmap, ar arrow.Record) (arrow.Record, error) {
 	indicesToKeep := bitmap.ToArray()
 	ranges := buildIndexRanges(indicesToKeep)
 
@@ -313,13 +461,13 @@ func filter(pool memory.Allocator, filterExpr BooleanExpression, ar arrow.Record
 
 		c, err := array.Concatenate(colRanges, pool)
 		if err != nil {
-			return nil, true, err
+			return nil, err
 		}
 
 		cols = append(cols, c)
 	}
 
-	return array.NewRecord(ar.Schema(), cols, totalRows), false, nil
+	return array.NewRecord(ar.Schema(), cols, totalRows), nil
 }
 
 type IndexRange struct {
@@ -1,9 +1,12 @@
 package physicalplan
 
 import (
+	"regexp"
 	"testing"
 
 	"github.com/apache/arrow/go/v17/arrow"
+	"github.com/apache/arrow/go/v17/arrow/array"
+	"github.com/apache/arrow/go/v17/arrow/memory"
 	"github.com/stretchr/testify/require"
 )
 
@@ -79,3 +82,29 @@ func TestAndExprShortCircuits(t *testing.T) {
 	require.NoError(t, err)
 	require.True(t, result.IsEmpty())
 }
+
+func TestDictionaryRegexMatch(t *testing.T) {
+	mem := memory.NewCheckedAllocator(memory.DefaultAllocator)
+	defer mem.AssertSize(t, 0)
+
+	b := array.NewDictionaryBuilder(mem, &arrow.DictionaryType{
+		IndexType: arrow.PrimitiveTypes.Uint32,
+		ValueType: arrow.BinaryTypes.Binary,
+	}).(*array.BinaryDictionaryBuilder)
+	defer b.Release()
+	require.NoError(t, b.AppendString("foo"))
+	require.NoError(t, b.AppendString("bar"))
+	b.AppendNull()
+	require.NoError(t, b.AppendString("foo"))
+	arr := b.NewArray()
+	defer arr.Release()
+
+	re := regexp.MustCompile("^f")
+	match, err := ArrayScalarRegexMatch(arr, re)
+	require.NoError(t, err)
+	require.Equal(t, []uint32{0, 3}, match.ToArray())
+
+	notMatch, err := ArrayScalarRegexNotMatch(arr, re)
+	require.NoError(t, err)
+	require.Equal(t, []uint32{1}, notMatch.ToArray())
+}
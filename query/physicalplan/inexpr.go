@@ -0,0 +1,209 @@
+package physicalplan
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/apache/arrow/go/v17/arrow"
+	"github.com/apache/arrow/go/v17/arrow/array"
+	"github.com/apache/arrow/go/v17/arrow/scalar"
+
+	"github.com/polarsignals/frostdb/query/logicalplan"
+)
+
+// InExpr matches rows whose value equals any of the values, or none of them
+// if Not is set. Null rows never match NOT IN.
+type InExpr struct {
+	Left   *ArrayRef
+	Values []scalar.Scalar
+	Not    bool
+}
+
+func (e *InExpr) Eval(r arrow.Record) (*Bitmap, error) {
+	leftData, exists, err := e.Left.ArrowArray(r)
+	if err != nil {
+		return nil, err
+	}
+
+	if !exists {
+		// A missing column is treated like a column of empty strings, just
+		// like for BinaryScalarExpr equality.
+		res := NewBitmap()
+		matchesMissing := false
+		for _, v := range e.Values {
+			if isNullOrEmpty(v) {
+				matchesMissing = true
+				break
+			}
+		}
+		if matchesMissing != e.Not {
+			res.AddRange(0, uint64(r.NumRows()))
+		}
+		return res, nil
+	}
+
+	return InOperation(leftData, e.Values, e.Not)
+}
+
+func (e *InExpr) String() string {
+	values := make([]string, len(e.Values))
+	for i, v := range e.Values {
+		values[i] = v.String()
+	}
+	op := " in "
+	if e.Not {
+		op = " not in "
+	}
+	return e.Left.String() + op + "(" + strings.Join(values, ", ") + ")"
+}
+
+func isNullOrEmpty(v scalar.Scalar) bool {
+	if !v.IsValid() {
+		return true
+	}
+	switch t := v.(type) {
+	case *scalar.Binary:
+		return len(t.Data()) == 0
+	case *scalar.String:
+		return len(t.Data()) == 0
+	}
+	return false
+}
+
+// InOperation returns the rows of the array that equal any of the values, or
+// the non-null rows that equal none of them if not is set. Binary and string
+// values are looked up in a set, evaluating dictionary entries only once.
+// Other types are compared to each value in turn.
+func InOperation(left arrow.Array, values []scalar.Scalar, not bool) (*Bitmap, error) {
+	var (
+		res *Bitmap
+		err error
+	)
+	switch arr := left.(type) {
+	case *array.Dictionary:
+		res, err = dictionaryIn(arr, values)
+	case *array.Binary:
+		res, err = bytesIn(arr, arr.Value, values)
+	case *array.String:
+		res, err = bytesIn(arr, func(i int) []byte { return unsafeStringToBytes(arr.Value(i)) }, values)
+	default:
+		res = NewBitmap()
+		for _, v := range values {
+			if !v.IsValid() {
+				addNulls(res, left)
+				continue
+			}
+			matches, err := BinaryScalarOperation(left, v, logicalplan.OpEq)
+			if err != nil {
+				return nil, err
+			}
+			res.Or(matches)
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if !not {
+		return res, nil
+	}
+
+	notIn := NewBitmap()
+	for i := 0; i < left.Len(); i++ {
+		if !left.IsNull(i) && !res.ContainsInt(i) {
+			notIn.AddInt(i)
+		}
+	}
+	return notIn, nil
+}
+
+// valueSet returns the set of binary or string values, and whether any of
+// the values is null.
+func valueSet(values []scalar.Scalar) (map[string]struct{}, bool, error) {
+	set := make(map[string]struct{}, len(values))
+	hasNull := false
+	for _, v := range values {
+		if !v.IsValid() {
+			hasNull = true
+			continue
+		}
+		switch t := v.(type) {
+		case *scalar.Binary:
+			set[string(t.Data())] = struct{}{}
+		case *scalar.String:
+			set[string(t.Data())] = struct{}{}
+		default:
+			return nil, false, fmt.Errorf("unsupported scalar type for in expression on binary column: %T", v)
+		}
+	}
+	return set, hasNull, nil
+}
+
+func addNulls(res *Bitmap, arr arrow.Array) {
+	if arr.NullN() == 0 {
+		return
+	}
+	for i := 0; i < arr.Len(); i++ {
+		if arr.IsNull(i) {
+			res.AddInt(i)
+		}
+	}
+}
+
+func bytesIn(arr arrow.Array, value func(i int) []byte, values []scalar.Scalar) (*Bitmap, error) {
+	set, hasNull, err := valueSet(values)
+	if err != nil {
+		return nil, err
+	}
+
+	res := NewBitmap()
+	for i := 0; i < arr.Len(); i++ {
+		if arr.IsNull(i) {
+			if hasNull {
+				res.AddInt(i)
+			}
+			continue
+		}
+		if _, ok := set[string(value(i))]; ok {
+			res.AddInt(i)
+		}
+	}
+	return res, nil
+}
+
+func dictionaryIn(arr *array.Dictionary, values []scalar.Scalar) (*Bitmap, error) {
+	set, hasNull, err := valueSet(values)
+	if err != nil {
+		return nil, err
+	}
+
+	var value func(i int) []byte
+	switch dict := arr.Dictionary().(type) {
+	case *array.Binary:
+		value = dict.Value
+	case *array.String:
+		value = func(i int) []byte { return unsafeStringToBytes(dict.Value(i)) }
+	default:
+		return nil, fmt.Errorf("unsupported dictionary type: %T", dict)
+	}
+
+	// Look up each dictionary entry only once.
+	matches := make([]bool, arr.Dictionary().Len())
+	for i := range matches {
+		_, matches[i] = set[string(value(i))]
+	}
+
+	res := NewBitmap()
+	for i := 0; i < arr.Len(); i++ {
+		if arr.IsNull(i) {
+			if hasNull {
+				res.AddInt(i)
+			}
+			continue
+		}
+		if matches[arr.GetValueIndex(i)] {
+			res.AddInt(i)
+		}
+	}
+	return res, nil
+}
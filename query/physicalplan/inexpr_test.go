@@ -0,0 +1,96 @@
+package physicalplan
+
+import (
+	"testing"
+
+	"github.com/apache/arrow/go/v17/arrow"
+	"github.com/apache/arrow/go/v17/arrow/array"
+	"github.com/apache/arrow/go/v17/arrow/memory"
+	"github.com/stretchr/testify/require"
+
+	"github.com/polarsignals/frostdb/query/logicalplan"
+)
+
+func TestInExpr(t *testing.T) {
+	mem := memory.NewCheckedAllocator(memory.DefaultAllocator)
+	defer mem.AssertSize(t, 0)
+
+	schema := arrow.NewSchema([]arrow.Field{
+		{Name: "region", Type: &arrow.DictionaryType{IndexType: arrow.PrimitiveTypes.Uint32, ValueType: arrow.BinaryTypes.Binary}, Nullable: true},
+		{Name: "name", Type: arrow.BinaryTypes.String},
+		{Name: "value", Type: arrow.PrimitiveTypes.Int64, Nullable: true},
+	}, nil)
+
+	rb := array.NewRecordBuilder(mem, schema)
+	defer rb.Release()
+	db := rb.Field(0).(*array.BinaryDictionaryBuilder)
+	require.NoError(t, db.AppendString("us-east"))
+	require.NoError(t, db.AppendString("eu-west"))
+	db.AppendNull()
+	require.NoError(t, db.AppendString("us-east"))
+	require.NoError(t, db.AppendString("ap-south"))
+	rb.Field(1).(*array.StringBuilder).AppendValues([]string{"a", "b", "c", "d", "e"}, nil)
+	rb.Field(2).(*array.Int64Builder).AppendValues([]int64{1, 2, 0, 4, 5}, []bool{true, true, false, true, true})
+	r := rb.NewRecord()
+	defer r.Release()
+
+	for _, tc := range []struct {
+		name     string
+		expr     logicalplan.Expr
+		expected []uint32
+	}{
+		{
+			name:     "DictionaryIn",
+			expr:     logicalplan.Col("region").In(logicalplan.Literal("us-east"), logicalplan.Literal("eu-west")),
+			expected: []uint32{0, 1, 3},
+		},
+		{
+			name:     "DictionaryNotIn",
+			expr:     logicalplan.Col("region").NotIn(logicalplan.Literal("us-east"), logicalplan.Literal("eu-west")),
+			expected: []uint32{4},
+		},
+		{
+			name:     "DictionaryInNull",
+			expr:     logicalplan.Col("region").In(logicalplan.Literal("ap-south"), logicalplan.Literal(nil)),
+			expected: []uint32{2, 4},
+		},
+		{
+			name:     "StringIn",
+			expr:     logicalplan.Col("name").In(logicalplan.Literal("b"), logicalplan.Literal("e"), logicalplan.Literal("z")),
+			expected: []uint32{1, 4},
+		},
+		{
+			name:     "IntIn",
+			expr:     logicalplan.Col("value").In(logicalplan.Literal(int64(2)), logicalplan.Literal(int64(5))),
+			expected: []uint32{1, 4},
+		},
+		{
+			name:     "IntNotIn",
+			expr:     logicalplan.Col("value").NotIn(logicalplan.Literal(int64(2)), logicalplan.Literal(int64(5))),
+			expected: []uint32{0, 3},
+		},
+		{
+			name:     "MissingColumnIn",
+			expr:     logicalplan.Col("missing").In(logicalplan.Literal("a")),
+			expected: []uint32{},
+		},
+		{
+			name:     "MissingColumnInEmpty",
+			expr:     logicalplan.Col("missing").In(logicalplan.Literal("a"), logicalplan.Literal("")),
+			expected: []uint32{0, 1, 2, 3, 4},
+		},
+		{
+			name:     "MissingColumnNotIn",
+			expr:     logicalplan.Col("missing").NotIn(logicalplan.Literal("a")),
+			expected: []uint32{0, 1, 2, 3, 4},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			expr, err := booleanExpr(tc.expr)
+			require.NoError(t, err)
+			res, err := expr.Eval(r)
+			require.NoError(t, err)
+			require.Equal(t, tc.expected, res.ToArray())
+		})
+	}
+}
@@ -0,0 +1,57 @@
+package physicalplan
+
+import (
+	"github.com/apache/arrow/go/v17/arrow"
+)
+
+// IsNullFilter matches the rows where the column is null, or not null if not
+// is set. A column missing from a record is null in every row.
+type IsNullFilter struct {
+	left *ArrayRef
+	not  bool
+}
+
+func (f *IsNullFilter) Eval(r arrow.Record) (*Bitmap, error) {
+	leftData, exists, err := f.left.ArrowArray(r)
+	if err != nil {
+		return nil, err
+	}
+
+	res := NewBitmap()
+	if !exists {
+		if !f.not {
+			res.AddRange(0, uint64(r.NumRows()))
+		}
+		return res, nil
+	}
+
+	return ArrayIsNull(leftData, f.not), nil
+}
+
+func (f *IsNullFilter) String() string {
+	if f.not {
+		return f.left.String() + " IS NOT NULL"
+	}
+	return f.left.String() + " IS NULL"
+}
+
+// ArrayIsNull returns the rows of the array that are null, or not null if not
+// is set.
+func ArrayIsNull(arr arrow.Array, not bool) *Bitmap {
+	res := NewBitmap()
+	nulls := arr.NullN()
+	switch {
+	case nulls == 0 && not, nulls == arr.Len() && !not:
+		res.AddRange(0, uint64(arr.Len()))
+		return res
+	case nulls == 0 && !not, nulls == arr.Len() && not:
+		return res
+	}
+
+	for i := 0; i < arr.Len(); i++ {
+		if arr.IsNull(i) != not {
+			res.AddInt(i)
+		}
+	}
+	return res
+}
@@ -0,0 +1,54 @@
+package physicalplan
+
+import (
+	"testing"
+
+	"github.com/apache/arrow/go/v17/arrow"
+	"github.com/apache/arrow/go/v17/arrow/array"
+	"github.com/apache/arrow/go/v17/arrow/memory"
+	"github.com/stretchr/testify/require"
+
+	"github.com/polarsignals/frostdb/query/logicalplan"
+)
+
+func TestIsNullFilter(t *testing.T) {
+	mem := memory.NewCheckedAllocator(memory.DefaultAllocator)
+	defer mem.AssertSize(t, 0)
+
+	schema := arrow.NewSchema([]arrow.Field{
+		{Name: "labels.region", Type: &arrow.DictionaryType{IndexType: arrow.PrimitiveTypes.Uint32, ValueType: arrow.BinaryTypes.Binary}, Nullable: true},
+		{Name: "value", Type: arrow.PrimitiveTypes.Int64},
+	}, nil)
+
+	rb := array.NewRecordBuilder(mem, schema)
+	defer rb.Release()
+	db := rb.Field(0).(*array.BinaryDictionaryBuilder)
+	require.NoError(t, db.AppendString("us-east"))
+	db.AppendNull()
+	db.AppendNull()
+	require.NoError(t, db.AppendString("eu-west"))
+	rb.Field(1).(*array.Int64Builder).AppendValues([]int64{1, 2, 3, 4}, nil)
+	r := rb.NewRecord()
+	defer r.Release()
+
+	for _, tc := range []struct {
+		name     string
+		expr     logicalplan.Expr
+		expected []uint32
+	}{
+		{name: "IsNull", expr: logicalplan.Col("labels.region").IsNull(), expected: []uint32{1, 2}},
+		{name: "IsNotNull", expr: logicalplan.Col("labels.region").IsNotNull(), expected: []uint32{0, 3}},
+		{name: "NoNulls", expr: logicalplan.Col("value").IsNull(), expected: []uint32{}},
+		{name: "NoNullsNot", expr: logicalplan.Col("value").IsNotNull(), expected: []uint32{0, 1, 2, 3}},
+		{name: "MissingColumn", expr: logicalplan.Col("labels.missing").IsNull(), expected: []uint32{0, 1, 2, 3}},
+		{name: "MissingColumnNot", expr: logicalplan.Col("labels.missing").IsNotNull(), expected: []uint32{}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			expr, err := booleanExpr(tc.expr)
+			require.NoError(t, err)
+			res, err := expr.Eval(r)
+			require.NoError(t, err)
+			require.Equal(t, tc.expected, res.ToArray())
+		})
+	}
+}
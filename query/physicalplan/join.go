@@ -0,0 +1,275 @@
+package physicalplan
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/apache/arrow/go/v17/arrow"
+	"github.com/apache/arrow/go/v17/arrow/array"
+	"github.com/apache/arrow/go/v17/arrow/compute"
+	"github.com/apache/arrow/go/v17/arrow/memory"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/polarsignals/frostdb/dynparquet"
+	"github.com/polarsignals/frostdb/pqarrow/arrowutils"
+	"github.com/polarsignals/frostdb/query/logicalplan"
+)
+
+// HashJoin joins the records it is called with to the records produced by a
+// separate plan, the build side. The records of the build side are collected
+// into a hash table keyed by their join columns once the first record is to
+// be joined, so the build side is not executed if there is nothing to join.
+// Records must not be passed to a HashJoin concurrently.
+type HashJoin struct {
+	pool   memory.Allocator
+	tracer trace.Tracer
+	next   PhysicalPlan
+	stats  StatsCollector
+
+	// right is the build side, which is executed with rightPool.
+	right     *OutputPlan
+	rightPool memory.Allocator
+	on        []logicalplan.Expr
+	leftKeys  []string
+	rightKeys []string
+	// omitted are the columns of the build side that are not part of joined
+	// records, as they were joined by name.
+	omitted map[string]struct{}
+
+	built   bool
+	records []arrow.Record
+	// rows are the rows of the build side by the hash of their join columns.
+	rows map[uint64][]joinRow
+}
+
+// joinRow is a row of a record of the build side of a join.
+type joinRow struct {
+	record int
+	row    int
+}
+
+// joinedRow is a row of a record to join and the row of a record of the build
+// side it matches.
+type joinedRow struct {
+	left  int
+	right int
+}
+
+// NewHashJoin returns a HashJoin that joins the records it is called with to the
+// records produced by right, which is executed with rightPool.
+func NewHashJoin(
+	pool memory.Allocator,
+	tracer trace.Tracer,
+	right *OutputPlan,
+	rightPool memory.Allocator,
+	join *logicalplan.Join,
+) (*HashJoin, error) {
+	leftCols, rightCols, ok := join.Keys()
+	if !ok {
+		return nil, fmt.Errorf("invalid join expressions: %v", join.On)
+	}
+
+	j := &HashJoin{
+		pool:      pool,
+		tracer:    tracer,
+		right:     right,
+		rightPool: rightPool,
+		on:        join.On,
+		leftKeys:  make([]string, 0, len(leftCols)),
+		rightKeys: make([]string, 0, len(rightCols)),
+		omitted:   make(map[string]struct{}),
+		rows:      make(map[uint64][]joinRow),
+	}
+	for i := range leftCols {
+		j.leftKeys = append(j.leftKeys, leftCols[i].ColumnName)
+		j.rightKeys = append(j.rightKeys, rightCols[i].ColumnName)
+		if leftCols[i].ColumnName == rightCols[i].ColumnName {
+			j.omitted[leftCols[i].ColumnName] = struct{}{}
+		}
+	}
+	right.SetNextCallback(j.collect)
+	return j, nil
+}
+
+func (j *HashJoin) SetNext(next PhysicalPlan) {
+	j.next = next
+}
+
+func (j *HashJoin) Finish(ctx context.Context) error {
+	return j.next.Finish(ctx)
+}
+
+func (j *HashJoin) Close() {
+	for _, r := range j.records {
+		r.Release()
+	}
+	j.records = nil
+	j.rows = nil
+	j.next.Close()
+}
+
+func (j *HashJoin) Draw() *Diagram {
+	var child *Diagram
+	if j.next != nil {
+		child = j.next.Draw()
+	}
+
+	on := make([]string, 0, len(j.on))
+	for _, expr := range j.on {
+		on = append(on, expr.String())
+	}
+	return &Diagram{
+		Details: fmt.Sprintf("HashJoin (%s) [%s]", strings.Join(on, ","), j.right.DrawString()),
+		Child:   child,
+	}
+}
+
+func (j *HashJoin) Name() string { return "HashJoin" }
+
+func (j *HashJoin) Children() []Operator { return nextOperators(j.next) }
+
+func (j *HashJoin) Stats() OperatorStats { return j.stats.Stats() }
+
+// collect adds a record of the build side to the hash table.
+func (j *HashJoin) collect(_ context.Context, r arrow.Record) error {
+	hashes, ok := joinHashes(r, j.rightKeys)
+	if !ok {
+		// Rows without join columns don't match any rows.
+		return nil
+	}
+
+	r.Retain()
+	j.records = append(j.records, r)
+	record := len(j.records) - 1
+	for row, hash := range hashes {
+		if hash.valid {
+			j.rows[hash.value] = append(j.rows[hash.value], joinRow{record: record, row: row})
+		}
+	}
+	return nil
+}
+
+func (j *HashJoin) Callback(ctx context.Context, r arrow.Record) error {
+	j.stats.Observe(r)
+	if !j.built {
+		j.built = true
+		if err := j.right.executeScan(ctx, j.rightPool); err != nil {
+			return fmt.Errorf("execute right input of join: %w", err)
+		}
+	}
+
+	hashes, ok := joinHashes(r, j.leftKeys)
+	if !ok {
+		return nil
+	}
+
+	// Joined rows are grouped by the record of the build side they match,
+	// since records of the build side can have different schemas.
+	matches := make(map[int][]joinedRow)
+	for row, hash := range hashes {
+		if !hash.valid {
+			continue
+		}
+		for _, match := range j.rows[hash.value] {
+			matches[match.record] = append(matches[match.record], joinedRow{left: row, right: match.row})
+		}
+	}
+
+	for record := range j.records {
+		rows, ok := matches[record]
+		if !ok {
+			continue
+		}
+		if err := j.emit(ctx, r, j.records[record], rows); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// emit passes the joined rows of left and right to the next plan.
+func (j *HashJoin) emit(ctx context.Context, left, right arrow.Record, rows []joinedRow) error {
+	fields := make([]arrow.Field, 0, left.NumCols()+right.NumCols())
+	names := make(map[string]struct{}, left.NumCols())
+	for _, field := range left.Schema().Fields() {
+		fields = append(fields, field)
+		names[field.Name] = struct{}{}
+	}
+	rightColumns := make([]int, 0, right.NumCols())
+	for i, field := range right.Schema().Fields() {
+		if _, ok := j.omitted[field.Name]; ok {
+			continue
+		}
+		if _, ok := names[field.Name]; ok {
+			return fmt.Errorf("join: column %q exists in both inputs", field.Name)
+		}
+		fields = append(fields, field)
+		rightColumns = append(rightColumns, i)
+	}
+
+	takeCtx := compute.WithAllocator(ctx, j.pool)
+	leftRows, rightRows := array.NewInt32Builder(j.pool), array.NewInt32Builder(j.pool)
+	defer leftRows.Release()
+	defer rightRows.Release()
+	for _, row := range rows {
+		leftRows.Append(int32(row.left))
+		rightRows.Append(int32(row.right))
+	}
+	leftIndices := leftRows.NewInt32Array()
+	defer leftIndices.Release()
+	rightIndices := rightRows.NewInt32Array()
+	defer rightIndices.Release()
+
+	leftTaken, err := arrowutils.Take(takeCtx, left, leftIndices)
+	if err != nil {
+		return fmt.Errorf("take joined rows: %w", err)
+	}
+	defer leftTaken.Release()
+	rightTaken, err := arrowutils.Take(takeCtx, right, rightIndices)
+	if err != nil {
+		return fmt.Errorf("take joined rows: %w", err)
+	}
+	defer rightTaken.Release()
+
+	columns := make([]arrow.Array, 0, len(fields))
+	columns = append(columns, leftTaken.Columns()...)
+	for _, i := range rightColumns {
+		columns = append(columns, rightTaken.Column(i))
+	}
+
+	joined := array.NewRecord(arrow.NewSchema(fields, nil), columns, int64(len(rows)))
+	defer joined.Release()
+	return j.next.Callback(ctx, joined)
+}
+
+type joinHash struct {
+	value uint64
+	// valid is unset if a join column of the row is null.
+	valid bool
+}
+
+// joinHashes returns the hashes of the join columns of each row of r. It
+// returns false if r is missing a join column.
+func joinHashes(r arrow.Record, keys []string) ([]joinHash, bool) {
+	hashes := make([]joinHash, r.NumRows())
+	for i := range hashes {
+		hashes[i].valid = true
+	}
+	for _, key := range keys {
+		indices := r.Schema().FieldIndices(key)
+		if len(indices) == 0 {
+			return nil, false
+		}
+		arr := r.Column(indices[0])
+		columnHashes := dynparquet.HashArray(arr)
+		for i := range hashes {
+			if arr.IsNull(i) {
+				hashes[i].valid = false
+				continue
+			}
+			hashes[i].value = hashCombine(hashes[i].value, columnHashes[i])
+		}
+	}
+	return hashes, true
+}
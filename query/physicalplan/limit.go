@@ -5,44 +5,84 @@ import (
 	"fmt"
 
 	"github.com/apache/arrow/go/v17/arrow"
-	"github.com/apache/arrow/go/v17/arrow/array"
 	"github.com/apache/arrow/go/v17/arrow/memory"
 	"github.com/apache/arrow/go/v17/arrow/scalar"
 	"go.opentelemetry.io/otel/trace"
 
-	"github.com/polarsignals/frostdb/pqarrow/arrowutils"
 	"github.com/polarsignals/frostdb/query/logicalplan"
 )
 
+// Limiter skips the first offset rows it receives and passes on at most count
+// of the remaining rows. Callbacks must not be called concurrently.
 type Limiter struct {
 	pool   memory.Allocator
 	tracer trace.Tracer
 	next   PhysicalPlan
+	stats  StatsCollector
+
+	// offset is the number of rows that remain to be skipped.
+	offset uint64
+	// count is the number of rows that remain to be passed on if limited is
+	// true.
+	count   uint64
+	limited bool
+	// done is called once count rows were passed on, if set.
+	done func()
+}
+
+// Limit returns a Limiter for the given limit and offset expressions, which
+// must be integer literals. A nil limit passes on all rows after the offset
+// and a nil offset skips no rows.
+func Limit(pool memory.Allocator, tracer trace.Tracer, expr, offset logicalplan.Expr) (*Limiter, error) {
+	var (
+		count   uint64
+		limited bool
+		skip    uint64
+		err     error
+	)
+	if expr != nil {
+		count, err = limitValue(expr)
+		if err != nil {
+			return nil, err
+		}
+		limited = true
+	}
+	if offset != nil {
+		skip, err = limitValue(offset)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return newLimiter(pool, tracer, count, limited, skip), nil
+}
 
-	count uint64
+func newLimiter(pool memory.Allocator, tracer trace.Tracer, count uint64, limited bool, offset uint64) *Limiter {
+	return &Limiter{
+		pool:    pool,
+		tracer:  tracer,
+		count:   count,
+		limited: limited,
+		offset:  offset,
+	}
 }
 
-func Limit(pool memory.Allocator, tracer trace.Tracer, expr logicalplan.Expr) (*Limiter, error) {
+func limitValue(expr logicalplan.Expr) (uint64, error) {
 	literal, ok := expr.(*logicalplan.LiteralExpr)
 	if !ok {
-		return nil, fmt.Errorf("expected literal expression, got %T", expr)
+		return 0, fmt.Errorf("expected literal expression, got %T", expr)
 	}
 
-	var count uint64
 	switch v := literal.Value.(type) {
 	case *scalar.Uint64:
-		count = v.Value
+		return v.Value, nil
 	case *scalar.Int64:
-		count = uint64(v.Value)
+		if v.Value < 0 {
+			return 0, fmt.Errorf("expected non-negative limit count, got %d", v.Value)
+		}
+		return uint64(v.Value), nil
 	default:
-		return nil, fmt.Errorf("expected limit count type, got %T", v)
+		return 0, fmt.Errorf("expected limit count type, got %T", v)
 	}
-
-	return &Limiter{
-		pool:   pool,
-		tracer: tracer,
-		count:  count,
-	}, nil
 }
 
 func (l *Limiter) SetNext(next PhysicalPlan) { l.next = next }
@@ -56,43 +96,65 @@ func (l *Limiter) Draw() *Diagram {
 	if l.next != nil {
 		child = l.next.Draw()
 	}
-	details := fmt.Sprintf("Limit(%d)", l.count)
+	details := "Limit"
+	if l.limited {
+		details += fmt.Sprintf("(%d)", l.count)
+	}
+	if l.offset > 0 {
+		details += fmt.Sprintf(" Offset(%d)", l.offset)
+	}
 	return &Diagram{Details: details, Child: child}
 }
 
+func (l *Limiter) Name() string { return "Limit" }
+
+func (l *Limiter) Children() []Operator { return nextOperators(l.next) }
+
+func (l *Limiter) Stats() OperatorStats { return l.stats.Stats() }
+
 func (l *Limiter) Callback(ctx context.Context, r arrow.Record) error {
+	l.stats.Observe(r)
 	if r.NumRows() == 0 {
 		return l.next.Callback(ctx, r)
 	}
-	if l.count == 0 {
-		newRecord := array.NewRecord(r.Schema(), nil, 0)
-		return l.next.Callback(ctx, newRecord)
+	if l.limited && l.count == 0 {
+		// The limit was reached, no more rows are needed.
+		if l.done != nil {
+			l.done()
+		}
+		return nil
 	}
 
-	if uint64(r.NumRows()) <= l.count {
-		return l.next.Callback(ctx, r)
+	start, end := int64(0), r.NumRows()
+	if l.offset > 0 {
+		if uint64(end) <= l.offset {
+			l.offset -= uint64(end)
+			return nil
+		}
+		start = int64(l.offset)
+		l.offset = 0
 	}
-
-	// TODO: We should figure out a way to avoid copying the record here.
-	// Maybe we can use a different approach to limit the record.
-
-	indicesBuilder := array.NewInt32Builder(l.pool)
-	defer indicesBuilder.Release()
-
-	for i := int32(0); i < int32(l.count); i++ {
-		indicesBuilder.Append(i)
+	if l.limited && uint64(end-start) > l.count {
+		end = start + int64(l.count)
 	}
-	indices := indicesBuilder.NewInt32Array()
-	defer indices.Release()
-
-	limitedRecord, err := arrowutils.Take(ctx, r, indices)
-	if err != nil {
-		return err
+	if l.limited {
+		l.count -= uint64(end - start)
 	}
 
-	if err := l.next.Callback(ctx, limitedRecord); err != nil {
-		return err
+	if start == 0 && end == r.NumRows() {
+		if err := l.next.Callback(ctx, r); err != nil {
+			return err
+		}
+	} else {
+		slice := r.NewSlice(start, end)
+		defer slice.Release()
+		if err := l.next.Callback(ctx, slice); err != nil {
+			return err
+		}
 	}
 
+	if l.limited && l.count == 0 && l.done != nil {
+		l.done()
+	}
 	return nil
 }
@@ -0,0 +1,104 @@
+package physicalplan
+
+import (
+	"context"
+	"testing"
+
+	"github.com/apache/arrow/go/v17/arrow"
+	"github.com/apache/arrow/go/v17/arrow/array"
+	"github.com/apache/arrow/go/v17/arrow/memory"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/trace/noop"
+
+	"github.com/polarsignals/frostdb/query/logicalplan"
+)
+
+func TestLimiter(t *testing.T) {
+	ctx := context.Background()
+	mem := memory.NewCheckedAllocator(memory.DefaultAllocator)
+	defer mem.AssertSize(t, 0)
+
+	newRecord := func(values ...int64) arrow.Record {
+		b := array.NewInt64Builder(mem)
+		defer b.Release()
+		b.AppendValues(values, nil)
+		arr := b.NewArray()
+		defer arr.Release()
+		return array.NewRecord(
+			arrow.NewSchema([]arrow.Field{{Name: "a", Type: arrow.PrimitiveTypes.Int64}}, nil),
+			[]arrow.Array{arr},
+			int64(len(values)),
+		)
+	}
+
+	for _, tc := range []struct {
+		name     string
+		limit    logicalplan.Expr
+		offset   logicalplan.Expr
+		expected []int64
+		done     bool
+	}{
+		{
+			name:     "Limit",
+			limit:    logicalplan.Literal(int64(4)),
+			expected: []int64{0, 1, 2, 3},
+			done:     true,
+		},
+		{
+			name:     "LimitOffset",
+			limit:    logicalplan.Literal(int64(3)),
+			offset:   logicalplan.Literal(int64(2)),
+			expected: []int64{2, 3, 4},
+			done:     true,
+		},
+		{
+			name:     "Offset",
+			offset:   logicalplan.Literal(uint64(5)),
+			expected: []int64{5, 6, 7},
+		},
+		{
+			name:     "OffsetPastEnd",
+			limit:    logicalplan.Literal(int64(3)),
+			offset:   logicalplan.Literal(int64(10)),
+			expected: nil,
+		},
+		{
+			name:     "Zero",
+			limit:    logicalplan.Literal(int64(0)),
+			expected: nil,
+			done:     true,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			l, err := Limit(mem, noop.NewTracerProvider().Tracer(""), tc.limit, tc.offset)
+			require.NoError(t, err)
+			done := false
+			l.done = func() { done = true }
+
+			var got []int64
+			l.SetNext(&OutputPlan{
+				callback: func(_ context.Context, r arrow.Record) error {
+					col := r.Column(0).(*array.Int64)
+					got = append(got, col.Int64Values()...)
+					return nil
+				},
+			})
+
+			for _, values := range [][]int64{{0, 1}, {2, 3, 4}, {5}, {6, 7}} {
+				r := newRecord(values...)
+				require.NoError(t, l.Callback(ctx, r))
+				r.Release()
+			}
+			require.NoError(t, l.Finish(ctx))
+			require.Equal(t, tc.expected, got)
+			require.Equal(t, tc.done, done)
+		})
+	}
+
+	t.Run("Invalid", func(t *testing.T) {
+		_, err := Limit(mem, noop.NewTracerProvider().Tracer(""), logicalplan.Col("a"), nil)
+		require.Error(t, err)
+		_, err = Limit(mem, noop.NewTracerProvider().Tracer(""), logicalplan.Literal(int64(-1)), nil)
+		require.Error(t, err)
+	})
+}
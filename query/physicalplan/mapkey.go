@@ -0,0 +1,117 @@
+package physicalplan
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/apache/arrow/go/v17/arrow"
+	"github.com/apache/arrow/go/v17/arrow/array"
+	"github.com/apache/arrow/go/v17/arrow/compute"
+	"github.com/apache/arrow/go/v17/arrow/memory"
+
+	"github.com/polarsignals/frostdb/query/logicalplan"
+)
+
+type mapKeyProjection struct {
+	expr *logicalplan.MapKeyExpr
+	p    columnProjection
+}
+
+func (p mapKeyProjection) Name() string {
+	return p.expr.Name()
+}
+
+func (p mapKeyProjection) String() string {
+	return p.expr.Name()
+}
+
+func (p mapKeyProjection) Project(mem memory.Allocator, ar arrow.Record) ([]arrow.Field, []arrow.Array, error) {
+	fields, cols, err := p.p.Project(mem, ar)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer func() {
+		for _, arr := range cols {
+			arr.Release()
+		}
+	}()
+
+	if len(fields) == 0 {
+		// The map column is not part of the record.
+		return nil, nil, nil
+	}
+	if len(fields) != 1 || len(fields) != len(cols) {
+		return nil, nil, fmt.Errorf("invalid projection for map key: expected 1 field and array, got %d fields %d arrays", len(fields), len(cols))
+	}
+
+	res, err := mapKeyValues(mem, cols[0], p.expr.Key)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return []arrow.Field{{
+		Name:     p.expr.Name(),
+		Type:     res.DataType(),
+		Nullable: true,
+		Metadata: fields[0].Metadata,
+	}}, []arrow.Array{res}, nil
+}
+
+// mapKeyValues returns the values of key in the maps of arr. The value of a
+// row is null if its map is null or does not contain the key. If a map
+// contains the key more than once, its first value is used.
+func mapKeyValues(mem memory.Allocator, arr arrow.Array, key string) (arrow.Array, error) {
+	m, ok := arr.(*array.Map)
+	if !ok {
+		return nil, fmt.Errorf("map key %q: unsupported type %s, expected map", key, arr.DataType())
+	}
+
+	matches, err := mapKeyMatcher(m.Keys(), key)
+	if err != nil {
+		return nil, err
+	}
+
+	indices := array.NewInt32Builder(mem)
+	defer indices.Release()
+	indices.Reserve(m.Len())
+	for i := 0; i < m.Len(); i++ {
+		found := false
+		if m.IsValid(i) {
+			start, end := m.ValueOffsets(i)
+			for j := int(start); j < int(end); j++ {
+				if matches(j) {
+					indices.Append(int32(j))
+					found = true
+					break
+				}
+			}
+		}
+		if !found {
+			indices.AppendNull()
+		}
+	}
+	idx := indices.NewInt32Array()
+	defer idx.Release()
+
+	// Null indices result in null values.
+	return compute.TakeArray(compute.WithAllocator(context.Background(), mem), m.Items(), idx)
+}
+
+// mapKeyMatcher returns a function that reports whether the key at an index of
+// the keys of a map is equal to key.
+func mapKeyMatcher(keys arrow.Array, key string) (func(i int) bool, error) {
+	switch k := keys.(type) {
+	case *array.String:
+		return func(i int) bool { return k.Value(i) == key }, nil
+	case *array.Binary:
+		return func(i int) bool { return string(k.Value(i)) == key }, nil
+	case *array.Dictionary:
+		dict, err := mapKeyMatcher(k.Dictionary(), key)
+		if err != nil {
+			return nil, err
+		}
+		return func(i int) bool { return dict(k.GetValueIndex(i)) }, nil
+	default:
+		return nil, fmt.Errorf("map key %q: unsupported key type %s, expected string", key, keys.DataType())
+	}
+}
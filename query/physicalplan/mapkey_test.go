@@ -0,0 +1,65 @@
+package physicalplan
+
+import (
+	"testing"
+
+	"github.com/apache/arrow/go/v17/arrow"
+	"github.com/apache/arrow/go/v17/arrow/array"
+	"github.com/apache/arrow/go/v17/arrow/memory"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMapKeyValues(t *testing.T) {
+	mem := memory.NewCheckedAllocator(memory.DefaultAllocator)
+	defer mem.AssertSize(t, 0)
+
+	b := array.NewMapBuilder(mem, arrow.BinaryTypes.String, arrow.BinaryTypes.String, false)
+	defer b.Release()
+	keys := b.KeyBuilder().(*array.StringBuilder)
+	items := b.ItemBuilder().(*array.StringBuilder)
+
+	// {job: api, instance: a}
+	b.Append(true)
+	keys.AppendValues([]string{"instance", "job"}, nil)
+	items.AppendValues([]string{"a", "api"}, nil)
+	// null
+	b.AppendNull()
+	// {instance: b}
+	b.Append(true)
+	keys.Append("instance")
+	items.Append("b")
+	// {job: null, job: db}
+	b.Append(true)
+	keys.AppendValues([]string{"job", "job"}, nil)
+	items.AppendNull()
+	items.Append("db")
+
+	arr := b.NewArray()
+	defer arr.Release()
+
+	res, err := mapKeyValues(mem, arr, "job")
+	require.NoError(t, err)
+	defer res.Release()
+
+	// The first value of a key is used, even if it is null.
+	values := res.(*array.String)
+	require.Equal(t, 4, values.Len())
+	require.Equal(t, "api", values.Value(0))
+	require.True(t, values.IsNull(1))
+	require.True(t, values.IsNull(2))
+	require.True(t, values.IsNull(3))
+
+	// A slice of a map array only flattens the maps of the slice.
+	slice := array.NewSlice(arr, 2, 4)
+	defer slice.Release()
+	res, err = mapKeyValues(mem, slice, "instance")
+	require.NoError(t, err)
+	defer res.Release()
+	values = res.(*array.String)
+	require.Equal(t, 2, values.Len())
+	require.Equal(t, "b", values.Value(0))
+	require.True(t, values.IsNull(1))
+
+	_, err = mapKeyValues(mem, arr.(*array.Map).Items(), "job")
+	require.Error(t, err)
+}
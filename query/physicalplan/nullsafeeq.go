@@ -0,0 +1,71 @@
+package physicalplan
+
+import (
+	"github.com/apache/arrow/go/v17/arrow"
+	"github.com/apache/arrow/go/v17/arrow/scalar"
+
+	"github.com/polarsignals/frostdb/query/logicalplan"
+)
+
+// NullSafeEqFilter matches the rows where the column is equal to the value, or
+// to the column on the right, with null being equal to null and not equal to
+// any value. A column missing from a record is null in every row.
+type NullSafeEqFilter struct {
+	left *ArrayRef
+	// Exactly one of right and rightColumn is set.
+	right       scalar.Scalar
+	rightColumn *ArrayRef
+}
+
+func (f *NullSafeEqFilter) Eval(r arrow.Record) (*Bitmap, error) {
+	leftData, leftExists, err := f.left.ArrowArray(r)
+	if err != nil {
+		return nil, err
+	}
+
+	if f.rightColumn == nil {
+		if !f.right.IsValid() {
+			return (&IsNullFilter{left: f.left}).Eval(r)
+		}
+		if !leftExists {
+			return NewBitmap(), nil
+		}
+		// Nulls never compare equal to the non-null value.
+		return BinaryScalarOperation(leftData, f.right, logicalplan.OpEq)
+	}
+
+	rightData, rightExists, err := f.rightColumn.ArrowArray(r)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case !leftExists && !rightExists:
+		res := NewBitmap()
+		res.AddRange(0, uint64(r.NumRows()))
+		return res, nil
+	case !leftExists:
+		return ArrayIsNull(rightData, false), nil
+	case !rightExists:
+		return ArrayIsNull(leftData, false), nil
+	}
+
+	res, err := BinaryArrayOperation(leftData, rightData, logicalplan.OpEq)
+	if err != nil {
+		return nil, err
+	}
+	if leftData.NullN() > 0 && rightData.NullN() > 0 {
+		for i := 0; i < leftData.Len(); i++ {
+			if leftData.IsNull(i) && rightData.IsNull(i) {
+				res.AddInt(i)
+			}
+		}
+	}
+	return res, nil
+}
+
+func (f *NullSafeEqFilter) String() string {
+	if f.rightColumn != nil {
+		return f.left.String() + " <=> " + f.rightColumn.String()
+	}
+	return f.left.String() + " <=> " + f.right.String()
+}
@@ -0,0 +1,87 @@
+package physicalplan
+
+import (
+	"sync/atomic"
+
+	"github.com/apache/arrow/go/v17/arrow"
+)
+
+// Operator is implemented by all operators of a physical plan, so that plans
+// can be inspected, e.g. to explain or trace them, without knowing the
+// concrete operators they are made of. Custom operators implementing
+// PhysicalPlan can be composed with the built-in operators, see
+// WithOverrideInput.
+type Operator interface {
+	// Name returns the name of the operator, e.g. "PredicateFilter".
+	Name() string
+	// Children returns the operators the operator passes its records to.
+	// Operators of concurrent streams are passed on to the same operator
+	// once the streams are synchronized.
+	Children() []Operator
+	// Stats returns the statistics of the operator so far. It is safe to
+	// call concurrently with the execution of the plan.
+	Stats() OperatorStats
+}
+
+// OperatorStats are the statistics of the execution of an operator.
+type OperatorStats struct {
+	// Records is the number of records passed to the operator.
+	Records int64
+	// Rows is the number of rows of the records passed to the operator.
+	Rows int64
+}
+
+// StatsCollector collects the OperatorStats of an operator. Operators call
+// Observe with each record they receive. The zero value is ready to use and
+// it is safe for concurrent use.
+type StatsCollector struct {
+	records atomic.Int64
+	rows    atomic.Int64
+}
+
+// Observe records that r was passed to the operator.
+func (c *StatsCollector) Observe(r arrow.Record) {
+	c.records.Add(1)
+	c.rows.Add(r.NumRows())
+}
+
+// Stats returns the statistics collected so far.
+func (c *StatsCollector) Stats() OperatorStats {
+	return OperatorStats{
+		Records: c.records.Load(),
+		Rows:    c.rows.Load(),
+	}
+}
+
+// Walk calls fn with op and all operators reachable through the children of
+// op, depth first. Each operator is visited once, even if it is the child of
+// multiple operators. If fn returns false the children of the operator are
+// not visited.
+func Walk(op Operator, fn func(Operator) bool) {
+	walk(op, fn, map[Operator]struct{}{})
+}
+
+func walk(op Operator, fn func(Operator) bool, visited map[Operator]struct{}) {
+	if op == nil {
+		return
+	}
+	if _, ok := visited[op]; ok {
+		return
+	}
+	visited[op] = struct{}{}
+	if !fn(op) {
+		return
+	}
+	for _, child := range op.Children() {
+		walk(child, fn, visited)
+	}
+}
+
+// nextOperators returns the children of an operator that passes its records
+// to next.
+func nextOperators(next PhysicalPlan) []Operator {
+	if next == nil {
+		return nil
+	}
+	return []Operator{next}
+}
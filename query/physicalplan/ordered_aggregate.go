@@ -48,6 +48,7 @@ type OrderedAggregate struct {
 	groupByColumnMatchers []logicalplan.Expr
 	aggregationFunction   logicalplan.AggFunc
 	next                  PhysicalPlan
+	stats                 StatsCollector
 	columnToAggregate     logicalplan.Expr
 	// Indicate is this is the last aggregation or if this is an aggregation
 	// with another aggregation to follow after synchronizing.
@@ -160,7 +161,14 @@ func (a *OrderedAggregate) Draw() *Diagram {
 	return &Diagram{Details: details, Child: child}
 }
 
+func (a *OrderedAggregate) Name() string { return "OrderedAggregate" }
+
+func (a *OrderedAggregate) Children() []Operator { return nextOperators(a.next) }
+
+func (a *OrderedAggregate) Stats() OperatorStats { return a.stats.Stats() }
+
 func (a *OrderedAggregate) Callback(_ context.Context, r arrow.Record) error {
+	a.stats.Observe(r)
 	// Generates high volume of spans. Comment out if needed during development.
 	// ctx, span := a.tracer.Start(ctx, "OrderedAggregate/Callback")
 	// defer span.End()
@@ -0,0 +1,299 @@
+package physicalplan
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"sync"
+
+	"github.com/apache/arrow/go/v17/arrow"
+	"github.com/apache/arrow/go/v17/arrow/array"
+	"github.com/apache/arrow/go/v17/arrow/memory"
+
+	"github.com/polarsignals/frostdb/pqarrow/arrowutils"
+)
+
+// TimeOrderedMerge merges the records of multiple concurrent inputs into a
+// single stream ordered by a time column in ascending order. The records of
+// each input must already be ordered by the time column, which must be an
+// Int64 or Timestamp column. Rows are passed on as soon as every input that
+// has not finished yet has sent a row with an equal or later time, so results
+// are emitted progressively rather than once all inputs have finished. Rows
+// with a null time are emitted last.
+type TimeOrderedMerge struct {
+	pool   memory.Allocator
+	column string
+	next   PhysicalPlan
+	stats  StatsCollector
+
+	mtx     sync.Mutex
+	inputs  []*timeOrderedMergeInput
+	running int
+	open    int
+}
+
+// NewTimeOrderedMerge returns a TimeOrderedMerge of the given number of
+// inputs ordered by the given time column. The inputs are obtained with
+// Input.
+func NewTimeOrderedMerge(pool memory.Allocator, inputs int, column string) *TimeOrderedMerge {
+	m := &TimeOrderedMerge{
+		pool:    pool,
+		column:  column,
+		inputs:  make([]*timeOrderedMergeInput, inputs),
+		running: inputs,
+		open:    inputs,
+	}
+	for i := range m.inputs {
+		m.inputs[i] = &timeOrderedMergeInput{merge: m}
+	}
+	return m
+}
+
+// Input returns the i-th input of the merge, which previous operators push
+// their records to.
+func (m *TimeOrderedMerge) Input(i int) PhysicalPlan {
+	return m.inputs[i]
+}
+
+func (m *TimeOrderedMerge) SetNext(next PhysicalPlan) {
+	m.next = next
+}
+
+func (m *TimeOrderedMerge) Draw() *Diagram {
+	return &Diagram{Details: fmt.Sprintf("TimeOrderedMerge(%s)", m.column), Child: m.next.Draw()}
+}
+
+func (m *TimeOrderedMerge) Name() string { return "TimeOrderedMerge" }
+
+func (m *TimeOrderedMerge) Children() []Operator { return nextOperators(m.next) }
+
+func (m *TimeOrderedMerge) Stats() OperatorStats { return m.stats.Stats() }
+
+func (m *TimeOrderedMerge) callback(ctx context.Context, in *timeOrderedMergeInput, r arrow.Record) error {
+	m.stats.Observe(r)
+	if r.NumRows() == 0 {
+		return nil
+	}
+	last, err := m.timeAt(r, int(r.NumRows())-1)
+	if err != nil {
+		return err
+	}
+
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+	r.Retain()
+	in.records = append(in.records, r)
+	in.last = last
+	in.hasLast = true
+	return m.emitLocked(ctx, false)
+}
+
+func (m *TimeOrderedMerge) finish(ctx context.Context, in *timeOrderedMergeInput) error {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+	if in.finished {
+		return errors.New("too many TimeOrderedMerge Finish calls")
+	}
+	in.finished = true
+	m.running--
+	if m.running > 0 {
+		// The finished input no longer holds back the other inputs.
+		return m.emitLocked(ctx, false)
+	}
+	if err := m.emitLocked(ctx, true); err != nil {
+		return err
+	}
+	return m.next.Finish(ctx)
+}
+
+func (m *TimeOrderedMerge) close() {
+	m.mtx.Lock()
+	m.open--
+	open := m.open
+	if open == 0 {
+		for _, in := range m.inputs {
+			in.release()
+		}
+	}
+	m.mtx.Unlock()
+
+	if open < 0 {
+		panic("too many TimeOrderedMerge Close calls")
+	}
+	if open == 0 {
+		m.next.Close()
+	}
+}
+
+// emitLocked merges and passes on all buffered rows that no input can
+// precede anymore. If all is true, all buffered rows are passed on. It must
+// be called while holding m.mtx.
+func (m *TimeOrderedMerge) emitLocked(ctx context.Context, all bool) error {
+	watermark := int64(math.MaxInt64)
+	if !all {
+		for _, in := range m.inputs {
+			if in.finished {
+				continue
+			}
+			if !in.hasLast {
+				// Any row of this input could precede the buffered rows.
+				return nil
+			}
+			watermark = min(watermark, in.last)
+		}
+	}
+
+	var records []arrow.Record
+	for _, in := range m.inputs {
+		taken, err := m.take(in, watermark, all)
+		if err != nil {
+			for _, r := range records {
+				r.Release()
+			}
+			return err
+		}
+		records = append(records, taken...)
+	}
+	if len(records) == 0 {
+		return nil
+	}
+
+	records, err := arrowutils.EnsureSameSchema(records)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		for _, r := range records {
+			r.Release()
+		}
+	}()
+
+	indices := records[0].Schema().FieldIndices(m.column)
+	if len(indices) != 1 {
+		// The time column is null for all rows, so there is no order to
+		// maintain.
+		for _, r := range records {
+			if err := m.next.Callback(ctx, r); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	merged, err := arrowutils.MergeRecords(m.pool, records, []arrowutils.SortingColumn{{Index: indices[0]}}, 0)
+	if err != nil {
+		return err
+	}
+	defer merged.Release()
+	return m.next.Callback(ctx, merged)
+}
+
+// take removes the buffered rows of the given input with a time up to and
+// including the watermark and returns them. If all is true, all buffered rows
+// are returned.
+func (m *TimeOrderedMerge) take(in *timeOrderedMergeInput, watermark int64, all bool) ([]arrow.Record, error) {
+	if all {
+		taken := in.records
+		in.records = nil
+		return taken, nil
+	}
+
+	var taken []arrow.Record
+	for len(in.records) > 0 {
+		r := in.records[0]
+		numRows := int(r.NumRows())
+		var searchErr error
+		n := sort.Search(numRows, func(i int) bool {
+			t, err := m.timeAt(r, i)
+			if err != nil {
+				searchErr = err
+				return true
+			}
+			return t > watermark
+		})
+		if searchErr != nil {
+			return taken, searchErr
+		}
+		if n == numRows {
+			taken = append(taken, r)
+			in.records = in.records[1:]
+			continue
+		}
+		if n > 0 {
+			taken = append(taken, r.NewSlice(0, int64(n)))
+			in.records[0] = r.NewSlice(int64(n), int64(numRows))
+			r.Release()
+		}
+		break
+	}
+	return taken, nil
+}
+
+// timeAt returns the time of the i-th row of r. Rows with a null time, or
+// records without the time column, are treated as the latest possible time.
+func (m *TimeOrderedMerge) timeAt(r arrow.Record, i int) (int64, error) {
+	indices := r.Schema().FieldIndices(m.column)
+	if len(indices) != 1 {
+		return math.MaxInt64, nil
+	}
+	col := r.Column(indices[0])
+	if col.IsNull(i) {
+		return math.MaxInt64, nil
+	}
+	switch arr := col.(type) {
+	case *array.Int64:
+		return arr.Value(i), nil
+	case *array.Timestamp:
+		return int64(arr.Value(i)), nil
+	default:
+		return 0, fmt.Errorf("unsupported time column type %s for column %s", col.DataType(), m.column)
+	}
+}
+
+// timeOrderedMergeInput is one of the inputs of a TimeOrderedMerge. It
+// buffers the rows of the input that cannot be passed on yet.
+type timeOrderedMergeInput struct {
+	merge *TimeOrderedMerge
+	stats StatsCollector
+
+	// The following fields are protected by merge.mtx.
+	records  []arrow.Record
+	last     int64
+	hasLast  bool
+	finished bool
+}
+
+func (in *timeOrderedMergeInput) Callback(ctx context.Context, r arrow.Record) error {
+	in.stats.Observe(r)
+	return in.merge.callback(ctx, in, r)
+}
+
+func (in *timeOrderedMergeInput) Finish(ctx context.Context) error {
+	return in.merge.finish(ctx, in)
+}
+
+func (in *timeOrderedMergeInput) SetNext(_ PhysicalPlan) {
+	panic("bug in builder! the next plan of a TimeOrderedMerge input is the merge itself")
+}
+
+func (in *timeOrderedMergeInput) Draw() *Diagram {
+	return in.merge.Draw()
+}
+
+func (in *timeOrderedMergeInput) Name() string { return "TimeOrderedMergeInput" }
+
+func (in *timeOrderedMergeInput) Children() []Operator { return []Operator{in.merge} }
+
+func (in *timeOrderedMergeInput) Stats() OperatorStats { return in.stats.Stats() }
+
+func (in *timeOrderedMergeInput) Close() {
+	in.merge.close()
+}
+
+func (in *timeOrderedMergeInput) release() {
+	for _, r := range in.records {
+		r.Release()
+	}
+	in.records = nil
+}
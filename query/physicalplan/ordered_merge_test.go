@@ -0,0 +1,63 @@
+package physicalplan
+
+import (
+	"context"
+	"testing"
+
+	"github.com/apache/arrow/go/v17/arrow"
+	"github.com/apache/arrow/go/v17/arrow/array"
+	"github.com/apache/arrow/go/v17/arrow/memory"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTimeOrderedMerge(t *testing.T) {
+	const (
+		inputs    = 4
+		batches   = 50
+		batchSize = 10
+		column    = "timestamp"
+	)
+	mem := memory.NewCheckedAllocator(memory.NewGoAllocator())
+	defer mem.AssertSize(t, 0)
+
+	merge := NewTimeOrderedMerge(mem, inputs, column)
+	var timestamps []int64
+	merge.SetNext(&OutputPlan{
+		callback: func(_ context.Context, r arrow.Record) error {
+			arr := r.Column(r.Schema().FieldIndices(column)[0]).(*array.Int64)
+			timestamps = append(timestamps, arr.Int64Values()...)
+			return nil
+		},
+	})
+
+	ctx := context.Background()
+	schema := arrow.NewSchema([]arrow.Field{{Name: column, Type: arrow.PrimitiveTypes.Int64}}, nil)
+	for j := 0; j < batches; j++ {
+		for i := 0; i < inputs; i++ {
+			b := array.NewInt64Builder(mem)
+			for k := 0; k < batchSize; k++ {
+				// Interleave the timestamps of all inputs.
+				b.Append(int64((j*batchSize+k)*inputs + i))
+			}
+			arr := b.NewArray()
+			b.Release()
+			r := array.NewRecord(schema, []arrow.Array{arr}, int64(arr.Len()))
+			arr.Release()
+			require.NoError(t, merge.Input(i).Callback(ctx, r))
+			r.Release()
+		}
+	}
+	// Results are emitted before the inputs finish.
+	require.Greater(t, len(timestamps), 0)
+	for i := 0; i < inputs; i++ {
+		require.NoError(t, merge.Input(i).Finish(ctx))
+	}
+	for i := 0; i < inputs; i++ {
+		merge.Input(i).Close()
+	}
+
+	require.Len(t, timestamps, inputs*batches*batchSize)
+	for i, ts := range timestamps {
+		require.Equal(t, int64(i), ts)
+	}
+}
@@ -38,8 +38,9 @@ type OrderedSynchronizer struct {
 		// that have not called Finish yet.
 		inputsRunning int
 	}
-	wait chan struct{}
-	next PhysicalPlan
+	wait  chan struct{}
+	next  PhysicalPlan
+	stats StatsCollector
 }
 
 func NewOrderedSynchronizer(pool memory.Allocator, inputs int, orderByExprs []logicalplan.Expr) *OrderedSynchronizer {
@@ -57,6 +58,7 @@ func (o *OrderedSynchronizer) Close() {
 }
 
 func (o *OrderedSynchronizer) Callback(ctx context.Context, r arrow.Record) error {
+	o.stats.Observe(r)
 	o.sync.mtx.Lock()
 	o.sync.data = append(o.sync.data, r)
 	o.sync.inputsWaiting++
@@ -247,3 +249,9 @@ func (o *OrderedSynchronizer) SetNext(next PhysicalPlan) {
 func (o *OrderedSynchronizer) Draw() *Diagram {
 	return &Diagram{Details: "OrderedSynchronizer", Child: o.next.Draw()}
 }
+
+func (o *OrderedSynchronizer) Name() string { return "OrderedSynchronizer" }
+
+func (o *OrderedSynchronizer) Children() []Operator { return nextOperators(o.next) }
+
+func (o *OrderedSynchronizer) Stats() OperatorStats { return o.stats.Stats() }
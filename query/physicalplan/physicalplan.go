@@ -5,6 +5,8 @@ import (
 	"fmt"
 	"hash/maphash"
 	"runtime"
+	"slices"
+	"sync"
 
 	"github.com/apache/arrow/go/v17/arrow"
 	"github.com/apache/arrow/go/v17/arrow/memory"
@@ -21,7 +23,11 @@ import (
 // TODO: Make this smarter.
 var concurrencyHardcoded = runtime.GOMAXPROCS(0)
 
+// PhysicalPlan is an operator that records are pushed to. Once all records
+// were pushed, Finish is called, and Close releases the resources of the
+// operator and the operators it passes its records to.
 type PhysicalPlan interface {
+	Operator
 	Callback(ctx context.Context, r arrow.Record) error
 	Finish(ctx context.Context) error
 	SetNext(next PhysicalPlan)
@@ -29,7 +35,11 @@ type PhysicalPlan interface {
 	Close()
 }
 
+// ScanPhysicalPlan is the operator at the root of a plan that reads the
+// records passed to the other operators. Its children are the operators it
+// passes the records to.
 type ScanPhysicalPlan interface {
+	Operator
 	Execute(ctx context.Context, pool memory.Allocator) error
 	Draw() *Diagram
 }
@@ -57,6 +67,10 @@ func (f PostPlanVisitorFunc) PostVisit(plan *logicalplan.LogicalPlan) bool {
 type OutputPlan struct {
 	callback func(ctx context.Context, r arrow.Record) error
 	scan     ScanPhysicalPlan
+	stats    StatsCollector
+
+	// allocations is set if allocations are tracked.
+	allocations *allocationTracker
 }
 
 func (e *OutputPlan) Draw() *Diagram {
@@ -64,11 +78,24 @@ func (e *OutputPlan) Draw() *Diagram {
 	return &Diagram{}
 }
 
+func (e *OutputPlan) Name() string { return "Output" }
+
+func (e *OutputPlan) Children() []Operator { return nil }
+
+func (e *OutputPlan) Stats() OperatorStats { return e.stats.Stats() }
+
 func (e *OutputPlan) DrawString() string {
 	return e.scan.Draw().String()
 }
 
+// Walk calls fn with the operators of the plan, starting at the scan, as
+// described by the package-level Walk.
+func (e *OutputPlan) Walk(fn func(Operator) bool) {
+	Walk(e.scan, fn)
+}
+
 func (e *OutputPlan) Callback(ctx context.Context, r arrow.Record) error {
+	e.stats.Observe(r)
 	return e.callback(ctx, r)
 }
 
@@ -90,13 +117,53 @@ func (e *OutputPlan) SetNext(_ PhysicalPlan) {
 
 func (e *OutputPlan) Execute(ctx context.Context, pool memory.Allocator, callback func(ctx context.Context, r arrow.Record) error) error {
 	e.callback = callback
-	return e.scan.Execute(ctx, pool)
+	if err := e.executeScan(ctx, pool); err != nil {
+		return err
+	}
+	if e.allocations == nil {
+		return nil
+	}
+	// All operators have been closed once the scan returns, so any memory
+	// that is still allocated has been leaked.
+	return e.allocations.leaks()
+}
+
+// executeScan executes the scan of the plan, passing the results to the
+// callback of the plan.
+func (e *OutputPlan) executeScan(ctx context.Context, pool memory.Allocator) error {
+	return e.scan.Execute(ctx, e.allocations.allocator(pool, e.scan.Name()))
 }
 
 type TableScan struct {
 	tracer  trace.Tracer
 	options *logicalplan.TableScan
 	plans   []PhysicalPlan
+	// stats are the statistics of the records read by the scan.
+	stats StatsCollector
+
+	// mtx protects stopped and cancel. The scan is stopped once subsequent
+	// operators need no more records, in which case the table iteration is
+	// canceled.
+	mtx     sync.Mutex
+	stopped bool
+	cancel  context.CancelFunc
+}
+
+// stop stops the table scan early. It is safe to call concurrently and
+// multiple times.
+func (s *TableScan) stop() {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	s.stopped = true
+	if s.cancel != nil {
+		s.cancel()
+	}
+}
+
+func (s *TableScan) isStopped() bool {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	return s.stopped
 }
 
 func (s *TableScan) Draw() *Diagram {
@@ -111,6 +178,12 @@ func (s *TableScan) Draw() *Diagram {
 	return &Diagram{Details: details, Child: child}
 }
 
+func (s *TableScan) Name() string { return "TableScan" }
+
+func (s *TableScan) Children() []Operator { return planOperators(s.plans) }
+
+func (s *TableScan) Stats() OperatorStats { return s.stats.Stats() }
+
 func (s *TableScan) Execute(ctx context.Context, pool memory.Allocator) error {
 	ctx, span := s.tracer.Start(ctx, "TableScan/Execute")
 	defer span.End()
@@ -122,7 +195,7 @@ func (s *TableScan) Execute(ctx context.Context, pool memory.Allocator) error {
 
 	callbacks := make([]logicalplan.Callback, 0, len(s.plans))
 	for _, plan := range s.plans {
-		callbacks = append(callbacks, plan.Callback)
+		callbacks = append(callbacks, observeCallback(&s.stats, plan.Callback))
 	}
 	defer func() { // Close all plans to ensure memory cleanup.
 		for _, plan := range s.plans {
@@ -135,12 +208,30 @@ func (s *TableScan) Execute(ctx context.Context, pool memory.Allocator) error {
 		logicalplan.WithProjection(s.options.Projection...),
 		logicalplan.WithFilter(s.options.Filter),
 		logicalplan.WithDistinctColumns(s.options.Distinct...),
+		logicalplan.WithGroupBy(s.options.GroupBy...),
 		logicalplan.WithReadMode(s.options.ReadMode),
 	}
+	if s.options.Provenance {
+		opts = append(opts, logicalplan.WithProvenance())
+	}
+	if s.options.DictionaryEncode {
+		opts = append(opts, logicalplan.WithDictionaryEncoding())
+	}
 
-	errg, _ := errgroup.WithContext(ctx)
+	// The iteration is canceled if the scan is stopped early, e.g. because
+	// a limit was reached.
+	iterCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	s.mtx.Lock()
+	s.cancel = cancel
+	if s.stopped {
+		cancel()
+	}
+	s.mtx.Unlock()
+
+	errg, _ := errgroup.WithContext(iterCtx)
 	errg.Go(recovery.Do(func() error {
-		return table.View(ctx, func(ctx context.Context, tx uint64) error {
+		return table.View(iterCtx, func(ctx context.Context, tx uint64) error {
 			return table.Iterator(
 				ctx,
 				tx,
@@ -150,7 +241,7 @@ func (s *TableScan) Execute(ctx context.Context, pool memory.Allocator) error {
 			)
 		})
 	}))
-	if err := errg.Wait(); err != nil {
+	if err := errg.Wait(); err != nil && (!s.isStopped() || ctx.Err() != nil) {
 		return err
 	}
 
@@ -169,6 +260,8 @@ type SchemaScan struct {
 	tracer  trace.Tracer
 	options *logicalplan.SchemaScan
 	plans   []PhysicalPlan
+	// stats are the statistics of the records read by the scan.
+	stats StatsCollector
 }
 
 func (s *SchemaScan) Draw() *Diagram {
@@ -183,6 +276,12 @@ func (s *SchemaScan) Draw() *Diagram {
 	return &Diagram{Details: details, Child: child}
 }
 
+func (s *SchemaScan) Name() string { return "SchemaScan" }
+
+func (s *SchemaScan) Children() []Operator { return planOperators(s.plans) }
+
+func (s *SchemaScan) Stats() OperatorStats { return s.stats.Stats() }
+
 func (s *SchemaScan) Execute(ctx context.Context, pool memory.Allocator) error {
 	table, err := s.options.TableProvider.GetTable(s.options.TableName)
 	if table == nil || err != nil {
@@ -191,7 +290,7 @@ func (s *SchemaScan) Execute(ctx context.Context, pool memory.Allocator) error {
 
 	callbacks := make([]logicalplan.Callback, 0, len(s.plans))
 	for _, plan := range s.plans {
-		callbacks = append(callbacks, plan.Callback)
+		callbacks = append(callbacks, observeCallback(&s.stats, plan.Callback))
 	}
 
 	opts := []logicalplan.Option{
@@ -229,8 +328,27 @@ func (s *SchemaScan) Execute(ctx context.Context, pool memory.Allocator) error {
 	return errg.Wait()
 }
 
+// planOperators returns the operators of plans.
+func planOperators(plans []PhysicalPlan) []Operator {
+	ops := make([]Operator, 0, len(plans))
+	for _, plan := range plans {
+		ops = append(ops, plan)
+	}
+	return ops
+}
+
+// observeCallback returns a callback that observes the records passed to
+// callback with stats.
+func observeCallback(stats *StatsCollector, callback logicalplan.Callback) logicalplan.Callback {
+	return func(ctx context.Context, r arrow.Record) error {
+		stats.Observe(r)
+		return callback(ctx, r)
+	}
+}
+
 type noopOperator struct {
-	next PhysicalPlan
+	next  PhysicalPlan
+	stats StatsCollector
 }
 
 func (p *noopOperator) Close() {
@@ -238,6 +356,7 @@ func (p *noopOperator) Close() {
 }
 
 func (p *noopOperator) Callback(ctx context.Context, r arrow.Record) error {
+	p.stats.Observe(r)
 	return p.next.Callback(ctx, r)
 }
 
@@ -256,10 +375,28 @@ func (p *noopOperator) Draw() *Diagram {
 	return p.next.Draw()
 }
 
+func (p *noopOperator) Name() string { return "Noop" }
+
+func (p *noopOperator) Children() []Operator { return nextOperators(p.next) }
+
+func (p *noopOperator) Stats() OperatorStats { return p.stats.Stats() }
+
 type execOptions struct {
 	orderedAggregations bool
 	overrideInput       []PhysicalPlan
 	readMode            logicalplan.ReadMode
+	provenance          bool
+	dictionaryEncode    bool
+	allocationTracking  bool
+	sortMemoryLimit     int64
+	sortSpillDir        string
+	distinctMemoryLimit int64
+	distinctSpillDir    string
+	timeOrderedColumn   string
+
+	// allocations is the tracker of the plan that the plan being built is
+	// part of, if any.
+	allocations *allocationTracker
 }
 
 type Option func(o *execOptions)
@@ -270,12 +407,86 @@ func WithReadMode(m logicalplan.ReadMode) Option {
 	}
 }
 
+// ReadModeFromOptions returns the read mode configured by the given options.
+func ReadModeFromOptions(options ...Option) logicalplan.ReadMode {
+	o := &execOptions{}
+	for _, opt := range options {
+		opt(o)
+	}
+	return o.readMode
+}
+
+// WithProvenance annotates the records produced by table scans with metadata
+// describing where their rows were read from (e.g. the block and transaction).
+// The metadata is kept by operators that pass rows through, such as filters
+// and projections, but not by operators that combine rows, such as
+// aggregations.
+func WithProvenance() Option {
+	return func(o *execOptions) {
+		o.provenance = true
+	}
+}
+
+// WithDictionaryEncoding makes table scans produce dictionary encoded columns
+// for binary columns, and lists of them, even if they are not dictionary
+// encoded in storage. Operators keep the encoding, e.g. aggregations produce
+// dictionary encoded group by columns, which reduces the memory used by
+// queries over columns with few distinct values, such as labels.
+func WithDictionaryEncoding() Option {
+	return func(o *execOptions) {
+		o.dictionaryEncode = true
+	}
+}
+
 func WithOrderedAggregations() Option {
 	return func(o *execOptions) {
 		o.orderedAggregations = true
 	}
 }
 
+// WithSortSpill configures sorts to spill sorted runs to files in dir once
+// more than memoryLimit bytes are buffered. If dir is empty, the default
+// directory for temporary files is used. A memoryLimit <= 0 disables spilling.
+// The default memory limit is DefaultSortMemoryLimit.
+func WithSortSpill(memoryLimit int64, dir string) Option {
+	return func(o *execOptions) {
+		o.sortMemoryLimit = memoryLimit
+		o.sortSpillDir = dir
+	}
+}
+
+// WithDistinctSpill configures distincts to spill the rows they have not
+// emitted yet to files in dir once tracking the rows they have seen uses more
+// than approximately memoryLimit bytes. If dir is empty, the default
+// directory for temporary files is used. A memoryLimit <= 0 disables spilling.
+// The default memory limit is DefaultDistinctMemoryLimit.
+func WithDistinctSpill(memoryLimit int64, dir string) Option {
+	return func(o *execOptions) {
+		o.distinctMemoryLimit = memoryLimit
+		o.distinctSpillDir = dir
+	}
+}
+
+// WithTimeOrderedResults makes queries pass their results on in ascending
+// order of the given time column, which must be an Int64 or Timestamp column.
+// The results of each concurrent stream are sorted and the sorted streams are
+// merged, so results are emitted progressively in time order instead of in
+// the order in which the scanned blocks complete. Queries that order their
+// results with an OrderBy are not affected.
+func WithTimeOrderedResults(column string) Option {
+	return func(o *execOptions) {
+		o.timeOrderedColumn = column
+	}
+}
+
+// withAllocations makes the plan being built track its allocations with the
+// given tracker instead of a tracker of its own.
+func withAllocations(tracker *allocationTracker) Option {
+	return func(o *execOptions) {
+		o.allocations = tracker
+	}
+}
+
 // WithOverrideInput can be used to provide an input stage on top of which the
 // Build function can build the physical plan.
 func WithOverrideInput(input []PhysicalPlan) Option {
@@ -295,13 +506,22 @@ func Build(
 	_, span := tracer.Start(ctx, "PhysicalPlan/Build")
 	defer span.End()
 
-	execOpts := execOptions{}
+	execOpts := execOptions{
+		sortMemoryLimit:     DefaultSortMemoryLimit,
+		distinctMemoryLimit: DefaultDistinctMemoryLimit,
+	}
 	for _, o := range options {
 		o(&execOpts)
 	}
 	prev := execOpts.overrideInput
 
 	outputPlan := &OutputPlan{}
+	if execOpts.allocations != nil {
+		outputPlan.allocations = execOpts.allocations
+	} else if execOpts.allocationTracking {
+		outputPlan.allocations = newAllocationTracker()
+	}
+	tracker := outputPlan.allocations
 	oInfo := &planOrderingInfo{
 		state: planOrderingInfoStateInit,
 	}
@@ -311,7 +531,10 @@ func Build(
 		oInfo.sortingCols = s.ColumnDefinitionsForSortingColumns()
 	}
 
-	var visitErr error
+	var (
+		visitErr error
+		orderBy  bool
+	)
 	plan.Accept(PostPlanVisitorFunc(func(plan *logicalplan.LogicalPlan) bool {
 		oInfo.newNode()
 		switch {
@@ -339,6 +562,8 @@ func Build(
 				plans[i] = &noopOperator{}
 			}
 			plan.TableScan.ReadMode = execOpts.readMode
+			plan.TableScan.Provenance = execOpts.provenance
+			plan.TableScan.DictionaryEncode = execOpts.dictionaryEncode
 			outputPlan.scan = &TableScan{
 				tracer:  tracer,
 				options: plan.TableScan,
@@ -354,7 +579,7 @@ func Build(
 			}
 			// For each previous physical plan create one Projection
 			for i := range prev {
-				p, err := Project(pool, tracer, plan.Projection.Exprs)
+				p, err := Project(tracker.allocator(pool, "Projection"), tracer, plan.Projection.Exprs)
 				if err != nil {
 					visitErr = err
 					return false
@@ -369,7 +594,7 @@ func Build(
 				sync = Synchronize(len(prev))
 			}
 			for i := 0; i < len(prev); i++ {
-				d := Distinct(pool, tracer, plan.Distinct.Exprs)
+				d := Distinct(tracker.allocator(pool, "Distinct"), tracer, plan.Distinct.Exprs, execOpts.distinctMemoryLimit, execOpts.distinctSpillDir)
 				prev[i].SetNext(d)
 				prev[i] = d
 				if sync != nil {
@@ -379,47 +604,59 @@ func Build(
 			if sync != nil {
 				// Plan a distinct operator to run a distinct on all the
 				// synchronized distincts.
-				d := Distinct(pool, tracer, plan.Distinct.Exprs)
+				d := Distinct(tracker.allocator(pool, "Distinct"), tracer, plan.Distinct.Exprs, execOpts.distinctMemoryLimit, execOpts.distinctSpillDir)
 				sync.SetNext(d)
 				prev = prev[0:1]
 				prev[0] = d
 			}
 		case plan.Limit != nil:
-			var sync *Synchronizer
-			if len(prev) > 1 {
-				// These limit operators need to be synchronized.
-				sync = Synchronize(len(prev))
+			limit, err := Limit(tracker.allocator(pool, "Limit"), tracer, plan.Limit.Expr, plan.Limit.Offset)
+			if err != nil {
+				visitErr = err
+				return false
 			}
-			for i := 0; i < len(prev); i++ {
-				d, err := Limit(pool, tracer, plan.Limit.Expr)
-				if err != nil {
-					visitErr = err
-					return false
-				}
-				prev[i].SetNext(d)
-				prev[i] = d
-				if sync != nil {
-					d.SetNext(sync)
-				}
+			if scan, ok := outputPlan.scan.(*TableScan); ok {
+				// No more records need to be scanned once the limit is
+				// reached.
+				limit.done = scan.stop
 			}
-			if sync != nil {
-				// Plan a limit operator to run a limit on all the
-				// synchronized limits.
-				d, err := Limit(pool, tracer, plan.Limit.Expr)
-				if err != nil {
-					visitErr = err
-					return false
+			if len(prev) > 1 {
+				// These limit operators need to be synchronized. Each
+				// concurrent limiter passes on at most the rows needed by
+				// the synchronized limiter, which applies the offset.
+				sync := Synchronize(len(prev))
+				for i := 0; i < len(prev); i++ {
+					if limit.limited {
+						d := newLimiter(tracker.allocator(pool, "Limit"), tracer, limit.offset+limit.count, true, 0)
+						prev[i].SetNext(d)
+						prev[i] = d
+					}
+					prev[i].SetNext(sync)
 				}
-				sync.SetNext(d)
 				prev = prev[0:1]
-				prev[0] = d
+				prev[0] = sync
 			}
+			prev[0].SetNext(limit)
+			prev[0] = limit
+		case plan.OrderBy != nil:
+			orderBy = true
+			// All records need to be sorted by a single sorter.
+			if len(prev) > 1 {
+				sync := Synchronize(len(prev))
+				for i := range prev {
+					prev[i].SetNext(sync)
+				}
+				prev = append(prev[:0], sync)
+			}
+			s := Sort(tracker.allocator(pool, "OrderBy"), tracer, plan.OrderBy.Exprs, execOpts.sortMemoryLimit, execOpts.sortSpillDir)
+			prev[0].SetNext(s)
+			prev[0] = s
 		case plan.Filter != nil:
 			// Create a filter for each previous plan.
 			// Can be multiple filters or just a single
 			// filter depending on the previous concurrency.
 			for i := range prev {
-				f, err := Filter(pool, tracer, plan.Filter.Expr)
+				f, err := Filter(tracker.allocator(pool, "Filter"), tracer, plan.Filter.Expr)
 				if err != nil {
 					visitErr = err
 					return false
@@ -439,14 +676,25 @@ func Build(
 			if len(prev) > 1 {
 				// These aggregate operators need to be synchronized.
 				if ordered && len(plan.Aggregation.GroupExprs) > 0 {
-					sync = NewOrderedSynchronizer(pool, len(prev), plan.Aggregation.GroupExprs)
+					sync = NewOrderedSynchronizer(tracker.allocator(pool, "OrderedSynchronizer"), len(prev), plan.Aggregation.GroupExprs)
 				} else {
 					sync = Synchronize(len(prev))
 				}
 			}
 			seed := maphash.MakeSeed()
 			for i := 0; i < len(prev); i++ {
-				a, err := Aggregate(pool, tracer, plan.Aggregation, sync == nil, ordered, seed)
+				// Rows are assigned to their windows before the first
+				// aggregation, which groups by the window start.
+				if window := windowGroupExpr(plan.Aggregation); window != nil {
+					w, err := NewWindow(tracker.allocator(pool, "Window"), tracer, window)
+					if err != nil {
+						visitErr = err
+						return false
+					}
+					prev[i].SetNext(w)
+					prev[i] = w
+				}
+				a, err := Aggregate(tracker.allocator(pool, "Aggregation"), tracer, plan.Aggregation, false, ordered, seed)
 				if err != nil {
 					visitErr = err
 					return false
@@ -457,21 +705,62 @@ func Build(
 					a.SetNext(sync)
 				}
 			}
+			// Plan an aggregate operator to run an aggregation on all the
+			// aggregations. The final stage aggregates the results of the
+			// first stage, so it is planned even if there is a single
+			// stream.
+			a, err := Aggregate(tracker.allocator(pool, "Aggregation"), tracer, plan.Aggregation, true, ordered, seed)
+			if err != nil {
+				visitErr = err
+				return false
+			}
 			if sync != nil {
-				// Plan an aggregate operator to run an aggregation on all the
-				// aggregations.
-				a, err := Aggregate(pool, tracer, plan.Aggregation, true, ordered, seed)
-				if err != nil {
-					visitErr = err
-					return false
-				}
 				sync.SetNext(a)
-				prev = prev[0:1]
-				prev[0] = a
+			} else {
+				prev[0].SetNext(a)
 			}
+			prev = prev[0:1]
+			prev[0] = a
 			if ordered {
 				oInfo.nodeMaintainsOrdering()
 			}
+		case plan.Join != nil:
+			// The right input is built as a separate plan that shares the
+			// allocation tracker of this plan, since the join retains its
+			// records until the join is closed.
+			right, err := Build(
+				ctx,
+				pool,
+				tracer,
+				plan.Join.Right.InputSchema(),
+				plan.Join.Right,
+				append(
+					slices.Clone(options),
+					WithOverrideInput(nil),
+					withAllocations(tracker),
+					// Only the output of the join needs to be ordered.
+					WithTimeOrderedResults(""),
+				)...,
+			)
+			if err != nil {
+				visitErr = err
+				return false
+			}
+			// All records are joined by a single join.
+			if len(prev) > 1 {
+				sync := Synchronize(len(prev))
+				for i := range prev {
+					prev[i].SetNext(sync)
+				}
+				prev = append(prev[:0], sync)
+			}
+			j, err := NewHashJoin(tracker.allocator(pool, "Join"), tracer, right, pool, plan.Join)
+			if err != nil {
+				visitErr = err
+				return false
+			}
+			prev[0].SetNext(j)
+			prev[0] = j
 		case plan.Sample != nil:
 			v := plan.Sample.Expr.(*logicalplan.LiteralExpr).Value.(*scalar.Int64).Value
 			limit := plan.Sample.Limit.(*logicalplan.LiteralExpr).Value.(*scalar.Int64).Value
@@ -483,7 +772,7 @@ func Build(
 				if i < int(r) {
 					adjust = 1
 				}
-				s := NewReservoirSampler(perSampler+adjust, perSamplerLimit, pool)
+				s := NewReservoirSampler(perSampler+adjust, perSamplerLimit, tracker.allocator(pool, "Sample"))
 				prev[i].SetNext(s)
 				prev[i] = s
 			}
@@ -500,6 +789,29 @@ func Build(
 		span.SetAttributes(attribute.String("plan", outputPlan.scan.Draw().String()))
 	}
 
+	if execOpts.timeOrderedColumn != "" && !orderBy {
+		// Sort each stream by time and merge the sorted streams.
+		for i := range prev {
+			s := Sort(
+				tracker.allocator(pool, "TimeOrder"),
+				tracer,
+				[]logicalplan.Expr{logicalplan.Col(execOpts.timeOrderedColumn)},
+				execOpts.sortMemoryLimit,
+				execOpts.sortSpillDir,
+			)
+			prev[i].SetNext(s)
+			prev[i] = s
+		}
+		if len(prev) > 1 {
+			merge := NewTimeOrderedMerge(tracker.allocator(pool, "TimeOrderedMerge"), len(prev), execOpts.timeOrderedColumn)
+			for i := range prev {
+				prev[i].SetNext(merge.Input(i))
+			}
+			merge.SetNext(outputPlan)
+			return outputPlan, nil
+		}
+	}
+
 	// Synchronize the last stage if necessary.
 	var sync *Synchronizer
 	if len(prev) > 1 {
@@ -515,6 +827,17 @@ func Build(
 	return outputPlan, nil
 }
 
+// windowGroupExpr returns the window expression the aggregation groups by, if
+// any.
+func windowGroupExpr(agg *logicalplan.Aggregation) *logicalplan.WindowExpr {
+	for _, expr := range agg.GroupExprs {
+		if w, ok := expr.(*logicalplan.WindowExpr); ok {
+			return w
+		}
+	}
+	return nil
+}
+
 func shouldPlanOrderedAggregate(
 	execOpts execOptions, oInfo *planOrderingInfo, agg *logicalplan.Aggregation,
 ) (bool, error) {
@@ -526,6 +849,19 @@ func shouldPlanOrderedAggregate(
 		// More than one aggregation is not yet supported.
 		return false, nil
 	}
+	for _, expr := range agg.AggExprs {
+		switch expr.Func {
+		case logicalplan.AggFuncStddev, logicalplan.AggFuncVariance, logicalplan.AggFuncQuantile:
+			// Merging the states of statistical aggregations is only
+			// supported by hash aggregations.
+			return false, nil
+		}
+	}
+	if windowGroupExpr(agg) != nil {
+		// Rows are emitted once per window, so the input ordering does not
+		// carry over to the windowed rows.
+		return false, nil
+	}
 	if !oInfo.orderingMaintained() {
 		return false, nil
 	}
@@ -7,6 +7,7 @@ import (
 	"go.opentelemetry.io/otel/trace/noop"
 
 	"github.com/apache/arrow/go/v17/arrow"
+	"github.com/apache/arrow/go/v17/arrow/array"
 	"github.com/apache/arrow/go/v17/arrow/memory"
 	"github.com/stretchr/testify/require"
 
@@ -86,6 +87,86 @@ func TestBuildPhysicalPlan(t *testing.T) {
 	require.NoError(t, err)
 }
 
+// recordTableReader is a table reader passing a record to the first
+// callback of the scan.
+type recordTableReader struct {
+	mockTableReader
+	record arrow.Record
+}
+
+func (r *recordTableReader) View(ctx context.Context, fn func(ctx context.Context, tx uint64) error) error {
+	return fn(ctx, 1)
+}
+
+func (r *recordTableReader) Iterator(
+	ctx context.Context,
+	_ uint64,
+	_ memory.Allocator,
+	callbacks []logicalplan.Callback,
+	_ ...logicalplan.Option,
+) error {
+	return callbacks[0](ctx, r.record)
+}
+
+type recordTableProvider struct {
+	reader *recordTableReader
+}
+
+func (p *recordTableProvider) GetTable(_ string) (logicalplan.TableReader, error) {
+	return p.reader, nil
+}
+
+func TestBuildPhysicalPlanSingleStreamAggregation(t *testing.T) {
+	// A single stream is aggregated in two stages too, so that the final
+	// stage finds the results of the first stage.
+	concurrency := concurrencyHardcoded
+	concurrencyHardcoded = 1
+	defer func() { concurrencyHardcoded = concurrency }()
+
+	b := array.NewRecordBuilder(memory.DefaultAllocator, arrow.NewSchema([]arrow.Field{
+		{Name: "example_type", Type: arrow.BinaryTypes.String},
+		{Name: "value", Type: arrow.PrimitiveTypes.Int64},
+	}, nil))
+	defer b.Release()
+	b.Field(0).(*array.StringBuilder).AppendValues([]string{"a", "b", "a"}, nil)
+	b.Field(1).(*array.Int64Builder).AppendValues([]int64{1, 2, 3}, nil)
+	r := b.NewRecord()
+	defer r.Release()
+
+	schema := dynparquet.NewSampleSchema()
+	p, err := (&logicalplan.Builder{}).
+		Scan(&recordTableProvider{reader: &recordTableReader{
+			mockTableReader: mockTableReader{schema: schema},
+			record:          r,
+		}}, "table1").
+		Aggregate(
+			[]*logicalplan.AggregationFunction{logicalplan.Sum(logicalplan.Col("value"))},
+			[]logicalplan.Expr{logicalplan.Col("example_type")},
+		).
+		Build()
+	require.NoError(t, err)
+
+	plan, err := Build(
+		context.Background(),
+		memory.DefaultAllocator,
+		noop.NewTracerProvider().Tracer(""),
+		schema,
+		p,
+	)
+	require.NoError(t, err)
+
+	sums := map[string]int64{}
+	require.NoError(t, plan.Execute(context.Background(), memory.DefaultAllocator, func(_ context.Context, r arrow.Record) error {
+		types := r.Column(r.Schema().FieldIndices("example_type")[0]).(*array.String)
+		values := r.Column(r.Schema().FieldIndices("sum(value)")[0]).(*array.Int64)
+		for i := 0; i < int(r.NumRows()); i++ {
+			sums[types.Value(i)] += values.Value(i)
+		}
+		return nil
+	}))
+	require.Equal(t, map[string]int64{"a": 4, "b": 2}, sums)
+}
+
 type mockPhysicalPlan struct {
 	next PhysicalPlan
 }
@@ -106,6 +187,12 @@ func (m *mockPhysicalPlan) Draw() *Diagram {
 	return &Diagram{}
 }
 
+func (m *mockPhysicalPlan) Name() string { return "mock" }
+
+func (m *mockPhysicalPlan) Children() []Operator { return nextOperators(m.next) }
+
+func (m *mockPhysicalPlan) Stats() OperatorStats { return OperatorStats{} }
+
 func (m *mockPhysicalPlan) Close() {
 	m.next.Close()
 }
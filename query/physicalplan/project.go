@@ -99,299 +99,18 @@ func (b binaryExprProjection) Project(mem memory.Allocator, ar arrow.Record) ([]
 		return nil, nil, fmt.Errorf("binary expression projection expected one field and one array for each side, got %d fields and %d arrays on right", len(rightFields), len(rightArrays))
 	}
 
-	leftArray := leftArrays[0]
-	rightArray := rightArrays[0]
-
-	resultFields := []arrow.Field{{
-		Name:     b.expr.Name(),
-		Type:     leftFields[0].Type,
-		Nullable: leftFields[0].Nullable,
-		Metadata: leftFields[0].Metadata,
-	}}
-	switch leftArray := leftArray.(type) {
-	case *array.Int64:
-		switch b.expr.Op {
-		case logicalplan.OpAdd:
-			return resultFields, []arrow.Array{AddInt64s(mem, leftArray, rightArray.(*array.Int64))}, nil
-		case logicalplan.OpSub:
-			return resultFields, []arrow.Array{SubInt64s(mem, leftArray, rightArray.(*array.Int64))}, nil
-		case logicalplan.OpMul:
-			return resultFields, []arrow.Array{MulInt64s(mem, leftArray, rightArray.(*array.Int64))}, nil
-		case logicalplan.OpDiv:
-			return resultFields, []arrow.Array{DivInt64s(mem, leftArray, rightArray.(*array.Int64))}, nil
-		default:
-			return nil, nil, fmt.Errorf("unsupported binary expression: %s", b.expr.String())
-		}
-	case *array.Int32:
-		switch b.expr.Op {
-		case logicalplan.OpAdd:
-			return resultFields, []arrow.Array{AddInt32s(mem, leftArray, rightArray.(*array.Int32))}, nil
-		case logicalplan.OpSub:
-			return resultFields, []arrow.Array{SubInt32s(mem, leftArray, rightArray.(*array.Int32))}, nil
-		case logicalplan.OpMul:
-			return resultFields, []arrow.Array{MulInt32s(mem, leftArray, rightArray.(*array.Int32))}, nil
-		case logicalplan.OpDiv:
-			return resultFields, []arrow.Array{DivInt32s(mem, leftArray, rightArray.(*array.Int32))}, nil
-		default:
-			return nil, nil, fmt.Errorf("unsupported binary expression: %s", b.expr.String())
-		}
-	case *array.Uint64:
-		switch b.expr.Op {
-		case logicalplan.OpAdd:
-			return resultFields, []arrow.Array{AddUint64s(mem, leftArray, rightArray.(*array.Uint64))}, nil
-		case logicalplan.OpSub:
-			return resultFields, []arrow.Array{SubUint64s(mem, leftArray, rightArray.(*array.Uint64))}, nil
-		case logicalplan.OpMul:
-			return resultFields, []arrow.Array{MulUint64s(mem, leftArray, rightArray.(*array.Uint64))}, nil
-		case logicalplan.OpDiv:
-			return resultFields, []arrow.Array{DivUint64s(mem, leftArray, rightArray.(*array.Uint64))}, nil
-		default:
-			return nil, nil, fmt.Errorf("unsupported binary expression: %s", b.expr.String())
-		}
-	case *array.Float64:
-		switch b.expr.Op {
-		case logicalplan.OpAdd:
-			return resultFields, []arrow.Array{AddFloat64s(mem, leftArray, rightArray.(*array.Float64))}, nil
-		case logicalplan.OpSub:
-			return resultFields, []arrow.Array{SubFloat64s(mem, leftArray, rightArray.(*array.Float64))}, nil
-		case logicalplan.OpMul:
-			return resultFields, []arrow.Array{MulFloat64s(mem, leftArray, rightArray.(*array.Float64))}, nil
-		case logicalplan.OpDiv:
-			return resultFields, []arrow.Array{DivFloat64s(mem, leftArray, rightArray.(*array.Float64))}, nil
-		default:
-			return nil, nil, fmt.Errorf("unsupported binary expression: %s", b.expr.String())
-		}
-	default:
-		return nil, nil, fmt.Errorf("unsupported type: %T", leftArray)
-	}
-}
-
-func AddInt64s(mem memory.Allocator, left, right *array.Int64) *array.Int64 {
-	res := array.NewInt64Builder(mem)
-	defer res.Release()
-
-	res.Resize(left.Len())
-
-	for i := 0; i < left.Len(); i++ {
-		res.Append(left.Value(i) + right.Value(i))
-	}
-
-	return res.NewInt64Array()
-}
-
-func SubInt64s(mem memory.Allocator, left, right *array.Int64) *array.Int64 {
-	res := array.NewInt64Builder(mem)
-	defer res.Release()
-
-	res.Resize(left.Len())
-
-	for i := 0; i < left.Len(); i++ {
-		res.Append(left.Value(i) - right.Value(i))
-	}
-
-	return res.NewInt64Array()
-}
-
-func MulInt64s(mem memory.Allocator, left, right *array.Int64) *array.Int64 {
-	res := array.NewInt64Builder(mem)
-	defer res.Release()
-
-	res.Resize(left.Len())
-
-	for i := 0; i < left.Len(); i++ {
-		res.Append(left.Value(i) * right.Value(i))
-	}
-
-	return res.NewInt64Array()
-}
-
-func DivInt64s(mem memory.Allocator, left, right *array.Int64) *array.Int64 {
-	res := array.NewInt64Builder(mem)
-	defer res.Release()
-
-	res.Resize(left.Len())
-
-	for i := 0; i < left.Len(); i++ {
-		rightValue := right.Value(i)
-		if right.Value(i) == 0 {
-			res.AppendNull()
-		} else {
-			res.Append(left.Value(i) / rightValue)
-		}
-	}
-
-	return res.NewInt64Array()
-}
-
-func AddFloat64s(mem memory.Allocator, left, right *array.Float64) *array.Float64 {
-	res := array.NewFloat64Builder(mem)
-	defer res.Release()
-
-	res.Resize(left.Len())
-
-	for i := 0; i < left.Len(); i++ {
-		res.Append(left.Value(i) + right.Value(i))
-	}
-
-	return res.NewFloat64Array()
-}
-
-func SubFloat64s(mem memory.Allocator, left, right *array.Float64) *array.Float64 {
-	res := array.NewFloat64Builder(mem)
-	defer res.Release()
-
-	res.Resize(left.Len())
-
-	for i := 0; i < left.Len(); i++ {
-		res.Append(left.Value(i) - right.Value(i))
-	}
-
-	return res.NewFloat64Array()
-}
-
-func MulFloat64s(mem memory.Allocator, left, right *array.Float64) *array.Float64 {
-	res := array.NewFloat64Builder(mem)
-	defer res.Release()
-
-	res.Resize(left.Len())
-
-	for i := 0; i < left.Len(); i++ {
-		res.Append(left.Value(i) * right.Value(i))
-	}
-
-	return res.NewFloat64Array()
-}
-
-func DivFloat64s(mem memory.Allocator, left, right *array.Float64) *array.Float64 {
-	res := array.NewFloat64Builder(mem)
-	defer res.Release()
-
-	res.Resize(left.Len())
-
-	for i := 0; i < left.Len(); i++ {
-		rightValue := right.Value(i)
-		if right.Value(i) == 0 {
-			res.AppendNull()
-		} else {
-			res.Append(left.Value(i) / rightValue)
-		}
-	}
-
-	return res.NewFloat64Array()
-}
-
-func AddInt32s(mem memory.Allocator, left, right *array.Int32) *array.Int32 {
-	res := array.NewInt32Builder(mem)
-	defer res.Release()
-
-	res.Resize(left.Len())
-
-	for i := 0; i < left.Len(); i++ {
-		res.Append(left.Value(i) + right.Value(i))
-	}
-
-	return res.NewInt32Array()
-}
-
-func SubInt32s(mem memory.Allocator, left, right *array.Int32) *array.Int32 {
-	res := array.NewInt32Builder(mem)
-	defer res.Release()
-
-	res.Resize(left.Len())
-
-	for i := 0; i < left.Len(); i++ {
-		res.Append(left.Value(i) - right.Value(i))
-	}
-
-	return res.NewInt32Array()
-}
-
-func MulInt32s(mem memory.Allocator, left, right *array.Int32) *array.Int32 {
-	res := array.NewInt32Builder(mem)
-	defer res.Release()
-
-	res.Resize(left.Len())
-
-	for i := 0; i < left.Len(); i++ {
-		res.Append(left.Value(i) * right.Value(i))
-	}
-
-	return res.NewInt32Array()
-}
-
-func DivInt32s(mem memory.Allocator, left, right *array.Int32) *array.Int32 {
-	res := array.NewInt32Builder(mem)
-	defer res.Release()
-
-	res.Resize(left.Len())
-
-	for i := 0; i < left.Len(); i++ {
-		rightValue := right.Value(i)
-		if right.Value(i) == 0 {
-			res.AppendNull()
-		} else {
-			res.Append(left.Value(i) / rightValue)
-		}
-	}
-
-	return res.NewInt32Array()
-}
-
-func AddUint64s(mem memory.Allocator, left, right *array.Uint64) *array.Uint64 {
-	res := array.NewUint64Builder(mem)
-	defer res.Release()
-
-	res.Resize(left.Len())
-
-	for i := 0; i < left.Len(); i++ {
-		res.Append(left.Value(i) + right.Value(i))
-	}
-
-	return res.NewUint64Array()
-}
-
-func SubUint64s(mem memory.Allocator, left, right *array.Uint64) *array.Uint64 {
-	res := array.NewUint64Builder(mem)
-	defer res.Release()
-
-	res.Resize(left.Len())
-
-	for i := 0; i < left.Len(); i++ {
-		res.Append(left.Value(i) - right.Value(i))
-	}
-
-	return res.NewUint64Array()
-}
-
-func MulUint64s(mem memory.Allocator, left, right *array.Uint64) *array.Uint64 {
-	res := array.NewUint64Builder(mem)
-	defer res.Release()
-
-	res.Resize(left.Len())
-
-	for i := 0; i < left.Len(); i++ {
-		res.Append(left.Value(i) * right.Value(i))
-	}
-
-	return res.NewUint64Array()
-}
-
-func DivUint64s(mem memory.Allocator, left, right *array.Uint64) *array.Uint64 {
-	res := array.NewUint64Builder(mem)
-	defer res.Release()
-
-	res.Resize(left.Len())
-
-	for i := 0; i < left.Len(); i++ {
-		rightValue := right.Value(i)
-		if right.Value(i) == 0 {
-			res.AppendNull()
-		} else {
-			res.Append(left.Value(i) / rightValue)
-		}
+	res, err := arithmetic(mem, b.expr.Op, leftArrays[0], rightArrays[0])
+	if err != nil {
+		return nil, nil, fmt.Errorf("evaluate %s: %w", b.expr.String(), err)
 	}
 
-	return res.NewUint64Array()
+	return []arrow.Field{{
+		Name: b.expr.Name(),
+		Type: res.DataType(),
+		// Dividing by zero results in null.
+		Nullable: true,
+		Metadata: leftFields[0].Metadata,
+	}}, []arrow.Array{res}, nil
 }
 
 type boolExprProjection struct {
@@ -589,7 +308,7 @@ func (p isNullProjection) Project(mem memory.Allocator, ar arrow.Record) ([]arro
 	b.Resize(cols[0].Len())
 
 	for i := 0; i < cols[0].Len(); i++ {
-		b.Append(cols[0].IsNull(i))
+		b.Append(cols[0].IsNull(i) != p.expr.Not)
 	}
 
 	return []arrow.Field{{
@@ -797,7 +516,7 @@ func projectionFromExpr(expr logicalplan.Expr) (columnProjection, error) {
 		}, nil
 	case *logicalplan.BinaryExpr:
 		switch e.Op {
-		case logicalplan.OpEq, logicalplan.OpNotEq, logicalplan.OpGt, logicalplan.OpGtEq, logicalplan.OpLt, logicalplan.OpLtEq, logicalplan.OpRegexMatch, logicalplan.OpRegexNotMatch, logicalplan.OpAnd, logicalplan.OpOr:
+		case logicalplan.OpEq, logicalplan.OpNotEq, logicalplan.OpGt, logicalplan.OpGtEq, logicalplan.OpLt, logicalplan.OpLtEq, logicalplan.OpRegexMatch, logicalplan.OpRegexNotMatch, logicalplan.OpAnd, logicalplan.OpOr, logicalplan.OpNullSafeEq:
 			boolExpr, err := binaryBooleanExpr(e)
 			if err != nil {
 				return nil, fmt.Errorf("boolean projection from expr: %w", err)
@@ -857,6 +576,30 @@ func projectionFromExpr(expr logicalplan.Expr) (columnProjection, error) {
 			expr: e,
 			p:    p,
 		}, nil
+	case *logicalplan.DateTruncExpr:
+		p, err := projectionFromExpr(e.Expr)
+		if err != nil {
+			return nil, fmt.Errorf("projection for date_trunc projection: %w", err)
+		}
+
+		return dateTruncProjection{
+			expr: e,
+			p:    p,
+		}, nil
+	case *logicalplan.MapKeyExpr:
+		p, err := projectionFromExpr(e.Expr)
+		if err != nil {
+			return nil, fmt.Errorf("projection for map key projection: %w", err)
+		}
+
+		return mapKeyProjection{
+			expr: e,
+			p:    p,
+		}, nil
+	case *logicalplan.StringFuncExpr:
+		return newStringFuncProjection(e)
+	case *logicalplan.CoalesceExpr:
+		return newCoalesceProjection(e)
 	default:
 		return nil, fmt.Errorf("unsupported expression type for projection: %T", expr)
 	}
@@ -868,7 +611,8 @@ type Projection struct {
 
 	colProjections []columnProjection
 
-	next PhysicalPlan
+	next  PhysicalPlan
+	stats StatsCollector
 }
 
 func Project(mem memory.Allocator, tracer trace.Tracer, exprs []logicalplan.Expr) (*Projection, error) {
@@ -894,6 +638,7 @@ func (p *Projection) Close() {
 }
 
 func (p *Projection) Callback(ctx context.Context, r arrow.Record) error {
+	p.stats.Observe(r)
 	ar, err := p.Project(ctx, r)
 	if err != nil {
 		return err
@@ -929,8 +674,14 @@ func (p *Projection) Project(_ context.Context, r arrow.Record) (arrow.Record, e
 		rows = int64(resArrays[0].Len())
 	}
 
+	// Keep the schema metadata of the input (e.g. provenance annotations).
+	var metadata *arrow.Metadata
+	if md := r.Schema().Metadata(); md.Len() > 0 {
+		metadata = &md
+	}
+
 	ar := array.NewRecord(
-		arrow.NewSchema(resFields, nil),
+		arrow.NewSchema(resFields, metadata),
 		resArrays,
 		rows,
 	)
@@ -964,6 +715,12 @@ func (p *Projection) Draw() *Diagram {
 	return &Diagram{Details: details, Child: child}
 }
 
+func (p *Projection) Name() string { return "Projection" }
+
+func (p *Projection) Children() []Operator { return nextOperators(p.next) }
+
+func (p *Projection) Stats() OperatorStats { return p.stats.Stats() }
+
 type allProjection struct{}
 
 func (a allProjection) Name() string { return "all" }
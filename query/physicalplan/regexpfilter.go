@@ -56,6 +56,8 @@ func ArrayScalarRegexMatch(left arrow.Array, right *regexp.Regexp) (*Bitmap, err
 		switch dict := arr.Dictionary().(type) {
 		case *array.Binary:
 			return BinaryDictionaryArrayScalarRegexMatch(arr, dict, right)
+		case *array.String:
+			return StringDictionaryArrayScalarRegexMatch(arr, dict, right)
 		default:
 			return nil, fmt.Errorf("ArrayScalarRegexMatch: unsupported dictionary type: %T", dict)
 		}
@@ -74,6 +76,8 @@ func ArrayScalarRegexNotMatch(left arrow.Array, right *regexp.Regexp) (*Bitmap,
 		switch dict := arr.Dictionary().(type) {
 		case *array.Binary:
 			return BinaryDictionaryArrayScalarRegexNotMatch(arr, dict, right)
+		case *array.String:
+			return StringDictionaryArrayScalarRegexNotMatch(arr, dict, right)
 		default:
 			return nil, fmt.Errorf("ArrayScalarRegexNotMatch: unsupported dictionary type: %T", dict)
 		}
@@ -138,29 +142,67 @@ func StringArrayScalarRegexNotMatch(left *array.String, right *regexp.Regexp) (*
 	return res, nil
 }
 
+// BinaryDictionaryArrayScalarRegexMatch returns the indices of the non-null
+// values of dict matching right. The regular expression runs at most once per
+// dictionary entry.
 func BinaryDictionaryArrayScalarRegexMatch(dict *array.Dictionary, left *array.Binary, right *regexp.Regexp) (*Bitmap, error) {
-	res := NewBitmap()
-	for i := 0; i < dict.Len(); i++ {
-		if dict.IsNull(i) {
-			continue
-		}
-		if right.MatchString(string(left.Value(dict.GetValueIndex(i)))) {
-			res.Add(uint32(i))
-		}
-	}
-	return res, nil
+	return dictionaryRegexMatch(dict, left.Len(), func(i int) bool {
+		return right.Match(left.Value(i))
+	}, false), nil
 }
 
+// BinaryDictionaryArrayScalarRegexNotMatch returns the indices of the non-null
+// values of dict not matching right. The regular expression runs at most once
+// per dictionary entry.
 func BinaryDictionaryArrayScalarRegexNotMatch(dict *array.Dictionary, left *array.Binary, right *regexp.Regexp) (*Bitmap, error) {
+	return dictionaryRegexMatch(dict, left.Len(), func(i int) bool {
+		return right.Match(left.Value(i))
+	}, true), nil
+}
+
+// StringDictionaryArrayScalarRegexMatch is like
+// BinaryDictionaryArrayScalarRegexMatch for string dictionaries.
+func StringDictionaryArrayScalarRegexMatch(dict *array.Dictionary, left *array.String, right *regexp.Regexp) (*Bitmap, error) {
+	return dictionaryRegexMatch(dict, left.Len(), func(i int) bool {
+		return right.MatchString(left.Value(i))
+	}, false), nil
+}
+
+// StringDictionaryArrayScalarRegexNotMatch is like
+// BinaryDictionaryArrayScalarRegexNotMatch for string dictionaries.
+func StringDictionaryArrayScalarRegexNotMatch(dict *array.Dictionary, left *array.String, right *regexp.Regexp) (*Bitmap, error) {
+	return dictionaryRegexMatch(dict, left.Len(), func(i int) bool {
+		return right.MatchString(left.Value(i))
+	}, true), nil
+}
+
+// dictionaryRegexMatch returns the indices of the non-null values of dict for
+// which match returns !notMatch. match is called with dictionary indices and
+// its result is memoized, so it is called at most once per dictionary entry
+// that is referenced.
+func dictionaryRegexMatch(dict *array.Dictionary, dictLen int, match func(i int) bool, notMatch bool) *Bitmap {
+	const (
+		unknown = iota
+		matched
+		notMatched
+	)
+	results := make([]uint8, dictLen)
+
 	res := NewBitmap()
 	for i := 0; i < dict.Len(); i++ {
 		if dict.IsNull(i) {
 			continue
 		}
-		if !right.MatchString(string(left.Value(dict.GetValueIndex(i)))) {
+		idx := dict.GetValueIndex(i)
+		if results[idx] == unknown {
+			results[idx] = notMatched
+			if match(idx) {
+				results[idx] = matched
+			}
+		}
+		if (results[idx] == matched) != notMatch {
 			res.Add(uint32(i))
 		}
 	}
-
-	return res, nil
+	return res
 }
@@ -17,6 +17,7 @@ import (
 
 type ReservoirSampler struct {
 	next      PhysicalPlan
+	stats     StatsCollector
 	allocator memory.Allocator
 
 	// size is the max number of rows in the reservoir
@@ -93,6 +94,12 @@ func (s *ReservoirSampler) Draw() *Diagram {
 	return &Diagram{Details: details, Child: child}
 }
 
+func (s *ReservoirSampler) Name() string { return "ReservoirSampler" }
+
+func (s *ReservoirSampler) Children() []Operator { return nextOperators(s.next) }
+
+func (s *ReservoirSampler) Stats() OperatorStats { return s.stats.Stats() }
+
 func (s *ReservoirSampler) Close() {
 	for _, r := range s.reservoir {
 		s.sizeInBytes -= r.ref.Release()
@@ -102,6 +109,7 @@ func (s *ReservoirSampler) Close() {
 
 // Callback collects all the records to sample.
 func (s *ReservoirSampler) Callback(_ context.Context, r arrow.Record) error {
+	s.stats.Observe(r)
 	var ref *referencedRecord
 	r, ref = s.fill(r)
 	if r == nil { // The record fit in the reservoir
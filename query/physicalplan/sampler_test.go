@@ -31,6 +31,9 @@ func (t *TestPlan) Finish(ctx context.Context) error {
 
 func (t *TestPlan) SetNext(_ PhysicalPlan) {}
 func (t *TestPlan) Draw() *Diagram         { return nil }
+func (t *TestPlan) Name() string           { return "TestPlan" }
+func (t *TestPlan) Children() []Operator   { return nil }
+func (t *TestPlan) Stats() OperatorStats   { return OperatorStats{} }
 func (t *TestPlan) Close()                 {}
 
 func Test_Sampler(t *testing.T) {
@@ -0,0 +1,572 @@
+package physicalplan
+
+import (
+	"bytes"
+	"cmp"
+	"container/heap"
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/apache/arrow/go/v17/arrow"
+	"github.com/apache/arrow/go/v17/arrow/array"
+	"github.com/apache/arrow/go/v17/arrow/compute"
+	"github.com/apache/arrow/go/v17/arrow/ipc"
+	"github.com/apache/arrow/go/v17/arrow/memory"
+	"github.com/apache/arrow/go/v17/arrow/util"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/polarsignals/frostdb/pqarrow/arrowutils"
+	"github.com/polarsignals/frostdb/pqarrow/builder"
+	"github.com/polarsignals/frostdb/query/logicalplan"
+)
+
+// DefaultSortMemoryLimit is the number of bytes a sort buffers in memory
+// before spilling sorted runs to disk.
+const DefaultSortMemoryLimit = 256 << 20
+
+// sortBatchSize is the maximum number of rows of the records produced when
+// merging sorted runs.
+const sortBatchSize = 8192
+
+// Sorter sorts all records it receives by the given expressions and emits
+// them in order once all inputs have finished. Received records are sorted
+// individually and buffered. When the buffered records exceed the memory
+// limit, they are merged into a single sorted run that is spilled to disk.
+// All buffered records and spilled runs are merged on Finish.
+type Sorter struct {
+	pool   memory.Allocator
+	tracer trace.Tracer
+	next   PhysicalPlan
+	stats  StatsCollector
+
+	exprs       []logicalplan.Expr
+	descending  []bool
+	memoryLimit int64
+	spillDir    string
+
+	// types are the types of the sort columns of the records received so
+	// far, or nil if no record contained the column yet.
+	types []arrow.DataType
+	// records are the individually sorted records buffered in memory.
+	records       []arrow.Record
+	bufferedBytes int64
+	// runs are the paths of the files sorted runs were spilled to.
+	runs []string
+}
+
+// Sort returns a Sorter ordering records by the given expressions. Sorted
+// runs are spilled to files in spillDir, or the default temporary directory
+// if it is empty, once more than memoryLimit bytes are buffered. A
+// memoryLimit <= 0 disables spilling.
+func Sort(
+	pool memory.Allocator,
+	tracer trace.Tracer,
+	exprs []logicalplan.Expr,
+	memoryLimit int64,
+	spillDir string,
+) *Sorter {
+	s := &Sorter{
+		pool:        pool,
+		tracer:      tracer,
+		exprs:       make([]logicalplan.Expr, len(exprs)),
+		descending:  make([]bool, len(exprs)),
+		types:       make([]arrow.DataType, len(exprs)),
+		memoryLimit: memoryLimit,
+		spillDir:    spillDir,
+	}
+	for i, expr := range exprs {
+		if sortExpr, ok := expr.(*logicalplan.SortExpr); ok {
+			expr = sortExpr.Expr
+			s.descending[i] = sortExpr.Descending
+		}
+		s.exprs[i] = expr
+	}
+	return s
+}
+
+func (s *Sorter) SetNext(next PhysicalPlan) { s.next = next }
+
+func (s *Sorter) Draw() *Diagram {
+	var child *Diagram
+	if s.next != nil {
+		child = s.next.Draw()
+	}
+	names := make([]string, 0, len(s.exprs))
+	for i, expr := range s.exprs {
+		if s.descending[i] {
+			names = append(names, expr.Name()+" desc")
+		} else {
+			names = append(names, expr.Name())
+		}
+	}
+	return &Diagram{Details: fmt.Sprintf("Sort(%s)", strings.Join(names, ", ")), Child: child}
+}
+
+func (s *Sorter) Name() string { return "Sort" }
+
+func (s *Sorter) Children() []Operator { return nextOperators(s.next) }
+
+func (s *Sorter) Stats() OperatorStats { return s.stats.Stats() }
+
+func (s *Sorter) Close() {
+	s.reset()
+	s.next.Close()
+}
+
+func (s *Sorter) Callback(ctx context.Context, r arrow.Record) error {
+	s.stats.Observe(r)
+	if r.NumRows() == 0 {
+		return nil
+	}
+	if err := s.checkSortColumns(r); err != nil {
+		return err
+	}
+
+	sorted, err := s.sortRecord(ctx, r)
+	if err != nil {
+		return err
+	}
+	s.records = append(s.records, sorted)
+	s.bufferedBytes += util.TotalRecordSize(sorted)
+	if s.memoryLimit > 0 && s.bufferedBytes > s.memoryLimit {
+		return s.spill(ctx)
+	}
+	return nil
+}
+
+func (s *Sorter) Finish(ctx context.Context) error {
+	defer s.reset()
+
+	if len(s.runs) == 0 && len(s.records) == 1 {
+		// Nothing to merge.
+		if err := s.next.Callback(ctx, s.records[0]); err != nil {
+			return err
+		}
+		return s.next.Finish(ctx)
+	}
+
+	cursors := s.memoryCursors()
+	for _, path := range s.runs {
+		c, err := s.fileCursor(path)
+		if err != nil {
+			for _, c := range cursors {
+				c.release()
+			}
+			return err
+		}
+		cursors = append(cursors, c)
+	}
+	if err := s.merge(cursors, func(r arrow.Record) error {
+		return s.next.Callback(ctx, r)
+	}); err != nil {
+		return err
+	}
+	return s.next.Finish(ctx)
+}
+
+// sortColumns returns the columns of r for each sort expression, or nil if r
+// does not contain the column, in which case all its values are null.
+func (s *Sorter) sortColumns(r arrow.Record) []arrow.Array {
+	cols := make([]arrow.Array, len(s.exprs))
+	for i, expr := range s.exprs {
+		if indices := r.Schema().FieldIndices(expr.Name()); len(indices) == 1 {
+			cols[i] = r.Column(indices[0])
+		}
+	}
+	return cols
+}
+
+// checkSortColumns returns an error if a sort column of r is of a type that
+// cannot be sorted, or of a different type than in previous records, so that
+// rows of all records can be compared when merging them.
+func (s *Sorter) checkSortColumns(r arrow.Record) error {
+	for i, col := range s.sortColumns(r) {
+		if col == nil {
+			continue
+		}
+		t := col.DataType()
+		if !arrowutils.IsSortableType(t) {
+			return fmt.Errorf("unsupported type %s for sorting by %s", t, s.exprs[i].Name())
+		}
+		if dict, ok := t.(*arrow.DictionaryType); ok {
+			// Only the values are compared, the index type may differ.
+			t = dict.ValueType
+		}
+		switch {
+		case s.types[i] == nil:
+			s.types[i] = t
+		case !arrow.TypeEqual(s.types[i], t):
+			return fmt.Errorf("sorting by %s: type %s does not match type %s of previous records", s.exprs[i].Name(), t, s.types[i])
+		}
+	}
+	return nil
+}
+
+// sortRecord returns a copy of r sorted by the sort expressions. Nulls are
+// sorted last.
+func (s *Sorter) sortRecord(ctx context.Context, r arrow.Record) (arrow.Record, error) {
+	sortingCols := make([]arrowutils.SortingColumn, 0, len(s.exprs))
+	for i, expr := range s.exprs {
+		indices := r.Schema().FieldIndices(expr.Name())
+		if len(indices) != 1 {
+			// Missing columns are null for all rows so they do not affect
+			// the order.
+			continue
+		}
+		direction := arrowutils.Ascending
+		if s.descending[i] {
+			direction = arrowutils.Descending
+		}
+		sortingCols = append(sortingCols, arrowutils.SortingColumn{
+			Index:     indices[0],
+			Direction: direction,
+		})
+	}
+	if len(sortingCols) == 0 {
+		r.Retain()
+		return r, nil
+	}
+
+	indices, err := arrowutils.SortRecord(r, sortingCols)
+	if err != nil {
+		return nil, fmt.Errorf("sort record: %w", err)
+	}
+	defer indices.Release()
+	return arrowutils.Take(compute.WithAllocator(ctx, s.pool), r, indices)
+}
+
+// spill merges the buffered records into a sorted run written to a file in
+// the spill directory.
+func (s *Sorter) spill(_ context.Context) error {
+	f, err := os.CreateTemp(s.spillDir, "frostdb-sort-*.arrow")
+	if err != nil {
+		return fmt.Errorf("create spill file: %w", err)
+	}
+	// Track the run right away so the file is removed even if writing fails.
+	s.runs = append(s.runs, f.Name())
+	defer f.Close()
+
+	var w *ipc.Writer
+	err = s.merge(s.memoryCursors(), func(r arrow.Record) error {
+		if w == nil {
+			w = ipc.NewWriter(f, ipc.WithSchema(r.Schema()), ipc.WithAllocator(s.pool))
+		}
+		return w.Write(r)
+	})
+	s.releaseRecords()
+	if err != nil {
+		return fmt.Errorf("write spill file: %w", err)
+	}
+	if w != nil {
+		if err := w.Close(); err != nil {
+			return fmt.Errorf("close spill file writer: %w", err)
+		}
+	}
+	return f.Close()
+}
+
+// reset releases all buffered records and removes all spilled runs.
+func (s *Sorter) reset() {
+	s.releaseRecords()
+	for _, path := range s.runs {
+		_ = os.Remove(path)
+	}
+	s.runs = nil
+}
+
+func (s *Sorter) releaseRecords() {
+	for _, r := range s.records {
+		r.Release()
+	}
+	s.records = nil
+	s.bufferedBytes = 0
+}
+
+// memoryCursors returns a cursor for each buffered record.
+func (s *Sorter) memoryCursors() []*sortCursor {
+	cursors := make([]*sortCursor, 0, len(s.records))
+	for _, r := range s.records {
+		r := r
+		cursors = append(cursors, &sortCursor{
+			schema: r.Schema(),
+			next: func() (arrow.Record, error) {
+				if r == nil {
+					return nil, nil
+				}
+				next := r
+				r = nil
+				next.Retain()
+				return next, nil
+			},
+		})
+	}
+	return cursors
+}
+
+// fileCursor returns a cursor reading the sorted run spilled to path.
+func (s *Sorter) fileCursor(path string) (*sortCursor, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("open spill file: %w", err)
+	}
+	rdr, err := ipc.NewReader(f, ipc.WithAllocator(s.pool))
+	if err != nil {
+		f.Close()
+		return nil, fmt.Errorf("read spill file: %w", err)
+	}
+	return &sortCursor{
+		schema: rdr.Schema(),
+		next: func() (arrow.Record, error) {
+			if !rdr.Next() {
+				return nil, rdr.Err()
+			}
+			r := rdr.Record()
+			r.Retain()
+			return r, nil
+		},
+		close: func() error {
+			rdr.Release()
+			return f.Close()
+		},
+	}, nil
+}
+
+// merge merges the sorted records of the given cursors and calls emit with
+// records of at most sortBatchSize rows. The emitted records are released
+// once emit returns. All cursors are closed when merge returns.
+func (s *Sorter) merge(cursors []*sortCursor, emit func(arrow.Record) error) error {
+	h := &sortCursorHeap{descending: s.descending}
+	defer func() {
+		for _, c := range cursors {
+			c.release()
+		}
+	}()
+
+	// The merged schema contains the fields of all runs in the order they
+	// are first seen.
+	fields := make([]arrow.Field, 0)
+	fieldIndices := make(map[string]int)
+	for _, c := range cursors {
+		for _, field := range c.schema.Fields() {
+			if _, ok := fieldIndices[field.Name]; !ok {
+				fieldIndices[field.Name] = len(fields)
+				fields = append(fields, field)
+			}
+		}
+	}
+	schema := arrow.NewSchema(fields, nil)
+
+	for _, c := range cursors {
+		c.fields = make([]int, len(fields))
+		for i, field := range fields {
+			c.fields[i] = -1
+			if indices := c.schema.FieldIndices(field.Name); len(indices) == 1 {
+				c.fields[i] = indices[0]
+			}
+		}
+		ok, err := c.advance(s.sortColumns)
+		if err != nil {
+			return err
+		}
+		if ok {
+			h.cursors = append(h.cursors, c)
+		}
+	}
+	heap.Init(h)
+
+	b := builder.NewRecordBuilder(s.pool, schema)
+	defer b.Release()
+	flush := func() error {
+		r := b.NewRecord()
+		defer r.Release()
+		return emit(r)
+	}
+
+	rows := 0
+	for h.Len() > 0 {
+		// The cursor with the next row in order is always at index 0.
+		c := h.cursors[0]
+		for i, cb := range b.Fields() {
+			var arr arrow.Array
+			if idx := c.fields[i]; idx >= 0 {
+				arr = c.r.Column(idx)
+			}
+			if err := builder.AppendValue(cb, arr, c.idx); err != nil {
+				return err
+			}
+		}
+		rows++
+		if rows == sortBatchSize {
+			if err := flush(); err != nil {
+				return err
+			}
+			rows = 0
+		}
+
+		ok, err := c.advance(s.sortColumns)
+		if err != nil {
+			return err
+		}
+		if ok {
+			heap.Fix(h, 0)
+		} else {
+			heap.Pop(h)
+		}
+	}
+	if rows > 0 {
+		return flush()
+	}
+	return nil
+}
+
+// sortCursor iterates over the rows of a sorted run.
+type sortCursor struct {
+	schema *arrow.Schema
+	// next returns the next record of the run, or nil if the run is
+	// exhausted. The caller must release the record.
+	next  func() (arrow.Record, error)
+	close func() error
+
+	r   arrow.Record
+	idx int
+	// fields maps the fields of the merged schema to the columns of r, or -1
+	// if r does not contain the field.
+	fields []int
+	// cols are the sort columns of r.
+	cols []arrow.Array
+}
+
+// advance moves the cursor to the next row. It returns false if the run is
+// exhausted.
+func (c *sortCursor) advance(sortColumns func(arrow.Record) []arrow.Array) (bool, error) {
+	if c.r != nil {
+		c.idx++
+		if c.idx < int(c.r.NumRows()) {
+			return true, nil
+		}
+		c.r.Release()
+		c.r = nil
+	}
+
+	for {
+		r, err := c.next()
+		if err != nil {
+			return false, err
+		}
+		if r == nil {
+			return false, nil
+		}
+		if r.NumRows() == 0 {
+			r.Release()
+			continue
+		}
+		c.r = r
+		c.idx = 0
+		c.cols = sortColumns(r)
+		return true, nil
+	}
+}
+
+func (c *sortCursor) release() {
+	if c.r != nil {
+		c.r.Release()
+		c.r = nil
+	}
+	if c.close != nil {
+		_ = c.close()
+		c.close = nil
+	}
+}
+
+type sortCursorHeap struct {
+	cursors    []*sortCursor
+	descending []bool
+}
+
+func (h sortCursorHeap) Len() int { return len(h.cursors) }
+
+func (h sortCursorHeap) Less(i, j int) bool {
+	a, b := h.cursors[i], h.cursors[j]
+	for k, descending := range h.descending {
+		aNull := a.cols[k] == nil || a.cols[k].IsNull(a.idx)
+		bNull := b.cols[k] == nil || b.cols[k].IsNull(b.idx)
+		switch {
+		case aNull && bNull:
+			continue
+		case aNull:
+			// Nulls are sorted last.
+			return false
+		case bNull:
+			return true
+		}
+
+		c := compareValues(a.cols[k], a.idx, b.cols[k], b.idx)
+		if c != 0 {
+			if descending {
+				return c > 0
+			}
+			return c < 0
+		}
+	}
+	return false
+}
+
+func (h sortCursorHeap) Swap(i, j int) {
+	h.cursors[i], h.cursors[j] = h.cursors[j], h.cursors[i]
+}
+
+func (h sortCursorHeap) Push(_ any) {
+	panic(
+		"number of cursors are known at Init time, none should ever be pushed",
+	)
+}
+
+func (h *sortCursorHeap) Pop() any {
+	n := len(h.cursors) - 1
+	c := h.cursors[n]
+	h.cursors = h.cursors[:n]
+	return c
+}
+
+// compareValues compares the non-null values a[i] and b[j]. Both arrays must
+// be of the same type supported by arrowutils.SortRecord, which is checked by
+// checkSortColumns for each record the Sorter receives.
+func compareValues(a arrow.Array, i int, b arrow.Array, j int) int {
+	// Dictionaries are compared by their values, so a dictionary encoded
+	// column can be compared to a plain one.
+	if dict, ok := a.(*array.Dictionary); ok {
+		return compareValues(dict.Dictionary(), dict.GetValueIndex(i), b, j)
+	}
+	if dict, ok := b.(*array.Dictionary); ok {
+		return compareValues(a, i, dict.Dictionary(), dict.GetValueIndex(j))
+	}
+
+	switch a := a.(type) {
+	case *array.Int16:
+		return cmp.Compare(a.Value(i), b.(*array.Int16).Value(j))
+	case *array.Int32:
+		return cmp.Compare(a.Value(i), b.(*array.Int32).Value(j))
+	case *array.Int64:
+		return cmp.Compare(a.Value(i), b.(*array.Int64).Value(j))
+	case *array.Uint16:
+		return cmp.Compare(a.Value(i), b.(*array.Uint16).Value(j))
+	case *array.Uint32:
+		return cmp.Compare(a.Value(i), b.(*array.Uint32).Value(j))
+	case *array.Uint64:
+		return cmp.Compare(a.Value(i), b.(*array.Uint64).Value(j))
+	case *array.Float64:
+		return cmp.Compare(a.Value(i), b.(*array.Float64).Value(j))
+	case *array.Timestamp:
+		return cmp.Compare(a.Value(i), b.(*array.Timestamp).Value(j))
+	case *array.String:
+		return strings.Compare(a.Value(i), b.(*array.String).Value(j))
+	case *array.Binary:
+		return bytes.Compare(a.Value(i), b.(*array.Binary).Value(j))
+	case *array.FixedSizeBinary:
+		return bytes.Compare(a.Value(i), b.(*array.FixedSizeBinary).Value(j))
+	default:
+		panic(fmt.Sprintf("unsupported type for sorting %T", a))
+	}
+}
@@ -0,0 +1,176 @@
+package physicalplan
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/apache/arrow/go/v17/arrow"
+	"github.com/apache/arrow/go/v17/arrow/array"
+	"github.com/apache/arrow/go/v17/arrow/memory"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/trace/noop"
+
+	"github.com/polarsignals/frostdb/query/logicalplan"
+)
+
+func TestSorter(t *testing.T) {
+	ctx := context.Background()
+	mem := memory.DefaultAllocator
+
+	// newRecord returns a record with an int64 column "a" and a string
+	// column "b". Nil values of a are nulls. If a is nil, the record has no
+	// column "a".
+	newRecord := func(a []*int64, b []string) arrow.Record {
+		fields := []arrow.Field{}
+		cols := []arrow.Array{}
+		if a != nil {
+			ab := array.NewInt64Builder(mem)
+			defer ab.Release()
+			for _, v := range a {
+				if v == nil {
+					ab.AppendNull()
+				} else {
+					ab.Append(*v)
+				}
+			}
+			fields = append(fields, arrow.Field{Name: "a", Type: arrow.PrimitiveTypes.Int64, Nullable: true})
+			cols = append(cols, ab.NewArray())
+		}
+		bb := array.NewStringBuilder(mem)
+		defer bb.Release()
+		bb.AppendValues(b, nil)
+		fields = append(fields, arrow.Field{Name: "b", Type: arrow.BinaryTypes.String})
+		cols = append(cols, bb.NewArray())
+		r := array.NewRecord(arrow.NewSchema(fields, nil), cols, int64(len(b)))
+		for _, c := range cols {
+			c.Release()
+		}
+		return r
+	}
+	i64 := func(v int64) *int64 { return &v }
+
+	inputs := func() []arrow.Record {
+		return []arrow.Record{
+			newRecord([]*int64{i64(3), nil, i64(1)}, []string{"c", "null1", "a"}),
+			newRecord(nil, []string{"null2"}),
+			newRecord([]*int64{i64(2), i64(4), i64(0)}, []string{"b", "d", "z"}),
+			newRecord([]*int64{i64(2)}, []string{"a"}),
+		}
+	}
+
+	for _, tc := range []struct {
+		name  string
+		exprs []logicalplan.Expr
+		// expected is the expected order of column b.
+		expected []string
+	}{
+		{
+			name:     "Asc",
+			exprs:    []logicalplan.Expr{logicalplan.Col("a"), logicalplan.Col("b")},
+			expected: []string{"z", "a", "a", "b", "c", "d", "null1", "null2"},
+		},
+		{
+			name:     "Desc",
+			exprs:    []logicalplan.Expr{logicalplan.Desc(logicalplan.Col("a")), logicalplan.Asc(logicalplan.Col("b"))},
+			expected: []string{"d", "c", "a", "b", "a", "z", "null1", "null2"},
+		},
+	} {
+		for _, spill := range []bool{false, true} {
+			name := tc.name
+			if spill {
+				name += "/Spill"
+			}
+			t.Run(name, func(t *testing.T) {
+				dir := t.TempDir()
+				memoryLimit := int64(0)
+				if spill {
+					// Spill after every record.
+					memoryLimit = 1
+				}
+				s := Sort(mem, noop.NewTracerProvider().Tracer(""), tc.exprs, memoryLimit, dir)
+
+				var got []string
+				s.SetNext(&OutputPlan{
+					callback: func(_ context.Context, r arrow.Record) error {
+						idx := r.Schema().FieldIndices("b")
+						require.Len(t, idx, 1)
+						col := r.Column(idx[0]).(*array.String)
+						for i := 0; i < col.Len(); i++ {
+							got = append(got, col.Value(i))
+						}
+						return nil
+					},
+				})
+
+				for _, r := range inputs() {
+					require.NoError(t, s.Callback(ctx, r))
+					r.Release()
+				}
+				if spill {
+					entries, err := os.ReadDir(dir)
+					require.NoError(t, err)
+					require.Len(t, entries, 4)
+				}
+				require.NoError(t, s.Finish(ctx))
+				require.Equal(t, tc.expected, got)
+
+				// Spilled runs are removed once the sort is finished.
+				entries, err := os.ReadDir(dir)
+				require.NoError(t, err)
+				require.Empty(t, entries)
+			})
+		}
+	}
+}
+
+func TestSorterUnsupportedTypes(t *testing.T) {
+	ctx := context.Background()
+	mem := memory.NewCheckedAllocator(memory.NewGoAllocator())
+	defer mem.AssertSize(t, 0)
+
+	newRecord := func(b arrow.Array) arrow.Record {
+		defer b.Release()
+		return array.NewRecord(
+			arrow.NewSchema([]arrow.Field{{Name: "a", Type: b.DataType()}}, nil),
+			[]arrow.Array{b},
+			int64(b.Len()),
+		)
+	}
+	newSorter := func() *Sorter {
+		s := Sort(mem, noop.NewTracerProvider().Tracer(""), []logicalplan.Expr{logicalplan.Col("a")}, 0, t.TempDir())
+		s.SetNext(&OutputPlan{callback: func(context.Context, arrow.Record) error { return nil }})
+		return s
+	}
+
+	t.Run("Bool", func(t *testing.T) {
+		// Single row records are not sorted individually, so the type must
+		// be checked before merging them.
+		s := newSorter()
+		defer s.Close()
+		bb := array.NewBooleanBuilder(mem)
+		defer bb.Release()
+		bb.Append(true)
+		r := newRecord(bb.NewArray())
+		defer r.Release()
+		require.ErrorContains(t, s.Callback(ctx, r), "unsupported type bool")
+	})
+
+	t.Run("Mismatch", func(t *testing.T) {
+		s := newSorter()
+		defer s.Close()
+		ib := array.NewInt64Builder(mem)
+		defer ib.Release()
+		ib.Append(1)
+		r := newRecord(ib.NewArray())
+		defer r.Release()
+		require.NoError(t, s.Callback(ctx, r))
+
+		sb := array.NewStringBuilder(mem)
+		defer sb.Release()
+		sb.Append("a")
+		r = newRecord(sb.NewArray())
+		defer r.Release()
+		require.ErrorContains(t, s.Callback(ctx, r), "does not match type int64")
+	})
+}
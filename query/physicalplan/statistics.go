@@ -0,0 +1,356 @@
+package physicalplan
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/apache/arrow/go/v17/arrow"
+	"github.com/apache/arrow/go/v17/arrow/array"
+	"github.com/apache/arrow/go/v17/arrow/memory"
+)
+
+// Statistical aggregations cannot be computed from the results of partial
+// aggregations, so aggregations that are not in the final stage produce a
+// binary encoded state per group, which the final stage merges. Raw values
+// are aggregated directly by the final stage as well, so the aggregations
+// also work without a preceding stage.
+
+// VarianceAggregation computes the population variance, or the population
+// standard deviation if Stddev is set. The state of partial aggregations are
+// the count, mean and sum of squared differences from the mean of the values,
+// which are merged with Chan's parallel algorithm.
+type VarianceAggregation struct {
+	Stddev     bool
+	FinalStage bool
+}
+
+func (a *VarianceAggregation) Aggregate(pool memory.Allocator, arrs []arrow.Array) (arrow.Array, error) {
+	if !a.FinalStage {
+		return aggregateStates(pool, arrs, func(arr arrow.Array) ([]byte, error) {
+			var m moments
+			if err := forEachFloat64(arr, m.add); err != nil {
+				return nil, err
+			}
+			return m.encode(), nil
+		})
+	}
+
+	res := array.NewFloat64Builder(pool)
+	defer res.Release()
+	for _, arr := range arrs {
+		var m moments
+		if err := mergeStates(arr, m.add, func(state []byte) error {
+			other, err := decodeMoments(state)
+			if err != nil {
+				return err
+			}
+			m.merge(other)
+			return nil
+		}); err != nil {
+			return nil, err
+		}
+		if m.count == 0 {
+			res.AppendNull()
+			continue
+		}
+		variance := m.m2 / m.count
+		if a.Stddev {
+			res.Append(math.Sqrt(variance))
+			continue
+		}
+		res.Append(variance)
+	}
+	return res.NewArray(), nil
+}
+
+// moments are the count, mean and sum of squared differences from the mean of
+// a set of values.
+type moments struct {
+	count float64
+	mean  float64
+	m2    float64
+}
+
+func (m *moments) add(v float64) {
+	m.count++
+	delta := v - m.mean
+	m.mean += delta / m.count
+	m.m2 += delta * (v - m.mean)
+}
+
+func (m *moments) merge(other moments) {
+	if other.count == 0 {
+		return
+	}
+	count := m.count + other.count
+	delta := other.mean - m.mean
+	m.mean += delta * other.count / count
+	m.m2 += other.m2 + delta*delta*m.count*other.count/count
+	m.count = count
+}
+
+func (m *moments) encode() []byte {
+	buf := make([]byte, 0, 24)
+	buf = binary.LittleEndian.AppendUint64(buf, math.Float64bits(m.count))
+	buf = binary.LittleEndian.AppendUint64(buf, math.Float64bits(m.mean))
+	return binary.LittleEndian.AppendUint64(buf, math.Float64bits(m.m2))
+}
+
+func decodeMoments(buf []byte) (moments, error) {
+	if len(buf) != 24 {
+		return moments{}, fmt.Errorf("invalid variance state of %d bytes", len(buf))
+	}
+	return moments{
+		count: math.Float64frombits(binary.LittleEndian.Uint64(buf)),
+		mean:  math.Float64frombits(binary.LittleEndian.Uint64(buf[8:])),
+		m2:    math.Float64frombits(binary.LittleEndian.Uint64(buf[16:])),
+	}, nil
+}
+
+// QuantileAggregation computes an approximation of the given quantile using a
+// t-digest. The states of partial aggregations are the digests of the values.
+type QuantileAggregation struct {
+	Quantile   float64
+	FinalStage bool
+}
+
+func (a *QuantileAggregation) Aggregate(pool memory.Allocator, arrs []arrow.Array) (arrow.Array, error) {
+	if !a.FinalStage {
+		return aggregateStates(pool, arrs, func(arr arrow.Array) ([]byte, error) {
+			d := newTDigest()
+			if err := forEachFloat64(arr, d.add); err != nil {
+				return nil, err
+			}
+			return d.encode(), nil
+		})
+	}
+
+	res := array.NewFloat64Builder(pool)
+	defer res.Release()
+	for _, arr := range arrs {
+		d := newTDigest()
+		if err := mergeStates(arr, d.add, func(state []byte) error {
+			other, err := decodeTDigest(state)
+			if err != nil {
+				return err
+			}
+			d.merge(other)
+			return nil
+		}); err != nil {
+			return nil, err
+		}
+		if d.count() == 0 {
+			res.AppendNull()
+			continue
+		}
+		res.Append(d.quantile(a.Quantile))
+	}
+	return res.NewArray(), nil
+}
+
+// aggregateStates returns a binary array with the state of each array.
+func aggregateStates(pool memory.Allocator, arrs []arrow.Array, state func(arrow.Array) ([]byte, error)) (arrow.Array, error) {
+	res := array.NewBinaryBuilder(pool, arrow.BinaryTypes.Binary)
+	defer res.Release()
+	for _, arr := range arrs {
+		s, err := state(arr)
+		if err != nil {
+			return nil, err
+		}
+		res.Append(s)
+	}
+	return res.NewArray(), nil
+}
+
+// mergeStates calls merge with each state of arr if it contains the states of
+// partial aggregations, otherwise it calls add with each of its values.
+func mergeStates(arr arrow.Array, add func(float64), merge func([]byte) error) error {
+	states, ok := arr.(*array.Binary)
+	if !ok {
+		return forEachFloat64(arr, add)
+	}
+	for i := 0; i < states.Len(); i++ {
+		if states.IsNull(i) {
+			continue
+		}
+		if err := merge(states.Value(i)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// forEachFloat64 calls f with each non-null value of arr converted to a
+// float64.
+func forEachFloat64(arr arrow.Array, f func(float64)) error {
+	switch arr := arr.(type) {
+	case *array.Int64:
+		for i := 0; i < arr.Len(); i++ {
+			if arr.IsValid(i) {
+				f(float64(arr.Value(i)))
+			}
+		}
+	case *array.Uint64:
+		for i := 0; i < arr.Len(); i++ {
+			if arr.IsValid(i) {
+				f(float64(arr.Value(i)))
+			}
+		}
+	case *array.Float64:
+		for i := 0; i < arr.Len(); i++ {
+			if arr.IsValid(i) {
+				f(arr.Value(i))
+			}
+		}
+	default:
+		return fmt.Errorf("unsupported type for statistical aggregation: %s", arr.DataType())
+	}
+	return nil
+}
+
+// tdigestCompression bounds the number of centroids of a t-digest. Higher
+// values are more accurate but use more memory.
+const tdigestCompression = 100
+
+// tdigest is a merging t-digest, a sketch of the distribution of a set of
+// values from which quantiles can be approximated. Values are buffered as
+// centroids of weight one and compressed once there are too many.
+type tdigest struct {
+	centroids []centroid
+	min, max  float64
+	// compressed is the number of leading centroids that are compressed.
+	compressed int
+}
+
+type centroid struct {
+	mean   float64
+	weight float64
+}
+
+func newTDigest() *tdigest {
+	return &tdigest{min: math.Inf(1), max: math.Inf(-1)}
+}
+
+func (d *tdigest) add(v float64) {
+	d.addCentroid(centroid{mean: v, weight: 1}, v, v)
+}
+
+func (d *tdigest) merge(other *tdigest) {
+	for _, c := range other.centroids {
+		d.addCentroid(c, other.min, other.max)
+	}
+}
+
+func (d *tdigest) addCentroid(c centroid, lo, hi float64) {
+	d.min = math.Min(d.min, lo)
+	d.max = math.Max(d.max, hi)
+	d.centroids = append(d.centroids, c)
+	if len(d.centroids)-d.compressed > 10*tdigestCompression {
+		d.compress()
+	}
+}
+
+func (d *tdigest) count() float64 {
+	var count float64
+	for _, c := range d.centroids {
+		count += c.weight
+	}
+	return count
+}
+
+// compress merges neighbouring centroids as long as their combined weight is
+// within the limit given by the k1 scale function, which allows smaller
+// centroids towards the tails of the distribution.
+func (d *tdigest) compress() {
+	if len(d.centroids) == d.compressed {
+		return
+	}
+	sort.Slice(d.centroids, func(i, j int) bool {
+		return d.centroids[i].mean < d.centroids[j].mean
+	})
+
+	total := d.count()
+	scale := func(q float64) float64 {
+		return tdigestCompression / (2 * math.Pi) * math.Asin(2*q-1)
+	}
+	inverse := func(k float64) float64 {
+		return (math.Sin(k*2*math.Pi/tdigestCompression) + 1) / 2
+	}
+
+	compressed := d.centroids[:1]
+	q0 := 0.0
+	limit := inverse(scale(q0) + 1)
+	for _, c := range d.centroids[1:] {
+		cur := &compressed[len(compressed)-1]
+		if q := q0 + (cur.weight+c.weight)/total; q <= limit {
+			cur.weight += c.weight
+			cur.mean += (c.mean - cur.mean) * c.weight / cur.weight
+			continue
+		}
+		q0 += cur.weight / total
+		limit = inverse(scale(q0) + 1)
+		compressed = append(compressed, c)
+	}
+	d.centroids = compressed
+	d.compressed = len(compressed)
+}
+
+// quantile returns the approximate quantile q of the values, interpolating
+// linearly between the centers of the centroids.
+func (d *tdigest) quantile(q float64) float64 {
+	d.compress()
+	cs := d.centroids
+	if len(cs) == 1 {
+		return cs[0].mean
+	}
+
+	target := q * d.count()
+	if first := cs[0]; target < first.weight/2 {
+		return d.min + (first.mean-d.min)*target/(first.weight/2)
+	}
+	var cumulative float64
+	for i := 0; i < len(cs)-1; i++ {
+		left := cumulative + cs[i].weight/2
+		right := cumulative + cs[i].weight + cs[i+1].weight/2
+		if target <= right {
+			return cs[i].mean + (cs[i+1].mean-cs[i].mean)*(target-left)/(right-left)
+		}
+		cumulative += cs[i].weight
+	}
+	last := cs[len(cs)-1]
+	left := cumulative + last.weight/2
+	return last.mean + (d.max-last.mean)*math.Min(1, (target-left)/(last.weight/2))
+}
+
+func (d *tdigest) encode() []byte {
+	d.compress()
+	buf := make([]byte, 0, 16+16*len(d.centroids))
+	buf = binary.LittleEndian.AppendUint64(buf, math.Float64bits(d.min))
+	buf = binary.LittleEndian.AppendUint64(buf, math.Float64bits(d.max))
+	for _, c := range d.centroids {
+		buf = binary.LittleEndian.AppendUint64(buf, math.Float64bits(c.mean))
+		buf = binary.LittleEndian.AppendUint64(buf, math.Float64bits(c.weight))
+	}
+	return buf
+}
+
+func decodeTDigest(buf []byte) (*tdigest, error) {
+	if len(buf) < 16 || len(buf)%16 != 0 {
+		return nil, fmt.Errorf("invalid quantile state of %d bytes", len(buf))
+	}
+	d := &tdigest{
+		min:       math.Float64frombits(binary.LittleEndian.Uint64(buf)),
+		max:       math.Float64frombits(binary.LittleEndian.Uint64(buf[8:])),
+		centroids: make([]centroid, 0, len(buf)/16-1),
+	}
+	for buf = buf[16:]; len(buf) > 0; buf = buf[16:] {
+		d.centroids = append(d.centroids, centroid{
+			mean:   math.Float64frombits(binary.LittleEndian.Uint64(buf)),
+			weight: math.Float64frombits(binary.LittleEndian.Uint64(buf[8:])),
+		})
+	}
+	d.compressed = len(d.centroids)
+	return d, nil
+}
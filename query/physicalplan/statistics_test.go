@@ -0,0 +1,72 @@
+package physicalplan
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/apache/arrow/go/v17/arrow"
+	"github.com/apache/arrow/go/v17/arrow/array"
+	"github.com/apache/arrow/go/v17/arrow/memory"
+	"github.com/stretchr/testify/require"
+)
+
+// mergePartialStates aggregates each of the arrays of values with a partial
+// aggregation and merges the states with a final aggregation.
+func mergePartialStates(t *testing.T, pool memory.Allocator, partial, final AggregationFunction, values [][]float64) float64 {
+	t.Helper()
+	arrs := make([]arrow.Array, 0, len(values))
+	for _, v := range values {
+		b := array.NewFloat64Builder(pool)
+		b.AppendValues(v, nil)
+		arrs = append(arrs, b.NewArray())
+		b.Release()
+	}
+	states, err := partial.Aggregate(pool, arrs)
+	require.NoError(t, err)
+	for _, arr := range arrs {
+		arr.Release()
+	}
+	defer states.Release()
+
+	// The final stage receives the states of a group from all partial
+	// aggregations in a single array.
+	res, err := final.Aggregate(pool, []arrow.Array{states})
+	require.NoError(t, err)
+	defer res.Release()
+	require.Equal(t, 1, res.Len())
+	return res.(*array.Float64).Value(0)
+}
+
+func TestQuantileAggregation(t *testing.T) {
+	pool := memory.NewCheckedAllocator(memory.NewGoAllocator())
+	defer pool.AssertSize(t, 0)
+
+	rng := rand.New(rand.NewSource(1))
+	values := make([][]float64, 10)
+	for i := range values {
+		values[i] = make([]float64, 10_000)
+		for j := range values[i] {
+			values[i][j] = rng.Float64()
+		}
+	}
+
+	for _, q := range []float64{0, 0.01, 0.5, 0.9, 0.99, 0.999, 1} {
+		v := mergePartialStates(t, pool,
+			&QuantileAggregation{Quantile: q},
+			&QuantileAggregation{Quantile: q, FinalStage: true},
+			values,
+		)
+		require.InDelta(t, q, v, 0.01, "quantile %v", q)
+	}
+}
+
+func TestVarianceAggregation(t *testing.T) {
+	pool := memory.NewCheckedAllocator(memory.NewGoAllocator())
+	defer pool.AssertSize(t, 0)
+
+	values := [][]float64{{2, 4, 4}, {}, {4, 5}, {5, 7, 9}}
+	v := mergePartialStates(t, pool, &VarianceAggregation{}, &VarianceAggregation{FinalStage: true}, values)
+	require.InDelta(t, 4, v, 1e-9)
+	v = mergePartialStates(t, pool, &VarianceAggregation{Stddev: true}, &VarianceAggregation{Stddev: true, FinalStage: true}, values)
+	require.InDelta(t, 2, v, 1e-9)
+}
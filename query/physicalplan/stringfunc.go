@@ -0,0 +1,223 @@
+package physicalplan
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/apache/arrow/go/v17/arrow"
+	"github.com/apache/arrow/go/v17/arrow/array"
+	"github.com/apache/arrow/go/v17/arrow/compute"
+	"github.com/apache/arrow/go/v17/arrow/memory"
+	"github.com/apache/arrow/go/v17/arrow/scalar"
+
+	"github.com/polarsignals/frostdb/query/logicalplan"
+)
+
+type stringFuncProjection struct {
+	expr *logicalplan.StringFuncExpr
+	args []columnProjection
+	fn   func(args []string) (string, bool)
+}
+
+func newStringFuncProjection(expr *logicalplan.StringFuncExpr) (stringFuncProjection, error) {
+	fn, err := stringFunc(expr)
+	if err != nil {
+		return stringFuncProjection{}, err
+	}
+	args := make([]columnProjection, 0, len(expr.Args))
+	for _, arg := range expr.StringArgs() {
+		p, err := projectionFromExpr(arg)
+		if err != nil {
+			return stringFuncProjection{}, fmt.Errorf("projection for %s argument: %w", expr.Func, err)
+		}
+		args = append(args, p)
+	}
+	return stringFuncProjection{
+		expr: expr,
+		args: args,
+		fn:   fn,
+	}, nil
+}
+
+func (p stringFuncProjection) Name() string {
+	return p.expr.Name()
+}
+
+func (p stringFuncProjection) String() string {
+	return p.expr.Name()
+}
+
+func (p stringFuncProjection) Project(mem memory.Allocator, ar arrow.Record) ([]arrow.Field, []arrow.Array, error) {
+	cols := make([]arrow.Array, 0, len(p.args))
+	defer func() {
+		for _, arr := range cols {
+			arr.Release()
+		}
+	}()
+	for _, arg := range p.args {
+		fields, arrs, err := arg.Project(mem, ar)
+		if err != nil {
+			return nil, nil, err
+		}
+		cols = append(cols, arrs...)
+		if len(fields) == 0 {
+			// The column is not part of the record.
+			return nil, nil, nil
+		}
+		if len(fields) != 1 || len(fields) != len(arrs) {
+			return nil, nil, fmt.Errorf("invalid projection for %s: expected 1 field and array, got %d fields %d arrays", p.expr.Func, len(fields), len(arrs))
+		}
+	}
+
+	res, err := evalStringFunc(mem, p.fn, cols)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%s: %w", p.expr.Func, err)
+	}
+	return []arrow.Field{{
+		Name:     p.expr.Name(),
+		Type:     res.DataType(),
+		Nullable: true,
+	}}, []arrow.Array{res}, nil
+}
+
+// evalStringFunc applies fn to the strings of the rows of cols, which must be
+// of equal length. A row is null if any of its strings is null or if fn
+// returns false.
+func evalStringFunc(mem memory.Allocator, fn func(args []string) (string, bool), cols []arrow.Array) (arrow.Array, error) {
+	if len(cols) == 1 {
+		if dict, ok := cols[0].(*array.Dictionary); ok {
+			// The function is applied to the distinct values of the
+			// dictionary only, which are then taken by the indices.
+			values, err := evalStringFunc(mem, fn, []arrow.Array{dict.Dictionary()})
+			if err != nil {
+				return nil, err
+			}
+			defer values.Release()
+			return compute.TakeArray(compute.WithAllocator(context.Background(), mem), values, dict.Indices())
+		}
+	}
+
+	values := make([]func(i int) string, 0, len(cols))
+	for _, col := range cols {
+		if col.Len() != cols[0].Len() {
+			return nil, fmt.Errorf("arguments must have the same length, got %d and %d", cols[0].Len(), col.Len())
+		}
+		v, err := stringValues(col)
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, v)
+	}
+
+	b := array.NewStringBuilder(mem)
+	defer b.Release()
+	b.Reserve(cols[0].Len())
+	args := make([]string, len(cols))
+rows:
+	for i := 0; i < cols[0].Len(); i++ {
+		for j, col := range cols {
+			if col.IsNull(i) {
+				b.AppendNull()
+				continue rows
+			}
+			args[j] = values[j](i)
+		}
+		if v, ok := fn(args); ok {
+			b.Append(v)
+		} else {
+			b.AppendNull()
+		}
+	}
+	return b.NewArray(), nil
+}
+
+// stringValues returns a function that returns the string of arr at an index.
+func stringValues(arr arrow.Array) (func(i int) string, error) {
+	switch a := arr.(type) {
+	case *array.String:
+		return a.Value, nil
+	case *array.Binary:
+		return a.ValueString, nil
+	case *array.Dictionary:
+		dict, err := stringValues(a.Dictionary())
+		if err != nil {
+			return nil, err
+		}
+		return func(i int) string { return dict(a.GetValueIndex(i)) }, nil
+	default:
+		return nil, fmt.Errorf("unsupported type %s, expected string", arr.DataType())
+	}
+}
+
+// stringFunc returns the function computing the result of expr from the
+// strings of its string arguments. It returns false if the result is null.
+func stringFunc(expr *logicalplan.StringFuncExpr) (func(args []string) (string, bool), error) {
+	if err := expr.ValidateArgs(); err != nil {
+		return nil, err
+	}
+	param := func(i int) scalar.Scalar {
+		return expr.Args[i].(*logicalplan.LiteralExpr).Value
+	}
+
+	switch expr.Func {
+	case logicalplan.StringFuncConcat:
+		return func(args []string) (string, bool) {
+			return strings.Join(args, ""), true
+		}, nil
+	case logicalplan.StringFuncLower:
+		return func(args []string) (string, bool) {
+			return strings.ToLower(args[0]), true
+		}, nil
+	case logicalplan.StringFuncUpper:
+		return func(args []string) (string, bool) {
+			return strings.ToUpper(args[0]), true
+		}, nil
+	case logicalplan.StringFuncTrimPrefix:
+		prefix := string(param(1).(*scalar.String).Data())
+		return func(args []string) (string, bool) {
+			return strings.TrimPrefix(args[0], prefix), true
+		}, nil
+	case logicalplan.StringFuncSubstring:
+		start := param(1).(*scalar.Int64).Value - 1
+		length := param(2).(*scalar.Int64).Value
+		return func(args []string) (string, bool) {
+			return substring(args[0], start, length), true
+		}, nil
+	case logicalplan.StringFuncRegexExtract:
+		re, err := regexp.Compile(string(param(1).(*scalar.String).Data()))
+		if err != nil {
+			return nil, err
+		}
+		group := int(param(2).(*scalar.Int64).Value)
+		return func(args []string) (string, bool) {
+			m := re.FindStringSubmatchIndex(args[0])
+			if m == nil || m[2*group] < 0 {
+				return "", false
+			}
+			return args[0][m[2*group]:m[2*group+1]], true
+		}, nil
+	default:
+		return nil, fmt.Errorf("unsupported string function %s", expr.Func)
+	}
+}
+
+// substring returns at most length characters of s starting at the character
+// at the zero-based start.
+func substring(s string, start, length int64) string {
+	var (
+		n     int64
+		begin = len(s)
+	)
+	for i := range s {
+		if n == start {
+			begin = i
+		}
+		if n == start+length {
+			return s[begin:i]
+		}
+		n++
+	}
+	return s[begin:]
+}
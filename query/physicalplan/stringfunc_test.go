@@ -0,0 +1,129 @@
+package physicalplan
+
+import (
+	"testing"
+
+	"github.com/apache/arrow/go/v17/arrow"
+	"github.com/apache/arrow/go/v17/arrow/array"
+	"github.com/apache/arrow/go/v17/arrow/memory"
+	"github.com/stretchr/testify/require"
+
+	"github.com/polarsignals/frostdb/query/logicalplan"
+)
+
+func TestStringFuncs(t *testing.T) {
+	mem := memory.NewCheckedAllocator(memory.DefaultAllocator)
+	defer mem.AssertSize(t, 0)
+
+	schema := arrow.NewSchema([]arrow.Field{
+		{Name: "job", Type: &arrow.DictionaryType{IndexType: arrow.PrimitiveTypes.Uint32, ValueType: arrow.BinaryTypes.Binary}, Nullable: true},
+		{Name: "instance", Type: arrow.BinaryTypes.String, Nullable: true},
+	}, nil)
+	rb := array.NewRecordBuilder(mem, schema)
+	defer rb.Release()
+	job := rb.Field(0).(*array.BinaryDictionaryBuilder)
+	for _, v := range []string{"K8s/API", "k8s/Db", "K8s/API"} {
+		require.NoError(t, job.AppendString(v))
+	}
+	job.AppendNull()
+	rb.Field(1).(*array.StringBuilder).AppendValues([]string{"host-1:8080", "héllo", "", "x"}, []bool{true, true, false, true})
+	r := rb.NewRecord()
+	defer r.Release()
+
+	for _, tc := range []struct {
+		name     string
+		expr     logicalplan.Expr
+		expected []*string
+	}{{
+		name:     "Lower",
+		expr:     logicalplan.Lower(logicalplan.Col("job")),
+		expected: nullableStrings("k8s/api", "k8s/db", "k8s/api", nil),
+	}, {
+		name:     "Upper",
+		expr:     logicalplan.Upper(logicalplan.Col("instance")),
+		expected: nullableStrings("HOST-1:8080", "HÉLLO", nil, "X"),
+	}, {
+		name:     "TrimPrefix",
+		expr:     logicalplan.TrimPrefix(logicalplan.Lower(logicalplan.Col("job")), "k8s/"),
+		expected: nullableStrings("api", "db", "api", nil),
+	}, {
+		name:     "Substring",
+		expr:     logicalplan.Substring(logicalplan.Col("instance"), 2, 3),
+		expected: nullableStrings("ost", "éll", nil, ""),
+	}, {
+		name:     "RegexExtract",
+		expr:     logicalplan.RegexExtract(logicalplan.Col("instance"), `:(\d+)$`, 1),
+		expected: nullableStrings("8080", nil, nil, nil),
+	}, {
+		name:     "Concat",
+		expr:     logicalplan.Concat(logicalplan.Col("job"), logicalplan.Literal("@"), logicalplan.Col("instance")),
+		expected: nullableStrings("K8s/API@host-1:8080", "k8s/Db@héllo", nil, nil),
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			p, err := projectionFromExpr(tc.expr)
+			require.NoError(t, err)
+			fields, cols, err := p.Project(mem, r)
+			require.NoError(t, err)
+			require.Len(t, cols, 1)
+			defer cols[0].Release()
+			require.Equal(t, tc.expr.Name(), fields[0].Name)
+
+			res := cols[0].(*array.String)
+			require.Equal(t, len(tc.expected), res.Len())
+			for i, v := range tc.expected {
+				if v == nil {
+					require.True(t, res.IsNull(i), "row %d", i)
+					continue
+				}
+				require.Equal(t, *v, res.Value(i), "row %d", i)
+			}
+		})
+	}
+
+	t.Run("Filter", func(t *testing.T) {
+		f, err := booleanExpr(logicalplan.Lower(logicalplan.Col("job")).Eq(logicalplan.Literal("k8s/api")))
+		require.NoError(t, err)
+		bm, err := f.Eval(r)
+		require.NoError(t, err)
+		require.Equal(t, []uint32{0, 2}, bm.ToArray())
+
+		f, err = booleanExpr(logicalplan.TrimPrefix(logicalplan.Col("instance"), "host-").RegexMatch("^[0-9]"))
+		require.NoError(t, err)
+		bm, err = f.Eval(r)
+		require.NoError(t, err)
+		require.Equal(t, []uint32{0}, bm.ToArray())
+
+		// Functions of missing columns behave like missing columns.
+		f, err = booleanExpr(logicalplan.Lower(logicalplan.Col("missing")).Eq(logicalplan.Literal("a")))
+		require.NoError(t, err)
+		bm, err = f.Eval(r)
+		require.NoError(t, err)
+		require.True(t, bm.IsEmpty())
+	})
+
+	t.Run("InvalidArgs", func(t *testing.T) {
+		for _, expr := range []logicalplan.Expr{
+			logicalplan.Concat(),
+			logicalplan.Substring(logicalplan.Col("instance"), 0, 1),
+			logicalplan.RegexExtract(logicalplan.Col("instance"), "(", 0),
+			logicalplan.RegexExtract(logicalplan.Col("instance"), "a", 1),
+			&logicalplan.StringFuncExpr{Func: logicalplan.StringFuncLower, Args: []logicalplan.Expr{logicalplan.Col("job"), logicalplan.Col("instance")}},
+		} {
+			_, err := projectionFromExpr(expr)
+			require.Error(t, err, expr.String())
+		}
+	})
+}
+
+func nullableStrings(vs ...any) []*string {
+	res := make([]*string, 0, len(vs))
+	for _, v := range vs {
+		if v == nil {
+			res = append(res, nil)
+			continue
+		}
+		s := v.(string)
+		res = append(res, &s)
+	}
+	return res
+}
@@ -15,6 +15,7 @@ import (
 // stages have finished.
 type Synchronizer struct {
 	next    PhysicalPlan
+	stats   StatsCollector
 	nextMtx sync.Mutex
 	running *atomic.Int64
 	open    *atomic.Int64
@@ -29,6 +30,7 @@ func Synchronize(concurrency int) *Synchronizer {
 }
 
 func (m *Synchronizer) Callback(ctx context.Context, r arrow.Record) error {
+	m.stats.Observe(r)
 	// multiple threads can emit the results to the next step, but they will do
 	// it synchronously
 	m.nextMtx.Lock()
@@ -64,6 +66,12 @@ func (m *Synchronizer) Draw() *Diagram {
 	return &Diagram{Details: "Synchronizer", Child: m.next.Draw()}
 }
 
+func (m *Synchronizer) Name() string { return "Synchronizer" }
+
+func (m *Synchronizer) Children() []Operator { return nextOperators(m.next) }
+
+func (m *Synchronizer) Stats() OperatorStats { return m.stats.Stats() }
+
 func (m *Synchronizer) Close() {
 	open := m.open.Add(-1)
 	if open < 0 {
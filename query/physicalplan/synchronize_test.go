@@ -82,3 +82,54 @@ func (m *mockTableScan) Execute(ctx context.Context, pool memory.Allocator) erro
 func (m *mockTableScan) Draw() *Diagram {
 	return &Diagram{}
 }
+
+func (m *mockTableScan) Name() string { return "mockTableScan" }
+
+func (m *mockTableScan) Children() []Operator { return planOperators(m.plans) }
+
+func (m *mockTableScan) Stats() OperatorStats { return OperatorStats{} }
+
+func TestWalkOperators(t *testing.T) {
+	concurrency := 4
+	plans := make([]PhysicalPlan, 0, concurrency)
+	for i := 0; i < concurrency; i++ {
+		plans = append(plans, &noopOperator{})
+	}
+	op := &OutputPlan{
+		scan: &mockTableScan{plans: plans},
+	}
+
+	synchronizer := Synchronize(len(plans))
+	synchronizer.SetNext(op)
+	for _, p := range plans {
+		p.SetNext(synchronizer)
+	}
+
+	err := op.Execute(
+		context.Background(),
+		memory.NewGoAllocator(),
+		func(_ context.Context, _ arrow.Record) error { return nil },
+	)
+	require.NoError(t, err)
+
+	// The synchronizer and the output are visited once, although they are
+	// children of all concurrent operators.
+	var names []string
+	stats := map[string]OperatorStats{}
+	op.Walk(func(o Operator) bool {
+		names = append(names, o.Name())
+		stats[o.Name()] = o.Stats()
+		return true
+	})
+	require.Equal(t, []string{"mockTableScan", "Noop", "Synchronizer", "Output", "Noop", "Noop", "Noop"}, names)
+	require.Equal(t, OperatorStats{Records: 40, Rows: 40}, stats["Synchronizer"])
+	require.Equal(t, OperatorStats{Records: 40, Rows: 40}, stats["Output"])
+
+	// Children are not visited if fn returns false.
+	names = names[:0]
+	op.Walk(func(o Operator) bool {
+		names = append(names, o.Name())
+		return false
+	})
+	require.Equal(t, []string{"mockTableScan"}, names)
+}
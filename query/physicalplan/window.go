@@ -0,0 +1,158 @@
+package physicalplan
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/apache/arrow/go/v17/arrow"
+	"github.com/apache/arrow/go/v17/arrow/array"
+	"github.com/apache/arrow/go/v17/arrow/compute"
+	"github.com/apache/arrow/go/v17/arrow/memory"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/polarsignals/frostdb/pqarrow/arrowutils"
+	"github.com/polarsignals/frostdb/query/logicalplan"
+)
+
+// Window assigns the rows it receives to the windows of a window expression.
+// Each row is emitted once for every window containing it, with an additional
+// column named after the expression that holds the start of the window, so
+// that a subsequent aggregation grouping by the expression emits one row per
+// group and window. Rows with a null timestamp are not part of any window and
+// are dropped.
+type Window struct {
+	pool   memory.Allocator
+	tracer trace.Tracer
+	expr   *logicalplan.WindowExpr
+	p      columnProjection
+	next   PhysicalPlan
+	stats  StatsCollector
+}
+
+func NewWindow(pool memory.Allocator, tracer trace.Tracer, expr *logicalplan.WindowExpr) (*Window, error) {
+	p, err := projectionFromExpr(expr.Expr)
+	if err != nil {
+		return nil, fmt.Errorf("projection for window: %w", err)
+	}
+	return &Window{
+		pool:   pool,
+		tracer: tracer,
+		expr:   expr,
+		p:      p,
+	}, nil
+}
+
+func (w *Window) SetNext(next PhysicalPlan) { w.next = next }
+
+func (w *Window) Finish(ctx context.Context) error { return w.next.Finish(ctx) }
+
+func (w *Window) Close() { w.next.Close() }
+
+func (w *Window) Draw() *Diagram {
+	var child *Diagram
+	if w.next != nil {
+		child = w.next.Draw()
+	}
+	return &Diagram{Details: "Window (" + w.expr.String() + ")", Child: child}
+}
+
+func (w *Window) Name() string { return "Window" }
+
+func (w *Window) Children() []Operator { return nextOperators(w.next) }
+
+func (w *Window) Stats() OperatorStats { return w.stats.Stats() }
+
+func (w *Window) Callback(ctx context.Context, r arrow.Record) error {
+	w.stats.Observe(r)
+	_, cols, err := w.p.Project(w.pool, r)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		for _, arr := range cols {
+			arr.Release()
+		}
+	}()
+	if len(cols) != 1 {
+		return fmt.Errorf("invalid projection for window: expected 1 array, got %d", len(cols))
+	}
+
+	var (
+		values []int64
+		unit   arrow.TimeUnit
+	)
+	switch c := cols[0].(type) {
+	case *array.Int64:
+		values = c.Int64Values()
+		unit = w.expr.Precision
+	case *array.Timestamp:
+		values = make([]int64, c.Len())
+		for i, v := range c.TimestampValues() {
+			values[i] = int64(v)
+		}
+		unit = c.DataType().(*arrow.TimestampType).Unit
+	default:
+		return fmt.Errorf("window: unsupported type %s, expected int64 or timestamp", cols[0].DataType())
+	}
+
+	size := int64(w.expr.Size / unit.Multiplier())
+	step := int64(w.expr.Step / unit.Multiplier())
+	if size <= 0 || step <= 0 {
+		return fmt.Errorf("window: size %s and step %s must be at least one %s", w.expr.Size, w.expr.Step, unit)
+	}
+
+	rows := array.NewInt32Builder(w.pool)
+	defer rows.Release()
+	starts := make([]int64, 0, len(values))
+	for i, ts := range values {
+		if cols[0].IsNull(i) {
+			continue
+		}
+		// The last window containing the timestamp starts at the timestamp
+		// rounded down to a multiple of the step.
+		last := ts - ts%step
+		if ts%step < 0 {
+			last -= step
+		}
+		for start := last; start > ts-size; start -= step {
+			rows.Append(int32(i))
+			starts = append(starts, start)
+		}
+	}
+	indices := rows.NewInt32Array()
+	defer indices.Release()
+
+	taken, err := arrowutils.Take(compute.WithAllocator(ctx, w.pool), r, indices)
+	if err != nil {
+		return fmt.Errorf("take windowed rows: %w", err)
+	}
+	defer taken.Release()
+
+	var startArr arrow.Array
+	switch typ := cols[0].DataType().(type) {
+	case *arrow.TimestampType:
+		b := array.NewTimestampBuilder(w.pool, typ)
+		defer b.Release()
+		for _, start := range starts {
+			b.Append(arrow.Timestamp(start))
+		}
+		startArr = b.NewArray()
+	default:
+		b := array.NewInt64Builder(w.pool)
+		defer b.Release()
+		b.AppendValues(starts, nil)
+		startArr = b.NewArray()
+	}
+	defer startArr.Release()
+
+	fields := make([]arrow.Field, 0, r.NumCols()+1)
+	fields = append(fields, r.Schema().Fields()...)
+	fields = append(fields, arrow.Field{Name: w.expr.Name(), Type: startArr.DataType()})
+	columns := make([]arrow.Array, 0, len(fields))
+	columns = append(columns, taken.Columns()...)
+	columns = append(columns, startArr)
+
+	windowed := array.NewRecord(arrow.NewSchema(fields, nil), columns, int64(len(starts)))
+	defer windowed.Release()
+	return w.next.Callback(ctx, windowed)
+}
@@ -0,0 +1,284 @@
+package query
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/apache/arrow/go/v17/arrow"
+	"github.com/apache/arrow/go/v17/arrow/array"
+
+	"github.com/polarsignals/frostdb/internal/records"
+)
+
+// Rows executes the query of builder and returns its result rows mapped to
+// values of type T, which must be a struct or a pointer to a struct.
+//
+// Columns are mapped to the exported fields of T by name. Like for
+// frostdb.GenericTable, the name of a field is the first item of its `frostdb`
+// struct tag, or else its snake cased Go name, and fields tagged with "-" are
+// ignored. A field of type map[string]V receives the concrete columns of the
+// dynamic column of its name, keyed by the column name without the dynamic
+// column prefix, e.g. "labels.job" is stored as "job" in the field "labels".
+// Fields without a column in a result record, and fields of null values, keep
+// their zero value, and dynamic fields are only allocated if they receive a
+// value.
+//
+// Numeric values are converted to the type of their field like by a Go
+// conversion, strings and binary values can be stored in string and []byte
+// fields, timestamps in time.Time fields, lists in slices and any value in a
+// pointer to its field type.
+func Rows[T any](ctx context.Context, builder Builder) ([]T, error) {
+	var rows []T
+	if err := EachRow(ctx, builder, func(row T) error {
+		rows = append(rows, row)
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	return rows, nil
+}
+
+// EachRow is like Rows, but calls fn with each row as the results of the query
+// are produced instead of collecting them. Returning an error from fn stops
+// the query and EachRow returns the error.
+func EachRow[T any](ctx context.Context, builder Builder, fn func(T) error) error {
+	m, err := newRowMapper[T]()
+	if err != nil {
+		return err
+	}
+	return builder.Execute(ctx, func(_ context.Context, r arrow.Record) error {
+		return m.mapRecord(r, fn)
+	})
+}
+
+type rowMapper[T any] struct {
+	// typ is the struct type rows are mapped to. ptr is set if T is a
+	// pointer to it.
+	typ    reflect.Type
+	ptr    bool
+	fields []rowField
+}
+
+type rowField struct {
+	index   int
+	name    string
+	dynamic bool
+}
+
+func newRowMapper[T any]() (*rowMapper[T], error) {
+	typ := reflect.TypeOf((*T)(nil)).Elem()
+	m := &rowMapper[T]{}
+	if typ.Kind() == reflect.Ptr {
+		m.ptr = true
+		typ = typ.Elem()
+	}
+	if typ.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("rows: %s is not a struct or a pointer to a struct", reflect.TypeOf((*T)(nil)).Elem())
+	}
+	m.typ = typ
+
+	for i := 0; i < typ.NumField(); i++ {
+		f := typ.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		tag := f.Tag.Get(records.TagName)
+		if tag == "-" {
+			continue
+		}
+		name, _, _ := strings.Cut(tag, ",")
+		if name == "" {
+			name = records.ToSnakeCase(f.Name)
+		}
+		m.fields = append(m.fields, rowField{
+			index:   i,
+			name:    name,
+			dynamic: f.Type.Kind() == reflect.Map && f.Type.Key().Kind() == reflect.String,
+		})
+	}
+	return m, nil
+}
+
+func (m *rowMapper[T]) mapRecord(r arrow.Record, fn func(T) error) error {
+	schema := r.Schema()
+
+	// columns are the indices of the columns of each field, and keys are the
+	// map keys of the concrete columns of dynamic fields.
+	columns := make([][]int, len(m.fields))
+	keys := make([][]string, len(m.fields))
+	for i, f := range m.fields {
+		for j, field := range schema.Fields() {
+			if !f.dynamic {
+				if field.Name == f.name {
+					columns[i] = append(columns[i], j)
+					break
+				}
+				continue
+			}
+			if key, ok := strings.CutPrefix(field.Name, f.name+"."); ok {
+				columns[i] = append(columns[i], j)
+				keys[i] = append(keys[i], key)
+			}
+		}
+	}
+
+	for row := 0; row < int(r.NumRows()); row++ {
+		v := reflect.New(m.typ)
+		for i, f := range m.fields {
+			dst := v.Elem().Field(f.index)
+			for k, col := range columns[i] {
+				val := columnValue(r.Column(col), row)
+				if val == nil {
+					continue
+				}
+				if !f.dynamic {
+					if err := assign(dst, val); err != nil {
+						return fmt.Errorf("rows: column %s: %w", schema.Field(col).Name, err)
+					}
+					continue
+				}
+
+				if dst.IsNil() {
+					dst.Set(reflect.MakeMap(dst.Type()))
+				}
+				elem := reflect.New(dst.Type().Elem()).Elem()
+				if err := assign(elem, val); err != nil {
+					return fmt.Errorf("rows: column %s: %w", schema.Field(col).Name, err)
+				}
+				dst.SetMapIndex(reflect.ValueOf(keys[i][k]).Convert(dst.Type().Key()), elem)
+			}
+		}
+
+		if !m.ptr {
+			v = v.Elem()
+		}
+		if err := fn(v.Interface().(T)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// columnValue returns the value at index i of arr, or nil if it is null.
+// Values that reference the memory of arr are copied, since records are
+// released once they are mapped.
+func columnValue(arr arrow.Array, i int) any {
+	if arr.IsNull(i) {
+		return nil
+	}
+
+	switch a := arr.(type) {
+	case *array.Dictionary:
+		return columnValue(a.Dictionary(), a.GetValueIndex(i))
+	case *array.Boolean:
+		return a.Value(i)
+	case *array.Int8:
+		return a.Value(i)
+	case *array.Int16:
+		return a.Value(i)
+	case *array.Int32:
+		return a.Value(i)
+	case *array.Int64:
+		return a.Value(i)
+	case *array.Uint8:
+		return a.Value(i)
+	case *array.Uint16:
+		return a.Value(i)
+	case *array.Uint32:
+		return a.Value(i)
+	case *array.Uint64:
+		return a.Value(i)
+	case *array.Float32:
+		return a.Value(i)
+	case *array.Float64:
+		return a.Value(i)
+	case *array.String:
+		return strings.Clone(a.Value(i))
+	case *array.Binary:
+		return bytes.Clone(a.Value(i))
+	case *array.FixedSizeBinary:
+		return bytes.Clone(a.Value(i))
+	case *array.Timestamp:
+		return a.Value(i).ToTime(a.DataType().(*arrow.TimestampType).Unit)
+	case *array.List:
+		start, end := a.ValueOffsets(i)
+		values := make([]any, 0, end-start)
+		for j := start; j < end; j++ {
+			values = append(values, columnValue(a.ListValues(), int(j)))
+		}
+		return values
+	default:
+		return a.GetOneForMarshal(i)
+	}
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// assign stores the value v in dst, converting it to the type of dst.
+func assign(dst reflect.Value, v any) error {
+	if v == nil {
+		return nil
+	}
+
+	if dst.Kind() == reflect.Ptr {
+		p := reflect.New(dst.Type().Elem())
+		if err := assign(p.Elem(), v); err != nil {
+			return err
+		}
+		dst.Set(p)
+		return nil
+	}
+
+	if values, ok := v.([]any); ok {
+		if dst.Kind() != reflect.Slice {
+			return fmt.Errorf("cannot store list in field of type %s", dst.Type())
+		}
+		s := reflect.MakeSlice(dst.Type(), len(values), len(values))
+		for i, e := range values {
+			if err := assign(s.Index(i), e); err != nil {
+				return err
+			}
+		}
+		dst.Set(s)
+		return nil
+	}
+
+	src := reflect.ValueOf(v)
+	switch {
+	case src.Type().AssignableTo(dst.Type()):
+		dst.Set(src)
+	case isNumeric(src.Kind()) && isNumeric(dst.Kind()),
+		isText(src.Type()) && isText(dst.Type()):
+		dst.Set(src.Convert(dst.Type()))
+	case src.Kind() == reflect.Slice && dst.Kind() == reflect.Array &&
+		dst.Type().Elem().Kind() == reflect.Uint8 && src.Len() == dst.Len():
+		// Fixed size binary values, e.g. UUIDs.
+		reflect.Copy(dst, src)
+	case src.Type() == timeType && dst.Type().ConvertibleTo(timeType):
+		dst.Set(src.Convert(dst.Type()))
+	default:
+		return fmt.Errorf("cannot store %T in field of type %s", v, dst.Type())
+	}
+	return nil
+}
+
+func isNumeric(k reflect.Kind) bool {
+	switch k {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return true
+	default:
+		return false
+	}
+}
+
+// isText returns whether typ is a string or a byte slice type.
+func isText(typ reflect.Type) bool {
+	return typ.Kind() == reflect.String ||
+		(typ.Kind() == reflect.Slice && typ.Elem().Kind() == reflect.Uint8)
+}
@@ -0,0 +1,68 @@
+package query
+
+import (
+	"context"
+	"testing"
+
+	"github.com/apache/arrow/go/v17/arrow"
+	"github.com/apache/arrow/go/v17/arrow/memory"
+	"github.com/stretchr/testify/require"
+
+	"github.com/polarsignals/frostdb/dynparquet"
+	"github.com/polarsignals/frostdb/query/logicalplan"
+)
+
+func TestRows(t *testing.T) {
+	mem := memory.NewCheckedAllocator(memory.NewGoAllocator())
+	defer mem.AssertSize(t, 0)
+
+	samples := dynparquet.NewTestSamples()
+	r, err := samples.ToRecord()
+	require.NoError(t, err)
+	defer r.Release()
+
+	engine := NewEngine(mem, &FakeTableProvider{
+		Tables: map[string]logicalplan.TableReader{
+			"test": &FakeTableReader{
+				FrostdbSchema: dynparquet.NewSampleSchema(),
+				Records:       []arrow.Record{r},
+			},
+		},
+	})
+
+	type sample struct {
+		Type      string            `frostdb:"example_type"`
+		Labels    map[string]string `frostdb:"labels,rle_dict"`
+		Timestamp *int64
+		Value     float64
+		Missing   int64
+		Ignored   string `frostdb:"-"`
+	}
+	rows, err := Rows[sample](context.Background(), engine.ScanTable("test"))
+	require.NoError(t, err)
+	expected := make([]sample, 0, len(samples))
+	for _, s := range samples {
+		expected = append(expected, sample{
+			Type:      s.ExampleType,
+			Labels:    s.Labels,
+			Timestamp: &s.Timestamp,
+			Value:     float64(s.Value),
+		})
+	}
+	require.Equal(t, expected, rows)
+
+	ptrs, err := Rows[*sample](context.Background(), engine.ScanTable("test"))
+	require.NoError(t, err)
+	require.Len(t, ptrs, len(samples))
+	require.Equal(t, expected[0], *ptrs[0])
+
+	type invalid struct {
+		Labels int64
+		Value  bool
+	}
+	_, err = Rows[invalid](context.Background(), engine.ScanTable("test"))
+	require.ErrorContains(t, err, "cannot store int64 in field of type bool")
+
+	_, err = Rows[int64](context.Background(), engine.ScanTable("test"))
+	require.Error(t, err)
+}
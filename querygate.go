@@ -0,0 +1,87 @@
+package frostdb
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/polarsignals/frostdb/internal/limiter"
+)
+
+// WithQueryConcurrency limits the number of queries that execute at the same
+// time across all databases of the column store. Queries past the limit wait
+// in FIFO order until a running query completes or their context is done, so
+// bursts of queries are served in the order they arrived instead of
+// competing for memory all at once. A limit of 0, the default, is unlimited.
+//
+// Each query takes one slot, no matter how many tables it reads. The limit
+// holds for all queries, including those of query engines configured with
+// query.WithMaxConcurrentQueries, which take a slot of the engine first and a
+// slot of the store second. Since slots are always taken in this order, a
+// query never waits for a slot held by a query waiting for its own.
+func WithQueryConcurrency(limit int) Option {
+	return func(s *ColumnStore) error {
+		if limit < 0 {
+			return fmt.Errorf("query concurrency must not be negative: %d", limit)
+		}
+		s.queryConcurrency = limit
+		return nil
+	}
+}
+
+type queryGateMetrics struct {
+	queueDuration prometheus.Observer
+	queued        prometheus.Gauge
+	running       prometheus.Gauge
+}
+
+// queryGate limits the number of queries executing at the same time. Queries
+// that can't start right away wait in a FIFO queue.
+type queryGate struct {
+	// limiter is nil if the number of queries is not limited.
+	limiter *limiter.Limiter
+	metrics queryGateMetrics
+}
+
+func newQueryGate(limit int, metrics queryGateMetrics) *queryGate {
+	g := &queryGate{metrics: metrics}
+	if limit > 0 {
+		g.limiter = limiter.New(limit)
+	}
+	return g
+}
+
+// acquire waits until the query may execute. It returns the context to
+// execute the query with and a function that must be called once the query
+// completed. Queries that are already admitted by the gate, e.g. subqueries,
+// are not limited again.
+func (g *queryGate) acquire(ctx context.Context) (context.Context, func(), error) {
+	if g.limiter == nil || g.limiter.Admitted(ctx) {
+		return ctx, func() {}, nil
+	}
+
+	start := time.Now()
+	release, ok := g.limiter.TryAcquire()
+	if !ok {
+		g.metrics.queued.Inc()
+		var err error
+		release, err = g.limiter.Acquire(ctx)
+		g.metrics.queued.Dec()
+		if err != nil {
+			return ctx, nil, fmt.Errorf("wait for query slot: %w", err)
+		}
+	}
+
+	g.metrics.queueDuration.Observe(time.Since(start).Seconds())
+	g.metrics.running.Inc()
+	var once sync.Once
+	return g.limiter.WithAdmitted(ctx), func() {
+		once.Do(func() {
+			g.metrics.running.Dec()
+			release()
+		})
+	}, nil
+}
@@ -0,0 +1,104 @@
+package frostdb
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/apache/arrow/go/v17/arrow"
+	"github.com/apache/arrow/go/v17/arrow/memory"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/sync/errgroup"
+
+	"github.com/polarsignals/frostdb/dynparquet"
+	"github.com/polarsignals/frostdb/internal/limiter"
+	"github.com/polarsignals/frostdb/query"
+)
+
+func TestQueryGate(t *testing.T) {
+	ctx := context.Background()
+	metrics := makeAndRegisterGlobalMetrics(prometheus.NewRegistry()).queryMetrics
+	g := newQueryGate(1, metrics)
+
+	admittedCtx, release, err := g.acquire(ctx)
+	require.NoError(t, err)
+
+	// Subqueries of an admitted query are not limited.
+	_, releaseSubquery, err := g.acquire(admittedCtx)
+	require.NoError(t, err)
+	releaseSubquery()
+
+	// Queries admitted by another limiter, e.g. of a query engine, are
+	// limited.
+	engineCtx, cancel := context.WithTimeout(limiter.New(1).WithAdmitted(ctx), time.Millisecond)
+	defer cancel()
+	_, _, err = g.acquire(engineCtx)
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+
+	// Queries past the limit are admitted in the order they arrived.
+	order := make(chan int, 2)
+	for i := 0; i < 2; i++ {
+		go func(i int) {
+			_, release, err := g.acquire(ctx)
+			if err != nil {
+				order <- -1
+				return
+			}
+			order <- i
+			release()
+		}(i)
+		require.Eventually(t, func() bool {
+			return testutil.ToFloat64(metrics.queued) == float64(i+1)
+		}, time.Second, time.Millisecond)
+	}
+
+	// Waiting stops when the context is done.
+	canceledCtx, cancelWait := context.WithTimeout(ctx, time.Millisecond)
+	defer cancelWait()
+	_, _, err = g.acquire(canceledCtx)
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+	require.Equal(t, float64(2), testutil.ToFloat64(metrics.queued))
+
+	release()
+	// Releasing twice does not free another slot.
+	release()
+	require.Equal(t, 0, <-order)
+	require.Equal(t, 1, <-order)
+
+	require.Eventually(t, func() bool {
+		return testutil.ToFloat64(metrics.running) == 0
+	}, time.Second, time.Millisecond)
+	require.Zero(t, testutil.ToFloat64(metrics.queued))
+	require.Zero(t, g.limiter.Running())
+}
+
+func TestQueryConcurrencyEngineLimit(t *testing.T) {
+	c, db, table := openTestTable(t, []Option{WithQueryConcurrency(2)})
+	defer c.Close()
+	insertSamples(t, table, dynparquet.GenerateTestSamples(10))
+
+	// The limit of the store holds for queries of an engine with a larger
+	// limit.
+	engine := query.NewEngine(memory.DefaultAllocator, db.TableProvider(), query.WithMaxConcurrentQueries(100))
+	var (
+		mtx        sync.Mutex
+		maxRunning int
+	)
+	errg, ctx := errgroup.WithContext(context.Background())
+	for i := 0; i < 8; i++ {
+		errg.Go(func() error {
+			return engine.ScanTable("test").Execute(ctx, func(context.Context, arrow.Record) error {
+				mtx.Lock()
+				maxRunning = max(maxRunning, c.queryGate.limiter.Running())
+				mtx.Unlock()
+				time.Sleep(10 * time.Millisecond)
+				return nil
+			})
+		})
+	}
+	require.NoError(t, errg.Wait())
+	require.Equal(t, 2, maxRunning)
+}
@@ -0,0 +1,294 @@
+package frostdb
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"path/filepath"
+
+	"github.com/go-kit/log/level"
+
+	tablepb "github.com/polarsignals/frostdb/gen/proto/go/frostdb/table/v1alpha1"
+	walpkg "github.com/polarsignals/frostdb/wal"
+)
+
+// StorageUsageReporter is implemented by data sinks that can report the number
+// of bytes of persisted blocks, so that storage quotas also account for blocks
+// persisted before the table was opened.
+type StorageUsageReporter interface {
+	// StorageUsage returns the total size in bytes of all blocks under
+	// prefix.
+	StorageUsage(ctx context.Context, prefix string) (int64, error)
+}
+
+// StorageUsage implements the StorageUsageReporter interface.
+func (b *DefaultObjstoreBucket) StorageUsage(ctx context.Context, prefix string) (int64, error) {
+	var size int64
+	if err := b.iterBlocks(ctx, prefix, func(blockDir string) error {
+		attrs, err := b.Attributes(ctx, filepath.Join(blockDir, "data.parquet"))
+		if err != nil {
+			if b.IsObjNotFoundErr(err) {
+				return nil
+			}
+			return err
+		}
+		size += attrs.Size
+		return nil
+	}); err != nil {
+		return 0, err
+	}
+	return size, nil
+}
+
+// TableStats describes the storage usage of a table.
+type TableStats struct {
+	// PersistedBytes is the size in bytes of the persisted blocks of the
+	// table.
+	PersistedBytes int64
+	// ActiveMemoryBytes is the size in bytes of the in-memory data of the
+	// table, including blocks that are still being persisted.
+	ActiveMemoryBytes int64
+	// WALBytes is the size in bytes of the inserts into the in-memory blocks
+	// of the table that are logged to the WAL and not yet covered by a
+	// snapshot. It is zero if the WAL is disabled.
+	WALBytes int64
+}
+
+// Stats returns the storage usage of the table. Blocks persisted before the
+// table was opened are only accounted for once RefreshStorageUsage was called,
+// which inserts into tables with a storage quota do implicitly.
+func (t *Table) Stats() TableStats {
+	t.mtx.RLock()
+	defer t.mtx.RUnlock()
+
+	_, walDisabled := unwrapWAL(t.wal).(*walpkg.NopWAL)
+	stats := TableStats{PersistedBytes: t.persistedBytes.Load()}
+	add := func(block *TableBlock) {
+		stats.ActiveMemoryBytes += block.Size()
+		if !walDisabled {
+			stats.WALBytes += block.uncompressedInsertsSize.Load() - block.lastSnapshotSize.Load()
+		}
+	}
+	if t.active != nil {
+		add(t.active)
+	}
+	for block := range t.pendingBlocks {
+		add(block)
+	}
+	return stats
+}
+
+// RefreshStorageUsage recomputes the number of persisted bytes of the table
+// from the data sinks that implement StorageUsageReporter.
+func (t *Table) RefreshStorageUsage(ctx context.Context) error {
+	prefix := filepath.Join(t.db.name, t.name)
+	var size int64
+	for _, sink := range t.db.sinks {
+		reporter, ok := sink.(StorageUsageReporter)
+		if !ok {
+			continue
+		}
+		n, err := reporter.StorageUsage(ctx, prefix)
+		if err != nil {
+			return fmt.Errorf("storage usage of %s: %w", sink, err)
+		}
+		size += n
+	}
+	t.persistedBytes.Store(size)
+	return nil
+}
+
+// loadStorageUsage loads the number of persisted bytes of the table from the
+// data sinks, unless it was loaded before.
+func (t *Table) loadStorageUsage(ctx context.Context) {
+	t.storageUsageOnce.Do(func() {
+		if err := t.RefreshStorageUsage(ctx); err != nil {
+			level.Warn(t.logger).Log("msg", "failed to load storage usage, only blocks persisted from now on are accounted for", "table", t.name, "err", err)
+		}
+	})
+}
+
+// Quota limits the resources used by a table, see WithQuota, or by all tables
+// of a database, see WithDBQuota. A zero limit disables the quota of the
+// resource.
+type Quota struct {
+	// ActiveMemoryBytes limits the size in bytes of the in-memory data,
+	// including blocks that are still being persisted.
+	ActiveMemoryBytes int64
+	// BucketBytes limits the size in bytes of the persisted blocks.
+	BucketBytes int64
+	// WALBytes limits the size in bytes of the inserts logged to the WAL that
+	// are not yet covered by a snapshot, see TableStats.WALBytes.
+	WALBytes int64
+	// StorageBytes limits the sum of the sizes in bytes of the persisted
+	// blocks and the in-memory data, see WithStorageQuota.
+	StorageBytes int64
+}
+
+func (q Quota) validate() error {
+	if q.ActiveMemoryBytes < 0 || q.BucketBytes < 0 || q.WALBytes < 0 || q.StorageBytes < 0 {
+		return fmt.Errorf("quota must not be negative: %+v", q)
+	}
+	return nil
+}
+
+// exceeded returns the first resource whose usage in stats exceeds the quota,
+// along with its quota and usage.
+func (q Quota) exceeded(stats TableStats) (QuotaResource, int64, int64, bool) {
+	switch {
+	case q.ActiveMemoryBytes != 0 && stats.ActiveMemoryBytes > q.ActiveMemoryBytes:
+		return QuotaActiveMemory, q.ActiveMemoryBytes, stats.ActiveMemoryBytes, true
+	case q.BucketBytes != 0 && stats.PersistedBytes > q.BucketBytes:
+		return QuotaBucket, q.BucketBytes, stats.PersistedBytes, true
+	case q.WALBytes != 0 && stats.WALBytes > q.WALBytes:
+		return QuotaWAL, q.WALBytes, stats.WALBytes, true
+	case q.StorageBytes != 0 && stats.PersistedBytes+stats.ActiveMemoryBytes > q.StorageBytes:
+		return QuotaStorage, q.StorageBytes, stats.PersistedBytes + stats.ActiveMemoryBytes, true
+	default:
+		return 0, 0, 0, false
+	}
+}
+
+// QuotaResource is a resource limited by a Quota.
+type QuotaResource int
+
+const (
+	QuotaActiveMemory QuotaResource = iota
+	QuotaBucket
+	QuotaWAL
+	QuotaStorage
+)
+
+func (r QuotaResource) String() string {
+	switch r {
+	case QuotaActiveMemory:
+		return "active memory"
+	case QuotaBucket:
+		return "bucket"
+	case QuotaWAL:
+		return "WAL"
+	case QuotaStorage:
+		return "storage"
+	default:
+		return fmt.Sprintf("QuotaResource(%d)", int(r))
+	}
+}
+
+// ErrQuotaExceeded is returned by inserts into a table once the table, or its
+// database, uses more of a resource than its quota allows. Inserts are
+// rejected until the usage drops, e.g. because blocks were persisted or
+// removed by retention, or the quota is raised.
+type ErrQuotaExceeded struct {
+	Database string
+	// Table is the table whose quota is exceeded. It is empty if the quota
+	// of the database is exceeded.
+	Table    string
+	Resource QuotaResource
+	// Quota is the quota of the resource in bytes.
+	Quota int64
+	// Usage is the number of bytes of the resource used.
+	Usage int64
+}
+
+func (e ErrQuotaExceeded) Error() string {
+	if e.Table == "" {
+		return fmt.Sprintf("database %s exceeds its %s quota: %d bytes used of %d", e.Database, e.Resource, e.Usage, e.Quota)
+	}
+	return fmt.Sprintf("table %s exceeds its %s quota: %d bytes used of %d", e.Table, e.Resource, e.Usage, e.Quota)
+}
+
+// WithDBQuota rejects inserts into all tables of the database with an
+// ErrQuotaExceeded error once the tables together use more of a resource than
+// the quota allows. It is enforced in addition to the quotas of the tables.
+// Only the tables that were opened are accounted for.
+func WithDBQuota(quota Quota) DBOption {
+	return func(db *DB) error {
+		if err := quota.validate(); err != nil {
+			return err
+		}
+		db.quota.Store(&quota)
+		return nil
+	}
+}
+
+// Stats returns the sum of the storage usage of the open tables of the
+// database, see Table.Stats.
+func (db *DB) Stats() TableStats {
+	var stats TableStats
+	for _, table := range db.openTables() {
+		tableStats := table.Stats()
+		stats.PersistedBytes += tableStats.PersistedBytes
+		stats.ActiveMemoryBytes += tableStats.ActiveMemoryBytes
+		stats.WALBytes += tableStats.WALBytes
+	}
+	return stats
+}
+
+// openTables returns the open tables of the database.
+func (db *DB) openTables() []*Table {
+	db.mtx.RLock()
+	defer db.mtx.RUnlock()
+	tables := make([]*Table, 0, len(db.tables))
+	for _, table := range db.tables {
+		tables = append(tables, table)
+	}
+	return tables
+}
+
+// tableQuota returns the quota configured by config, see WithQuota.
+func tableQuota(config *tablepb.TableConfig) Quota {
+	return Quota{
+		ActiveMemoryBytes: int64(config.GetActiveMemoryQuotaBytes()),
+		BucketBytes:       int64(config.GetBucketQuotaBytes()),
+		WALBytes:          int64(config.GetWalQuotaBytes()),
+		StorageBytes:      int64(config.GetStorageQuotaBytes()),
+	}
+}
+
+// validateQuotas checks that the quotas configured by config are not
+// negative. Quotas are stored unsigned, so negative quotas passed to
+// WithQuota or WithStorageQuota are detected when the table is opened, even
+// though NewTableConfig ignores the errors of options.
+func validateQuotas(config *tablepb.TableConfig) error {
+	return tableQuota(config).validate()
+}
+
+// checkQuotas returns an ErrQuotaExceeded error if the table or its database
+// uses more of a resource than its quota allows.
+func (t *Table) checkQuotas(ctx context.Context) error {
+	quota := tableQuota(t.config.Load())
+	if quota != (Quota{}) {
+		if quota.BucketBytes != 0 || quota.StorageBytes != 0 {
+			t.loadStorageUsage(ctx)
+		}
+		if resource, limit, usage, ok := quota.exceeded(t.Stats()); ok {
+			return ErrQuotaExceeded{Database: t.db.name, Table: t.name, Resource: resource, Quota: limit, Usage: usage}
+		}
+	}
+
+	dbQuota := t.db.quota.Load()
+	if dbQuota == nil || *dbQuota == (Quota{}) {
+		return nil
+	}
+	if dbQuota.BucketBytes != 0 || dbQuota.StorageBytes != 0 {
+		for _, table := range t.db.openTables() {
+			table.loadStorageUsage(ctx)
+		}
+	}
+	if resource, limit, usage, ok := dbQuota.exceeded(t.db.Stats()); ok {
+		return ErrQuotaExceeded{Database: t.db.name, Resource: resource, Quota: limit, Usage: usage}
+	}
+	return nil
+}
+
+// countingWriter counts the number of bytes written to the underlying writer.
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
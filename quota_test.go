@@ -0,0 +1,184 @@
+package frostdb
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/thanos-io/objstore"
+
+	"github.com/polarsignals/frostdb/dynparquet"
+)
+
+func TestStorageQuota(t *testing.T) {
+	samples := dynparquet.Samples{
+		{ExampleType: "cpu", Labels: map[string]string{"label1": "a"}, Timestamp: 1, Value: 1},
+		{ExampleType: "cpu", Labels: map[string]string{"label1": "b"}, Timestamp: 2, Value: 2},
+	}
+
+	t.Run("Memory", func(t *testing.T) {
+		c, db, table := openTestTable(t, nil, WithStorageQuota(1))
+		defer c.Close()
+
+		// The quota is checked before inserting, so the first insert
+		// succeeds.
+		require.NoError(t, tryInsertSamples(t, table, samples))
+		stats := table.Stats()
+		require.Zero(t, stats.PersistedBytes)
+		require.Positive(t, stats.ActiveMemoryBytes)
+
+		var exceeded ErrQuotaExceeded
+		require.ErrorAs(t, tryInsertSamples(t, table, samples), &exceeded)
+		require.Equal(t, ErrQuotaExceeded{
+			Database: "test",
+			Table:    "test",
+			Resource: QuotaStorage,
+			Quota:    1,
+			Usage:    stats.ActiveMemoryBytes,
+		}, exceeded)
+
+		// Removing the quota allows inserts again.
+		_, err := db.Table("test", NewTableConfig(dynparquet.SampleDefinition()))
+		require.NoError(t, err)
+		require.NoError(t, tryInsertSamples(t, table, samples))
+	})
+
+	t.Run("Persisted", func(t *testing.T) {
+		bucket := objstore.NewInMemBucket()
+		options := []Option{WithReadWriteStorage(NewDefaultObjstoreBucket(bucket))}
+
+		c, _, table := openTestTable(t, options)
+		require.NoError(t, tryInsertSamples(t, table, samples))
+		require.NoError(t, c.Close())
+		persisted := table.Stats().PersistedBytes
+		require.Positive(t, persisted)
+
+		var bucketSize int64
+		for _, object := range bucket.Objects() {
+			bucketSize += int64(len(object))
+		}
+		require.LessOrEqual(t, persisted, bucketSize)
+
+		// Blocks persisted before the table was opened count towards the
+		// quota.
+		c, _, table = openTestTable(t, options, WithStorageQuota(persisted))
+		defer c.Close()
+		require.Zero(t, table.Stats().PersistedBytes)
+		require.NoError(t, tryInsertSamples(t, table, samples))
+		require.Equal(t, persisted, table.Stats().PersistedBytes)
+		var exceeded ErrQuotaExceeded
+		require.ErrorAs(t, tryInsertSamples(t, table, samples), &exceeded)
+		require.Equal(t, QuotaStorage, exceeded.Resource)
+	})
+}
+
+func TestQuota(t *testing.T) {
+	ctx := context.Background()
+	samples := dynparquet.Samples{
+		{ExampleType: "cpu", Labels: map[string]string{"label1": "a"}, Timestamp: 1, Value: 1},
+		{ExampleType: "cpu", Labels: map[string]string{"label1": "b"}, Timestamp: 2, Value: 2},
+	}
+
+	t.Run("Table", func(t *testing.T) {
+		c, db, table := openTestTable(t, nil, WithQuota(Quota{ActiveMemoryBytes: 1}))
+		defer c.Close()
+		other, err := db.Table("other", NewTableConfig(dynparquet.SampleDefinition()))
+		require.NoError(t, err)
+
+		require.NoError(t, tryInsertSamples(t, table, samples))
+		var exceeded ErrQuotaExceeded
+		require.ErrorAs(t, tryInsertSamples(t, table, samples), &exceeded)
+		require.Equal(t, ErrQuotaExceeded{
+			Database: "test",
+			Table:    "test",
+			Resource: QuotaActiveMemory,
+			Quota:    1,
+			Usage:    table.Stats().ActiveMemoryBytes,
+		}, exceeded)
+
+		// The quota only applies to the table.
+		require.NoError(t, tryInsertSamples(t, other, samples))
+		require.NoError(t, tryInsertSamples(t, other, samples))
+
+		_, err = db.Table("test", NewTableConfig(dynparquet.SampleDefinition(), WithQuota(Quota{ActiveMemoryBytes: -1})))
+		require.Error(t, err)
+	})
+
+	t.Run("DB", func(t *testing.T) {
+		c, err := New(WithLogger(newTestLogger(t)))
+		require.NoError(t, err)
+		defer c.Close()
+		db, err := c.DB(ctx, "test")
+		require.NoError(t, err)
+		a, err := db.Table("a", NewTableConfig(dynparquet.SampleDefinition()))
+		require.NoError(t, err)
+		b, err := db.Table("b", NewTableConfig(dynparquet.SampleDefinition()))
+		require.NoError(t, err)
+
+		require.NoError(t, tryInsertSamples(t, a, samples))
+		_, err = c.DB(ctx, "test", WithDBQuota(Quota{ActiveMemoryBytes: a.Stats().ActiveMemoryBytes}))
+		require.NoError(t, err)
+		require.NoError(t, tryInsertSamples(t, b, samples))
+
+		// The tables of the database together exceed the quota.
+		var exceeded ErrQuotaExceeded
+		require.ErrorAs(t, tryInsertSamples(t, a, samples), &exceeded)
+		require.Empty(t, exceeded.Table)
+		require.Equal(t, QuotaActiveMemory, exceeded.Resource)
+		require.Equal(t, db.Stats().ActiveMemoryBytes, exceeded.Usage)
+		require.ErrorAs(t, tryInsertSamples(t, b, samples), &exceeded)
+
+		// Other databases are not limited.
+		otherDB, err := c.DB(ctx, "other")
+		require.NoError(t, err)
+		other, err := otherDB.Table("a", NewTableConfig(dynparquet.SampleDefinition()))
+		require.NoError(t, err)
+		require.NoError(t, tryInsertSamples(t, other, samples))
+		require.NoError(t, tryInsertSamples(t, other, samples))
+	})
+
+	t.Run("WAL", func(t *testing.T) {
+		c, db, table := openTestTable(t, []Option{WithWAL(), WithStoragePath(t.TempDir())}, WithQuota(Quota{WALBytes: 1}))
+		defer c.Close()
+
+		require.NoError(t, tryInsertSamples(t, table, samples))
+		require.Positive(t, table.Stats().WALBytes)
+		var exceeded ErrQuotaExceeded
+		require.ErrorAs(t, tryInsertSamples(t, table, samples), &exceeded)
+		require.Equal(t, QuotaWAL, exceeded.Resource)
+
+		// Tables without a WAL don't use any.
+		noWAL, err := db.Table("nowal", NewTableConfig(dynparquet.SampleDefinition(), WithoutWAL(), WithQuota(Quota{WALBytes: 1})))
+		require.NoError(t, err)
+		require.NoError(t, tryInsertSamples(t, noWAL, samples))
+		require.Zero(t, noWAL.Stats().WALBytes)
+		require.NoError(t, tryInsertSamples(t, noWAL, samples))
+	})
+
+	t.Run("Bucket", func(t *testing.T) {
+		bucket := objstore.NewInMemBucket()
+		c, err := New(
+			WithLogger(newTestLogger(t)),
+			WithReadWriteStorage(NewDefaultObjstoreBucket(bucket)),
+		)
+		require.NoError(t, err)
+		defer c.Close()
+		db, err := c.DB(ctx, "test", WithDBQuota(Quota{BucketBytes: 1}))
+		require.NoError(t, err)
+		table, err := db.Table("test", NewTableConfig(dynparquet.SampleDefinition()))
+		require.NoError(t, err)
+
+		require.NoError(t, tryInsertSamples(t, table, samples))
+		require.NoError(t, tryInsertSamples(t, table, samples))
+		require.NoError(t, table.RotateBlock(ctx, table.ActiveBlock()))
+		require.Eventually(t, func() bool {
+			return table.Stats().PersistedBytes > 0
+		}, time.Second, 10*time.Millisecond)
+
+		var exceeded ErrQuotaExceeded
+		require.ErrorAs(t, tryInsertSamples(t, table, samples), &exceeded)
+		require.Equal(t, QuotaBucket, exceeded.Resource)
+		require.Equal(t, table.Stats().PersistedBytes, exceeded.Usage)
+	})
+}
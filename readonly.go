@@ -0,0 +1,39 @@
+package frostdb
+
+import (
+	"context"
+	"errors"
+)
+
+// ErrReadOnly is returned for writes to a read-only column store, see
+// WithReadOnlyStorage.
+var ErrReadOnly = errors.New("the column store is read-only")
+
+// ReadOnly returns whether the column store is read-only, see
+// WithReadOnlyStorage.
+func (s *ColumnStore) ReadOnly() bool {
+	return s.readOnly
+}
+
+// openReadOnlyDBs opens all databases found in the read-only storage of the
+// column store, which opens all of their tables for querying.
+func (s *ColumnStore) openReadOnlyDBs(ctx context.Context) error {
+	names := map[string]struct{}{}
+	for _, source := range s.sources {
+		prefixes, err := source.Prefixes(ctx, "")
+		if err != nil {
+			return err
+		}
+		for _, name := range prefixes {
+			if validateName(name) {
+				names[name] = struct{}{}
+			}
+		}
+	}
+	for name := range names {
+		if _, err := s.DB(ctx, name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,460 @@
+package frostdb
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+
+	"github.com/polarsignals/frostdb/dynparquet"
+	"github.com/polarsignals/frostdb/query/logicalplan"
+)
+
+const (
+	// DefaultReplicationRetryInterval is the default interval at which failed
+	// replications are retried.
+	DefaultReplicationRetryInterval = 10 * time.Second
+	// DefaultReplicationQueueSize is the default maximum number of pending
+	// replications per replica.
+	DefaultReplicationQueueSize = 1024
+	// DefaultBucketHealthCheckInterval is the default duration a bucket is
+	// skipped for reads after a failed read.
+	DefaultBucketHealthCheckInterval = 30 * time.Second
+)
+
+// ReplicatedBucket is a DataSinkSource that persists blocks to a primary
+// bucket and replicates them asynchronously to one or more replica buckets,
+// e.g. in other zones or regions. Uploads and deletes return once they
+// succeed on the primary; they are then queued for each replica and retried
+// until they succeed. Reads are served by the first healthy bucket, in the
+// order primary, replicas. A bucket is considered unhealthy for a while after
+// a read from it fails.
+type ReplicatedBucket struct {
+	logger log.Logger
+	clock  Clock
+
+	buckets  []DataSinkSource
+	replicas []*replica
+
+	retryInterval       time.Duration
+	queueSize           int
+	healthCheckInterval time.Duration
+
+	healthMtx      sync.Mutex
+	unhealthyUntil []time.Time
+
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+type ReplicatedBucketOption func(*ReplicatedBucket)
+
+func ReplicationWithLogger(logger log.Logger) ReplicatedBucketOption {
+	return func(b *ReplicatedBucket) {
+		b.logger = logger
+	}
+}
+
+// ReplicationWithClock sets the clock used to retry failed replications and to
+// track unhealthy buckets. The default is the system clock.
+func ReplicationWithClock(clock Clock) ReplicatedBucketOption {
+	return func(b *ReplicatedBucket) {
+		b.clock = clock
+	}
+}
+
+// ReplicationWithRetryInterval sets the interval at which failed replications
+// are retried.
+func ReplicationWithRetryInterval(interval time.Duration) ReplicatedBucketOption {
+	return func(b *ReplicatedBucket) {
+		b.retryInterval = interval
+	}
+}
+
+// ReplicationWithQueueSize sets the maximum number of pending replications
+// per replica. Replications past the limit are dropped and logged, so the
+// replica misses the corresponding blocks until they are copied manually.
+func ReplicationWithQueueSize(size int) ReplicatedBucketOption {
+	return func(b *ReplicatedBucket) {
+		b.queueSize = size
+	}
+}
+
+// ReplicationWithHealthCheckInterval sets the duration a bucket is skipped for
+// reads after a read from it failed.
+func ReplicationWithHealthCheckInterval(interval time.Duration) ReplicatedBucketOption {
+	return func(b *ReplicatedBucket) {
+		b.healthCheckInterval = interval
+	}
+}
+
+// NewReplicatedBucket returns a bucket that persists blocks to primary and
+// replicates them to replicas. Close must be called to stop replicating.
+func NewReplicatedBucket(primary DataSinkSource, replicas []DataSinkSource, options ...ReplicatedBucketOption) *ReplicatedBucket {
+	b := &ReplicatedBucket{
+		logger:              log.NewNopLogger(),
+		clock:               systemClock{},
+		buckets:             append([]DataSinkSource{primary}, replicas...),
+		retryInterval:       DefaultReplicationRetryInterval,
+		queueSize:           DefaultReplicationQueueSize,
+		healthCheckInterval: DefaultBucketHealthCheckInterval,
+	}
+	for _, option := range options {
+		option(b)
+	}
+	b.unhealthyUntil = make([]time.Time, len(b.buckets))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	b.cancel = cancel
+	for _, bucket := range replicas {
+		r := &replica{
+			bucket: bucket,
+			ch:     make(chan replication, b.queueSize),
+		}
+		b.replicas = append(b.replicas, r)
+		b.wg.Add(1)
+		go func() {
+			defer b.wg.Done()
+			b.replicate(ctx, r)
+		}()
+	}
+	return b
+}
+
+type replica struct {
+	bucket DataSinkSource
+	ch     chan replication
+}
+
+// replication is an operation to apply to a replica.
+type replication struct {
+	name string
+	do   func(ctx context.Context, bucket DataSinkSource) error
+}
+
+// replicate applies the queued replications to r in order, retrying each one
+// until it succeeds or ctx is canceled.
+func (b *ReplicatedBucket) replicate(ctx context.Context, r *replica) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case op := <-r.ch:
+			for {
+				err := op.do(ctx, r.bucket)
+				if err == nil {
+					break
+				}
+				level.Warn(b.logger).Log(
+					"msg", "failed to replicate to bucket; retrying",
+					"bucket", r.bucket.String(),
+					"name", op.name,
+					"err", err,
+				)
+				if !b.wait(ctx, b.retryInterval) {
+					return
+				}
+			}
+		}
+	}
+}
+
+// wait waits for d to pass on the bucket's clock. It returns false if ctx is
+// done first.
+func (b *ReplicatedBucket) wait(ctx context.Context, d time.Duration) bool {
+	ticker := b.clock.NewTicker(d)
+	defer ticker.Stop()
+	select {
+	case <-ctx.Done():
+		return false
+	case <-ticker.C():
+		return true
+	}
+}
+
+func (b *ReplicatedBucket) enqueue(op replication) {
+	for _, r := range b.replicas {
+		select {
+		case r.ch <- op:
+		default:
+			level.Error(b.logger).Log(
+				"msg", "replication queue full; dropping replication",
+				"bucket", r.bucket.String(),
+				"name", op.name,
+			)
+		}
+	}
+}
+
+// Pending returns the number of replications that have not been applied to
+// the replica at the given index yet, not counting a replication currently
+// being applied.
+func (b *ReplicatedBucket) Pending(replica int) int {
+	return len(b.replicas[replica].ch)
+}
+
+// Close stops replicating. Pending replications are dropped.
+func (b *ReplicatedBucket) Close() error {
+	b.cancel()
+	b.wg.Wait()
+	for _, r := range b.replicas {
+		if n := len(r.ch); n > 0 {
+			level.Warn(b.logger).Log(
+				"msg", "dropping pending replications on close",
+				"bucket", r.bucket.String(),
+				"pending", n,
+			)
+		}
+	}
+	return nil
+}
+
+func (b *ReplicatedBucket) String() string {
+	names := make([]string, 0, len(b.buckets))
+	for _, bucket := range b.buckets {
+		names = append(names, bucket.String())
+	}
+	return "replicated(" + strings.Join(names, ", ") + ")"
+}
+
+// Upload uploads the object to the primary bucket and queues its upload to
+// the replicas. The object is buffered in memory until it is uploaded to all
+// replicas.
+func (b *ReplicatedBucket) Upload(ctx context.Context, name string, r io.Reader) error {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	if err := b.buckets[0].Upload(ctx, name, bytes.NewReader(data)); err != nil {
+		return err
+	}
+	b.enqueue(replication{
+		name: name,
+		do: func(ctx context.Context, bucket DataSinkSource) error {
+			return bucket.Upload(ctx, name, bytes.NewReader(data))
+		},
+	})
+	return nil
+}
+
+// Delete deletes the object from the primary bucket and queues its deletion
+// from the replicas.
+func (b *ReplicatedBucket) Delete(ctx context.Context, name string) error {
+	if err := b.buckets[0].Delete(ctx, name); err != nil {
+		return err
+	}
+	b.enqueue(replication{
+		name: name,
+		do: func(ctx context.Context, bucket DataSinkSource) error {
+			return bucket.Delete(ctx, name)
+		},
+	})
+	return nil
+}
+
+// DeleteBlocks implements BlockDeleter if the primary bucket does. Blocks are
+// deleted from the primary bucket and the deletion is queued for the replicas
+// that implement BlockDeleter. The number of blocks deleted from the primary
+// bucket is returned.
+func (b *ReplicatedBucket) DeleteBlocks(ctx context.Context, prefix string, filter logicalplan.Expr) (int, error) {
+	deleter, ok := b.buckets[0].(BlockDeleter)
+	if !ok {
+		return 0, fmt.Errorf("primary bucket %s does not support deleting blocks", b.buckets[0])
+	}
+	n, err := deleter.DeleteBlocks(ctx, prefix, filter)
+	if err != nil {
+		return n, err
+	}
+	b.enqueue(replication{
+		name: prefix,
+		do: func(ctx context.Context, bucket DataSinkSource) error {
+			deleter, ok := bucket.(BlockDeleter)
+			if !ok {
+				return nil
+			}
+			_, err := deleter.DeleteBlocks(ctx, prefix, filter)
+			return err
+		},
+	})
+	return n, nil
+}
+
+// PurgeBlocks implements BlockPurger if the primary bucket does. Blocks are
+// purged from the primary bucket and the purge is queued for the replicas
+// that implement BlockPurger. The number of blocks purged from the primary
+// bucket is returned.
+func (b *ReplicatedBucket) PurgeBlocks(ctx context.Context, prefix string) (int, error) {
+	purger, ok := b.buckets[0].(BlockPurger)
+	if !ok {
+		return 0, fmt.Errorf("primary bucket %s does not support purging blocks", b.buckets[0])
+	}
+	n, err := purger.PurgeBlocks(ctx, prefix)
+	if err != nil {
+		return n, err
+	}
+	b.enqueue(replication{
+		name: prefix,
+		do: func(ctx context.Context, bucket DataSinkSource) error {
+			purger, ok := bucket.(BlockPurger)
+			if !ok {
+				return nil
+			}
+			_, err := purger.PurgeBlocks(ctx, prefix)
+			return err
+		},
+	})
+	return n, nil
+}
+
+// DeletePrefix implements PrefixDeleter if the primary bucket does. Objects
+// are deleted from the primary bucket and the deletion is queued for the
+// replicas that implement PrefixDeleter. The number of objects deleted from
+// the primary bucket is returned.
+func (b *ReplicatedBucket) DeletePrefix(ctx context.Context, prefix string) (int, error) {
+	deleter, ok := b.buckets[0].(PrefixDeleter)
+	if !ok {
+		return 0, fmt.Errorf("primary bucket %s does not support deleting prefixes", b.buckets[0])
+	}
+	n, err := deleter.DeletePrefix(ctx, prefix)
+	if err != nil {
+		return n, err
+	}
+	b.enqueue(replication{
+		name: prefix,
+		do: func(ctx context.Context, bucket DataSinkSource) error {
+			deleter, ok := bucket.(PrefixDeleter)
+			if !ok {
+				return nil
+			}
+			_, err := deleter.DeletePrefix(ctx, prefix)
+			return err
+		},
+	})
+	return n, nil
+}
+
+// RewriteBlocks implements BlockRewriter if the primary bucket does. Blocks are
+// rewritten in the primary bucket and the rewrite is queued for the replicas
+// that implement BlockRewriter. The number of blocks rewritten in the primary
+// bucket is returned.
+func (b *ReplicatedBucket) RewriteBlocks(ctx context.Context, prefix string, rewrite BlockRewriteFunc) (int, error) {
+	rewriter, ok := b.buckets[0].(BlockRewriter)
+	if !ok {
+		return 0, fmt.Errorf("primary bucket %s does not support rewriting blocks", b.buckets[0])
+	}
+	n, err := rewriter.RewriteBlocks(ctx, prefix, rewrite)
+	if err != nil {
+		return n, err
+	}
+	b.enqueue(replication{
+		name: prefix,
+		do: func(ctx context.Context, bucket DataSinkSource) error {
+			rewriter, ok := bucket.(BlockRewriter)
+			if !ok {
+				return nil
+			}
+			_, err := rewriter.RewriteBlocks(ctx, prefix, rewrite)
+			return err
+		},
+	})
+	return n, nil
+}
+
+// Prefixes returns the prefixes of the first healthy bucket.
+func (b *ReplicatedBucket) Prefixes(ctx context.Context, prefix string) ([]string, error) {
+	var prefixes []string
+	err := b.read(func(bucket DataSinkSource) (bool, error) {
+		var err error
+		prefixes, err = bucket.Prefixes(ctx, prefix)
+		return false, err
+	})
+	return prefixes, err
+}
+
+// Scan scans the first healthy bucket. If a scan fails before any data was
+// passed to callback, the next healthy bucket is scanned instead.
+func (b *ReplicatedBucket) Scan(ctx context.Context, prefix string, schema *dynparquet.Schema, filter logicalplan.Expr, lastBlockTimestamp uint64, callback func(context.Context, any) error) error {
+	return b.read(func(bucket DataSinkSource) (bool, error) {
+		// The callback may be called concurrently for different blocks.
+		var (
+			mtx         sync.Mutex
+			called      bool
+			callbackErr error
+		)
+		err := bucket.Scan(ctx, prefix, schema, filter, lastBlockTimestamp, func(ctx context.Context, v any) error {
+			mtx.Lock()
+			called = true
+			mtx.Unlock()
+			if err := callback(ctx, v); err != nil {
+				mtx.Lock()
+				if callbackErr == nil {
+					callbackErr = err
+				}
+				mtx.Unlock()
+				return err
+			}
+			return nil
+		})
+		mtx.Lock()
+		defer mtx.Unlock()
+		if callbackErr != nil {
+			// Errors of the callback are not caused by the bucket.
+			return true, callbackErr
+		}
+		return called, err
+	})
+}
+
+// read calls fn with each healthy bucket in order until fn succeeds. fn
+// returns whether the read must not be retried on another bucket after an
+// error, e.g. because data was already returned to the caller.
+func (b *ReplicatedBucket) read(fn func(bucket DataSinkSource) (bool, error)) error {
+	var errs []error
+	tried := false
+	for i, bucket := range b.buckets {
+		if !b.healthy(i) {
+			continue
+		}
+		tried = true
+		final, err := fn(bucket)
+		if err == nil {
+			return nil
+		}
+		if final {
+			return err
+		}
+		b.markUnhealthy(i)
+		level.Warn(b.logger).Log(
+			"msg", "failed to read from bucket; marking it unhealthy",
+			"bucket", bucket.String(),
+			"err", err,
+		)
+		errs = append(errs, fmt.Errorf("%s: %w", bucket, err))
+	}
+	if !tried {
+		// All buckets are unhealthy, try the primary anyway rather than
+		// failing without trying.
+		_, err := fn(b.buckets[0])
+		return err
+	}
+	return errors.Join(errs...)
+}
+
+func (b *ReplicatedBucket) healthy(i int) bool {
+	b.healthMtx.Lock()
+	defer b.healthMtx.Unlock()
+	return !b.clock.Now().Before(b.unhealthyUntil[i])
+}
+
+func (b *ReplicatedBucket) markUnhealthy(i int) {
+	b.healthMtx.Lock()
+	defer b.healthMtx.Unlock()
+	b.unhealthyUntil[i] = b.clock.Now().Add(b.healthCheckInterval)
+}
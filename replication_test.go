@@ -0,0 +1,169 @@
+package frostdb
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/apache/arrow/go/v17/arrow"
+	"github.com/apache/arrow/go/v17/arrow/memory"
+	"github.com/stretchr/testify/require"
+	"github.com/thanos-io/objstore"
+
+	"github.com/polarsignals/frostdb/dynparquet"
+	"github.com/polarsignals/frostdb/query"
+	"github.com/polarsignals/frostdb/query/logicalplan"
+)
+
+// flakyBucket fails uploads and prefix listings while failing is set.
+type flakyBucket struct {
+	*DefaultObjstoreBucket
+	failing atomic.Bool
+}
+
+var errFlakyBucket = errors.New("flaky bucket")
+
+func (b *flakyBucket) Upload(ctx context.Context, name string, r io.Reader) error {
+	if b.failing.Load() {
+		return errFlakyBucket
+	}
+	return b.DefaultObjstoreBucket.Upload(ctx, name, r)
+}
+
+func (b *flakyBucket) Prefixes(ctx context.Context, prefix string) ([]string, error) {
+	if b.failing.Load() {
+		return nil, errFlakyBucket
+	}
+	return b.DefaultObjstoreBucket.Prefixes(ctx, prefix)
+}
+
+func (b *flakyBucket) Scan(ctx context.Context, prefix string, schema *dynparquet.Schema, filter logicalplan.Expr, lastBlockTimestamp uint64, callback func(context.Context, any) error) error {
+	if b.failing.Load() {
+		return errFlakyBucket
+	}
+	return b.DefaultObjstoreBucket.Scan(ctx, prefix, schema, filter, lastBlockTimestamp, callback)
+}
+
+func TestReplicatedBucket(t *testing.T) {
+	ctx := context.Background()
+	primaryBucket := objstore.NewInMemBucket()
+	replicaBucket := objstore.NewInMemBucket()
+	primary := &flakyBucket{DefaultObjstoreBucket: NewDefaultObjstoreBucket(primaryBucket)}
+	replica := &flakyBucket{DefaultObjstoreBucket: NewDefaultObjstoreBucket(replicaBucket)}
+
+	b := NewReplicatedBucket(
+		primary,
+		[]DataSinkSource{replica},
+		ReplicationWithRetryInterval(time.Millisecond),
+		ReplicationWithHealthCheckInterval(time.Hour),
+	)
+	defer b.Close()
+
+	// Uploads are retried until the replica is available.
+	replica.failing.Store(true)
+	require.NoError(t, b.Upload(ctx, "db/table/block/data.parquet", bytes.NewReader([]byte("data"))))
+	exists, err := primaryBucket.Exists(ctx, "db/table/block/data.parquet")
+	require.NoError(t, err)
+	require.True(t, exists)
+	replica.failing.Store(false)
+	require.Eventually(t, func() bool {
+		exists, err := replicaBucket.Exists(ctx, "db/table/block/data.parquet")
+		require.NoError(t, err)
+		return exists
+	}, time.Second, time.Millisecond)
+
+	// Reads fall back to the replica if the primary fails.
+	primary.failing.Store(true)
+	prefixes, err := b.Prefixes(ctx, "db")
+	require.NoError(t, err)
+	require.Equal(t, []string{"table"}, prefixes)
+	require.False(t, b.healthy(0))
+
+	// Uploads fail if the primary fails.
+	require.ErrorIs(t, b.Upload(ctx, "db/table/block2/data.parquet", bytes.NewReader([]byte("data"))), errFlakyBucket)
+
+	// Deletes are replicated.
+	require.NoError(t, b.Delete(ctx, "db/table/block/data.parquet"))
+	require.Eventually(t, func() bool {
+		exists, err := replicaBucket.Exists(ctx, "db/table/block/data.parquet")
+		require.NoError(t, err)
+		return !exists
+	}, time.Second, time.Millisecond)
+	require.Equal(t, 0, b.Pending(0))
+}
+
+func TestReplicatedBucketScan(t *testing.T) {
+	ctx := context.Background()
+	clock := NewManualClock(time.UnixMilli(0))
+	primary := &flakyBucket{DefaultObjstoreBucket: NewDefaultObjstoreBucket(objstore.NewInMemBucket())}
+	replicaBucket := objstore.NewInMemBucket()
+	b := NewReplicatedBucket(
+		primary,
+		[]DataSinkSource{NewDefaultObjstoreBucket(replicaBucket)},
+		ReplicationWithClock(clock),
+		ReplicationWithHealthCheckInterval(time.Minute),
+	)
+	defer b.Close()
+
+	c, err := New(WithLogger(newTestLogger(t)), WithReadWriteStorage(b))
+	require.NoError(t, err)
+	defer c.Close()
+	db, err := c.DB(ctx, "test")
+	require.NoError(t, err)
+	table, err := db.Table("test", NewTableConfig(dynparquet.SampleDefinition()))
+	require.NoError(t, err)
+
+	// Persist several blocks, so that the callback is called concurrently
+	// for them.
+	const numBlocks = 4
+	for i := 0; i < numBlocks; i++ {
+		insertSampleRecords(ctx, t, table, int64(3*i), int64(3*i+1), int64(3*i+2))
+		var wg sync.WaitGroup
+		wg.Add(1)
+		require.NoError(t, table.RotateBlock(ctx, table.ActiveBlock(), WithRotateBlockWaitGroup(&wg)))
+		wg.Wait()
+	}
+	require.Eventually(t, func() bool {
+		blocks := 0
+		for name := range replicaBucket.Objects() {
+			if filepath.Base(name) == "data.parquet" {
+				blocks++
+			}
+		}
+		return blocks == numBlocks
+	}, time.Second, time.Millisecond)
+
+	engine := query.NewEngine(memory.DefaultAllocator, db.TableProvider())
+	countRows := func() int {
+		var rows atomic.Int64
+		require.NoError(t, engine.ScanTable("test").Execute(ctx, func(_ context.Context, r arrow.Record) error {
+			rows.Add(r.NumRows())
+			return nil
+		}))
+		return int(rows.Load())
+	}
+	require.Equal(t, 3*numBlocks, countRows())
+
+	// Errors of the callback are returned without failing over.
+	errCallback := errors.New("callback")
+	require.ErrorIs(t, engine.ScanTable("test").Execute(ctx, func(_ context.Context, _ arrow.Record) error {
+		return errCallback
+	}), errCallback)
+	require.True(t, b.healthy(0))
+
+	// Scans fall back to the replica while the primary fails, and the
+	// primary is read again once the health check interval passed.
+	primary.failing.Store(true)
+	require.Equal(t, 3*numBlocks, countRows())
+	require.False(t, b.healthy(0))
+	primary.failing.Store(false)
+	clock.Advance(time.Minute)
+	require.True(t, b.healthy(0))
+	require.Equal(t, 3*numBlocks, countRows())
+}
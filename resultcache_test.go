@@ -0,0 +1,126 @@
+package frostdb
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/apache/arrow/go/v17/arrow"
+	"github.com/apache/arrow/go/v17/arrow/array"
+	"github.com/apache/arrow/go/v17/arrow/memory"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/require"
+
+	"github.com/polarsignals/frostdb/dynparquet"
+	"github.com/polarsignals/frostdb/query"
+	"github.com/polarsignals/frostdb/query/logicalplan"
+)
+
+func TestResultCache(t *testing.T) {
+	ctx := context.Background()
+	clock := NewManualClock(time.Now())
+	c, err := New(WithLogger(newTestLogger(t)), WithClock(clock))
+	require.NoError(t, err)
+	defer c.Close()
+	db, err := c.DB(ctx, "test")
+	require.NoError(t, err)
+	table, err := db.Table("test", NewTableConfig(dynparquet.SampleDefinition()))
+	require.NoError(t, err)
+	retained, err := db.Table("retained", NewTableConfig(dynparquet.SampleDefinition(), WithRetention(time.Hour)))
+	require.NoError(t, err)
+	other, err := db.Table("other", NewTableConfig(dynparquet.SampleDefinition()))
+	require.NoError(t, err)
+
+	reg := prometheus.NewRegistry()
+	cache := query.NewResultCache(1<<20,
+		query.WithResultCacheRegistry(reg),
+		query.WithResultCacheTTL(time.Minute),
+		query.WithResultCacheNow(clock.Now),
+	)
+	engine := query.NewEngine(memory.DefaultAllocator, db.TableProvider(), query.WithResultCache(cache))
+	sum := func(t *testing.T, table string, minTimestamp int64) int64 {
+		t.Helper()
+		var total int64
+		require.NoError(t, engine.ScanTable(table).
+			Filter(logicalplan.Col("timestamp").GtEq(logicalplan.Literal(minTimestamp))).
+			Aggregate([]*logicalplan.AggregationFunction{logicalplan.Sum(logicalplan.Col("value"))}, nil).
+			Execute(ctx, func(_ context.Context, r arrow.Record) error {
+				total += r.Column(0).(*array.Int64).Value(0)
+				return nil
+			}))
+		return total
+	}
+	hitsAndMisses := func(t *testing.T) (float64, float64) {
+		t.Helper()
+		families, err := reg.Gather()
+		require.NoError(t, err)
+		values := map[string]float64{}
+		for _, f := range families {
+			values[f.GetName()] = f.GetMetric()[0].GetCounter().GetValue()
+		}
+		return values["frostdb_result_cache_hits_total"], values["frostdb_result_cache_misses_total"]
+	}
+
+	samples := dynparquet.GenerateTestSamples(10)
+	var total int64
+	for _, s := range samples {
+		total += s.Value
+	}
+	insert := func(t *testing.T, table *Table) {
+		t.Helper()
+		r, err := samples.ToRecord()
+		require.NoError(t, err)
+		defer r.Release()
+		_, err = table.InsertRecord(ctx, r)
+		require.NoError(t, err)
+	}
+	insert(t, table)
+	insert(t, retained)
+
+	// Identical queries of unchanged data are served from the cache.
+	require.Equal(t, total, sum(t, "test", 0))
+	require.Equal(t, total, sum(t, "test", 0))
+	hits, misses := hitsAndMisses(t)
+	require.Equal(t, float64(1), hits)
+	require.Equal(t, float64(1), misses)
+	require.Equal(t, 1, cache.Len())
+
+	// Writes to other tables don't change the version of the table's data.
+	insert(t, other)
+	require.Equal(t, total, sum(t, "test", 0))
+	hits, misses = hitsAndMisses(t)
+	require.Equal(t, float64(2), hits)
+	require.Equal(t, float64(1), misses)
+
+	// Results expire once they were cached for the TTL.
+	clock.Advance(time.Minute)
+	require.Equal(t, total, sum(t, "test", 0))
+	hits, misses = hitsAndMisses(t)
+	require.Equal(t, float64(2), hits)
+	require.Equal(t, float64(2), misses)
+	require.Equal(t, 1, cache.Len())
+
+	// Queries with different plans are cached separately.
+	require.Zero(t, sum(t, "test", samples[len(samples)-1].Timestamp+1))
+	require.Equal(t, 2, cache.Len())
+
+	// Inserts and deletes change the version of the table's data.
+	insert(t, table)
+	require.Equal(t, 2*total, sum(t, "test", 0))
+	require.NoError(t, table.Delete(ctx, logicalplan.Col("timestamp").GtEq(logicalplan.Literal(int64(0)))))
+	require.Zero(t, sum(t, "test", 0))
+	hits, misses = hitsAndMisses(t)
+	require.Equal(t, float64(2), hits)
+	require.Equal(t, float64(5), misses)
+
+	// The data of tables with a retention period changes without a new
+	// version, so their results are not cached.
+	require.Equal(t, sum(t, "retained", 0), sum(t, "retained", 0))
+	hits, misses = hitsAndMisses(t)
+	require.Equal(t, float64(2), hits)
+	require.Equal(t, float64(5), misses)
+	require.Equal(t, 4, cache.Len())
+
+	cache.Purge()
+	require.Zero(t, cache.Len())
+}
@@ -0,0 +1,252 @@
+package frostdb
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"time"
+
+	"github.com/apache/arrow/go/v17/arrow"
+	"github.com/apache/arrow/go/v17/arrow/array"
+	"github.com/go-kit/log/level"
+	"github.com/parquet-go/parquet-go"
+
+	"github.com/polarsignals/frostdb/dynparquet"
+	tablepb "github.com/polarsignals/frostdb/gen/proto/go/frostdb/table/v1alpha1"
+	"github.com/polarsignals/frostdb/parts"
+	"github.com/polarsignals/frostdb/query/expr"
+	"github.com/polarsignals/frostdb/query/logicalplan"
+)
+
+const (
+	// defaultRetentionColumn is the column retention is enforced on unless
+	// configured otherwise with WithRetentionColumn.
+	defaultRetentionColumn = "timestamp"
+	// defaultRetentionUnit is the unit of the values of the retention column
+	// unless configured otherwise with WithRetentionColumn.
+	defaultRetentionUnit = time.Millisecond
+
+	// DefaultRetentionCheckInterval is the default interval at which
+	// retention is enforced for tables configured with WithRetention.
+	DefaultRetentionCheckInterval = time.Minute
+)
+
+// WithRetentionCheckInterval sets the interval at which retention is enforced
+// in the background for tables configured with WithRetention. A value <= 0
+// disables background enforcement, in which case Table.EnforceRetention must
+// be called to delete expired blocks from storage.
+func WithRetentionCheckInterval(interval time.Duration) Option {
+	return func(s *ColumnStore) error {
+		s.retentionCheckInterval = interval
+		return nil
+	}
+}
+
+// BlockDeleter is implemented by data sinks that can delete persisted blocks
+// based on their contents.
+type BlockDeleter interface {
+	// DeleteBlocks deletes all blocks under prefix that cannot contain any
+	// rows matching filter. It returns the number of deleted blocks.
+	DeleteBlocks(ctx context.Context, prefix string, filter logicalplan.Expr) (int, error)
+}
+
+// retentionColumn returns the column retention is enforced on according to
+// the given config and the unit of its values.
+func retentionColumn(config *tablepb.TableConfig) (string, time.Duration) {
+	column, unit := config.GetRetentionColumn(), time.Duration(config.GetRetentionColumnUnitNs())
+	if column == "" {
+		column = defaultRetentionColumn
+	}
+	if unit <= 0 {
+		unit = defaultRetentionUnit
+	}
+	return column, unit
+}
+
+// validateRetention checks that retention can be enforced for the given
+// schema and config.
+func validateRetention(schema *dynparquet.Schema, config *tablepb.TableConfig) error {
+	column, _ := retentionColumn(config)
+	def, ok := schema.ColumnByName(column)
+	if !ok {
+		return fmt.Errorf("retention requires a %q column", column)
+	}
+	if def.Dynamic || def.StorageLayout.Type().Kind() != parquet.Int64 {
+		return fmt.Errorf("retention requires %q to be a non-dynamic int64 column", column)
+	}
+	return nil
+}
+
+// retention returns the retention window of the table, or 0 if retention is
+// disabled.
+func (t *Table) retention() time.Duration {
+	config := t.config.Load()
+	if config == nil || t.schema.Load() == nil {
+		return 0
+	}
+	return time.Duration(config.RetentionMs) * time.Millisecond
+}
+
+// retentionCutoff returns the value of the retention column before which
+// data is expired. The second return value is false if retention is disabled.
+func (t *Table) retentionCutoff() (int64, bool) {
+	retention := t.retention()
+	if retention == 0 {
+		return 0, false
+	}
+	_, unit := retentionColumn(t.config.Load())
+	return t.db.columnStore.clock.Now().Add(-retention).UnixNano() / unit.Nanoseconds(), true
+}
+
+// retentionFilter returns a filter matching all rows of the table that are
+// not expired given the cutoff.
+func (t *Table) retentionFilter(cutoff int64) logicalplan.Expr {
+	column, _ := retentionColumn(t.config.Load())
+	return logicalplan.Col(column).GtEq(logicalplan.Literal(cutoff))
+}
+
+// expiredRecord is returned by rewritePart for parts that only contain data
+// older than the retention window, so that the index drops them.
+func expiredRecord() arrow.Record {
+	return array.NewRecord(arrow.NewSchema(nil, nil), nil, 0)
+}
+
+// partExpired returns true if the part contains no data at or after cutoff.
+func (t *Table) partExpired(p parts.Part, cutoff int64) (bool, error) {
+	if r := p.Record(); r != nil {
+		column, _ := retentionColumn(t.config.Load())
+		return recordExpired(r, column, cutoff)
+	}
+
+	buf, err := p.AsSerializedBuffer(t.schema.Load())
+	if err != nil {
+		return false, err
+	}
+	return rowGroupsExpired(buf, t.retentionFilter(cutoff))
+}
+
+// recordExpired returns true if no value of the retention column in r is
+// greater than or equal to cutoff.
+func recordExpired(r arrow.Record, column string, cutoff int64) (bool, error) {
+	indices := r.Schema().FieldIndices(column)
+	if len(indices) != 1 {
+		return false, nil
+	}
+	col, ok := r.Column(indices[0]).(*array.Int64)
+	if !ok {
+		return false, fmt.Errorf("unexpected type %s for column %q", r.Column(indices[0]).DataType(), column)
+	}
+	for i := 0; i < col.Len(); i++ {
+		if col.IsValid(i) && col.Value(i) >= cutoff {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// rowGroupsExpired returns true if the statistics of all row groups in buf
+// show that none of their rows match the given retention filter.
+func rowGroupsExpired(buf *dynparquet.SerializedBuffer, filter logicalplan.Expr) (bool, error) {
+	booleanFilter, err := expr.BooleanExpr(filter)
+	if err != nil {
+		return false, fmt.Errorf("boolean expr: %w", err)
+	}
+	for i := 0; i < buf.NumRowGroups(); i++ {
+		mayContainUnexpiredRows, err := booleanFilter.Eval(buf.DynamicRowGroup(i), false)
+		if err != nil {
+			return false, err
+		}
+		if mayContainUnexpiredRows {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// EnforceRetention deletes all persisted blocks of the table that only
+// contain data older than the table's retention window from data sinks that
+// implement BlockDeleter. It is a no-op for tables without retention.
+//
+// In-memory data older than the retention window is hidden from queries and
+// dropped when it is compacted or persisted, so it does not need to be
+// enforced explicitly.
+func (t *Table) EnforceRetention(ctx context.Context) error {
+	cutoff, ok := t.retentionCutoff()
+	if !ok {
+		return nil
+	}
+	filter := t.retentionFilter(cutoff)
+
+	prefix := filepath.Join(t.db.name, t.name)
+	deleted := false
+	for _, sink := range t.db.sinks {
+		deleter, ok := sink.(BlockDeleter)
+		if !ok {
+			continue
+		}
+		n, err := deleter.DeleteBlocks(ctx, prefix, filter)
+		if err != nil {
+			return fmt.Errorf("delete expired blocks from %s: %w", sink, err)
+		}
+		if n > 0 {
+			level.Debug(t.logger).Log("msg", "deleted expired blocks", "table", t.name, "sink", sink.String(), "n", n)
+			deleted = true
+		}
+	}
+	if deleted {
+		t.db.blockSchemas.invalidate(t.name)
+		return t.RefreshStorageUsage(ctx)
+	}
+	return nil
+}
+
+// startRetentionLoop starts enforcing retention in the background if the
+// table has a retention window and no background loop is running yet.
+func (t *Table) startRetentionLoop() {
+	interval := t.db.columnStore.retentionCheckInterval
+	if interval <= 0 || t.retention() == 0 || len(t.db.sinks) == 0 {
+		return
+	}
+
+	t.retentionMtx.Lock()
+	defer t.retentionMtx.Unlock()
+	if t.stopRetention != nil {
+		return
+	}
+
+	t.stopRetention = make(chan struct{})
+	t.retentionDone = make(chan struct{})
+	go func(stop <-chan struct{}, done chan<- struct{}) {
+		defer close(done)
+		ticker := t.db.columnStore.clock.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C():
+				if t.db.MaintenancePaused() {
+					continue
+				}
+				t.db.columnStore.scheduler.Do(WorkRetention, t.db.name+"/"+t.name, func() {
+					if err := t.EnforceRetention(context.Background()); err != nil {
+						level.Warn(t.logger).Log("msg", "failed to enforce retention", "table", t.name, "err", err)
+					}
+				})
+			}
+		}
+	}(t.stopRetention, t.retentionDone)
+}
+
+// stopRetentionLoop stops the background retention loop, if any, and waits
+// for it to exit.
+func (t *Table) stopRetentionLoop() {
+	t.retentionMtx.Lock()
+	defer t.retentionMtx.Unlock()
+	if t.stopRetention == nil {
+		return
+	}
+	close(t.stopRetention)
+	<-t.retentionDone
+	t.stopRetention = nil
+}
@@ -0,0 +1,127 @@
+package frostdb
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/thanos-io/objstore"
+
+	"github.com/polarsignals/frostdb/dynparquet"
+)
+
+func TestTableRetention(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now().UnixMilli()
+	expired := dynparquet.Samples{
+		{ExampleType: "cpu", Labels: map[string]string{"label1": "old"}, Timestamp: 1, Value: 1},
+		{ExampleType: "cpu", Labels: map[string]string{"label1": "old"}, Timestamp: 2, Value: 2},
+	}
+	recent := dynparquet.Samples{
+		{ExampleType: "cpu", Labels: map[string]string{"label1": "new"}, Timestamp: now, Value: 3},
+	}
+
+	t.Run("InMemory", func(t *testing.T) {
+		c, db, table := openTestTable(t, nil, WithRetention(time.Hour))
+		defer c.Close()
+
+		insertSamples(t, table, expired)
+		insertSamples(t, table, recent)
+		require.Equal(t, map[string]int{"new": 1}, countRowsBy(t, db, "test", "labels.label1"))
+
+		require.NoError(t, table.EnsureCompaction())
+		require.Equal(t, map[string]int{"new": 1}, countRowsBy(t, db, "test", "labels.label1"))
+	})
+
+	t.Run("InvalidSchema", func(t *testing.T) {
+		c, err := New(WithLogger(newTestLogger(t)))
+		require.NoError(t, err)
+		defer c.Close()
+		db, err := c.DB(ctx, "test")
+		require.NoError(t, err)
+
+		def := dynparquet.SampleDefinition()
+		columns := def.Columns[:0]
+		for _, col := range def.Columns {
+			if col.Name != "timestamp" {
+				columns = append(columns, col)
+			}
+		}
+		def.Columns = columns
+		sortingColumns := def.SortingColumns[:0]
+		for _, col := range def.SortingColumns {
+			if col.Name != "timestamp" {
+				sortingColumns = append(sortingColumns, col)
+			}
+		}
+		def.SortingColumns = sortingColumns
+		_, err = db.Table("test", NewTableConfig(def))
+		require.NoError(t, err)
+		_, err = db.Table("test2", NewTableConfig(def, WithRetention(time.Hour)))
+		require.ErrorContains(t, err, "retention")
+	})
+
+	t.Run("Column", func(t *testing.T) {
+		// Timestamps are in seconds.
+		c, db, table := openTestTable(t, nil, WithRetention(time.Hour), WithRetentionColumn("timestamp", time.Second))
+		defer c.Close()
+
+		// The retention column must be a non-dynamic int64 column.
+		for _, column := range []string{"missing", "labels", "example_type"} {
+			_, err := db.Table("invalid", NewTableConfig(
+				dynparquet.SampleDefinition(),
+				WithRetention(time.Hour),
+				WithRetentionColumn(column, time.Second),
+			))
+			require.ErrorContains(t, err, "retention requires")
+		}
+		require.Error(t, WithRetentionColumn("timestamp", 0)(NewTableConfig(dynparquet.SampleDefinition())))
+
+		nowSeconds := time.Now().Unix()
+		insertSamples(t, table, dynparquet.Samples{
+			{ExampleType: "cpu", Labels: map[string]string{"label1": "old"}, Timestamp: nowSeconds - 2*60*60, Value: 1},
+		})
+		insertSamples(t, table, dynparquet.Samples{
+			{ExampleType: "cpu", Labels: map[string]string{"label1": "new"}, Timestamp: nowSeconds, Value: 2},
+		})
+		require.Equal(t, map[string]int{"new": 1}, countRowsBy(t, db, "test", "labels.label1"))
+	})
+
+	t.Run("Bucket", func(t *testing.T) {
+		bucket := objstore.NewInMemBucket()
+		options := []Option{
+			WithReadWriteStorage(NewDefaultObjstoreBucket(bucket)),
+			WithRetentionCheckInterval(0),
+		}
+		blocks := func(t *testing.T) int {
+			t.Helper()
+			n := 0
+			for name := range bucket.Objects() {
+				if strings.HasSuffix(name, "data.parquet") {
+					n++
+				}
+			}
+			return n
+		}
+
+		// Persist one block with expired and one with recent data before
+		// retention is configured.
+		c, _, table := openTestTable(t, options)
+		insertSamples(t, table, expired)
+		require.NoError(t, c.Close())
+		c, _, table = openTestTable(t, options)
+		insertSamples(t, table, recent)
+		require.NoError(t, c.Close())
+		require.Equal(t, 2, blocks(t))
+
+		c, _, table = openTestTable(t, options, WithRetention(time.Hour))
+		defer c.Close()
+		require.Equal(t, map[string]int{"new": 1}, countRowsBy(t, table.db, "test", "labels.label1"))
+
+		require.NoError(t, table.EnforceRetention(ctx))
+		require.Equal(t, 1, blocks(t))
+		require.Equal(t, map[string]int{"new": 1}, countRowsBy(t, table.db, "test", "labels.label1"))
+	})
+}
@@ -0,0 +1,334 @@
+package samples
+
+import (
+	"bytes"
+	"cmp"
+	"fmt"
+	"math/rand"
+	"slices"
+
+	"github.com/apache/arrow/go/v17/arrow"
+	"github.com/apache/arrow/go/v17/arrow/array"
+	"github.com/apache/arrow/go/v17/arrow/memory"
+
+	schemapb "github.com/polarsignals/frostdb/gen/proto/go/frostdb/schema/v1alpha1"
+)
+
+// Generator generates random records that are valid for a v1alpha1 schema
+// definition, to test tables with schemas beyond the Sample schema. Values are
+// drawn from a limited number of distinct values per column, so that the
+// records contain duplicates to group, deduplicate and filter on. Generators
+// created with the same definition, seed and options generate the same
+// records.
+type Generator struct {
+	rng     *rand.Rand
+	columns []generatorColumn
+	schema  *arrow.Schema
+	// sortBy holds the indexes of the generated columns to sort rows by, in
+	// order of precedence.
+	sortBy []sortBy
+
+	cardinality     int
+	dynamicColumns  int
+	nullProbability float64
+	sorted          bool
+}
+
+type generatorColumn struct {
+	layout   *schemapb.StorageLayout
+	nullable bool
+}
+
+type sortBy struct {
+	column     int
+	descending bool
+	nullsFirst bool
+}
+
+type GeneratorOption func(*Generator)
+
+// WithCardinality sets the number of distinct values generated per column.
+// The default is 10.
+func WithCardinality(n int) GeneratorOption {
+	return func(g *Generator) {
+		g.cardinality = n
+	}
+}
+
+// WithDynamicColumns sets the number of concrete columns generated per
+// dynamic column. The default is 3.
+func WithDynamicColumns(n int) GeneratorOption {
+	return func(g *Generator) {
+		g.dynamicColumns = n
+	}
+}
+
+// WithNullProbability sets the probability of a value of a nullable or
+// dynamic column being null. The default is 0.1.
+func WithNullProbability(p float64) GeneratorOption {
+	return func(g *Generator) {
+		g.nullProbability = p
+	}
+}
+
+// WithSorted sorts the rows of the generated records by the sorting columns
+// of the schema definition, as they would be stored in a table.
+func WithSorted() GeneratorOption {
+	return func(g *Generator) {
+		g.sorted = true
+	}
+}
+
+// NewGenerator returns a Generator of records for the schema definition def,
+// seeded with seed.
+func NewGenerator(def *schemapb.Schema, seed int64, options ...GeneratorOption) (*Generator, error) {
+	g := &Generator{
+		rng:             rand.New(rand.NewSource(seed)),
+		cardinality:     10,
+		dynamicColumns:  3,
+		nullProbability: 0.1,
+	}
+	for _, option := range options {
+		option(g)
+	}
+	if g.cardinality < 1 {
+		return nil, fmt.Errorf("cardinality must be positive, got %d", g.cardinality)
+	}
+	if g.dynamicColumns < 0 {
+		return nil, fmt.Errorf("number of dynamic columns must not be negative, got %d", g.dynamicColumns)
+	}
+	if g.nullProbability < 0 || g.nullProbability > 1 {
+		return nil, fmt.Errorf("null probability must be between 0 and 1, got %v", g.nullProbability)
+	}
+
+	// Columns are generated in the order of their names, which is the order
+	// they are stored in.
+	columns := slices.Clone(def.GetColumns())
+	slices.SortFunc(columns, func(a, b *schemapb.Column) int {
+		return cmp.Compare(a.GetName(), b.GetName())
+	})
+	indexes := make(map[string][]int, len(columns))
+	fields := make([]arrow.Field, 0, len(columns))
+	for _, col := range columns {
+		layout := col.GetStorageLayout()
+		dt, err := arrowType(layout)
+		if err != nil {
+			return nil, fmt.Errorf("column %q: %w", col.GetName(), err)
+		}
+		names := []string{col.GetName()}
+		if col.GetDynamic() {
+			names = names[:0]
+			for i := 0; i < g.dynamicColumns; i++ {
+				names = append(names, fmt.Sprintf("%s.key%d", col.GetName(), i))
+			}
+		}
+		for _, name := range names {
+			indexes[col.GetName()] = append(indexes[col.GetName()], len(g.columns))
+			g.columns = append(g.columns, generatorColumn{
+				layout:   layout,
+				nullable: layout.GetNullable() || col.GetDynamic(),
+			})
+			fields = append(fields, arrow.Field{
+				Name:     name,
+				Type:     dt,
+				Nullable: layout.GetNullable() || col.GetDynamic(),
+			})
+		}
+	}
+	g.schema = arrow.NewSchema(fields, nil)
+
+	for _, col := range def.GetSortingColumns() {
+		is, ok := indexes[col.GetName()]
+		if !ok {
+			return nil, fmt.Errorf("sorting column %q not found", col.GetName())
+		}
+		for _, i := range is {
+			g.sortBy = append(g.sortBy, sortBy{
+				column:     i,
+				descending: col.GetDirection() == schemapb.SortingColumn_DIRECTION_DESCENDING,
+				nullsFirst: col.GetNullsFirst(),
+			})
+		}
+	}
+	return g, nil
+}
+
+func arrowType(layout *schemapb.StorageLayout) (arrow.DataType, error) {
+	var dt arrow.DataType
+	switch layout.GetType() {
+	case schemapb.StorageLayout_TYPE_STRING:
+		dt = arrow.BinaryTypes.String
+	case schemapb.StorageLayout_TYPE_INT64:
+		dt = arrow.PrimitiveTypes.Int64
+	case schemapb.StorageLayout_TYPE_DOUBLE:
+		dt = arrow.PrimitiveTypes.Float64
+	case schemapb.StorageLayout_TYPE_BOOL:
+		dt = arrow.FixedWidthTypes.Boolean
+	case schemapb.StorageLayout_TYPE_INT32:
+		dt = arrow.PrimitiveTypes.Int32
+	case schemapb.StorageLayout_TYPE_UINT64:
+		dt = arrow.PrimitiveTypes.Uint64
+	case schemapb.StorageLayout_TYPE_TIMESTAMP_MILLIS:
+		dt = &arrow.TimestampType{Unit: arrow.Millisecond, TimeZone: "UTC"}
+	case schemapb.StorageLayout_TYPE_TIMESTAMP_MICROS:
+		dt = &arrow.TimestampType{Unit: arrow.Microsecond, TimeZone: "UTC"}
+	case schemapb.StorageLayout_TYPE_TIMESTAMP_NANOS:
+		dt = &arrow.TimestampType{Unit: arrow.Nanosecond, TimeZone: "UTC"}
+	default:
+		return nil, fmt.Errorf("unsupported type %s", layout.GetType())
+	}
+	if layout.GetRepeated() {
+		dt = arrow.ListOf(dt)
+	}
+	return dt, nil
+}
+
+// Schema returns the schema of the generated records. Dynamic columns are
+// generated as the concrete columns "<name>.key0" to "<name>.key<n-1>", see
+// WithDynamicColumns.
+func (g *Generator) Schema() *arrow.Schema {
+	return g.schema
+}
+
+// Record generates a record with n rows. The caller must release it.
+func (g *Generator) Record(mem memory.Allocator, n int) arrow.Record {
+	rows := make([][]any, n)
+	for i := range rows {
+		row := make([]any, len(g.columns))
+		for j, col := range g.columns {
+			if col.nullable && g.rng.Float64() < g.nullProbability {
+				continue
+			}
+			if !col.layout.GetRepeated() {
+				row[j] = g.value(col.layout.GetType())
+				continue
+			}
+			list := make([]any, g.rng.Intn(4))
+			for k := range list {
+				list[k] = g.value(col.layout.GetType())
+			}
+			row[j] = list
+		}
+		rows[i] = row
+	}
+	if g.sorted {
+		slices.SortStableFunc(rows, g.compareRows)
+	}
+
+	b := array.NewRecordBuilder(mem, g.schema)
+	defer b.Release()
+	for i := range g.columns {
+		fb := b.Field(i)
+		for _, row := range rows {
+			appendValue(fb, row[i])
+		}
+	}
+	return b.NewRecord()
+}
+
+// value returns a random value of the given type. Strings are returned as
+// []byte, all other types as their Go equivalent.
+func (g *Generator) value(typ schemapb.StorageLayout_Type) any {
+	v := g.rng.Intn(g.cardinality)
+	switch typ {
+	case schemapb.StorageLayout_TYPE_STRING:
+		return []byte(fmt.Sprintf("value%d", v))
+	case schemapb.StorageLayout_TYPE_DOUBLE:
+		return float64(v) + 0.5
+	case schemapb.StorageLayout_TYPE_BOOL:
+		return v%2 == 1
+	case schemapb.StorageLayout_TYPE_INT32:
+		return int32(v)
+	case schemapb.StorageLayout_TYPE_UINT64:
+		return uint64(v)
+	default:
+		// Integers and timestamps.
+		return int64(v)
+	}
+}
+
+func (g *Generator) compareRows(a, b []any) int {
+	for _, s := range g.sortBy {
+		av, bv := a[s.column], b[s.column]
+		switch {
+		case av == nil && bv == nil:
+			continue
+		case av == nil:
+			if s.nullsFirst {
+				return -1
+			}
+			return 1
+		case bv == nil:
+			if s.nullsFirst {
+				return 1
+			}
+			return -1
+		}
+		c := compareValues(av, bv)
+		if s.descending {
+			c = -c
+		}
+		if c != 0 {
+			return c
+		}
+	}
+	return 0
+}
+
+func compareValues(a, b any) int {
+	switch a := a.(type) {
+	case []byte:
+		return bytes.Compare(a, b.([]byte))
+	case int64:
+		return cmp.Compare(a, b.(int64))
+	case float64:
+		return cmp.Compare(a, b.(float64))
+	case bool:
+		switch b := b.(bool); {
+		case a == b:
+			return 0
+		case b:
+			return -1
+		default:
+			return 1
+		}
+	case int32:
+		return cmp.Compare(a, b.(int32))
+	case uint64:
+		return cmp.Compare(a, b.(uint64))
+	case []any:
+		return slices.CompareFunc(a, b.([]any), compareValues)
+	default:
+		panic(fmt.Sprintf("unsupported value type %T", a))
+	}
+}
+
+func appendValue(b array.Builder, v any) {
+	if v == nil {
+		b.AppendNull()
+		return
+	}
+	switch b := b.(type) {
+	case *array.StringBuilder:
+		b.Append(string(v.([]byte)))
+	case *array.Int64Builder:
+		b.Append(v.(int64))
+	case *array.Float64Builder:
+		b.Append(v.(float64))
+	case *array.BooleanBuilder:
+		b.Append(v.(bool))
+	case *array.Int32Builder:
+		b.Append(v.(int32))
+	case *array.Uint64Builder:
+		b.Append(v.(uint64))
+	case *array.TimestampBuilder:
+		b.Append(arrow.Timestamp(v.(int64)))
+	case *array.ListBuilder:
+		b.Append(true)
+		for _, e := range v.([]any) {
+			appendValue(b.ValueBuilder(), e)
+		}
+	default:
+		panic(fmt.Sprintf("unsupported builder %T", b))
+	}
+}
@@ -0,0 +1,96 @@
+package samples_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/apache/arrow/go/v17/arrow"
+	"github.com/apache/arrow/go/v17/arrow/array"
+	"github.com/apache/arrow/go/v17/arrow/memory"
+	"github.com/stretchr/testify/require"
+
+	"github.com/polarsignals/frostdb"
+	schemapb "github.com/polarsignals/frostdb/gen/proto/go/frostdb/schema/v1alpha1"
+	"github.com/polarsignals/frostdb/query"
+	"github.com/polarsignals/frostdb/query/logicalplan"
+	"github.com/polarsignals/frostdb/samples"
+)
+
+func TestGenerator(t *testing.T) {
+	def := &schemapb.Schema{
+		Name: "test",
+		Columns: []*schemapb.Column{{
+			Name:          "name",
+			StorageLayout: &schemapb.StorageLayout{Type: schemapb.StorageLayout_TYPE_STRING, Encoding: schemapb.StorageLayout_ENCODING_RLE_DICTIONARY},
+		}, {
+			Name:          "labels",
+			StorageLayout: &schemapb.StorageLayout{Type: schemapb.StorageLayout_TYPE_STRING, Nullable: true},
+			Dynamic:       true,
+		}, {
+			Name:          "timestamp",
+			StorageLayout: &schemapb.StorageLayout{Type: schemapb.StorageLayout_TYPE_TIMESTAMP_MILLIS},
+		}, {
+			Name:          "value",
+			StorageLayout: &schemapb.StorageLayout{Type: schemapb.StorageLayout_TYPE_DOUBLE, Nullable: true},
+		}, {
+			Name:          "count",
+			StorageLayout: &schemapb.StorageLayout{Type: schemapb.StorageLayout_TYPE_UINT64},
+		}, {
+			Name:          "ids",
+			StorageLayout: &schemapb.StorageLayout{Type: schemapb.StorageLayout_TYPE_INT64, Repeated: true},
+		}},
+		SortingColumns: []*schemapb.SortingColumn{{
+			Name:      "name",
+			Direction: schemapb.SortingColumn_DIRECTION_DESCENDING,
+		}, {
+			Name:       "labels",
+			Direction:  schemapb.SortingColumn_DIRECTION_ASCENDING,
+			NullsFirst: true,
+		}, {
+			Name:      "timestamp",
+			Direction: schemapb.SortingColumn_DIRECTION_ASCENDING,
+		}},
+	}
+	mem := memory.NewGoAllocator()
+
+	g, err := samples.NewGenerator(def, 42, samples.WithSorted())
+	require.NoError(t, err)
+	r := g.Record(mem, 100)
+	defer r.Release()
+	require.Equal(t, int64(100), r.NumRows())
+	require.True(t, r.Schema().Equal(g.Schema()))
+
+	// The same seed generates the same records.
+	g, err = samples.NewGenerator(def, 42, samples.WithSorted())
+	require.NoError(t, err)
+	same := g.Record(mem, 100)
+	defer same.Release()
+	require.True(t, array.RecordEqual(r, same))
+
+	names := r.Column(r.Schema().FieldIndices("name")[0]).(*array.String)
+	for i := 1; i < names.Len(); i++ {
+		require.GreaterOrEqual(t, names.Value(i-1), names.Value(i))
+	}
+
+	// Generated records are valid for the schema.
+	c, err := frostdb.New()
+	require.NoError(t, err)
+	defer c.Close()
+	db, err := c.DB(context.Background(), "test")
+	require.NoError(t, err)
+	table, err := db.Table("test", frostdb.NewTableConfig(def))
+	require.NoError(t, err)
+	_, err = table.InsertRecord(context.Background(), r)
+	require.NoError(t, err)
+
+	rows := int64(0)
+	err = query.NewEngine(mem, db.TableProvider()).
+		ScanTable("test").
+		Project(logicalplan.DynCol("labels"), logicalplan.Col("ids")).
+		Execute(context.Background(), func(_ context.Context, r arrow.Record) error {
+			rows += r.NumRows()
+			return nil
+		})
+	require.NoError(t, err)
+	require.Equal(t, int64(100), rows)
+}
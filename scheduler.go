@@ -0,0 +1,213 @@
+package frostdb
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// WorkClass is a class of background work run by the column store. Work
+// classes are listed in order of decreasing priority: when the total
+// background concurrency is limited, queued work of a higher priority class is
+// started before queued work of a lower priority class.
+type WorkClass int
+
+const (
+	// WorkPersistence is the persistence of rotated table blocks to storage.
+	// It has the highest priority since rotated blocks are held in memory
+	// until they are persisted.
+	WorkPersistence WorkClass = iota
+	// WorkCompaction is the compaction of the LSM index of a table.
+	WorkCompaction
+	// WorkSnapshot is the snapshotting of a database and the background
+	// verification of snapshots.
+	WorkSnapshot
+	// WorkRetention is the enforcement of the retention window of a table.
+	WorkRetention
+	// WorkMigration is the rewriting of persisted blocks with the current
+	// layout of a table, see Table.Migrate.
+	WorkMigration
+	// WorkBlockGC is the deletion of superseded persisted blocks of a
+	// table, see WithBlockGC.
+	WorkBlockGC
+
+	numWorkClasses
+)
+
+func (c WorkClass) String() string {
+	switch c {
+	case WorkPersistence:
+		return "persistence"
+	case WorkCompaction:
+		return "compaction"
+	case WorkSnapshot:
+		return "snapshot"
+	case WorkRetention:
+		return "retention"
+	case WorkMigration:
+		return "migration"
+	case WorkBlockGC:
+		return "block_gc"
+	default:
+		return fmt.Sprintf("WorkClass(%d)", int(c))
+	}
+}
+
+// TaskState is the state of a background task.
+type TaskState int
+
+const (
+	TaskQueued TaskState = iota
+	TaskRunning
+)
+
+func (s TaskState) String() string {
+	switch s {
+	case TaskQueued:
+		return "queued"
+	case TaskRunning:
+		return "running"
+	default:
+		return fmt.Sprintf("TaskState(%d)", int(s))
+	}
+}
+
+// TaskInfo describes a queued or running background task.
+type TaskInfo struct {
+	Class WorkClass
+	// Name describes the task, e.g. the table it operates on.
+	Name  string
+	State TaskState
+	// Since is the time the task was queued if it is queued, or the time it
+	// started running if it is running.
+	Since time.Time
+}
+
+type task struct {
+	id    uint64
+	class WorkClass
+	name  string
+	since time.Time
+	fn    func()
+}
+
+// scheduler runs the background work of a column store. Each work class has a
+// concurrency budget, the maximum number of tasks of the class that run at the
+// same time, and the total number of running tasks can be limited as well.
+// Tasks that can't be started right away are queued until a slot frees up. A
+// budget <= 0 is unlimited.
+type scheduler struct {
+	mtx sync.Mutex
+
+	maxConcurrency int
+	budgets        [numWorkClasses]int
+
+	nextID  uint64
+	queued  [numWorkClasses][]*task
+	running map[uint64]*task
+	counts  [numWorkClasses]int
+
+	// now returns the time tasks are queued and started at.
+	now func() time.Time
+}
+
+func newScheduler() *scheduler {
+	return &scheduler{
+		running: make(map[uint64]*task),
+		now:     time.Now,
+	}
+}
+
+// Go runs fn in a new goroutine once the budgets of the work class allow it.
+func (s *scheduler) Go(class WorkClass, name string, fn func()) {
+	s.mtx.Lock()
+	s.nextID++
+	t := &task{
+		id:    s.nextID,
+		class: class,
+		name:  name,
+		since: s.now(),
+		fn:    fn,
+	}
+	s.queued[class] = append(s.queued[class], t)
+	s.dispatchLocked()
+	s.mtx.Unlock()
+}
+
+// Do runs fn once the budgets of the work class allow it and waits for it to
+// return.
+func (s *scheduler) Do(class WorkClass, name string, fn func()) {
+	done := make(chan struct{})
+	s.Go(class, name, func() {
+		defer close(done)
+		fn()
+	})
+	<-done
+}
+
+// dispatchLocked starts queued tasks in priority order until no more tasks
+// can be started. s.mtx must be held.
+func (s *scheduler) dispatchLocked() {
+	for class := WorkClass(0); class < numWorkClasses; class++ {
+		for len(s.queued[class]) > 0 {
+			if s.maxConcurrency > 0 && len(s.running) >= s.maxConcurrency {
+				return
+			}
+			if budget := s.budgets[class]; budget > 0 && s.counts[class] >= budget {
+				break
+			}
+			t := s.queued[class][0]
+			s.queued[class][0] = nil
+			s.queued[class] = s.queued[class][1:]
+			t.since = s.now()
+			s.running[t.id] = t
+			s.counts[class]++
+			go s.run(t)
+		}
+	}
+}
+
+func (s *scheduler) run(t *task) {
+	defer func() {
+		s.mtx.Lock()
+		delete(s.running, t.id)
+		s.counts[t.class]--
+		s.dispatchLocked()
+		s.mtx.Unlock()
+	}()
+	t.fn()
+}
+
+// Tasks returns the queued and running tasks, ordered by work class and the
+// time they were queued or started.
+func (s *scheduler) Tasks() []TaskInfo {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	tasks := make([]TaskInfo, 0, len(s.running))
+	for _, t := range s.running {
+		tasks = append(tasks, TaskInfo{Class: t.class, Name: t.name, State: TaskRunning, Since: t.since})
+	}
+	for class := WorkClass(0); class < numWorkClasses; class++ {
+		for _, t := range s.queued[class] {
+			tasks = append(tasks, TaskInfo{Class: t.class, Name: t.name, State: TaskQueued, Since: t.since})
+		}
+	}
+	sort.SliceStable(tasks, func(i, j int) bool {
+		if tasks[i].Class != tasks[j].Class {
+			return tasks[i].Class < tasks[j].Class
+		}
+		if tasks[i].State != tasks[j].State {
+			return tasks[i].State > tasks[j].State
+		}
+		return tasks[i].Since.Before(tasks[j].Since)
+	})
+	return tasks
+}
+
+// BackgroundTasks returns the background tasks of the column store that are
+// currently queued or running.
+func (s *ColumnStore) BackgroundTasks() []TaskInfo {
+	return s.scheduler.Tasks()
+}
@@ -0,0 +1,74 @@
+package frostdb
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestScheduler(t *testing.T) {
+	t.Run("Budget", func(t *testing.T) {
+		s := newScheduler()
+		s.budgets[WorkCompaction] = 1
+
+		release := make(chan struct{})
+		started := make(chan string, 2)
+		var wg sync.WaitGroup
+		wg.Add(2)
+		for _, name := range []string{"a", "b"} {
+			name := name
+			s.Go(WorkCompaction, name, func() {
+				defer wg.Done()
+				started <- name
+				<-release
+			})
+		}
+		require.Equal(t, "a", <-started)
+
+		tasks := s.Tasks()
+		require.Len(t, tasks, 2)
+		require.Equal(t, "a", tasks[0].Name)
+		require.Equal(t, TaskRunning, tasks[0].State)
+		require.Equal(t, "b", tasks[1].Name)
+		require.Equal(t, TaskQueued, tasks[1].State)
+
+		release <- struct{}{}
+		require.Equal(t, "b", <-started)
+		release <- struct{}{}
+		wg.Wait()
+		require.Eventually(t, func() bool { return len(s.Tasks()) == 0 }, time.Second, time.Millisecond)
+	})
+
+	t.Run("Priority", func(t *testing.T) {
+		s := newScheduler()
+		s.maxConcurrency = 1
+
+		release := make(chan struct{})
+		var (
+			mtx   sync.Mutex
+			order []WorkClass
+		)
+		record := func(class WorkClass) func() {
+			return func() {
+				mtx.Lock()
+				order = append(order, class)
+				mtx.Unlock()
+			}
+		}
+		s.Go(WorkSnapshot, "blocker", func() { <-release })
+		s.Go(WorkRetention, "", record(WorkRetention))
+		s.Go(WorkSnapshot, "", record(WorkSnapshot))
+		s.Go(WorkCompaction, "", record(WorkCompaction))
+		s.Go(WorkPersistence, "", record(WorkPersistence))
+		require.Len(t, s.Tasks(), 5)
+
+		close(release)
+		s.Do(WorkRetention, "", func() {})
+
+		mtx.Lock()
+		defer mtx.Unlock()
+		require.Equal(t, []WorkClass{WorkPersistence, WorkCompaction, WorkSnapshot, WorkRetention}, order)
+	})
+}
@@ -0,0 +1,251 @@
+package frostdb
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/oklog/ulid/v2"
+	"github.com/parquet-go/parquet-go"
+	"golang.org/x/sync/errgroup"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/anypb"
+
+	"github.com/polarsignals/frostdb/dynparquet"
+)
+
+// Parquet key-value metadata keys recording the schema a block was written
+// with.
+const (
+	// BlockSchemaKey holds the schema definition, encoded as the JSON
+	// representation of a google.protobuf.Any.
+	BlockSchemaKey = "frostdb.schema"
+	// BlockSchemaFingerprintKey holds the fingerprint of the schema
+	// definition, see SchemaFingerprint.
+	BlockSchemaFingerprintKey = "frostdb.schema.fingerprint"
+)
+
+// SchemaFingerprint returns a fingerprint identifying the given schema
+// definition: the hex encoded SHA-256 of its deterministic protobuf encoding.
+func SchemaFingerprint(def proto.Message) (string, error) {
+	b, err := proto.MarshalOptions{Deterministic: true}.Marshal(def)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// schemaMetadataOptions returns the writer options that record the given
+// schema in the metadata of the written parquet file.
+func schemaMetadataOptions(schema *dynparquet.Schema) ([]parquet.WriterOption, error) {
+	def := schema.Definition()
+	fingerprint, err := SchemaFingerprint(def)
+	if err != nil {
+		return nil, fmt.Errorf("fingerprint schema: %w", err)
+	}
+	msg, err := anypb.New(def)
+	if err != nil {
+		return nil, fmt.Errorf("wrap schema: %w", err)
+	}
+	encoded, err := protojson.Marshal(msg)
+	if err != nil {
+		return nil, fmt.Errorf("encode schema: %w", err)
+	}
+	return []parquet.WriterOption{
+		parquet.KeyValueMetadata(BlockSchemaFingerprintKey, fingerprint),
+		parquet.KeyValueMetadata(BlockSchemaKey, string(encoded)),
+	}, nil
+}
+
+// blockSchemaFromFile returns the fingerprint and definition of the schema
+// recorded in the metadata of the given block file. Both are empty if the
+// block does not record its schema, e.g. because it was written by an older
+// version.
+func blockSchemaFromFile(file *parquet.File) (string, proto.Message, error) {
+	fingerprint, ok := file.Lookup(BlockSchemaFingerprintKey)
+	if !ok {
+		return "", nil, nil
+	}
+	encoded, ok := file.Lookup(BlockSchemaKey)
+	if !ok {
+		return fingerprint, nil, nil
+	}
+	msg := &anypb.Any{}
+	if err := protojson.Unmarshal([]byte(encoded), msg); err != nil {
+		return "", nil, fmt.Errorf("decode schema: %w", err)
+	}
+	def, err := msg.UnmarshalNew()
+	if err != nil {
+		return "", nil, fmt.Errorf("decode schema: %w", err)
+	}
+	return fingerprint, def, nil
+}
+
+// BlockSchemaReader is implemented by data sources that can read the schema
+// their blocks were written with.
+type BlockSchemaReader interface {
+	// BlockSchemas calls callback with the ID of each block under prefix and
+	// the fingerprint and definition of the schema it was written with. The
+	// fingerprint and definition are empty for blocks that do not record
+	// their schema. Callback may be called concurrently.
+	BlockSchemas(ctx context.Context, prefix string, callback func(ctx context.Context, block ulid.ULID, fingerprint string, def proto.Message) error) error
+}
+
+// BlockSchemas implements the BlockSchemaReader interface. Only the footer of
+// each block is read. Corrupt blocks are quarantined and skipped.
+func (b *DefaultObjstoreBucket) BlockSchemas(ctx context.Context, prefix string, callback func(ctx context.Context, block ulid.ULID, fingerprint string, def proto.Message) error) error {
+	ctx, span := b.tracer.Start(ctx, "Source/BlockSchemas")
+	defer span.End()
+
+	errg := &errgroup.Group{}
+	errg.SetLimit(b.blockReaderLimit)
+	err := b.iterBlocks(ctx, prefix, func(blockDir string) error {
+		errg.Go(func() error {
+			blockUlid, err := ulid.Parse(filepath.Base(blockDir))
+			if err != nil {
+				return err
+			}
+			blockName := filepath.Join(blockDir, "data.parquet")
+			if b.quarantine.quarantined(blockName, b.clock.Now()) {
+				return nil
+			}
+			file, err := b.openOrQuarantine(blockName, func() (*parquet.File, error) {
+				return b.openBlockFooter(ctx, blockName)
+			})
+			if err != nil {
+				return err
+			}
+			if file == nil {
+				return nil
+			}
+			fingerprint, def, err := blockSchemaFromFile(file)
+			if err != nil {
+				return fmt.Errorf("block %s: %w", blockUlid, err)
+			}
+			return callback(ctx, blockUlid, fingerprint, def)
+		})
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	return errg.Wait()
+}
+
+// SchemaVersion is a schema that persisted blocks of a table were written
+// with.
+type SchemaVersion struct {
+	// Fingerprint identifies the schema, see SchemaFingerprint. It is empty
+	// for blocks that do not record their schema.
+	Fingerprint string
+	// Definition is the schema definition, either a v1alpha1 or a v1alpha2
+	// schema. It is nil for blocks that do not record their schema.
+	Definition proto.Message
+	// FirstBlock and LastBlock are the oldest and newest blocks written with
+	// the schema.
+	FirstBlock ulid.ULID
+	LastBlock  ulid.ULID
+	// Blocks is the number of blocks written with the schema.
+	Blocks int
+}
+
+// FirstSeen returns the time the oldest block written with the schema was
+// created.
+func (v SchemaVersion) FirstSeen() time.Time {
+	return ulid.Time(v.FirstBlock.Time())
+}
+
+// LastSeen returns the time the newest block written with the schema was
+// created.
+func (v SchemaVersion) LastSeen() time.Time {
+	return ulid.Time(v.LastBlock.Time())
+}
+
+// SchemaHistory returns the schemas the persisted blocks of the table were
+// written with, ordered by the time they were first used. Only data sources
+// implementing BlockSchemaReader are considered. If a schema was used again
+// after another one, e.g. because a schema change was rolled back, it is
+// reported once, covering all of its blocks.
+func (t *Table) SchemaHistory(ctx context.Context) ([]SchemaVersion, error) {
+	var (
+		mtx      sync.Mutex
+		versions = make(map[string]*SchemaVersion)
+	)
+	for _, source := range t.db.sources {
+		reader, ok := source.(BlockSchemaReader)
+		if !ok {
+			continue
+		}
+		if err := reader.BlockSchemas(ctx, filepath.Join(t.db.name, t.name), func(_ context.Context, block ulid.ULID, fingerprint string, def proto.Message) error {
+			mtx.Lock()
+			defer mtx.Unlock()
+			v, ok := versions[fingerprint]
+			if !ok {
+				versions[fingerprint] = &SchemaVersion{
+					Fingerprint: fingerprint,
+					Definition:  def,
+					FirstBlock:  block,
+					LastBlock:   block,
+					Blocks:      1,
+				}
+				return nil
+			}
+			if block.Compare(v.FirstBlock) < 0 {
+				v.FirstBlock = block
+			}
+			if block.Compare(v.LastBlock) > 0 {
+				v.LastBlock = block
+			}
+			v.Blocks++
+			return nil
+		}); err != nil {
+			return nil, fmt.Errorf("read block schemas from %s: %w", source, err)
+		}
+	}
+
+	history := make([]SchemaVersion, 0, len(versions))
+	for _, v := range versions {
+		history = append(history, *v)
+	}
+	sort.Slice(history, func(i, j int) bool {
+		return history[i].FirstBlock.Compare(history[j].FirstBlock) < 0
+	})
+	return history, nil
+}
+
+// newestBlockSchema returns the definition of the schema the newest persisted
+// block of the table with the given name was written with, or nil if no
+// block records its schema. Only data sources implementing BlockSchemaReader
+// are considered.
+func (db *DB) newestBlockSchema(ctx context.Context, name string) (proto.Message, error) {
+	var (
+		mtx    sync.Mutex
+		newest ulid.ULID
+		schema proto.Message
+	)
+	for _, source := range db.sources {
+		reader, ok := source.(BlockSchemaReader)
+		if !ok {
+			continue
+		}
+		if err := reader.BlockSchemas(ctx, filepath.Join(db.name, name), func(_ context.Context, block ulid.ULID, _ string, def proto.Message) error {
+			mtx.Lock()
+			defer mtx.Unlock()
+			if def != nil && block.Compare(newest) > 0 {
+				newest = block
+				schema = def
+			}
+			return nil
+		}); err != nil {
+			return nil, fmt.Errorf("read blocks of table %s from %s: %w", name, source, err)
+		}
+	}
+	return schema, nil
+}
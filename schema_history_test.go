@@ -0,0 +1,63 @@
+package frostdb
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/thanos-io/objstore"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/polarsignals/frostdb/dynparquet"
+)
+
+func TestTableSchemaHistory(t *testing.T) {
+	ctx := context.Background()
+	bucket := NewDefaultObjstoreBucket(objstore.NewInMemBucket())
+
+	v1 := dynparquet.SampleDefinition()
+	v2 := dynparquet.SampleDefinition()
+	v2.Name = "test_v2"
+
+	// persist writes a block per timestamp with the given schema.
+	persist := func(t *testing.T, def proto.Message, timestamps ...int64) *Table {
+		c, err := New(WithLogger(newTestLogger(t)), WithReadWriteStorage(bucket))
+		require.NoError(t, err)
+		t.Cleanup(func() { c.Close() })
+		db, err := c.DB(ctx, "test")
+		require.NoError(t, err)
+		table, err := db.Table("test", NewTableConfig(def))
+		require.NoError(t, err)
+		for _, ts := range timestamps {
+			insertSampleRecords(ctx, t, table, ts)
+			var wg sync.WaitGroup
+			wg.Add(1)
+			require.NoError(t, table.RotateBlock(ctx, table.ActiveBlock(), WithRotateBlockWaitGroup(&wg)))
+			wg.Wait()
+		}
+		return table
+	}
+
+	persist(t, v1, 1, 2)
+	table := persist(t, v2, 3)
+
+	history, err := table.SchemaHistory(ctx)
+	require.NoError(t, err)
+	require.Len(t, history, 2)
+
+	fingerprint, err := SchemaFingerprint(v1)
+	require.NoError(t, err)
+	require.Equal(t, fingerprint, history[0].Fingerprint)
+	require.True(t, proto.Equal(v1, history[0].Definition))
+	require.Equal(t, 2, history[0].Blocks)
+	require.Equal(t, -1, history[0].FirstBlock.Compare(history[0].LastBlock))
+
+	fingerprint, err = SchemaFingerprint(v2)
+	require.NoError(t, err)
+	require.Equal(t, fingerprint, history[1].Fingerprint)
+	require.True(t, proto.Equal(v2, history[1].Definition))
+	require.Equal(t, 1, history[1].Blocks)
+	require.Equal(t, history[1].FirstBlock, history[1].LastBlock)
+	require.False(t, history[1].FirstSeen().Before(history[0].LastSeen()))
+}
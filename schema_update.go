@@ -0,0 +1,148 @@
+package frostdb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"google.golang.org/protobuf/proto"
+
+	"github.com/polarsignals/frostdb/dynparquet"
+	schemapb "github.com/polarsignals/frostdb/gen/proto/go/frostdb/schema/v1alpha1"
+	tablepb "github.com/polarsignals/frostdb/gen/proto/go/frostdb/table/v1alpha1"
+)
+
+// UpdateSchema changes the schema of the table to the v1alpha1 schema
+// definition def. Only changes that are compatible with the data written with
+// the current schema are allowed, i.e. adding nullable or dynamic columns,
+// other changes are reported as an *dynparquet.IncompatibleSchemaError. The
+// active block is rotated, so that the blocks created from now on, and the
+// new schema they are logged with in the WAL, use the new schema. Blocks
+// written with older schemas remain readable, the columns they lack are null.
+// The tables storing the sort orders of the table are updated accordingly.
+func (t *Table) UpdateSchema(def proto.Message) error {
+	newSchema, ok := def.(*schemapb.Schema)
+	if !ok {
+		return errors.New("only v1alpha1 schemas can be updated")
+	}
+	config := t.config.Load()
+	oldSchema := config.GetDeprecatedSchema()
+	if oldSchema == nil {
+		return errors.New("only tables with a v1alpha1 schema can be updated")
+	}
+	if proto.Equal(oldSchema, newSchema) {
+		return nil
+	}
+	if err := dynparquet.CheckCompatibility(oldSchema, newSchema).Err(); err != nil {
+		return err
+	}
+
+	config = proto.Clone(config).(*tablepb.TableConfig)
+	config.Schema = &tablepb.TableConfig_DeprecatedSchema{
+		DeprecatedSchema: proto.Clone(newSchema).(*schemapb.Schema),
+	}
+
+	// The sort order tables are updated first, so that all inserts into the
+	// table with the new schema can be mirrored into them.
+	for _, so := range config.SortOrders {
+		soTable, err := t.db.GetTable(sortOrderTableName(t.name, so.Name))
+		if err != nil {
+			return fmt.Errorf("sort order %s: %w", so.Name, err)
+		}
+		soConfig, err := sortOrderConfig(config, so)
+		if err != nil {
+			return fmt.Errorf("sort order %s: %w", so.Name, err)
+		}
+		if err := soTable.updateSchema(soConfig); err != nil {
+			return fmt.Errorf("sort order %s: %w", so.Name, err)
+		}
+	}
+	return t.updateSchema(config)
+}
+
+// UpdateSortingColumns changes the sorting columns of the table's v1alpha1
+// schema and starts a Migration re-sorting the persisted blocks in the
+// background, see Table.Migrate. Until the migration is done, queries are
+// served from a mix of blocks sorted by the old and by the new sorting
+// columns. Blocks that are not persisted yet are re-sorted when they are
+// persisted. The sorting columns of tables with a unique primary index or a
+// merge reducer, which identify rows by their sorting columns, cannot be
+// changed.
+func (t *Table) UpdateSortingColumns(ctx context.Context, sortingColumns []*schemapb.SortingColumn, options ...MigrationOption) (*Migration, error) {
+	config := t.config.Load()
+	oldSchema := config.GetDeprecatedSchema()
+	if oldSchema == nil {
+		return nil, errors.New("only tables with a v1alpha1 schema can be updated")
+	}
+	if oldSchema.UniquePrimaryIndex || t.mergeReducer != nil {
+		return nil, errors.New("the sorting columns of a table that deduplicates rows cannot be changed")
+	}
+	if len(sortingColumns) == 0 {
+		return nil, errors.New("at least one sorting column is required")
+	}
+	schema := t.schema.Load()
+	for _, col := range sortingColumns {
+		if _, ok := schema.ColumnByName(col.Name); !ok {
+			return nil, fmt.Errorf("sorting column %q not found", col.Name)
+		}
+	}
+	if err := t.checkBlockRewriters(); err != nil {
+		return nil, err
+	}
+
+	newSchema := proto.Clone(oldSchema).(*schemapb.Schema)
+	newSchema.SortingColumns = make([]*schemapb.SortingColumn, 0, len(sortingColumns))
+	for _, col := range sortingColumns {
+		newSchema.SortingColumns = append(newSchema.SortingColumns, proto.Clone(col).(*schemapb.SortingColumn))
+	}
+	if !proto.Equal(oldSchema, newSchema) {
+		config = proto.Clone(config).(*tablepb.TableConfig)
+		config.Schema = &tablepb.TableConfig_DeprecatedSchema{DeprecatedSchema: newSchema}
+		if err := t.updateSchema(config); err != nil {
+			return nil, err
+		}
+	}
+	return t.Migrate(ctx, options...)
+}
+
+// updateSchema replaces the config and schema of the table and rotates the
+// active block, so that the new config is logged in the WAL.
+func (t *Table) updateSchema(config *tablepb.TableConfig) error {
+	schema, err := schemaFromTableConfig(config)
+	if err != nil {
+		return fmt.Errorf("initialize schema: %w", err)
+	}
+
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+	if t.closing {
+		return ErrTableClosing
+	}
+
+	t.config.Store(config)
+	t.schema.Store(schema)
+	return t.rotateActiveBlockLocked()
+}
+
+// withUpdatedSchema returns config with the schema of the current config of a
+// table if the table's schema is a compatible update of the schema of config,
+// apart from its sorting columns. This allows the table to be opened with the
+// config it was created with after its schema was updated, e.g. on every
+// start of an application that calls Table.UpdateSchema or
+// Table.UpdateSortingColumns afterwards.
+func withUpdatedSchema(current, config *tablepb.TableConfig) *tablepb.TableConfig {
+	currentSchema, schema := current.GetDeprecatedSchema(), config.GetDeprecatedSchema()
+	if currentSchema == nil || schema == nil || proto.Equal(currentSchema, schema) {
+		return config
+	}
+	schema = proto.Clone(schema).(*schemapb.Schema)
+	schema.SortingColumns = currentSchema.SortingColumns
+	if dynparquet.CheckCompatibility(schema, currentSchema).Compatibility != dynparquet.Compatible {
+		return config
+	}
+	config = proto.Clone(config).(*tablepb.TableConfig)
+	config.Schema = &tablepb.TableConfig_DeprecatedSchema{
+		DeprecatedSchema: proto.Clone(currentSchema).(*schemapb.Schema),
+	}
+	return config
+}
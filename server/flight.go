@@ -0,0 +1,82 @@
+package server
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/apache/arrow/go/v17/arrow"
+	"github.com/apache/arrow/go/v17/arrow/flight"
+	"github.com/apache/arrow/go/v17/arrow/ipc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/polarsignals/frostdb"
+	pb "github.com/polarsignals/frostdb/gen/proto/go/frostdb/storage/v1alpha1"
+)
+
+// FlightServer serves the results of query plans as Arrow Flight streams, so
+// that Arrow-native clients such as pyarrow can consume large result sets
+// directly. It can be registered with a grpc.Server using
+// flight.RegisterFlightServiceServer.
+//
+// The ticket of a DoGet call is a binary encoded pb.QueryRequest, see
+// NewTicket. The records of a Flight stream share a single schema, so queries
+// whose results have varying schemas, e.g. due to dynamic columns, need to
+// project a fixed set of columns.
+type FlightServer struct {
+	flight.BaseFlightServer
+
+	server *Server
+}
+
+// NewFlightServer returns a FlightServer that queries the databases of store.
+func NewFlightServer(store *frostdb.ColumnStore, options ...Option) *FlightServer {
+	return &FlightServer{server: New(store, options...)}
+}
+
+// NewTicket returns the ticket to retrieve the results of the query of req
+// with DoGet.
+func NewTicket(req *pb.QueryRequest) (*flight.Ticket, error) {
+	data, err := req.MarshalVT()
+	if err != nil {
+		return nil, err
+	}
+	return &flight.Ticket{Ticket: data}, nil
+}
+
+// DoGet executes the query of the ticket and streams its results.
+func (s *FlightServer) DoGet(ticket *flight.Ticket, stream flight.FlightService_DoGetServer) error {
+	ctx, span := s.server.tracer.Start(stream.Context(), "FlightServer/DoGet")
+	defer span.End()
+
+	req := &pb.QueryRequest{}
+	if err := req.UnmarshalVT(ticket.GetTicket()); err != nil {
+		return status.Error(codes.InvalidArgument, fmt.Sprintf("decode ticket: %v", err))
+	}
+	builder, err := s.server.queryBuilder(req)
+	if err != nil {
+		return err
+	}
+
+	var (
+		w      *flight.Writer
+		schema *arrow.Schema
+	)
+	err = builder.Execute(ctx, func(_ context.Context, r arrow.Record) error {
+		if w == nil {
+			schema = r.Schema()
+			w = flight.NewRecordWriter(stream, ipc.WithSchema(schema), ipc.WithAllocator(s.server.pool))
+		} else if !schema.Equal(r.Schema()) {
+			return status.Error(codes.FailedPrecondition, fmt.Sprintf(
+				"query results have differing schemas %s and %s, project a fixed set of columns", schema, r.Schema(),
+			))
+		}
+		return w.Write(r)
+	})
+	if w != nil {
+		if closeErr := w.Close(); err == nil {
+			err = closeErr
+		}
+	}
+	return err
+}
@@ -0,0 +1,60 @@
+package server
+
+import (
+	"context"
+	"testing"
+
+	"github.com/apache/arrow/go/v17/arrow/flight"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/polarsignals/frostdb"
+	"github.com/polarsignals/frostdb/dynparquet"
+)
+
+func TestFlightServerDoGet(t *testing.T) {
+	ctx := context.Background()
+
+	c, err := frostdb.New()
+	require.NoError(t, err)
+	defer c.Close()
+
+	db, err := c.DB(ctx, "test")
+	require.NoError(t, err)
+	table, err := db.Table("test", frostdb.NewTableConfig(dynparquet.SampleDefinition()))
+	require.NoError(t, err)
+
+	samples := dynparquet.NewTestSamples()
+	r, err := samples.ToRecord()
+	require.NoError(t, err)
+	defer r.Release()
+	_, err = table.InsertRecord(ctx, r)
+	require.NoError(t, err)
+
+	client := flight.NewFlightServiceClient(newTestConn(t, func(srv *grpc.Server) {
+		flight.RegisterFlightServiceServer(srv, NewFlightServer(c))
+	}))
+
+	ticket, err := NewTicket(scan("test"))
+	require.NoError(t, err)
+	stream, err := client.DoGet(ctx, ticket)
+	require.NoError(t, err)
+	reader, err := flight.NewRecordReader(stream)
+	require.NoError(t, err)
+	defer reader.Release()
+	rows := int64(0)
+	for reader.Next() {
+		rows += reader.Record().NumRows()
+	}
+	require.NoError(t, reader.Err())
+	require.Equal(t, int64(len(samples)), rows)
+
+	ticket, err = NewTicket(scan("unknown"))
+	require.NoError(t, err)
+	stream, err = client.DoGet(ctx, ticket)
+	require.NoError(t, err)
+	_, err = stream.Recv()
+	require.Equal(t, codes.NotFound, status.Code(err))
+}
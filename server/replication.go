@@ -0,0 +1,110 @@
+package server
+
+import (
+	"errors"
+	"io"
+	"sync"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/polarsignals/frostdb"
+	walpb "github.com/polarsignals/frostdb/gen/proto/go/frostdb/wal/v1alpha1"
+)
+
+// ReplicationServer applies the WAL entries shipped by primaries, see
+// frostdb.WithWALShipping, to the databases of a standby ColumnStore. It
+// tracks the last entry applied from each primary, so that entries sent
+// again after a stream was reestablished are applied once. The tracked
+// entries are not persisted, a restarted standby applies entries it already
+// applied again. Once a primary marks the standby as out of sync, no further
+// entries of the primary are applied and its streams are rejected.
+type ReplicationServer struct {
+	walpb.UnimplementedWALReplicationServiceServer
+
+	store *frostdb.ColumnStore
+
+	mtx       sync.Mutex
+	primaries map[string]*primaryState
+}
+
+type primaryState struct {
+	// mtx serializes the streams of a primary, e.g. while a stream that
+	// failed on the primary's side is still open on the standby's side.
+	mtx     sync.Mutex
+	applied uint64
+	// outOfSync is set once the primary dropped entries.
+	outOfSync bool
+}
+
+// errOutOfSync returns the error returned to a primary that dropped entries.
+func errOutOfSync(primaryID string) error {
+	return status.Errorf(codes.FailedPrecondition, "standby is out of sync with primary %s, it needs to be restored from a snapshot of the primary", primaryID)
+}
+
+// NewReplicationServer returns a ReplicationServer that applies entries to the
+// databases of store. It can be registered with a grpc.Server using
+// walpb.RegisterWALReplicationServiceServer.
+func NewReplicationServer(store *frostdb.ColumnStore) *ReplicationServer {
+	return &ReplicationServer{
+		store:     store,
+		primaries: map[string]*primaryState{},
+	}
+}
+
+// Replicate applies the entries received on the stream in order and
+// acknowledges each of them once applied.
+func (s *ReplicationServer) Replicate(stream walpb.WALReplicationService_ReplicateServer) error {
+	ctx := stream.Context()
+
+	hello, err := stream.Recv()
+	if err != nil {
+		return err
+	}
+	if hello.GetPrimaryId() == "" {
+		return status.Error(codes.InvalidArgument, "the first request must identify the primary")
+	}
+	s.mtx.Lock()
+	primary, ok := s.primaries[hello.GetPrimaryId()]
+	if !ok {
+		primary = &primaryState{}
+		s.primaries[hello.GetPrimaryId()] = primary
+	}
+	s.mtx.Unlock()
+
+	primary.mtx.Lock()
+	defer primary.mtx.Unlock()
+	if primary.outOfSync {
+		return errOutOfSync(hello.GetPrimaryId())
+	}
+	if err := stream.Send(&walpb.ReplicateResponse{AckedSeq: primary.applied}); err != nil {
+		return err
+	}
+
+	for {
+		req, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if req.GetSeq() > primary.applied && req.GetOutOfSync() {
+			primary.outOfSync = true
+			return errOutOfSync(hello.GetPrimaryId())
+		}
+		if req.GetSeq() > primary.applied {
+			db, err := s.store.DB(ctx, req.GetDatabase())
+			if err != nil {
+				return status.Error(codes.Internal, err.Error())
+			}
+			if err := db.ApplyWALRecord(ctx, req.GetRecord()); err != nil {
+				return status.Errorf(codes.Internal, "apply entry %d: %v", req.GetSeq(), err)
+			}
+			primary.applied = req.GetSeq()
+		}
+		if err := stream.Send(&walpb.ReplicateResponse{AckedSeq: primary.applied}); err != nil {
+			return err
+		}
+	}
+}
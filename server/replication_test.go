@@ -0,0 +1,121 @@
+package server
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+
+	"github.com/polarsignals/frostdb"
+	"github.com/polarsignals/frostdb/dynparquet"
+	walpb "github.com/polarsignals/frostdb/gen/proto/go/frostdb/wal/v1alpha1"
+	"github.com/polarsignals/frostdb/query/logicalplan"
+)
+
+func TestReplication(t *testing.T) {
+	ctx := context.Background()
+
+	standby, err := frostdb.New()
+	require.NoError(t, err)
+	defer standby.Close()
+	conn := newTestConn(t, func(srv *grpc.Server) {
+		walpb.RegisterWALReplicationServiceServer(srv, NewReplicationServer(standby))
+	})
+
+	primary, err := frostdb.New(frostdb.WithWALShipping(
+		walpb.NewWALReplicationServiceClient(conn),
+		frostdb.WithWALShippingRetryInterval(10*time.Millisecond),
+	))
+	require.NoError(t, err)
+	defer primary.Close()
+
+	db, err := primary.DB(ctx, "test")
+	require.NoError(t, err)
+	table, err := db.Table("test", frostdb.NewTableConfig(dynparquet.SampleDefinition()))
+	require.NoError(t, err)
+	samples := dynparquet.NewTestSamples()
+	for i := 0; i < 3; i++ {
+		r, err := samples.ToRecord()
+		require.NoError(t, err)
+		_, err = table.InsertRecord(ctx, r)
+		require.NoError(t, err)
+		r.Release()
+	}
+	require.NoError(t, db.MetaPut(ctx, "key", []byte("value")))
+
+	require.Eventually(t, func() bool {
+		return primary.WALShippingStats().Pending == 0
+	}, 5*time.Second, 10*time.Millisecond)
+	stats := primary.WALShippingStats()
+	require.Zero(t, stats.Dropped)
+	require.NotZero(t, stats.Acknowledged)
+
+	client := newTestClient(t, standby)
+	require.Equal(t, int64(3*len(samples)), countRows(t, client, "test"))
+	standbyDB, err := standby.GetDB("test")
+	require.NoError(t, err)
+	value, ok := standbyDB.MetaGet("key")
+	require.True(t, ok)
+	require.Equal(t, []byte("value"), value)
+
+	// Deletes and truncates are applied to the standby's tables.
+	require.NoError(t, table.Delete(ctx, logicalplan.Col("labels.namespace").Eq(logicalplan.Literal("default"))))
+	require.Eventually(t, func() bool {
+		return countRows(t, client, "test") == 3
+	}, 5*time.Second, 10*time.Millisecond)
+	require.NoError(t, table.Truncate(ctx))
+	require.Eventually(t, func() bool {
+		return countRows(t, client, "test") == 0
+	}, 5*time.Second, 10*time.Millisecond)
+}
+
+func TestReplicationOutOfSync(t *testing.T) {
+	ctx := context.Background()
+
+	standby, err := frostdb.New()
+	require.NoError(t, err)
+	defer standby.Close()
+	srv := NewReplicationServer(standby)
+	conn := newTestConn(t, func(s *grpc.Server) {
+		walpb.RegisterWALReplicationServiceServer(s, srv)
+	})
+
+	// No entry fits, so the first entry already puts the standby out of
+	// sync.
+	primary, err := frostdb.New(frostdb.WithWALShipping(
+		walpb.NewWALReplicationServiceClient(conn),
+		frostdb.WithWALShippingMaxPendingBytes(1),
+		frostdb.WithWALShippingRetryInterval(10*time.Millisecond),
+	))
+	require.NoError(t, err)
+	defer primary.Close()
+
+	db, err := primary.DB(ctx, "test")
+	require.NoError(t, err)
+	require.NoError(t, db.MetaPut(ctx, "first", []byte("value")))
+	require.NoError(t, db.MetaPut(ctx, "second", []byte("value")))
+
+	stats := primary.WALShippingStats()
+	require.True(t, stats.OutOfSync)
+	require.Equal(t, uint64(2), stats.Dropped)
+	require.Eventually(t, func() bool {
+		srv.mtx.Lock()
+		defer srv.mtx.Unlock()
+		for _, p := range srv.primaries {
+			p.mtx.Lock()
+			outOfSync := p.outOfSync
+			p.mtx.Unlock()
+			if outOfSync {
+				return true
+			}
+		}
+		return false
+	}, 5*time.Second, 10*time.Millisecond)
+
+	// None of the entries was applied.
+	standbyDB, err := standby.DB(ctx, "test")
+	require.NoError(t, err)
+	require.Empty(t, standbyDB.MetaKeys())
+}
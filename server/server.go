@@ -0,0 +1,328 @@
+// Package server implements the FrostDBService gRPC service, which allows
+// other processes to query and write to the databases of a ColumnStore.
+package server
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/apache/arrow/go/v17/arrow"
+	"github.com/apache/arrow/go/v17/arrow/array"
+	"github.com/apache/arrow/go/v17/arrow/ipc"
+	"github.com/apache/arrow/go/v17/arrow/memory"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/polarsignals/frostdb"
+	"github.com/polarsignals/frostdb/dynparquet"
+	pb "github.com/polarsignals/frostdb/gen/proto/go/frostdb/storage/v1alpha1"
+	"github.com/polarsignals/frostdb/pqarrow"
+	"github.com/polarsignals/frostdb/query/exprpb"
+	"github.com/polarsignals/frostdb/query/logicalplan"
+	"github.com/polarsignals/frostdb/query/physicalplan"
+)
+
+// Server executes the query plans it receives against the databases of a
+// ColumnStore and streams the results back as Arrow IPC encoded records. It
+// also inserts the records it receives into the tables of the ColumnStore.
+type Server struct {
+	pb.UnimplementedFrostDBServiceServer
+
+	store    *frostdb.ColumnStore
+	pool     memory.Allocator
+	tracer   trace.Tracer
+	execOpts []physicalplan.Option
+}
+
+type Option func(*Server)
+
+func WithAllocator(pool memory.Allocator) Option {
+	return func(s *Server) {
+		s.pool = pool
+	}
+}
+
+func WithTracer(tracer trace.Tracer) Option {
+	return func(s *Server) {
+		s.tracer = tracer
+	}
+}
+
+func WithPhysicalplanOptions(opts ...physicalplan.Option) Option {
+	return func(s *Server) {
+		s.execOpts = opts
+	}
+}
+
+// New returns a Server that queries the databases of store. It can be
+// registered with a grpc.Server using pb.RegisterFrostDBServiceServer.
+func New(store *frostdb.ColumnStore, options ...Option) *Server {
+	s := &Server{
+		store:  store,
+		pool:   memory.NewGoAllocator(),
+		tracer: noop.NewTracerProvider().Tracer(""),
+	}
+
+	for _, option := range options {
+		option(s)
+	}
+
+	return s
+}
+
+// Query executes the query plan of the request against the database named by
+// its scan.
+func (s *Server) Query(req *pb.QueryRequest, stream pb.FrostDBService_QueryServer) error {
+	ctx, span := s.tracer.Start(stream.Context(), "Server/Query")
+	defer span.End()
+
+	builder, err := s.queryBuilder(req)
+	if err != nil {
+		return err
+	}
+
+	return builder.Execute(ctx, func(_ context.Context, r arrow.Record) error {
+		// gRPC may still reference the message after Send returns, so each
+		// record is encoded into its own buffer.
+		var buf bytes.Buffer
+		if err := writeRecord(&buf, r); err != nil {
+			return fmt.Errorf("encode record: %w", err)
+		}
+		return stream.Send(&pb.QueryResponse{Record: buf.Bytes()})
+	})
+}
+
+// queryBuilder returns the query builder of the plan of the request, or a
+// status error if the plan is invalid or its database does not exist.
+func (s *Server) queryBuilder(req *pb.QueryRequest) (exprpb.ProtoQueryBuilder, error) {
+	scan := scanBase(req.GetPlanRoot())
+	if scan == nil {
+		return exprpb.ProtoQueryBuilder{}, status.Error(codes.InvalidArgument, "query plan does not scan a table")
+	}
+	db, err := s.store.GetDB(scan.GetDatabase())
+	if err != nil {
+		return exprpb.ProtoQueryBuilder{}, status.Error(codes.NotFound, err.Error())
+	}
+
+	engine := exprpb.NewEngine(
+		s.pool,
+		db.TableProvider(),
+		exprpb.WithTracer(s.tracer),
+		exprpb.WithPhysicalplanOptions(s.execOpts...),
+	)
+	builder, err := engine.FromProto(req.GetPlanRoot())
+	if err != nil {
+		return exprpb.ProtoQueryBuilder{}, status.Error(codes.InvalidArgument, err.Error())
+	}
+	return builder, nil
+}
+
+// Write inserts the arrow records or the parquet file of the request into its
+// table. The database must exist unless the request asks to create it, and so
+// must the table unless the request contains its config. All the records of
+// the request are inserted in a single transaction.
+func (s *Server) Write(ctx context.Context, req *pb.WriteRequest) (*pb.WriteResponse, error) {
+	ctx, span := s.tracer.Start(ctx, "Server/Write")
+	defer span.End()
+
+	if req.GetDatabase() == "" || req.GetTable() == "" {
+		return nil, status.Error(codes.InvalidArgument, "database and table must be set")
+	}
+	if (len(req.GetRecord()) > 0) == (len(req.GetParquet()) > 0) {
+		return nil, status.Error(codes.InvalidArgument, "exactly one of record and parquet must be set")
+	}
+
+	db, err := s.store.GetDB(req.GetDatabase())
+	if err != nil {
+		if !req.GetCreateDatabase() {
+			return nil, status.Error(codes.NotFound, err.Error())
+		}
+		db, err = s.store.DB(ctx, req.GetDatabase())
+		if err != nil {
+			return nil, status.Error(codes.Internal, err.Error())
+		}
+	}
+	var table *frostdb.Table
+	if req.GetConfig() != nil {
+		table, err = db.Table(req.GetTable(), req.GetConfig())
+	} else {
+		table, err = db.GetTable(req.GetTable())
+	}
+	if err != nil {
+		var notFound frostdb.ErrTableNotFound
+		if errors.As(err, &notFound) {
+			return nil, status.Error(codes.NotFound, err.Error())
+		}
+		return nil, status.Error(codes.FailedPrecondition, err.Error())
+	}
+
+	var tx uint64
+	insert := func(r arrow.Record) error {
+		var err error
+		tx, err = table.InsertRecord(ctx, r)
+		return err
+	}
+	if len(req.GetRecord()) > 0 {
+		err = readRecords(req.GetRecord(), s.pool, insert)
+	} else {
+		err = readParquet(ctx, req.GetParquet(), s.pool, table.Schema(), insert)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &pb.WriteResponse{Tx: tx}, nil
+}
+
+// CopyTable copies the table with the given name of db as of a snapshot into
+// a table with the same name and config in the database targetDB of the
+// server the client is connected to, creating the database and the table if
+// they don't exist. Each exported record is sent in its own write request. It
+// returns the transaction of the snapshot, see frostdb.DB.ExportTable.
+func CopyTable(ctx context.Context, db *frostdb.DB, name string, client pb.FrostDBServiceClient, targetDB string) (uint64, error) {
+	table, err := db.GetTable(name)
+	if err != nil {
+		return 0, err
+	}
+	config := table.Config()
+
+	var buf bytes.Buffer
+	return db.ExportTable(ctx, name, func(ctx context.Context, r arrow.Record) error {
+		buf.Reset()
+		if err := writeRecord(&buf, r); err != nil {
+			return err
+		}
+		_, err := client.Write(ctx, &pb.WriteRequest{
+			Database:       targetDB,
+			Table:          name,
+			Config:         config,
+			Record:         buf.Bytes(),
+			CreateDatabase: true,
+		})
+		return err
+	})
+}
+
+// readRecords concatenates the records of the arrow IPC stream data into a
+// single record and calls f with it, so that a failed insert doesn't leave
+// part of the stream written.
+func readRecords(data []byte, pool memory.Allocator, f func(arrow.Record) error) error {
+	reader, err := ipc.NewReader(bytes.NewReader(data), ipc.WithAllocator(pool))
+	if err != nil {
+		return status.Error(codes.InvalidArgument, fmt.Sprintf("read record: %v", err))
+	}
+	defer reader.Release()
+
+	var records []arrow.Record
+	defer func() {
+		for _, r := range records {
+			r.Release()
+		}
+	}()
+	for reader.Next() {
+		r := reader.Record()
+		r.Retain()
+		records = append(records, r)
+	}
+	if err := reader.Err(); err != nil {
+		return status.Error(codes.InvalidArgument, fmt.Sprintf("read record: %v", err))
+	}
+	if len(records) == 0 {
+		return nil
+	}
+
+	r, err := concatRecords(pool, reader.Schema(), records)
+	if err != nil {
+		return status.Error(codes.InvalidArgument, fmt.Sprintf("concatenate records: %v", err))
+	}
+	defer r.Release()
+
+	if err := f(r); err != nil {
+		return status.Error(codes.Internal, err.Error())
+	}
+	return nil
+}
+
+// concatRecords concatenates records sharing the given schema column by column.
+func concatRecords(pool memory.Allocator, schema *arrow.Schema, records []arrow.Record) (arrow.Record, error) {
+	if len(records) == 1 {
+		records[0].Retain()
+		return records[0], nil
+	}
+
+	cols := make([]arrow.Array, schema.NumFields())
+	defer func() {
+		for _, c := range cols {
+			if c != nil {
+				c.Release()
+			}
+		}
+	}()
+	var rows int64
+	for _, r := range records {
+		rows += r.NumRows()
+	}
+	arrs := make([]arrow.Array, len(records))
+	for i := range cols {
+		for j, r := range records {
+			arrs[j] = r.Column(i)
+		}
+		c, err := array.Concatenate(arrs, pool)
+		if err != nil {
+			return nil, err
+		}
+		cols[i] = c
+	}
+	return array.NewRecord(schema, cols, rows), nil
+}
+
+// readParquet converts the parquet file data to an arrow record and calls f
+// with it.
+func readParquet(ctx context.Context, data []byte, pool memory.Allocator, schema *dynparquet.Schema, f func(arrow.Record) error) error {
+	buf, err := dynparquet.ReaderFromBytes(data)
+	if err != nil {
+		return status.Error(codes.InvalidArgument, fmt.Sprintf("read parquet: %v", err))
+	}
+
+	converter := pqarrow.NewParquetConverter(pool, logicalplan.IterOptions{})
+	defer converter.Close()
+	for i := 0; i < buf.NumRowGroups(); i++ {
+		if err := converter.Convert(ctx, buf.DynamicRowGroup(i), schema); err != nil {
+			return status.Error(codes.InvalidArgument, fmt.Sprintf("convert parquet: %v", err))
+		}
+	}
+	r := converter.NewRecord()
+	if r == nil {
+		return nil
+	}
+	defer r.Release()
+
+	if err := f(r); err != nil {
+		return status.Error(codes.Internal, err.Error())
+	}
+	return nil
+}
+
+// scanBase returns the scan of the plan, which is the last node of a plan.
+func scanBase(plan *pb.PlanNode) *pb.ScanBase {
+	for ; plan != nil; plan = plan.GetNext() {
+		switch {
+		case plan.GetSpec().GetTableScan() != nil:
+			return plan.GetSpec().GetTableScan().GetBase()
+		case plan.GetSpec().GetSchemaScan() != nil:
+			return plan.GetSpec().GetSchemaScan().GetBase()
+		}
+	}
+	return nil
+}
+
+func writeRecord(buf *bytes.Buffer, r arrow.Record) error {
+	w := ipc.NewWriter(buf, ipc.WithSchema(r.Schema()))
+	if err := w.Write(r); err != nil {
+		return err
+	}
+	return w.Close()
+}
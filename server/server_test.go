@@ -0,0 +1,209 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net"
+	"testing"
+
+	"github.com/apache/arrow/go/v17/arrow/ipc"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+
+	"github.com/polarsignals/frostdb"
+	"github.com/polarsignals/frostdb/dynparquet"
+	pb "github.com/polarsignals/frostdb/gen/proto/go/frostdb/storage/v1alpha1"
+	"github.com/polarsignals/frostdb/pqarrow"
+)
+
+func TestServerQuery(t *testing.T) {
+	ctx := context.Background()
+
+	c, err := frostdb.New()
+	require.NoError(t, err)
+	defer c.Close()
+
+	db, err := c.DB(ctx, "test")
+	require.NoError(t, err)
+	table, err := db.Table("test", frostdb.NewTableConfig(dynparquet.SampleDefinition()))
+	require.NoError(t, err)
+
+	samples := dynparquet.NewTestSamples()
+	r, err := samples.ToRecord()
+	require.NoError(t, err)
+	_, err = table.InsertRecord(ctx, r)
+	require.NoError(t, err)
+
+	client := newTestClient(t, c)
+
+	require.Equal(t, int64(len(samples)), countRows(t, client, "test"))
+
+	stream, err := client.Query(ctx, scan("unknown"))
+	require.NoError(t, err)
+	_, err = stream.Recv()
+	require.Equal(t, codes.NotFound, status.Code(err))
+}
+
+func TestServerWrite(t *testing.T) {
+	ctx := context.Background()
+
+	c, err := frostdb.New()
+	require.NoError(t, err)
+	defer c.Close()
+	client := newTestClient(t, c)
+
+	r, err := dynparquet.NewTestSamples().ToRecord()
+	require.NoError(t, err)
+	defer r.Release()
+
+	var record bytes.Buffer
+	w := ipc.NewWriter(&record, ipc.WithSchema(r.Schema()))
+	require.NoError(t, w.Write(r))
+	require.NoError(t, w.Close())
+
+	// The database must exist unless the request asks to create it.
+	_, err = client.Write(ctx, &pb.WriteRequest{
+		Database: "test",
+		Table:    "test",
+		Config:   frostdb.NewTableConfig(dynparquet.SampleDefinition()),
+		Record:   record.Bytes(),
+	})
+	require.Equal(t, codes.NotFound, status.Code(err))
+	_, err = c.GetDB("test")
+	require.Error(t, err)
+
+	// The table must exist unless its config is sent.
+	_, err = client.Write(ctx, &pb.WriteRequest{
+		Database:       "test",
+		Table:          "test",
+		Record:         record.Bytes(),
+		CreateDatabase: true,
+	})
+	require.Equal(t, codes.NotFound, status.Code(err))
+
+	_, err = client.Write(ctx, &pb.WriteRequest{
+		Database:       "test",
+		Table:          "test",
+		Config:         frostdb.NewTableConfig(dynparquet.SampleDefinition()),
+		Record:         record.Bytes(),
+		CreateDatabase: true,
+	})
+	require.NoError(t, err)
+	require.Equal(t, r.NumRows(), countRows(t, client, "test"))
+
+	schema := dynparquet.NewSampleSchema()
+	var file bytes.Buffer
+	pw, err := schema.GetWriter(&file, pqarrow.RecordDynamicCols(r), false)
+	require.NoError(t, err)
+	require.NoError(t, pqarrow.RecordToFile(schema, pw.ParquetWriter, r))
+	schema.PutWriter(pw)
+
+	resp, err := client.Write(ctx, &pb.WriteRequest{
+		Database: "test",
+		Table:    "test",
+		Parquet:  file.Bytes(),
+	})
+	require.NoError(t, err)
+	require.Equal(t, 2*r.NumRows(), countRows(t, client, "test"))
+
+	_, err = client.Write(ctx, &pb.WriteRequest{
+		Database: "test",
+		Table:    "test",
+		Record:   record.Bytes(),
+		Parquet:  file.Bytes(),
+	})
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	// All the records of a stream are inserted in a single transaction.
+	var records bytes.Buffer
+	w = ipc.NewWriter(&records, ipc.WithSchema(r.Schema()))
+	require.NoError(t, w.Write(r))
+	require.NoError(t, w.Write(r))
+	require.NoError(t, w.Close())
+
+	prev := resp.GetTx()
+	resp, err = client.Write(ctx, &pb.WriteRequest{
+		Database: "test",
+		Table:    "test",
+		Record:   records.Bytes(),
+	})
+	require.NoError(t, err)
+	require.Equal(t, prev+1, resp.GetTx())
+	require.Equal(t, 4*r.NumRows(), countRows(t, client, "test"))
+}
+
+// newTestClient serves the column store over an in-memory connection and
+// returns a client for it.
+func newTestClient(t *testing.T, c *frostdb.ColumnStore) pb.FrostDBServiceClient {
+	return pb.NewFrostDBServiceClient(newTestConn(t, func(srv *grpc.Server) {
+		pb.RegisterFrostDBServiceServer(srv, New(c))
+	}))
+}
+
+// newTestConn serves the services registered by register over an in-memory
+// connection and returns a connection to it.
+func newTestConn(t *testing.T, register func(*grpc.Server)) *grpc.ClientConn {
+	lis := bufconn.Listen(1 << 20)
+	srv := grpc.NewServer()
+	register(srv)
+	go func() {
+		_ = srv.Serve(lis)
+	}()
+	t.Cleanup(srv.Stop)
+
+	conn, err := grpc.NewClient(
+		"passthrough:///bufconn",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return lis.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+func scan(database string) *pb.QueryRequest {
+	return &pb.QueryRequest{
+		PlanRoot: &pb.PlanNode{
+			Spec: &pb.PlanNodeSpec{
+				Spec: &pb.PlanNodeSpec_TableScan{
+					TableScan: &pb.TableScan{
+						Base: &pb.ScanBase{
+							Database: database,
+							Table:    "test",
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+// countRows returns the number of rows of the table "test" of database.
+func countRows(t *testing.T, client pb.FrostDBServiceClient, database string) int64 {
+	stream, err := client.Query(context.Background(), scan(database))
+	require.NoError(t, err)
+	rows := int64(0)
+	for {
+		resp, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		require.NoError(t, err)
+		reader, err := ipc.NewReader(bytes.NewReader(resp.Record))
+		require.NoError(t, err)
+		for reader.Next() {
+			rows += reader.Record().NumRows()
+		}
+		require.NoError(t, reader.Err())
+		reader.Release()
+	}
+	return rows
+}
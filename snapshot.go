@@ -19,6 +19,8 @@ import (
 	"github.com/apache/arrow/go/v17/arrow/ipc"
 	"github.com/apache/arrow/go/v17/arrow/util"
 	"github.com/go-kit/log/level"
+	"github.com/klauspost/compress/zstd"
+	"github.com/parquet-go/parquet-go"
 
 	"github.com/polarsignals/frostdb/dynparquet"
 	snapshotpb "github.com/polarsignals/frostdb/gen/proto/go/frostdb/snapshot/v1alpha1"
@@ -42,6 +44,8 @@ import (
 // 4-byte checksum (little endian)
 // 4-byte magic "FDBS"
 //
+// Since version 2, every part is written as a zstd frame.
+//
 // Readers should start reading a snapshot by first verifying that the magic
 // bytes are correct, followed by the version number to ensure that the snapshot
 // was encoded using a version the reader supports. A version bump could, for
@@ -58,14 +62,21 @@ const (
 	// changes are backwards-compatible, this version number is only necessary
 	// for the non-proto format (e.g. if compression is introduced).
 	// Version 1: Initial snapshot version with checksum and version number.
-	snapshotVersion = 1
+	// Version 2: Parts are compressed with zstd.
+	snapshotVersion = 2
 	// minReadVersion is bumped when deprecating older versions. For example,
 	// a reader of the new version can choose to still support reading older
 	// versions, but will bump this constant to the minimum version it claims
 	// to support.
-	minReadVersion = snapshotVersion
+	minReadVersion = 1
+	// compressedPartsVersion is the first version with compressed parts.
+	compressedPartsVersion = 2
 )
 
+// snapshotDecoder decompresses the parts of snapshots. It is safe for
+// concurrent use.
+var snapshotDecoder, _ = zstd.NewReader(nil, zstd.WithDecoderConcurrency(0))
+
 // segmentName returns a 20-byte textual representation of a snapshot file name
 // at a given txn used for lexical ordering.
 func snapshotFileName(tx uint64) string {
@@ -99,6 +110,14 @@ func (db *DB) snapshot(ctx context.Context, async bool, onSuccess func()) {
 	if !db.columnStore.enableWAL {
 		return
 	}
+	if err := db.Quarantined(); err != nil {
+		// Snapshotting a quarantined database could persist corrupt data.
+		level.Debug(db.logger).Log(
+			"msg", "cannot start snapshot; db is quarantined",
+			"err", err,
+		)
+		return
+	}
 	if !db.snapshotInProgress.CompareAndSwap(false, true) {
 		// Snapshot already in progress.
 		level.Debug(db.logger).Log(
@@ -152,7 +171,9 @@ func (db *DB) snapshot(ctx context.Context, async bool, onSuccess func()) {
 	}
 
 	if async {
-		go doSnapshot(db.snapshotWriter(tx))
+		db.columnStore.scheduler.Go(WorkSnapshot, db.name, func() {
+			doSnapshot(db.snapshotWriter(tx))
+		})
 	} else {
 		doSnapshot(db.offlineSnapshotWriter(tx))
 	}
@@ -216,8 +237,11 @@ func (db *DB) snapshotAtTX(ctx context.Context, tx uint64, writeSnapshot func(co
 		db.metrics.snapshotFileSizeBytes.Set(float64(fileSize))
 	}
 	db.metrics.snapshotDurationHistogram.Observe(time.Since(start).Seconds())
-	// TODO(asubiotto): If snapshot file sizes become too large, investigate
-	// adding compression.
+	db.columnStore.lifecycleHooks.snapshot(SnapshotEvent{
+		Database: db.name,
+		Tx:       tx,
+		Path:     filepath.Join(SnapshotDir(db, tx), snapshotFileName(tx)),
+	})
 	return nil
 }
 
@@ -244,12 +268,16 @@ func (db *DB) loadLatestSnapshotFromDir(ctx context.Context, dir string) (uint64
 			if err != nil {
 				return err
 			}
-			watermark, err := LoadSnapshot(ctx, db, parsedTx, f, info.Size(), filepath.Join(dir, entry.Name()), false)
-			if err != nil {
+			// If background verification is enabled, the checksum is not
+			// validated before loading the snapshot since doing so requires
+			// reading the whole file.
+			verifyChecksum := !db.columnStore.backgroundSnapshotVerification
+			if err := loadSnapshot(ctx, db, f, info.Size(), filepath.Join(dir, entry.Name()), verifyChecksum); err != nil {
 				return err
 			}
+			db.resetToTxn(parsedTx, nil)
 			// Success.
-			loadedTxn = watermark
+			loadedTxn = parsedTx
 			return nil
 		}(); err != nil {
 			err = fmt.Errorf("unable to read snapshot file %s: %w", entry.Name(), err)
@@ -275,7 +303,7 @@ func (db *DB) loadLatestSnapshotFromDir(ctx context.Context, dir string) (uint64
 }
 
 func LoadSnapshot(ctx context.Context, db *DB, tx uint64, r io.ReaderAt, size int64, dir string, truncateWAL bool) (uint64, error) {
-	if err := loadSnapshot(ctx, db, r, size, dir); err != nil {
+	if err := loadSnapshot(ctx, db, r, size, dir, true); err != nil {
 		return 0, err
 	}
 	watermark := tx
@@ -406,6 +434,12 @@ func WriteSnapshot(ctx context.Context, tx uint64, db *DB, w io.Writer) error {
 		return err
 	}
 
+	enc, err := zstd.NewWriter(nil)
+	if err != nil {
+		return err
+	}
+	defer enc.Close()
+
 	metadata := &snapshotpb.FooterData{}
 	for _, t := range tables {
 		if err := func() error {
@@ -443,15 +477,15 @@ func WriteSnapshot(ctx context.Context, tx uint64, db *DB, w io.Writer) error {
 					return err
 				}
 
-				if record := p.Record(); record != nil {
-					partMeta.Encoding = snapshotpb.Part_ENCODING_ARROW
-				} else {
-					partMeta.Encoding = snapshotpb.Part_ENCODING_PARQUET
+				enc.Reset(w)
+				encoding, err := writeSnapshotPart(enc, p, t.schema.Load())
+				if err != nil {
+					return err
 				}
-
-				if err := p.Write(w); err != nil {
+				if err := enc.Close(); err != nil {
 					return err
 				}
+				partMeta.Encoding = encoding
 
 				partMeta.EndOffset = int64(offW.offset)
 				granuleMeta.PartMetadata = append(granuleMeta.PartMetadata, partMeta)
@@ -467,6 +501,7 @@ func WriteSnapshot(ctx context.Context, tx uint64, db *DB, w io.Writer) error {
 			return err
 		}
 	}
+	metadata.Meta = db.snapshotMeta()
 	footer, err := metadata.MarshalVT()
 	if err != nil {
 		return err
@@ -488,34 +523,85 @@ func WriteSnapshot(ctx context.Context, tx uint64, db *DB, w io.Writer) error {
 	return nil
 }
 
-func readFooter(r io.ReaderAt, size int64) (*snapshotpb.FooterData, error) {
-	buffer := make([]byte, 16)
-	if _, err := r.ReadAt(buffer[:4], 0); err != nil {
-		return nil, err
+// writeSnapshotPart writes the part to w and returns its encoding. Arrow
+// parts are written as parquet if the table schema specifies compression
+// codecs, so that the codecs are honored by snapshots as well.
+func writeSnapshotPart(w io.Writer, p parts.Part, schema *dynparquet.Schema) (snapshotpb.Part_Encoding, error) {
+	if p.Record() == nil {
+		return snapshotpb.Part_ENCODING_PARQUET, p.Write(w)
 	}
-	if string(buffer[:4]) != snapshotMagic {
-		return nil, fmt.Errorf("invalid snapshot magic: %q", buffer[:4])
+	if !schema.HasCompressedColumns() {
+		return snapshotpb.Part_ENCODING_ARROW, p.Write(w)
 	}
-	if _, err := r.ReadAt(buffer, size-int64(len(buffer))); err != nil {
+
+	buf, err := p.AsSerializedBuffer(schema)
+	if err != nil {
+		return 0, err
+	}
+	f := buf.ParquetFile()
+	if _, err := io.Copy(w, io.NewSectionReader(f, 0, f.Size())); err != nil {
+		return 0, err
+	}
+	return snapshotpb.Part_ENCODING_PARQUET, nil
+}
+
+// snapshotFooter is the footer of a snapshot along with the version the
+// snapshot was written with.
+type snapshotFooter struct {
+	*snapshotpb.FooterData
+	version uint32
+}
+
+// readFooter reads the footer of the snapshot in r after validating the
+// snapshot's checksum.
+func readFooter(r io.ReaderAt, size int64) (*snapshotFooter, error) {
+	if err := validateSnapshotChecksum(r, size); err != nil {
 		return nil, err
 	}
-	if string(buffer[12:]) != snapshotMagic {
-		return nil, fmt.Errorf("invalid snapshot magic: %q", buffer[4:])
+	return readFooterUnvalidated(r, size)
+}
+
+// validateSnapshotChecksum computes the checksum of the snapshot in r and
+// compares it to the checksum stored in the snapshot. Note that this requires
+// reading the whole snapshot.
+func validateSnapshotChecksum(r io.ReaderAt, size int64) error {
+	buffer := make([]byte, 4)
+	if _, err := r.ReadAt(buffer, size-8); err != nil {
+		return err
 	}
 
 	// The checksum does not include the last 8 bytes of the file, which is the
 	// magic and the checksum. Create a section reader of all but the last 8
 	// bytes to compute the checksum and validate it against the read checksum.
-	checksum := binary.LittleEndian.Uint32(buffer[8:12])
+	checksum := binary.LittleEndian.Uint32(buffer)
 	checksumWriter := newChecksumWriter()
 	if _, err := io.Copy(checksumWriter, io.NewSectionReader(r, 0, size-8)); err != nil {
-		return nil, fmt.Errorf("failed to compute checksum: %w", err)
+		return fmt.Errorf("failed to compute checksum: %w", err)
 	}
 	if checksum != checksumWriter.Sum32() {
-		return nil, fmt.Errorf(
+		return fmt.Errorf(
 			"snapshot file corrupt: invalid checksum: expected %x, got %x", checksum, checksumWriter.Sum32(),
 		)
 	}
+	return nil
+}
+
+// readFooterUnvalidated reads the footer of the snapshot in r without
+// validating the snapshot's checksum.
+func readFooterUnvalidated(r io.ReaderAt, size int64) (*snapshotFooter, error) {
+	buffer := make([]byte, 16)
+	if _, err := r.ReadAt(buffer[:4], 0); err != nil {
+		return nil, err
+	}
+	if string(buffer[:4]) != snapshotMagic {
+		return nil, fmt.Errorf("invalid snapshot magic: %q", buffer[:4])
+	}
+	if _, err := r.ReadAt(buffer, size-int64(len(buffer))); err != nil {
+		return nil, err
+	}
+	if string(buffer[12:]) != snapshotMagic {
+		return nil, fmt.Errorf("invalid snapshot magic: %q", buffer[4:])
+	}
 
 	version := binary.LittleEndian.Uint32(buffer[4:8])
 	if version > snapshotVersion {
@@ -538,14 +624,36 @@ func readFooter(r io.ReaderAt, size int64) (*snapshotpb.FooterData, error) {
 	if err := footer.UnmarshalVT(footerBytes); err != nil {
 		return nil, fmt.Errorf("could not unmarshal footer: %v", err)
 	}
-	return footer, nil
+	return &snapshotFooter{FooterData: footer, version: version}, nil
+}
+
+// readSnapshotPart reads the data of the given part of a snapshot written
+// with the given version, decompressing it if necessary.
+func readSnapshotPart(r io.ReaderAt, partMeta *snapshotpb.Part, version uint32) ([]byte, error) {
+	partBytes := make([]byte, partMeta.EndOffset-partMeta.StartOffset)
+	if _, err := r.ReadAt(partBytes, partMeta.StartOffset); err != nil {
+		return nil, err
+	}
+	if version < compressedPartsVersion {
+		return partBytes, nil
+	}
+	partBytes, err := snapshotDecoder.DecodeAll(partBytes, nil)
+	if err != nil {
+		return nil, fmt.Errorf("decompress part: %w", err)
+	}
+	return partBytes, nil
 }
 
 // loadSnapshot loads a snapshot from the given io.ReaderAt and returns the
 // txnMetadata (if any) the snapshot was created with and an error if any
-// occurred.
-func loadSnapshot(ctx context.Context, db *DB, r io.ReaderAt, size int64, dir string) error {
-	footer, err := readFooter(r, size)
+// occurred. If verifyChecksum is false, the snapshot's checksum is not
+// validated before loading.
+func loadSnapshot(ctx context.Context, db *DB, r io.ReaderAt, size int64, dir string, verifyChecksum bool) error {
+	readFooterFn := readFooterUnvalidated
+	if verifyChecksum {
+		readFooterFn = readFooter
+	}
+	footer, err := readFooterFn(r, size)
 	if err != nil {
 		return err
 	}
@@ -607,10 +715,8 @@ func loadSnapshot(ctx context.Context, db *DB, r io.ReaderAt, size int64, dir st
 					if err := ctx.Err(); err != nil {
 						return err
 					}
-					startOffset := partMeta.StartOffset
-					endOffset := partMeta.EndOffset
-					partBytes := make([]byte, endOffset-startOffset)
-					if _, err := r.ReadAt(partBytes, startOffset); err != nil {
+					partBytes, err := readSnapshotPart(r, partMeta, footer.version)
+					if err != nil {
 						return err
 					}
 					partOptions := parts.WithCompactionLevel(int(partMeta.CompactionLevel))
@@ -637,7 +743,7 @@ func loadSnapshot(ctx context.Context, db *DB, r io.ReaderAt, size int64, dir st
 							record.Retain()
 							resultParts = append(
 								resultParts,
-								parts.NewArrowPart(partMeta.Tx, record, uint64(util.TotalRecordSize(record)), table.schema, partOptions),
+								parts.NewArrowPart(partMeta.Tx, record, uint64(util.TotalRecordSize(record)), table.schema.Load(), partOptions),
 							)
 							return nil
 						}(); err != nil {
@@ -664,21 +770,167 @@ func loadSnapshot(ctx context.Context, db *DB, r io.ReaderAt, size int64, dir st
 		}
 	}
 
+	db.loadSnapshotMeta(footer.Meta)
 	return nil
 }
 
+// verifySnapshotInBackground verifies the snapshot at the given tx in a
+// background goroutine. If verification succeeds, snapshots older than tx
+// are removed and the given WAL is truncated, as done synchronously on
+// recovery when background verification is disabled. If verification fails,
+// the database is quarantined and the corrupt snapshot is removed so that the
+// next recovery falls back to a previous snapshot and the WAL. Verification
+// is canceled when the database is closed.
+func (db *DB) verifySnapshotInBackground(tx uint64, wal WAL) {
+	// The context passed on recovery may be canceled once the database is
+	// opened, so it is not used here.
+	ctx, cancel := context.WithCancel(context.Background())
+	db.verifyCancel = cancel
+	db.verifyWg.Add(1)
+	db.columnStore.scheduler.Go(WorkSnapshot, db.name, func() {
+		defer db.verifyWg.Done()
+		if ctx.Err() != nil {
+			return
+		}
+		clock := db.columnStore.clock
+		start := clock.Now()
+		dir := SnapshotDir(db, tx)
+		f, err := os.Open(filepath.Join(dir, snapshotFileName(tx)))
+		if err != nil {
+			db.quarantine(fmt.Errorf("open snapshot at tx %d for verification: %w", tx, err))
+			return
+		}
+		info, err := f.Stat()
+		if err == nil {
+			err = verifySnapshot(ctx, f, info.Size())
+		}
+		f.Close()
+		if ctx.Err() != nil {
+			// The database was closed, the snapshot is verified on the next
+			// recovery.
+			return
+		}
+		if err != nil {
+			db.quarantine(fmt.Errorf("snapshot at tx %d failed verification: %w", tx, err))
+			if err := os.RemoveAll(dir); err != nil {
+				level.Error(db.logger).Log(
+					"msg", "failed to remove corrupt snapshot",
+					"err", err,
+					"snapshot_tx", tx,
+				)
+			}
+			return
+		}
+		level.Debug(db.logger).Log(
+			"msg", "snapshot verified",
+			"snapshot_tx", tx,
+			"duration", clock.Now().Sub(start),
+		)
+
+		// Only snapshots older than the verified snapshot are removed, since
+		// newer snapshots may have been taken in the meantime.
+		if err := db.snapshotsDo(ctx, db.snapshotsDir(), func(fileTx uint64, entry os.DirEntry) (bool, error) {
+			if fileTx >= tx {
+				return true, nil
+			}
+			return true, db.removeSnapshot(fileTx, entry)
+		}); err != nil {
+			level.Info(db.logger).Log(
+				"msg", "failed to remove snapshots older than verified snapshot",
+				"err", err,
+				"snapshot_tx", tx,
+			)
+		}
+		if err := db.truncateWAL(wal, tx+1); err != nil {
+			level.Info(db.logger).Log(
+				"msg", "failed to truncate WAL after verifying snapshot",
+				"err", err,
+				"snapshot_tx", tx,
+			)
+		}
+	})
+}
+
+// verifySnapshot validates the checksum of the snapshot in r as well as the
+// integrity of every part it contains.
+func verifySnapshot(ctx context.Context, r io.ReaderAt, size int64) error {
+	footer, err := readFooter(r, size)
+	if err != nil {
+		return err
+	}
+	for _, tableMeta := range footer.TableMetadata {
+		for _, granuleMeta := range tableMeta.GranuleMetadata {
+			for _, partMeta := range granuleMeta.PartMetadata {
+				if err := ctx.Err(); err != nil {
+					return err
+				}
+				if err := verifySnapshotPart(r, partMeta, footer.version); err != nil {
+					return fmt.Errorf("table %s: part at tx %d: %w", tableMeta.Name, partMeta.Tx, err)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// verifySnapshotPart reads and decodes all the data of the given part.
+func verifySnapshotPart(r io.ReaderAt, partMeta *snapshotpb.Part, version uint32) error {
+	partBytes, err := readSnapshotPart(r, partMeta, version)
+	if err != nil {
+		return err
+	}
+	switch partMeta.Encoding {
+	case snapshotpb.Part_ENCODING_PARQUET:
+		serBuf, err := dynparquet.ReaderFromBytes(partBytes)
+		if err != nil {
+			return err
+		}
+		for _, rg := range serBuf.ParquetFile().RowGroups() {
+			for _, chunk := range rg.ColumnChunks() {
+				if err := func() error {
+					pages := chunk.Pages()
+					defer pages.Close()
+					for {
+						page, err := pages.ReadPage()
+						if err == io.EOF {
+							return nil
+						}
+						if err != nil {
+							return err
+						}
+						parquet.Release(page)
+					}
+				}(); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	case snapshotpb.Part_ENCODING_ARROW:
+		arrowReader, err := ipc.NewReader(bytes.NewReader(partBytes))
+		if err != nil {
+			return err
+		}
+		defer arrowReader.Release()
+		for arrowReader.Next() {
+		}
+		return arrowReader.Err()
+	default:
+		return fmt.Errorf("unknown part encoding: %s", partMeta.Encoding)
+	}
+}
+
 // cleanupSnapshotDir should be called with a tx at which the caller is certain
 // a valid snapshot exists (e.g. the tx returned from
 // getLatestValidSnapshotTxn). This method deletes all snapshots taken at any
-// other transaction.
+// other transaction, except for pinned snapshots.
 func (db *DB) cleanupSnapshotDir(ctx context.Context, tx uint64) error {
-	dir := db.snapshotsDir()
-	return db.snapshotsDo(ctx, dir, func(fileTx uint64, entry os.DirEntry) (bool, error) {
+	return db.snapshotsDo(ctx, db.snapshotsDir(), func(fileTx uint64, entry os.DirEntry) (bool, error) {
 		if fileTx == tx {
 			// Continue.
 			return true, nil
 		}
-		if err := os.RemoveAll(filepath.Join(dir, entry.Name())); err != nil {
+		if err := db.removeSnapshot(fileTx, entry); err != nil {
 			return false, err
 		}
 		return true, nil
@@ -0,0 +1,153 @@
+package frostdb
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// SnapshotInfo describes a snapshot stored in the snapshots directory of a
+// database.
+type SnapshotInfo struct {
+	// Tx is the transaction the snapshot was taken at.
+	Tx uint64
+	// Dir is the directory containing all files of the snapshot. Snapshot
+	// directories are named after the snapshot's transaction zero-padded to
+	// 20 digits, see SnapshotDir. Backup tools should copy the whole
+	// directory, since it contains the index files of the snapshot's tables in
+	// addition to the snapshot file.
+	Dir string
+	// Path is the path of the snapshot file, which is named
+	// "<zero-padded tx>.fdbs" and stored in Dir.
+	Path string
+	// Size is the total size in bytes of all files in Dir.
+	Size int64
+	// Tables are the names of the tables contained in the snapshot.
+	Tables []string
+	// Checksum is the CRC32 (Castagnoli) checksum stored in the snapshot
+	// file, computed over all but the last 8 bytes of the file.
+	Checksum uint32
+	// Pinned is true if the snapshot is pinned and therefore not removed.
+	Pinned bool
+}
+
+// Snapshots returns the snapshots stored in the database's snapshots
+// directory, most recent first. Snapshots that are still being written or
+// whose footer cannot be read are omitted. Note that a listed snapshot may be
+// removed at any time unless it is pinned with PinSnapshot.
+func (db *DB) Snapshots(ctx context.Context) ([]SnapshotInfo, error) {
+	var snapshots []SnapshotInfo
+	if err := db.snapshotsDo(ctx, db.snapshotsDir(), func(tx uint64, _ os.DirEntry) (bool, error) {
+		info, err := db.snapshotInfo(tx)
+		if err != nil {
+			return true, nil
+		}
+		snapshots = append(snapshots, info)
+		return true, nil
+	}); err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return snapshots, nil
+}
+
+func (db *DB) snapshotInfo(tx uint64) (SnapshotInfo, error) {
+	info := SnapshotInfo{
+		Tx:   tx,
+		Dir:  SnapshotDir(db, tx),
+		Path: filepath.Join(SnapshotDir(db, tx), snapshotFileName(tx)),
+	}
+
+	f, err := os.Open(info.Path)
+	if err != nil {
+		return info, err
+	}
+	defer f.Close()
+	stat, err := f.Stat()
+	if err != nil {
+		return info, err
+	}
+	footer, err := readFooterUnvalidated(f, stat.Size())
+	if err != nil {
+		return info, err
+	}
+	for _, table := range footer.TableMetadata {
+		info.Tables = append(info.Tables, table.Name)
+	}
+	checksum := make([]byte, 4)
+	if _, err := f.ReadAt(checksum, stat.Size()-8); err != nil {
+		return info, err
+	}
+	info.Checksum = binary.LittleEndian.Uint32(checksum)
+
+	if err := filepath.WalkDir(info.Dir, func(_ string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		fi, err := d.Info()
+		if err != nil {
+			return err
+		}
+		info.Size += fi.Size()
+		return nil
+	}); err != nil {
+		return info, err
+	}
+
+	db.pinnedSnapshotsMtx.Lock()
+	info.Pinned = db.pinnedSnapshots[tx] > 0
+	db.pinnedSnapshotsMtx.Unlock()
+	return info, nil
+}
+
+// PinSnapshot prevents the snapshot taken at the given transaction from being
+// removed when newer snapshots are taken, e.g. while an external backup tool
+// copies it. Pins are reference counted: the snapshot may be removed again
+// once UnpinSnapshot was called as many times as PinSnapshot. Pins are not
+// persisted across restarts.
+func (db *DB) PinSnapshot(tx uint64) error {
+	db.pinnedSnapshotsMtx.Lock()
+	defer db.pinnedSnapshotsMtx.Unlock()
+	if _, err := os.Stat(filepath.Join(SnapshotDir(db, tx), snapshotFileName(tx))); err != nil {
+		return fmt.Errorf("snapshot at tx %d: %w", tx, err)
+	}
+	if db.pinnedSnapshots == nil {
+		db.pinnedSnapshots = make(map[uint64]int)
+	}
+	db.pinnedSnapshots[tx]++
+	return nil
+}
+
+// UnpinSnapshot releases a pin of the snapshot taken at the given transaction
+// acquired with PinSnapshot.
+func (db *DB) UnpinSnapshot(tx uint64) error {
+	db.pinnedSnapshotsMtx.Lock()
+	defer db.pinnedSnapshotsMtx.Unlock()
+	if db.pinnedSnapshots[tx] == 0 {
+		return fmt.Errorf("snapshot at tx %d is not pinned", tx)
+	}
+	db.pinnedSnapshots[tx]--
+	if db.pinnedSnapshots[tx] == 0 {
+		delete(db.pinnedSnapshots, tx)
+	}
+	return nil
+}
+
+// removeSnapshot removes the snapshot directory entry of the snapshot taken at
+// the given transaction unless the snapshot is pinned.
+func (db *DB) removeSnapshot(tx uint64, entry os.DirEntry) error {
+	db.pinnedSnapshotsMtx.Lock()
+	defer db.pinnedSnapshotsMtx.Unlock()
+	if db.pinnedSnapshots[tx] > 0 {
+		return nil
+	}
+	return os.RemoveAll(filepath.Join(db.snapshotsDir(), entry.Name()))
+}
@@ -1,9 +1,14 @@
 package frostdb
 
 import (
+	"bytes"
 	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
 	"math"
 	"os"
+	"path/filepath"
 	"strconv"
 	"testing"
 	"time"
@@ -12,10 +17,15 @@ import (
 	"github.com/apache/arrow/go/v17/arrow/array"
 	"github.com/apache/arrow/go/v17/arrow/memory"
 	"github.com/google/uuid"
+	"github.com/parquet-go/parquet-go"
+	"github.com/parquet-go/parquet-go/format"
 	"github.com/stretchr/testify/require"
+	"github.com/thanos-io/objstore"
 	"golang.org/x/sync/errgroup"
 
 	"github.com/polarsignals/frostdb/dynparquet"
+	schemapb "github.com/polarsignals/frostdb/gen/proto/go/frostdb/schema/v1alpha1"
+	snapshotpb "github.com/polarsignals/frostdb/gen/proto/go/frostdb/snapshot/v1alpha1"
 	"github.com/polarsignals/frostdb/query"
 	"github.com/polarsignals/frostdb/query/logicalplan"
 )
@@ -162,8 +172,8 @@ func TestSnapshot(t *testing.T) {
 				)
 			}
 			// Reset sync.Maps so reflect.DeepEqual can be used below.
-			db.tables[testCase.name].schema.ResetWriters()
-			db.tables[testCase.name].schema.ResetBuffers()
+			db.tables[testCase.name].schema.Load().ResetWriters()
+			db.tables[testCase.name].schema.Load().ResetBuffers()
 			require.Equal(t, db.tables[testCase.name].config.Load(), snapshotDB.tables[testCase.name].config.Load())
 		}
 	})
@@ -405,3 +415,356 @@ func TestSnapshotIsTakenOnUncompressedInserts(t *testing.T) {
 		"expected snapshot to be taken",
 	)
 }
+
+func TestSnapshotBackgroundVerification(t *testing.T) {
+	for _, corrupt := range []bool{false, true} {
+		t.Run(fmt.Sprintf("corrupt=%t", corrupt), func(t *testing.T) {
+			const dbAndTableName = "test"
+			var (
+				ctx          = context.Background()
+				dir          = t.TempDir()
+				snapshotTx   uint64
+				snapshotPath string
+			)
+			func() {
+				c, err := New(
+					WithWAL(),
+					WithStoragePath(dir),
+					WithManualBlockRotation(),
+				)
+				require.NoError(t, err)
+				defer c.Close()
+
+				db, err := c.DB(ctx, dbAndTableName)
+				require.NoError(t, err)
+				table, err := db.Table(dbAndTableName, NewTableConfig(dynparquet.SampleDefinition()))
+				require.NoError(t, err)
+				insertSampleRecords(ctx, t, table, 1, 2, 3)
+
+				success := false
+				db.snapshot(ctx, false, func() {
+					success = true
+				})
+				require.True(t, success)
+
+				files, err := os.ReadDir(db.snapshotsDir())
+				require.NoError(t, err)
+				require.Len(t, files, 1)
+				snapshotTx, err = strconv.ParseUint(files[0].Name()[:20], 10, 64)
+				require.NoError(t, err)
+				snapshotPath = SnapshotDir(db, snapshotTx)
+			}()
+
+			if corrupt {
+				// Corrupt the stored checksum, which is only validated when
+				// verifying the snapshot.
+				f, err := os.OpenFile(filepath.Join(snapshotPath, snapshotFileName(snapshotTx)), os.O_RDWR, 0)
+				require.NoError(t, err)
+				info, err := f.Stat()
+				require.NoError(t, err)
+				_, err = f.WriteAt([]byte{0xde, 0xad, 0xbe, 0xef}, info.Size()-8)
+				require.NoError(t, err)
+				require.NoError(t, f.Close())
+			}
+
+			c, err := New(
+				WithWAL(),
+				WithStoragePath(dir),
+				WithManualBlockRotation(),
+				WithBackgroundSnapshotVerification(),
+			)
+			require.NoError(t, err)
+			defer c.Close()
+
+			db, err := c.DB(ctx, dbAndTableName)
+			require.NoError(t, err)
+			table, err := db.GetTable(dbAndTableName)
+			require.NoError(t, err)
+
+			engine := query.NewEngine(memory.DefaultAllocator, db.TableProvider())
+			if !corrupt {
+				// Verification is asynchronous, so give it some time to
+				// complete.
+				time.Sleep(100 * time.Millisecond)
+				require.NoError(t, db.Quarantined())
+
+				rows := int64(0)
+				require.NoError(t, engine.ScanTable(dbAndTableName).Execute(ctx, func(_ context.Context, r arrow.Record) error {
+					rows += r.NumRows()
+					return nil
+				}))
+				require.Equal(t, int64(3), rows)
+				return
+			}
+
+			require.Eventually(t, func() bool {
+				return db.Quarantined() != nil
+			}, time.Second, 10*time.Millisecond)
+
+			var quarantinedErr *ErrDBQuarantined
+			require.ErrorAs(t, db.Quarantined(), &quarantinedErr)
+			require.Equal(t, dbAndTableName, quarantinedErr.DB)
+
+			r, err := dynparquet.Samples{{
+				ExampleType: "ex",
+				Labels:      map[string]string{"label1": "value1"},
+				Timestamp:   4,
+			}}.ToRecord()
+			require.NoError(t, err)
+			defer r.Release()
+			_, err = table.InsertRecord(ctx, r)
+			require.ErrorAs(t, err, &quarantinedErr)
+
+			err = engine.ScanTable(dbAndTableName).Execute(ctx, func(context.Context, arrow.Record) error {
+				return nil
+			})
+			require.ErrorAs(t, err, &quarantinedErr)
+
+			// The corrupt snapshot is removed so it is not loaded again.
+			_, err = os.Stat(snapshotPath)
+			require.ErrorIs(t, err, os.ErrNotExist)
+		})
+	}
+}
+
+func TestSnapshotCatalog(t *testing.T) {
+	const dbAndTableName = "test"
+	ctx := context.Background()
+	c, err := New(
+		WithWAL(),
+		WithStoragePath(t.TempDir()),
+		WithManualBlockRotation(),
+	)
+	require.NoError(t, err)
+	defer c.Close()
+
+	db, err := c.DB(ctx, dbAndTableName)
+	require.NoError(t, err)
+	table, err := db.Table(dbAndTableName, NewTableConfig(dynparquet.SampleDefinition()))
+	require.NoError(t, err)
+
+	snapshots, err := db.Snapshots(ctx)
+	require.NoError(t, err)
+	require.Empty(t, snapshots)
+
+	insertSampleRecords(ctx, t, table, 1, 2, 3)
+	require.NoError(t, db.Snapshot(ctx))
+
+	snapshots, err = db.Snapshots(ctx)
+	require.NoError(t, err)
+	require.Len(t, snapshots, 1)
+	first := snapshots[0]
+	require.Equal(t, SnapshotDir(db, first.Tx), first.Dir)
+	require.Equal(t, filepath.Join(first.Dir, snapshotFileName(first.Tx)), first.Path)
+	require.Equal(t, []string{dbAndTableName}, first.Tables)
+	require.False(t, first.Pinned)
+
+	f, err := os.Open(first.Path)
+	require.NoError(t, err)
+	info, err := f.Stat()
+	require.NoError(t, err)
+	require.NoError(t, validateSnapshotChecksum(f, info.Size()))
+	checksumWriter := newChecksumWriter()
+	_, err = io.Copy(checksumWriter, io.NewSectionReader(f, 0, info.Size()-8))
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+	require.Equal(t, checksumWriter.Sum32(), first.Checksum)
+	require.GreaterOrEqual(t, first.Size, info.Size())
+
+	// A pinned snapshot is kept when a newer snapshot is taken.
+	require.NoError(t, db.PinSnapshot(first.Tx))
+	insertSampleRecords(ctx, t, table, 4)
+	require.NoError(t, db.Snapshot(ctx))
+
+	snapshots, err = db.Snapshots(ctx)
+	require.NoError(t, err)
+	require.Len(t, snapshots, 2)
+	require.Greater(t, snapshots[0].Tx, first.Tx)
+	require.False(t, snapshots[0].Pinned)
+	require.Equal(t, first.Tx, snapshots[1].Tx)
+	require.True(t, snapshots[1].Pinned)
+
+	// Once unpinned, it is removed with the next snapshot.
+	require.NoError(t, db.UnpinSnapshot(first.Tx))
+	require.Error(t, db.UnpinSnapshot(first.Tx))
+	insertSampleRecords(ctx, t, table, 5)
+	require.NoError(t, db.Snapshot(ctx))
+
+	snapshots, err = db.Snapshots(ctx)
+	require.NoError(t, err)
+	require.Len(t, snapshots, 1)
+	require.Greater(t, snapshots[0].Tx, first.Tx)
+	require.Error(t, db.PinSnapshot(first.Tx))
+}
+
+func TestSnapshotColumnCompression(t *testing.T) {
+	ctx := context.Background()
+	def := dynparquet.SampleDefinition()
+	for _, col := range def.Columns {
+		switch col.Name {
+		case "example_type":
+			col.StorageLayout.Compression = schemapb.StorageLayout_COMPRESSION_ZSTD
+		case "labels":
+			col.StorageLayout.Compression = schemapb.StorageLayout_COMPRESSION_SNAPPY
+		}
+	}
+
+	requireCodecs := func(t *testing.T, f *parquet.File) {
+		t.Helper()
+		codecs := map[string]format.CompressionCodec{}
+		for _, rg := range f.Metadata().RowGroups {
+			for _, col := range rg.Columns {
+				codecs[col.MetaData.PathInSchema[0]] = col.MetaData.Codec
+			}
+		}
+		require.Equal(t, format.Zstd, codecs["example_type"])
+		require.Equal(t, format.Snappy, codecs["labels.label1"])
+		require.Equal(t, format.Uncompressed, codecs["timestamp"])
+	}
+
+	bucket := objstore.NewInMemBucket()
+	c, err := New(
+		WithStoragePath(t.TempDir()),
+		WithWAL(),
+		WithSnapshotTriggerSize(math.MaxInt64),
+		WithReadWriteStorage(NewDefaultObjstoreBucket(bucket)),
+	)
+	require.NoError(t, err)
+	defer c.Close()
+
+	db, err := c.DB(ctx, "test")
+	require.NoError(t, err)
+	table, err := db.Table("test", NewTableConfig(def))
+	require.NoError(t, err)
+	insertSampleRecords(ctx, t, table, 1, 2, 3)
+
+	// Arrow parts are written as parquet so that the codecs are honored.
+	tx := db.highWatermark.Load()
+	require.NoError(t, db.snapshotAtTX(ctx, tx, db.snapshotWriter(tx)))
+	snapshot, err := os.ReadFile(filepath.Join(SnapshotDir(db, tx), snapshotFileName(tx)))
+	require.NoError(t, err)
+	footer, err := readFooter(bytes.NewReader(snapshot), int64(len(snapshot)))
+	require.NoError(t, err)
+	require.Len(t, footer.TableMetadata, 1)
+	var numParts int
+	for _, granule := range footer.TableMetadata[0].GranuleMetadata {
+		for _, part := range granule.PartMetadata {
+			numParts++
+			require.Equal(t, snapshotpb.Part_ENCODING_PARQUET, part.Encoding)
+			partBytes, err := readSnapshotPart(bytes.NewReader(snapshot), part, footer.version)
+			require.NoError(t, err)
+			f, err := parquet.OpenFile(bytes.NewReader(partBytes), int64(len(partBytes)))
+			require.NoError(t, err)
+			requireCodecs(t, f)
+		}
+	}
+	require.Equal(t, 1, numParts)
+
+	// The snapshot can be loaded.
+	snapshotDB, err := c.DB(ctx, "testsnapshot")
+	require.NoError(t, err)
+	_, err = snapshotDB.loadLatestSnapshotFromDir(ctx, db.snapshotsDir())
+	require.NoError(t, err)
+	var rows int64
+	require.NoError(t, query.NewEngine(memory.DefaultAllocator, snapshotDB.TableProvider()).
+		ScanTable("test").
+		Execute(ctx, func(_ context.Context, r arrow.Record) error {
+			rows += r.NumRows()
+			return nil
+		}))
+	require.Equal(t, int64(3), rows)
+
+	// Persisted blocks honor the codecs as well.
+	require.NoError(t, table.RotateBlock(ctx, table.ActiveBlock()))
+	var block []byte
+	require.Eventually(t, func() bool {
+		require.NoError(t, bucket.Iter(ctx, "", func(name string) error {
+			if filepath.Base(name) != "data.parquet" {
+				return nil
+			}
+			r, err := bucket.Get(ctx, name)
+			if err != nil {
+				return err
+			}
+			defer r.Close()
+			block, err = io.ReadAll(r)
+			return err
+		}, objstore.WithRecursiveIter))
+		return block != nil
+	}, time.Second, 10*time.Millisecond)
+	f, err := parquet.OpenFile(bytes.NewReader(block), int64(len(block)))
+	require.NoError(t, err)
+	requireCodecs(t, f)
+}
+
+func TestSnapshotUncompressedVersion(t *testing.T) {
+	ctx := context.Background()
+	c, err := New(
+		WithStoragePath(t.TempDir()),
+		WithWAL(),
+		WithSnapshotTriggerSize(math.MaxInt64),
+	)
+	require.NoError(t, err)
+	defer c.Close()
+
+	db, err := c.DB(ctx, "test")
+	require.NoError(t, err)
+	table, err := db.Table("test", NewTableConfig(dynparquet.SampleDefinition()))
+	require.NoError(t, err)
+	insertSampleRecords(ctx, t, table, 1, 2, 3)
+
+	tx := db.highWatermark.Load()
+	require.NoError(t, db.snapshotAtTX(ctx, tx, db.snapshotWriter(tx)))
+	path := filepath.Join(SnapshotDir(db, tx), snapshotFileName(tx))
+	snapshot, err := os.ReadFile(path)
+	require.NoError(t, err)
+	footer, err := readFooter(bytes.NewReader(snapshot), int64(len(snapshot)))
+	require.NoError(t, err)
+	require.Equal(t, uint32(snapshotVersion), footer.version)
+
+	// Rewrite the snapshot with uncompressed parts, as written by version 1.
+	var buf bytes.Buffer
+	w := newOffsetWriter(&buf)
+	_, err = w.Write([]byte(snapshotMagic))
+	require.NoError(t, err)
+	for _, tableMeta := range footer.TableMetadata {
+		for _, granuleMeta := range tableMeta.GranuleMetadata {
+			for _, partMeta := range granuleMeta.PartMetadata {
+				partBytes, err := readSnapshotPart(bytes.NewReader(snapshot), partMeta, footer.version)
+				require.NoError(t, err)
+				partMeta.StartOffset = int64(w.offset)
+				_, err = w.Write(partBytes)
+				require.NoError(t, err)
+				partMeta.EndOffset = int64(w.offset)
+			}
+		}
+	}
+	footerBytes, err := footer.MarshalVT()
+	require.NoError(t, err)
+	footerBytes = binary.LittleEndian.AppendUint32(footerBytes, uint32(len(footerBytes)))
+	_, err = w.Write(footerBytes)
+	require.NoError(t, err)
+	_, err = w.Write(binary.LittleEndian.AppendUint32(nil, 1))
+	require.NoError(t, err)
+	_, err = w.Write(binary.LittleEndian.AppendUint32(nil, w.checksum()))
+	require.NoError(t, err)
+	_, err = w.Write([]byte(snapshotMagic))
+	require.NoError(t, err)
+	// The compressed snapshot is smaller.
+	require.Less(t, len(snapshot), buf.Len())
+	require.NoError(t, os.WriteFile(path, buf.Bytes(), filePerms))
+
+	// The uncompressed snapshot can still be loaded.
+	snapshotDB, err := c.DB(ctx, "testsnapshot")
+	require.NoError(t, err)
+	_, err = snapshotDB.loadLatestSnapshotFromDir(ctx, db.snapshotsDir())
+	require.NoError(t, err)
+	var rows int64
+	require.NoError(t, query.NewEngine(memory.DefaultAllocator, snapshotDB.TableProvider()).
+		ScanTable("test").
+		Execute(ctx, func(_ context.Context, r arrow.Record) error {
+			rows += r.NumRows()
+			return nil
+		}))
+	require.Equal(t, int64(3), rows)
+}
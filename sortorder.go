@@ -0,0 +1,156 @@
+package frostdb
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/go-kit/log/level"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/polarsignals/frostdb/dynparquet"
+	schemapb "github.com/polarsignals/frostdb/gen/proto/go/frostdb/schema/v1alpha1"
+	tablepb "github.com/polarsignals/frostdb/gen/proto/go/frostdb/table/v1alpha1"
+	"github.com/polarsignals/frostdb/query/logicalplan"
+)
+
+// sortOrderSeparator separates the name of a table from the name of one of its
+// sort orders in the name of the table storing the sort order.
+const sortOrderSeparator = "@"
+
+func sortOrderTableName(table, sortOrder string) string {
+	return table + sortOrderSeparator + sortOrder
+}
+
+// sortOrderConfig returns the config of the table storing the given sort order
+// of a table with the given config. It is identical to the table's config
+// except for the sorting columns of its schema.
+func sortOrderConfig(config *tablepb.TableConfig, so *tablepb.SortOrder) (*tablepb.TableConfig, error) {
+	config = proto.Clone(config).(*tablepb.TableConfig)
+	config.SortOrders = nil
+	switch schema := config.Schema.(type) {
+	case *tablepb.TableConfig_DeprecatedSchema:
+		sortingColumns := make([]*schemapb.SortingColumn, 0, len(so.SortingColumns))
+		for _, col := range so.SortingColumns {
+			sortingColumns = append(sortingColumns, &schemapb.SortingColumn{
+				Name:       col.Path,
+				Direction:  schemapb.SortingColumn_Direction(col.Direction),
+				NullsFirst: col.NullsFirst,
+			})
+		}
+		schema.DeprecatedSchema.SortingColumns = sortingColumns
+	case *tablepb.TableConfig_SchemaV2:
+		schema.SchemaV2.SortingColumns = so.SortingColumns
+	default:
+		return nil, errors.New("sort orders require a schema")
+	}
+	return config, nil
+}
+
+// sortOrdersEqual returns whether a and b configure the same sort orders.
+func sortOrdersEqual(a, b *tablepb.TableConfig) bool {
+	return slices.EqualFunc(a.GetSortOrders(), b.GetSortOrders(), func(a, b *tablepb.SortOrder) bool {
+		return proto.Equal(a, b)
+	})
+}
+
+// ensureSortOrders creates the tables storing the sort orders of the table
+// with the given name and config, if they do not exist yet.
+func (db *DB) ensureSortOrders(name string, config *tablepb.TableConfig) error {
+	for _, so := range config.SortOrders {
+		soConfig, err := sortOrderConfig(config, so)
+		if err != nil {
+			return fmt.Errorf("sort order %s: %w", so.Name, err)
+		}
+		if _, err := db.Table(sortOrderTableName(name, so.Name), soConfig); err != nil {
+			return fmt.Errorf("sort order %s: %w", so.Name, err)
+		}
+	}
+	return nil
+}
+
+// sortOrderTables returns the tables storing the table's sort orders.
+func (t *Table) sortOrderTables() []*Table {
+	sortOrders := t.config.Load().GetSortOrders()
+	if len(sortOrders) == 0 {
+		return nil
+	}
+	tables := make([]*Table, 0, len(sortOrders))
+	for _, so := range sortOrders {
+		table, err := t.db.GetTable(sortOrderTableName(t.name, so.Name))
+		if err != nil {
+			continue
+		}
+		tables = append(tables, table)
+	}
+	return tables
+}
+
+// markSortOrderStale marks the table storing a sort order as stale after it
+// failed to mirror a record, so that queries no longer read it.
+func (t *Table) markSortOrderStale(err error) {
+	t.sortOrderStale.Store(true)
+	level.Error(t.logger).Log(
+		"msg", "failed to insert into sort order table, queries will no longer read it",
+		"sort_order", t.name,
+		"err", err,
+	)
+}
+
+// sortOrderFor returns the table storing the sort order that best matches the
+// columns the query filters and groups by, or nil if the table's own sort
+// order matches at least as well. Stale sort orders are never returned.
+func (t *Table) sortOrderFor(iterOpts *logicalplan.IterOptions) *Table {
+	tables := t.sortOrderTables()
+	if len(tables) == 0 {
+		return nil
+	}
+
+	var used []logicalplan.Expr
+	if iterOpts.Filter != nil {
+		used = append(used, iterOpts.Filter.ColumnsUsedExprs()...)
+	}
+	for _, expr := range iterOpts.GroupBy {
+		used = append(used, expr.ColumnsUsedExprs()...)
+	}
+	if len(used) == 0 {
+		return nil
+	}
+
+	var best *Table
+	bestScore := leadingSortingColumnsUsed(t.schema.Load(), used)
+	for _, table := range tables {
+		if table.sortOrderStale.Load() {
+			continue
+		}
+		if score := leadingSortingColumnsUsed(table.schema.Load(), used); score > bestScore {
+			best, bestScore = table, score
+		}
+	}
+	return best
+}
+
+// leadingSortingColumnsUsed returns the number of leading sorting columns of
+// the schema that are used by the given expressions. Data sorted by these
+// columns is laid out so that rows with equal values are read together and
+// granules that don't match a filter can be skipped.
+func leadingSortingColumnsUsed(schema *dynparquet.Schema, used []logicalplan.Expr) int {
+	if schema == nil {
+		return 0
+	}
+	n := 0
+	for _, col := range schema.SortingColumns() {
+		if !slices.ContainsFunc(used, func(expr logicalplan.Expr) bool {
+			name := expr.Name()
+			if name == col.ColumnName() {
+				return true
+			}
+			def, ok := schema.FindDynamicColumnForConcreteColumn(name)
+			return ok && def.Name == col.ColumnName()
+		}) {
+			break
+		}
+		n++
+	}
+	return n
+}
@@ -0,0 +1,159 @@
+package frostdb
+
+import (
+	"context"
+	"testing"
+
+	"github.com/apache/arrow/go/v17/arrow"
+	"github.com/apache/arrow/go/v17/arrow/array"
+	"github.com/apache/arrow/go/v17/arrow/memory"
+	"github.com/stretchr/testify/require"
+
+	"github.com/polarsignals/frostdb/dynparquet"
+	schemav2pb "github.com/polarsignals/frostdb/gen/proto/go/frostdb/schema/v1alpha2"
+	"github.com/polarsignals/frostdb/query"
+	"github.com/polarsignals/frostdb/query/logicalplan"
+)
+
+func TestTableSortOrder(t *testing.T) {
+	ctx := context.Background()
+	c, err := New(WithLogger(newTestLogger(t)))
+	require.NoError(t, err)
+	defer c.Close()
+	db, err := c.DB(ctx, "test")
+	require.NoError(t, err)
+
+	byTimestamp := WithSortOrder("by_timestamp", &schemav2pb.SortingColumn{
+		Path:      "timestamp",
+		Direction: schemav2pb.SortingColumn_DIRECTION_ASCENDING,
+	})
+	table, err := db.Table("test", NewTableConfig(dynparquet.SampleDefinition(), byTimestamp))
+	require.NoError(t, err)
+
+	sortOrderTable, err := db.GetTable("test@by_timestamp")
+	require.NoError(t, err)
+	require.Equal(t, []*Table{sortOrderTable}, table.sortOrderTables())
+	require.Equal(t, "timestamp", sortOrderTable.Schema().SortingColumns()[0].ColumnName())
+
+	samples := dynparquet.Samples{
+		{ExampleType: "cpu", Labels: map[string]string{"label1": "a"}, Timestamp: 3, Value: 3},
+		{ExampleType: "cpu", Labels: map[string]string{"label1": "b"}, Timestamp: 1, Value: 1},
+		{ExampleType: "mem", Labels: map[string]string{"label1": "a"}, Timestamp: 2, Value: 2},
+	}
+	r, err := samples.ToRecord()
+	require.NoError(t, err)
+	defer r.Release()
+	_, err = table.InsertRecord(ctx, r)
+	require.NoError(t, err)
+
+	t.Run("Select", func(t *testing.T) {
+		timestampFilter := logicalplan.Col("timestamp").Gt(logicalplan.Literal(int64(1)))
+		for _, tc := range []struct {
+			name     string
+			iterOpts logicalplan.IterOptions
+			expected *Table
+		}{
+			{name: "NoFilter"},
+			{
+				name:     "FilterTimestamp",
+				iterOpts: logicalplan.IterOptions{Filter: timestampFilter},
+				expected: sortOrderTable,
+			},
+			{
+				name:     "GroupByTimestamp",
+				iterOpts: logicalplan.IterOptions{GroupBy: []logicalplan.Expr{logicalplan.Col("timestamp")}},
+				expected: sortOrderTable,
+			},
+			{
+				name: "FilterPrimary",
+				iterOpts: logicalplan.IterOptions{
+					Filter: logicalplan.And(
+						logicalplan.Col("example_type").Eq(logicalplan.Literal("cpu")),
+						logicalplan.Col("labels.label1").Eq(logicalplan.Literal("a")),
+					),
+					GroupBy: []logicalplan.Expr{logicalplan.Col("timestamp")},
+				},
+			},
+		} {
+			t.Run(tc.name, func(t *testing.T) {
+				require.Equal(t, tc.expected, table.sortOrderFor(&tc.iterOpts))
+			})
+		}
+	})
+
+	// queryValues returns the values of the rows matching the filter.
+	queryValues := func(t *testing.T, filter logicalplan.Expr) []int64 {
+		t.Helper()
+		var values []int64
+		require.NoError(t, query.NewEngine(memory.DefaultAllocator, db.TableProvider()).
+			ScanTable("test").
+			Filter(filter).
+			Project(logicalplan.Col("value")).
+			Execute(ctx, func(_ context.Context, r arrow.Record) error {
+				idx := r.Schema().FieldIndices("value")
+				require.Len(t, idx, 1)
+				col := r.Column(idx[0]).(*array.Int64)
+				for i := 0; i < col.Len(); i++ {
+					values = append(values, col.Value(i))
+				}
+				return nil
+			}))
+		return values
+	}
+
+	t.Run("Query", func(t *testing.T) {
+		// Served by the sort order.
+		require.ElementsMatch(t, []int64{2, 3}, queryValues(t, logicalplan.Col("timestamp").Gt(logicalplan.Literal(int64(1)))))
+		require.ElementsMatch(t, []int64{1, 3}, queryValues(t, logicalplan.Col("example_type").Eq(logicalplan.Literal("cpu"))))
+	})
+
+	t.Run("Delete", func(t *testing.T) {
+		require.NoError(t, table.Delete(ctx, logicalplan.Col("timestamp").Eq(logicalplan.Literal(int64(2)))))
+		require.Equal(t, []int64{3}, queryValues(t, logicalplan.Col("timestamp").Gt(logicalplan.Literal(int64(1)))))
+		require.Equal(t, []int64{3}, queryValues(t, logicalplan.And(
+			logicalplan.Col("example_type").Eq(logicalplan.Literal("cpu")),
+			logicalplan.Col("labels.label1").Eq(logicalplan.Literal("a")),
+		)))
+	})
+
+	t.Run("MirrorFailure", func(t *testing.T) {
+		// Make inserts into the sort order table fail.
+		sortOrderTable.mtx.Lock()
+		sortOrderTable.closing = true
+		sortOrderTable.mtx.Unlock()
+		defer func() {
+			sortOrderTable.mtx.Lock()
+			sortOrderTable.closing = false
+			sortOrderTable.mtx.Unlock()
+		}()
+
+		r, err := dynparquet.Samples{
+			{ExampleType: "cpu", Labels: map[string]string{"label1": "c"}, Timestamp: 4, Value: 4},
+		}.ToRecord()
+		require.NoError(t, err)
+		defer r.Release()
+		_, err = table.InsertRecord(ctx, r)
+		require.NoError(t, err)
+
+		// The stale sort order is no longer read, so the record is found.
+		require.True(t, sortOrderTable.sortOrderStale.Load())
+		require.Nil(t, table.sortOrderFor(&logicalplan.IterOptions{
+			Filter: logicalplan.Col("timestamp").Gt(logicalplan.Literal(int64(1))),
+		}))
+		require.ElementsMatch(t, []int64{3, 4}, queryValues(t, logicalplan.Col("timestamp").Gt(logicalplan.Literal(int64(1)))))
+	})
+
+	t.Run("Config", func(t *testing.T) {
+		// The same sort orders can be passed when getting the table again, but
+		// they cannot be changed.
+		_, err := db.Table("test", NewTableConfig(dynparquet.SampleDefinition(), byTimestamp))
+		require.NoError(t, err)
+		_, err = db.Table("test", NewTableConfig(dynparquet.SampleDefinition()))
+		require.Error(t, err)
+
+		config := NewTableConfig(dynparquet.SampleDefinition(), byTimestamp)
+		require.Error(t, byTimestamp(config))
+		require.Error(t, WithSortOrder("by@value", &schemav2pb.SortingColumn{Path: "value"})(config))
+		require.Error(t, WithSortOrder("by_value")(config))
+	})
+}
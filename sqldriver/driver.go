@@ -0,0 +1,333 @@
+// Package sqldriver implements a read-only database/sql driver for FrostDB.
+// Queries are compiled with the SQL frontend in sqlparse, so only the SELECT
+// statements supported by it can be run, e.g.:
+//
+//	db := sql.OpenDB(sqldriver.NewConnector(engine))
+//	rows, err := db.QueryContext(ctx, "SELECT sum(value) FROM test GROUP BY labels.label1")
+//
+// Alternatively, an engine can be registered under a name with RegisterEngine
+// and opened with sql.Open("frostdb", name), for tooling that is configured
+// using a driver and data source name.
+package sqldriver
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"sync"
+
+	"github.com/apache/arrow/go/v17/arrow"
+	"github.com/apache/arrow/go/v17/arrow/array"
+
+	"github.com/polarsignals/frostdb/query"
+	"github.com/polarsignals/frostdb/sqlparse"
+)
+
+// DriverName is the name the driver is registered with in database/sql.
+const DriverName = "frostdb"
+
+// ErrReadOnly is returned for statements and transactions that would modify
+// data. The driver only supports queries.
+var ErrReadOnly = errors.New("frostdb: the sql driver is read-only")
+
+var (
+	enginesMtx sync.RWMutex
+	engines    = map[string]*query.LocalEngine{}
+)
+
+func init() {
+	sql.Register(DriverName, &Driver{})
+}
+
+// RegisterEngine makes the engine available to sql.Open("frostdb", name).
+// Registering an engine under an existing name replaces it.
+func RegisterEngine(name string, engine *query.LocalEngine) {
+	enginesMtx.Lock()
+	defer enginesMtx.Unlock()
+	engines[name] = engine
+}
+
+// UnregisterEngine removes the engine registered under the given name.
+// Connections that were already opened keep using it.
+func UnregisterEngine(name string) {
+	enginesMtx.Lock()
+	defer enginesMtx.Unlock()
+	delete(engines, name)
+}
+
+// Driver is the database/sql driver. The data source name is the name of an
+// engine registered with RegisterEngine.
+type Driver struct{}
+
+var (
+	_ driver.Driver        = (*Driver)(nil)
+	_ driver.DriverContext = (*Driver)(nil)
+)
+
+func (d *Driver) Open(name string) (driver.Conn, error) {
+	c, err := d.OpenConnector(name)
+	if err != nil {
+		return nil, err
+	}
+	return c.Connect(context.Background())
+}
+
+func (d *Driver) OpenConnector(name string) (driver.Connector, error) {
+	enginesMtx.RLock()
+	engine, ok := engines[name]
+	enginesMtx.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("frostdb: no engine registered as %q", name)
+	}
+	return NewConnector(engine), nil
+}
+
+// NewConnector returns a connector querying the given engine, to be used with
+// sql.OpenDB.
+func NewConnector(engine *query.LocalEngine) driver.Connector {
+	return &connector{engine: engine}
+}
+
+type connector struct {
+	engine *query.LocalEngine
+}
+
+func (c *connector) Connect(context.Context) (driver.Conn, error) {
+	return &conn{engine: c.engine, parser: sqlparse.NewParser()}, nil
+}
+
+func (c *connector) Driver() driver.Driver { return &Driver{} }
+
+// conn is a connection to an engine. Connections are not used concurrently by
+// database/sql, so the parser is not synchronized.
+type conn struct {
+	engine *query.LocalEngine
+	parser *sqlparse.Parser
+}
+
+var (
+	_ driver.QueryerContext = (*conn)(nil)
+	_ driver.ConnBeginTx    = (*conn)(nil)
+)
+
+func (c *conn) Prepare(query string) (driver.Stmt, error) {
+	return &stmt{conn: c, query: query}, nil
+}
+
+func (c *conn) Close() error { return nil }
+
+func (c *conn) Begin() (driver.Tx, error) { return nil, ErrReadOnly }
+
+func (c *conn) BeginTx(context.Context, driver.TxOptions) (driver.Tx, error) {
+	return nil, ErrReadOnly
+}
+
+func (c *conn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	if len(args) > 0 {
+		return nil, errors.New("frostdb: query arguments are not supported")
+	}
+
+	res, err := c.parser.Parse(c.engine, query)
+	if err != nil {
+		return nil, err
+	}
+
+	if res.Explain {
+		explain, err := res.Plan.Explain(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return &explainRows{plan: explain}, nil
+	}
+
+	r := &rows{}
+	if err := res.Plan.Execute(ctx, func(_ context.Context, record arrow.Record) error {
+		r.add(record)
+		return nil
+	}); err != nil {
+		r.Close()
+		return nil, err
+	}
+	return r, nil
+}
+
+type stmt struct {
+	conn  *conn
+	query string
+}
+
+var _ driver.StmtQueryContext = (*stmt)(nil)
+
+func (s *stmt) Close() error { return nil }
+
+// NumInput returns -1 since placeholders are not supported, so that queries
+// with arguments fail with a descriptive error.
+func (s *stmt) NumInput() int { return -1 }
+
+func (s *stmt) Exec([]driver.Value) (driver.Result, error) { return nil, ErrReadOnly }
+
+func (s *stmt) Query(args []driver.Value) (driver.Rows, error) {
+	named := make([]driver.NamedValue, 0, len(args))
+	for i, arg := range args {
+		named = append(named, driver.NamedValue{Ordinal: i + 1, Value: arg})
+	}
+	return s.QueryContext(context.Background(), named)
+}
+
+func (s *stmt) QueryContext(ctx context.Context, args []driver.NamedValue) (driver.Rows, error) {
+	return s.conn.QueryContext(ctx, s.query, args)
+}
+
+// rows are the rows of a query result. Records may have different schemas,
+// e.g. when selecting dynamic columns, so the columns are the union of the
+// columns of all records in order of appearance, and columns that a record
+// does not contain are NULL.
+type rows struct {
+	columns []string
+	types   []arrow.DataType
+	indices map[string]int
+
+	records []arrow.Record
+	// fields maps the columns to the fields of each record, or -1.
+	fields [][]int
+
+	record int
+	row    int
+}
+
+var _ driver.RowsColumnTypeDatabaseTypeName = (*rows)(nil)
+
+func (r *rows) add(record arrow.Record) {
+	if r.indices == nil {
+		r.indices = map[string]int{}
+	}
+	schema := record.Schema()
+	for _, field := range schema.Fields() {
+		if _, ok := r.indices[field.Name]; ok {
+			continue
+		}
+		r.indices[field.Name] = len(r.columns)
+		r.columns = append(r.columns, field.Name)
+		r.types = append(r.types, field.Type)
+	}
+
+	record.Retain()
+	r.records = append(r.records, record)
+	r.fields = append(r.fields, nil)
+}
+
+func (r *rows) Columns() []string { return r.columns }
+
+func (r *rows) ColumnTypeDatabaseTypeName(index int) string {
+	return r.types[index].String()
+}
+
+func (r *rows) Close() error {
+	for _, record := range r.records {
+		record.Release()
+	}
+	r.records = nil
+	return nil
+}
+
+func (r *rows) Next(dest []driver.Value) error {
+	for r.record < len(r.records) && int64(r.row) >= r.records[r.record].NumRows() {
+		r.record++
+		r.row = 0
+	}
+	if r.record >= len(r.records) {
+		return io.EOF
+	}
+
+	record := r.records[r.record]
+	if r.fields[r.record] == nil {
+		fields := make([]int, len(r.columns))
+		for i, column := range r.columns {
+			fields[i] = -1
+			if indices := record.Schema().FieldIndices(column); len(indices) > 0 {
+				fields[i] = indices[0]
+			}
+		}
+		r.fields[r.record] = fields
+	}
+
+	for i, field := range r.fields[r.record] {
+		if field == -1 {
+			dest[i] = nil
+			continue
+		}
+		dest[i] = value(record.Column(field), r.row)
+	}
+	r.row++
+	return nil
+}
+
+// value converts the value at index i of the array to one of the types
+// supported by database/sql drivers.
+func value(arr arrow.Array, i int) driver.Value {
+	if arr.IsNull(i) {
+		return nil
+	}
+	switch a := arr.(type) {
+	case *array.Boolean:
+		return a.Value(i)
+	case *array.Int8:
+		return int64(a.Value(i))
+	case *array.Int16:
+		return int64(a.Value(i))
+	case *array.Int32:
+		return int64(a.Value(i))
+	case *array.Int64:
+		return a.Value(i)
+	case *array.Uint8:
+		return int64(a.Value(i))
+	case *array.Uint16:
+		return int64(a.Value(i))
+	case *array.Uint32:
+		return int64(a.Value(i))
+	case *array.Uint64:
+		v := a.Value(i)
+		if v > math.MaxInt64 {
+			return strconv.FormatUint(v, 10)
+		}
+		return int64(v)
+	case *array.Float32:
+		return float64(a.Value(i))
+	case *array.Float64:
+		return a.Value(i)
+	case *array.String:
+		return a.Value(i)
+	case *array.Binary:
+		return append([]byte(nil), a.Value(i)...)
+	case *array.Timestamp:
+		return a.Value(i).ToTime(a.DataType().(*arrow.TimestampType).Unit)
+	case *array.Dictionary:
+		return value(a.Dictionary(), a.GetValueIndex(i))
+	default:
+		return arr.ValueStr(i)
+	}
+}
+
+// explainRows is the single row result of an EXPLAIN statement.
+type explainRows struct {
+	plan string
+	done bool
+}
+
+func (r *explainRows) Columns() []string { return []string{"plan"} }
+
+func (r *explainRows) Close() error { return nil }
+
+func (r *explainRows) Next(dest []driver.Value) error {
+	if r.done {
+		return io.EOF
+	}
+	dest[0] = r.plan
+	r.done = true
+	return nil
+}
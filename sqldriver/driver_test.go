@@ -0,0 +1,121 @@
+package sqldriver_test
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/apache/arrow/go/v17/arrow/memory"
+	"github.com/stretchr/testify/require"
+
+	"github.com/polarsignals/frostdb"
+	"github.com/polarsignals/frostdb/dynparquet"
+	"github.com/polarsignals/frostdb/query"
+	"github.com/polarsignals/frostdb/sqldriver"
+)
+
+func TestDriver(t *testing.T) {
+	ctx := context.Background()
+	c, err := frostdb.New()
+	require.NoError(t, err)
+	defer c.Close()
+
+	db, err := c.DB(ctx, "test")
+	require.NoError(t, err)
+	table, err := db.Table("test", frostdb.NewTableConfig(dynparquet.SampleDefinition()))
+	require.NoError(t, err)
+
+	samples := dynparquet.Samples{
+		{ExampleType: "cpu", Labels: map[string]string{"label1": "a"}, Timestamp: 50, Value: 1},
+		{ExampleType: "cpu", Labels: map[string]string{"label1": "a"}, Timestamp: 150, Value: 2},
+		{ExampleType: "cpu", Labels: map[string]string{"label1": "b", "label2": "c"}, Timestamp: 300, Value: 4},
+	}
+	r, err := samples.ToRecord()
+	require.NoError(t, err)
+	defer r.Release()
+	_, err = table.InsertRecord(ctx, r)
+	require.NoError(t, err)
+
+	engine := query.NewEngine(memory.DefaultAllocator, db.TableProvider())
+	sqlDB := sql.OpenDB(sqldriver.NewConnector(engine))
+	defer sqlDB.Close()
+
+	t.Run("Aggregation", func(t *testing.T) {
+		rows, err := sqlDB.QueryContext(ctx, "SELECT labels.label1, sum(value) FROM test WHERE timestamp > 100 GROUP BY labels.label1")
+		require.NoError(t, err)
+		defer rows.Close()
+
+		columns, err := rows.Columns()
+		require.NoError(t, err)
+		require.ElementsMatch(t, []string{"labels.label1", "sum(value)"}, columns)
+
+		sums := map[string]int64{}
+		for rows.Next() {
+			var (
+				label string
+				sum   int64
+			)
+			dest := []any{&label, &sum}
+			if columns[0] != "labels.label1" {
+				dest = []any{&sum, &label}
+			}
+			require.NoError(t, rows.Scan(dest...))
+			sums[label] = sum
+		}
+		require.NoError(t, rows.Err())
+		require.Equal(t, map[string]int64{"a": 2, "b": 4}, sums)
+	})
+
+	t.Run("DynamicColumns", func(t *testing.T) {
+		rows, err := sqlDB.QueryContext(ctx, "SELECT labels FROM test")
+		require.NoError(t, err)
+		defer rows.Close()
+
+		columns, err := rows.Columns()
+		require.NoError(t, err)
+		require.ElementsMatch(t, []string{"labels.label1", "labels.label2"}, columns)
+
+		n := 0
+		for rows.Next() {
+			values := make([]sql.NullString, len(columns))
+			dest := make([]any, len(values))
+			for i := range values {
+				dest[i] = &values[i]
+			}
+			require.NoError(t, rows.Scan(dest...))
+			n++
+		}
+		require.NoError(t, rows.Err())
+		require.Equal(t, 3, n)
+	})
+
+	t.Run("Explain", func(t *testing.T) {
+		var plan string
+		require.NoError(t, sqlDB.QueryRowContext(ctx, "EXPLAIN SELECT labels FROM test").Scan(&plan))
+		require.NotEmpty(t, plan)
+	})
+
+	t.Run("RegisteredEngine", func(t *testing.T) {
+		sqldriver.RegisterEngine("test", engine)
+		defer sqldriver.UnregisterEngine("test")
+
+		registered, err := sql.Open(sqldriver.DriverName, "test")
+		require.NoError(t, err)
+		defer registered.Close()
+
+		var count int64
+		require.NoError(t, registered.QueryRowContext(ctx, "SELECT count(value) FROM test").Scan(&count))
+		require.Equal(t, int64(3), count)
+
+		_, err = sql.Open(sqldriver.DriverName, "unknown")
+		require.Error(t, err)
+	})
+
+	t.Run("ReadOnly", func(t *testing.T) {
+		_, err := sqlDB.BeginTx(ctx, nil)
+		require.ErrorIs(t, err, sqldriver.ErrReadOnly)
+
+		_, err = sqlDB.QueryContext(ctx, "SELECT value FROM test WHERE timestamp > ?", 100)
+		require.Error(t, err)
+	})
+}
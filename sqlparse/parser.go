@@ -4,6 +4,7 @@ import (
 	"fmt"
 
 	"github.com/pingcap/tidb/parser"
+	"github.com/pingcap/tidb/parser/ast"
 
 	"github.com/polarsignals/frostdb/query"
 )
@@ -32,20 +33,95 @@ func (p *Parser) ExperimentalParse(
 	dynColNames []string,
 	sql string,
 ) (ParseResult, error) {
-	asts, _, err := p.p.Parse(sql, "", "")
+	stmt, err := p.parseStmt(sql)
+	if err != nil {
+		return ParseResult{}, err
+	}
+	return p.build(builder, dynColNames, stmt)
+}
+
+// Parse compiles the given SQL query into a query on the given engine, e.g.:
+//
+//	SELECT labels.label1, sum(value) FROM test WHERE timestamp > 100 GROUP BY labels.label1
+//
+// The table to query is taken from the FROM clause. Columns are resolved using
+// the table's schema, so dynamic columns can be referenced either by their
+// name (e.g. labels) or by the name of a concrete column (e.g.
+// labels.label1).
+func (p *Parser) Parse(engine *query.LocalEngine, sql string) (ParseResult, error) {
+	stmt, err := p.parseStmt(sql)
+	if err != nil {
+		return ParseResult{}, err
+	}
+
+	tableName, err := tableNameFromStmt(stmt)
 	if err != nil {
 		return ParseResult{}, err
 	}
+	table, err := engine.TableProvider().GetTable(tableName)
+	if err != nil {
+		return ParseResult{}, fmt.Errorf("get table %q: %w", tableName, err)
+	}
+
+	var dynColNames []string
+	for _, col := range table.Schema().Columns() {
+		if col.Dynamic {
+			dynColNames = append(dynColNames, col.Name)
+		}
+	}
+
+	return p.build(engine.ScanTable(tableName), dynColNames, stmt)
+}
+
+func (p *Parser) parseStmt(sql string) (ast.StmtNode, error) {
+	asts, _, err := p.p.Parse(sql, "", "")
+	if err != nil {
+		return nil, err
+	}
 
 	if len(asts) != 1 {
-		return ParseResult{}, fmt.Errorf("cannot handle multiple asts, found %d", len(asts))
+		return nil, fmt.Errorf("cannot handle multiple asts, found %d", len(asts))
 	}
+	return asts[0], nil
+}
 
+func (p *Parser) build(
+	builder query.Builder,
+	dynColNames []string,
+	stmt ast.StmtNode,
+) (ParseResult, error) {
 	v := newASTVisitor(builder, dynColNames)
-	asts[0].Accept(v)
+	stmt.Accept(v)
 	if v.err != nil {
 		return ParseResult{}, v.err
 	}
 
 	return ParseResult{Explain: v.explain, Plan: v.builder}, nil
 }
+
+// tableNameFromStmt returns the name of the single table queried by the given
+// (possibly explained) select statement.
+func tableNameFromStmt(stmt ast.StmtNode) (string, error) {
+	if explain, ok := stmt.(*ast.ExplainStmt); ok {
+		stmt = explain.Stmt
+	}
+	sel, ok := stmt.(*ast.SelectStmt)
+	if !ok {
+		return "", fmt.Errorf("unsupported statement %T: only SELECT is supported", stmt)
+	}
+	if sel.From == nil || sel.From.TableRefs == nil {
+		return "", fmt.Errorf("no table specified: missing FROM clause")
+	}
+	if sel.From.TableRefs.Right != nil {
+		return "", fmt.Errorf("joins are not supported")
+	}
+	source, ok := sel.From.TableRefs.Left.(*ast.TableSource)
+	if !ok {
+		return "", fmt.Errorf("unsupported FROM clause %T", sel.From.TableRefs.Left)
+	}
+	table, ok := source.Source.(*ast.TableName)
+	if !ok {
+		return "", fmt.Errorf("unsupported table source %T: subqueries are not supported", source.Source)
+	}
+	return table.Name.O, nil
+}
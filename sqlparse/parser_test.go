@@ -0,0 +1,99 @@
+package sqlparse_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/apache/arrow/go/v17/arrow"
+	"github.com/apache/arrow/go/v17/arrow/array"
+	"github.com/apache/arrow/go/v17/arrow/memory"
+	"github.com/stretchr/testify/require"
+
+	"github.com/polarsignals/frostdb"
+	"github.com/polarsignals/frostdb/dynparquet"
+	"github.com/polarsignals/frostdb/query"
+	"github.com/polarsignals/frostdb/sqlparse"
+)
+
+func TestParse(t *testing.T) {
+	ctx := context.Background()
+	c, err := frostdb.New()
+	require.NoError(t, err)
+	defer c.Close()
+
+	db, err := c.DB(ctx, "test")
+	require.NoError(t, err)
+	table, err := db.Table("test", frostdb.NewTableConfig(dynparquet.SampleDefinition()))
+	require.NoError(t, err)
+
+	samples := dynparquet.Samples{
+		{ExampleType: "cpu", Labels: map[string]string{"label1": "a"}, Timestamp: 50, Value: 1},
+		{ExampleType: "cpu", Labels: map[string]string{"label1": "a"}, Timestamp: 150, Value: 2},
+		{ExampleType: "cpu", Labels: map[string]string{"label1": "a"}, Timestamp: 200, Value: 3},
+		{ExampleType: "cpu", Labels: map[string]string{"label1": "b"}, Timestamp: 300, Value: 4},
+	}
+	r, err := samples.ToRecord()
+	require.NoError(t, err)
+	defer r.Release()
+	_, err = table.InsertRecord(ctx, r)
+	require.NoError(t, err)
+
+	engine := query.NewEngine(memory.DefaultAllocator, db.TableProvider())
+	p := sqlparse.NewParser()
+
+	t.Run("Aggregation", func(t *testing.T) {
+		res, err := p.Parse(engine, "SELECT labels.label1, sum(value) FROM test WHERE timestamp > 100 GROUP BY labels.label1")
+		require.NoError(t, err)
+		require.False(t, res.Explain)
+
+		sums := map[string]int64{}
+		require.NoError(t, res.Plan.Execute(ctx, func(_ context.Context, r arrow.Record) error {
+			labels := r.Column(r.Schema().FieldIndices("labels.label1")[0])
+			values := r.Column(r.Schema().FieldIndices("sum(value)")[0]).(*array.Int64)
+			for i := 0; i < int(r.NumRows()); i++ {
+				sums[labels.ValueStr(i)] = values.Value(i)
+			}
+			return nil
+		}))
+		require.Equal(t, map[string]int64{"a": 5, "b": 4}, sums)
+	})
+
+	t.Run("LimitOffset", func(t *testing.T) {
+		for sql, expected := range map[string]int64{
+			"SELECT value FROM test LIMIT 2":          2,
+			"SELECT value FROM test LIMIT 2 OFFSET 1": 2,
+			"SELECT value FROM test LIMIT 2 OFFSET 3": 1,
+			"SELECT value FROM test LIMIT 1, 5":       3,
+		} {
+			res, err := p.Parse(engine, sql)
+			require.NoError(t, err)
+			rows := int64(0)
+			require.NoError(t, res.Plan.Execute(ctx, func(_ context.Context, r arrow.Record) error {
+				rows += r.NumRows()
+				return nil
+			}))
+			require.Equal(t, expected, rows, sql)
+		}
+	})
+
+	t.Run("Explain", func(t *testing.T) {
+		res, err := p.Parse(engine, "EXPLAIN SELECT labels FROM test")
+		require.NoError(t, err)
+		require.True(t, res.Explain)
+	})
+
+	for _, tc := range []struct {
+		name string
+		sql  string
+	}{
+		{name: "MissingFrom", sql: "SELECT value"},
+		{name: "UnknownTable", sql: "SELECT value FROM unknown"},
+		{name: "Join", sql: "SELECT value FROM test JOIN other"},
+		{name: "NotSelect", sql: "DELETE FROM test"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := p.Parse(engine, tc.sql)
+			require.Error(t, err)
+		})
+	}
+}
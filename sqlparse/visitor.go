@@ -55,10 +55,12 @@ func (v *astVisitor) Enter(n ast.Node) (nRes ast.Node, skipChildren bool) {
 		}
 		expr.Fields.Accept(v)
 		switch {
-		case expr.GroupBy != nil:
+		case expr.GroupBy != nil || hasAggregations(v.exprStack):
 			// This represents everything before the "group by" clause.
 			beforeGroupBy := v.exprStack
-			expr.GroupBy.Accept(v)
+			if expr.GroupBy != nil {
+				expr.GroupBy.Accept(v)
+			}
 			// This represents everything after the "group by" clause.
 			afterGroupBy := v.exprStack[len(beforeGroupBy):]
 			groups := afterGroupBy
@@ -137,15 +139,11 @@ func (v *astVisitor) Enter(n ast.Node) (nRes ast.Node, skipChildren bool) {
 
 			// Finally we check if the result should be limited.
 			if expr.Limit != nil {
-				expr.Limit.Count.Accept(v)
-				lastExpr, _ := pop(v.exprStack)
-				v.builder = v.builder.Limit(lastExpr)
+				v.limit(expr.Limit)
 			}
 		case expr.Limit != nil:
-			expr.Limit.Count.Accept(v)
-			lastExpr, newExprs := pop(v.exprStack)
-			v.builder = v.builder.Project(newExprs...)
-			v.builder = v.builder.Limit(lastExpr)
+			v.builder = v.builder.Project(v.exprStack...)
+			v.limit(expr.Limit)
 		case expr.Distinct:
 			v.builder = v.builder.Project(v.exprStack...)
 			v.builder = v.builder.Distinct(v.exprStack...)
@@ -157,6 +155,19 @@ func (v *astVisitor) Enter(n ast.Node) (nRes ast.Node, skipChildren bool) {
 	return n, false
 }
 
+// hasAggregations returns whether any of the given expressions contains an
+// aggregation, in which case the query is aggregated even without a "group
+// by" clause, for example:
+//
+// SELECT count(value) FROM test
+func hasAggregations(exprs []logicalplan.Expr) bool {
+	aggCollector := &aggregationCollector{}
+	for _, expr := range exprs {
+		expr.Accept(aggCollector)
+	}
+	return len(aggCollector.aggregations) > 0
+}
+
 type aggregationCollector struct {
 	aggregations []*logicalplan.AggregationFunction
 }
@@ -207,6 +218,10 @@ func (v *astVisitor) leaveImpl(n ast.Node) error {
 			v.exprStack[lastExpr] = logicalplan.Max(v.exprStack[lastExpr])
 		case "avg":
 			v.exprStack[lastExpr] = logicalplan.Avg(v.exprStack[lastExpr])
+		case "std", "stddev", "stddev_pop":
+			v.exprStack[lastExpr] = logicalplan.Stddev(v.exprStack[lastExpr])
+		case "variance", "var_pop":
+			v.exprStack[lastExpr] = logicalplan.Variance(v.exprStack[lastExpr])
 		default:
 			return fmt.Errorf("unhandled aggregate function %s", expr.F)
 		}
@@ -231,6 +246,8 @@ func (v *astVisitor) leaveImpl(n ast.Node) error {
 			frostDBOp = logicalplan.OpEq
 		case opcode.NE:
 			frostDBOp = logicalplan.OpNotEq
+		case opcode.NullEQ:
+			frostDBOp = logicalplan.OpNullSafeEq
 		case opcode.Plus:
 			frostDBOp = logicalplan.OpAdd
 		case opcode.Minus:
@@ -280,6 +297,8 @@ func (v *astVisitor) leaveImpl(n ast.Node) error {
 				v.exprStack[lastExpr] = e.Alias(as)
 			case *logicalplan.Column:
 				v.exprStack[lastExpr] = e.Alias(as)
+			case *logicalplan.CoalesceExpr:
+				v.exprStack[lastExpr] = e.Alias(as)
 			default:
 				return fmt.Errorf("unhandled select field %s", as)
 			}
@@ -333,6 +352,10 @@ func (v *astVisitor) leaveImpl(n ast.Node) error {
 				exprStack = append(exprStack, logicalplan.Duration(duration))
 				v.exprStack = exprStack
 			}
+		case ast.Coalesce:
+			first := len(v.exprStack) - len(expr.Args)
+			args := append([]logicalplan.Expr(nil), v.exprStack[first:]...)
+			v.exprStack = append(v.exprStack[:first], logicalplan.Coalesce(args...))
 		default:
 			return fmt.Errorf("unhandled func call: %s", expr.FnName.String())
 		}
@@ -366,6 +389,18 @@ func columnNameToString(c *ast.ColumnName) string {
 	return colName
 }
 
+// limit adds the limit and offset of the given limit clause to the plan.
+func (v *astVisitor) limit(limit *ast.Limit) {
+	var count, offset logicalplan.Expr
+	limit.Count.Accept(v)
+	count, v.exprStack = pop(v.exprStack)
+	if limit.Offset != nil {
+		limit.Offset.Accept(v)
+		offset, v.exprStack = pop(v.exprStack)
+	}
+	v.builder = v.builder.Limit(count).Offset(offset)
+}
+
 func pop[T any](s []T) (T, []T) {
 	lastIdx := len(s) - 1
 	return s[lastIdx], s[:lastIdx]
@@ -0,0 +1,392 @@
+package storage
+
+import (
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// DefaultCacheChunkSize is the default size of the byte ranges a LocalCache
+// caches objects in.
+const DefaultCacheChunkSize = 1 << 20 // 1MiB
+
+// LocalCache caches byte ranges of objects on local disk, evicting the least
+// recently used ranges once the cache exceeds its size budget. Objects are
+// split into chunks of a fixed size so that reading the footer of a large
+// block does not download the whole block. Cached chunks survive restarts.
+//
+// Cached objects are assumed to be immutable: a LocalCache does not notice if
+// an object changes in the bucket, so objects must be invalidated when they
+// are overwritten or deleted. CachedBucket does so for its own writes.
+type LocalCache struct {
+	dir       string
+	maxBytes  int64
+	chunkSize int64
+
+	mtx     sync.Mutex
+	lru     *list.List
+	objects map[string]map[int64]*list.Element
+	size    int64
+	stats   CacheStats
+}
+
+// CacheStats are the statistics of a LocalCache.
+type CacheStats struct {
+	// Hits and Misses count the chunks read from the cache and downloaded
+	// from the bucket respectively.
+	Hits   uint64
+	Misses uint64
+	// Evictions counts the chunks evicted to stay within the size budget.
+	Evictions uint64
+	// Bytes and Chunks are the current size of the cache.
+	Bytes  int64
+	Chunks int
+}
+
+type cacheChunk struct {
+	object string
+	index  int64
+	size   int64
+}
+
+// LocalCacheOption configures a LocalCache.
+type LocalCacheOption func(*LocalCache)
+
+// CacheWithChunkSize sets the size of the byte ranges objects are cached in.
+func CacheWithChunkSize(size int64) LocalCacheOption {
+	return func(c *LocalCache) {
+		c.chunkSize = size
+	}
+}
+
+// NewLocalCache returns a LocalCache storing at most maxBytes in dir. Chunks
+// cached in dir by a previous LocalCache are loaded, oldest first.
+func NewLocalCache(dir string, maxBytes int64, options ...LocalCacheOption) (*LocalCache, error) {
+	if maxBytes <= 0 {
+		return nil, fmt.Errorf("cache size must be positive, got %d", maxBytes)
+	}
+	c := &LocalCache{
+		dir:       dir,
+		maxBytes:  maxBytes,
+		chunkSize: DefaultCacheChunkSize,
+		lru:       list.New(),
+		objects:   make(map[string]map[int64]*list.Element),
+	}
+	for _, option := range options {
+		option(c)
+	}
+	if c.chunkSize <= 0 {
+		return nil, fmt.Errorf("cache chunk size must be positive, got %d", c.chunkSize)
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("create cache directory: %w", err)
+	}
+	if err := c.load(); err != nil {
+		return nil, fmt.Errorf("load cache: %w", err)
+	}
+	return c, nil
+}
+
+// load adds the chunks found in the cache directory to the cache.
+func (c *LocalCache) load() error {
+	type found struct {
+		chunk   *cacheChunk
+		modTime time.Time
+	}
+	var chunks []found
+	err := filepath.WalkDir(c.dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		rel, err := filepath.Rel(c.dir, path)
+		if err != nil {
+			return err
+		}
+		object, name := filepath.Split(rel)
+		index, err := strconv.ParseInt(name, 10, 64)
+		if err != nil || filepath.Dir(rel) == "." {
+			// Leftover temporary file of an interrupted download.
+			return os.Remove(path)
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		chunks = append(chunks, found{
+			chunk: &cacheChunk{
+				object: filepath.Clean(object),
+				index:  index,
+				size:   info.Size(),
+			},
+			modTime: info.ModTime(),
+		})
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	sort.Slice(chunks, func(i, j int) bool {
+		return chunks[i].modTime.Before(chunks[j].modTime)
+	})
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	for _, f := range chunks {
+		c.addLocked(f.chunk)
+	}
+	return nil
+}
+
+// Stats returns the statistics of the cache.
+func (c *LocalCache) Stats() CacheStats {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	stats := c.stats
+	stats.Bytes = c.size
+	stats.Chunks = c.lru.Len()
+	return stats
+}
+
+// Invalidate removes all cached chunks of the given object.
+func (c *LocalCache) Invalidate(name string) error {
+	object := objectKey(name)
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	for _, e := range c.objects[object] {
+		c.removeLocked(e)
+	}
+	return os.RemoveAll(filepath.Join(c.dir, object))
+}
+
+// objectKey returns the directory the chunks of the given object are cached
+// in. Object names are hashed so that they map to a flat directory structure.
+func objectKey(name string) string {
+	sum := sha256.Sum256([]byte(name))
+	return hex.EncodeToString(sum[:])
+}
+
+func (c *LocalCache) chunkPath(object string, index int64) string {
+	return filepath.Join(c.dir, object, strconv.FormatInt(index, 10))
+}
+
+// addLocked adds the given chunk to the cache, evicting the least recently
+// used chunks if the cache exceeds its size budget. c.mtx must be held.
+func (c *LocalCache) addLocked(chunk *cacheChunk) {
+	if e, ok := c.objects[chunk.object][chunk.index]; ok {
+		c.lru.MoveToFront(e)
+		return
+	}
+	if _, ok := c.objects[chunk.object]; !ok {
+		c.objects[chunk.object] = make(map[int64]*list.Element)
+	}
+	c.objects[chunk.object][chunk.index] = c.lru.PushFront(chunk)
+	c.size += chunk.size
+	for c.size > c.maxBytes && c.lru.Len() > 1 {
+		oldest := c.lru.Back()
+		c.removeLocked(oldest)
+		// A chunk that is being read remains readable after its file is
+		// removed.
+		_ = os.Remove(c.chunkPath(oldest.Value.(*cacheChunk).object, oldest.Value.(*cacheChunk).index))
+		c.stats.Evictions++
+	}
+}
+
+// removeLocked removes the given chunk from the cache without removing its
+// file. c.mtx must be held.
+func (c *LocalCache) removeLocked(e *list.Element) {
+	chunk := e.Value.(*cacheChunk)
+	c.lru.Remove(e)
+	c.size -= chunk.size
+	delete(c.objects[chunk.object], chunk.index)
+	if len(c.objects[chunk.object]) == 0 {
+		delete(c.objects, chunk.object)
+	}
+}
+
+// readAt reads len(p) bytes of the given object at off, reading cached chunks
+// from disk and downloading missing chunks from bucket.
+func (c *LocalCache) readAt(ctx context.Context, bucket Bucket, name string, p []byte, off int64) (int, error) {
+	object := objectKey(name)
+	n := 0
+	for n < len(p) {
+		pos := off + int64(n)
+		index := pos / c.chunkSize
+		m, err := c.readChunk(ctx, bucket, name, object, index, p[n:], pos-index*c.chunkSize)
+		n += m
+		if err != nil {
+			return n, err
+		}
+	}
+	return n, nil
+}
+
+// readChunk reads the given chunk into p starting at off within the chunk. It
+// returns io.EOF if the object ends within the chunk before p is filled.
+func (c *LocalCache) readChunk(ctx context.Context, bucket Bucket, name, object string, index int64, p []byte, off int64) (int, error) {
+	if remaining := c.chunkSize - off; int64(len(p)) > remaining {
+		p = p[:remaining]
+	}
+
+	c.mtx.Lock()
+	e, ok := c.objects[object][index]
+	if ok {
+		c.lru.MoveToFront(e)
+		c.stats.Hits++
+	} else {
+		c.stats.Misses++
+	}
+	c.mtx.Unlock()
+
+	if ok {
+		n, err := c.readCachedChunk(object, index, p, off)
+		if err == nil || errors.Is(err, io.EOF) {
+			return n, err
+		}
+		// The chunk was evicted concurrently or its file is unreadable,
+		// download it again.
+		c.mtx.Lock()
+		if e, ok := c.objects[object][index]; ok {
+			c.removeLocked(e)
+		}
+		c.mtx.Unlock()
+	}
+
+	data, err := c.download(ctx, bucket, name, object, index)
+	if err != nil {
+		return 0, err
+	}
+	if off >= int64(len(data)) {
+		return 0, io.EOF
+	}
+	n := copy(p, data[off:])
+	if n < len(p) {
+		return n, io.EOF
+	}
+	return n, nil
+}
+
+func (c *LocalCache) readCachedChunk(object string, index int64, p []byte, off int64) (int, error) {
+	f, err := os.Open(c.chunkPath(object, index))
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+	return f.ReadAt(p, off)
+}
+
+// download reads the given chunk from bucket and adds it to the cache.
+func (c *LocalCache) download(ctx context.Context, bucket Bucket, name, object string, index int64) ([]byte, error) {
+	rc, err := bucket.GetRange(ctx, name, index*c.chunkSize, c.chunkSize)
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+	data, err := io.ReadAll(rc)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) == 0 {
+		return data, nil
+	}
+
+	if err := c.store(object, index, data); err != nil {
+		// The cache is best effort, the chunk was read successfully.
+		return data, nil
+	}
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	c.addLocked(&cacheChunk{object: object, index: index, size: int64(len(data))})
+	return data, nil
+}
+
+// store writes the given chunk to disk. The chunk is written to a temporary
+// file first so that readers never observe a partially written chunk.
+func (c *LocalCache) store(object string, index int64, data []byte) error {
+	if err := os.MkdirAll(filepath.Join(c.dir, object), 0o755); err != nil {
+		return err
+	}
+	f, err := os.CreateTemp(c.dir, "chunk-*.tmp")
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(f.Name())
+		return err
+	}
+	if err := os.Rename(f.Name(), c.chunkPath(object, index)); err != nil {
+		os.Remove(f.Name())
+		return err
+	}
+	return nil
+}
+
+// CachedBucket is a Bucket whose reads through GetReaderAt are cached in a
+// LocalCache. Objects uploaded or deleted through the CachedBucket are
+// invalidated in the cache.
+type CachedBucket struct {
+	Bucket
+	cache *LocalCache
+}
+
+// NewCachedBucket returns a CachedBucket caching reads from bucket in cache.
+func NewCachedBucket(bucket Bucket, cache *LocalCache) *CachedBucket {
+	return &CachedBucket{Bucket: bucket, cache: cache}
+}
+
+// Cache returns the cache of the bucket.
+func (b *CachedBucket) Cache() *LocalCache {
+	return b.cache
+}
+
+// GetReaderAt returns an io.ReaderAt for the given object that reads through
+// the cache.
+func (b *CachedBucket) GetReaderAt(ctx context.Context, name string) (io.ReaderAt, error) {
+	return &cachedReaderAt{ctx: ctx, bucket: b.Bucket, cache: b.cache, name: name}, nil
+}
+
+// Upload invalidates the given object in the cache and uploads it.
+func (b *CachedBucket) Upload(ctx context.Context, name string, r io.Reader) error {
+	if err := b.cache.Invalidate(name); err != nil {
+		return fmt.Errorf("invalidate cache: %w", err)
+	}
+	return b.Bucket.Upload(ctx, name, r)
+}
+
+// Delete deletes the given object and invalidates it in the cache.
+func (b *CachedBucket) Delete(ctx context.Context, name string) error {
+	if err := b.Bucket.Delete(ctx, name); err != nil {
+		return err
+	}
+	return b.cache.Invalidate(name)
+}
+
+type cachedReaderAt struct {
+	ctx    context.Context
+	bucket Bucket
+	cache  *LocalCache
+	name   string
+}
+
+// ReadAt implements the io.ReaderAt interface.
+func (r *cachedReaderAt) ReadAt(p []byte, off int64) (int, error) {
+	return r.cache.readAt(r.ctx, r.bucket, r.name, p, off)
+}
@@ -0,0 +1,89 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/thanos-io/objstore"
+)
+
+// countingBucket counts the range requests made to the bucket.
+type countingBucket struct {
+	objstore.Bucket
+	ranges int
+}
+
+func (b *countingBucket) GetRange(ctx context.Context, name string, off, length int64) (io.ReadCloser, error) {
+	b.ranges++
+	return b.Bucket.GetRange(ctx, name, off, length)
+}
+
+func TestLocalCache(t *testing.T) {
+	ctx := context.Background()
+	data := make([]byte, 100)
+	for i := range data {
+		data[i] = byte(i)
+	}
+	inner := &countingBucket{Bucket: objstore.NewInMemBucket()}
+	require.NoError(t, inner.Upload(ctx, "a", bytes.NewReader(data)))
+	require.NoError(t, inner.Upload(ctx, "b", bytes.NewReader(data)))
+
+	dir := t.TempDir()
+	cache, err := NewLocalCache(dir, 64, CacheWithChunkSize(16))
+	require.NoError(t, err)
+	bucket := NewCachedBucket(NewBucketReaderAt(inner), cache)
+
+	read := func(name string, off, length int64) ([]byte, error) {
+		r, err := bucket.GetReaderAt(ctx, name)
+		require.NoError(t, err)
+		p := make([]byte, length)
+		n, err := r.ReadAt(p, off)
+		return p[:n], err
+	}
+
+	// Reads spanning chunks download each chunk once.
+	p, err := read("a", 10, 30)
+	require.NoError(t, err)
+	require.Equal(t, data[10:40], p)
+	require.Equal(t, 3, inner.ranges)
+	p, err = read("a", 20, 20)
+	require.NoError(t, err)
+	require.Equal(t, data[20:40], p)
+	require.Equal(t, 3, inner.ranges)
+	stats := cache.Stats()
+	require.Equal(t, uint64(2), stats.Hits)
+	require.Equal(t, uint64(3), stats.Misses)
+	require.Equal(t, int64(48), stats.Bytes)
+
+	// Reads past the end of the object return io.EOF.
+	p, err = read("a", 90, 20)
+	require.ErrorIs(t, err, io.EOF)
+	require.Equal(t, data[90:], p)
+
+	// The least recently used chunks are evicted to stay within budget.
+	_, err = read("b", 0, 32)
+	require.NoError(t, err)
+	stats = cache.Stats()
+	require.LessOrEqual(t, stats.Bytes, int64(64))
+	require.NotZero(t, stats.Evictions)
+
+	// Cached chunks are loaded on restart.
+	reopened, err := NewLocalCache(dir, 64, CacheWithChunkSize(16))
+	require.NoError(t, err)
+	require.Equal(t, cache.Stats().Chunks, reopened.Stats().Chunks)
+	require.Equal(t, cache.Stats().Bytes, reopened.Stats().Bytes)
+
+	// Uploads invalidate cached chunks.
+	updated := bytes.Repeat([]byte{0xff}, 32)
+	require.NoError(t, bucket.Upload(ctx, "b", bytes.NewReader(updated)))
+	p, err = read("b", 0, 32)
+	require.NoError(t, err)
+	require.Equal(t, updated, p)
+
+	require.NoError(t, bucket.Delete(ctx, "b"))
+	_, err = read("b", 0, 32)
+	require.Error(t, err)
+}
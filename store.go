@@ -1,10 +1,14 @@
 package frostdb
 
 import (
+	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"path/filepath"
+	"strings"
+	"sync"
 
 	"go.opentelemetry.io/otel/trace/noop"
 
@@ -26,6 +30,19 @@ import (
 // DefaultBlockReaderLimit is the concurrency limit for reading blocks.
 const DefaultBlockReaderLimit = 10
 
+// DefaultBlockSchemaCacheSize is the default number of block schemas cached by
+// a DefaultObjstoreBucket.
+const DefaultBlockSchemaCacheSize = 4096
+
+const (
+	// DefaultUploadPartSize is the default size of the parts blocks are
+	// split into when uploading them to a MultipartUploader.
+	DefaultUploadPartSize = 16 * MiB
+	// DefaultUploadConcurrency is the default number of parts that are
+	// uploaded in parallel to a MultipartUploader.
+	DefaultUploadConcurrency = 4
+)
+
 // Persist uploads the block to the underlying bucket.
 func (t *TableBlock) Persist() error {
 	if len(t.table.db.sinks) == 0 {
@@ -36,31 +53,140 @@ func (t *TableBlock) Persist() error {
 		if i > 0 {
 			return fmt.Errorf("multiple sinks not supported")
 		}
-		r, w := io.Pipe()
-		var err error
-		go func() {
-			defer w.Close()
-			err = t.Serialize(w)
-		}()
-		defer r.Close()
 
-		fileName := filepath.Join(t.table.db.name, t.table.name, t.ulid.String(), "data.parquet")
-		if err := sink.Upload(context.Background(), fileName, r); err != nil {
-			return fmt.Errorf("failed to upload block %v", err)
+		if p, ok := tablePartitioning(t.table.config.Load()); ok {
+			if err := t.persistPartitioned(context.Background(), sink, p); err != nil {
+				return err
+			}
+			continue
 		}
 
+		fileName := filepath.Join(t.table.db.name, t.table.name, t.ulid.String(), "data.parquet")
+		n, err := t.table.uploadBlock(context.Background(), sink, fileName, t.Serialize)
 		if err != nil {
-			if deleteErr := sink.Delete(context.Background(), fileName); deleteErr != nil {
-				err = fmt.Errorf("%v failed to delete file on error: %w", err, deleteErr)
-			}
-			return fmt.Errorf("failed to serialize block: %w", err)
+			return err
 		}
+		t.table.persistedBytes.Add(n)
 	}
 
+	t.table.db.blockSchemas.invalidateBlock(t.table.name, t.ulid)
 	t.table.metrics.blockPersisted.Inc()
 	return nil
 }
 
+// uploadBlock uploads the block file written by serialize to the sink and
+// returns its size. Sinks that support multipart uploads are uploaded to
+// with parallel part uploads while the rest of the block is still being
+// serialized.
+func (t *Table) uploadBlock(ctx context.Context, sink DataSink, fileName string, serialize func(io.Writer) error) (int64, error) {
+	if uploader, ok := multipartUploader(sink); ok {
+		return t.uploadMultipart(ctx, uploader, fileName, serialize)
+	}
+
+	r, w := io.Pipe()
+	var err error
+	cw := &countingWriter{w: w}
+	go func() {
+		defer w.Close()
+		err = serialize(cw)
+	}()
+	defer r.Close()
+
+	if err := sink.Upload(ctx, fileName, r); err != nil {
+		return 0, fmt.Errorf("failed to upload block %v", err)
+	}
+
+	if err != nil {
+		if deleteErr := sink.Delete(ctx, fileName); deleteErr != nil {
+			err = fmt.Errorf("%v failed to delete file on error: %w", err, deleteErr)
+		}
+		return 0, fmt.Errorf("failed to serialize block: %w", err)
+	}
+	return cw.n, nil
+}
+
+// multipartUploader returns the sink as a MultipartUploader if it supports
+// multipart uploads. A DefaultObjstoreBucket only does if it was configured
+// with StorageWithMultipartUploader.
+func multipartUploader(sink DataSink) (MultipartUploader, bool) {
+	if b, ok := sink.(*DefaultObjstoreBucket); ok {
+		return b, b.multipartUploader != nil
+	}
+	uploader, ok := sink.(MultipartUploader)
+	return uploader, ok
+}
+
+// uploadMultipart uploads the block file written by serialize as a multipart
+// upload and returns its size. Parts are uploaded in parallel while the rest
+// of the block is still being serialized.
+func (t *Table) uploadMultipart(ctx context.Context, uploader MultipartUploader, fileName string, serialize func(io.Writer) error) (int64, error) {
+	upload, err := uploader.NewMultipartUpload(ctx, fileName)
+	if err != nil {
+		return 0, fmt.Errorf("failed to start multipart upload: %w", err)
+	}
+
+	r, w := io.Pipe()
+	cw := &countingWriter{w: w}
+	go func() {
+		w.CloseWithError(serialize(cw))
+	}()
+	defer r.Close()
+
+	partSize := t.db.columnStore.uploadPartSize
+	errg, uploadCtx := errgroup.WithContext(ctx)
+	errg.SetLimit(t.db.columnStore.uploadConcurrency)
+	err = func() error {
+		for partNumber := 1; ; partNumber++ {
+			buf := make([]byte, partSize)
+			n, err := io.ReadFull(r, buf)
+			if n > 0 {
+				part, partNumber := buf[:n], partNumber
+				// Go blocks while uploadConcurrency parts are in flight,
+				// which bounds the memory used for buffered parts.
+				errg.Go(func() error {
+					if err := upload.UploadPart(uploadCtx, partNumber, bytes.NewReader(part)); err != nil {
+						return fmt.Errorf("failed to upload part %d: %w", partNumber, err)
+					}
+					return nil
+				})
+			}
+			switch {
+			case errors.Is(err, io.EOF), errors.Is(err, io.ErrUnexpectedEOF):
+				return nil
+			case err != nil:
+				return fmt.Errorf("failed to serialize block: %w", err)
+			}
+			if uploadCtx.Err() != nil {
+				// A part upload failed, stop serializing.
+				return nil
+			}
+		}
+	}()
+	if waitErr := errg.Wait(); err == nil {
+		err = waitErr
+	}
+	if err == nil {
+		err = upload.Complete(ctx)
+	}
+	if err != nil {
+		if abortErr := upload.Abort(ctx); abortErr != nil {
+			err = fmt.Errorf("%v failed to abort upload on error: %w", err, abortErr)
+		}
+		return 0, fmt.Errorf("failed to upload block: %w", err)
+	}
+	return cw.n, nil
+}
+
+// SchemaScanner is implemented by data sources that can read the schemas of
+// their blocks without reading any of their data. Unfiltered schema scans use
+// it instead of Scan.
+type SchemaScanner interface {
+	// ScanSchemas calls callback with the parquet schema of each block under
+	// prefix. Blocks with a timestamp >= lastBlockTimestamp are skipped if
+	// lastBlockTimestamp is not 0.
+	ScanSchemas(ctx context.Context, prefix string, lastBlockTimestamp uint64, callback func(context.Context, *parquet.Schema) error) error
+}
+
 // DefaultObjstoreBucket is the default implementation of the DataSource and DataSink interface.
 type DefaultObjstoreBucket struct {
 	storage.Bucket
@@ -68,6 +194,58 @@ type DefaultObjstoreBucket struct {
 	logger log.Logger
 
 	blockReaderLimit int
+
+	// blockSchemas caches the schemas of blocks read by ScanSchemas. Blocks
+	// are immutable, so cached schemas only need to be removed when blocks
+	// are deleted.
+	blockSchemasMtx      sync.Mutex
+	blockSchemas         map[string]*parquet.Schema
+	blockSchemaCacheSize int
+
+	// blockIndexes holds the loaded block index of each database if block
+	// indexes are enabled.
+	blockIndexEnabled bool
+	blockIndexesMtx   sync.Mutex
+	blockIndexes      map[string]*blockIndex
+
+	// blockStats caches the column statistics of blocks used to prune
+	// scans. A nil entry records that a block has no statistics.
+	blockStatsEnabled   bool
+	blockStatsMtx       sync.Mutex
+	blockStats          map[string]*blockStats
+	blockStatsCacheSize int
+
+	// blockMetadata caches the footers, page indexes and bloom filters of
+	// blocks.
+	blockMetadata *blockMetadataCache
+
+	// quarantine tracks the blocks that are skipped by scans because they
+	// are corrupt.
+	quarantine *blockQuarantine
+
+	// leases, if set, are the table leases acquired before the blocks of a
+	// table are uploaded or deleted.
+	leases *tableLeases
+
+	// clock provides the current time, e.g. to expire table leases. It is
+	// the clock of the column store using the bucket.
+	clock Clock
+
+	// manifests holds the loaded manifest of each table if manifests are
+	// enabled.
+	manifestsEnabled bool
+	manifestsMtx     sync.Mutex
+	manifests        map[string]*tableManifest
+
+	// committing counts the uploads of each block directory that are not
+	// committed to the manifest of their table yet. Their blocks are never
+	// deleted as orphans.
+	committingMtx sync.Mutex
+	committing    map[string]int
+
+	// multipartUploader, if set, uploads objects of the bucket as multipart
+	// uploads, see StorageWithMultipartUploader.
+	multipartUploader MultipartUploader
 }
 
 type DefaultObjstoreBucketOption func(*DefaultObjstoreBucket)
@@ -78,6 +256,25 @@ func StorageWithBlockReaderLimit(limit int) DefaultObjstoreBucketOption {
 	}
 }
 
+// StorageWithBlockSchemaCacheSize sets the number of block schemas cached for
+// schema scans. A size <= 0 disables caching.
+func StorageWithBlockSchemaCacheSize(size int) DefaultObjstoreBucketOption {
+	return func(b *DefaultObjstoreBucket) {
+		b.blockSchemaCacheSize = size
+	}
+}
+
+// StorageWithMultipartUploader sets the multipart uploader of the underlying
+// object storage, e.g. one using the multipart upload API of an S3 client. The
+// bucket then implements MultipartUploader, so that blocks are uploaded as
+// parallel part uploads. Objects uploaded this way are committed like the
+// ones uploaded with Upload once they are complete.
+func StorageWithMultipartUploader(uploader MultipartUploader) DefaultObjstoreBucketOption {
+	return func(b *DefaultObjstoreBucket) {
+		b.multipartUploader = uploader
+	}
+}
+
 func StorageWithTracer(tracer trace.Tracer) DefaultObjstoreBucketOption {
 	return func(b *DefaultObjstoreBucket) {
 		b.tracer = tracer
@@ -92,10 +289,21 @@ func StorageWithLogger(logger log.Logger) DefaultObjstoreBucketOption {
 
 func NewDefaultBucket(b storage.Bucket, options ...DefaultObjstoreBucketOption) *DefaultObjstoreBucket {
 	d := &DefaultObjstoreBucket{
-		Bucket:           b,
-		tracer:           noop.NewTracerProvider().Tracer(""),
-		logger:           log.NewNopLogger(),
-		blockReaderLimit: DefaultBlockReaderLimit,
+		Bucket:               b,
+		tracer:               noop.NewTracerProvider().Tracer(""),
+		logger:               log.NewNopLogger(),
+		blockReaderLimit:     DefaultBlockReaderLimit,
+		blockSchemas:         make(map[string]*parquet.Schema),
+		blockSchemaCacheSize: DefaultBlockSchemaCacheSize,
+		blockIndexes:         make(map[string]*blockIndex),
+		blockStatsEnabled:    true,
+		blockStats:           make(map[string]*blockStats),
+		blockStatsCacheSize:  DefaultBlockStatsCacheSize,
+		blockMetadata:        newBlockMetadataCache(DefaultBlockMetadataCacheSize),
+		quarantine:           newBlockQuarantine(),
+		manifests:            make(map[string]*tableManifest),
+		committing:           make(map[string]int),
+		clock:                systemClock{},
 	}
 
 	for _, option := range options {
@@ -107,10 +315,21 @@ func NewDefaultBucket(b storage.Bucket, options ...DefaultObjstoreBucketOption)
 
 func NewDefaultObjstoreBucket(b objstore.Bucket, options ...DefaultObjstoreBucketOption) *DefaultObjstoreBucket {
 	d := &DefaultObjstoreBucket{
-		Bucket:           storage.NewBucketReaderAt(b),
-		tracer:           noop.NewTracerProvider().Tracer(""),
-		logger:           log.NewNopLogger(),
-		blockReaderLimit: DefaultBlockReaderLimit,
+		Bucket:               storage.NewBucketReaderAt(b),
+		tracer:               noop.NewTracerProvider().Tracer(""),
+		logger:               log.NewNopLogger(),
+		blockReaderLimit:     DefaultBlockReaderLimit,
+		blockSchemas:         make(map[string]*parquet.Schema),
+		blockSchemaCacheSize: DefaultBlockSchemaCacheSize,
+		blockIndexes:         make(map[string]*blockIndex),
+		blockStatsEnabled:    true,
+		blockStats:           make(map[string]*blockStats),
+		blockStatsCacheSize:  DefaultBlockStatsCacheSize,
+		blockMetadata:        newBlockMetadataCache(DefaultBlockMetadataCacheSize),
+		quarantine:           newBlockQuarantine(),
+		manifests:            make(map[string]*tableManifest),
+		committing:           make(map[string]int),
+		clock:                systemClock{},
 	}
 
 	for _, option := range options {
@@ -124,8 +343,28 @@ func (b *DefaultObjstoreBucket) Prefixes(ctx context.Context, prefix string) ([]
 	ctx, span := b.tracer.Start(ctx, "Source/Prefixes")
 	defer span.End()
 
+	// The block index lists the tables of a database, databases are listed
+	// from the bucket.
+	if b.blockIndexEnabled && prefix != "" && !strings.Contains(filepath.Clean(prefix), "/") {
+		b.blockIndexesMtx.Lock()
+		defer b.blockIndexesMtx.Unlock()
+		index, err := b.loadBlockIndex(ctx, filepath.Clean(prefix))
+		if err != nil {
+			return nil, err
+		}
+		return index.tableNames(), nil
+	}
+
+	return b.listPrefixes(ctx, prefix)
+}
+
+// listPrefixes lists the bucket for the prefixes under prefix.
+func (b *DefaultObjstoreBucket) listPrefixes(ctx context.Context, prefix string) ([]string, error) {
 	var prefixes []string
 	err := b.Iter(ctx, prefix, func(prefix string) error {
+		if filepath.Base(prefix) == BlockIndexName || strings.HasSuffix(prefix, LeaseSuffix) || strings.HasSuffix(filepath.Clean(prefix), ManifestSuffix) {
+			return nil
+		}
 		prefixes = append(prefixes, filepath.Base(prefix))
 		return nil
 	})
@@ -140,7 +379,7 @@ func (b *DefaultObjstoreBucket) String() string {
 	return b.Bucket.Name()
 }
 
-func (b *DefaultObjstoreBucket) Scan(ctx context.Context, prefix string, _ *dynparquet.Schema, filter logicalplan.Expr, lastBlockTimestamp uint64, callback func(context.Context, any) error) error {
+func (b *DefaultObjstoreBucket) Scan(ctx context.Context, prefix string, schema *dynparquet.Schema, filter logicalplan.Expr, lastBlockTimestamp uint64, callback func(context.Context, any) error) error {
 	ctx, span := b.tracer.Start(ctx, "Source/Scan")
 	span.SetAttributes(attribute.Int64("lastBlockTimestamp", int64(lastBlockTimestamp)))
 	defer span.End()
@@ -150,12 +389,23 @@ func (b *DefaultObjstoreBucket) Scan(ctx context.Context, prefix string, _ *dynp
 		return err
 	}
 
+	// Bloom filters are only read if the table has indexed columns, since
+	// reading them requires additional requests per column chunk.
+	readBloomFilters := schema != nil && len(schema.IndexedColumns()) > 0
+
 	n := 0
 	errg := &errgroup.Group{}
 	errg.SetLimit(int(b.blockReaderLimit))
-	err = b.Iter(ctx, prefix, func(blockDir string) error {
+	// Partitions that cannot match the filter are skipped without listing
+	// their blocks.
+	mayMatch := func(p timePartition) bool {
+		return p.mayMatch(filter)
+	}
+	err = b.iterPartitionedBlocks(ctx, prefix, mayMatch, func(blockDir string) error {
 		n++
-		errg.Go(func() error { return b.ProcessFile(ctx, blockDir, lastBlockTimestamp, f, callback) })
+		errg.Go(func() error {
+			return b.processFile(ctx, blockDir, lastBlockTimestamp, f, readBloomFilters, callback)
+		})
 		return nil
 	})
 	if err != nil {
@@ -167,23 +417,31 @@ func (b *DefaultObjstoreBucket) Scan(ctx context.Context, prefix string, _ *dynp
 	return errg.Wait()
 }
 
-func (b *DefaultObjstoreBucket) openBlockFile(ctx context.Context, blockName string, size int64) (*parquet.File, error) {
+func (b *DefaultObjstoreBucket) openBlockFile(ctx context.Context, blockName string, size int64, readBloomFilters bool) (*parquet.File, error) {
 	ctx, span := b.tracer.Start(ctx, "Source/Scan/OpenFile")
 	defer span.End()
-	r, err := b.GetReaderAt(ctx, blockName)
+	reader, err := b.blockReaderAt(ctx, blockName)
 	if err != nil {
 		return nil, err
 	}
+	r := newReadErrorReaderAt(reader, size)
 
 	file, err := parquet.OpenFile(
 		r,
 		size,
 		parquet.ReadBufferSize(5*MiB), // 5MB read buffers
-		parquet.SkipBloomFilters(true),
+		parquet.SkipBloomFilters(!readBloomFilters),
 		parquet.FileReadMode(parquet.ReadModeAsync),
 	)
 	if err != nil {
-		return nil, fmt.Errorf("failed to open block: %s :%v", blockName, err)
+		return nil, blockError(blockName, r, err)
+	}
+
+	if b.quarantine.needsVerification(blockName) {
+		if err := verifyPages(blockName, r, file); err != nil {
+			return nil, err
+		}
+		b.quarantine.setVerified(blockName)
 	}
 
 	return file, nil
@@ -191,6 +449,10 @@ func (b *DefaultObjstoreBucket) openBlockFile(ctx context.Context, blockName str
 
 // ProcessFile will process a bucket block parquet file.
 func (b *DefaultObjstoreBucket) ProcessFile(ctx context.Context, blockDir string, lastBlockTimestamp uint64, filter expr.TrueNegativeFilter, callback func(context.Context, any) error) error {
+	return b.processFile(ctx, blockDir, lastBlockTimestamp, filter, false, callback)
+}
+
+func (b *DefaultObjstoreBucket) processFile(ctx context.Context, blockDir string, lastBlockTimestamp uint64, filter expr.TrueNegativeFilter, readBloomFilters bool, callback func(context.Context, any) error) error {
 	ctx, span := b.tracer.Start(ctx, "Source/Scan/ProcessFile")
 	defer span.End()
 
@@ -210,7 +472,26 @@ func (b *DefaultObjstoreBucket) ProcessFile(ctx context.Context, blockDir string
 		return nil
 	}
 
+	ok, err := b.blockMayContainUsefulData(ctx, blockDir, filter)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		level.Debug(b.logger).Log(
+			"msg", "ignoring block due to block stats",
+			"blockTime", blockUlid.Time(),
+		)
+		return nil
+	}
+
 	blockName := filepath.Join(blockDir, "data.parquet")
+	if b.quarantine.quarantined(blockName, b.clock.Now()) {
+		level.Debug(b.logger).Log(
+			"msg", "ignoring quarantined block",
+			"blockTime", blockUlid.Time(),
+		)
+		return nil
+	}
 	attribs, err := b.Attributes(ctx, blockName)
 	if err != nil {
 		return err
@@ -226,10 +507,15 @@ func (b *DefaultObjstoreBucket) ProcessFile(ctx context.Context, blockDir string
 		return nil
 	}
 
-	file, err := b.openBlockFile(ctx, blockName, attribs.Size)
+	file, err := b.openOrQuarantine(blockName, func() (*parquet.File, error) {
+		return b.openBlockFile(ctx, blockName, attribs.Size, readBloomFilters)
+	})
 	if err != nil {
 		return err
 	}
+	if file == nil {
+		return nil
+	}
 
 	// Get a reader from the file bytes
 	buf, err := dynparquet.NewSerializedBuffer(file)
@@ -237,7 +523,317 @@ func (b *DefaultObjstoreBucket) ProcessFile(ctx context.Context, blockDir string
 		return err
 	}
 
-	return b.filterRowGroups(ctx, buf, filter, callback)
+	return b.filterRowGroups(contextWithBlockID(ctx, blockUlid), buf, filter, callback)
+}
+
+// ScanSchemas implements the SchemaScanner interface. Only the footer of each
+// block is read, and the schemas are cached so that repeated schema scans do
+// not read blocks again.
+func (b *DefaultObjstoreBucket) ScanSchemas(ctx context.Context, prefix string, lastBlockTimestamp uint64, callback func(context.Context, *parquet.Schema) error) error {
+	ctx, span := b.tracer.Start(ctx, "Source/ScanSchemas")
+	span.SetAttributes(attribute.Int64("lastBlockTimestamp", int64(lastBlockTimestamp)))
+	defer span.End()
+
+	n := 0
+	errg := &errgroup.Group{}
+	errg.SetLimit(int(b.blockReaderLimit))
+	err := b.iterBlocks(ctx, prefix, func(blockDir string) error {
+		n++
+		errg.Go(func() error {
+			blockUlid, err := ulid.Parse(filepath.Base(blockDir))
+			if err != nil {
+				return err
+			}
+			if lastBlockTimestamp != 0 && blockUlid.Time() >= lastBlockTimestamp {
+				return nil
+			}
+
+			schema, err := b.blockSchema(ctx, filepath.Join(blockDir, "data.parquet"))
+			if err != nil {
+				return err
+			}
+			if schema == nil {
+				return nil
+			}
+			return callback(contextWithBlockID(ctx, blockUlid), schema)
+		})
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	span.SetAttributes(attribute.Int("blocks", n))
+	return errg.Wait()
+}
+
+// blockSchema returns the schema of the given block, reading only its footer
+// if it is not cached. It returns nil if the block is empty or quarantined.
+func (b *DefaultObjstoreBucket) blockSchema(ctx context.Context, blockName string) (*parquet.Schema, error) {
+	b.blockSchemasMtx.Lock()
+	schema, ok := b.blockSchemas[blockName]
+	b.blockSchemasMtx.Unlock()
+	if ok {
+		return schema, nil
+	}
+	if b.quarantine.quarantined(blockName, b.clock.Now()) {
+		return nil, nil
+	}
+
+	file, err := b.openOrQuarantine(blockName, func() (*parquet.File, error) {
+		return b.openBlockFooter(ctx, blockName)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if file == nil {
+		return nil, nil
+	}
+	schema = file.Schema()
+
+	b.blockSchemasMtx.Lock()
+	defer b.blockSchemasMtx.Unlock()
+	if b.blockSchemaCacheSize > 0 {
+		if len(b.blockSchemas) >= b.blockSchemaCacheSize {
+			// Evict an arbitrary block.
+			for name := range b.blockSchemas {
+				delete(b.blockSchemas, name)
+				break
+			}
+		}
+		b.blockSchemas[blockName] = schema
+	}
+	return schema, nil
+}
+
+// openBlockFooter opens the given block reading only its footer. It returns
+// nil if the block is empty.
+func (b *DefaultObjstoreBucket) openBlockFooter(ctx context.Context, blockName string) (*parquet.File, error) {
+	attribs, err := b.Attributes(ctx, blockName)
+	if err != nil {
+		return nil, err
+	}
+	if attribs.Size == 0 {
+		return nil, nil
+	}
+
+	reader, err := b.blockReaderAt(ctx, blockName)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := reader.(*blockMetadataReaderAt); !ok {
+		reader = footerReaderAt{reader}
+	}
+	r := newReadErrorReaderAt(reader, attribs.Size)
+	file, err := parquet.OpenFile(
+		r,
+		attribs.Size,
+		parquet.SkipPageIndex(true),
+		parquet.SkipBloomFilters(true),
+	)
+	if err != nil {
+		return nil, blockError(blockName, r, err)
+	}
+	return file, nil
+}
+
+// footerReaderAt serves the magic header of a parquet file without reading it,
+// so that opening a block only reads its footer. Blocks are written by frostdb,
+// so their header is known to be valid.
+type footerReaderAt struct {
+	io.ReaderAt
+}
+
+func (r footerReaderAt) ReadAt(p []byte, off int64) (int, error) {
+	if off == 0 && len(p) <= len(parquetMagic) {
+		return copy(p, parquetMagic), nil
+	}
+	return r.ReaderAt.ReadAt(p, off)
+}
+
+var parquetMagic = []byte("PAR1")
+
+// DeleteBlocks implements the BlockDeleter interface. A block is deleted if
+// the statistics of its row groups show that none of its rows match filter.
+// If manifests are enabled, deleted blocks are only superseded and kept until
+// they are collected, see CollectGarbage.
+func (b *DefaultObjstoreBucket) DeleteBlocks(ctx context.Context, prefix string, filter logicalplan.Expr) (int, error) {
+	ctx, span := b.tracer.Start(ctx, "Source/DeleteBlocks")
+	defer span.End()
+
+	var blockDirs []string
+	if err := b.iterBlocks(ctx, prefix, func(blockDir string) error {
+		blockDirs = append(blockDirs, blockDir)
+		return nil
+	}); err != nil {
+		return 0, err
+	}
+
+	n := 0
+	for _, blockDir := range blockDirs {
+		blockName := filepath.Join(blockDir, "data.parquet")
+		attribs, err := b.Attributes(ctx, blockName)
+		if err != nil {
+			if b.IsObjNotFoundErr(err) {
+				continue
+			}
+			return n, err
+		}
+		if attribs.Size == 0 {
+			continue
+		}
+
+		file, err := b.openBlockFile(ctx, blockName, attribs.Size, false)
+		if err != nil {
+			return n, err
+		}
+		buf, err := dynparquet.NewSerializedBuffer(file)
+		if err != nil {
+			return n, err
+		}
+		expired, err := rowGroupsExpired(buf, filter)
+		if err != nil {
+			return n, err
+		}
+		if !expired {
+			continue
+		}
+
+		if b.manifestsEnabled {
+			err = b.supersedeBlock(ctx, blockDir, nil)
+		} else {
+			err = b.Delete(ctx, blockName)
+		}
+		if err != nil {
+			return n, fmt.Errorf("delete block %s: %w", blockName, err)
+		}
+		b.forgetBlock(blockDir)
+		level.Debug(b.logger).Log("msg", "deleted block", "block", blockName)
+		n++
+	}
+
+	span.SetAttributes(attribute.Int("deleted", n))
+	return n, nil
+}
+
+// PurgeBlocks implements the BlockPurger interface. With manifests enabled,
+// the blocks are superseded, so that queries reading them don't fail, and
+// deleted by the garbage collection.
+func (b *DefaultObjstoreBucket) PurgeBlocks(ctx context.Context, prefix string) (int, error) {
+	ctx, span := b.tracer.Start(ctx, "Source/PurgeBlocks")
+	defer span.End()
+
+	var blockDirs []string
+	if err := b.iterBlocks(ctx, prefix, func(blockDir string) error {
+		blockDirs = append(blockDirs, blockDir)
+		return nil
+	}); err != nil {
+		return 0, err
+	}
+
+	n := 0
+	for _, blockDir := range blockDirs {
+		var err error
+		if b.manifestsEnabled {
+			err = b.supersedeBlock(ctx, blockDir, nil)
+		} else {
+			err = b.Delete(ctx, filepath.Join(blockDir, "data.parquet"))
+		}
+		if err != nil {
+			return n, fmt.Errorf("purge block %s: %w", blockDir, err)
+		}
+		b.forgetBlock(blockDir)
+		n++
+	}
+
+	span.SetAttributes(attribute.Int("purged", n))
+	return n, nil
+}
+
+// RewriteBlocks implements the BlockRewriter interface. Blocks are replaced in
+// place, so queries reading a block while it is rewritten may fail. If
+// manifests are enabled, rewritten blocks are written as new blocks instead
+// and the replaced blocks are kept until they are collected, see
+// CollectGarbage.
+func (b *DefaultObjstoreBucket) RewriteBlocks(ctx context.Context, prefix string, rewrite BlockRewriteFunc) (int, error) {
+	ctx, span := b.tracer.Start(ctx, "Source/RewriteBlocks")
+	defer span.End()
+
+	var blockDirs []string
+	if err := b.iterBlocks(ctx, prefix, func(blockDir string) error {
+		blockDirs = append(blockDirs, blockDir)
+		return nil
+	}); err != nil {
+		return 0, err
+	}
+
+	n := 0
+	for _, blockDir := range blockDirs {
+		id, err := ulid.Parse(filepath.Base(blockDir))
+		if err != nil {
+			return n, err
+		}
+		blockName := filepath.Join(blockDir, "data.parquet")
+		attribs, err := b.Attributes(ctx, blockName)
+		if err != nil {
+			if b.IsObjNotFoundErr(err) {
+				continue
+			}
+			return n, err
+		}
+		if attribs.Size == 0 {
+			continue
+		}
+
+		file, err := b.openBlockFile(ctx, blockName, attribs.Size, false)
+		if err != nil {
+			return n, err
+		}
+		buf, err := dynparquet.NewSerializedBuffer(file)
+		if err != nil {
+			return n, err
+		}
+		var rewritten bytes.Buffer
+		ok, err := rewrite(ctx, id, buf, &rewritten)
+		if err != nil {
+			return n, fmt.Errorf("rewrite block %s: %w", blockName, err)
+		}
+		if !ok {
+			continue
+		}
+
+		switch {
+		case b.manifestsEnabled && rewritten.Len() == 0:
+			err = b.supersedeBlock(ctx, blockDir, nil)
+		case b.manifestsEnabled:
+			err = b.supersedeBlock(ctx, blockDir, &rewritten)
+		case rewritten.Len() == 0:
+			err = b.Delete(ctx, blockName)
+		default:
+			err = b.Upload(ctx, blockName, &rewritten)
+		}
+		if err != nil {
+			return n, fmt.Errorf("replace block %s: %w", blockName, err)
+		}
+		b.forgetBlock(blockDir)
+		level.Debug(b.logger).Log("msg", "rewrote block", "block", blockName)
+		n++
+	}
+
+	span.SetAttributes(attribute.Int("rewritten", n))
+	return n, nil
+}
+
+// forgetBlock removes the cached schema and metadata of the given block after
+// it was deleted or replaced.
+func (b *DefaultObjstoreBucket) forgetBlock(blockDir string) {
+	b.blockSchemasMtx.Lock()
+	delete(b.blockSchemas, filepath.Join(blockDir, "data.parquet"))
+	b.blockSchemasMtx.Unlock()
+	b.quarantine.forget(filepath.Join(blockDir, "data.parquet"))
+	if id, err := ulid.Parse(filepath.Base(blockDir)); err == nil {
+		b.blockMetadata.remove(id)
+	}
 }
 
 func (b *DefaultObjstoreBucket) filterRowGroups(ctx context.Context, buf *dynparquet.SerializedBuffer, filter expr.TrueNegativeFilter, callback func(context.Context, any) error) error {
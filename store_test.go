@@ -0,0 +1,830 @@
+package frostdb
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/apache/arrow/go/v17/arrow"
+	"github.com/apache/arrow/go/v17/arrow/array"
+	"github.com/apache/arrow/go/v17/arrow/memory"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+	"github.com/thanos-io/objstore"
+
+	"github.com/polarsignals/frostdb/dynparquet"
+	"github.com/polarsignals/frostdb/query"
+	"github.com/polarsignals/frostdb/query/logicalplan"
+)
+
+// testMultipartUploader uploads objects to a bucket as multipart uploads by
+// buffering parts in memory and uploading the assembled object on completion.
+type testMultipartUploader struct {
+	bucket objstore.Bucket
+
+	// failPart, if non-zero, is the part number for which UploadPart fails.
+	failPart int
+
+	mtx      sync.Mutex
+	parts    int
+	aborted  int
+	inFlight int
+	// maxInFlight is the maximum number of concurrent UploadPart calls.
+	maxInFlight int
+}
+
+func (u *testMultipartUploader) NewMultipartUpload(_ context.Context, name string) (MultipartUpload, error) {
+	return &testMultipartUpload{uploader: u, name: name, parts: map[int][]byte{}}, nil
+}
+
+type testMultipartUpload struct {
+	uploader *testMultipartUploader
+	name     string
+
+	mtx   sync.Mutex
+	parts map[int][]byte
+}
+
+func (u *testMultipartUpload) UploadPart(_ context.Context, partNumber int, r io.Reader) error {
+	u.uploader.mtx.Lock()
+	u.uploader.inFlight++
+	u.uploader.maxInFlight = max(u.uploader.maxInFlight, u.uploader.inFlight)
+	u.uploader.mtx.Unlock()
+	defer func() {
+		u.uploader.mtx.Lock()
+		u.uploader.inFlight--
+		u.uploader.mtx.Unlock()
+	}()
+
+	if partNumber == u.uploader.failPart {
+		return errors.New("upload failed")
+	}
+	b, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+
+	u.mtx.Lock()
+	defer u.mtx.Unlock()
+	u.parts[partNumber] = b
+	return nil
+}
+
+func (u *testMultipartUpload) Complete(ctx context.Context) error {
+	u.mtx.Lock()
+	defer u.mtx.Unlock()
+
+	numbers := make([]int, 0, len(u.parts))
+	for n := range u.parts {
+		numbers = append(numbers, n)
+	}
+	sort.Ints(numbers)
+	var buf bytes.Buffer
+	for i, n := range numbers {
+		if n != i+1 {
+			return fmt.Errorf("missing part %d", i+1)
+		}
+		buf.Write(u.parts[n])
+	}
+
+	u.uploader.mtx.Lock()
+	u.uploader.parts += len(numbers)
+	u.uploader.mtx.Unlock()
+	return u.uploader.bucket.Upload(ctx, u.name, &buf)
+}
+
+func (u *testMultipartUpload) Abort(_ context.Context) error {
+	u.uploader.mtx.Lock()
+	defer u.uploader.mtx.Unlock()
+	u.uploader.aborted++
+	return nil
+}
+
+func TestMultipartPersist(t *testing.T) {
+	samples := dynparquet.GenerateTestSamples(1000)
+
+	newBucket := func(uploader *testMultipartUploader) *DefaultObjstoreBucket {
+		return NewDefaultObjstoreBucket(
+			uploader.bucket,
+			StorageWithMultipartUploader(uploader),
+			StorageWithBlockIndex(true),
+			StorageWithManifests(true),
+		)
+	}
+	newStore := func(t *testing.T, bucket *DefaultObjstoreBucket, options ...TableOption) (*ColumnStore, *Table) {
+		c, _, table := openTestTable(t, []Option{WithReadWriteStorage(bucket), WithMultipartUpload(1024, 2)}, options...)
+		insertSamples(t, table, samples)
+		return c, table
+	}
+	// countPersistedRows counts the rows of the table read from the bucket
+	// by a new column store.
+	countPersistedRows := func(t *testing.T, bucket *DefaultObjstoreBucket, options ...TableOption) int64 {
+		c, db, _ := openTestTable(t, []Option{WithReadWriteStorage(bucket)}, options...)
+		defer c.Close()
+		return countRows(t, db, "test")
+	}
+
+	t.Run("Parallel", func(t *testing.T) {
+		uploader := &testMultipartUploader{bucket: objstore.NewInMemBucket()}
+		bucket := newBucket(uploader)
+		c, _ := newStore(t, bucket)
+		// Closing the column store persists the active block.
+		require.NoError(t, c.Close())
+
+		require.Greater(t, uploader.parts, 1)
+		require.LessOrEqual(t, uploader.maxInFlight, 2)
+		require.Zero(t, uploader.aborted)
+
+		// The assembled block is committed like uploaded blocks, so it is
+		// read through the manifest of the table by a new bucket.
+		require.Equal(t, int64(len(samples)), countPersistedRows(t, newBucket(&testMultipartUploader{bucket: uploader.bucket})))
+	})
+
+	t.Run("Partitioned", func(t *testing.T) {
+		uploader := &testMultipartUploader{bucket: objstore.NewInMemBucket()}
+		partitioning := WithTimePartitioning(time.Hour)
+		c, _ := newStore(t, newBucket(uploader), partitioning)
+		require.NoError(t, c.Close())
+
+		require.Greater(t, uploader.parts, 1)
+		require.Equal(t, int64(len(samples)), countPersistedRows(t, newBucket(&testMultipartUploader{bucket: uploader.bucket}), partitioning))
+	})
+
+	t.Run("PartFailure", func(t *testing.T) {
+		uploader := &testMultipartUploader{bucket: objstore.NewInMemBucket(), failPart: 2}
+		bucket := newBucket(uploader)
+		c, table := newStore(t, bucket)
+		defer c.Close()
+
+		require.Error(t, table.ActiveBlock().Persist())
+		require.Equal(t, 1, uploader.aborted)
+		require.Zero(t, uploader.parts)
+		for name := range uploader.bucket.(*objstore.InMemBucket).Objects() {
+			// Only the empty block index of the table has been written.
+			require.True(t, strings.HasSuffix(name, BlockIndexName), name)
+		}
+	})
+}
+
+func TestScanSchemasFooterOnly(t *testing.T) {
+	ctx := context.Background()
+	bucket := &rangeCountingBucket{Bucket: objstore.NewInMemBucket()}
+
+	c, _, table := openTestTable(t, []Option{WithReadWriteStorage(NewDefaultObjstoreBucket(bucket))})
+	insertSamples(t, table, dynparquet.GenerateTestSamples(1000))
+	persistActiveBlock(t, table)
+	require.NoError(t, c.Close())
+
+	var blockSize int64
+	require.NoError(t, bucket.Iter(ctx, "", func(name string) error {
+		attrs, err := bucket.Attributes(ctx, name)
+		require.NoError(t, err)
+		blockSize += attrs.Size
+		return nil
+	}, objstore.WithRecursiveIter))
+	require.NotZero(t, blockSize)
+
+	// Uploading the block cached its footer, reopen the store with an empty
+	// cache. Opening the table reads the footers of its blocks.
+	bucket.readBytes = 0
+	c, db, _ := openTestTable(t, []Option{WithReadWriteStorage(NewDefaultObjstoreBucket(bucket))})
+	defer c.Close()
+
+	scanSchema := func() []string {
+		var names []string
+		require.NoError(t, query.NewEngine(memory.DefaultAllocator, db.TableProvider()).
+			ScanSchema("test").
+			Execute(ctx, func(_ context.Context, r arrow.Record) error {
+				col := r.Column(0).(*array.String)
+				for i := 0; i < col.Len(); i++ {
+					names = append(names, col.Value(i))
+				}
+				return nil
+			}))
+		sort.Strings(names)
+		return names
+	}
+
+	names := scanSchema()
+	require.Contains(t, names, "labels.node")
+	require.Contains(t, names, "timestamp")
+	// Only the footer of the block was read.
+	require.Greater(t, bucket.readBytes, int64(0))
+	require.Less(t, bucket.readBytes, blockSize/2)
+
+	// The schema is cached.
+	bucket.readBytes = 0
+	require.Equal(t, names, scanSchema())
+	require.Zero(t, bucket.readBytes)
+}
+
+func TestBlockStatsPruning(t *testing.T) {
+	ctx := context.Background()
+	bucket := &rangeCountingBucket{Bucket: objstore.NewInMemBucket()}
+	options := []Option{WithReadWriteStorage(NewDefaultObjstoreBucket(bucket))}
+
+	c, _, table := openTestTable(t, options)
+	insertSamples(t, table, dynparquet.GenerateTestSamples(1000))
+	persistActiveBlock(t, table)
+	require.NoError(t, c.Close())
+
+	var statsObjects int
+	require.NoError(t, bucket.Iter(ctx, "", func(name string) error {
+		if filepath.Base(name) == BlockStatsName {
+			statsObjects++
+		}
+		return nil
+	}, objstore.WithRecursiveIter))
+	require.Equal(t, 1, statsObjects)
+
+	c, db, _ := openTestTable(t, options)
+	defer c.Close()
+
+	// The block is skipped without being read.
+	bucket.readBytes = 0
+	require.Zero(t, countMatchingRows(t, db, "test", logicalplan.Col("value").Gt(logicalplan.Literal(int64(1000)))))
+	require.Zero(t, bucket.readBytes)
+	require.Zero(t, countMatchingRows(t, db, "test", logicalplan.Col("labels.node").Eq(logicalplan.Literal("test4"))))
+	require.Zero(t, bucket.readBytes)
+
+	require.Equal(t, int64(10), countMatchingRows(t, db, "test", logicalplan.Col("value").GtEq(logicalplan.Literal(int64(990)))))
+	require.Greater(t, bucket.readBytes, int64(0))
+}
+
+func TestBlockSchemaCache(t *testing.T) {
+	ctx := context.Background()
+	options := []Option{WithReadWriteStorage(NewDefaultObjstoreBucket(objstore.NewInMemBucket()))}
+
+	c, _, table := openTestTable(t, options)
+	insertSamples(t, table, dynparquet.GenerateTestSamples(10))
+	persistActiveBlock(t, table)
+	require.NoError(t, c.Close())
+
+	c, db, table := openTestTable(t, options)
+	defer c.Close()
+	count := func(t *testing.T) int64 {
+		t.Helper()
+		var rows int64
+		require.NoError(t, query.NewEngine(memory.DefaultAllocator, db.TableProvider()).
+			ScanTable("test").
+			Execute(ctx, func(_ context.Context, r arrow.Record) error {
+				require.True(t, r.Schema().HasField("labels.node"))
+				rows += r.NumRows()
+				return nil
+			}))
+		return rows
+	}
+
+	require.Zero(t, db.blockSchemas.len())
+	require.Equal(t, int64(10), count(t))
+	require.Equal(t, 1, db.blockSchemas.len())
+	// The cached schema is used by subsequent queries.
+	require.Equal(t, int64(10), count(t))
+	require.Equal(t, 1, db.blockSchemas.len())
+
+	// Rewriting blocks invalidates their schemas.
+	require.NoError(t, table.Delete(ctx, logicalplan.Col("value").Lt(logicalplan.Literal(int64(5)))))
+	require.NoError(t, table.CompactTombstones(ctx))
+	require.Zero(t, db.blockSchemas.len())
+	require.Equal(t, int64(5), count(t))
+	require.Equal(t, 1, db.blockSchemas.len())
+}
+
+func TestBlockBloomFilterPruning(t *testing.T) {
+	bucket := &rangeCountingBucket{Bucket: objstore.NewInMemBucket()}
+	options := []Option{WithReadWriteStorage(NewDefaultObjstoreBucket(bucket))}
+
+	c, _, table := openTestTable(t, options)
+	samples := dynparquet.GenerateTestSamples(1000)
+	for i := range samples {
+		// The values of the first sorting column span "b", but don't
+		// contain it.
+		samples[i].ExampleType = []string{"a", "c"}[i%2]
+	}
+	insertSamples(t, table, samples)
+	persistActiveBlock(t, table)
+	require.NoError(t, c.Close())
+
+	c, db, _ := openTestTable(t, options)
+	defer c.Close()
+
+	// The block is skipped by its bloom filter without being read.
+	bucket.readBytes = 0
+	require.Zero(t, countMatchingRows(t, db, "test", logicalplan.Col("example_type").Eq(logicalplan.Literal("b"))))
+	require.Zero(t, bucket.readBytes)
+
+	require.Equal(t, int64(500), countMatchingRows(t, db, "test", logicalplan.Col("example_type").Eq(logicalplan.Literal("c"))))
+	require.Greater(t, bucket.readBytes, int64(0))
+}
+
+// iterCountingBucket counts the number of times the bucket is listed.
+type iterCountingBucket struct {
+	objstore.Bucket
+
+	mtx   sync.Mutex
+	iters int
+}
+
+func (b *iterCountingBucket) Iter(ctx context.Context, dir string, f func(string) error, options ...objstore.IterOption) error {
+	b.mtx.Lock()
+	b.iters++
+	b.mtx.Unlock()
+	return b.Bucket.Iter(ctx, dir, f, options...)
+}
+
+func TestBlockIndex(t *testing.T) {
+	ctx := context.Background()
+	bucket := &iterCountingBucket{Bucket: objstore.NewInMemBucket()}
+	samples := dynparquet.GenerateTestSamples(100)
+
+	openDB := func() (*ColumnStore, *DB) {
+		c, err := New(
+			WithLogger(newTestLogger(t)),
+			WithReadWriteStorage(NewDefaultObjstoreBucket(bucket, StorageWithBlockIndex(true))),
+		)
+		require.NoError(t, err)
+		db, err := c.DB(ctx, "test")
+		require.NoError(t, err)
+		return c, db
+	}
+	countRows := func(db *DB) int64 {
+		_, err := db.Table("test", NewTableConfig(dynparquet.SampleDefinition()))
+		require.NoError(t, err)
+		rows := int64(0)
+		require.NoError(t, query.NewEngine(memory.DefaultAllocator, db.TableProvider()).
+			ScanTable("test").
+			Execute(ctx, func(_ context.Context, r arrow.Record) error {
+				rows += r.NumRows()
+				return nil
+			}))
+		return rows
+	}
+
+	// Persist two blocks.
+	for i := 0; i < 2; i++ {
+		c, db := openDB()
+		table, err := db.Table("test", NewTableConfig(dynparquet.SampleDefinition()))
+		require.NoError(t, err)
+		r, err := samples.ToRecord()
+		require.NoError(t, err)
+		_, err = table.InsertRecord(ctx, r)
+		r.Release()
+		require.NoError(t, err)
+		// Closing the column store persists the active block.
+		require.NoError(t, c.Close())
+	}
+
+	exists, err := bucket.Exists(ctx, "test/"+BlockIndexName)
+	require.NoError(t, err)
+	require.True(t, exists)
+
+	// Opening the database and scanning the table uses the index instead of
+	// listing the bucket.
+	bucket.iters = 0
+	c, db := openDB()
+	require.Equal(t, int64(2*len(samples)), countRows(db))
+	require.Zero(t, bucket.iters)
+	require.NoError(t, c.Close())
+
+	// A missing index is rebuilt by listing the bucket.
+	require.NoError(t, bucket.Delete(ctx, "test/"+BlockIndexName))
+	bucket.iters = 0
+	c, db = openDB()
+	require.Equal(t, int64(2*len(samples)), countRows(db))
+	require.NotZero(t, bucket.iters)
+	require.NoError(t, c.Close())
+
+	exists, err = bucket.Exists(ctx, "test/"+BlockIndexName)
+	require.NoError(t, err)
+	require.True(t, exists)
+	bucket.iters = 0
+	c, db = openDB()
+	defer c.Close()
+	require.Equal(t, int64(2*len(samples)), countRows(db))
+	require.Zero(t, bucket.iters)
+}
+
+func TestBlockQuarantine(t *testing.T) {
+	ctx := context.Background()
+	clock := NewManualClock(time.Now())
+	objects := objstore.NewInMemBucket()
+	var (
+		mtx     sync.Mutex
+		corrupt []CorruptBlock
+	)
+	sinksource := NewDefaultObjstoreBucket(objects,
+		StorageWithBlockQuarantineDuration(time.Minute),
+		StorageWithBlockVerification(),
+		StorageWithCorruptBlockHandler(func(block CorruptBlock) {
+			mtx.Lock()
+			defer mtx.Unlock()
+			corrupt = append(corrupt, block)
+		}),
+	)
+	options := []Option{
+		WithReadWriteStorage(sinksource),
+		WithClock(clock),
+	}
+
+	// Persist two blocks.
+	for i := 0; i < 2; i++ {
+		c, _, table := openTestTable(t, options)
+		insertSamples(t, table, dynparquet.GenerateTestSamples(10))
+		persistActiveBlock(t, table)
+		require.NoError(t, c.Close())
+		// Blocks persisted at the same time would have the same ID.
+		clock.Advance(time.Millisecond)
+	}
+
+	var blocks []string
+	require.NoError(t, objects.Iter(ctx, "", func(name string) error {
+		if filepath.Base(name) == "data.parquet" {
+			blocks = append(blocks, name)
+		}
+		return nil
+	}, objstore.WithRecursiveIter))
+	require.Len(t, blocks, 2)
+	r, err := objects.Get(ctx, blocks[0])
+	require.NoError(t, err)
+	intact, err := io.ReadAll(r)
+	require.NoError(t, err)
+	// The length of the footer exceeds the size of the block.
+	garbage := append(append([]byte("PAR1"), bytes.Repeat([]byte{0xff}, 64)...), "PAR1"...)
+	require.NoError(t, objects.Upload(ctx, blocks[0], bytes.NewReader(garbage)))
+
+	c, db, _ := openTestTable(t, options)
+	defer c.Close()
+
+	// The corrupt block is skipped.
+	require.Equal(t, int64(10), countRows(t, db, "test"))
+	require.Equal(t, []string{blocks[0]}, sinksource.QuarantinedBlocks())
+	mtx.Lock()
+	require.Len(t, corrupt, 1)
+	require.Equal(t, blocks[0], corrupt[0].Block)
+	require.Equal(t, clock.Now().Add(time.Minute), corrupt[0].Until)
+	mtx.Unlock()
+
+	// Once the block is repaired, it is read again after its quarantine.
+	require.NoError(t, objects.Upload(ctx, blocks[0], bytes.NewReader(intact)))
+	clock.Advance(time.Minute - time.Millisecond)
+	require.Equal(t, int64(10), countRows(t, db, "test"))
+	require.Equal(t, []string{blocks[0]}, sinksource.QuarantinedBlocks())
+	clock.Advance(time.Millisecond)
+	require.Empty(t, sinksource.QuarantinedBlocks())
+	require.Equal(t, int64(20), countRows(t, db, "test"))
+}
+
+func TestBucketLeases(t *testing.T) {
+	ctx := context.Background()
+	bucket := objstore.NewInMemBucket()
+	clock := NewManualClock(time.Now())
+	a := NewDefaultObjstoreBucket(bucket, StorageWithBlockStats(false), StorageWithLeases("a", time.Minute))
+	a.clock = clock
+	b := NewDefaultObjstoreBucket(bucket, StorageWithBlockStats(false), StorageWithLeases("b", 50*time.Millisecond))
+	b.clock = clock
+
+	upload := func(bucket *DefaultObjstoreBucket, table string) error {
+		return bucket.Upload(ctx, filepath.Join("db", table, generateULIDAt(clock.Now()).String(), "data.parquet"), bytes.NewReader(nil))
+	}
+
+	require.NoError(t, upload(a, "table1"))
+	var held *LeaseHeldError
+	require.ErrorAs(t, upload(b, "table1"), &held)
+	require.Equal(t, "db/table1", held.Table)
+	require.Equal(t, "a", held.Owner)
+	require.NoError(t, upload(b, "table2"))
+
+	// Leases are not listed as tables.
+	tables, err := a.Prefixes(ctx, "db")
+	require.NoError(t, err)
+	require.ElementsMatch(t, []string{"table1", "table2"}, tables)
+
+	// A released lease can be taken over.
+	require.NoError(t, a.ReleaseLeases(ctx))
+	require.NoError(t, upload(b, "table1"))
+	require.ErrorAs(t, upload(a, "table1"), &held)
+	require.Equal(t, "b", held.Owner)
+
+	// An expired lease can be taken over.
+	clock.Advance(100 * time.Millisecond)
+	require.NoError(t, upload(a, "table2"))
+	require.ErrorAs(t, b.Delete(ctx, filepath.Join("db", "table2", generateULIDAt(clock.Now()).String(), "data.parquet")), &held)
+
+	// A lease taken over by a writer whose clock is ahead is noticed before
+	// the next upload, even though it has not expired for its holder.
+	c := NewDefaultObjstoreBucket(bucket, StorageWithBlockStats(false), StorageWithLeases("c", time.Minute))
+	c.clock = NewManualClock(clock.Now().Add(2 * time.Minute))
+	require.NoError(t, upload(c, "table2"))
+	require.ErrorAs(t, upload(a, "table2"), &held)
+	require.Equal(t, "c", held.Owner)
+}
+
+// versionedBucket is an in-memory bucket supporting conditional writes.
+type versionedBucket struct {
+	objstore.Bucket
+
+	mtx      sync.Mutex
+	versions map[string]int
+	// beforeUpload is called with the name of the object before a
+	// conditional upload is attempted.
+	beforeUpload func(name string)
+}
+
+func newVersionedBucket() *versionedBucket {
+	return &versionedBucket{
+		Bucket:   objstore.NewInMemBucket(),
+		versions: map[string]int{},
+	}
+}
+
+func (b *versionedBucket) Upload(ctx context.Context, name string, r io.Reader) error {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+	b.versions[name]++
+	return b.Bucket.Upload(ctx, name, r)
+}
+
+func (b *versionedBucket) GetWithVersion(ctx context.Context, name string) (io.ReadCloser, string, error) {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+	r, err := b.Bucket.Get(ctx, name)
+	if err != nil {
+		return nil, "", err
+	}
+	return r, strconv.Itoa(b.versions[name]), nil
+}
+
+func (b *versionedBucket) UploadIfVersion(ctx context.Context, name string, r io.Reader, version string) error {
+	if b.beforeUpload != nil {
+		b.beforeUpload(name)
+	}
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+	current := ""
+	if _, ok := b.versions[name]; ok {
+		current = strconv.Itoa(b.versions[name])
+	}
+	if current != version {
+		return ErrPreconditionFailed
+	}
+	b.versions[name]++
+	return b.Bucket.Upload(ctx, name, r)
+}
+
+func TestBucketLeasesConditional(t *testing.T) {
+	ctx := context.Background()
+	bucket := newVersionedBucket()
+	a := NewDefaultObjstoreBucket(bucket, StorageWithBlockStats(false), StorageWithLeases("a", time.Minute))
+	upload := func(table string) error {
+		return a.Upload(ctx, filepath.Join("db", table, generateULID(systemClock{}).String(), "data.parquet"), bytes.NewReader(nil))
+	}
+	require.NoError(t, upload("table1"))
+
+	// A lease written concurrently by another writer is not overwritten.
+	bucket.beforeUpload = func(string) {
+		require.NoError(t, bucket.Upload(ctx, leaseName("db/table2"), strings.NewReader(`{"owner":"b","token":1,"expires":"2100-01-01T00:00:00Z"}`)))
+	}
+	var held *LeaseHeldError
+	require.ErrorAs(t, upload("table2"), &held)
+	require.Equal(t, "b", held.Owner)
+}
+
+func TestManifests(t *testing.T) {
+	ctx := context.Background()
+	bucket := objstore.NewInMemBucket()
+	sinksource := NewDefaultObjstoreBucket(bucket, StorageWithManifests(true))
+	samples := dynparquet.GenerateTestSamples(100)
+
+	openDB := func() (*ColumnStore, *DB) {
+		c, err := New(
+			WithLogger(newTestLogger(t)),
+			WithReadWriteStorage(sinksource),
+		)
+		require.NoError(t, err)
+		db, err := c.DB(ctx, "test")
+		require.NoError(t, err)
+		return c, db
+	}
+	countRows := func(db *DB) int64 {
+		rows := int64(0)
+		require.NoError(t, query.NewEngine(memory.DefaultAllocator, db.TableProvider()).
+			ScanTable("test").
+			Execute(ctx, func(_ context.Context, r arrow.Record) error {
+				rows += r.NumRows()
+				return nil
+			}))
+		return rows
+	}
+
+	// Persist three blocks, each committing a new manifest version.
+	for i := 0; i < 3; i++ {
+		c, db := openDB()
+		table, err := db.Table("test", NewTableConfig(dynparquet.SampleDefinition()))
+		require.NoError(t, err)
+		r, err := samples.ToRecord()
+		require.NoError(t, err)
+		_, err = table.InsertRecord(ctx, r)
+		r.Release()
+		require.NoError(t, err)
+		require.NoError(t, c.Close())
+	}
+	var versions []string
+	require.NoError(t, bucket.Iter(ctx, "test/test"+ManifestSuffix, func(name string) error {
+		versions = append(versions, filepath.Base(name))
+		return nil
+	}))
+	require.Equal(t, []string{"00000000000000000002", "00000000000000000003"}, versions)
+
+	// A block that was only partially uploaded is not committed, so scans
+	// don't read it.
+	orphan := filepath.Join("test", "test", generateULIDAt(time.Now().Add(-time.Hour)).String())
+	require.NoError(t, bucket.Upload(ctx, filepath.Join(orphan, "data.parquet"), bytes.NewReader([]byte("partial"))))
+	c, db := openDB()
+	require.Equal(t, int64(3*len(samples)), countRows(db))
+	require.NoError(t, c.Close())
+
+	tables, err := sinksource.Prefixes(ctx, "test")
+	require.NoError(t, err)
+	require.Equal(t, []string{"test"}, tables)
+
+	// Recently uploaded blocks may still be uploading and are kept, even if
+	// their IDs are older.
+	clock := NewManualClock(time.Now())
+	sinksource.clock = clock
+	n, err := sinksource.DeleteOrphanedBlocks(ctx, "test/test", time.Minute)
+	require.NoError(t, err)
+	require.Zero(t, n)
+	clock.Advance(time.Hour)
+	n, err = sinksource.DeleteOrphanedBlocks(ctx, "test/test", 2*time.Hour)
+	require.NoError(t, err)
+	require.Zero(t, n)
+	n, err = sinksource.DeleteOrphanedBlocks(ctx, "test/test", time.Minute)
+	require.NoError(t, err)
+	require.Equal(t, 1, n)
+	exists, err := bucket.Exists(ctx, filepath.Join(orphan, "data.parquet"))
+	require.NoError(t, err)
+	require.False(t, exists)
+
+	// Blocks whose commit to the manifest is still running are kept,
+	// however long they took to upload.
+	uploading := filepath.Join("test", "test", generateULIDAt(clock.Now()).String(), "data.parquet")
+	committed := sinksource.startCommit(uploading)
+	require.NoError(t, bucket.Upload(ctx, uploading, bytes.NewReader([]byte("partial"))))
+	clock.Advance(time.Hour)
+	n, err = sinksource.DeleteOrphanedBlocks(ctx, "test/test", time.Minute)
+	require.NoError(t, err)
+	require.Zero(t, n)
+	committed()
+	n, err = sinksource.DeleteOrphanedBlocks(ctx, "test/test", time.Minute)
+	require.NoError(t, err)
+	require.Equal(t, 1, n)
+
+	c, db = openDB()
+	defer c.Close()
+	require.Equal(t, int64(3*len(samples)), countRows(db))
+}
+
+func TestBlockGC(t *testing.T) {
+	ctx := context.Background()
+	bucket := objstore.NewInMemBucket()
+	sinksource := NewDefaultObjstoreBucket(bucket, StorageWithManifests(true))
+
+	c, err := New(
+		WithLogger(newTestLogger(t)),
+		WithReadWriteStorage(sinksource),
+		WithBlockGC(0, 0),
+	)
+	require.NoError(t, err)
+	defer c.Close()
+	db, err := c.DB(ctx, "test")
+	require.NoError(t, err)
+	table, err := db.Table("test", NewTableConfig(dynparquet.SampleDefinition()))
+	require.NoError(t, err)
+	r, err := dynparquet.GenerateTestSamples(100).ToRecord()
+	require.NoError(t, err)
+	_, err = table.InsertRecord(ctx, r)
+	r.Release()
+	require.NoError(t, err)
+	var wg sync.WaitGroup
+	wg.Add(1)
+	require.NoError(t, table.RotateBlock(ctx, table.ActiveBlock(), WithRotateBlockWaitGroup(&wg)))
+	wg.Wait()
+
+	var blockDirs []string
+	require.NoError(t, sinksource.iterBlocks(ctx, "test/test", func(blockDir string) error {
+		blockDirs = append(blockDirs, blockDir)
+		return nil
+	}))
+	require.Len(t, blockDirs, 1)
+	blockName := filepath.Join(blockDirs[0], "data.parquet")
+
+	// Deleting the block only supersedes it, so queries reading it don't
+	// fail.
+	n, err := sinksource.DeleteBlocks(ctx, "test/test", logicalplan.Col("timestamp").GtEq(logicalplan.Literal(int64(1000))))
+	require.NoError(t, err)
+	require.Equal(t, 1, n)
+	require.NoError(t, sinksource.iterBlocks(ctx, "test/test", func(blockDir string) error {
+		return fmt.Errorf("unexpected block %s", blockDir)
+	}))
+	exists, err := bucket.Exists(ctx, blockName)
+	require.NoError(t, err)
+	require.True(t, exists)
+
+	attrs, err := bucket.Attributes(ctx, blockName)
+	require.NoError(t, err)
+	require.NoError(t, table.CollectGarbage(ctx))
+	exists, err = bucket.Exists(ctx, blockName)
+	require.NoError(t, err)
+	require.False(t, exists)
+	require.Equal(t, float64(1), testutil.ToFloat64(table.metrics.blocksCollected))
+	require.GreaterOrEqual(t, testutil.ToFloat64(table.metrics.bytesReclaimed), float64(attrs.Size))
+
+	// Collected blocks are removed from the manifest.
+	sinksource.manifestsMtx.Lock()
+	manifest, err := sinksource.loadManifest(ctx, "test/test")
+	sinksource.manifestsMtx.Unlock()
+	require.NoError(t, err)
+	require.Empty(t, manifest.blocks)
+	require.Empty(t, manifest.superseded)
+}
+
+func TestBlockGCLeases(t *testing.T) {
+	ctx := context.Background()
+	bucket := newVersionedBucket()
+	clock := NewManualClock(time.Now())
+	newBucket := func(owner string) *DefaultObjstoreBucket {
+		b := NewDefaultObjstoreBucket(bucket,
+			StorageWithBlockStats(false),
+			StorageWithManifests(true),
+			StorageWithLeases(owner, time.Minute),
+		)
+		b.clock = clock
+		return b
+	}
+	a := newBucket("a")
+	b := newBucket("b")
+	loadManifest := func() *tableManifest {
+		c := newBucket("c")
+		c.manifestsMtx.Lock()
+		defer c.manifestsMtx.Unlock()
+		manifest, err := c.loadManifest(ctx, "db/table")
+		require.NoError(t, err)
+		return manifest
+	}
+
+	blockName := filepath.Join("db", "table", generateULIDAt(clock.Now()).String(), "data.parquet")
+	require.NoError(t, a.Upload(ctx, blockName, bytes.NewReader(nil)))
+	n, err := a.PurgeBlocks(ctx, "db/table")
+	require.NoError(t, err)
+	require.Equal(t, 1, n)
+	version := loadManifest().version
+
+	// The garbage of the table is only collected by the writer holding its
+	// lease, even once the lease expired.
+	clock.Advance(time.Hour)
+	stats, err := b.CollectGarbage(ctx, "db/table", time.Minute)
+	require.NoError(t, err)
+	require.Zero(t, stats.Blocks)
+	exists, err := bucket.Exists(ctx, blockName)
+	require.NoError(t, err)
+	require.True(t, exists)
+	require.Equal(t, version, loadManifest().version)
+
+	stats, err = a.CollectGarbage(ctx, "db/table", time.Minute)
+	require.NoError(t, err)
+	require.Equal(t, 1, stats.Blocks)
+	exists, err = bucket.Exists(ctx, blockName)
+	require.NoError(t, err)
+	require.False(t, exists)
+
+	// A manifest version committed concurrently by another writer is not
+	// overwritten.
+	version = loadManifest().version
+	concurrent := generateULIDAt(clock.Now().Add(time.Second)).String()
+	committed := false
+	bucket.beforeUpload = func(name string) {
+		if committed || name != manifestName("db/table", version+1) {
+			return
+		}
+		committed = true
+		require.NoError(t, b.commitManifest(ctx, "db/table", func(m *tableManifest) bool {
+			m.blocks[concurrent] = struct{}{}
+			return true
+		}))
+	}
+	block := generateULIDAt(clock.Now()).String()
+	require.NoError(t, a.Upload(ctx, filepath.Join("db", "table", block, "data.parquet"), bytes.NewReader(nil)))
+	require.True(t, committed)
+	manifest := loadManifest()
+	require.Equal(t, version+2, manifest.version)
+	require.Equal(t, []string{block, concurrent}, manifest.sortedBlocks())
+}
@@ -0,0 +1,392 @@
+package frostdb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/apache/arrow/go/v17/arrow"
+	"github.com/apache/arrow/go/v17/arrow/memory"
+	"github.com/apache/arrow/go/v17/arrow/util"
+
+	"github.com/polarsignals/frostdb/query/logicalplan"
+	"github.com/polarsignals/frostdb/query/physicalplan"
+)
+
+// subscriptionBufferSize is the number of inserted records buffered per
+// subscription. Subscribers that fall further behind are disconnected.
+const subscriptionBufferSize = 64
+
+// ErrTailUnavailable is returned by TailIterator if records committed after
+// the requested transaction are no longer buffered.
+var ErrTailUnavailable = errors.New("records to tail are no longer available")
+
+// ErrSubscriptionLagged is returned by Subscribe and TailIterator if the
+// subscriber fell too far behind the inserts into the table, in which case
+// records inserted since have not been delivered.
+var ErrSubscriptionLagged = errors.New("subscriber fell too far behind")
+
+type insertedRecord struct {
+	tx     uint64
+	record arrow.Record
+}
+
+type subscription struct {
+	records chan insertedRecord
+	// closed is closed when the table is closed.
+	closed chan struct{}
+	// lagged is closed once a record could not be published because the
+	// subscription's buffer was full. No records are published afterwards.
+	lagged     chan struct{}
+	laggedOnce sync.Once
+}
+
+// Subscribe calls fn with every record inserted into the table after
+// Subscribe was called, filtered to the rows matching filterExpr. A nil
+// filterExpr matches all rows. Records are delivered in the order of their
+// transactions, once they are visible to readers. Records are released
+// after fn returns, so fn must retain a record to keep using it.
+//
+// Subscribe blocks until ctx is canceled, fn returns an error or the table is
+// closed, in which case it returns ctx.Err(), the error returned by fn or
+// ErrTableClosing respectively. Inserts never wait for subscribers, a
+// subscriber that falls more than subscriptionBufferSize records behind is
+// disconnected with ErrSubscriptionLagged, so fn should return quickly.
+func (t *Table) Subscribe(ctx context.Context, filterExpr logicalplan.Expr, fn func(arrow.Record) error) error {
+	plan, err := t.subscriptionPlan(filterExpr, func(_ context.Context, _ uint64, r arrow.Record) error {
+		return fn(r)
+	})
+	if err != nil {
+		return err
+	}
+
+	s := newSubscription()
+	if !t.subscribe(s) {
+		return ErrTableClosing
+	}
+	defer t.unsubscribe(s)
+	return t.consume(ctx, s, 0, nil, plan)
+}
+
+// TailIterator calls callback with the rows of every transaction committed to
+// the table after fromTx that match filterExpr, along with the transaction
+// that committed them. A nil filterExpr matches all rows. Transactions are
+// delivered in order once they are visible to readers, so the transaction
+// passed to callback can be used as fromTx to resume tailing later. Records
+// are released after callback returns, so callback must retain a record to
+// keep using it.
+//
+// Records committed before TailIterator was called are delivered from a
+// buffer of recently committed records, see WithTailBufferSize. If records
+// committed after fromTx are no longer buffered, ErrTailUnavailable is
+// returned and the table needs to be scanned instead. Otherwise TailIterator
+// blocks like Subscribe.
+func (t *Table) TailIterator(
+	ctx context.Context,
+	fromTx uint64,
+	filterExpr logicalplan.Expr,
+	callback func(ctx context.Context, tx uint64, r arrow.Record) error,
+) error {
+	plan, err := t.subscriptionPlan(filterExpr, callback)
+	if err != nil {
+		return err
+	}
+
+	s := newSubscription()
+	// Buffered records are copied while registering the subscription, so
+	// that every record is either buffered or published to the subscription.
+	t.subscriptionsMtx.Lock()
+	if t.subscriptionsClosed {
+		t.subscriptionsMtx.Unlock()
+		return ErrTableClosing
+	}
+	backlog, ok := t.tailFrom(fromTx)
+	if !ok {
+		t.subscriptionsMtx.Unlock()
+		return ErrTailUnavailable
+	}
+	t.subscribeLocked(s)
+	t.subscriptionsMtx.Unlock()
+	defer t.unsubscribe(s)
+
+	return t.consume(ctx, s, fromTx, backlog, plan)
+}
+
+// subscriptionPlan returns the plan that filters records for a subscription
+// and passes them on to fn.
+func (t *Table) subscriptionPlan(
+	filterExpr logicalplan.Expr,
+	fn func(ctx context.Context, tx uint64, r arrow.Record) error,
+) (*subscriptionPlan, error) {
+	p := &subscriptionPlan{}
+	output := &physicalplan.OutputPlan{}
+	output.SetNextCallback(func(ctx context.Context, r arrow.Record) error {
+		return fn(ctx, p.tx, r)
+	})
+	p.PhysicalPlan = output
+	if filterExpr != nil {
+		filter, err := physicalplan.Filter(memory.DefaultAllocator, t.tracer, filterExpr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid subscription filter: %w", err)
+		}
+		filter.SetNext(output)
+		p.PhysicalPlan = filter
+	}
+	return p, nil
+}
+
+// subscriptionPlan is the plan of a subscription. Records are delivered one
+// at a time, so tx is the transaction of the record that is being delivered.
+type subscriptionPlan struct {
+	physicalplan.PhysicalPlan
+	tx uint64
+}
+
+func newSubscription() *subscription {
+	return &subscription{
+		records: make(chan insertedRecord, subscriptionBufferSize),
+		closed:  make(chan struct{}),
+		lagged:  make(chan struct{}),
+	}
+}
+
+// publish hands the record committed in tx to the subscription without
+// blocking. If the subscription's buffer is full, the record is dropped and
+// the subscription is marked as lagged.
+func (s *subscription) publish(tx uint64, record arrow.Record) {
+	select {
+	case <-s.lagged:
+		return
+	default:
+	}
+	record.Retain()
+	select {
+	case s.records <- insertedRecord{tx: tx, record: record}:
+	default:
+		record.Release()
+		s.laggedOnce.Do(func() {
+			close(s.lagged)
+		})
+	}
+}
+
+// consume delivers the backlog and the records published to the subscription
+// that were committed after fromTx in transaction order until ctx is canceled,
+// delivering fails or the table is closed.
+//
+// Concurrent inserts may publish their records out of order. Records are
+// published before their transaction commits, so once the high watermark has
+// passed a transaction, the records of all transactions up to it have been
+// published. Records are therefore held back until the high watermark has
+// passed them, and delivered in order after that.
+func (t *Table) consume(ctx context.Context, s *subscription, fromTx uint64, backlog []insertedRecord, plan *subscriptionPlan) error {
+	pending := backlog
+	defer func() {
+		for _, r := range pending {
+			r.record.Release()
+		}
+	}()
+	add := func(r insertedRecord) {
+		if r.tx <= fromTx {
+			r.record.Release()
+			return
+		}
+		i := sort.Search(len(pending), func(i int) bool {
+			return pending[i].tx > r.tx
+		})
+		pending = append(pending, insertedRecord{})
+		copy(pending[i+1:], pending[i:])
+		pending[i] = r
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-s.closed:
+			return ErrTableClosing
+		case <-s.lagged:
+			return ErrSubscriptionLagged
+		default:
+		}
+
+		// The watermark is loaded before draining the published records, so
+		// that all records committed up to it are pending.
+		watermark := t.db.HighWatermark()
+	drain:
+		for {
+			select {
+			case r := <-s.records:
+				add(r)
+			default:
+				break drain
+			}
+		}
+
+		for len(pending) > 0 && pending[0].tx <= watermark {
+			r := pending[0]
+			pending[0] = insertedRecord{}
+			pending = pending[1:]
+			if err := t.deliver(ctx, r, plan); err != nil {
+				return err
+			}
+		}
+
+		if len(pending) > 0 {
+			// The pending transaction is about to commit, wait for the
+			// watermark to pass it.
+			t.db.Wait(pending[0].tx)
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-s.closed:
+			return ErrTableClosing
+		case <-s.lagged:
+			return ErrSubscriptionLagged
+		case r := <-s.records:
+			add(r)
+		}
+	}
+}
+
+// deliver passes the record through the plan and releases it.
+func (t *Table) deliver(ctx context.Context, r insertedRecord, plan *subscriptionPlan) error {
+	defer r.record.Release()
+	plan.tx = r.tx
+	return plan.Callback(ctx, r.record)
+}
+
+// subscribe registers the subscription. It returns false if the table is
+// closed.
+func (t *Table) subscribe(s *subscription) bool {
+	t.subscriptionsMtx.Lock()
+	defer t.subscriptionsMtx.Unlock()
+	if t.subscriptionsClosed {
+		return false
+	}
+	t.subscribeLocked(s)
+	return true
+}
+
+func (t *Table) subscribeLocked(s *subscription) {
+	if t.subscriptions == nil {
+		t.subscriptions = make(map[*subscription]struct{})
+	}
+	t.subscriptions[s] = struct{}{}
+}
+
+// unsubscribe removes the subscription and releases all records that were
+// not delivered.
+func (t *Table) unsubscribe(s *subscription) {
+	t.subscriptionsMtx.Lock()
+	delete(t.subscriptions, s)
+	t.subscriptionsMtx.Unlock()
+
+	for {
+		select {
+		case r := <-s.records:
+			r.record.Release()
+		default:
+			return
+		}
+	}
+}
+
+// publish hands the record inserted in tx to all subscriptions and the tail
+// buffer. It must be called before tx commits, by a pending writer of the
+// active block, so that closing the table cannot race with it.
+func (t *Table) publish(tx uint64, record arrow.Record) {
+	t.subscriptionsMtx.RLock()
+	defer t.subscriptionsMtx.RUnlock()
+	t.appendTail(tx, record)
+	for s := range t.subscriptions {
+		s.publish(tx, record)
+	}
+}
+
+// appendTail adds the record committed in tx to the tail buffer and evicts
+// the oldest records once the buffer exceeds its size.
+func (t *Table) appendTail(tx uint64, record arrow.Record) {
+	t.tailMtx.Lock()
+	defer t.tailMtx.Unlock()
+	if !t.tailInitialized {
+		// Records committed before this one, e.g. before the table was
+		// recovered, are not buffered.
+		t.tailInitialized = true
+		t.tailEvicted = tx - 1
+	}
+
+	// Concurrent inserts may be published out of order.
+	i := sort.Search(len(t.tail), func(i int) bool {
+		return t.tail[i].tx > tx
+	})
+	record.Retain()
+	t.tail = append(t.tail, insertedRecord{})
+	copy(t.tail[i+1:], t.tail[i:])
+	t.tail[i] = insertedRecord{tx: tx, record: record}
+	t.tailSize += util.TotalRecordSize(record)
+
+	for len(t.tail) > 0 && t.tailSize > t.db.columnStore.tailBufferSize {
+		evicted := t.tail[0]
+		t.tail[0] = insertedRecord{}
+		t.tail = t.tail[1:]
+		t.tailSize -= util.TotalRecordSize(evicted.record)
+		t.tailEvicted = max(t.tailEvicted, evicted.tx)
+		evicted.record.Release()
+	}
+}
+
+// tailFrom returns the retained buffered records committed after fromTx. It
+// returns false if records committed after fromTx have been evicted.
+// subscriptionsMtx must be held so that no records are published
+// concurrently.
+func (t *Table) tailFrom(fromTx uint64) ([]insertedRecord, bool) {
+	t.tailMtx.Lock()
+	defer t.tailMtx.Unlock()
+	if !t.tailInitialized {
+		// Nothing has been published yet, so everything that is committed
+		// is unavailable.
+		t.tailInitialized = true
+		t.tailEvicted = t.db.HighWatermark()
+	}
+	if fromTx < t.tailEvicted {
+		return nil, false
+	}
+
+	i := sort.Search(len(t.tail), func(i int) bool {
+		return t.tail[i].tx > fromTx
+	})
+	backlog := make([]insertedRecord, 0, len(t.tail)-i)
+	for _, r := range t.tail[i:] {
+		r.record.Retain()
+		backlog = append(backlog, r)
+	}
+	return backlog, true
+}
+
+// closeSubscriptions ends all subscriptions, prevents new ones and releases
+// the tail buffer.
+func (t *Table) closeSubscriptions() {
+	t.subscriptionsMtx.Lock()
+	defer t.subscriptionsMtx.Unlock()
+	if t.subscriptionsClosed {
+		return
+	}
+	t.subscriptionsClosed = true
+	for s := range t.subscriptions {
+		close(s.closed)
+	}
+
+	t.tailMtx.Lock()
+	defer t.tailMtx.Unlock()
+	for _, r := range t.tail {
+		r.record.Release()
+	}
+	t.tail = nil
+	t.tailSize = 0
+}
@@ -0,0 +1,256 @@
+package frostdb
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/apache/arrow/go/v17/arrow"
+	"github.com/apache/arrow/go/v17/arrow/array"
+	"github.com/stretchr/testify/require"
+
+	"github.com/polarsignals/frostdb/dynparquet"
+	"github.com/polarsignals/frostdb/query/logicalplan"
+)
+
+// waitForSubscriptions waits until the table has n subscriptions.
+func waitForSubscriptions(t *testing.T, table *Table, n int) {
+	t.Helper()
+	require.Eventually(t, func() bool {
+		table.subscriptionsMtx.RLock()
+		defer table.subscriptionsMtx.RUnlock()
+		return len(table.subscriptions) == n
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestTableSubscribe(t *testing.T) {
+	ctx := context.Background()
+	c, _, table := openTestTable(t, nil)
+	defer c.Close()
+
+	t.Run("Filter", func(t *testing.T) {
+		values := make(chan int64, 10)
+		errStop := errors.New("stop")
+		done := make(chan error, 1)
+		go func() {
+			done <- table.Subscribe(
+				ctx,
+				logicalplan.Col("labels.label1").Eq(logicalplan.Literal("match")),
+				func(r arrow.Record) error {
+					idx := r.Schema().FieldIndices("value")
+					require.Len(t, idx, 1)
+					col := r.Column(idx[0]).(*array.Int64)
+					for i := 0; i < col.Len(); i++ {
+						values <- col.Value(i)
+					}
+					if col.Len() > 0 && col.Value(col.Len()-1) == 3 {
+						return errStop
+					}
+					return nil
+				},
+			)
+		}()
+		waitForSubscriptions(t, table, 1)
+
+		insertSamples(t, table, dynparquet.Samples{
+			{ExampleType: "cpu", Labels: map[string]string{"label1": "match"}, Timestamp: 1, Value: 1},
+			{ExampleType: "cpu", Labels: map[string]string{"label1": "other"}, Timestamp: 2, Value: 2},
+		})
+		insertSamples(t, table, dynparquet.Samples{
+			{ExampleType: "cpu", Labels: map[string]string{"label1": "match"}, Timestamp: 3, Value: 3},
+		})
+
+		require.ErrorIs(t, <-done, errStop)
+		close(values)
+		var got []int64
+		for v := range values {
+			got = append(got, v)
+		}
+		require.Equal(t, []int64{1, 3}, got)
+
+		waitForSubscriptions(t, table, 0)
+	})
+
+	t.Run("Cancel", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(ctx)
+		cancel()
+		require.ErrorIs(t, table.Subscribe(ctx, nil, func(arrow.Record) error { return nil }), context.Canceled)
+	})
+
+	t.Run("InvalidFilter", func(t *testing.T) {
+		require.Error(t, table.Subscribe(ctx, logicalplan.Col("value"), func(arrow.Record) error { return nil }))
+	})
+
+	t.Run("Lagged", func(t *testing.T) {
+		blocked := make(chan struct{}, 1)
+		unblock := make(chan struct{})
+		done := make(chan error, 1)
+		go func() {
+			done <- table.Subscribe(ctx, nil, func(arrow.Record) error {
+				blocked <- struct{}{}
+				<-unblock
+				return nil
+			})
+		}()
+		waitForSubscriptions(t, table, 1)
+
+		insertSamples(t, table, dynparquet.Samples{
+			{ExampleType: "cpu", Labels: map[string]string{"label1": "lagged"}, Timestamp: 1, Value: 1},
+		})
+		<-blocked
+		// Inserts do not wait for the blocked subscriber.
+		for i := 0; i < subscriptionBufferSize+1; i++ {
+			insertSamples(t, table, dynparquet.Samples{
+				{ExampleType: "cpu", Labels: map[string]string{"label1": "lagged"}, Timestamp: int64(i), Value: int64(i)},
+			})
+		}
+		close(unblock)
+		require.ErrorIs(t, <-done, ErrSubscriptionLagged)
+		waitForSubscriptions(t, table, 0)
+	})
+
+	t.Run("Close", func(t *testing.T) {
+		c, _, table := openTestTable(t, nil)
+		done := make(chan error, 1)
+		go func() {
+			done <- table.Subscribe(ctx, nil, func(arrow.Record) error { return nil })
+		}()
+		waitForSubscriptions(t, table, 1)
+
+		require.NoError(t, c.Close())
+		require.ErrorIs(t, <-done, ErrTableClosing)
+		require.ErrorIs(t, table.Subscribe(ctx, nil, func(arrow.Record) error { return nil }), ErrTableClosing)
+	})
+}
+
+func TestTableTailIterator(t *testing.T) {
+	ctx := context.Background()
+	// sample returns a single sample with the given label1 value and value.
+	sample := func(label string, value int64) dynparquet.Samples {
+		return dynparquet.Samples{
+			{ExampleType: "cpu", Labels: map[string]string{"label1": label}, Timestamp: value, Value: value},
+		}
+	}
+
+	type tailed struct {
+		tx    uint64
+		value int64
+	}
+	errStop := errors.New("stop")
+	// tail tails the table until the row with value stop is delivered.
+	tail := func(table *Table, fromTx uint64, filter logicalplan.Expr, stop int64) ([]tailed, error) {
+		var got []tailed
+		err := table.TailIterator(ctx, fromTx, filter, func(_ context.Context, tx uint64, r arrow.Record) error {
+			col := r.Column(r.Schema().FieldIndices("value")[0]).(*array.Int64)
+			for i := 0; i < col.Len(); i++ {
+				got = append(got, tailed{tx: tx, value: col.Value(i)})
+				if col.Value(i) == stop {
+					return errStop
+				}
+			}
+			return nil
+		})
+		return got, err
+	}
+
+	t.Run("Buffered", func(t *testing.T) {
+		c, db, table := openTestTable(t, []Option{WithTailBufferSize(MiB)})
+		defer c.Close()
+
+		fromTx := db.HighWatermark()
+		tx1 := insertSamples(t, table, sample("match", 1))
+		insertSamples(t, table, sample("other", 2))
+
+		done := make(chan error, 1)
+		var got []tailed
+		go func() {
+			var err error
+			got, err = tail(table, fromTx, logicalplan.Col("labels.label1").Eq(logicalplan.Literal("match")), 3)
+			done <- err
+		}()
+		waitForSubscriptions(t, table, 1)
+		tx3 := insertSamples(t, table, sample("match", 3))
+		require.ErrorIs(t, <-done, errStop)
+		require.Equal(t, []tailed{{tx: tx1, value: 1}, {tx: tx3, value: 3}}, got)
+
+		// Tailing resumes after the given transaction.
+		got, err := tail(table, tx1, nil, 3)
+		require.ErrorIs(t, err, errStop)
+		require.Equal(t, []int64{2, 3}, []int64{got[0].value, got[1].value})
+	})
+
+	t.Run("Unavailable", func(t *testing.T) {
+		c, db, table := openTestTable(t, nil)
+		defer c.Close()
+
+		fromTx := db.HighWatermark()
+		insertSamples(t, table, sample("match", 1))
+		tx := insertSamples(t, table, sample("match", 2))
+		_, err := tail(table, fromTx, nil, 2)
+		require.ErrorIs(t, err, ErrTailUnavailable)
+
+		// Tailing from the latest transaction only needs new records.
+		done := make(chan error, 1)
+		var got []tailed
+		go func() {
+			var err error
+			got, err = tail(table, tx, nil, 3)
+			done <- err
+		}()
+		waitForSubscriptions(t, table, 1)
+		tx3 := insertSamples(t, table, sample("match", 3))
+		require.ErrorIs(t, <-done, errStop)
+		require.Equal(t, []tailed{{tx: tx3, value: 3}}, got)
+	})
+
+	t.Run("ConcurrentInserts", func(t *testing.T) {
+		c, db, table := openTestTable(t, []Option{WithTailBufferSize(MiB)})
+		defer c.Close()
+
+		const (
+			writers = 4
+			inserts = 10
+		)
+		fromTx := db.HighWatermark()
+		done := make(chan error, 1)
+		var got []uint64
+		go func() {
+			done <- table.TailIterator(ctx, fromTx, nil, func(_ context.Context, tx uint64, r arrow.Record) error {
+				for i := int64(0); i < r.NumRows(); i++ {
+					got = append(got, tx)
+				}
+				if len(got) == writers*inserts {
+					return errStop
+				}
+				return nil
+			})
+		}()
+		waitForSubscriptions(t, table, 1)
+
+		var (
+			mtx sync.Mutex
+			txs []uint64
+			wg  sync.WaitGroup
+		)
+		for w := 0; w < writers; w++ {
+			wg.Add(1)
+			go func(w int) {
+				defer wg.Done()
+				for i := 0; i < inserts; i++ {
+					tx := insertSamples(t, table, sample("match", int64(w*inserts+i)))
+					mtx.Lock()
+					txs = append(txs, tx)
+					mtx.Unlock()
+				}
+			}(w)
+		}
+		wg.Wait()
+
+		require.ErrorIs(t, <-done, errStop)
+		sort.Slice(txs, func(i, j int) bool { return txs[i] < txs[j] })
+		require.Equal(t, txs, got)
+	})
+}
@@ -9,7 +9,10 @@ import (
 	"math/rand"
 	"path/filepath"
 	"runtime"
+	"slices"
 	"sort"
+	"strconv"
+	"strings"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -99,6 +102,84 @@ func WithoutWAL() TableOption {
 	}
 }
 
+// WithRetention drops data whose "timestamp" column, interpreted as
+// milliseconds since the Unix epoch, is older than the given retention window.
+// A different column or unit can be configured with WithRetentionColumn.
+// Expired data is hidden from queries, dropped from memory when it is
+// compacted or persisted, and blocks that only contain expired data are
+// deleted from storage. Retention is enforced at the granularity of parts and
+// row groups, so individual expired rows may still be returned by queries.
+func WithRetention(retention time.Duration) TableOption {
+	return func(config *tablepb.TableConfig) error {
+		if retention < 0 {
+			return fmt.Errorf("retention must not be negative: %s", retention)
+		}
+		config.RetentionMs = uint64(retention.Milliseconds())
+		return nil
+	}
+}
+
+// WithRetentionColumn enforces the retention configured with WithRetention
+// on the given column, whose values are interpreted as multiples of unit since
+// the Unix epoch, e.g. time.Second for Unix timestamps in seconds. The column
+// must be a non-dynamic int64 column of the table's schema.
+func WithRetentionColumn(column string, unit time.Duration) TableOption {
+	return func(config *tablepb.TableConfig) error {
+		if column == "" {
+			return errors.New("retention column must not be empty")
+		}
+		if unit <= 0 {
+			return fmt.Errorf("retention column unit must be positive: %s", unit)
+		}
+		config.RetentionColumn = column
+		config.RetentionColumnUnitNs = uint64(unit)
+		return nil
+	}
+}
+
+// WithColumnIndex writes bloom filters for the given columns in addition to
+// the sorting columns, so that granules and row groups can be skipped when
+// filtering for equality on these columns. Dynamic columns must be given as
+// concrete columns, e.g. "labels.pod". The bloom filters are stored in the
+// parquet files of the table's in-memory granules and persisted blocks.
+func WithColumnIndex(columns ...string) TableOption {
+	return func(config *tablepb.TableConfig) error {
+		for _, col := range columns {
+			if !slices.Contains(config.IndexedColumns, col) {
+				config.IndexedColumns = append(config.IndexedColumns, col)
+			}
+		}
+		return nil
+	}
+}
+
+// WithSortOrder additionally maintains the table's data sorted by the given
+// sorting columns. Queries read the sort order whose leading sorting columns
+// best match the columns they filter and group by, trading storage and insert
+// throughput for query speed. Each sort order is stored as a separate table
+// named "<table>@<name>". Sort orders must be configured when the table is
+// created and cannot be changed afterwards.
+func WithSortOrder(name string, sortingColumns ...*schemav2pb.SortingColumn) TableOption {
+	return func(config *tablepb.TableConfig) error {
+		if !validateName(name) || strings.Contains(name, sortOrderSeparator) {
+			return fmt.Errorf("invalid sort order name: %q", name)
+		}
+		if len(sortingColumns) == 0 {
+			return fmt.Errorf("sort order %s has no sorting columns", name)
+		}
+		for _, so := range config.SortOrders {
+			if so.Name == name {
+				return fmt.Errorf("duplicate sort order: %s", name)
+			}
+		}
+		config.SortOrders = append(config.SortOrders, &tablepb.SortOrder{
+			Name:           name,
+			SortingColumns: sortingColumns,
+		})
+		return nil
+	}
+}
+
 func WithUniquePrimaryIndex(unique bool) TableOption {
 	return func(config *tablepb.TableConfig) error {
 		switch e := config.Schema.(type) {
@@ -111,6 +192,151 @@ func WithUniquePrimaryIndex(unique bool) TableOption {
 	}
 }
 
+// WithUnsortedDynamicColumns excludes dynamic columns from the sorting columns
+// of the table's schema, so that they are stored as unsorted payload and
+// ignored when sorting and merging data. This significantly reduces the CPU
+// spent on inserts and compactions of tables with many dynamic columns, e.g.
+// append-only tables that are only aggregated by time, at the cost of not
+// being able to skip data when filtering by dynamic columns. The option also
+// applies to the table's sort orders, and cannot be combined with a unique
+// primary index.
+func WithUnsortedDynamicColumns() TableOption {
+	return func(config *tablepb.TableConfig) error {
+		config.UnsortedDynamicColumns = true
+		return nil
+	}
+}
+
+// WithMonotonicTimestamps detects inserts of rows whose "timestamp" is older
+// than the last inserted row of the same series, where a series is identified
+// by the values of the sorting columns other than "timestamp". The last
+// timestamp of up to cacheSize recently inserted series is tracked, so rows of
+// series evicted from the cache are not checked against older inserts.
+// Depending on the behavior, offending inserts are either rejected with an
+// *ErrNonMonotonicTimestamp error or only counted in the
+// frostdb_table_non_monotonic_rows_total metric.
+func WithMonotonicTimestamps(cacheSize int, behavior MonotonicTimestampBehavior) TableOption {
+	return func(config *tablepb.TableConfig) error {
+		if cacheSize <= 0 {
+			return fmt.Errorf("monotonic timestamps cache size must be positive: %d", cacheSize)
+		}
+		config.MonotonicTimestampsCacheSize = uint64(cacheSize)
+		config.RejectNonMonotonicTimestamps = behavior == MonotonicTimestampsReject
+		return nil
+	}
+}
+
+// WithMonotonicTimestampsColumn checks the timestamps of the given column
+// instead of "timestamp" when monotonic timestamps are enabled with
+// WithMonotonicTimestamps. The column must be a non-dynamic int64 column of the
+// table's schema, and is not used to identify series.
+func WithMonotonicTimestampsColumn(column string) TableOption {
+	return func(config *tablepb.TableConfig) error {
+		if column == "" {
+			return errors.New("monotonic timestamps column must not be empty")
+		}
+		config.MonotonicTimestampsColumn = column
+		return nil
+	}
+}
+
+// WithStorageQuota rejects inserts with an ErrQuotaExceeded error for the
+// QuotaStorage resource once the persisted blocks and in-memory data of the
+// table take up more than the given number of bytes, so that a single table
+// cannot fill up shared disk or bucket capacity. It sets the StorageBytes
+// limit of the table's Quota, see WithQuota. Inserts are rejected until
+// retention frees up space or the quota is raised. Zero disables the quota. A
+// negative quota fails opening the table.
+func WithStorageQuota(bytes int64) TableOption {
+	return func(config *tablepb.TableConfig) error {
+		config.StorageQuotaBytes = uint64(bytes)
+		return validateQuotas(config)
+	}
+}
+
+// WithQuota rejects inserts into the table with an ErrQuotaExceeded error once
+// the table uses more of a resource than the quota allows, see Quota. A
+// negative limit fails opening the table.
+func WithQuota(quota Quota) TableOption {
+	return func(config *tablepb.TableConfig) error {
+		config.ActiveMemoryQuotaBytes = uint64(quota.ActiveMemoryBytes)
+		config.BucketQuotaBytes = uint64(quota.BucketBytes)
+		config.WalQuotaBytes = uint64(quota.WALBytes)
+		config.StorageQuotaBytes = uint64(quota.StorageBytes)
+		return validateQuotas(config)
+	}
+}
+
+// WithMergeKeepLatest keeps only the most recently inserted row of rows with
+// equal values in all sorting columns, which allows updating rows by inserting
+// them again. Rows are merged when the table's in-memory data is compacted and
+// persisted. Queries merge the rows of each in-memory block too, so they
+// return a single version of a row per block, but rows of different blocks
+// are not merged. It cannot be used with a unique primary index.
+func WithMergeKeepLatest() TableOption {
+	return func(config *tablepb.TableConfig) error {
+		config.MergePolicy = tablepb.MergePolicy_MERGE_POLICY_KEEP_LATEST
+		config.MergeReducer = ""
+		return nil
+	}
+}
+
+// WithDeduplication is an alias of WithMergeKeepLatest for tables receiving
+// rows more than once, e.g. from a pipeline with at-least-once delivery. Of
+// rows with equal values in all sorting columns, only the most recently
+// inserted one is kept. Duplicates are only dropped within a block, so
+// duplicates in different persisted blocks are still returned by queries.
+// The number of rows dropped by compactions is exported as the
+// frostdb_table_rows_merged_total metric.
+func WithDeduplication() TableOption {
+	return WithMergeKeepLatest()
+}
+
+// WithMergeReducer merges rows with equal values in all sorting columns using
+// the reducer registered with the column store under the given name, see
+// WithRowReducer, e.g. to sum counters. Rows are merged at the same time and
+// with the same limitations as with WithMergeKeepLatest.
+func WithMergeReducer(name string) TableOption {
+	return func(config *tablepb.TableConfig) error {
+		if name == "" {
+			return errors.New("merge reducer name must not be empty")
+		}
+		config.MergePolicy = tablepb.MergePolicy_MERGE_POLICY_REDUCE
+		config.MergeReducer = name
+		return nil
+	}
+}
+
+// WithMergeAggregation merges rows with equal values in all sorting columns
+// into a single row whose other columns are aggregated with agg, e.g. to keep
+// counters that are incremented by inserting rows with the increment. Rows
+// are merged at the same time and with the same limitations as with
+// WithMergeKeepLatest. A reducer registered with WithRowReducer under the
+// same name as agg takes precedence over the built-in aggregation.
+func WithMergeAggregation(agg dynparquet.Aggregation) TableOption {
+	return func(config *tablepb.TableConfig) error {
+		if agg == "" {
+			return errors.New("merge aggregation must not be empty")
+		}
+		config.MergePolicy = tablepb.MergePolicy_MERGE_POLICY_REDUCE
+		config.MergeReducer = string(agg)
+		return nil
+	}
+}
+
+// WithV1Alpha1DualRead enables a dual-read mode for a table migrated from a
+// v1alpha1 to a v1alpha2 schema. Persisted data written under the v1alpha1
+// schema is reshaped into the v1alpha2 layout using the given field mapping
+// when read, so it can be queried alongside data written under the new schema.
+// See dynparquet.MigrateV1Alpha1Schema to convert the schema definition itself.
+func WithV1Alpha1DualRead(mapping dynparquet.FieldMapping) TableOption {
+	return func(config *tablepb.TableConfig) error {
+		config.V1Alpha1DualRead = true
+		config.V1Alpha1FieldMapping = mapping
+		return nil
+	}
+}
+
 // FromConfig sets the table configuration from the given config.
 // NOTE: that this does not override the schema even though that is included in the passed in config.
 func FromConfig(config *tablepb.TableConfig) TableOption {
@@ -120,6 +346,23 @@ func FromConfig(config *tablepb.TableConfig) TableOption {
 		}
 		cfg.DisableWal = config.DisableWal
 		cfg.RowGroupSize = config.RowGroupSize
+		cfg.RetentionMs = config.RetentionMs
+		cfg.RetentionColumn = config.RetentionColumn
+		cfg.RetentionColumnUnitNs = config.RetentionColumnUnitNs
+		cfg.IndexedColumns = config.IndexedColumns
+		cfg.SortOrders = config.SortOrders
+		cfg.UnsortedDynamicColumns = config.UnsortedDynamicColumns
+		cfg.MonotonicTimestampsCacheSize = config.MonotonicTimestampsCacheSize
+		cfg.RejectNonMonotonicTimestamps = config.RejectNonMonotonicTimestamps
+		cfg.MonotonicTimestampsColumn = config.MonotonicTimestampsColumn
+		cfg.StorageQuotaBytes = config.StorageQuotaBytes
+		cfg.MergePolicy = config.MergePolicy
+		cfg.MergeReducer = config.MergeReducer
+		cfg.ActiveMemoryQuotaBytes = config.ActiveMemoryQuotaBytes
+		cfg.BucketQuotaBytes = config.BucketQuotaBytes
+		cfg.WalQuotaBytes = config.WalQuotaBytes
+		cfg.V1Alpha1DualRead = config.V1Alpha1DualRead
+		cfg.V1Alpha1FieldMapping = config.V1Alpha1FieldMapping
 		return nil
 	}
 }
@@ -245,6 +488,16 @@ func (t *GenericTable[T]) Write(ctx context.Context, values ...T) (uint64, error
 	return t.InsertRecord(ctx, t.build.NewRecord())
 }
 
+// buildRecord builds an arrow.Record from values, which must be released.
+func (t *GenericTable[T]) buildRecord(values ...T) (arrow.Record, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if err := t.build.Append(values...); err != nil {
+		return nil, err
+	}
+	return t.build.NewRecord(), nil
+}
+
 func NewGenericTable[T any](db *DB, name string, mem memory.Allocator, options ...TableOption) (*GenericTable[T], error) {
 	build := records.NewBuild[T](mem)
 	table, err := db.Table(name, NewTableConfig(build.Schema(name), options...))
@@ -262,7 +515,32 @@ type Table struct {
 	tracer  trace.Tracer
 
 	config atomic.Pointer[tablepb.TableConfig]
-	schema *dynparquet.Schema
+	// schema is the schema of the table's config. It changes along with the
+	// config when the schema is updated.
+	schema atomic.Pointer[dynparquet.Schema]
+
+	// mergeReducer, if set, merges rows with equal sorting columns when
+	// parts are compacted according to the table's merge policy.
+	mergeReducer dynparquet.RowReducer
+
+	// persistedBytes is the size of the persisted blocks of the table, see
+	// Stats. storageUsageOnce loads it from the data sinks once the storage
+	// quota is first checked.
+	persistedBytes   atomic.Int64
+	storageUsageOnce sync.Once
+
+	// dynamicColumns tracks the concrete dynamic columns of the table, and
+	// pendingDynamicColumns the new ones of inserts that are in progress. They
+	// are only populated if a dynamic column limit is configured, once the
+	// first insert is limited, see limitDynamicColumns.
+	dynamicColumnsMtx     sync.Mutex
+	dynamicColumns        map[string]struct{}
+	pendingDynamicColumns map[string]int
+
+	// sortOrderStale is set on a table storing a sort order once a record
+	// could not be mirrored into it. Queries are no longer served from it
+	// since it misses rows.
+	sortOrderStale atomic.Bool
 
 	pendingBlocks   map[*TableBlock]struct{}
 	completedBlocks []completedBlock
@@ -273,6 +551,41 @@ type Table struct {
 
 	wal     WAL
 	closing bool
+
+	tombstonesMtx   sync.RWMutex
+	tombstones      []tombstone
+	nextTombstoneID uint64
+
+	// dataVersion is incremented whenever the data of the table changes, see
+	// DataVersion. lastInsertTx is the transaction of the latest insert.
+	dataVersion  atomic.Uint64
+	lastInsertTx atomic.Uint64
+
+	// seriesTracker tracks the last timestamp of series if the table
+	// enforces monotonic timestamps.
+	seriesTrackerMtx sync.Mutex
+	seriesTracker    *seriesTracker
+
+	retentionMtx  sync.Mutex
+	stopRetention chan struct{}
+	retentionDone chan struct{}
+
+	blockGCMtx  sync.Mutex
+	stopBlockGC chan struct{}
+	blockGCDone chan struct{}
+
+	subscriptionsMtx    sync.RWMutex
+	subscriptions       map[*subscription]struct{}
+	subscriptionsClosed bool
+
+	// tail buffers the most recently committed records for TailIterator,
+	// sorted by tx. tailEvicted is the highest tx whose records are no
+	// longer buffered.
+	tailMtx         sync.Mutex
+	tail            []insertedRecord
+	tailSize        int64
+	tailEvicted     uint64
+	tailInitialized bool
 }
 
 type Sync interface {
@@ -283,6 +596,9 @@ type WAL interface {
 	Close() error
 	Log(tx uint64, record *walpb.Record) error
 	LogRecord(tx uint64, table string, record arrow.Record) error
+	// LogRecords logs the given records as a single entry of the given
+	// transaction. The records must share the same schema.
+	LogRecords(tx uint64, table string, records []arrow.Record) error
 	// Replay replays WAL records from the given first index. If firstIndex is
 	// 0, the first index read from the WAL is used (i.e. given a truncation,
 	// using 0 is still valid). If the given firstIndex is less than the WAL's
@@ -317,10 +633,19 @@ type TableBlock struct {
 	lastSnapshotSize atomic.Int64
 
 	index *index.LSM
+	// schema is the schema of the table when the block was created. The parts
+	// of the block are sorted by its sorting columns.
+	schema *dynparquet.Schema
 
 	pendingWritersWg sync.WaitGroup
 	pendingReadersWg sync.WaitGroup
 
+	// discarded is set if the block was truncated while it was pending, in
+	// which case it is not persisted.
+	discarded atomic.Bool
+	// written is closed once the block was written, see Table.writeBlock.
+	written chan struct{}
+
 	mtx *sync.RWMutex
 }
 
@@ -331,15 +656,80 @@ type Closer interface {
 func schemaFromTableConfig(tableConfig *tablepb.TableConfig) (*dynparquet.Schema, error) {
 	switch schema := tableConfig.Schema.(type) {
 	case *tablepb.TableConfig_DeprecatedSchema:
-		return dynparquet.SchemaFromDefinition(schema.DeprecatedSchema)
+		return tableSchema(schema.DeprecatedSchema, tableConfig)
 	case *tablepb.TableConfig_SchemaV2:
-		return dynparquet.SchemaFromDefinition(schema.SchemaV2)
+		return tableSchema(schema.SchemaV2, tableConfig)
 	default:
 		// No schema defined for table; read/only table
 		return nil, nil
 	}
 }
 
+// ErrTableSchemaChanged is returned by DB.Table if the schema of the given
+// config differs from the schema of the existing table. Compatible schema
+// changes of an open table are applied with Table.UpdateSchema, or when the
+// table is opened with the new config, e.g. after a restart.
+var ErrTableSchemaChanged = errors.New("the schema of an open table cannot be changed")
+
+// checkSchemaCompatibility returns an error if the new config of an open table
+// changes its schema. Changes between v1alpha1 schemas that cannot be applied
+// to the data written with the old schema are reported as an
+// *dynparquet.IncompatibleSchemaError, all other changes as
+// ErrTableSchemaChanged.
+func checkSchemaCompatibility(old, new *tablepb.TableConfig) error {
+	if old.GetSchema() == nil || new.GetSchema() == nil {
+		return nil
+	}
+	oldSchema, newSchema := old.GetDeprecatedSchema(), new.GetDeprecatedSchema()
+	if oldSchema != nil && newSchema != nil {
+		if proto.Equal(oldSchema, newSchema) {
+			return nil
+		}
+		if err := dynparquet.CheckCompatibility(oldSchema, newSchema).Err(); err != nil {
+			return err
+		}
+		return ErrTableSchemaChanged
+	}
+	if oldV2, newV2 := old.GetSchemaV2(), new.GetSchemaV2(); oldV2 != nil && newV2 != nil && proto.Equal(oldV2, newV2) {
+		return nil
+	}
+	return ErrTableSchemaChanged
+}
+
+// tableSchema returns the schema of a table with the given config from the
+// schema definition def.
+func tableSchema(def proto.Message, tableConfig *tablepb.TableConfig) (*dynparquet.Schema, error) {
+	s, err := dynparquet.SchemaFromDefinition(def)
+	if err != nil || !tableConfig.GetUnsortedDynamicColumns() {
+		return s, err
+	}
+	if s.UniquePrimaryIndex {
+		// Rows that only differ in their dynamic columns would be
+		// deduplicated.
+		return nil, errors.New("unsorted dynamic columns cannot be used with a unique primary index")
+	}
+	return dynparquet.SchemaFromDefinition(withoutDynamicSortingColumns(def, s))
+}
+
+// withoutDynamicSortingColumns returns a copy of the schema definition def of
+// s without the sorting columns that refer to dynamic columns.
+func withoutDynamicSortingColumns(def proto.Message, s *dynparquet.Schema) proto.Message {
+	def = proto.Clone(def)
+	switch def := def.(type) {
+	case *schemapb.Schema:
+		def.SortingColumns = slices.DeleteFunc(def.SortingColumns, func(col *schemapb.SortingColumn) bool {
+			_, ok := s.FindDynamicColumn(col.Name)
+			return ok
+		})
+	case *schemav2pb.Schema:
+		def.SortingColumns = slices.DeleteFunc(def.SortingColumns, func(col *schemav2pb.SortingColumn) bool {
+			_, ok := s.FindDynamicColumn(col.Path)
+			return ok
+		})
+	}
+	return def
+}
+
 func newTable(
 	db *DB,
 	name string,
@@ -368,19 +758,54 @@ func newTable(
 		return nil, err
 	}
 
+	if s != nil && len(tableConfig.IndexedColumns) > 0 {
+		if err := s.SetIndexedColumns(tableConfig.IndexedColumns...); err != nil {
+			return nil, err
+		}
+	}
+
+	if s != nil && tableConfig.RetentionMs != 0 {
+		if err := validateRetention(s, tableConfig); err != nil {
+			return nil, err
+		}
+	}
+
+	if s != nil && tableConfig.MonotonicTimestampsCacheSize != 0 {
+		if err := validateMonotonicTimestamps(s, tableConfig); err != nil {
+			return nil, err
+		}
+	}
+
+	if s != nil && tableConfig.PartitionDurationMs != 0 {
+		if err := validatePartitioning(s, tableConfig); err != nil {
+			return nil, err
+		}
+	}
+
+	var reduce dynparquet.RowReducer
+	if s != nil {
+		reduce, err = db.columnStore.mergeReducer(s, tableConfig)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	t := &Table{
-		db:      db,
-		name:    name,
-		logger:  logger,
-		tracer:  tracer,
-		mtx:     &sync.RWMutex{},
-		wal:     wal,
-		schema:  s,
-		metrics: metrics,
+		db:           db,
+		name:         name,
+		logger:       logger,
+		tracer:       tracer,
+		mtx:          &sync.RWMutex{},
+		wal:          wal,
+		metrics:      metrics,
+		mergeReducer: reduce,
+
+		pendingDynamicColumns: map[string]int{},
 	}
 
 	// Store the table config
 	t.config.Store(tableConfig)
+	t.schema.Store(s)
 
 	// Disable the WAL for this table by replacing any given WAL with a nop wal
 	if tableConfig.DisableWal {
@@ -389,6 +814,10 @@ func newTable(
 
 	t.pendingBlocks = make(map[*TableBlock]struct{})
 
+	if err := t.loadTombstones(); err != nil {
+		return nil, fmt.Errorf("load tombstones: %w", err)
+	}
+
 	return t, nil
 }
 
@@ -444,6 +873,7 @@ func (t *Table) writeBlock(
 	if rbo.wg != nil {
 		defer rbo.wg.Done()
 	}
+	defer close(block.written)
 	level.Debug(t.logger).Log("msg", "syncing block", "next_txn", nextTxn, "ulid", block.ulid, "size", block.index.Size())
 	block.pendingWritersWg.Wait()
 
@@ -453,10 +883,11 @@ func (t *Table) writeBlock(
 
 	// Persist the block
 	var err error
-	if !rbo.skipPersist && block.index.Size() != 0 {
+	if !rbo.skipPersist && !block.discarded.Load() && block.index.Size() != 0 {
 		err = block.Persist()
 	}
 	t.dropPendingBlock(block)
+	t.dataVersion.Add(1)
 	if err != nil {
 		level.Error(t.logger).Log("msg", "failed to persist block")
 		level.Error(t.logger).Log("msg", err.Error())
@@ -593,6 +1024,19 @@ func (t *Table) RotateBlock(_ context.Context, block *TableBlock, opts ...Rotate
 		return nil
 	}
 
+	return t.rotateActiveBlockLocked(opts...)
+}
+
+// rotateActiveBlockLocked replaces the active block with a new block created
+// with the table's current config and schema, and persists the previously
+// active block in the background unless skipPersist is set. t.mtx must be
+// held.
+func (t *Table) rotateActiveBlockLocked(opts ...RotateBlockOption) error {
+	rbo := &rotateBlockOptions{}
+	for _, o := range opts {
+		o(rbo)
+	}
+	block := t.active
 	level.Debug(t.logger).Log(
 		"msg", "rotating block",
 		"ulid", block.ulid,
@@ -604,17 +1048,18 @@ func (t *Table) RotateBlock(_ context.Context, block *TableBlock, opts ...Rotate
 	tx, _, commit := t.db.begin()
 	defer commit()
 
-	id := generateULID()
-	for id.Time() == block.ulid.Time() { // Ensure the new block has a different timestamp.
-		// Sleep a millisecond to ensure the ULID has a different timestamp.
-		time.Sleep(time.Millisecond)
-		id = generateULID()
+	id := generateULID(t.db.columnStore.clock)
+	if id.Time() <= block.ulid.Time() {
+		// Ensure the new block has a later timestamp, even if the clock has
+		// not advanced since the previous block was created.
+		id = generateULIDAt(ulid.Time(block.ulid.Time() + 1))
 	}
 	if err := t.newTableBlock(t.active.minTx, tx, id); err != nil {
 		return err
 	}
 	t.metrics.blockRotated.Inc()
 	t.metrics.numParts.Set(float64(0))
+	t.dataVersion.Add(1)
 
 	if !rbo.skipPersist {
 		// If skipping persist, this block rotation is simply a block discard,
@@ -626,7 +1071,9 @@ func (t *Table) RotateBlock(_ context.Context, block *TableBlock, opts ...Rotate
 	// We don't check t.db.columnStore.manualBlockRotation here because this is
 	// the entry point for users to trigger a manual block rotation and they
 	// will specify through skipPersist if they want the block to be persisted.
-	go t.writeBlock(block, tx, true, opts...)
+	t.db.columnStore.scheduler.Go(WorkPersistence, t.db.name+"/"+t.name, func() {
+		t.writeBlock(block, tx, true, opts...)
+	})
 
 	return nil
 }
@@ -654,7 +1101,7 @@ func (t *Table) Schema() *dynparquet.Schema {
 	if t.config.Load() == nil {
 		return nil
 	}
-	return t.schema
+	return t.schema.Load()
 }
 
 func (t *Table) EnsureCompaction() error {
@@ -662,30 +1109,102 @@ func (t *Table) EnsureCompaction() error {
 }
 
 func (t *Table) InsertRecord(ctx context.Context, record arrow.Record) (uint64, error) {
+	if t.db.columnStore.readOnly {
+		return 0, ErrReadOnly
+	}
+	record, commitDynamicColumns, err := t.limitDynamicColumns(ctx, record)
+	if err != nil {
+		return 0, err
+	}
+	defer record.Release()
+	inserted := false
+	defer func() {
+		commitDynamicColumns(inserted)
+	}()
+
+	commitTimestamps, err := t.checkMonotonicTimestamps(record)
+	if err != nil {
+		return 0, err
+	}
+	defer func() {
+		commitTimestamps(inserted)
+	}()
+
+	if err := t.checkQuotas(ctx); err != nil {
+		return 0, err
+	}
+
+	// Sort orders are inserted into before this transaction begins, so that
+	// their transactions are committed by the time the record becomes
+	// visible in this table, and queries served by a sort order see it too.
+	// A failure to mirror the record does not fail the insert, the sort order
+	// is no longer read instead.
+	sortOrders := t.sortOrderTables()
+	for _, so := range sortOrders {
+		if _, err := so.InsertRecord(ctx, record); err != nil {
+			so.markSortOrderStale(err)
+		}
+	}
+
+	// The active block is only written to once the record was checked and
+	// mirrored, since both may lock this table, which Truncate holds while
+	// waiting for the writers of the active block.
 	block, finish, err := t.appender(ctx)
 	if err != nil {
+		for _, so := range sortOrders {
+			so.markSortOrderStale(errors.New("insert into table failed"))
+		}
 		return 0, fmt.Errorf("get appender: %w", err)
 	}
 	defer finish()
 
-	tx, _, commit := t.db.begin()
-	defer commit()
+	tx, _, commit := t.db.begin(t.name)
+	defer func() {
+		if !inserted {
+			// The sort orders contain a record that this table does not.
+			for _, so := range sortOrders {
+				so.markSortOrderStale(errors.New("insert into table failed"))
+			}
+		}
+		// Publish before committing, so that subscribers can tell that no
+		// record is missing once the high watermark passed a transaction.
+		// Publishing never blocks, so slow subscribers cannot hold back the
+		// high watermark.
+		if inserted {
+			t.publish(tx, record)
+			t.insertedAt(tx)
+		}
+		commit()
+	}()
 
-	preHashedRecord := dynparquet.PrehashColumns(t.schema, record)
+	preHashedRecord := dynparquet.PrehashColumns(t.schema.Load(), record)
 	defer preHashedRecord.Release()
 
-	if err := t.wal.LogRecord(tx, t.name, preHashedRecord); err != nil {
+	chunks := chunkRecord(preHashedRecord, t.db.columnStore.maxInsertChunkSize)
+	defer func() {
+		for _, c := range chunks {
+			c.Release()
+		}
+	}()
+
+	if err := t.wal.LogRecords(tx, t.name, chunks); err != nil {
 		return tx, fmt.Errorf("append to log: %w", err)
 	}
 
-	if err := block.InsertRecord(ctx, tx, preHashedRecord); err != nil {
-		return tx, fmt.Errorf("insert buffer into block: %w", err)
+	for _, c := range chunks {
+		if err := block.InsertRecord(ctx, tx, c); err != nil {
+			return tx, fmt.Errorf("insert buffer into block: %w", err)
+		}
 	}
+	inserted = true
 
 	return tx, nil
 }
 
 func (t *Table) appender(ctx context.Context) (*TableBlock, func(), error) {
+	if err := t.db.Quarantined(); err != nil {
+		return nil, nil, err
+	}
 	for {
 		// Using active write block is important because it ensures that we don't
 		// miss pending writers when synchronizing the block.
@@ -695,7 +1214,7 @@ func (t *Table) appender(ctx context.Context) (*TableBlock, func(), error) {
 		}
 
 		uncompressedInsertsSize := block.uncompressedInsertsSize.Load()
-		if t.db.columnStore.snapshotTriggerSize != 0 &&
+		if t.db.columnStore.snapshotTriggerSize != 0 && !t.db.MaintenancePaused() &&
 			// If size-lastSnapshotSize > snapshotTriggerSize (a column store
 			// option), a new snapshot is triggered. This is basically the size
 			// of the new data in this block since the last snapshot.
@@ -722,7 +1241,9 @@ func (t *Table) appender(ctx context.Context) (*TableBlock, func(), error) {
 			})
 		}
 		blockSize := block.Size()
-		if blockSize < t.db.columnStore.activeMemorySize || t.db.columnStore.manualBlockRotation {
+		rotate := blockSize >= t.db.columnStore.activeMemorySize ||
+			(blockSize > 0 && t.crossedPartitionBoundary(block))
+		if !rotate || t.db.columnStore.manualBlockRotation || t.db.MaintenancePaused() {
 			return block, finish, nil
 		}
 
@@ -736,11 +1257,20 @@ func (t *Table) appender(ctx context.Context) (*TableBlock, func(), error) {
 	}
 }
 
+// View calls fn with a consistent read transaction of the database. Views are
+// limited by the query concurrency of the column store, see
+// WithQueryConcurrency, unless ctx belongs to a query that was already
+// admitted by the column store.
 func (t *Table) View(ctx context.Context, fn func(ctx context.Context, tx uint64) error) error {
 	ctx, span := t.tracer.Start(ctx, "Table/View")
+	defer span.End()
+	ctx, release, err := t.db.columnStore.queryGate.acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer release()
 	tx := t.db.beginRead()
 	span.SetAttributes(attribute.Int64("tx", int64(tx))) // Attributes don't support uint64...
-	defer span.End()
 	return fn(ctx, tx)
 }
 
@@ -762,18 +1292,22 @@ func (t *Table) Iterator(
 	span.SetAttributes(attribute.Int("distinct", len(iterOpts.DistinctColumns)))
 	defer span.End()
 
+	if err := t.db.Quarantined(); err != nil {
+		return err
+	}
+
+	if so := t.sortOrderFor(iterOpts); so != nil {
+		span.SetAttributes(attribute.String("sortOrder", so.name))
+		return so.Iterator(ctx, tx, pool, callbacks, options...)
+	}
+
 	if len(callbacks) == 0 {
 		return errors.New("no callbacks provided")
 	}
 	rowGroups := make(chan any, len(callbacks)*4) // buffer up to 4 row groups per callback
 	defer func() {                                // Drain the channel of any leftover parts due to cancellation or error
 		for rg := range rowGroups {
-			switch t := rg.(type) {
-			case index.ReleaseableRowGroup:
-				t.Release()
-			case arrow.Record:
-				t.Release()
-			}
+			releaseScanValue(rg)
 		}
 	}()
 
@@ -787,13 +1321,125 @@ func (t *Table) Iterator(
 	// buffered results are flushed to the next operator.
 	const bufferSize = 1024
 
+	convertOpts := []pqarrow.ConverterOption{
+		pqarrow.WithConcurrency(t.db.columnStore.conversionConcurrency),
+	}
+	if decodePool := t.db.columnStore.decodePool; decodePool != nil {
+		convertOpts = append(convertOpts, pqarrow.WithDecodePool(decodePool))
+	}
+
+	// project projects in-memory records like the converter projects row
+	// groups.
+	project := func(r arrow.Record) (arrow.Record, error) {
+		r = pqarrow.Project(r, iterOpts.PhysicalProjection)
+		if !iterOpts.DictionaryEncode {
+			return r, nil
+		}
+		defer r.Release()
+		return pqarrow.DictionaryEncode(pool, r)
+	}
+
 	errg, ctx := errgroup.WithContext(ctx)
 	for _, callback := range callbacks {
 		callback := callback
 		errg.Go(recovery.Do(func() error {
-			converter := pqarrow.NewParquetConverter(pool, *iterOpts)
+			converter := pqarrow.NewParquetConverter(pool, *iterOpts, convertOpts...)
 			defer converter.Close()
 
+			// v1alpha1Converter converts row groups written under a v1alpha1
+			// schema when dual-reading. These are converted separately since
+			// their records need to be reshaped before being passed on.
+			var v1alpha1Converter *pqarrow.ParquetConverter
+			defer func() {
+				if v1alpha1Converter != nil {
+					v1alpha1Converter.Close()
+				}
+			}()
+			convertV1Alpha1 := func(rg parquet.RowGroup, callback logicalplan.Callback) error {
+				if v1alpha1Converter == nil {
+					v1alpha1Converter = pqarrow.NewParquetConverter(pool, *iterOpts, convertOpts...)
+				}
+				if err := v1alpha1Converter.Convert(ctx, rg, t.schema.Load()); err != nil {
+					return fmt.Errorf("failed to convert row group to arrow record: %v", err)
+				}
+				if len(v1alpha1Converter.Fields()) == 0 {
+					return nil
+				}
+				r := v1alpha1Converter.NewRecord()
+				defer r.Release()
+				v1alpha1Converter.Reset()
+				if r.NumRows() == 0 {
+					return nil
+				}
+				migrated, err := pqarrow.MigrateRecord(r, t.config.Load().V1Alpha1FieldMapping)
+				if err != nil {
+					return err
+				}
+				defer migrated.Release()
+				return callback(ctx, migrated)
+			}
+
+			// provenanceConverter converts row groups annotated with their
+			// provenance. Each row group is converted into its own record so
+			// that every record has a single provenance.
+			var provenanceConverter *pqarrow.ParquetConverter
+			defer func() {
+				if provenanceConverter != nil {
+					provenanceConverter.Close()
+				}
+			}()
+			emitWithProvenance := func(pv provenanceValue) error {
+				annotated := func(ctx context.Context, r arrow.Record) error {
+					r = pv.provenance.annotate(r)
+					defer r.Release()
+					return callback(ctx, r)
+				}
+				switch rg := pv.value.(type) {
+				case arrow.Record:
+					defer rg.Release()
+					r, err := project(rg)
+					if err != nil {
+						return err
+					}
+					defer r.Release()
+					return annotated(ctx, r)
+				case dynparquet.DynamicRowGroup:
+					if rrg, ok := rg.(index.ReleaseableRowGroup); ok {
+						defer rrg.Release()
+					}
+					if t.isV1Alpha1RowGroup(rg) {
+						return convertV1Alpha1(rg, annotated)
+					}
+					if provenanceConverter == nil {
+						provenanceConverter = pqarrow.NewParquetConverter(pool, *iterOpts, convertOpts...)
+					}
+					if err := provenanceConverter.Convert(ctx, rg, t.schema.Load()); err != nil {
+						return fmt.Errorf("failed to convert row group to arrow record: %v", err)
+					}
+					if len(provenanceConverter.Fields()) == 0 {
+						return nil
+					}
+					r := provenanceConverter.NewRecord()
+					defer r.Release()
+					provenanceConverter.Reset()
+					if r.NumRows() == 0 {
+						return nil
+					}
+					return annotated(ctx, r)
+				default:
+					return fmt.Errorf("unknown row group type: %T", rg)
+				}
+			}
+
+			emitColumnless := func(numRows int64) error {
+				if iterOpts.Filter == nil || numRows == 0 {
+					return nil
+				}
+				r := array.NewRecord(arrow.NewSchema(nil, nil), nil, numRows)
+				defer r.Release()
+				return callback(ctx, r)
+			}
+
 			for {
 				select {
 				case <-ctx.Done():
@@ -811,22 +1457,43 @@ func (t *Table) Iterator(
 						return callback(ctx, r)
 					}
 
+					if pv, ok := rg.(provenanceValue); ok {
+						if err := emitWithProvenance(pv); err != nil {
+							return err
+						}
+						continue
+					}
+
 					switch rg := rg.(type) {
 					case arrow.Record:
 						defer rg.Release()
-						r := pqarrow.Project(rg, iterOpts.PhysicalProjection)
+						r, err := project(rg)
+						if err != nil {
+							return err
+						}
 						defer r.Release()
-						err := callback(ctx, r)
+						err = callback(ctx, r)
 						if err != nil {
 							return err
 						}
 					case index.ReleaseableRowGroup:
 						defer rg.Release()
-						if err := converter.Convert(ctx, rg, t.schema); err != nil {
+						if t.isV1Alpha1RowGroup(rg) {
+							if err := convertV1Alpha1(rg, callback); err != nil {
+								return err
+							}
+							continue
+						}
+						if err := converter.Convert(ctx, rg, t.schema.Load()); err != nil {
 							return fmt.Errorf("failed to convert row group to arrow record: %v", err)
 						}
-						// This RowGroup had no relevant data. Ignore it.
 						if len(converter.Fields()) == 0 {
+							// This RowGroup has none of the columns read. Its
+							// rows are only relevant to filters that match
+							// absent columns, e.g. IS NULL.
+							if err := emitColumnless(rg.NumRows()); err != nil {
+								return err
+							}
 							continue
 						}
 						if converter.NumRows() >= bufferSize {
@@ -841,11 +1508,22 @@ func (t *Table) Iterator(
 							}
 						}
 					case dynparquet.DynamicRowGroup:
-						if err := converter.Convert(ctx, rg, t.schema); err != nil {
+						if t.isV1Alpha1RowGroup(rg) {
+							if err := convertV1Alpha1(rg, callback); err != nil {
+								return err
+							}
+							continue
+						}
+						if err := converter.Convert(ctx, rg, t.schema.Load()); err != nil {
 							return fmt.Errorf("failed to convert row group to arrow record: %v", err)
 						}
-						// This RowGroup had no relevant data. Ignore it.
 						if len(converter.Fields()) == 0 {
+							// This RowGroup has none of the columns read. Its
+							// rows are only relevant to filters that match
+							// absent columns, e.g. IS NULL.
+							if err := emitColumnless(rg.NumRows()); err != nil {
+								return err
+							}
 							continue
 						}
 						if converter.NumRows() >= bufferSize {
@@ -869,12 +1547,131 @@ func (t *Table) Iterator(
 
 	errg.Go(func() error {
 		defer close(rowGroups)
-		return t.collectRowGroups(ctx, tx, iterOpts.Filter, iterOpts.ReadMode, rowGroups)
+		return t.collectRowGroups(ctx, pool, tx, iterOpts.Filter, iterOpts.ReadMode, iterOpts.Provenance, false, rowGroups)
 	})
 
 	return errg.Wait()
 }
 
+// DataVersion returns the version of the data the table returns when read at
+// tx, see logicalplan.DataVersioner. The version changes with inserts, block
+// rotation and persistence, deletes and truncation of the table, but not with
+// writes to other tables. Reads at a transaction preceding an insert whose
+// version was already taken are not versioned, since they would be cached
+// under the version including the insert. Tables of read-only column stores
+// and tables with a retention period are not versioned either, since their
+// data changes without a new version.
+func (t *Table) DataVersion(tx uint64) (string, bool) {
+	if t.retention() != 0 {
+		return "", false
+	}
+	t.mtx.RLock()
+	active := t.active
+	t.mtx.RUnlock()
+	if active == nil {
+		return "", false
+	}
+	// The version is loaded before the transaction of the latest insert,
+	// which is stored before the version is incremented, so that an insert
+	// not visible at tx is detected once the version includes it.
+	version := t.dataVersion.Load()
+	if t.lastInsertTx.Load() > tx {
+		return "", false
+	}
+	// The active block identifies the table across instances of the same
+	// name, e.g. once the table was dropped and created again.
+	return active.ulid.String() + "/" + strconv.FormatUint(version, 10), true
+}
+
+// insertedAt records an insert at tx, which must happen before the
+// transaction is committed.
+func (t *Table) insertedAt(tx uint64) {
+	for {
+		last := t.lastInsertTx.Load()
+		if last >= tx || t.lastInsertTx.CompareAndSwap(last, tx) {
+			break
+		}
+	}
+	t.dataVersion.Add(1)
+}
+
+// RowCount returns the number of rows in the table at the given transaction.
+// Rows are counted from the metadata of the active parts, row groups and
+// blocks, so no columns are read. Deleted rows and row groups that only
+// contain data past the retention period are not counted, but rows that the
+// table's merge policy merges are counted until they are compacted. Only the
+// logicalplan.WithReadMode option is taken into account.
+func (t *Table) RowCount(ctx context.Context, tx uint64, options ...logicalplan.Option) (int64, error) {
+	iterOpts := &logicalplan.IterOptions{}
+	for _, opt := range options {
+		opt(iterOpts)
+	}
+	ctx, span := t.tracer.Start(ctx, "Table/RowCount")
+	defer span.End()
+
+	if err := t.db.Quarantined(); err != nil {
+		return 0, err
+	}
+
+	rowGroups := make(chan any, 16)
+	errg, ctx := errgroup.WithContext(ctx)
+	errg.Go(func() error {
+		defer close(rowGroups)
+		return t.collectRowGroups(ctx, t.db.columnStore.allocator, tx, nil, iterOpts.ReadMode, false, false, rowGroups)
+	})
+
+	var count int64
+	for v := range rowGroups {
+		count += scanValueNumRows(v)
+		releaseScanValue(v)
+	}
+	if err := errg.Wait(); err != nil {
+		return 0, err
+	}
+	span.SetAttributes(attribute.Int64("rows", count))
+	return count, nil
+}
+
+// scanValueNumRows returns the number of rows of a value sent by
+// collectRowGroups.
+func scanValueNumRows(v any) int64 {
+	switch v := v.(type) {
+	case provenanceValue:
+		return scanValueNumRows(v.value)
+	case arrow.Record:
+		return v.NumRows()
+	case parquet.RowGroup:
+		return v.NumRows()
+	default:
+		return 0
+	}
+}
+
+// isV1Alpha1RowGroup returns whether the given row group was written under a
+// v1alpha1 schema while the table uses a v1alpha2 schema and dual-reading is
+// enabled. v1alpha1 row groups are identified by top-level leaf columns that
+// are either concrete dynamic columns (which contain a period) or renamed by
+// the field mapping.
+func (t *Table) isV1Alpha1RowGroup(rg parquet.RowGroup) bool {
+	config := t.config.Load()
+	if !config.V1Alpha1DualRead {
+		return false
+	}
+	if _, ok := t.schema.Load().Definition().(*schemav2pb.Schema); !ok {
+		return false
+	}
+	mapping := dynparquet.FieldMapping(config.V1Alpha1FieldMapping)
+	for _, f := range rg.Schema().Fields() {
+		if !f.Leaf() {
+			continue
+		}
+		if strings.Contains(f.Name(), ".") || mapping.Path(f.Name()) != f.Name() {
+			return true
+		}
+	}
+	return false
+}
+
 // SchemaIterator iterates in order over all granules in the table and returns
 // all the schemas seen across the table.
 func (t *Table) SchemaIterator(
@@ -894,6 +1691,10 @@ func (t *Table) SchemaIterator(
 	span.SetAttributes(attribute.Int("distinct", len(iterOpts.DistinctColumns)))
 	defer span.End()
 
+	if err := t.db.Quarantined(); err != nil {
+		return err
+	}
+
 	if len(callbacks) == 0 {
 		return errors.New("no callbacks provided")
 	}
@@ -957,6 +1758,21 @@ func (t *Table) SchemaIterator(
 
 						b.Field(0).(*array.StringBuilder).AppendValues(fieldNames, nil)
 
+						record := b.NewRecord()
+						if err := callback(ctx, record); err != nil {
+							return err
+						}
+						record.Release()
+						b.Release()
+					case *parquet.Schema:
+						parquetFields := t.Fields()
+						fieldNames := make([]string, 0, len(parquetFields))
+						for _, f := range parquetFields {
+							fieldNames = append(fieldNames, f.Name())
+						}
+
+						b.Field(0).(*array.StringBuilder).AppendValues(fieldNames, nil)
+
 						record := b.NewRecord()
 						if err := callback(ctx, record); err != nil {
 							return err
@@ -990,7 +1806,7 @@ func (t *Table) SchemaIterator(
 	}
 
 	errg.Go(func() error {
-		if err := t.collectRowGroups(ctx, tx, iterOpts.Filter, iterOpts.ReadMode, rowGroups); err != nil {
+		if err := t.collectRowGroups(ctx, pool, tx, iterOpts.Filter, iterOpts.ReadMode, false, true, rowGroups); err != nil {
 			return err
 		}
 		close(rowGroups)
@@ -1000,8 +1816,11 @@ func (t *Table) SchemaIterator(
 	return errg.Wait()
 }
 
-func generateULID() ulid.ULID {
-	t := time.Now()
+func generateULID(clock Clock) ulid.ULID {
+	return generateULIDAt(clock.Now())
+}
+
+func generateULIDAt(t time.Time) ulid.ULID {
 	entropy := ulid.Monotonic(rand.New(rand.NewSource(t.UnixNano())), 0)
 	return ulid.MustNew(ulid.Timestamp(t), entropy)
 }
@@ -1015,16 +1834,32 @@ func newTableBlock(table *Table, prevTx, tx uint64, id ulid.ULID) (*TableBlock,
 		tracer: table.tracer,
 		minTx:  tx,
 		prevTx: prevTx,
+		schema: table.schema.Load(),
+
+		written: make(chan struct{}),
+	}
+
+	options := []index.LSMOption{
+		index.LSMWithMetrics(&table.metrics.indexMetrics),
+		index.LSMWithLogger(table.logger),
+		index.LSMWithPartRewriter(table.rewritePart),
+		index.LSMWithCompactionPaused(table.db.MaintenancePaused),
+		index.LSMWithNow(table.db.columnStore.clock.Now),
+		index.LSMWithCompactionScheduler(func(compact func()) {
+			table.db.columnStore.scheduler.Go(WorkCompaction, table.db.name+"/"+table.name, compact)
+		}),
+	}
+	if policy := table.db.columnStore.compactionPolicy; policy != nil {
+		options = append(options, index.LSMWithCompactionPolicy(policy))
 	}
 
 	var err error
 	tb.index, err = index.NewLSM(
 		filepath.Join(table.db.indexDir(), table.name, id.String()), // Any index files are found at <db.indexDir>/<table.name>/<block.id>
-		table.schema,
+		tb.schema,
 		table.IndexConfig(),
 		table.db.HighWatermark,
-		index.LSMWithMetrics(&table.metrics.indexMetrics),
-		index.LSMWithLogger(table.logger),
+		options...,
 	)
 	if err != nil {
 		return nil, fmt.Errorf("new LSM: %w", err)
@@ -1038,6 +1873,26 @@ func (t *TableBlock) EnsureCompaction() error {
 	return t.index.EnsureCompaction()
 }
 
+// chunkRecord splits the record into slices of consecutive rows whose size is
+// at most maxSize, or a single row if a row alone exceeds it. A maxSize <= 0
+// disables chunking. The caller is responsible for releasing the returned
+// records.
+func chunkRecord(record arrow.Record, maxSize int64) []arrow.Record {
+	rows := record.NumRows()
+	size := util.TotalRecordSize(record)
+	if maxSize <= 0 || size <= maxSize || rows <= 1 {
+		record.Retain()
+		return []arrow.Record{record}
+	}
+
+	rowsPerChunk := max(1, rows*maxSize/size)
+	chunks := make([]arrow.Record, 0, (rows+rowsPerChunk-1)/rowsPerChunk)
+	for i := int64(0); i < rows; i += rowsPerChunk {
+		chunks = append(chunks, record.NewSlice(i, min(i+rowsPerChunk, rows)))
+	}
+	return chunks
+}
+
 func (t *TableBlock) InsertRecord(_ context.Context, tx uint64, record arrow.Record) error {
 	recordSize := util.TotalRecordSize(record)
 	defer func() {
@@ -1057,6 +1912,128 @@ func (t *TableBlock) InsertRecord(_ context.Context, tx uint64, record arrow.Rec
 	return nil
 }
 
+// limitDynamicColumns enforces the column store's dynamic column limit on the
+// given record. Concrete dynamic columns seen for the first time once the limit
+// is reached are either rejected or folded into an overflow column. The new
+// columns of the returned record are reserved until the returned function is
+// called, with whether the record was inserted, so that concurrent inserts
+// cannot exceed the limit together and a failed insert doesn't use it up. The
+// caller is responsible for releasing the returned record.
+func (t *Table) limitDynamicColumns(ctx context.Context, record arrow.Record) (arrow.Record, func(inserted bool), error) {
+	limit := t.db.columnStore.dynamicColumnLimit
+	if limit <= 0 {
+		record.Retain()
+		return record, func(bool) {}, nil
+	}
+
+	t.dynamicColumnsMtx.Lock()
+	defer t.dynamicColumnsMtx.Unlock()
+
+	if err := t.loadDynamicColumnsLocked(ctx); err != nil {
+		return nil, nil, err
+	}
+
+	var (
+		reserved   []string
+		newColumns []string
+	)
+	for _, f := range record.Schema().Fields() {
+		if !t.isConcreteDynamicColumn(f.Name) {
+			continue
+		}
+		if _, ok := t.dynamicColumns[f.Name]; ok {
+			continue
+		}
+		if _, ok := t.pendingDynamicColumns[f.Name]; ok {
+			reserved = append(reserved, f.Name)
+			continue
+		}
+		newColumns = append(newColumns, f.Name)
+	}
+
+	used := len(t.dynamicColumns) + len(t.pendingDynamicColumns)
+	if total := used + len(newColumns); total > limit {
+		if t.db.columnStore.dynamicColumnLimitBehavior != dynparquet.DynamicColumnLimitOverflow {
+			return nil, nil, dynparquet.ErrTooManyDynamicColumns{Columns: total, Limit: limit}
+		}
+
+		// Data written before the limit was configured may have exceeded it
+		// already.
+		admitted := newColumns[:max(limit-used, 0)]
+		fold := make(map[string]struct{}, len(newColumns)-len(admitted))
+		for _, name := range newColumns[len(admitted):] {
+			def, _ := t.schema.Load().FindDynamicColumnForConcreteColumn(name)
+			if def.StorageLayout.Type().Kind() != parquet.ByteArray {
+				// Only string-like values can be map-encoded.
+				return nil, nil, dynparquet.ErrTooManyDynamicColumns{Columns: total, Limit: limit}
+			}
+			fold[name] = struct{}{}
+		}
+		folded, err := dynparquet.FoldDynamicColumns(t.db.columnStore.allocator, record, fold)
+		if err != nil {
+			return nil, nil, err
+		}
+		record = folded
+		newColumns = admitted
+	} else {
+		record.Retain()
+	}
+
+	reserved = append(reserved, newColumns...)
+	for _, name := range reserved {
+		t.pendingDynamicColumns[name]++
+	}
+	return record, func(inserted bool) {
+		t.dynamicColumnsMtx.Lock()
+		defer t.dynamicColumnsMtx.Unlock()
+		for _, name := range reserved {
+			if t.pendingDynamicColumns[name]--; t.pendingDynamicColumns[name] == 0 {
+				delete(t.pendingDynamicColumns, name)
+			}
+			if inserted {
+				t.dynamicColumns[name] = struct{}{}
+			}
+		}
+	}, nil
+}
+
+// loadDynamicColumnsLocked loads the concrete dynamic columns of all data of
+// the table, in memory and persisted, unless they were loaded already. Only
+// inserted records add columns afterwards. t.dynamicColumnsMtx must be held.
+func (t *Table) loadDynamicColumnsLocked(ctx context.Context) error {
+	if t.dynamicColumns != nil {
+		return nil
+	}
+
+	columns := map[string]struct{}{}
+	if err := t.SchemaIterator(ctx, t.db.beginRead(), t.db.columnStore.allocator, []logicalplan.Callback{
+		func(_ context.Context, r arrow.Record) error {
+			names := r.Column(0).(*array.String)
+			for i := 0; i < names.Len(); i++ {
+				if t.isConcreteDynamicColumn(names.Value(i)) {
+					columns[names.Value(i)] = struct{}{}
+				}
+			}
+			return nil
+		},
+	}); err != nil {
+		return fmt.Errorf("load dynamic columns: %w", err)
+	}
+	t.dynamicColumns = columns
+	return nil
+}
+
+// isConcreteDynamicColumn returns whether the given column name is a concrete
+// column of a dynamic column that counts towards the dynamic column limit.
+func (t *Table) isConcreteDynamicColumn(name string) bool {
+	_, concrete, ok := strings.Cut(name, ".")
+	if !ok || concrete == dynparquet.OverflowColumnName {
+		return false
+	}
+	_, ok = t.schema.Load().FindDynamicColumnForConcreteColumn(name)
+	return ok
+}
+
 // Size returns the cumulative size of all buffers in the table. This is roughly the size of the table in bytes.
 func (t *TableBlock) Size() int64 {
 	return t.index.Size()
@@ -1069,7 +2046,42 @@ func (t *TableBlock) Index() *index.LSM {
 
 // Serialize the table block into a single Parquet file.
 func (t *TableBlock) Serialize(writer io.Writer) error {
-	return t.index.Rotate(t.table.externalParquetCompaction(writer))
+	// Record the schema and layout the block is written with, so that it
+	// can be interpreted correctly after the table schema changed and
+	// migrated after the table settings changed.
+	options, err := t.table.blockMetadataOptions()
+	if err != nil {
+		return err
+	}
+	if !sortOrderChanged(t.schema, t.table.schema.Load()) {
+		return t.index.Rotate(t.table.externalParquetCompaction(writer, options...))
+	}
+
+	// The sorting columns of the table changed since the block was created,
+	// so its parts are not sorted by the current sorting columns. The rows
+	// are re-sorted once all parts are written.
+	var b bytes.Buffer
+	if err := t.index.Rotate(t.table.externalParquetCompaction(&b, options...)); err != nil {
+		return err
+	}
+	if b.Len() == 0 {
+		return nil
+	}
+	buf, err := dynparquet.ReaderFromBytes(b.Bytes())
+	if err != nil {
+		return err
+	}
+	return t.table.migrateBlock(buf, writer)
+}
+
+// sortOrderChanged returns true if rows sorted by the sorting columns of old
+// are not necessarily sorted by the sorting columns of new.
+func sortOrderChanged(old, new *dynparquet.Schema) bool {
+	return !slices.EqualFunc(old.SortingColumns(), new.SortingColumns(), func(a, b dynparquet.SortingColumn) bool {
+		return a.ColumnName() == b.ColumnName() &&
+			a.Descending() == b.Descending() &&
+			a.NullsFirst() == b.NullsFirst()
+	})
 }
 
 type ParquetWriter interface {
@@ -1104,7 +2116,7 @@ func (t *TableBlock) rowWriter(w ParquetWriter, options ...parquetRowWriterOptio
 
 	p := &parquetRowWriter{
 		w:            w,
-		schema:       t.table.schema,
+		schema:       t.table.schema.Load(),
 		rowsBuf:      make([]parquet.Row, buffSize),
 		rowGroupSize: int(config.RowGroupSize),
 	}
@@ -1184,16 +2196,43 @@ func (t *Table) memoryBlocks() ([]*TableBlock, uint64) {
 }
 
 // collectRowGroups collects all the row groups from the table for the given filter.
+// If provenance is true, the row groups are wrapped in a provenanceValue. If
+// schemaOnly is true, only the schemas of the row groups are needed, and data
+// sources implementing SchemaScanner send the *parquet.Schema of their blocks
+// instead of row groups if there is nothing to filter. Records of persisted
+// row groups that contain deleted rows are allocated from pool.
 func (t *Table) collectRowGroups(
 	ctx context.Context,
+	pool memory.Allocator,
 	tx uint64,
 	filterExpr logicalplan.Expr,
 	readMode logicalplan.ReadMode,
+	provenance bool,
+	schemaOnly bool,
 	rowGroups chan<- any,
 ) error {
 	ctx, span := t.tracer.Start(ctx, "Table/collectRowGroups")
 	defer span.End()
 
+	// Skip parts and row groups that only contain expired data.
+	if cutoff, ok := t.retentionCutoff(); ok {
+		if filterExpr == nil {
+			filterExpr = t.retentionFilter(cutoff)
+		} else {
+			filterExpr = logicalplan.And(filterExpr, t.retentionFilter(cutoff))
+		}
+	}
+
+	send := func(ctx context.Context, v any) error {
+		select {
+		case <-ctx.Done():
+			releaseScanValue(v)
+			return ctx.Err()
+		case rowGroups <- v:
+			return nil
+		}
+	}
+
 	// pending blocks could be uploaded to the bucket while we iterate on them.
 	// to avoid to iterate on them again while reading the block file
 	// we keep the last block timestamp to be read from the bucket and pass it to the IterateBucketBlocks() function
@@ -1208,16 +2247,21 @@ func (t *Table) collectRowGroups(
 			}
 		}()
 		for _, block := range memoryBlocks {
-			if err := block.index.Scan(ctx, "", t.schema, filterExpr, tx, func(ctx context.Context, v any) error {
-				select {
-				case <-ctx.Done():
-					if rg, ok := v.(index.ReleaseableRowGroup); ok {
-						rg.Release()
-					}
-					return ctx.Err()
-				case rowGroups <- v:
-					return nil
+			if t.mergeReducer != nil {
+				if err := t.scanMergedBlock(ctx, block, tx, filterExpr, provenance, send); err != nil {
+					return err
 				}
+				continue
+			}
+			if err := block.index.ScanParts(ctx, filterExpr, tx, func(ctx context.Context, v any, part parts.Part) error {
+				if provenance {
+					v = provenanceValue{value: v, provenance: Provenance{
+						Source: ProvenanceSourceMemory,
+						Block:  block.ulid,
+						Tx:     part.TX(),
+					}}
+				}
+				return send(ctx, v)
 			}); err != nil {
 				return err
 			}
@@ -1231,16 +2275,30 @@ func (t *Table) collectRowGroups(
 	// Collect from all other data sources.
 	for _, source := range t.db.sources {
 		span.AddEvent(fmt.Sprintf("source/%s", source.String()))
-		if err := source.Scan(ctx, filepath.Join(t.db.name, t.name), t.schema, filterExpr, lastBlockTimestamp, func(ctx context.Context, v any) error {
-			select {
-			case <-ctx.Done():
-				if rg, ok := v.(index.ReleaseableRowGroup); ok {
-					rg.Release()
-				}
-				return ctx.Err()
-			case rowGroups <- v:
+		if scanner, ok := source.(SchemaScanner); ok && schemaOnly && filterExpr == nil && !t.hasTombstones() {
+			if err := scanner.ScanSchemas(ctx, filepath.Join(t.db.name, t.name), lastBlockTimestamp, func(ctx context.Context, schema *parquet.Schema) error {
+				return send(ctx, schema)
+			}); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := source.Scan(ctx, filepath.Join(t.db.name, t.name), t.schema.Load(), filterExpr, lastBlockTimestamp, func(ctx context.Context, v any) error {
+			v, err := t.removeDeletedRowsFromSource(pool, blockIDFromContext(ctx), v)
+			if err != nil {
+				return err
+			}
+			if v == nil {
 				return nil
 			}
+			v = t.withBlockSchema(blockIDFromContext(ctx), v)
+			if provenance {
+				v = provenanceValue{value: v, provenance: Provenance{
+					Source: ProvenanceSourceBucket,
+					Block:  blockIDFromContext(ctx),
+				}}
+			}
+			return send(ctx, v)
 		}); err != nil {
 			return err
 		}
@@ -1251,17 +2309,33 @@ func (t *Table) collectRowGroups(
 
 // close notifies a table to stop accepting writes.
 func (t *Table) close() {
+	t.stopRetentionLoop()
+	t.stopBlockGCLoop()
+
+	t.mtx.Lock()
+	pending := make([]*TableBlock, 0, len(t.pendingBlocks))
+	for block := range t.pendingBlocks {
+		pending = append(pending, block)
+	}
+	t.mtx.Unlock()
+	// Rotated blocks are persisted by the scheduler, wait for them so that no
+	// background work outlives the table.
+	for _, block := range pending {
+		<-block.written
+	}
+
 	t.mtx.Lock()
 	defer t.mtx.Unlock()
 
 	t.active.pendingWritersWg.Wait()
 	t.closing = true
 	t.active.index.WaitForPendingCompactions()
+	t.closeSubscriptions()
 }
 
-func (t *Table) externalParquetCompaction(writer io.Writer) func(compact []parts.Part) (parts.Part, int64, int64, error) {
+func (t *Table) externalParquetCompaction(writer io.Writer, options ...parquet.WriterOption) func(compact []parts.Part) (parts.Part, int64, int64, error) {
 	return func(compact []parts.Part) (parts.Part, int64, int64, error) {
-		size, err := t.compactParts(writer, compact)
+		size, err := t.compactParts(writer, compact, options...)
 		if err != nil {
 			return nil, 0, 0, err
 		}
@@ -1282,7 +2356,11 @@ func (t *Table) compactParts(w io.Writer, compact []parts.Part, options ...parqu
 		preCompactionSize += p.Size()
 	}
 
-	if t.schema.UniquePrimaryIndex {
+	if t.mergeReducer != nil {
+		return preCompactionSize, t.reduceParts(w, compact, options...)
+	}
+
+	if t.schema.Load().UniquePrimaryIndex {
 		distinctRecords, err := t.distinctRecordsForCompaction(compact)
 		if err != nil {
 			return 0, err
@@ -1309,53 +2387,71 @@ func (t *Table) compactParts(w io.Writer, compact []parts.Part, options ...parqu
 		return preCompactionSize, nil
 	}
 
-	merged, err := t.schema.MergeDynamicRowGroups(bufs)
+	merged, err := t.schema.Load().MergeDynamicRowGroups(bufs)
 	if err != nil {
 		return 0, err
 	}
-	err = func() error {
-		var writer dynparquet.ParquetWriter
-		if len(options) > 0 {
-			writer, err = t.schema.NewWriter(w, merged.DynamicColumns(), false, options...)
-			if err != nil {
-				return err
-			}
-		} else {
-			pw, err := t.schema.GetWriter(w, merged.DynamicColumns(), false)
-			if err != nil {
-				return err
-			}
-			defer t.schema.PutWriter(pw)
-			writer = pw.ParquetWriter
+	if err := t.writeMergedRowGroups(w, merged, options...); err != nil {
+		return 0, err
+	}
+
+	return preCompactionSize, nil
+}
+
+// writeMergedRowGroups writes the rows of the given sorted row group to a
+// Parquet file written to w.
+func (t *Table) writeMergedRowGroups(w io.Writer, merged dynparquet.DynamicRowGroup, options ...parquet.WriterOption) error {
+	schema := t.schema.Load()
+	var writer dynparquet.ParquetWriter
+	if len(options) > 0 {
+		var err error
+		writer, err = schema.NewWriter(w, merged.DynamicColumns(), false, options...)
+		if err != nil {
+			return err
 		}
-		p, err := t.active.rowWriter(writer)
+	} else {
+		pw, err := schema.GetWriter(w, merged.DynamicColumns(), false)
 		if err != nil {
 			return err
 		}
-		defer p.close()
+		defer schema.PutWriter(pw)
+		writer = pw.ParquetWriter
+	}
+	p, err := t.active.rowWriter(writer)
+	if err != nil {
+		return err
+	}
+	defer p.close()
 
-		rows := merged.Rows()
-		defer rows.Close()
+	// Row groups written under a previous schema of the table, e.g. parts of
+	// a block created before the schema was updated, are converted to the
+	// layout of the current schema.
+	ps, err := schema.GetDynamicParquetSchema(merged.DynamicColumns())
+	if err != nil {
+		return err
+	}
+	defer schema.PutPooledParquetSchema(ps)
+	conv, err := parquet.Convert(ps.Schema, merged.Schema())
+	if err != nil {
+		return fmt.Errorf("convert row group schema: %w", err)
+	}
 
-		var rowReader parquet.RowReader = rows
-		if t.schema.UniquePrimaryIndex {
-			// Given all inputs are sorted, we can deduplicate the rows using
-			// DedupeRowReader, which deduplicates consecutive rows that are
-			// equal on the sorting columns.
-			rowReader = parquet.DedupeRowReader(rows, merged.Schema().Comparator(merged.SortingColumns()...))
-		}
+	rows := merged.Rows()
+	defer rows.Close()
 
-		if _, err := p.writeRows(rowReader); err != nil {
-			return err
-		}
+	var rowReader parquet.RowReader = parquet.ConvertRowReader(rows, conv)
+	if schema.UniquePrimaryIndex {
+		// Given all inputs are sorted, we can deduplicate the rows using
+		// DedupeRowReader, which deduplicates consecutive rows that are
+		// equal on the sorting columns.
+		rowReader = parquet.DedupeRowReader(rowReader, ps.Schema.Comparator(merged.SortingColumns()...))
+	}
 
-		return nil
-	}()
-	if err != nil {
-		return 0, err
+	if _, err := p.writeRows(rowReader); err != nil {
+		return err
 	}
 
-	return preCompactionSize, nil
+	return nil
 }
 
 // buffersForCompaction, given a slice of possibly overlapping parts, returns
@@ -1363,13 +2459,14 @@ func (t *Table) compactParts(w io.Writer, compact []parts.Part, options ...parqu
 // If nil, nil is returned, the resulting serialized buffer is written directly
 // to w as an optimization.
 func (t *Table) buffersForCompaction(w io.Writer, inputParts []parts.Part, options ...parquet.WriterOption) ([]dynparquet.DynamicRowGroup, error) {
-	nonOverlappingParts, overlappingParts, err := parts.FindMaximumNonOverlappingSet(t.schema, inputParts)
+	schema := t.schema.Load()
+	nonOverlappingParts, overlappingParts, err := parts.FindMaximumNonOverlappingSet(schema, inputParts)
 	if err != nil {
 		return nil, err
 	}
 	result := make([]dynparquet.DynamicRowGroup, 0, len(inputParts))
 	for _, p := range overlappingParts {
-		buf, err := p.AsSerializedBuffer(t.schema)
+		buf, err := p.AsSerializedBuffer(schema)
 		if err != nil {
 			return nil, err
 		}
@@ -1391,7 +2488,7 @@ func (t *Table) buffersForCompaction(w io.Writer, inputParts []parts.Part, optio
 		// is at least one non-arrow part then optimizations cannot be made.
 		nonOverlappingRowGroups := make([]dynparquet.DynamicRowGroup, 0, len(nonOverlappingParts))
 		for _, p := range nonOverlappingParts {
-			buf, err := p.AsSerializedBuffer(t.schema)
+			buf, err := p.AsSerializedBuffer(schema)
 			if err != nil {
 				return nil, err
 			}
@@ -1402,7 +2499,7 @@ func (t *Table) buffersForCompaction(w io.Writer, inputParts []parts.Part, optio
 			// WithAlreadySorted ensures that a parquet.MultiRowGroup is created
 			// here, which is much cheaper than actually merging all these row
 			// groups.
-			merged, err = t.schema.MergeDynamicRowGroups(nonOverlappingRowGroups, dynparquet.WithAlreadySorted())
+			merged, err = schema.MergeDynamicRowGroups(nonOverlappingRowGroups, dynparquet.WithAlreadySorted())
 			if err != nil {
 				return nil, err
 			}
@@ -1442,6 +2539,7 @@ func (t *Table) buffersForCompaction(w io.Writer, inputParts []parts.Part, optio
 }
 
 func (t *Table) writeRecordsToParquet(w io.Writer, records []arrow.Record, sortInput bool, options ...parquet.WriterOption) error {
+	schema := t.schema.Load()
 	dynColSets := make([]map[string][]string, 0, len(records))
 	for _, r := range records {
 		dynColSets = append(dynColSets, pqarrow.RecordDynamicCols(r))
@@ -1450,20 +2548,20 @@ func (t *Table) writeRecordsToParquet(w io.Writer, records []arrow.Record, sortI
 	var writer dynparquet.ParquetWriter
 	if len(options) > 0 {
 		var err error
-		writer, err = t.schema.NewWriter(w, dynCols, false, options...)
+		writer, err = schema.NewWriter(w, dynCols, sortInput, options...)
 		if err != nil {
 			return err
 		}
 	} else {
-		pw, err := t.schema.GetWriter(w, dynCols, sortInput)
+		pw, err := schema.GetWriter(w, dynCols, sortInput)
 		if err != nil {
 			return err
 		}
-		defer t.schema.PutWriter(pw)
+		defer schema.PutWriter(pw)
 		writer = pw
 	}
 
-	return pqarrow.RecordsToFile(t.schema, writer, records)
+	return pqarrow.RecordsToFile(schema, writer, records)
 }
 
 // distinctRecordsForCompaction performs a distinct on the given parts. If at
@@ -1471,7 +2569,7 @@ func (t *Table) writeRecordsToParquet(w io.Writer, records []arrow.Record, sortI
 // caller should fall back to normal compaction. On success, the caller is
 // responsible for releasing the returned records.
 func (t *Table) distinctRecordsForCompaction(compact []parts.Part) ([]arrow.Record, error) {
-	sortingCols := t.schema.ColumnDefinitionsForSortingColumns()
+	sortingCols := t.schema.Load().ColumnDefinitionsForSortingColumns()
 	columnExprs := make([]logicalplan.Expr, 0, len(sortingCols))
 	for _, col := range sortingCols {
 		var expr logicalplan.Expr
@@ -1483,7 +2581,7 @@ func (t *Table) distinctRecordsForCompaction(compact []parts.Part) ([]arrow.Reco
 		columnExprs = append(columnExprs, expr)
 	}
 
-	d := physicalplan.Distinct(memory.NewGoAllocator(), t.tracer, columnExprs)
+	d := physicalplan.Distinct(memory.NewGoAllocator(), t.tracer, columnExprs, 0, "")
 	output := physicalplan.OutputPlan{}
 	newRecords := make([]arrow.Record, 0)
 	output.SetNextCallback(func(_ context.Context, r arrow.Record) error {
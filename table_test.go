@@ -8,6 +8,7 @@ import (
 	"math"
 	"math/rand"
 	"runtime"
+	"strings"
 	"sync"
 	"sync/atomic"
 	"testing"
@@ -22,9 +23,12 @@ import (
 	"github.com/google/uuid"
 	"github.com/parquet-go/parquet-go"
 	"github.com/stretchr/testify/require"
+	"github.com/thanos-io/objstore"
+	"google.golang.org/protobuf/proto"
 
 	"github.com/polarsignals/frostdb/dynparquet"
 	schemapb "github.com/polarsignals/frostdb/gen/proto/go/frostdb/schema/v1alpha1"
+	tablepb "github.com/polarsignals/frostdb/gen/proto/go/frostdb/table/v1alpha1"
 	"github.com/polarsignals/frostdb/index"
 	"github.com/polarsignals/frostdb/pqarrow"
 	"github.com/polarsignals/frostdb/query"
@@ -73,6 +77,143 @@ func basicTable(t *testing.T, options ...Option) (*ColumnStore, *Table) {
 	return c, table
 }
 
+// openTestDB opens a column store with the given options and its "test"
+// database.
+func openTestDB(t testing.TB, options ...Option) (*ColumnStore, *DB) {
+	t.Helper()
+	c, err := New(append([]Option{WithLogger(newTestLogger(t))}, options...)...)
+	require.NoError(t, err)
+	db, err := c.DB(context.Background(), "test")
+	require.NoError(t, err)
+	return c, db
+}
+
+// openTestTable is like openTestDB, but also opens the "test" table of the
+// database with the sample schema and the given table options.
+func openTestTable(t testing.TB, options []Option, tableOptions ...TableOption) (*ColumnStore, *DB, *Table) {
+	t.Helper()
+	c, db := openTestDB(t, options...)
+	table, err := db.Table("test", NewTableConfig(dynparquet.SampleDefinition(), tableOptions...))
+	require.NoError(t, err)
+	return c, db, table
+}
+
+// insertSamples inserts the samples into the table and returns the
+// transaction they were inserted in.
+func insertSamples(t testing.TB, table *Table, samples dynparquet.Samples) uint64 {
+	t.Helper()
+	r, err := samples.ToRecord()
+	require.NoError(t, err)
+	defer r.Release()
+	tx, err := table.InsertRecord(context.Background(), r)
+	require.NoError(t, err)
+	return tx
+}
+
+// tryInsertSamples is like insertSamples, but returns the error of the
+// insert.
+func tryInsertSamples(t testing.TB, table *Table, samples dynparquet.Samples) error {
+	t.Helper()
+	r, err := samples.ToRecord()
+	require.NoError(t, err)
+	defer r.Release()
+	_, err = table.InsertRecord(context.Background(), r)
+	return err
+}
+
+// persistActiveBlock rotates the active block of the table and waits until it
+// is persisted.
+func persistActiveBlock(t testing.TB, table *Table) {
+	t.Helper()
+	var wg sync.WaitGroup
+	wg.Add(1)
+	require.NoError(t, table.RotateBlock(context.Background(), table.ActiveBlock(), WithRotateBlockWaitGroup(&wg)))
+	wg.Wait()
+}
+
+// rangeCountingBucket counts the range requests made with GetRange and the
+// bytes they read.
+type rangeCountingBucket struct {
+	objstore.Bucket
+
+	ranges    atomic.Int64
+	mtx       sync.Mutex
+	readBytes int64
+}
+
+func (b *rangeCountingBucket) GetRange(ctx context.Context, name string, off, length int64) (io.ReadCloser, error) {
+	b.ranges.Add(1)
+	b.mtx.Lock()
+	b.readBytes += length
+	b.mtx.Unlock()
+	return b.Bucket.GetRange(ctx, name, off, length)
+}
+
+// countRows returns the number of rows of the table, as read by a query.
+func countRows(t testing.TB, db *DB, table string) int64 {
+	t.Helper()
+	rows := int64(0)
+	require.NoError(t, query.NewEngine(memory.DefaultAllocator, db.TableProvider()).
+		ScanTable(table).
+		Execute(context.Background(), func(_ context.Context, r arrow.Record) error {
+			rows += r.NumRows()
+			return nil
+		}))
+	return rows
+}
+
+// countMatchingRows returns the number of rows of the table that match the
+// filter, as read by a query.
+func countMatchingRows(t testing.TB, db *DB, table string, filter logicalplan.Expr) int64 {
+	t.Helper()
+	rows := int64(0)
+	require.NoError(t, query.NewEngine(memory.DefaultAllocator, db.TableProvider()).
+		ScanTable(table).
+		Filter(filter).
+		Execute(context.Background(), func(_ context.Context, r arrow.Record) error {
+			rows += r.NumRows()
+			return nil
+		}))
+	return rows
+}
+
+// countRowsBy returns the number of rows of the table per value of the given
+// string column, as read by a query. Rows where the column is null are not
+// counted.
+func countRowsBy(t testing.TB, db *DB, table, column string) map[string]int {
+	t.Helper()
+	counts := map[string]int{}
+	require.NoError(t, query.NewEngine(memory.DefaultAllocator, db.TableProvider()).
+		ScanTable(table).
+		Execute(context.Background(), func(_ context.Context, r arrow.Record) error {
+			idx := r.Schema().FieldIndices(column)
+			require.Len(t, idx, 1)
+			col := r.Column(idx[0])
+			for i := 0; i < col.Len(); i++ {
+				if !col.IsNull(i) {
+					counts[stringValue(col, i)]++
+				}
+			}
+			return nil
+		}))
+	return counts
+}
+
+// stringValue returns the value at index i of a string, binary or dictionary
+// array as a string. Unlike ValueStr it does not base64 encode binary values.
+func stringValue(arr arrow.Array, i int) string {
+	switch a := arr.(type) {
+	case *array.String:
+		return a.Value(i)
+	case *array.Binary:
+		return a.ValueString(i)
+	case *array.Dictionary:
+		return stringValue(a.Dictionary(), a.GetValueIndex(i))
+	default:
+		return arr.ValueStr(i)
+	}
+}
+
 // This test issues concurrent writes to the database, and expects all of them to be recorded successfully.
 func Test_Table_Concurrency(t *testing.T) {
 	c, table := basicTable(t)
@@ -677,10 +818,10 @@ func Test_RowWriter(t *testing.T) {
 	defer c.Close()
 
 	b := &bytes.Buffer{}
-	pw, err := table.schema.GetWriter(b, map[string][]string{
+	pw, err := table.schema.Load().GetWriter(b, map[string][]string{
 		"labels": {"node"},
 	}, false)
-	defer table.schema.PutWriter(pw)
+	defer table.schema.Load().PutWriter(pw)
 	require.NoError(t, err)
 	rowWriter, err := table.ActiveBlock().rowWriter(pw)
 	require.NoError(t, err)
@@ -737,6 +878,364 @@ func Test_Table_Size(t *testing.T) {
 	require.Equal(t, util.TotalRecordSize(rec), after-before)
 }
 
+func Test_Table_RowCount(t *testing.T) {
+	c, table := basicTable(t)
+	defer c.Close()
+
+	ctx := context.Background()
+	engine := query.NewEngine(memory.NewGoAllocator(), table.db.TableProvider())
+	count := func(t *testing.T, filter logicalplan.Expr) int64 {
+		t.Helper()
+		b := engine.ScanTable("test")
+		if filter != nil {
+			b = b.Filter(filter)
+		}
+		n, err := b.Count(ctx)
+		require.NoError(t, err)
+		return n
+	}
+
+	require.Equal(t, int64(0), count(t, nil))
+
+	samples := dynparquet.GenerateTestSamples(5)
+	for i := 0; i < 2; i++ {
+		rec, err := samples.ToRecord()
+		require.NoError(t, err)
+		_, err = table.InsertRecord(ctx, rec)
+		rec.Release()
+		require.NoError(t, err)
+	}
+	total := int64(2 * len(samples))
+	require.Equal(t, total, count(t, nil))
+
+	// Compacted parts are counted from their row group metadata.
+	require.NoError(t, table.EnsureCompaction())
+	require.Equal(t, total, count(t, nil))
+
+	// Filtered counts are executed.
+	filter := logicalplan.Col("timestamp").Eq(logicalplan.Literal(samples[0].Timestamp))
+	require.Equal(t, int64(2), count(t, filter))
+
+	// Deleted rows are not counted.
+	require.NoError(t, table.Delete(ctx, filter))
+	require.Equal(t, total-2, count(t, nil))
+}
+
+func Test_Table_CountAggregation(t *testing.T) {
+	c, table := basicTable(t)
+	defer c.Close()
+
+	ctx := context.Background()
+	engine := query.NewEngine(memory.NewGoAllocator(), table.db.TableProvider())
+	countAgg := func(t *testing.T, filter logicalplan.Expr) []int64 {
+		t.Helper()
+		b := engine.ScanTable("test")
+		if filter != nil {
+			b = b.Filter(filter)
+		}
+		var counts []int64
+		require.NoError(t, b.
+			Aggregate([]*logicalplan.AggregationFunction{logicalplan.Count(logicalplan.Col("value"))}, nil).
+			Execute(ctx, func(_ context.Context, r arrow.Record) error {
+				require.Equal(t, 1, len(r.Schema().Fields()))
+				require.Equal(t, "count(value)", r.Schema().Field(0).Name)
+				counts = append(counts, r.Column(0).(*array.Int64).Int64Values()...)
+				return nil
+			}))
+		return counts
+	}
+
+	// Aggregations of empty tables don't produce any rows.
+	require.Empty(t, countAgg(t, nil))
+
+	samples := dynparquet.GenerateTestSamples(5)
+	for i := 0; i < 2; i++ {
+		rec, err := samples.ToRecord()
+		require.NoError(t, err)
+		_, err = table.InsertRecord(ctx, rec)
+		rec.Release()
+		require.NoError(t, err)
+	}
+	require.NoError(t, table.EnsureCompaction())
+
+	// scanned counts the rows returned by a scan, without aggregating them.
+	scanned := func(t *testing.T) int64 {
+		t.Helper()
+		var rows int64
+		require.NoError(t, engine.ScanTable("test").Execute(ctx, func(_ context.Context, r arrow.Record) error {
+			rows += r.NumRows()
+			return nil
+		}))
+		return rows
+	}
+	// Filtered counts cannot be answered from metadata, so the aggregation
+	// is executed.
+	all := logicalplan.Col("timestamp").GtEq(logicalplan.Literal(int64(0)))
+
+	// Unfiltered counts are answered from metadata and match the executed
+	// aggregation.
+	total := int64(2 * len(samples))
+	require.Equal(t, total, scanned(t))
+	require.Equal(t, []int64{total}, countAgg(t, nil))
+	require.Equal(t, []int64{total}, countAgg(t, all))
+
+	filter := logicalplan.Col("timestamp").Eq(logicalplan.Literal(samples[0].Timestamp))
+	require.NoError(t, table.Delete(ctx, filter))
+	require.Equal(t, total-2, scanned(t))
+	require.Equal(t, []int64{total - 2}, countAgg(t, nil))
+	require.Equal(t, []int64{total - 2}, countAgg(t, all))
+}
+
+func Test_Table_ColumnComparison(t *testing.T) {
+	c, table := basicTable(t)
+	defer c.Close()
+
+	ctx := context.Background()
+	samples := dynparquet.GenerateTestSamples(10)
+	for i := range samples {
+		// Every third sample exceeds its timestamp.
+		if i%3 == 0 {
+			samples[i].Value = samples[i].Timestamp + 1
+		}
+	}
+	rec, err := samples.ToRecord()
+	require.NoError(t, err)
+	_, err = table.InsertRecord(ctx, rec)
+	rec.Release()
+	require.NoError(t, err)
+
+	engine := query.NewEngine(memory.NewGoAllocator(), table.db.TableProvider())
+	count := func(t *testing.T, filter logicalplan.Expr) int64 {
+		t.Helper()
+		n, err := engine.ScanTable("test").Filter(filter).Count(ctx)
+		require.NoError(t, err)
+		return n
+	}
+
+	gt := logicalplan.Col("value").Gt(logicalplan.Col("timestamp"))
+	eq := logicalplan.Col("value").Eq(logicalplan.Col("timestamp"))
+	require.Equal(t, int64(4), count(t, gt))
+	require.Equal(t, int64(6), count(t, eq))
+	require.Equal(t, int64(0), count(t, logicalplan.Col("value").Lt(logicalplan.Col("timestamp"))))
+
+	// Compacted parts are pruned using the statistics of both columns.
+	require.NoError(t, table.EnsureCompaction())
+	require.Equal(t, int64(4), count(t, gt))
+	require.Equal(t, int64(6), count(t, eq))
+	require.Equal(t, int64(0), count(t, logicalplan.Col("value").Lt(logicalplan.Col("timestamp"))))
+}
+
+func Test_Table_IsNull(t *testing.T) {
+	c, table := basicTable(t)
+	defer c.Close()
+
+	ctx := context.Background()
+	rec, err := dynparquet.NewTestSamples().ToRecord()
+	require.NoError(t, err)
+	_, err = table.InsertRecord(ctx, rec)
+	rec.Release()
+	require.NoError(t, err)
+
+	engine := query.NewEngine(memory.NewGoAllocator(), table.db.TableProvider())
+	count := func(t *testing.T, filter logicalplan.Expr) int64 {
+		t.Helper()
+		n, err := engine.ScanTable("test").Filter(filter).Count(ctx)
+		require.NoError(t, err)
+		return n
+	}
+
+	check := func(t *testing.T) {
+		require.Equal(t, int64(1), count(t, logicalplan.Col("labels.namespace").IsNull()))
+		require.Equal(t, int64(2), count(t, logicalplan.Col("labels.namespace").IsNotNull()))
+		require.Equal(t, int64(2), count(t, logicalplan.Col("labels.pod").IsNull()))
+		// Columns that don't exist are null in every row.
+		require.Equal(t, int64(3), count(t, logicalplan.Col("labels.missing").IsNull()))
+		require.Equal(t, int64(0), count(t, logicalplan.Col("labels.missing").IsNotNull()))
+		require.Equal(t, int64(1), count(t, logicalplan.And(
+			logicalplan.Col("labels.namespace").IsNotNull(),
+			logicalplan.Col("labels.pod").IsNull(),
+		)))
+	}
+
+	check(t)
+	require.NoError(t, table.EnsureCompaction())
+	check(t)
+}
+
+func Test_Table_StorageLayoutTypes(t *testing.T) {
+	schema := &schemapb.Schema{
+		Name: "types",
+		Columns: []*schemapb.Column{{
+			Name: "name",
+			StorageLayout: &schemapb.StorageLayout{
+				Type: schemapb.StorageLayout_TYPE_STRING,
+			},
+		}, {
+			Name: "count",
+			StorageLayout: &schemapb.StorageLayout{
+				Type: schemapb.StorageLayout_TYPE_INT32,
+			},
+		}, {
+			Name: "total",
+			StorageLayout: &schemapb.StorageLayout{
+				Type: schemapb.StorageLayout_TYPE_UINT64,
+			},
+		}, {
+			Name: "timestamp",
+			StorageLayout: &schemapb.StorageLayout{
+				Type: schemapb.StorageLayout_TYPE_TIMESTAMP_MILLIS,
+			},
+		}},
+		SortingColumns: []*schemapb.SortingColumn{{
+			Name:      "name",
+			Direction: schemapb.SortingColumn_DIRECTION_ASCENDING,
+		}},
+	}
+
+	c, err := New(WithLogger(newTestLogger(t)))
+	require.NoError(t, err)
+	defer c.Close()
+	db, err := c.DB(context.Background(), "test")
+	require.NoError(t, err)
+	table, err := db.Table("test", NewTableConfig(schema))
+	require.NoError(t, err)
+
+	// Timestamps are converted to the unit of their column.
+	mem := memory.NewGoAllocator()
+	bldr := array.NewRecordBuilder(mem, arrow.NewSchema([]arrow.Field{
+		{Name: "name", Type: arrow.BinaryTypes.String},
+		{Name: "count", Type: arrow.PrimitiveTypes.Int32},
+		{Name: "total", Type: arrow.PrimitiveTypes.Uint64},
+		{Name: "timestamp", Type: &arrow.TimestampType{Unit: arrow.Nanosecond, TimeZone: "UTC"}},
+	}, nil))
+	defer bldr.Release()
+	bldr.Field(0).(*array.StringBuilder).AppendValues([]string{"a", "b", "c"}, nil)
+	bldr.Field(1).(*array.Int32Builder).AppendValues([]int32{-1, 2, math.MaxInt32}, nil)
+	bldr.Field(2).(*array.Uint64Builder).AppendValues([]uint64{1, 2, math.MaxUint64}, nil)
+	bldr.Field(3).(*array.TimestampBuilder).AppendValues([]arrow.Timestamp{1e9, 2e9, 3e9}, nil)
+	rec := bldr.NewRecord()
+	defer rec.Release()
+
+	ctx := context.Background()
+	_, err = table.InsertRecord(ctx, rec)
+	require.NoError(t, err)
+
+	type row struct {
+		Name      string
+		Count     int32
+		Total     uint64
+		Timestamp time.Time
+	}
+	engine := query.NewEngine(mem, db.TableProvider())
+	check := func(t *testing.T) {
+		t.Helper()
+		rows, err := query.Rows[row](ctx, engine.ScanTable("test").
+			Filter(logicalplan.Col("timestamp").GtEq(logicalplan.Literal(time.Unix(2, 0)))).
+			Project(logicalplan.Col("name"), logicalplan.Col("count"), logicalplan.Col("total"), logicalplan.Col("timestamp")))
+		require.NoError(t, err)
+		require.Equal(t, []row{
+			{Name: "b", Count: 2, Total: 2, Timestamp: time.Unix(2, 0).UTC()},
+			{Name: "c", Count: math.MaxInt32, Total: math.MaxUint64, Timestamp: time.Unix(3, 0).UTC()},
+		}, rows)
+
+		// Timestamps that are not representable in the unit of the column
+		// don't prune any data.
+		n, err := engine.ScanTable("test").
+			Filter(logicalplan.Col("timestamp").Gt(logicalplan.Literal(time.Unix(3, 1)))).
+			Count(ctx)
+		require.NoError(t, err)
+		require.Equal(t, int64(0), n)
+	}
+
+	check(t)
+	require.NoError(t, table.EnsureCompaction())
+	check(t)
+}
+
+func Test_Table_DictionaryEncoding(t *testing.T) {
+	schema := &schemapb.Schema{
+		Name: "dictionary",
+		Columns: []*schemapb.Column{{
+			Name: "name",
+			StorageLayout: &schemapb.StorageLayout{
+				Type: schemapb.StorageLayout_TYPE_STRING,
+			},
+		}, {
+			Name: "value",
+			StorageLayout: &schemapb.StorageLayout{
+				Type: schemapb.StorageLayout_TYPE_INT64,
+			},
+		}},
+		SortingColumns: []*schemapb.SortingColumn{{
+			Name:      "name",
+			Direction: schemapb.SortingColumn_DIRECTION_ASCENDING,
+		}},
+	}
+
+	c, err := New(WithLogger(newTestLogger(t)))
+	require.NoError(t, err)
+	defer c.Close()
+	db, err := c.DB(context.Background(), "test")
+	require.NoError(t, err)
+	table, err := db.Table("test", NewTableConfig(schema))
+	require.NoError(t, err)
+
+	mem := memory.NewGoAllocator()
+	bldr := array.NewRecordBuilder(mem, arrow.NewSchema([]arrow.Field{
+		{Name: "name", Type: arrow.BinaryTypes.String},
+		{Name: "value", Type: arrow.PrimitiveTypes.Int64},
+	}, nil))
+	defer bldr.Release()
+	bldr.Field(0).(*array.StringBuilder).AppendValues([]string{"a", "a", "b", "b", "b"}, nil)
+	bldr.Field(1).(*array.Int64Builder).AppendValues([]int64{1, 2, 3, 4, 5}, nil)
+	rec := bldr.NewRecord()
+	defer rec.Release()
+
+	ctx := context.Background()
+	_, err = table.InsertRecord(ctx, rec)
+	require.NoError(t, err)
+
+	engine := query.NewEngine(mem, db.TableProvider(), query.WithDictionaryEncoding())
+	check := func(t *testing.T) {
+		t.Helper()
+		rows := 0
+		require.NoError(t, engine.ScanTable("test").Execute(ctx, func(_ context.Context, r arrow.Record) error {
+			rows += int(r.NumRows())
+			require.IsType(t, &arrow.DictionaryType{}, r.Schema().Field(r.Schema().FieldIndices("name")[0]).Type)
+			return nil
+		}))
+		require.Equal(t, 5, rows)
+
+		sums := map[string]int64{}
+		require.NoError(t, engine.ScanTable("test").
+			Aggregate(
+				[]*logicalplan.AggregationFunction{logicalplan.Sum(logicalplan.Col("value"))},
+				[]logicalplan.Expr{logicalplan.Col("name")},
+			).
+			Execute(ctx, func(_ context.Context, r arrow.Record) error {
+				names, ok := r.Column(r.Schema().FieldIndices("name")[0]).(*array.Dictionary)
+				require.True(t, ok, "group by column is not dictionary encoded")
+				values := r.Column(r.Schema().FieldIndices("sum(value)")[0]).(*array.Int64)
+				for i := 0; i < int(r.NumRows()); i++ {
+					var name string
+					switch dict := names.Dictionary().(type) {
+					case *array.Binary:
+						name = string(dict.Value(names.GetValueIndex(i)))
+					case *array.String:
+						name = dict.Value(names.GetValueIndex(i))
+					}
+					sums[name] = values.Value(i)
+				}
+				return nil
+			}))
+		require.Equal(t, map[string]int64{"a": 3, "b": 12}, sums)
+	}
+
+	check(t)
+	require.NoError(t, table.EnsureCompaction())
+	check(t)
+}
+
 func Test_Insert_Repeated(t *testing.T) {
 	schema := &schemapb.Schema{
 		Name: "repeated",
@@ -1097,6 +1596,76 @@ func TestTableUniquePrimaryIndex(t *testing.T) {
 	require.Equal(t, 1, rowsRead)
 }
 
+func Test_Table_UnsortedDynamicColumns(t *testing.T) {
+	ctx := context.Background()
+	c, err := New(
+		WithLogger(newTestLogger(t)),
+		WithIndexConfig([]*index.LevelConfig{
+			{Level: index.L0, MaxSize: 180, Type: index.CompactionTypeParquetMemory},
+			{Level: index.L1, MaxSize: 1 * TiB},
+		}),
+	)
+	require.NoError(t, err)
+	defer c.Close()
+
+	db, err := c.DB(ctx, "test")
+	require.NoError(t, err)
+
+	_, err = db.Table("unique", NewTableConfig(
+		dynparquet.SampleDefinition(),
+		WithUnsortedDynamicColumns(),
+		WithUniquePrimaryIndex(true),
+	))
+	require.Error(t, err)
+
+	table, err := db.Table("test", NewTableConfig(dynparquet.SampleDefinition(), WithUnsortedDynamicColumns()))
+	require.NoError(t, err)
+
+	sortingColumns := []string{}
+	for _, col := range table.Schema().SortingColumns() {
+		sortingColumns = append(sortingColumns, col.ColumnName())
+	}
+	require.Equal(t, []string{"example_type", "timestamp", "stacktrace"}, sortingColumns)
+
+	for i := 0; i < 3; i++ {
+		samples := dynparquet.Samples{{
+			ExampleType: "test",
+			Labels:      map[string]string{"label1": "b", fmt.Sprintf("label%d", i+2): "value"},
+			Timestamp:   int64(10 - i),
+			Value:       1,
+		}, {
+			ExampleType: "test",
+			Labels:      map[string]string{"label1": "a"},
+			Timestamp:   int64(10 - i),
+			Value:       2,
+		}}
+		r, err := samples.ToRecord()
+		require.NoError(t, err)
+		_, err = table.InsertRecord(ctx, r)
+		r.Release()
+		require.NoError(t, err)
+	}
+	require.NoError(t, table.EnsureCompaction())
+
+	timestamps := []int64{}
+	labels := []string{}
+	require.NoError(t, query.NewEngine(memory.DefaultAllocator, db.TableProvider()).
+		ScanTable("test").
+		Filter(logicalplan.Col("labels.label1").Eq(logicalplan.Literal("a"))).
+		Project(logicalplan.Col("timestamp"), logicalplan.Col("labels.label1")).
+		Execute(ctx, func(_ context.Context, r arrow.Record) error {
+			ts := r.Column(r.Schema().FieldIndices("timestamp")[0]).(*array.Int64)
+			label := r.Column(r.Schema().FieldIndices("labels.label1")[0])
+			for i := 0; i < int(r.NumRows()); i++ {
+				timestamps = append(timestamps, ts.Value(i))
+				labels = append(labels, stringValue(label, i))
+			}
+			return nil
+		}))
+	require.ElementsMatch(t, []int64{8, 9, 10}, timestamps)
+	require.Equal(t, []string{"a", "a", "a"}, labels)
+}
+
 func TestTable_write_ptr_struct(t *testing.T) {
 	columnstore, err := New()
 	require.Nil(t, err)
@@ -1218,3 +1787,412 @@ func Test_Issue741_Deadlock(t *testing.T) {
 	// This releases all the parts and waits for all reads to finish accessing the parts. This was causing a deadlock.
 	table.active.index.Close()
 }
+
+func Test_Table_DynamicColumnLimit(t *testing.T) {
+	samples := dynparquet.Samples{{
+		ExampleType: "test",
+		Labels: map[string]string{
+			"label1": "value1",
+			"label2": "value2",
+		},
+		Timestamp: 1,
+		Value:     1,
+	}, {
+		ExampleType: "test",
+		Labels: map[string]string{
+			"label1": "value1",
+			"label3": "value3",
+			"label4": "value4",
+		},
+		Timestamp: 2,
+		Value:     2,
+	}}
+
+	t.Run("Error", func(t *testing.T) {
+		c, table := basicTable(t, WithDynamicColumnLimit(2, dynparquet.DynamicColumnLimitError))
+		t.Cleanup(func() { c.Close() })
+
+		r, err := samples.ToRecord()
+		require.NoError(t, err)
+		defer r.Release()
+
+		_, err = table.InsertRecord(context.Background(), r)
+		var limitErr dynparquet.ErrTooManyDynamicColumns
+		require.ErrorAs(t, err, &limitErr)
+		require.Equal(t, 4, limitErr.Columns)
+		require.Equal(t, 2, limitErr.Limit)
+	})
+
+	t.Run("AcrossBlocks", func(t *testing.T) {
+		c, table := basicTable(t, WithDynamicColumnLimit(2, dynparquet.DynamicColumnLimitError))
+		t.Cleanup(func() { c.Close() })
+
+		ctx := context.Background()
+		r, err := samples[:1].ToRecord()
+		require.NoError(t, err)
+		defer r.Release()
+		_, err = table.InsertRecord(ctx, r)
+		require.NoError(t, err)
+
+		// The limit applies to the table, so rotating the block doesn't make
+		// room for new columns.
+		require.NoError(t, table.RotateBlock(ctx, table.ActiveBlock()))
+
+		r, err = samples[1:].ToRecord()
+		require.NoError(t, err)
+		defer r.Release()
+		_, err = table.InsertRecord(ctx, r)
+		var limitErr dynparquet.ErrTooManyDynamicColumns
+		require.ErrorAs(t, err, &limitErr)
+		require.Equal(t, 4, limitErr.Columns)
+
+		// Columns already seen are still accepted, and the rejected record
+		// did not use up the limit.
+		r, err = samples[:1].ToRecord()
+		require.NoError(t, err)
+		defer r.Release()
+		_, err = table.InsertRecord(ctx, r)
+		require.NoError(t, err)
+	})
+
+	t.Run("RejectedInsert", func(t *testing.T) {
+		c, err := New(
+			WithLogger(newTestLogger(t)),
+			WithDynamicColumnLimit(3, dynparquet.DynamicColumnLimitError),
+		)
+		require.NoError(t, err)
+		t.Cleanup(func() { c.Close() })
+		db, err := c.DB(context.Background(), "test")
+		require.NoError(t, err)
+		table, err := db.Table("test", NewTableConfig(
+			dynparquet.SampleDefinition(),
+			WithMonotonicTimestamps(10, MonotonicTimestampsReject),
+		))
+		require.NoError(t, err)
+
+		ctx := context.Background()
+		insert := func(samples dynparquet.Samples) error {
+			r, err := samples.ToRecord()
+			require.NoError(t, err)
+			defer r.Release()
+			_, err = table.InsertRecord(ctx, r)
+			return err
+		}
+		require.NoError(t, insert(samples[:1]))
+
+		// The insert is rejected after its columns were checked against the
+		// limit, since the first row is older than the row inserted before.
+		err = insert(dynparquet.Samples{{
+			ExampleType: "test",
+			Labels:      samples[0].Labels,
+			Timestamp:   0,
+		}, {
+			ExampleType: "test",
+			Labels:      map[string]string{"label3": "value3"},
+			Timestamp:   2,
+		}})
+		require.ErrorAs(t, err, &ErrNonMonotonicTimestamp{})
+
+		// The columns of the rejected insert did not use up the limit.
+		require.NoError(t, insert(dynparquet.Samples{{
+			ExampleType: "test",
+			Labels:      map[string]string{"label5": "value5"},
+			Timestamp:   3,
+		}}))
+		err = insert(dynparquet.Samples{{
+			ExampleType: "test",
+			Labels:      map[string]string{"label6": "value6"},
+			Timestamp:   4,
+		}})
+		var limitErr dynparquet.ErrTooManyDynamicColumns
+		require.ErrorAs(t, err, &limitErr)
+		require.Equal(t, 4, limitErr.Columns)
+	})
+
+	t.Run("Restart", func(t *testing.T) {
+		bucket := NewDefaultObjstoreBucket(objstore.NewInMemBucket())
+		open := func() (*ColumnStore, *Table) {
+			c, err := New(
+				WithLogger(newTestLogger(t)),
+				WithReadWriteStorage(bucket),
+				WithDynamicColumnLimit(2, dynparquet.DynamicColumnLimitError),
+			)
+			require.NoError(t, err)
+			db, err := c.DB(context.Background(), "test")
+			require.NoError(t, err)
+			table, err := db.Table("test", NewTableConfig(dynparquet.SampleDefinition()))
+			require.NoError(t, err)
+			return c, table
+		}
+
+		ctx := context.Background()
+		c, table := open()
+		r, err := samples[:1].ToRecord()
+		require.NoError(t, err)
+		defer r.Release()
+		_, err = table.InsertRecord(ctx, r)
+		require.NoError(t, err)
+		// Closing persists the block.
+		require.NoError(t, c.Close())
+
+		// The columns of the persisted block count towards the limit of the
+		// reopened table.
+		c, table = open()
+		t.Cleanup(func() { c.Close() })
+		r, err = samples[1:].ToRecord()
+		require.NoError(t, err)
+		defer r.Release()
+		_, err = table.InsertRecord(ctx, r)
+		var limitErr dynparquet.ErrTooManyDynamicColumns
+		require.ErrorAs(t, err, &limitErr)
+		require.Equal(t, 4, limitErr.Columns)
+	})
+
+	t.Run("Overflow", func(t *testing.T) {
+		c, table := basicTable(t, WithDynamicColumnLimit(2, dynparquet.DynamicColumnLimitOverflow))
+		t.Cleanup(func() { c.Close() })
+
+		r, err := samples.ToRecord()
+		require.NoError(t, err)
+		defer r.Release()
+
+		ctx := context.Background()
+		_, err = table.InsertRecord(ctx, r)
+		require.NoError(t, err)
+
+		overflow := map[string]string{}
+		err = table.View(ctx, func(ctx context.Context, tx uint64) error {
+			return table.Iterator(
+				ctx,
+				tx,
+				memory.NewGoAllocator(),
+				[]logicalplan.Callback{func(_ context.Context, ar arrow.Record) error {
+					for i, f := range ar.Schema().Fields() {
+						require.NotEqual(t, "labels.label3", f.Name)
+						require.NotEqual(t, "labels.label4", f.Name)
+						if f.Name != "labels."+dynparquet.OverflowColumnName {
+							continue
+						}
+						col := ar.Column(i)
+						for j := 0; j < col.Len(); j++ {
+							if col.IsNull(j) {
+								continue
+							}
+							values, err := dynparquet.DecodeOverflow(col.ValueStr(j))
+							require.NoError(t, err)
+							for k, v := range values {
+								overflow[k] = v
+							}
+						}
+					}
+					return nil
+				}},
+			)
+		})
+		require.NoError(t, err)
+		require.Equal(t, map[string]string{"label3": "value3", "label4": "value4"}, overflow)
+	})
+
+	t.Run("OverflowRejected", func(t *testing.T) {
+		c, err := New(
+			WithLogger(newTestLogger(t)),
+			WithDynamicColumnLimit(2, dynparquet.DynamicColumnLimitOverflow),
+		)
+		require.NoError(t, err)
+		t.Cleanup(func() { c.Close() })
+		db, err := c.DB(context.Background(), "test")
+		require.NoError(t, err)
+		table, err := db.Table("test", NewTableConfig(&schemapb.Schema{
+			Name: "test",
+			Columns: []*schemapb.Column{{
+				Name: "labels",
+				StorageLayout: &schemapb.StorageLayout{
+					Type:     schemapb.StorageLayout_TYPE_STRING,
+					Nullable: true,
+				},
+				Dynamic: true,
+			}, {
+				Name: "nums",
+				StorageLayout: &schemapb.StorageLayout{
+					Type:     schemapb.StorageLayout_TYPE_INT64,
+					Nullable: true,
+				},
+				Dynamic: true,
+			}, {
+				Name: "timestamp",
+				StorageLayout: &schemapb.StorageLayout{
+					Type: schemapb.StorageLayout_TYPE_INT64,
+				},
+			}},
+			SortingColumns: []*schemapb.SortingColumn{{
+				Name:      "timestamp",
+				Direction: schemapb.SortingColumn_DIRECTION_ASCENDING,
+			}},
+		}))
+		require.NoError(t, err)
+
+		newRecord := func(names ...string) arrow.Record {
+			fields := make([]arrow.Field, 0, len(names)+1)
+			for _, name := range names {
+				typ := arrow.DataType(arrow.BinaryTypes.String)
+				if strings.HasPrefix(name, "nums.") {
+					typ = arrow.PrimitiveTypes.Int64
+				}
+				fields = append(fields, arrow.Field{Name: name, Type: typ, Nullable: true})
+			}
+			fields = append(fields, arrow.Field{Name: "timestamp", Type: arrow.PrimitiveTypes.Int64})
+			b := array.NewRecordBuilder(memory.DefaultAllocator, arrow.NewSchema(fields, nil))
+			defer b.Release()
+			for i, f := range b.Fields() {
+				switch f := f.(type) {
+				case *array.StringBuilder:
+					f.Append("value")
+				case *array.Int64Builder:
+					f.Append(int64(i))
+				}
+			}
+			return b.NewRecord()
+		}
+
+		// The integer column cannot be folded into the overflow column, so
+		// the record is rejected.
+		r := newRecord("labels.a", "labels.b", "nums.x")
+		defer r.Release()
+		_, err = table.InsertRecord(context.Background(), r)
+		var limitErr dynparquet.ErrTooManyDynamicColumns
+		require.ErrorAs(t, err, &limitErr)
+
+		// The rejected record did not use up the limit.
+		r = newRecord("nums.x", "nums.y")
+		defer r.Release()
+		_, err = table.InsertRecord(context.Background(), r)
+		require.NoError(t, err)
+	})
+
+	t.Run("OverflowBinary", func(t *testing.T) {
+		c, table := basicTable(t, WithDynamicColumnLimit(1, dynparquet.DynamicColumnLimitOverflow))
+		t.Cleanup(func() { c.Close() })
+
+		// The values of the dynamic column are inserted as binary.
+		fields := []arrow.Field{
+			{Name: "example_type", Type: arrow.BinaryTypes.Binary},
+			{Name: "labels.a", Type: arrow.BinaryTypes.Binary, Nullable: true},
+			{Name: "labels.b", Type: arrow.BinaryTypes.Binary, Nullable: true},
+			{Name: "labels.c", Type: arrow.BinaryTypes.Binary, Nullable: true},
+			{Name: "stacktrace", Type: arrow.BinaryTypes.Binary},
+			{Name: "timestamp", Type: arrow.PrimitiveTypes.Int64},
+			{Name: "value", Type: arrow.PrimitiveTypes.Int64},
+		}
+		b := array.NewRecordBuilder(memory.DefaultAllocator, arrow.NewSchema(fields, nil))
+		defer b.Release()
+		for _, f := range b.Fields() {
+			switch f := f.(type) {
+			case *array.BinaryBuilder:
+				f.Append([]byte("value"))
+			case *array.Int64Builder:
+				f.Append(1)
+			}
+		}
+		r := b.NewRecord()
+		defer r.Release()
+
+		ctx := context.Background()
+		_, err := table.InsertRecord(ctx, r)
+		require.NoError(t, err)
+
+		overflow := map[string]string{}
+		err = table.View(ctx, func(ctx context.Context, tx uint64) error {
+			return table.Iterator(ctx, tx, memory.NewGoAllocator(), []logicalplan.Callback{
+				func(_ context.Context, ar arrow.Record) error {
+					for i, f := range ar.Schema().Fields() {
+						if f.Name != "labels."+dynparquet.OverflowColumnName {
+							continue
+						}
+						// The overflow column has the type of the dynamic
+						// column's values.
+						col, ok := ar.Column(i).(*array.Binary)
+						require.True(t, ok)
+						for j := 0; j < col.Len(); j++ {
+							values, err := dynparquet.DecodeOverflow(string(col.Value(j)))
+							require.NoError(t, err)
+							for k, v := range values {
+								overflow[k] = v
+							}
+						}
+					}
+					return nil
+				},
+			})
+		})
+		require.NoError(t, err)
+		require.Equal(t, map[string]string{"b": "value", "c": "value"}, overflow)
+	})
+}
+
+func Test_Table_InsertChunking(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	config := NewTableConfig(dynparquet.SampleDefinition())
+	open := func() (*ColumnStore, *Table) {
+		c, err := New(
+			WithLogger(newTestLogger(t)),
+			WithWAL(),
+			WithStoragePath(dir),
+			// Every row exceeds the chunk size, so each becomes its own part.
+			WithMaxInsertChunkSize(1),
+		)
+		require.NoError(t, err)
+		db, err := c.DB(ctx, "test")
+		require.NoError(t, err)
+		table, err := db.Table("test", config)
+		require.NoError(t, err)
+		return c, table
+	}
+
+	c, table := open()
+	r, err := dynparquet.GenerateTestSamples(10).ToRecord()
+	require.NoError(t, err)
+	defer r.Release()
+	tx, err := table.InsertRecord(ctx, r)
+	require.NoError(t, err)
+	table.db.Wait(tx)
+
+	countRows := func(table *Table) int64 {
+		rows := int64(0)
+		require.NoError(t, table.View(ctx, func(ctx context.Context, tx uint64) error {
+			return table.Iterator(ctx, tx, memory.NewGoAllocator(), []logicalplan.Callback{
+				func(_ context.Context, r arrow.Record) error {
+					rows += r.NumRows()
+					return nil
+				},
+			})
+		}))
+		return rows
+	}
+	require.Equal(t, 10, table.ActiveBlock().index.Stats()[index.L0].Parts)
+	require.Equal(t, int64(10), countRows(table))
+	require.NoError(t, c.Close())
+
+	// The chunks were logged as a single WAL entry and are replayed as
+	// separate parts of the same transaction.
+	c, table = open()
+	defer c.Close()
+	require.Equal(t, 10, table.ActiveBlock().index.Stats()[index.L0].Parts)
+	require.Equal(t, int64(10), countRows(table))
+}
+
+func Test_Table_V1Alpha1DualReadConfig(t *testing.T) {
+	config := NewTableConfig(
+		dynparquet.SampleDefinition(),
+		WithV1Alpha1DualRead(dynparquet.FieldMapping{"example_type": "type.example"}),
+	)
+	require.True(t, config.V1Alpha1DualRead)
+
+	// The dual-read settings are persisted with the table config.
+	b, err := config.MarshalVT()
+	require.NoError(t, err)
+	unmarshaled := &tablepb.TableConfig{}
+	require.NoError(t, unmarshaled.UnmarshalVT(b))
+	require.True(t, proto.Equal(config, unmarshaled))
+	require.Equal(t, "type.example", dynparquet.FieldMapping(unmarshaled.V1Alpha1FieldMapping).Path("example_type"))
+}
@@ -0,0 +1,179 @@
+package frostdb
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"sync"
+
+	"github.com/go-kit/log/level"
+
+	walpb "github.com/polarsignals/frostdb/gen/proto/go/frostdb/wal/v1alpha1"
+)
+
+// BlockPurger is implemented by data sinks that can delete all persisted
+// blocks of a table.
+type BlockPurger interface {
+	// PurgeBlocks deletes all blocks under prefix. It returns the number of
+	// deleted blocks.
+	PurgeBlocks(ctx context.Context, prefix string) (int, error)
+}
+
+// TruncateOption is an option for Table.Truncate.
+type TruncateOption func(*truncateOptions)
+
+type truncateOptions struct {
+	purgeBlocks bool
+}
+
+// WithTruncatePersistedBlocks also deletes the persisted blocks of the table
+// from all data sinks implementing BlockPurger. Without it, only the table's
+// in-memory data is discarded.
+func WithTruncatePersistedBlocks() TruncateOption {
+	return func(o *truncateOptions) {
+		o.purgeBlocks = true
+	}
+}
+
+// Truncate discards all in-memory data of the table, i.e. its active and
+// pending blocks, without persisting it. The active block is replaced by an
+// empty block and the truncation is recorded in the WAL, so that the
+// discarded writes are not replayed and standbys truncate the table as well,
+// and a snapshot is taken if snapshots are enabled. Truncate is atomic with
+// respect to concurrent inserts: inserts already writing to the active block
+// are discarded with it, and later inserts wait until the truncation is
+// logged and are kept. Persisted blocks are only deleted with
+// WithTruncatePersistedBlocks.
+func (t *Table) Truncate(ctx context.Context, options ...TruncateOption) error {
+	if t.db.columnStore.readOnly {
+		return ErrReadOnly
+	}
+	if err := t.db.Quarantined(); err != nil {
+		return err
+	}
+	opts := &truncateOptions{}
+	for _, o := range options {
+		o(opts)
+	}
+
+	// Pending blocks are hidden from queries immediately and waited for
+	// before the active block is rotated, so that their persistence is
+	// recorded in the WAL before the truncation. Writing a block locks the
+	// table, so they are waited for without holding the lock, until no block
+	// was rotated in the meantime.
+	for {
+		if err := waitForBlocks(ctx, t.discardPendingBlocks()); err != nil {
+			return err
+		}
+		t.mtx.Lock()
+		if len(t.pendingBlocks) == 0 {
+			break
+		}
+		t.mtx.Unlock()
+	}
+
+	// The table stays locked until the truncation is logged, so that no
+	// insert begins in between. Inserts already writing to the active block
+	// do not need the lock to finish, and are discarded with the block.
+	var wg sync.WaitGroup
+	wg.Add(1)
+	if err := func() error {
+		defer t.mtx.Unlock()
+		t.active.pendingWritersWg.Wait()
+		if err := t.rotateActiveBlockLocked(WithRotateBlockSkipPersist(), WithRotateBlockWaitGroup(&wg)); err != nil {
+			wg.Done()
+			return fmt.Errorf("rotate active block: %w", err)
+		}
+		if err := t.logTruncate(opts.purgeBlocks); err != nil {
+			return fmt.Errorf("log truncate: %w", err)
+		}
+		return nil
+	}(); err != nil {
+		return err
+	}
+	wg.Wait()
+	t.dataVersion.Add(1)
+	level.Debug(t.logger).Log("msg", "truncated table", "table", t.name)
+
+	if !opts.purgeBlocks {
+		return nil
+	}
+	defer t.dataVersion.Add(1)
+	return t.purgeBlocks(ctx)
+}
+
+// logTruncate logs the truncation of the table to the WAL in a new
+// transaction.
+func (t *Table) logTruncate(purgeBlocks bool) error {
+	tx, _, commit := t.db.begin(t.name)
+	defer commit()
+	return t.wal.Log(tx, &walpb.Record{
+		Entry: &walpb.Entry{
+			EntryType: &walpb.Entry_Truncate_{
+				Truncate: &walpb.Entry_Truncate{
+					TableName:   t.name,
+					PurgeBlocks: purgeBlocks,
+				},
+			},
+		},
+	})
+}
+
+// discardPendingBlocks removes the pending blocks of the table, so that they
+// are neither read nor persisted, and returns them.
+func (t *Table) discardPendingBlocks() []*TableBlock {
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+	return t.discardPendingBlocksLocked()
+}
+
+// discardPendingBlocksLocked is like discardPendingBlocks, but t.mtx must be
+// held.
+func (t *Table) discardPendingBlocksLocked() []*TableBlock {
+	blocks := make([]*TableBlock, 0, len(t.pendingBlocks))
+	for block := range t.pendingBlocks {
+		block.discarded.Store(true)
+		delete(t.pendingBlocks, block)
+		blocks = append(blocks, block)
+	}
+	return blocks
+}
+
+// waitForBlocks waits until the given blocks were written. Blocks that were
+// already being persisted when they were discarded finish persisting.
+func waitForBlocks(ctx context.Context, blocks []*TableBlock) error {
+	for _, block := range blocks {
+		select {
+		case <-block.written:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return nil
+}
+
+// purgeBlocks deletes all persisted blocks of the table from the data sinks
+// implementing BlockPurger.
+func (t *Table) purgeBlocks(ctx context.Context) error {
+	prefix := filepath.Join(t.db.name, t.name)
+	purged := false
+	for _, sink := range t.db.sinks {
+		purger, ok := sink.(BlockPurger)
+		if !ok {
+			continue
+		}
+		n, err := purger.PurgeBlocks(ctx, prefix)
+		if err != nil {
+			return fmt.Errorf("purge blocks from %s: %w", sink, err)
+		}
+		if n > 0 {
+			level.Debug(t.logger).Log("msg", "purged blocks", "table", t.name, "sink", sink.String(), "n", n)
+			purged = true
+		}
+	}
+	if purged {
+		t.db.blockSchemas.invalidate(t.name)
+		return t.RefreshStorageUsage(ctx)
+	}
+	return nil
+}
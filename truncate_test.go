@@ -0,0 +1,104 @@
+package frostdb
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/thanos-io/objstore"
+
+	"github.com/polarsignals/frostdb/dynparquet"
+)
+
+func TestTableTruncate(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("WAL", func(t *testing.T) {
+		options := []Option{WithWAL(), WithStoragePath(t.TempDir())}
+		c, db, table := openTestTable(t, options)
+		insertSamples(t, table, dynparquet.GenerateTestSamples(10))
+		require.Equal(t, int64(10), countRows(t, db, "test"))
+		require.NoError(t, table.Truncate(ctx))
+		require.Equal(t, int64(0), countRows(t, db, "test"))
+		insertSamples(t, table, dynparquet.GenerateTestSamples(3))
+		require.Equal(t, int64(3), countRows(t, db, "test"))
+		require.NoError(t, c.Close())
+
+		// Writes before the truncation are not replayed.
+		c, db, _ = openTestTable(t, options)
+		defer c.Close()
+		require.Equal(t, int64(3), countRows(t, db, "test"))
+	})
+
+	t.Run("ConcurrentInserts", func(t *testing.T) {
+		options := []Option{WithWAL(), WithStoragePath(t.TempDir())}
+		// Checking the quota locks the table, so inserts contend with Truncate
+		// rotating the active block.
+		quota := WithQuota(Quota{ActiveMemoryBytes: 1 << 40})
+		c, db, table := openTestTable(t, options, quota)
+
+		r, err := dynparquet.GenerateTestSamples(1).ToRecord()
+		require.NoError(t, err)
+		defer r.Release()
+		var wg sync.WaitGroup
+		stop := make(chan struct{})
+		for i := 0; i < 8; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for {
+					select {
+					case <-stop:
+						return
+					default:
+					}
+					if _, err := table.InsertRecord(ctx, r); err != nil {
+						t.Error(err)
+						return
+					}
+				}
+			}()
+		}
+		for i := 0; i < 50; i++ {
+			require.NoError(t, table.Truncate(ctx))
+		}
+		close(stop)
+		wg.Wait()
+		// Wait for all inserts to be visible.
+		db.Wait(db.tx.Load())
+		rows := countRows(t, db, "test")
+		require.NoError(t, c.Close())
+
+		// Every insert was either truncated or kept, both in memory and in
+		// the WAL.
+		c, db, _ = openTestTable(t, options, quota)
+		defer c.Close()
+		require.Equal(t, rows, countRows(t, db, "test"))
+	})
+
+	t.Run("PersistedBlocks", func(t *testing.T) {
+		bucket := objstore.NewInMemBucket()
+		c, db, table := openTestTable(t, []Option{WithReadWriteStorage(NewDefaultObjstoreBucket(bucket))})
+		defer c.Close()
+
+		insertSamples(t, table, dynparquet.GenerateTestSamples(10))
+		persistActiveBlock(t, table)
+		insertSamples(t, table, dynparquet.GenerateTestSamples(5))
+		require.Equal(t, int64(15), countRows(t, db, "test"))
+
+		// Without purging, persisted blocks are kept.
+		require.NoError(t, table.Truncate(ctx))
+		require.Equal(t, int64(10), countRows(t, db, "test"))
+
+		insertSamples(t, table, dynparquet.GenerateTestSamples(5))
+		require.NoError(t, table.Truncate(ctx, WithTruncatePersistedBlocks()))
+		require.Equal(t, int64(0), countRows(t, db, "test"))
+		blocks := 0
+		require.NoError(t, bucket.Iter(ctx, "test/test", func(string) error {
+			blocks++
+			return nil
+		}, objstore.WithRecursiveIter))
+		require.Zero(t, blocks)
+	})
+}
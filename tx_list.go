@@ -16,6 +16,19 @@ type TxPool struct {
 	tail   *atomic.Pointer[TxNode]
 	cancel context.CancelFunc
 	drain  chan interface{}
+
+	// onWatermark is called every time the cleaner advances the watermark.
+	onWatermark func(txn uint64)
+}
+
+type TxPoolOption func(*TxPool)
+
+// WithWatermarkCallback sets a function that is called with the new watermark
+// every time the pool cleaner advances the watermark.
+func WithWatermarkCallback(fn func(txn uint64)) TxPoolOption {
+	return func(l *TxPool) {
+		l.onWatermark = fn
+	}
 }
 
 // NewTxPool returns a new TxPool and starts the pool cleaner routine.
@@ -52,7 +65,7 @@ type TxPool struct {
 //
 // TxPool is a sorted lockless linked-list described in
 // https://timharris.uk/papers/2001-disc.pdf
-func NewTxPool(watermark *atomic.Uint64) *TxPool {
+func NewTxPool(watermark *atomic.Uint64, options ...TxPoolOption) *TxPool {
 	tail := &TxNode{
 		next:     &atomic.Pointer[TxNode]{},
 		original: &atomic.Pointer[TxNode]{},
@@ -66,6 +79,9 @@ func NewTxPool(watermark *atomic.Uint64) *TxPool {
 		tail:  &atomic.Pointer[TxNode]{},
 		drain: make(chan interface{}, 1),
 	}
+	for _, opt := range options {
+		opt(txpool)
+	}
 
 	// [head] -> [tail]
 	head.next.Store(tail)
@@ -204,6 +220,9 @@ func (l *TxPool) cleaner(ctx context.Context, watermark *atomic.Uint64) {
 				switch {
 				case mark+1 == txn:
 					watermark.Store(txn)
+					if l.onWatermark != nil {
+						l.onWatermark(txn)
+					}
 					return true // return true to indicate that this node should be removed from the tx list.
 				case mark >= txn:
 					return true
@@ -4,9 +4,11 @@ import (
 	"bytes"
 	"container/heap"
 	"context"
+	"errors"
 	"fmt"
 	"math"
 	"os"
+	"path/filepath"
 	"sync"
 	"time"
 
@@ -42,6 +44,10 @@ func (w *NopWAL) LogRecord(_ uint64, _ string, _ arrow.Record) error {
 	return nil
 }
 
+func (w *NopWAL) LogRecords(_ uint64, _ string, _ []arrow.Record) error {
+	return nil
+}
+
 func (w *NopWAL) Truncate(_ uint64) error {
 	return nil
 }
@@ -65,6 +71,11 @@ type Metrics struct {
 	WalRepairsLostRecords prometheus.Counter
 	WalCloseTimeouts      prometheus.Counter
 	WalQueueSize          prometheus.Gauge
+	WalQueueBytes         prometheus.Gauge
+	WalQueueFull          prometheus.Counter
+	// WalCorruptions counts corrupt records by the "type" label, the
+	// CorruptionType of the record.
+	WalCorruptions *prometheus.CounterVec
 }
 
 func newMetrics(reg prometheus.Registerer) *Metrics {
@@ -93,12 +104,62 @@ func newMetrics(reg prometheus.Registerer) *Metrics {
 			Name: "queue_size",
 			Help: "The number of unprocessed requests in the WAL queue",
 		}),
+		WalQueueBytes: promauto.With(reg).NewGauge(prometheus.GaugeOpts{
+			Name: "queue_bytes",
+			Help: "The number of bytes of unprocessed requests in the WAL queue",
+		}),
+		WalQueueFull: promauto.With(reg).NewCounter(prometheus.CounterOpts{
+			Name: "queue_full_total",
+			Help: "The number of log requests that were blocked or rejected because the WAL queue was full",
+		}),
+		WalCorruptions: promauto.With(reg).NewCounterVec(prometheus.CounterOpts{
+			Name: "corruptions_total",
+			Help: "The number of corrupt WAL records found, by type of corruption",
+		}, []string{"type"}),
 	}
 }
 
 const (
 	dirPerms           = os.FileMode(0o750)
 	progressLogTimeout = 10 * time.Second
+	// defaultBatchInterval is the default interval at which queued log
+	// requests are written to disk.
+	defaultBatchInterval = 50 * time.Millisecond
+)
+
+var (
+	// ErrQueueFull is returned by Log and LogRecord if the log request queue
+	// exceeds its memory limit and QueueFullError is configured.
+	ErrQueueFull = errors.New("WAL queue full")
+	// ErrWriteFailed is wrapped by the errors returned by Log and LogRecord
+	// once the WAL failed to write to disk if WithFailFast is configured.
+	ErrWriteFailed = errors.New("WAL write failed")
+)
+
+// QueueFullBehavior determines what Log and LogRecord do when the log request
+// queue exceeds its memory limit.
+type QueueFullBehavior int
+
+const (
+	// QueueFullBlock blocks until enough queued requests have been written.
+	QueueFullBlock QueueFullBehavior = iota
+	// QueueFullError returns ErrQueueFull.
+	QueueFullError
+)
+
+// Compression is the compression codec used for the arrow records logged with
+// LogRecord.
+type Compression int
+
+const (
+	// CompressionNone logs records uncompressed.
+	CompressionNone Compression = iota
+	// CompressionLZ4 compresses records with LZ4, which is fast but
+	// compresses less.
+	CompressionLZ4
+	// CompressionZstd compresses records with zstd, which compresses better
+	// at a higher CPU cost.
+	CompressionZstd
 )
 
 type FileWAL struct {
@@ -123,7 +184,40 @@ type FileWAL struct {
 		// nextTx is the next expected txn. The FileWAL will only log a record
 		// with this txn.
 		nextTx uint64
-	}
+		// queueBytes is the total size of the requests in the queue.
+		queueBytes int64
+		// queueSpace is signaled when requests are removed from the queue.
+		queueSpace *sync.Cond
+		// closed is set once Close is called. Log requests no longer block
+		// once the WAL is closed.
+		closed bool
+		// writeErr is the first error encountered writing to disk. It is only
+		// set if failFast is true.
+		writeErr error
+	}
+
+	// queueLimit is the maximum size in bytes of the log request queue. A
+	// value <= 0 disables the limit.
+	queueLimit        int64
+	queueFullBehavior QueueFullBehavior
+	// failFast makes Log and LogRecord fail once a write to disk failed.
+	failFast bool
+	// compression is the codec used to compress logged arrow records.
+	compression Compression
+	// batchInterval is the interval at which queued requests are written.
+	batchInterval time.Duration
+	// batchBytes is the size in bytes of queued requests that triggers a
+	// write before the batch interval elapsed. A value <= 0 disables
+	// size-triggered writes.
+	batchBytes int64
+	// flushCh is signaled when the queued requests exceed batchBytes.
+	flushCh chan struct{}
+	// quarantineDir is the directory the WAL files are copied to before
+	// corrupt records are removed.
+	quarantineDir string
+	// onSync is called with the last txn of each batch that was durably
+	// written.
+	onSync func(tx uint64)
 
 	// scratch memory reused to reduce allocations.
 	scratch struct {
@@ -179,6 +273,72 @@ func (q *logRequestQueue) Pop() any {
 
 type Option func(*FileWAL)
 
+// WithQueueLimit limits the memory used by log requests that have not been
+// written to disk yet, e.g. because the disk is stalled, to maxBytes. When
+// the limit is exceeded, LogRecord either blocks or returns ErrQueueFull
+// depending on the given behavior. Records logged with Log are small control
+// records and are always accepted. A request for a transaction preceding all
+// queued transactions is also always accepted, since the queued transactions
+// can only be written once it is.
+func WithQueueLimit(maxBytes int64, behavior QueueFullBehavior) Option {
+	return func(w *FileWAL) {
+		w.queueLimit = maxBytes
+		w.queueFullBehavior = behavior
+	}
+}
+
+// WithFailFast makes Log and LogRecord return an error wrapping
+// ErrWriteFailed once the WAL failed to write to disk, instead of queueing
+// requests that are unlikely to ever be written. This is useful to detect
+// read-only or otherwise broken disks early.
+func WithFailFast() Option {
+	return func(w *FileWAL) {
+		w.failFast = true
+	}
+}
+
+// WithCompression compresses the arrow records logged with LogRecord using the
+// given codec. Compression trades CPU for less data written to disk, which
+// helps deployments that are bound by WAL IO. Records are decompressed
+// transparently on replay regardless of the configured codec, so the codec can
+// be changed between restarts.
+func WithCompression(c Compression) Option {
+	return func(w *FileWAL) {
+		w.compression = c
+	}
+}
+
+// WithBatching configures when queued log requests are written to disk. The
+// queued requests are written every interval, or as soon as they exceed
+// maxBytes if maxBytes is positive. Longer intervals write fewer, larger
+// batches at the cost of higher latency until records are durable. The
+// default interval is 50ms without a size limit.
+func WithBatching(interval time.Duration, maxBytes int64) Option {
+	return func(w *FileWAL) {
+		w.batchInterval = interval
+		w.batchBytes = maxBytes
+	}
+}
+
+// WithQuarantineDir sets the directory the WAL files are copied to before
+// corrupt records are removed on replay. It defaults to the WAL directory
+// path with a "-quarantine" suffix.
+func WithQuarantineDir(dir string) Option {
+	return func(w *FileWAL) {
+		w.quarantineDir = dir
+	}
+}
+
+// WithSyncCallback sets a function that is called with the highest txn of
+// each batch once the batch was written and synced to disk. Records are
+// written in txn order, so all records up to and including this txn are
+// durable. The function is called from the WAL's run loop and must not block.
+func WithSyncCallback(fn func(tx uint64)) Option {
+	return func(w *FileWAL) {
+		w.onSync = fn
+	}
+}
+
 func WithTestingLogStoreWrapper(newLogStoreWrapper func(wal.LogStore) wal.LogStore) Option {
 	return func(w *FileWAL) {
 		w.newLogStoreWrapper = newLogStoreWrapper
@@ -252,14 +412,20 @@ func Open(
 				return &bytes.Buffer{}
 			},
 		},
-		closeTimeout: 1 * time.Second,
-		segmentSize:  segmentSize,
-		shutdownCh:   make(chan struct{}),
+		closeTimeout:  1 * time.Second,
+		segmentSize:   segmentSize,
+		shutdownCh:    make(chan struct{}),
+		batchInterval: defaultBatchInterval,
+		flushCh:       make(chan struct{}, 1),
+		quarantineDir: filepath.Clean(path) + "-quarantine",
 	}
 
 	for _, o := range opts {
 		o(w)
 	}
+	if w.batchInterval <= 0 {
+		return nil, fmt.Errorf("WAL batch interval must be positive, got %s", w.batchInterval)
+	}
 
 	logStore, err := wal.Open(path, wal.WithLogger(logger), wal.WithMetrics(w.storeMetrics), wal.WithSegmentSize(segmentSize))
 	if err != nil {
@@ -283,14 +449,14 @@ func Open(
 
 	w.scratch.walBatch = make([]types.LogEntry, 0, 64)
 	w.scratch.reqBatch = make([]*logRequest, 0, 64)
+	w.protected.queueSpace = sync.NewCond(&w.protected.Mutex)
 
 	return w, nil
 }
 
 func (w *FileWAL) run(ctx context.Context) {
-	const defaultTickTime = 50 * time.Millisecond
 	if w.ticker == nil {
-		w.ticker = realTicker{Ticker: time.NewTicker(defaultTickTime)}
+		w.ticker = realTicker{Ticker: time.NewTicker(w.batchInterval)}
 	}
 	defer w.ticker.Stop()
 	// lastQueueSize is only used on shutdown to reduce debug logging verbosity.
@@ -328,7 +494,7 @@ func (w *FileWAL) run(ctx context.Context) {
 
 				if n == lastQueueSize {
 					// No progress made.
-					time.Sleep(defaultTickTime)
+					time.Sleep(w.batchInterval)
 					continue
 				}
 
@@ -353,6 +519,8 @@ func (w *FileWAL) run(ctx context.Context) {
 			return
 		case <-w.ticker.C():
 			w.process()
+		case <-w.flushCh:
+			w.process()
 		}
 	}
 }
@@ -373,8 +541,7 @@ func (w *FileWAL) process() {
 					"expected", w.protected.nextTx,
 					"found", minTx,
 				)
-				w.logRequestPool.Put(heap.Pop(&w.protected.queue))
-				w.metrics.WalQueueSize.Sub(1)
+				w.logRequestPool.Put(w.popLocked())
 				// Keep on going since there might be other transactions
 				// below this one.
 				continue
@@ -390,8 +557,7 @@ func (w *FileWAL) process() {
 			// Next expected tx has not yet been seen.
 			break
 		}
-		r := heap.Pop(&w.protected.queue).(*logRequest)
-		w.metrics.WalQueueSize.Sub(1)
+		r := w.popLocked()
 		w.scratch.reqBatch = append(w.scratch.reqBatch, r)
 		batchSize += len(r.data)
 		w.protected.nextTx++
@@ -423,6 +589,13 @@ func (w *FileWAL) process() {
 	if len(w.scratch.walBatch) > 0 {
 		if err := w.log.StoreLogs(w.scratch.walBatch); err != nil {
 			w.metrics.FailedLogs.Add(float64(len(w.scratch.reqBatch)))
+			if w.failFast {
+				w.protected.Lock()
+				if w.protected.writeErr == nil {
+					w.protected.writeErr = err
+				}
+				w.protected.Unlock()
+			}
 			lastIndex, lastIndexErr := w.log.LastIndex()
 			level.Error(w.logger).Log(
 				"msg", "failed to write WAL batch",
@@ -430,6 +603,8 @@ func (w *FileWAL) process() {
 				"lastIndex", lastIndex,
 				"lastIndexErr", lastIndexErr,
 			)
+		} else if w.onSync != nil {
+			w.onSync(w.scratch.walBatch[len(w.scratch.walBatch)-1].Index)
 		}
 	}
 
@@ -454,8 +629,7 @@ func (w *FileWAL) process() {
 					if minTx := w.protected.queue[0].tx; minTx >= w.protected.nextTx {
 						break
 					}
-					w.logRequestPool.Put(heap.Pop(&w.protected.queue))
-					w.metrics.WalQueueSize.Sub(1)
+					w.logRequestPool.Put(w.popLocked())
 				}
 			}
 			w.protected.Unlock()
@@ -495,7 +669,7 @@ func (w *FileWAL) Reset(nextTx uint64) error {
 	defer w.protected.Unlock()
 	// Drain any pending records.
 	for w.protected.queue.Len() > 0 {
-		_ = heap.Pop(&w.protected.queue)
+		_ = w.popLocked()
 	}
 	// Set the next expected transaction.
 	w.protected.nextTx = nextTx
@@ -510,6 +684,10 @@ func (w *FileWAL) Close() error {
 		return nil
 	}
 	level.Debug(w.logger).Log("msg", "WAL received shutdown request; canceling run loop")
+	w.protected.Lock()
+	w.protected.closed = true
+	w.protected.queueSpace.Broadcast()
+	w.protected.Unlock()
 	w.cancel()
 	<-w.shutdownCh
 	return w.log.Close()
@@ -528,12 +706,93 @@ func (w *FileWAL) Log(tx uint64, record *walpb.Record) error {
 		return err
 	}
 
+	return w.enqueue(r, false)
+}
+
+// enqueue adds the given log request to the queue, applying the queue's
+// memory limit if limited is true. If the request is rejected, a placeholder
+// is queued in its place so that subsequent transactions can still be logged.
+func (w *FileWAL) enqueue(r *logRequest, limited bool) error {
+	size := int64(len(r.data))
+
 	w.protected.Lock()
+	defer w.protected.Unlock()
+	blocked := false
+	for {
+		if w.protected.writeErr != nil {
+			w.rejectLocked(r)
+			return fmt.Errorf("%w: %w", ErrWriteFailed, w.protected.writeErr)
+		}
+		if !limited || w.protected.closed || w.hasQueueSpaceLocked(r.tx, size) {
+			break
+		}
+		if !blocked {
+			w.metrics.WalQueueFull.Inc()
+			blocked = true
+		}
+		if w.queueFullBehavior == QueueFullError {
+			w.rejectLocked(r)
+			return ErrQueueFull
+		}
+		w.protected.queueSpace.Wait()
+	}
+
+	w.pushLocked(r)
+	return nil
+}
+
+// hasQueueSpaceLocked returns whether a request of the given size for the
+// given tx can be queued without exceeding the queue limit.
+func (w *FileWAL) hasQueueSpaceLocked(tx uint64, size int64) bool {
+	if w.queueLimit <= 0 || w.protected.queue.Len() == 0 {
+		return true
+	}
+	if w.protected.queueBytes+size <= w.queueLimit {
+		return true
+	}
+	// Queued transactions can only be written once all preceding
+	// transactions are, so the request must be accepted to make progress.
+	return tx < w.protected.queue[0].tx
+}
+
+// placeholderRecord is logged in place of rejected transactions. It has no
+// entry type and is ignored on replay.
+var placeholderRecord = func() []byte {
+	b, err := (&walpb.Record{Entry: &walpb.Entry{}}).MarshalVT()
+	if err != nil {
+		panic(err)
+	}
+	return b
+}()
+
+// rejectLocked replaces the data of a rejected request with a placeholder and
+// queues it, since the WAL can only log transactions in order.
+func (w *FileWAL) rejectLocked(r *logRequest) {
+	r.data = append(r.data[:0], placeholderRecord...)
+	w.pushLocked(r)
+}
+
+func (w *FileWAL) pushLocked(r *logRequest) {
 	heap.Push(&w.protected.queue, r)
+	w.protected.queueBytes += int64(len(r.data))
 	w.metrics.WalQueueSize.Add(1)
-	w.protected.Unlock()
+	w.metrics.WalQueueBytes.Add(float64(len(r.data)))
+	if w.batchBytes > 0 && w.protected.queueBytes >= w.batchBytes {
+		select {
+		case w.flushCh <- struct{}{}:
+		default:
+			// A write is already pending.
+		}
+	}
+}
 
-	return nil
+func (w *FileWAL) popLocked() *logRequest {
+	r := heap.Pop(&w.protected.queue).(*logRequest)
+	w.protected.queueBytes -= int64(len(r.data))
+	w.metrics.WalQueueSize.Sub(1)
+	w.metrics.WalQueueBytes.Sub(float64(len(r.data)))
+	w.protected.queueSpace.Broadcast()
+	return r
 }
 
 func (w *FileWAL) getArrowBuf() *bytes.Buffer {
@@ -545,17 +804,35 @@ func (w *FileWAL) putArrowBuf(b *bytes.Buffer) {
 	w.arrowBufPool.Put(b)
 }
 
-func (w *FileWAL) writeRecord(buf *bytes.Buffer, record arrow.Record) error {
-	writer := ipc.NewWriter(
-		buf,
-		ipc.WithSchema(record.Schema()),
-	)
+func (w *FileWAL) writeRecords(buf *bytes.Buffer, records []arrow.Record) error {
+	opts := []ipc.Option{ipc.WithSchema(records[0].Schema())}
+	switch w.compression {
+	case CompressionLZ4:
+		opts = append(opts, ipc.WithLZ4())
+	case CompressionZstd:
+		opts = append(opts, ipc.WithZstd())
+	}
+	writer := ipc.NewWriter(buf, opts...)
 	defer writer.Close()
 
-	return writer.Write(record)
+	for _, record := range records {
+		if err := writer.Write(record); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
 func (w *FileWAL) LogRecord(tx uint64, table string, record arrow.Record) error {
+	return w.LogRecords(tx, table, []arrow.Record{record})
+}
+
+// LogRecords logs the records as a single WAL entry, encoded as one arrow IPC
+// stream.
+func (w *FileWAL) LogRecords(tx uint64, table string, records []arrow.Record) error {
+	if len(records) == 0 {
+		return nil
+	}
 	w.protected.Lock()
 	nextTx := w.protected.nextTx
 	w.protected.Unlock()
@@ -571,7 +848,7 @@ func (w *FileWAL) LogRecord(tx uint64, table string, record arrow.Record) error
 	}
 	buf := w.getArrowBuf()
 	defer w.putArrowBuf(buf)
-	if err := w.writeRecord(buf, record); err != nil {
+	if err := w.writeRecords(buf, records); err != nil {
 		return err
 	}
 
@@ -599,12 +876,7 @@ func (w *FileWAL) LogRecord(tx uint64, table string, record arrow.Record) error
 		return err
 	}
 
-	w.protected.Lock()
-	heap.Push(&w.protected.queue, r)
-	w.metrics.WalQueueSize.Add(1)
-	w.protected.Unlock()
-
-	return nil
+	return w.enqueue(r, true)
 }
 
 func (w *FileWAL) FirstIndex() (uint64, error) {
@@ -640,6 +912,9 @@ func (w *FileWAL) Replay(tx uint64, handler ReplayHandlerFunc) (err error) {
 
 	level.Debug(w.logger).Log("msg", "replaying WAL", "first_index", tx, "last_index", lastIndex)
 
+	// corruption is the type of corruption of the record being replayed if
+	// it panics.
+	corruption := CorruptionReplay
 	defer func() {
 		// recover a panic of reading a transaction. Truncate the wal to the
 		// last valid transaction.
@@ -650,8 +925,17 @@ func (w *FileWAL) Replay(tx uint64, handler ReplayHandlerFunc) (err error) {
 				"first_index", logFirstIndex,
 				"last_index", lastIndex,
 				"offending_index", tx,
+				"corruption", corruption,
 				"err", r,
 			)
+			w.metrics.WalCorruptions.WithLabelValues(corruption.String()).Inc()
+			// Keep a copy of the records that are about to be removed.
+			dir, qerr := w.quarantine(tx, lastIndex)
+			if qerr != nil {
+				err = fmt.Errorf("quarantine corrupt WAL records: %w", qerr)
+				return
+			}
+			level.Warn(w.logger).Log("msg", "quarantined WAL files", "dir", dir)
 			if err = w.log.TruncateBack(tx - 1); err != nil {
 				return
 			}
@@ -663,12 +947,14 @@ func (w *FileWAL) Replay(tx uint64, handler ReplayHandlerFunc) (err error) {
 	var entry types.LogEntry
 	for ; tx <= lastIndex; tx++ {
 		level.Debug(w.logger).Log("msg", "replaying WAL record", "tx", tx)
+		corruption = CorruptionRead
 		if err := w.log.GetLog(tx, &entry); err != nil {
 			// Panic since this is most likely a corruption issue. The recover
 			// call above will truncate the WAL to the last valid transaction.
 			panic(fmt.Sprintf("read index %d: %v", tx, err))
 		}
 
+		corruption = CorruptionRecord
 		record := &walpb.Record{}
 		if err := record.UnmarshalVT(entry.Data); err != nil {
 			// Panic since this is most likely a corruption issue. The recover
@@ -676,6 +962,7 @@ func (w *FileWAL) Replay(tx uint64, handler ReplayHandlerFunc) (err error) {
 			panic(fmt.Sprintf("unmarshal WAL record: %v", err))
 		}
 
+		corruption = CorruptionReplay
 		if err := handler(tx, record); err != nil {
 			return fmt.Errorf("call replay handler: %w", err)
 		}
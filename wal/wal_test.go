@@ -1,13 +1,22 @@
 package wal
 
 import (
+	"bytes"
+	"context"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
 	"testing"
 	"time"
 
+	"github.com/apache/arrow/go/v17/arrow"
+	"github.com/apache/arrow/go/v17/arrow/array"
+	"github.com/apache/arrow/go/v17/arrow/ipc"
+	"github.com/apache/arrow/go/v17/arrow/memory"
 	"github.com/go-kit/log"
+	"github.com/polarsignals/wal"
+	"github.com/polarsignals/wal/types"
 	"github.com/stretchr/testify/require"
 
 	walpb "github.com/polarsignals/frostdb/gen/proto/go/frostdb/wal/v1alpha1"
@@ -290,3 +299,279 @@ func TestWALCloseTimeout(t *testing.T) {
 	err = w.Close()
 	require.NoError(t, err)
 }
+
+type testTicker struct {
+	c chan time.Time
+}
+
+func (t testTicker) C() <-chan time.Time { return t.c }
+
+func (t testTicker) Stop() {}
+
+func TestWALQueueLimit(t *testing.T) {
+	newRecord := func() arrow.Record {
+		b := array.NewInt64Builder(memory.DefaultAllocator)
+		defer b.Release()
+		b.AppendValues([]int64{1, 2, 3}, nil)
+		col := b.NewArray()
+		defer col.Release()
+		return array.NewRecord(
+			arrow.NewSchema([]arrow.Field{{Name: "value", Type: arrow.PrimitiveTypes.Int64}}, nil),
+			[]arrow.Array{col},
+			3,
+		)
+	}
+	record := newRecord()
+	defer record.Release()
+
+	t.Run("Error", func(t *testing.T) {
+		ticker := testTicker{c: make(chan time.Time)}
+		w, err := Open(
+			log.NewNopLogger(),
+			t.TempDir(),
+			WithQueueLimit(1, QueueFullError),
+			WithTestingLoopTicker(ticker),
+		)
+		require.NoError(t, err)
+		defer w.Close()
+		w.RunAsync()
+
+		// An empty queue always accepts a request.
+		require.NoError(t, w.LogRecord(2, "test", record))
+		require.ErrorIs(t, w.LogRecord(3, "test", record), ErrQueueFull)
+		// A request preceding all queued requests is always accepted.
+		require.NoError(t, w.LogRecord(1, "test", record))
+
+		ticker.c <- time.Now()
+		require.Eventually(t, func() bool {
+			tx, _ := w.LastIndex()
+			return tx == 3
+		}, time.Second, 10*time.Millisecond)
+
+		// The rejected transaction does not prevent logging later ones.
+		require.NoError(t, w.LogRecord(4, "test", record))
+		ticker.c <- time.Now()
+		require.Eventually(t, func() bool {
+			tx, _ := w.LastIndex()
+			return tx == 4
+		}, time.Second, 10*time.Millisecond)
+
+		var writes []uint64
+		require.NoError(t, w.Replay(0, func(tx uint64, r *walpb.Record) error {
+			if r.Entry.GetWrite() != nil {
+				writes = append(writes, tx)
+			}
+			return nil
+		}))
+		require.Equal(t, []uint64{1, 2, 4}, writes)
+	})
+
+	t.Run("Block", func(t *testing.T) {
+		ticker := testTicker{c: make(chan time.Time)}
+		w, err := Open(
+			log.NewNopLogger(),
+			t.TempDir(),
+			WithQueueLimit(1, QueueFullBlock),
+			WithTestingLoopTicker(ticker),
+		)
+		require.NoError(t, err)
+		defer w.Close()
+		w.RunAsync()
+
+		require.NoError(t, w.LogRecord(1, "test", record))
+		done := make(chan error, 1)
+		go func() {
+			done <- w.LogRecord(2, "test", record)
+		}()
+
+		select {
+		case err := <-done:
+			t.Fatalf("expected LogRecord to block, got %v", err)
+		case <-time.After(50 * time.Millisecond):
+		}
+
+		ticker.c <- time.Now()
+		select {
+		case err := <-done:
+			require.NoError(t, err)
+		case <-time.After(time.Second):
+			t.Fatal("LogRecord did not unblock after the queue was drained")
+		}
+	})
+}
+
+type failingLogStore struct {
+	wal.LogStore
+}
+
+func (s failingLogStore) StoreLogs([]types.LogEntry) error {
+	return errors.New("read-only file system")
+}
+
+func TestWALFailFast(t *testing.T) {
+	ticker := testTicker{c: make(chan time.Time)}
+	w, err := Open(
+		log.NewNopLogger(),
+		t.TempDir(),
+		WithFailFast(),
+		WithTestingLoopTicker(ticker),
+		WithTestingLogStoreWrapper(func(s wal.LogStore) wal.LogStore {
+			return failingLogStore{LogStore: s}
+		}),
+	)
+	require.NoError(t, err)
+	defer w.Close()
+	w.RunAsync()
+
+	record := &walpb.Record{Entry: &walpb.Entry{EntryType: &walpb.Entry_Write_{
+		Write: &walpb.Entry_Write{Data: []byte("test-data"), TableName: "test-table"},
+	}}}
+	require.NoError(t, w.Log(1, record))
+	ticker.c <- time.Now()
+	require.Eventually(t, func() bool {
+		return errors.Is(w.Log(2, record), ErrWriteFailed)
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestWALCompression(t *testing.T) {
+	b := array.NewInt64Builder(memory.DefaultAllocator)
+	defer b.Release()
+	for i := 0; i < 1000; i++ {
+		b.Append(int64(i % 10))
+	}
+	col := b.NewArray()
+	defer col.Release()
+	record := array.NewRecord(
+		arrow.NewSchema([]arrow.Field{{Name: "value", Type: arrow.PrimitiveTypes.Int64}}, nil),
+		[]arrow.Array{col},
+		int64(col.Len()),
+	)
+	defer record.Release()
+
+	logRecord := func(t *testing.T, c Compression) []byte {
+		t.Helper()
+		w, err := Open(log.NewNopLogger(), t.TempDir(), WithCompression(c))
+		require.NoError(t, err)
+		w.RunAsync()
+		defer w.Close()
+
+		require.NoError(t, w.LogRecord(1, "test", record))
+		require.Eventually(t, func() bool {
+			tx, _ := w.LastIndex()
+			return tx == 1
+		}, time.Second, 10*time.Millisecond)
+
+		var data []byte
+		require.NoError(t, w.Replay(0, func(_ uint64, r *walpb.Record) error {
+			data = r.Entry.GetWrite().Data
+			return nil
+		}))
+		reader, err := ipc.NewReader(bytes.NewReader(data))
+		require.NoError(t, err)
+		defer reader.Release()
+		require.True(t, reader.Next())
+		require.True(t, array.RecordEqual(record, reader.Record()))
+		return data
+	}
+
+	uncompressed := logRecord(t, CompressionNone)
+	for _, c := range []Compression{CompressionLZ4, CompressionZstd} {
+		require.Less(t, len(logRecord(t, c)), len(uncompressed))
+	}
+}
+
+func TestWALBatching(t *testing.T) {
+	w, err := Open(
+		log.NewNopLogger(),
+		t.TempDir(),
+		WithBatching(time.Hour, 1),
+	)
+	require.NoError(t, err)
+	w.RunAsync()
+	defer w.Close()
+
+	// The queued request exceeds the batch size, so it is written long
+	// before the batch interval elapses.
+	require.NoError(t, w.Log(1, &walpb.Record{
+		Entry: &walpb.Entry{
+			EntryType: &walpb.Entry_Write_{
+				Write: &walpb.Entry_Write{
+					Data:      []byte("test-data"),
+					TableName: "test-table",
+				},
+			},
+		},
+	}))
+	require.Eventually(t, func() bool {
+		tx, _ := w.LastIndex()
+		return tx == 1
+	}, time.Second, 10*time.Millisecond)
+
+	_, err = Open(log.NewNopLogger(), t.TempDir(), WithBatching(0, 0))
+	require.Error(t, err)
+}
+
+func TestWALCheckIntegrity(t *testing.T) {
+	dir := t.TempDir()
+	quarantineDir := filepath.Join(t.TempDir(), "quarantine")
+	w, err := Open(log.NewNopLogger(), dir, WithQuarantineDir(quarantineDir))
+	require.NoError(t, err)
+	w.RunAsync()
+
+	for tx, isArrow := range []bool{false, true, true, false} {
+		require.NoError(t, w.Log(uint64(tx+1), &walpb.Record{
+			Entry: &walpb.Entry{
+				EntryType: &walpb.Entry_Write_{
+					Write: &walpb.Entry_Write{
+						// Records flagged as arrow records contain invalid
+						// arrow data.
+						Data:      []byte("test-data"),
+						TableName: "test-table",
+						Arrow:     isArrow,
+					},
+				},
+			},
+		}))
+	}
+	require.Eventually(t, func() bool {
+		last, err := w.LastIndex()
+		require.NoError(t, err)
+		return last == 4
+	}, time.Second, 10*time.Millisecond)
+
+	report, err := w.CheckIntegrity(context.Background())
+	require.NoError(t, err)
+	require.False(t, report.OK())
+	require.Equal(t, uint64(1), report.FirstIndex)
+	require.Equal(t, uint64(4), report.LastIndex)
+	require.Len(t, report.Corrupt, 1)
+	require.Equal(t, CorruptionArrow, report.Corrupt[0].Type)
+	require.Equal(t, uint64(2), report.Corrupt[0].FirstIndex)
+	require.Equal(t, uint64(3), report.Corrupt[0].LastIndex)
+	require.Error(t, report.Corrupt[0].Err)
+	require.NoError(t, w.Close())
+
+	// Replaying the WAL quarantines the WAL files before removing the records
+	// starting at the record the handler panics on.
+	w, err = Open(log.NewNopLogger(), dir, WithQuarantineDir(quarantineDir))
+	require.NoError(t, err)
+	require.NoError(t, w.Replay(0, func(_ uint64, r *walpb.Record) error {
+		if r.Entry.GetWrite().Arrow {
+			panic("invalid arrow data")
+		}
+		return nil
+	}))
+	lastIdx, err := w.LastIndex()
+	require.NoError(t, err)
+	require.Equal(t, uint64(1), lastIdx)
+
+	entries, err := os.ReadDir(filepath.Join(quarantineDir, fmt.Sprintf("%020d-%020d", 2, 4)))
+	require.NoError(t, err)
+	require.NotEmpty(t, entries)
+
+	report, err = w.CheckIntegrity(context.Background())
+	require.NoError(t, err)
+	require.True(t, report.OK())
+	w.RunAsync()
+	require.NoError(t, w.Close())
+}
@@ -0,0 +1,490 @@
+package frostdb
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/apache/arrow/go/v17/arrow"
+	"github.com/apache/arrow/go/v17/arrow/ipc"
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"google.golang.org/protobuf/proto"
+
+	schemapb "github.com/polarsignals/frostdb/gen/proto/go/frostdb/schema/v1alpha1"
+	tablepb "github.com/polarsignals/frostdb/gen/proto/go/frostdb/table/v1alpha1"
+	walpb "github.com/polarsignals/frostdb/gen/proto/go/frostdb/wal/v1alpha1"
+	"github.com/polarsignals/frostdb/query/exprpb"
+)
+
+const (
+	// DefaultWALShippingMaxPendingBytes is the default size of the entries
+	// that are kept until the standby acknowledges them.
+	DefaultWALShippingMaxPendingBytes = 256 * MiB
+	// DefaultWALShippingRetryInterval is the default time waited before
+	// reconnecting to the standby after a stream failed.
+	DefaultWALShippingRetryInterval = time.Second
+)
+
+// WALShippingOption configures the shipping of WAL entries, see
+// WithWALShipping.
+type WALShippingOption func(*walShipper)
+
+// WithWALShippingMaxPendingBytes sets the max size of the entries that were
+// not acknowledged by the standby yet. Once an entry is logged while the limit
+// is reached, which bounds the memory used while the standby is unavailable,
+// the standby is out of sync: neither this entry nor any following entry is
+// shipped, and the standby stops applying entries of the primary after the
+// pending ones. The standby then needs to be restored from a snapshot of the
+// primary, after which the primary is restarted to resume shipping. The
+// default is DefaultWALShippingMaxPendingBytes.
+func WithWALShippingMaxPendingBytes(n int64) WALShippingOption {
+	return func(s *walShipper) {
+		s.maxPendingBytes = n
+	}
+}
+
+// WithWALShippingRetryInterval sets the time waited before reconnecting to
+// the standby after a stream failed. The default is
+// DefaultWALShippingRetryInterval.
+func WithWALShippingRetryInterval(d time.Duration) WALShippingOption {
+	return func(s *walShipper) {
+		s.retryInterval = d
+	}
+}
+
+// WithWALShipping streams the entries logged to the WAL of every database to
+// a standby instance using the given client, e.g. a server.ReplicationServer
+// of a standby ColumnStore, which applies them to its own databases. This
+// allows failing over to the standby without waiting for blocks to be
+// persisted to a bucket. The entries that were logged but not acknowledged
+// by the standby yet are the data lost when failing over, see
+// ColumnStore.WALShippingStats. Entries are shipped regardless of whether
+// the WAL is enabled, except for tables with a disabled WAL. Entries
+// replayed on startup are not shipped again.
+//
+// The standby persists and snapshots the shipped data itself, so it should
+// not persist to the same bucket as the primary.
+func WithWALShipping(client walpb.WALReplicationServiceClient, options ...WALShippingOption) Option {
+	return func(s *ColumnStore) error {
+		s.walShipper = newWALShipper(client, options...)
+		return nil
+	}
+}
+
+// WALShippingStats are statistics about the shipping of WAL entries to a
+// standby.
+type WALShippingStats struct {
+	// Pending is the number of shipped entries that were not acknowledged by
+	// the standby yet.
+	Pending int
+	// PendingBytes is the size of the pending entries.
+	PendingBytes int64
+	// Acknowledged is the number of entries acknowledged by the standby.
+	Acknowledged uint64
+	// Dropped is the number of entries that were not shipped because the
+	// standby is out of sync.
+	Dropped uint64
+	// OutOfSync is true once the max size of pending entries was reached,
+	// see WithWALShippingMaxPendingBytes.
+	OutOfSync bool
+}
+
+// WALShippingStats returns the statistics of the shipping of WAL entries, or
+// zero statistics if WithWALShipping is not used.
+func (s *ColumnStore) WALShippingStats() WALShippingStats {
+	if s.walShipper == nil {
+		return WALShippingStats{}
+	}
+	return s.walShipper.stats()
+}
+
+type pendingEntry struct {
+	req  *walpb.ReplicateRequest
+	size int64
+}
+
+// walShipper ships the WAL entries of all databases of a ColumnStore over a
+// single stream. Entries are numbered in the order they are logged and kept
+// until the standby acknowledges them, so that they can be sent again when
+// the stream is reestablished. The standby skips the entries it already
+// applied.
+type walShipper struct {
+	client          walpb.WALReplicationServiceClient
+	maxPendingBytes int64
+	retryInterval   time.Duration
+	logger          log.Logger
+	// id identifies the primary to the standby.
+	id string
+
+	mtx          sync.Mutex
+	pending      []pendingEntry
+	pendingBytes int64
+	nextSeq      uint64
+	acked        uint64
+	dropped      uint64
+	// outOfSync is set once an entry was dropped. The pending entries end
+	// with a request marking the standby as out of sync.
+	outOfSync bool
+	// notify is signaled when an entry is added to pending.
+	notify chan struct{}
+
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+func newWALShipper(client walpb.WALReplicationServiceClient, options ...WALShippingOption) *walShipper {
+	s := &walShipper{
+		client:          client,
+		maxPendingBytes: DefaultWALShippingMaxPendingBytes,
+		retryInterval:   DefaultWALShippingRetryInterval,
+		logger:          log.NewNopLogger(),
+		nextSeq:         1,
+		notify:          make(chan struct{}, 1),
+	}
+	for _, option := range options {
+		option(s)
+	}
+	return s
+}
+
+// start starts shipping entries in the background until stop is called.
+func (s *walShipper) start(id string, logger log.Logger) {
+	s.id = id
+	s.logger = logger
+	ctx, cancel := context.WithCancel(context.Background())
+	s.cancel = cancel
+	s.done = make(chan struct{})
+	go s.run(ctx)
+}
+
+func (s *walShipper) stop() {
+	if s.cancel == nil {
+		return
+	}
+	s.cancel()
+	<-s.done
+}
+
+// ship queues the record logged in the given transaction of the database.
+func (s *walShipper) ship(database string, tx uint64, record *walpb.Record) {
+	req := &walpb.ReplicateRequest{
+		Database: database,
+		Tx:       tx,
+		Record:   record,
+	}
+	size := int64(req.SizeVT())
+
+	s.mtx.Lock()
+	if s.outOfSync {
+		s.dropped++
+		s.mtx.Unlock()
+		return
+	}
+	if s.pendingBytes+size > s.maxPendingBytes {
+		// Applying later entries would leave a gap in the standby's data,
+		// so the standby is told to stop applying entries instead.
+		s.dropped++
+		s.outOfSync = true
+		req = &walpb.ReplicateRequest{OutOfSync: true}
+		size = int64(req.SizeVT())
+		level.Error(s.logger).Log("msg", "too many WAL entries pending, standby is out of sync and needs to be restored from a snapshot", "db", database, "tx", tx)
+	}
+	req.Seq = s.nextSeq
+	s.nextSeq++
+	s.pending = append(s.pending, pendingEntry{req: req, size: size})
+	s.pendingBytes += size
+	s.mtx.Unlock()
+
+	select {
+	case s.notify <- struct{}{}:
+	default:
+	}
+}
+
+// ack removes the entries up to seq from the pending entries.
+func (s *walShipper) ack(seq uint64) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	if seq <= s.acked {
+		return
+	}
+	s.acked = seq
+	i := 0
+	for ; i < len(s.pending) && s.pending[i].req.Seq <= seq; i++ {
+		s.pendingBytes -= s.pending[i].size
+		s.pending[i] = pendingEntry{}
+	}
+	s.pending = s.pending[i:]
+}
+
+// unsent returns the pending entries starting at seq.
+func (s *walShipper) unsent(seq uint64) []*walpb.ReplicateRequest {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	var reqs []*walpb.ReplicateRequest
+	for _, e := range s.pending {
+		if e.req.Seq >= seq {
+			reqs = append(reqs, e.req)
+		}
+	}
+	return reqs
+}
+
+func (s *walShipper) stats() WALShippingStats {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	return WALShippingStats{
+		Pending:      len(s.pending),
+		PendingBytes: s.pendingBytes,
+		Acknowledged: s.acked,
+		Dropped:      s.dropped,
+		OutOfSync:    s.outOfSync,
+	}
+}
+
+func (s *walShipper) run(ctx context.Context) {
+	defer close(s.done)
+	for {
+		err := s.stream(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+		level.Warn(s.logger).Log("msg", "shipping WAL entries failed", "err", err)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(s.retryInterval):
+		}
+	}
+}
+
+// stream ships the pending entries over a new stream until it fails.
+func (s *walShipper) stream(ctx context.Context) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	stream, err := s.client.Replicate(ctx)
+	if err != nil {
+		return err
+	}
+	if err := stream.Send(&walpb.ReplicateRequest{PrimaryId: s.id}); err != nil {
+		return err
+	}
+	resp, err := stream.Recv()
+	if err != nil {
+		return err
+	}
+	s.ack(resp.GetAckedSeq())
+
+	recvErr := make(chan error, 1)
+	go func() {
+		for {
+			resp, err := stream.Recv()
+			if err != nil {
+				recvErr <- err
+				return
+			}
+			s.ack(resp.GetAckedSeq())
+		}
+	}()
+
+	next := resp.GetAckedSeq() + 1
+	for {
+		for _, req := range s.unsent(next) {
+			if err := stream.Send(req); err != nil {
+				return err
+			}
+			next = req.Seq + 1
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case err := <-recvErr:
+			if err == nil {
+				err = errors.New("stream closed by standby")
+			}
+			return err
+		case <-s.notify:
+		}
+	}
+}
+
+// shippingWAL ships the entries logged to a WAL after they were logged.
+type shippingWAL struct {
+	WAL
+	database string
+	shipper  *walShipper
+}
+
+func (w *shippingWAL) Log(tx uint64, record *walpb.Record) error {
+	if err := w.WAL.Log(tx, record); err != nil {
+		return err
+	}
+	w.shipper.ship(w.database, tx, record)
+	return nil
+}
+
+func (w *shippingWAL) LogRecord(tx uint64, table string, record arrow.Record) error {
+	return w.LogRecords(tx, table, []arrow.Record{record})
+}
+
+func (w *shippingWAL) LogRecords(tx uint64, table string, records []arrow.Record) error {
+	if err := w.WAL.LogRecords(tx, table, records); err != nil {
+		return err
+	}
+	if len(records) == 0 {
+		return nil
+	}
+
+	var buf bytes.Buffer
+	writer := ipc.NewWriter(&buf, ipc.WithSchema(records[0].Schema()))
+	for _, r := range records {
+		if err := writer.Write(r); err != nil {
+			return err
+		}
+	}
+	if err := writer.Close(); err != nil {
+		return err
+	}
+	w.shipper.ship(w.database, tx, &walpb.Record{
+		Entry: &walpb.Entry{
+			EntryType: &walpb.Entry_Write_{
+				Write: &walpb.Entry_Write{
+					TableName: table,
+					Data:      buf.Bytes(),
+					Arrow:     true,
+				},
+			},
+		},
+	})
+	return nil
+}
+
+// ApplyWALRecord applies a record logged to the WAL of a database of another
+// ColumnStore to the database, e.g. a record shipped to a standby, see
+// WithWALShipping. Records are applied in new transactions of the database,
+// which are logged to its own WAL. New table blocks create missing tables
+// and update the schema of existing tables, writes are inserted into the
+// tables, and deletes and truncates are applied to the tables. Records of
+// tables storing sort orders are ignored, since the sort orders are
+// maintained by the tables of the database.
+func (db *DB) ApplyWALRecord(ctx context.Context, record *walpb.Record) error {
+	if db.columnStore.readOnly {
+		return ErrReadOnly
+	}
+	switch e := record.GetEntry().GetEntryType().(type) {
+	case *walpb.Entry_NewTableBlock_:
+		entry := e.NewTableBlock
+		if strings.Contains(entry.TableName, sortOrderSeparator) {
+			return nil
+		}
+		var schema proto.Message
+		switch v := entry.Config.Schema.(type) {
+		case *tablepb.TableConfig_DeprecatedSchema:
+			schema = v.DeprecatedSchema
+		case *tablepb.TableConfig_SchemaV2:
+			schema = v.SchemaV2
+		default:
+			return fmt.Errorf("unhandled schema type: %T", v)
+		}
+
+		table, err := db.GetTable(entry.TableName)
+		var tableErr ErrTableNotFound
+		if errors.As(err, &tableErr) {
+			_, err := db.Table(entry.TableName, NewTableConfig(schema, FromConfig(entry.Config)))
+			return err
+		}
+		if err != nil {
+			return err
+		}
+
+		newSchema, ok := schema.(*schemapb.Schema)
+		oldSchema := table.config.Load().GetDeprecatedSchema()
+		if !ok || oldSchema == nil || proto.Equal(oldSchema, newSchema) {
+			return nil
+		}
+		if !slices.EqualFunc(oldSchema.SortingColumns, newSchema.SortingColumns, func(a, b *schemapb.SortingColumn) bool {
+			return proto.Equal(a, b)
+		}) {
+			if _, err := table.UpdateSortingColumns(ctx, newSchema.SortingColumns); err != nil {
+				return fmt.Errorf("update sorting columns: %w", err)
+			}
+		}
+		return table.UpdateSchema(newSchema)
+	case *walpb.Entry_Write_:
+		entry := e.Write
+		if strings.Contains(entry.TableName, sortOrderSeparator) {
+			return nil
+		}
+		if !entry.Arrow {
+			return errors.New("parquet writes are deprecated")
+		}
+		table, err := db.GetTable(entry.TableName)
+		if err != nil {
+			return err
+		}
+		reader, err := ipc.NewReader(bytes.NewReader(entry.Data))
+		if err != nil {
+			return fmt.Errorf("create ipc reader: %w", err)
+		}
+		defer reader.Release()
+		for reader.Next() {
+			if _, err := table.InsertRecord(ctx, reader.Record()); err != nil {
+				return err
+			}
+		}
+		if err := reader.Err(); err != nil {
+			return fmt.Errorf("read record: %w", err)
+		}
+		return nil
+	case *walpb.Entry_Delete_:
+		entry := e.Delete
+		if strings.Contains(entry.TableName, sortOrderSeparator) {
+			return nil
+		}
+		table, err := db.GetTable(entry.TableName)
+		if err != nil {
+			return err
+		}
+		filter, err := exprpb.ExprFromProto(entry.Filter)
+		if err != nil {
+			return fmt.Errorf("delete filter: %w", err)
+		}
+		return table.Delete(ctx, filter)
+	case *walpb.Entry_Truncate_:
+		entry := e.Truncate
+		if strings.Contains(entry.TableName, sortOrderSeparator) {
+			return nil
+		}
+		table, err := db.GetTable(entry.TableName)
+		if err != nil {
+			return err
+		}
+		var options []TruncateOption
+		if entry.PurgeBlocks {
+			options = append(options, WithTruncatePersistedBlocks())
+		}
+		return table.Truncate(ctx, options...)
+	case *walpb.Entry_Meta_:
+		return db.logMeta(e.Meta)
+	case *walpb.Entry_TableBlockPersisted_, *walpb.Entry_Snapshot_, nil:
+		// Persisting blocks and snapshots are up to the database.
+		return nil
+	default:
+		return fmt.Errorf("unexpected WAL entry type: %T", e)
+	}
+}
+
+// unwrapWAL returns the WAL wrapped by a shippingWAL.
+func unwrapWAL(wal WAL) WAL {
+	if w, ok := wal.(*shippingWAL); ok {
+		return w.WAL
+	}
+	return wal
+}
@@ -0,0 +1,415 @@
+package frostdb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/apache/arrow/go/v17/arrow"
+	"github.com/apache/arrow/go/v17/arrow/array"
+	"github.com/apache/arrow/go/v17/arrow/memory"
+	"github.com/apache/arrow/go/v17/arrow/util"
+	"github.com/go-kit/log/level"
+
+	"github.com/polarsignals/frostdb/pqarrow/arrowutils"
+)
+
+// Default thresholds at which a TableWriter flushes its buffered records.
+const (
+	DefaultWriterFlushRows     = 10_000
+	DefaultWriterFlushBytes    = 4 * MiB
+	DefaultWriterFlushInterval = time.Second
+)
+
+// ErrWriterClosed is returned when inserting into a closed TableWriter.
+var ErrWriterClosed = errors.New("table writer closed")
+
+// TableWriterOption is an option for Table.NewWriter.
+type TableWriterOption func(*TableWriter)
+
+// WithWriterFlushRows flushes the buffered records once they contain at least
+// the given number of rows. A value <= 0 disables the row threshold.
+func WithWriterFlushRows(rows int64) TableWriterOption {
+	return func(w *TableWriter) {
+		w.flushRows = rows
+	}
+}
+
+// WithWriterFlushBytes flushes the buffered records once their size is at
+// least the given number of bytes. A value <= 0 disables the size threshold.
+func WithWriterFlushBytes(bytes int64) TableWriterOption {
+	return func(w *TableWriter) {
+		w.flushBytes = bytes
+	}
+}
+
+// WithWriterFlushInterval flushes the buffered records in the background at
+// the given interval, so that records are not buffered for longer than it. A
+// value <= 0 disables flushing in the background.
+func WithWriterFlushInterval(interval time.Duration) TableWriterOption {
+	return func(w *TableWriter) {
+		w.flushInterval = interval
+	}
+}
+
+// TableWriter buffers the records inserted into a table and inserts them as
+// a single record, in a single transaction, once a threshold is reached.
+// Inserting many small records into a table directly logs a WAL record and
+// creates a part per insert, which a TableWriter avoids at the cost of
+// buffered records only becoming visible once they are flushed. Buffered
+// records are lost if the process crashes before they are flushed. If
+// flushing them fails with a retryable error, i.e. an ErrQuotaExceeded or a
+// context error, records stay buffered and are flushed again by the next
+// flush. Otherwise, e.g. if the table rejects them, they are dropped and the
+// error reports the number of rows dropped. If records are dropped by a
+// flush that is not called explicitly, i.e. a flush in the background or one
+// triggered by a threshold, the error is returned by the next call to
+// InsertRecord, Flush or Close.
+//
+// A TableWriter is safe for concurrent use. Close must be called to flush the
+// remaining records and to stop flushing in the background.
+type TableWriter struct {
+	table         *Table
+	flushRows     int64
+	flushBytes    int64
+	flushInterval time.Duration
+
+	mtx     sync.Mutex
+	records []arrow.Record
+	rows    int64
+	bytes   int64
+	// fields maps the names of the columns of the buffered records to their
+	// types, so that records with conflicting columns are rejected before
+	// they are buffered.
+	fields map[string]arrow.DataType
+	closed bool
+	// dropErr is the error of the records dropped by flushes that were not
+	// called explicitly, which is returned by the next call.
+	dropErr error
+
+	stopOnce sync.Once
+	stop     chan struct{}
+	done     chan struct{}
+}
+
+// NewWriter returns a TableWriter inserting into the table, see TableWriter.
+func (t *Table) NewWriter(options ...TableWriterOption) *TableWriter {
+	w := &TableWriter{
+		table:         t,
+		flushRows:     DefaultWriterFlushRows,
+		flushBytes:    DefaultWriterFlushBytes,
+		flushInterval: DefaultWriterFlushInterval,
+	}
+	for _, o := range options {
+		o(w)
+	}
+	if w.flushInterval > 0 {
+		w.stop = make(chan struct{})
+		w.done = make(chan struct{})
+		// The ticker is created before returning, so that the interval
+		// starts when the writer is created.
+		go w.flushLoop(w.table.db.columnStore.clock.NewTicker(w.flushInterval))
+	}
+	return w
+}
+
+// InsertRecord buffers the record, which is flushed along with the other
+// buffered records once a threshold is reached. The record is retained, so
+// the caller may release it once InsertRecord returns. An error is only
+// returned if the record was not buffered: if records were dropped since the
+// last call, the error of dropping them is returned, and if the buffered
+// records still reach a threshold because the last flush failed, they are
+// flushed before the record is buffered, and the error of flushing them is
+// returned.
+func (w *TableWriter) InsertRecord(ctx context.Context, record arrow.Record) error {
+	if w.table.db.columnStore.readOnly {
+		return ErrReadOnly
+	}
+	w.mtx.Lock()
+	defer w.mtx.Unlock()
+	if w.closed {
+		return ErrWriterClosed
+	}
+	if err := w.takeDropErrLocked(); err != nil {
+		return err
+	}
+	if w.full() {
+		if _, err := w.flushLocked(ctx); err != nil {
+			return err
+		}
+	}
+	for _, f := range record.Schema().Fields() {
+		if typ, ok := w.fields[f.Name]; ok && !arrow.TypeEqual(typ, f.Type) {
+			return fmt.Errorf("column %s has type %s, but buffered records have type %s", f.Name, f.Type, typ)
+		}
+	}
+
+	record.Retain()
+	w.records = append(w.records, record)
+	w.rows += record.NumRows()
+	w.bytes += util.TotalRecordSize(record)
+	if w.fields == nil {
+		w.fields = map[string]arrow.DataType{}
+	}
+	for _, f := range record.Schema().Fields() {
+		w.fields[f.Name] = f.Type
+	}
+	if w.full() {
+		// The record is buffered either way, so a failure is only returned
+		// by the next call.
+		w.flushImplicitLocked(ctx)
+	}
+	return nil
+}
+
+// full returns whether the buffered records reach a threshold. w.mtx must be
+// held.
+func (w *TableWriter) full() bool {
+	return (w.flushRows > 0 && w.rows >= w.flushRows) || (w.flushBytes > 0 && w.bytes >= w.flushBytes)
+}
+
+// Flush inserts the buffered records into the table and returns the
+// transaction they were inserted in. It returns 0 if no records were
+// buffered. If inserting them fails with a retryable error, the records stay
+// buffered, otherwise they are dropped, see TableWriter. If records were
+// dropped since the last call, the error of dropping them is returned
+// instead, and the buffered records are flushed by the next call.
+func (w *TableWriter) Flush(ctx context.Context) (uint64, error) {
+	w.mtx.Lock()
+	defer w.mtx.Unlock()
+	if err := w.takeDropErrLocked(); err != nil {
+		return 0, err
+	}
+	return w.flushLocked(ctx)
+}
+
+// Close stops flushing in the background and flushes the remaining records.
+// If flushing them fails, the records are dropped and the returned error
+// reports the number of rows dropped, along with the error of records
+// dropped since the last call.
+func (w *TableWriter) Close(ctx context.Context) error {
+	if w.stop != nil {
+		w.stopOnce.Do(func() {
+			close(w.stop)
+		})
+		<-w.done
+	}
+
+	w.mtx.Lock()
+	defer w.mtx.Unlock()
+	if w.closed {
+		return nil
+	}
+	w.closed = true
+	dropErr := w.takeDropErrLocked()
+	if _, err := w.flushLocked(ctx); err != nil {
+		if len(w.records) == 0 {
+			// The records were already dropped.
+			return errors.Join(dropErr, err)
+		}
+		return errors.Join(dropErr, w.dropLocked(err))
+	}
+	return dropErr
+}
+
+// flushImplicitLocked flushes the buffered records for a caller that cannot
+// return the error, i.e. in the background or once a threshold is reached.
+// If the records are dropped, the error is returned by the next call to
+// InsertRecord, Flush or Close. w.mtx must be held.
+func (w *TableWriter) flushImplicitLocked(ctx context.Context) {
+	if _, err := w.flushLocked(ctx); err != nil {
+		level.Warn(w.table.logger).Log("msg", "failed to flush table writer", "table", w.table.name, "err", err)
+		if len(w.records) == 0 {
+			w.dropErr = errors.Join(w.dropErr, err)
+		}
+	}
+}
+
+// takeDropErrLocked returns and clears the error of the records dropped by
+// flushes that were not called explicitly. w.mtx must be held.
+func (w *TableWriter) takeDropErrLocked() error {
+	err := w.dropErr
+	w.dropErr = nil
+	return err
+}
+
+// flushLocked inserts the buffered records into the table as a single
+// record. The buffered records are kept if inserting them fails with a
+// retryable error and dropped if it fails otherwise. w.mtx must be held.
+func (w *TableWriter) flushLocked(ctx context.Context) (uint64, error) {
+	if len(w.records) == 0 {
+		return 0, nil
+	}
+	record, err := concatRecords(w.table.db.columnStore.allocator, w.records)
+	if err != nil {
+		return 0, w.dropLocked(fmt.Errorf("concatenate records: %w", err))
+	}
+	defer record.Release()
+	tx, err := w.table.InsertRecord(ctx, record)
+	if err != nil {
+		if isRetryableInsertError(err) {
+			return 0, err
+		}
+		return 0, w.dropLocked(err)
+	}
+	w.reset()
+	return tx, nil
+}
+
+// dropLocked releases the buffered records because flushing them failed with
+// err and returns err annotated with the number of rows dropped. w.mtx must
+// be held.
+func (w *TableWriter) dropLocked(err error) error {
+	rows := w.rows
+	w.table.metrics.writerRowsDropped.Add(float64(rows))
+	w.reset()
+	return fmt.Errorf("drop %d buffered rows: %w", rows, err)
+}
+
+// isRetryableInsertError returns whether inserting a record that failed with
+// err may succeed if retried unchanged.
+func isRetryableInsertError(err error) bool {
+	return errors.As(err, &ErrQuotaExceeded{}) ||
+		errors.Is(err, context.Canceled) ||
+		errors.Is(err, context.DeadlineExceeded)
+}
+
+// reset releases the buffered records. w.mtx must be held.
+func (w *TableWriter) reset() {
+	for _, r := range w.records {
+		r.Release()
+	}
+	w.records = nil
+	w.rows = 0
+	w.bytes = 0
+	w.fields = nil
+}
+
+func (w *TableWriter) flushLoop(ticker Ticker) {
+	defer close(w.done)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-w.stop:
+			return
+		case <-ticker.C():
+			w.mtx.Lock()
+			// Unless they are dropped, the records stay buffered and are
+			// flushed again by the next flush.
+			w.flushImplicitLocked(context.Background())
+			w.mtx.Unlock()
+		}
+	}
+}
+
+// GenericTableWriter is a TableWriter inserting structs of type T into a
+// GenericTable, see TableWriter.
+type GenericTableWriter[T any] struct {
+	*TableWriter
+	table *GenericTable[T]
+}
+
+// NewWriter returns a GenericTableWriter inserting into the table.
+func (t *GenericTable[T]) NewWriter(options ...TableWriterOption) *GenericTableWriter[T] {
+	return &GenericTableWriter[T]{
+		TableWriter: t.Table.NewWriter(options...),
+		table:       t,
+	}
+}
+
+// Write builds an arrow.Record from values and buffers it, see
+// (*TableWriter).InsertRecord.
+func (w *GenericTableWriter[T]) Write(ctx context.Context, values ...T) error {
+	record, err := w.table.buildRecord(values...)
+	if err != nil {
+		return err
+	}
+	defer record.Release()
+	return w.InsertRecord(ctx, record)
+}
+
+// concatRecords concatenates the given records into a single record. If the
+// records have different schemas, e.g. because they contain different
+// concrete dynamic columns, the record contains the union of their columns
+// sorted by name, and the columns a record is missing are null for its rows.
+func concatRecords(mem memory.Allocator, records []arrow.Record) (arrow.Record, error) {
+	if len(records) == 1 {
+		records[0].Retain()
+		return records[0], nil
+	}
+
+	schema := records[0].Schema()
+	for _, r := range records[1:] {
+		if !r.Schema().Equal(schema) {
+			schema = unionSchema(records)
+			break
+		}
+	}
+
+	cols := make([]arrow.Array, schema.NumFields())
+	defer func() {
+		for _, c := range cols {
+			if c != nil {
+				c.Release()
+			}
+		}
+	}()
+	var rows int64
+	for _, r := range records {
+		rows += r.NumRows()
+	}
+	arrs := make([]arrow.Array, len(records))
+	for i, field := range schema.Fields() {
+		var nulls []arrow.Array
+		for j, r := range records {
+			if idx := r.Schema().FieldIndices(field.Name); len(idx) == 1 {
+				arrs[j] = r.Column(idx[0])
+				continue
+			}
+			arrs[j] = arrowutils.MakeNullArray(mem, field.Type, int(r.NumRows()))
+			nulls = append(nulls, arrs[j])
+		}
+		c, err := array.Concatenate(arrs, mem)
+		for _, n := range nulls {
+			n.Release()
+		}
+		if err != nil {
+			return nil, fmt.Errorf("column %s: %w", field.Name, err)
+		}
+		cols[i] = c
+	}
+	return array.NewRecord(schema, cols, rows), nil
+}
+
+// unionSchema returns the schema containing the fields of all records, sorted
+// by name. A field is nullable if it is nullable in any record or missing from
+// any record.
+func unionSchema(records []arrow.Record) *arrow.Schema {
+	fields := map[string]arrow.Field{}
+	for _, r := range records {
+		for _, f := range r.Schema().Fields() {
+			if existing, ok := fields[f.Name]; ok {
+				f.Nullable = f.Nullable || existing.Nullable
+			}
+			fields[f.Name] = f
+		}
+	}
+	for name, f := range fields {
+		for _, r := range records {
+			if len(r.Schema().FieldIndices(name)) == 0 {
+				f.Nullable = true
+				fields[name] = f
+				break
+			}
+		}
+	}
+	union := make([]arrow.Field, 0, len(fields))
+	for _, f := range fields {
+		union = append(union, f)
+	}
+	sort.Slice(union, func(i, j int) bool { return union[i].Name < union[j].Name })
+	return arrow.NewSchema(union, nil)
+}
@@ -0,0 +1,239 @@
+package frostdb
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/apache/arrow/go/v17/arrow"
+	"github.com/apache/arrow/go/v17/arrow/array"
+	"github.com/apache/arrow/go/v17/arrow/memory"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+
+	"github.com/polarsignals/frostdb/dynparquet"
+)
+
+func TestTableWriter(t *testing.T) {
+	ctx := context.Background()
+	clock := NewManualClock(time.Unix(0, 0))
+	c, db, table := openTestTable(t, []Option{WithClock(clock)})
+	defer c.Close()
+
+	// Each record has a different concrete dynamic column.
+	record := func(t *testing.T, label string, rows int) arrow.Record {
+		t.Helper()
+		samples := make(dynparquet.Samples, 0, rows)
+		for i := 0; i < rows; i++ {
+			samples = append(samples, dynparquet.Sample{
+				ExampleType: "cpu",
+				Labels:      map[string]string{label: "value"},
+				Timestamp:   int64(i),
+				Value:       int64(i),
+			})
+		}
+		r, err := samples.ToRecord()
+		require.NoError(t, err)
+		t.Cleanup(r.Release)
+		return r
+	}
+
+	t.Run("Thresholds", func(t *testing.T) {
+		w := table.NewWriter(WithWriterFlushRows(10), WithWriterFlushInterval(time.Hour))
+		require.NoError(t, w.InsertRecord(ctx, record(t, "label1", 4)))
+		require.NoError(t, w.InsertRecord(ctx, record(t, "label2", 4)))
+		require.Zero(t, countRows(t, db, "test"))
+
+		// Reaching the row threshold inserts all records in a single
+		// transaction.
+		watermark := db.HighWatermark()
+		require.NoError(t, w.InsertRecord(ctx, record(t, "label3", 4)))
+		require.Equal(t, watermark+1, db.HighWatermark())
+		require.Equal(t, int64(12), countRows(t, db, "test"))
+		require.Equal(t, 4, countRowsBy(t, db, "test", "labels.label1")["value"])
+		require.Equal(t, 4, countRowsBy(t, db, "test", "labels.label2")["value"])
+		require.Equal(t, 4, countRowsBy(t, db, "test", "labels.label3")["value"])
+
+		// Close flushes the remaining records.
+		require.NoError(t, w.InsertRecord(ctx, record(t, "label1", 1)))
+		require.NoError(t, w.Close(ctx))
+		require.Equal(t, int64(13), countRows(t, db, "test"))
+		require.ErrorIs(t, w.InsertRecord(ctx, record(t, "label1", 1)), ErrWriterClosed)
+	})
+
+	t.Run("Interval", func(t *testing.T) {
+		w := table.NewWriter(WithWriterFlushRows(0), WithWriterFlushBytes(0), WithWriterFlushInterval(time.Second))
+		defer w.Close(ctx)
+		before := countRows(t, db, "test")
+		require.NoError(t, w.InsertRecord(ctx, record(t, "label1", 2)))
+		require.Equal(t, before, countRows(t, db, "test"))
+
+		clock.Advance(time.Second)
+		require.Eventually(t, func() bool {
+			return countRows(t, db, "test") == before+2
+		}, 5*time.Second, 10*time.Millisecond)
+	})
+
+	t.Run("FlushFailure", func(t *testing.T) {
+		w := table.NewWriter(WithWriterFlushRows(4), WithWriterFlushInterval(time.Hour))
+		before := countRows(t, db, "test")
+		require.NoError(t, w.InsertRecord(ctx, record(t, "label1", 2)))
+		configure := func(options ...TableOption) {
+			_, err := db.Table("test", NewTableConfig(dynparquet.SampleDefinition(), options...))
+			require.NoError(t, err)
+		}
+
+		// Records stay buffered if flushing them fails with a retryable
+		// error.
+		configure(WithStorageQuota(1))
+		_, err := w.Flush(ctx)
+		require.ErrorAs(t, err, &ErrQuotaExceeded{})
+		configure()
+		require.Equal(t, before, countRows(t, db, "test"))
+		_, err = w.Flush(ctx)
+		require.NoError(t, err)
+		require.Equal(t, before+2, countRows(t, db, "test"))
+
+		// Close reports the rows it drops.
+		require.NoError(t, w.InsertRecord(ctx, record(t, "label1", 3)))
+		configure(WithStorageQuota(1))
+		err = w.Close(ctx)
+		configure()
+		require.ErrorAs(t, err, &ErrQuotaExceeded{})
+		require.ErrorContains(t, err, "drop 3 buffered rows")
+		require.Equal(t, before+2, countRows(t, db, "test"))
+	})
+
+	t.Run("RejectedRecords", func(t *testing.T) {
+		_, err := db.Table("test", NewTableConfig(
+			dynparquet.SampleDefinition(),
+			WithMonotonicTimestamps(10, MonotonicTimestampsReject),
+		))
+		require.NoError(t, err)
+		defer func() {
+			_, err := db.Table("test", NewTableConfig(dynparquet.SampleDefinition()))
+			require.NoError(t, err)
+		}()
+
+		w := table.NewWriter(WithWriterFlushRows(0), WithWriterFlushBytes(0), WithWriterFlushInterval(time.Hour))
+		defer w.Close(ctx)
+		require.NoError(t, w.InsertRecord(ctx, record(t, "label4", 2)))
+		_, err = w.Flush(ctx)
+		require.NoError(t, err)
+		before := countRows(t, db, "test")
+		dropped := testutil.ToFloat64(table.metrics.writerRowsDropped)
+
+		// The series already has newer timestamps, so the table rejects the
+		// records and retrying them cannot succeed. They are dropped instead
+		// of failing every following flush.
+		require.NoError(t, w.InsertRecord(ctx, record(t, "label4", 3)))
+		_, err = w.Flush(ctx)
+		require.ErrorAs(t, err, &ErrNonMonotonicTimestamp{})
+		require.ErrorContains(t, err, "drop 3 buffered rows")
+
+		require.NoError(t, w.InsertRecord(ctx, record(t, "label5", 1)))
+		_, err = w.Flush(ctx)
+		require.NoError(t, err)
+		require.Equal(t, before+1, countRows(t, db, "test"))
+		require.Equal(t, dropped+3, testutil.ToFloat64(table.metrics.writerRowsDropped))
+
+		// Records dropped by a flush triggered by a threshold are reported
+		// by the next call, which does not buffer its record.
+		thresholdWriter := table.NewWriter(WithWriterFlushRows(2), WithWriterFlushInterval(time.Hour))
+		defer thresholdWriter.Close(ctx)
+		require.NoError(t, thresholdWriter.InsertRecord(ctx, record(t, "label4", 2)))
+		err = thresholdWriter.InsertRecord(ctx, record(t, "label6", 1))
+		require.ErrorAs(t, err, &ErrNonMonotonicTimestamp{})
+		require.ErrorContains(t, err, "drop 2 buffered rows")
+		_, err = thresholdWriter.Flush(ctx)
+		require.NoError(t, err)
+		require.Equal(t, before+1, countRows(t, db, "test"))
+
+		// Records dropped in the background are reported by the next call.
+		intervalWriter := table.NewWriter(WithWriterFlushRows(0), WithWriterFlushBytes(0), WithWriterFlushInterval(time.Second))
+		require.NoError(t, intervalWriter.InsertRecord(ctx, record(t, "label4", 1)))
+		clock.Advance(time.Second)
+		require.Eventually(t, func() bool {
+			return testutil.ToFloat64(table.metrics.writerRowsDropped) == dropped+6
+		}, 5*time.Second, 10*time.Millisecond)
+		err = intervalWriter.Close(ctx)
+		require.ErrorAs(t, err, &ErrNonMonotonicTimestamp{})
+		require.ErrorContains(t, err, "drop 1 buffered rows")
+	})
+
+	t.Run("Generic", func(t *testing.T) {
+		type row struct {
+			Name  string `frostdb:",asc(0)"`
+			Value int64  `frostdb:",asc(1)"`
+		}
+		generic, err := NewGenericTable[row](db, "generic", memory.NewGoAllocator())
+		require.NoError(t, err)
+		defer generic.Release()
+
+		w := generic.NewWriter(WithWriterFlushRows(3), WithWriterFlushInterval(time.Hour))
+		require.NoError(t, w.Write(ctx, row{Name: "a", Value: 1}, row{Name: "b", Value: 2}))
+		require.Zero(t, countRows(t, db, "generic"))
+		require.NoError(t, w.Write(ctx, row{Name: "c", Value: 3}))
+		require.Equal(t, int64(3), countRows(t, db, "generic"))
+		require.NoError(t, w.Write(ctx, row{Name: "d", Value: 4}))
+		require.NoError(t, w.Close(ctx))
+		require.Equal(t, 1, countRowsBy(t, db, "generic", "name")["d"])
+	})
+
+	t.Run("ConflictingColumns", func(t *testing.T) {
+		w := table.NewWriter(WithWriterFlushInterval(time.Hour))
+		defer w.Close(ctx)
+		require.NoError(t, w.InsertRecord(ctx, record(t, "label1", 1)))
+
+		b := array.NewRecordBuilder(memory.DefaultAllocator, arrow.NewSchema([]arrow.Field{
+			{Name: "labels.label1", Type: arrow.PrimitiveTypes.Int64, Nullable: true},
+		}, nil))
+		defer b.Release()
+		b.Field(0).(*array.Int64Builder).Append(1)
+		r := b.NewRecord()
+		defer r.Release()
+		require.Error(t, w.InsertRecord(ctx, r))
+	})
+}
+
+func TestConcatRecords(t *testing.T) {
+	mem := memory.NewCheckedAllocator(memory.NewGoAllocator())
+	defer mem.AssertSize(t, 0)
+
+	build := func(label string, values ...int64) arrow.Record {
+		b := array.NewRecordBuilder(mem, arrow.NewSchema([]arrow.Field{
+			{Name: "labels." + label, Type: arrow.BinaryTypes.String},
+			{Name: "value", Type: arrow.PrimitiveTypes.Int64},
+		}, nil))
+		defer b.Release()
+		for _, v := range values {
+			b.Field(0).(*array.StringBuilder).Append(label)
+			b.Field(1).(*array.Int64Builder).Append(v)
+		}
+		return b.NewRecord()
+	}
+	r1 := build("label1", 1, 2)
+	defer r1.Release()
+	r2 := build("label2", 3)
+	defer r2.Release()
+
+	r, err := concatRecords(mem, []arrow.Record{r1, r2})
+	require.NoError(t, err)
+	defer r.Release()
+
+	// The record contains the union of the dynamic columns, which are null
+	// for the rows of the records missing them.
+	require.Equal(t, int64(3), r.NumRows())
+	require.Equal(t, arrow.NewSchema([]arrow.Field{
+		{Name: "labels.label1", Type: arrow.BinaryTypes.String, Nullable: true},
+		{Name: "labels.label2", Type: arrow.BinaryTypes.String, Nullable: true},
+		{Name: "value", Type: arrow.PrimitiveTypes.Int64},
+	}, nil), r.Schema())
+	label1 := r.Column(0).(*array.String)
+	require.Equal(t, []bool{true, true, false}, []bool{label1.IsValid(0), label1.IsValid(1), label1.IsValid(2)})
+	require.Equal(t, "label1", label1.Value(0))
+	label2 := r.Column(1).(*array.String)
+	require.Equal(t, []bool{false, false, true}, []bool{label2.IsValid(0), label2.IsValid(1), label2.IsValid(2)})
+	require.Equal(t, "label2", label2.Value(2))
+	require.Equal(t, []int64{1, 2, 3}, r.Column(2).(*array.Int64).Int64Values())
+}